@@ -0,0 +1,69 @@
+// Command rbac-report compares the permission rules in the database with the routes protected
+// by the RBAC middleware. It lists routes without a permission rule, rules for routes that are
+// not registered and rules whose method is not registered for their route, and exits with
+// status 1 if there are any, so that RBAC_DENY_BY_DEFAULT can be enabled safely.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"kc-api/internal/database"
+	"kc-api/internal/rbac"
+	"kc-api/internal/server"
+)
+
+func main() {
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	db := database.New()
+	pm := rbac.NewPermissionManager(rbac.NewRepository(db.DB()))
+	err := pm.LoadPermissions(context.Background())
+	db.Close()
+	if err != nil {
+		log.Fatalf("Failed to load permission rules: %v", err)
+	}
+	if err := pm.SetRoutes(server.ProtectedRoutes()); err != nil {
+		log.Fatalf("Failed to collect routes: %v", err)
+	}
+	report := pm.Coverage()
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatalf("Failed to encode report: %v", err)
+		}
+	} else {
+		printReport(report)
+	}
+
+	if report.HasIssues() {
+		os.Exit(1)
+	}
+}
+
+func printReport(report *rbac.CoverageReport) {
+	fmt.Printf("%d protected routes, %d permission rules\n", report.RouteCount, report.RuleCount)
+
+	fmt.Printf("\nRoutes without a permission rule (%d):\n", len(report.UncoveredRoutes))
+	for _, route := range report.UncoveredRoutes {
+		fmt.Printf("  %-7s %s\n", route.Method, route.Pattern)
+	}
+
+	fmt.Printf("\nRules for routes that are not registered (%d):\n", len(report.StaleRules))
+	for _, rule := range report.StaleRules {
+		fmt.Printf("  %-7s %s\n", rule.Method, rule.Pattern)
+	}
+
+	fmt.Printf("\nRules whose method is not registered for their route (%d):\n", len(report.MethodMismatches))
+	for _, mismatch := range report.MethodMismatches {
+		fmt.Printf("  %-7s %s (registered: %s)\n", mismatch.Method, mismatch.Pattern, strings.Join(mismatch.RegisteredMethods, ", "))
+	}
+}
@@ -48,111 +48,116 @@ const docTemplate = `{
                 }
             }
         },
-        "/admin/refresh-permissions": {
-            "post": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Reloads API permissions from the database into the in-memory cache. This endpoint allows hot-reloading of permission rules without restarting the server.",
-                "consumes": [
-                    "application/json"
-                ],
+        "/.well-known/jwks.json": {
+            "get": {
+                "description": "Returns the public keys verifying access tokens as a JWK set (RFC 7517), including the next key before it signs. Services verify a token with the key named by its kid header. Empty while access tokens are signed with JWT_SECRET (HS256).",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "admin"
+                    "auth"
                 ],
-                "summary": "Refresh API permissions cache",
+                "summary": "Get the access token signing keys",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/rbac.SuccessResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/rbac.ErrorResponse"
-                        }
-                    },
-                    "403": {
-                        "description": "Forbidden - requires sysadmin role",
-                        "schema": {
-                            "$ref": "#/definitions/rbac.ErrorResponse"
+                            "$ref": "#/definitions/auth.JSONWebKeySet"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/rbac.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/ai/keywords": {
+        "/admin/auth/directory/sync": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Submits a keyword extraction task to the AI worker queue and returns a task ID for tracking",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Runs the directory sync now: creates and updates users and departments from the directory, soft-deletes users that are disabled or gone from it, and reconciles mapped group memberships. With dry_run=true the changes are only reported. The sync is refused if the directory returns no users at all.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "ai"
+                    "admin"
                 ],
-                "summary": "Submit keyword extraction task",
+                "summary": "Synchronize users from the LDAP directory",
                 "parameters": [
                     {
-                        "description": "Keyword extraction request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/aiqueue.KeywordsRequest"
-                        }
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Report the changes without applying them",
+                        "name": "dry_run",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "Accepted",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/aiqueue.KeywordsResponse"
+                            "$ref": "#/definitions/auth.DirectorySyncReport"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid request",
                         "schema": {
-                            "$ref": "#/definitions/utils.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A sync is already running",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/utils.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "LDAP directory unavailable or returned no users",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "LDAP directory is not configured",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/ai/normalize": {
+        "/admin/auth/impersonate": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Submits a JSON normalization task to the AI worker queue and returns a task ID for tracking. This normalizes unstructured text into a structured JSON format based on the provided schema.",
+                "description": "Issues a short-lived access token acting as another user to reproduce what they see, for members of AUTH_IMPERSONATION_ROLES. The token names the administrator in its act claim, cannot be refreshed, cannot change passwords, MFA, sessions or tokens, and every request made with it is recorded.",
                 "consumes": [
                     "application/json"
                 ],
@@ -160,201 +165,260 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "ai"
+                    "admin"
                 ],
-                "summary": "Submit JSON normalization task",
+                "summary": "Impersonate a user",
                 "parameters": [
                     {
-                        "description": "Normalization request",
+                        "description": "User to impersonate and reason",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/aiqueue.NormalizeRequest"
+                            "$ref": "#/definitions/auth.ImpersonateRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "Accepted",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/aiqueue.NormalizeResponse"
+                            "$ref": "#/definitions/auth.ImpersonationTokenResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid request",
                         "schema": {
-                            "$ref": "#/definitions/utils.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Impersonation not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/utils.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/ai/summarize": {
-            "post": {
+        "/admin/auth/impersonations": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Submits a text summarization task to the AI worker queue and returns a task ID for tracking",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Lists impersonations with their reason and number of recorded requests, newest first, optionally filtered by the administrator and the impersonated user.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "ai"
+                    "admin"
                 ],
-                "summary": "Submit text summarization task",
+                "summary": "List impersonations",
                 "parameters": [
                     {
-                        "description": "Summarization request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/aiqueue.SummarizeRequest"
-                        }
+                        "type": "string",
+                        "description": "Public ID of the impersonating administrator",
+                        "name": "actor_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Public ID of the impersonated user",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "Accepted",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/aiqueue.SummarizeResponse"
+                            "$ref": "#/definitions/auth.ImpersonationListResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/utils.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/utils.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/ai/tasks/{id}": {
+        "/admin/auth/impersonations/{id}/requests": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves the status and result of a previously submitted AI task. Returns task status (PENDING, STARTED, SUCCESS, FAILURE) and the result if available.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Lists the requests made with an impersonation token, oldest first, with their status codes.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "ai"
+                    "admin"
                 ],
-                "summary": "Get task status and result",
+                "summary": "List the requests of an impersonation",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Task ID",
+                        "type": "integer",
+                        "description": "Impersonation ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/aiqueue.TaskStatusResponse"
+                            "$ref": "#/definitions/auth.ImpersonatedRequestListResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid impersonation ID",
                         "schema": {
-                            "$ref": "#/definitions/utils.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "Impersonation not found",
                         "schema": {
-                            "$ref": "#/definitions/utils.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/utils.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/admin/auth/lockouts": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Deletes the result of a completed task from the queue system. This is useful for cleanup after retrieving results.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Lists login IDs and client IPs that are currently locked after too many failed logins.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "ai"
-                ],
-                "summary": "Delete task result",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Task ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "admin"
                 ],
+                "summary": "List login lockouts",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/auth.LockoutListResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/utils.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/utils.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/auth/login": {
+        "/admin/auth/lockouts/unlock": {
             "post": {
-                "description": "Authenticates a user with login_id/email and password. Returns access token in response body and refresh token as HTTP-only cookie.",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lifts the lockout and clears the failed logins of a login ID (or email) and/or a client IP. The unlock is recorded as a security event.",
                 "consumes": [
                     "application/json"
                 ],
@@ -362,17 +426,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "User login",
+                "summary": "Unlock a login",
                 "parameters": [
                     {
-                        "description": "Login credentials",
+                        "description": "Login ID and/or client IP",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/auth.LoginRequest"
+                            "$ref": "#/definitions/auth.UnlockLoginRequest"
                         }
                     }
                 ],
@@ -380,7 +444,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/auth.LoginResponse"
+                            "$ref": "#/definitions/auth.SuccessResponse"
                         }
                     },
                     "400": {
@@ -390,7 +454,19 @@ const docTemplate = `{
                         }
                     },
                     "401": {
-                        "description": "Invalid credentials",
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No failed logins tracked",
                         "schema": {
                             "$ref": "#/definitions/auth.ErrorResponse"
                         }
@@ -404,24 +480,38 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/logout": {
-            "post": {
-                "description": "Revokes the current refresh token and clears the cookie.",
-                "consumes": [
-                    "application/json"
+        "/admin/auth/password-policy": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Returns the rules new passwords must follow and whether the breached password list is loaded. The default policy is returned until one is saved.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "User logout",
+                "summary": "Get the password policy",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/auth.SuccessResponse"
+                            "$ref": "#/definitions/auth.PasswordPolicyResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
@@ -431,16 +521,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/auth/logout-all": {
-            "post": {
+            },
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Revokes all refresh tokens for the current user, logging them out from all devices.",
+                "description": "Replaces the password policy for all instances, effective immediately; omitted settings take their default. It applies to passwords set from now on; existing passwords are only affected by max_age_days, which makes users change older passwords on their next login.",
                 "consumes": [
                     "application/json"
                 ],
@@ -448,14 +536,31 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
+                ],
+                "summary": "Update the password policy",
+                "parameters": [
+                    {
+                        "description": "Password policy",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/password.Policy"
+                        }
+                    }
                 ],
-                "summary": "Logout from all devices",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/auth.SuccessResponse"
+                            "$ref": "#/definitions/auth.PasswordPolicyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid policy",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "401": {
@@ -464,6 +569,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -473,29 +584,53 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/me": {
+        "/admin/auth/security-events": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Returns the currently authenticated user's information and roles.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Lists recorded security events such as login lockouts, unlocks and refresh token reuse, newest first. Many lockouts of different login IDs from few client IPs indicate credential stuffing.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
+                ],
+                "summary": "List security events",
+                "parameters": [
+                    {
+                        "enum": [
+                            "LOGIN_LOCKOUT",
+                            "LOGIN_UNLOCK",
+                            "REFRESH_TOKEN_REUSE"
+                        ],
+                        "type": "string",
+                        "description": "Event type filter",
+                        "name": "event_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get current user info",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/auth.MeResponse"
+                            "$ref": "#/definitions/auth.SecurityEventListResponse"
                         }
                     },
                     "401": {
@@ -504,6 +639,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -513,28 +654,36 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/refresh": {
-            "post": {
-                "description": "Uses the refresh token from HTTP-only cookie to generate new access and refresh tokens. Implements token rotation for security.",
-                "consumes": [
-                    "application/json"
+        "/admin/auth/service-accounts": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Lists the service accounts, which are users without a password that authenticate with API keys.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Refresh access token",
+                "summary": "List service accounts",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/auth.TokenResponse"
+                            "$ref": "#/definitions/auth.ServiceAccountListResponse"
                         }
                     },
                     "401": {
-                        "description": "Invalid or expired refresh token",
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/auth.ErrorResponse"
                         }
@@ -546,11 +695,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/auth/register": {
+            },
             "post": {
-                "description": "Creates a new user account with email, password, and name. The user is automatically added to the 'public' group.",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a user for machine access that cannot log in and authenticates with API keys only. It belongs to no groups; grant it roles through user and group management before creating keys.",
                 "consumes": [
                     "application/json"
                 ],
@@ -558,17 +710,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Register a new user",
+                "summary": "Create a service account",
                 "parameters": [
                     {
-                        "description": "Registration data",
+                        "description": "Service account data",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/auth.RegisterRequest"
+                            "$ref": "#/definitions/auth.CreateServiceAccountRequest"
                         }
                     }
                 ],
@@ -576,7 +728,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/auth.RegisterResponse"
+                            "$ref": "#/definitions/auth.UserInfo"
                         }
                     },
                     "400": {
@@ -585,6 +737,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
                     "409": {
                         "description": "Email or login_id already exists",
                         "schema": {
@@ -600,51 +764,59 @@ const docTemplate = `{
                 }
             }
         },
-        "/common-codes": {
+        "/admin/auth/service-accounts/{id}/api-keys": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves a paginated list of common codes ordered by category and sort_order",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Lists the unrevoked API keys of a service account, including expired ones.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "common-codes"
+                    "admin"
                 ],
-                "summary": "List common codes",
+                "summary": "List API keys of a service account",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Items per page",
-                        "name": "limit",
-                        "in": "query"
+                        "type": "string",
+                        "description": "Service account ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.CommonCodeListResponseWrapper"
+                            "$ref": "#/definitions/auth.APIKeyListResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Service account not found",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
@@ -655,7 +827,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Creates a new common code. Category, code, and name are required. Category + code must be unique.",
+                "description": "Creates an API key for a service account. It is sent as ` + "`" + `Authorization: Bearer kcs_...` + "`" + ` and grants only the requested scopes, which must be roles of the service account that the caller holds as well (full_access holds every role). Without expires_at the key does not expire. The key is only returned once.",
                 "consumes": [
                     "application/json"
                 ],
@@ -663,17 +835,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "common-codes"
+                    "admin"
                 ],
-                "summary": "Create a new common code",
+                "summary": "Create an API key for a service account",
                 "parameters": [
                     {
-                        "description": "Common code data",
+                        "type": "string",
+                        "description": "Service account ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Key name, scopes and optional expiry",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.CreateCommonCodeRequest"
+                            "$ref": "#/definitions/auth.CreateAPIKeyRequest"
                         }
                     }
                 ],
@@ -681,239 +860,299 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.CommonCodeResponse"
+                            "$ref": "#/definitions/auth.CreateAPIKeyResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid name, scopes or expiry",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
-                    "409": {
-                        "description": "Category and code combination already exists",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden, e.g. a scope the caller doesn't hold",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Service account not found",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/common-codes/batch": {
-            "put": {
+        "/admin/auth/service-accounts/{id}/api-keys/{keyId}": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates multiple common codes in a single transaction",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Revokes an API key of a service account. Requests made with it are rejected immediately.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "common-codes"
+                    "admin"
                 ],
-                "summary": "Batch update common codes",
+                "summary": "Revoke an API key of a service account",
                 "parameters": [
                     {
-                        "description": "Batch update request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/commoncodes.BatchUpdateRequest"
-                        }
+                        "type": "string",
+                        "description": "Service account ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "API key ID",
+                        "name": "keyId",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.BatchOperationResponse"
+                            "$ref": "#/definitions/auth.SuccessResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Service account or API key not found",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/admin/auth/signing-keys": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Creates multiple common codes in a single transaction",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Lists the unexpired access token signing keys with their status: the active key signing new tokens, the next key published ahead of its activation, and retired keys still verifying the tokens they signed.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "common-codes"
+                    "admin"
                 ],
-                "summary": "Batch create common codes",
-                "parameters": [
-                    {
-                        "description": "Batch create request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
+                "summary": "List access token signing keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.BatchCreateRequest"
+                            "$ref": "#/definitions/auth.SigningKeyListResponse"
                         }
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "Created",
+                    },
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.BatchOperationResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/admin/auth/signing-keys/rotate": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Deletes multiple common codes in a single transaction",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Creates a signing key that signs new access tokens right away, e.g. after the active key was exposed. Replaced keys keep verifying the tokens they signed until AUTH_JWT_KEY_OVERLAP has passed, or stop right away with retire=true, which makes every client refresh its access token.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "common-codes"
+                    "admin"
                 ],
-                "summary": "Batch delete common codes",
+                "summary": "Rotate the access token signing key",
                 "parameters": [
                     {
-                        "description": "Batch delete request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/commoncodes.BatchDeleteRequest"
-                        }
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Stop verifying tokens signed by the replaced keys right away",
+                        "name": "retire",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.BatchOperationResponse"
+                            "$ref": "#/definitions/auth.SigningKeyInfo"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid request",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Access tokens are signed with JWT_SECRET",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/common-codes/categories": {
+        "/admin/auth/users/{id}/sessions": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves all unique categories from common codes",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Lists the active sessions (refresh token families) of any user.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "common-codes"
+                    "admin"
+                ],
+                "summary": "List sessions of a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "List all categories",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/commoncodes.CategoryResponse"
-                            }
+                            "$ref": "#/definitions/auth.SessionListResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/common-codes/categories/{category}": {
-            "get": {
+        "/admin/auth/users/{id}/sessions/{sessionId}": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves all common codes in a specific category, ordered by sort_order",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Ends a session of any user. Its refresh token stops working; access tokens already issued stay valid until they expire.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "common-codes"
+                    "admin"
                 ],
-                "summary": "Get codes by category",
+                "summary": "Revoke a session of a user",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Category name",
-                        "name": "category",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionId",
                         "in": "path",
                         "required": true
                     }
@@ -922,188 +1161,124 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/commoncodes.CommonCodeResponse"
-                            }
+                            "$ref": "#/definitions/auth.SuccessResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User or session not found",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/common-codes/categories/{category}/reorder": {
-            "put": {
+        "/admin/files/purge": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates the sort_order of multiple codes in a category",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Expires files according to the retention rules and purges the content of files deleted longer ago than the grace period, then reports the reclaimed bytes. The purger also runs on a schedule (FILE_PURGE_INTERVAL).",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "common-codes"
-                ],
-                "summary": "Reorder codes in a category",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Category name",
-                        "name": "category",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Reorder request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/commoncodes.ReorderRequest"
-                        }
-                    }
+                    "admin"
                 ],
+                "summary": "Run the retention purger",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.SuccessResponse"
+                            "$ref": "#/definitions/files.PurgeReport"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/common-codes/search": {
-            "post": {
+        "/admin/files/quotas": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Searches for common codes based on category, code, name, or description",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Lists storage quotas with the current usage of their user, department or group",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "common-codes"
+                    "admin"
                 ],
-                "summary": "Search common codes",
+                "summary": "List storage quotas",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Items per page",
-                        "name": "limit",
+                        "enum": [
+                            "USER",
+                            "DEPARTMENT",
+                            "GROUP"
+                        ],
+                        "type": "string",
+                        "description": "Filter by scope type",
+                        "name": "scope_type",
                         "in": "query"
-                    },
-                    {
-                        "description": "Search criteria",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/commoncodes.SearchCommonCodeRequest"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.CommonCodeListResponseWrapper"
+                            "$ref": "#/definitions/files.QuotaListResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
-                        }
-                    }
-                }
-            }
-        },
-        "/common-codes/{id}": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Retrieves a common code by its ID",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "common-codes"
-                ],
-                "summary": "Get common code by ID",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Common Code ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/commoncodes.CommonCodeResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     }
                 }
@@ -1114,7 +1289,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates an existing common code. Category + code must be unique.",
+                "description": "Creates or replaces the storage quota of a user, department or group. Department and group usage is the sum of the usage of their current members.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1122,24 +1297,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "common-codes"
+                    "admin"
                 ],
-                "summary": "Update common code",
+                "summary": "Set a storage quota",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Common Code ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Common code data to update",
+                        "description": "Quota",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.UpdateCommonCodeRequest"
+                            "$ref": "#/definitions/files.SetQuotaRequest"
                         }
                     }
                 ],
@@ -1147,56 +1315,49 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.CommonCodeResponse"
+                            "$ref": "#/definitions/files.QuotaResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     },
-                    "409": {
-                        "description": "Category and code combination already exists",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/admin/files/quotas/{id}": {
             "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Deletes a common code by ID",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Removes a storage quota. The scope falls back to no limit (or the default user quota for users).",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "common-codes"
+                    "admin"
                 ],
-                "summary": "Delete common code",
+                "summary": "Delete a storage quota",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Common Code ID",
+                        "type": "string",
+                        "description": "Quota ID (UUID)",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1206,69 +1367,62 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.SuccessResponse"
+                            "$ref": "#/definitions/files.SuccessResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/departments": {
+        "/admin/files/retention-rules": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves a paginated list of departments",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Lists file retention rules. Files matching an active rule are deleted once they are older than the longest matching retention.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "departments"
-                ],
-                "summary": "List departments",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Items per page",
-                        "name": "limit",
-                        "in": "query"
-                    }
+                    "admin"
                 ],
+                "summary": "List retention rules",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/departments.DepartmentListResponseWrapper"
+                            "$ref": "#/definitions/files.RetentionRuleListResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     }
                 }
@@ -1279,7 +1433,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Creates a new department with JSONB name and description",
+                "description": "Creates an age-based retention rule matching files by MIME type (exact or wildcard such as image/*) and/or a metadata key and value. A rule without conditions matches every file.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1287,17 +1441,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "departments"
+                    "admin"
                 ],
-                "summary": "Create a new department",
+                "summary": "Create a retention rule",
                 "parameters": [
                     {
-                        "description": "Department data",
+                        "description": "Retention rule",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/departments.CreateDepartmentRequest"
+                            "$ref": "#/definitions/files.CreateRetentionRuleRequest"
                         }
                     }
                 ],
@@ -1305,130 +1459,154 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/departments.DepartmentResponse"
+                            "$ref": "#/definitions/files.RetentionRuleResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/departments/batch": {
-            "put": {
+        "/admin/files/retention-rules/{id}": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates multiple departments in a single transaction",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Deletes a retention rule. Files already expired by the rule are not restored.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "departments"
+                    "admin"
                 ],
-                "summary": "Batch update departments",
+                "summary": "Delete a retention rule",
                 "parameters": [
                     {
-                        "description": "Batch update request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/departments.BatchUpdateDepartmentRequest"
-                        }
+                        "type": "string",
+                        "description": "Retention rule ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/departments.BatchOperationResponse"
+                            "$ref": "#/definitions/files.SuccessResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/admin/files/usage": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Creates multiple departments in a single transaction",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns the users, departments or groups using the most storage, with their quota if any",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "departments"
+                    "admin"
                 ],
-                "summary": "Batch create departments",
+                "summary": "Get top storage consumers",
                 "parameters": [
                     {
-                        "description": "Batch create request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/departments.BatchCreateDepartmentRequest"
-                        }
+                        "enum": [
+                            "USER",
+                            "DEPARTMENT",
+                            "GROUP"
+                        ],
+                        "type": "string",
+                        "default": "USER",
+                        "description": "Consumer type",
+                        "name": "scope_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Number of consumers",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/departments.BatchOperationResponse"
+                            "$ref": "#/definitions/files.UsageReportResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/admin/files/{id}/legal-hold": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Soft-deletes multiple departments in a single transaction",
+                "description": "A file under legal hold cannot be deleted by its owner, is never expired by retention rules and its content is never purged. Also applies to soft-deleted files whose content has not been purged yet.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1436,17 +1614,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "departments"
+                    "admin"
                 ],
-                "summary": "Batch delete departments",
+                "summary": "Place or release a legal hold",
                 "parameters": [
                     {
-                        "description": "Batch delete request",
+                        "type": "string",
+                        "description": "File Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Legal hold",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/departments.BatchDeleteDepartmentRequest"
+                            "$ref": "#/definitions/files.SetLegalHoldRequest"
                         }
                     }
                 ],
@@ -1454,32 +1639,85 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/departments.BatchOperationResponse"
+                            "$ref": "#/definitions/files.SuccessResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/departments/search": {
+        "/admin/permission-rules": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists all permission rules of the RBAC middleware",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List permission rules",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rbac.PermissionRuleListResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rbac.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/rbac.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/rbac.ErrorResponse"
+                        }
+                    }
+                }
+            },
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Searches for departments based on name or description",
+                "description": "Creates a permission rule requiring one of the given named permissions (or, for rules not migrated yet, one of the given roles) for a method (or * for all methods) of a registered route. The rule takes effect immediately and is recorded in the change history.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1487,280 +1725,256 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "departments"
+                    "admin"
                 ],
-                "summary": "Search departments",
+                "summary": "Create a permission rule",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Items per page",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "description": "Search criteria",
+                        "description": "Permission rule",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/departments.SearchDepartmentRequest"
+                            "$ref": "#/definitions/rbac.CreatePermissionRuleRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/departments.DepartmentListResponseWrapper"
+                            "$ref": "#/definitions/rbac.PermissionRuleResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid method, unknown route, unknown permission or unknown role",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/rbac.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/rbac.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A rule for the method and path pattern already exists",
+                        "schema": {
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/departments/tree": {
+        "/admin/permission-rules/coverage": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves the full department tree structure",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Compares the permission rules with the protected routes: routes without a rule (denied when RBAC_DENY_BY_DEFAULT is enabled), rules for routes that are not registered and rules whose method is not registered for their route",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "departments"
+                    "admin"
                 ],
-                "summary": "Get department tree",
+                "summary": "Get permission rule coverage",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/departments.DepartmentTreeResponse"
-                            }
+                            "$ref": "#/definitions/rbac.CoverageReport"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/rbac.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Routes not recorded yet",
+                        "schema": {
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/departments/{publicId}": {
+        "/admin/permission-rules/history": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves a department by its public ID",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Lists the change history of permission rules with the rule before and after each change, newest first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "departments"
+                    "admin"
                 ],
-                "summary": "Get department by public ID",
+                "summary": "List permission rule changes",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Department Public ID",
-                        "name": "publicId",
-                        "in": "path",
-                        "required": true
+                        "type": "integer",
+                        "description": "Only changes of this permission rule",
+                        "name": "permission_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/departments.DepartmentResponse"
+                            "$ref": "#/definitions/rbac.PermissionChangeListResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Invalid permission rule ID",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/admin/permission-rules/routes": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates an existing department",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Lists the methods and path patterns registered on the router, which permission rules can be created for",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "departments"
-                ],
-                "summary": "Update department",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Department Public ID",
-                        "name": "publicId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Department data to update",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/departments.UpdateDepartmentRequest"
-                        }
-                    }
+                    "admin"
                 ],
+                "summary": "List routes",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/departments.DepartmentResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/rbac.RouteListResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/admin/permission-rules/status": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Soft-deletes a department by public ID",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Reports the version (a hash of the rules, equal on instances with the same rules), number and load time of the permission rules loaded by the instance serving the request",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "departments"
-                ],
-                "summary": "Delete department",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Department Public ID",
-                        "name": "publicId",
-                        "in": "path",
-                        "required": true
-                    }
+                    "admin"
                 ],
+                "summary": "Get loaded permission version",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/departments.SuccessResponse"
+                            "$ref": "#/definitions/rbac.PermissionStatus"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/departments/{publicId}/children": {
+        "/admin/permission-rules/{id}": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves direct children of a department",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Retrieves a permission rule by its ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "departments"
+                    "admin"
                 ],
-                "summary": "Get department children",
+                "summary": "Get a permission rule",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Department Public ID",
-                        "name": "publicId",
+                        "type": "integer",
+                        "description": "Permission rule ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
@@ -1769,35 +1983,36 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/departments.DepartmentResponse"
-                            }
+                            "$ref": "#/definitions/rbac.PermissionRuleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "Permission rule not found",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/departments/{publicId}/move": {
+            },
             "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Moves a department to a new parent",
+                "description": "Changes the given fields of a permission rule. The change takes effect immediately and is recorded in the change history.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1805,24 +2020,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "departments"
+                    "admin"
                 ],
-                "summary": "Move department",
+                "summary": "Update a permission rule",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Department Public ID",
-                        "name": "publicId",
+                        "type": "integer",
+                        "description": "Permission rule ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Move request",
+                        "description": "Fields to change",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/departments.MoveDepartmentRequest"
+                            "$ref": "#/definitions/rbac.UpdatePermissionRuleRequest"
                         }
                     }
                 ],
@@ -1830,53 +2045,54 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/departments.SuccessResponse"
+                            "$ref": "#/definitions/rbac.PermissionRuleResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid method, unknown route, unknown permission or unknown role",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "Permission rule not found",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A rule for the method and path pattern already exists",
+                        "schema": {
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/departments/{publicId}/tree": {
-            "get": {
+            },
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves a subtree starting from a specific department",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Deletes a permission rule; the route is then handled by the default policy. The change takes effect immediately and is recorded in the change history.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "departments"
+                    "admin"
                 ],
-                "summary": "Get department subtree",
+                "summary": "Delete a permission rule",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Department Public ID",
-                        "name": "publicId",
+                        "type": "integer",
+                        "description": "Permission rule ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
@@ -1885,79 +2101,79 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/departments.DepartmentTreeResponse"
+                            "$ref": "#/definitions/rbac.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "Permission rule not found",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/departments.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/entries/{id}": {
+        "/admin/permissions": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves detailed entry information including tags and references",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Lists all named permissions with the roles they are assigned to",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "entries"
-                ],
-                "summary": "Get entry by ID",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Entry ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "admin"
                 ],
+                "summary": "List named permissions",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/tickets.EntryDetailResponse"
+                            "$ref": "#/definitions/rbac.NamedPermissionListResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     }
                 }
             },
-            "put": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates an existing entry",
+                "description": "Creates a named permission such as tickets:update and assigns it to roles. Users get the permission through any of the roles, directly or through their groups. The change takes effect immediately.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1965,75 +2181,79 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "entries"
+                    "admin"
                 ],
-                "summary": "Update entry",
+                "summary": "Create a named permission",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Entry ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Entry data to update",
+                        "description": "Named permission",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/tickets.UpdateEntryRequest"
+                            "$ref": "#/definitions/rbac.CreateNamedPermissionRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/tickets.EntryListResponse"
+                            "$ref": "#/definitions/rbac.NamedPermissionResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid name or unknown role",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/rbac.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A permission with this name already exists",
+                        "schema": {
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/admin/permissions/{id}": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Performs a soft delete on an entry",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Retrieves a named permission with its roles by its ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "entries"
+                    "admin"
                 ],
-                "summary": "Delete entry",
+                "summary": "Get a named permission",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Entry ID",
+                        "description": "Permission ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -2043,32 +2263,36 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/tickets.SuccessResponse"
+                            "$ref": "#/definitions/rbac.NamedPermissionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "Permission not found",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/entries/{id}/tags": {
-            "post": {
+            },
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Adds tags to an entry",
+                "description": "Changes the description or replaces the roles of a named permission. The name cannot be changed. The change takes effect immediately.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2076,24 +2300,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "entries"
+                    "admin"
                 ],
-                "summary": "Add tags to entry",
+                "summary": "Update a named permission",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Entry ID",
+                        "description": "Permission ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Tags to add",
+                        "description": "Fields to change",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/tickets.AddTagRequest"
+                            "$ref": "#/definitions/rbac.UpdateNamedPermissionRequest"
                         }
                     }
                 ],
@@ -2101,94 +2325,94 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/tickets.SuccessResponse"
+                            "$ref": "#/definitions/rbac.NamedPermissionResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Unknown role",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "Permission not found",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/entries/{id}/tags/{tagId}": {
+            },
             "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Removes a tag from an entry",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Deletes a named permission and its role assignments. Permissions still required by permission rules cannot be deleted.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "entries"
+                    "admin"
                 ],
-                "summary": "Remove tag from entry",
+                "summary": "Delete a named permission",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Entry ID",
+                        "description": "Permission ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Tag ID",
-                        "name": "tagId",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/tickets.SuccessResponse"
+                            "$ref": "#/definitions/rbac.SuccessResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Invalid ID",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Permission not found",
+                        "schema": {
+                            "$ref": "#/definitions/rbac.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Permission is required by permission rules",
+                        "schema": {
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/files": {
-            "get": {
+        "/admin/refresh-permissions": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves a paginated list of files uploaded by the current user",
+                "description": "Reloads API permissions from the database into the in-memory cache and notifies the other instances to reload theirs. This endpoint allows hot-reloading of permission rules without restarting the server.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2196,114 +2420,96 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "files"
-                ],
-                "summary": "List my uploaded files",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 20,
-                        "description": "Items per page",
-                        "name": "limit",
-                        "in": "query"
-                    }
+                    "admin"
                 ],
+                "summary": "Refresh API permissions cache",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/files.FileListResponse"
+                            "$ref": "#/definitions/rbac.SuccessResponse"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/files.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - requires the admin or full_access role",
+                        "schema": {
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/files.ErrorResponse"
+                            "$ref": "#/definitions/rbac.ErrorResponse"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/api/ai/keywords": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Uploads a file to the default storage backend. Maximum file size is 100MB.",
+                "description": "Submits a keyword extraction task to the AI worker queue and returns a task ID for tracking",
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "ai"
                 ],
-                "summary": "Upload a file",
+                "summary": "Submit keyword extraction task",
                 "parameters": [
                     {
-                        "type": "file",
-                        "description": "File to upload",
-                        "name": "file",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Optional metadata JSON object",
-                        "name": "metadata",
-                        "in": "formData"
+                        "description": "Keyword extraction request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/aiqueue.KeywordsRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/files.FileUploadResponse"
+                            "$ref": "#/definitions/aiqueue.KeywordsResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/files.ErrorResponse"
-                        }
-                    },
-                    "413": {
-                        "description": "File too large",
-                        "schema": {
-                            "$ref": "#/definitions/files.ErrorResponse"
+                            "$ref": "#/definitions/utils.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/files.ErrorResponse"
+                            "$ref": "#/definitions/utils.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/files/{id}": {
-            "get": {
+        "/api/ai/normalize": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves detailed information about a file without downloading it",
+                "description": "Submits a JSON normalization task to the AI worker queue and returns a task ID for tracking. This normalizes unstructured text into a structured JSON format based on the provided schema.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2311,46 +2517,50 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "ai"
                 ],
-                "summary": "Get file information",
+                "summary": "Submit JSON normalization task",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "File Public ID (UUID)",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Normalization request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/aiqueue.NormalizeRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/files.FileResponse"
+                            "$ref": "#/definitions/aiqueue.NormalizeResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/files.ErrorResponse"
+                            "$ref": "#/definitions/utils.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/files.ErrorResponse"
+                            "$ref": "#/definitions/utils.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/api/ai/summarize": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Performs a soft delete on a file. Only the file uploader can delete the file.",
+                "description": "Submits a text summarization task to the AI worker queue and returns a task ID for tracking",
                 "consumes": [
                     "application/json"
                 ],
@@ -2358,71 +2568,64 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "ai"
                 ],
-                "summary": "Delete a file",
+                "summary": "Submit text summarization task",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "File Public ID (UUID)",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Summarization request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/aiqueue.SummarizeRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/files.SuccessResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/files.ErrorResponse"
-                        }
-                    },
-                    "403": {
-                        "description": "Forbidden - not the file owner",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/files.ErrorResponse"
+                            "$ref": "#/definitions/aiqueue.SummarizeResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/files.ErrorResponse"
+                            "$ref": "#/definitions/utils.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/files.ErrorResponse"
+                            "$ref": "#/definitions/utils.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/files/{id}/download": {
+        "/api/ai/tasks/{id}": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Downloads the file content. Increments the download counter.",
+                "description": "Retrieves the status and result of a previously submitted AI task. Returns task status (PENDING, STARTED, SUCCESS, FAILURE) and the result if available.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
-                    "application/octet-stream"
+                    "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "ai"
                 ],
-                "summary": "Download a file",
+                "summary": "Get task status and result",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "File Public ID (UUID)",
+                        "description": "Task ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -2432,32 +2635,36 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "file"
+                            "$ref": "#/definitions/aiqueue.TaskStatusResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/files.ErrorResponse"
+                            "$ref": "#/definitions/utils.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/files.ErrorResponse"
+                            "$ref": "#/definitions/utils.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/files/{id}/metadata": {
-            "put": {
+            },
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates the metadata of a file. Only the file uploader can update metadata.",
+                "description": "Deletes the result of a completed task from the queue system. This is useful for cleanup after retrieving results.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2465,75 +2672,46 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "ai"
                 ],
-                "summary": "Update file metadata",
+                "summary": "Delete task result",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "File Public ID (UUID)",
+                        "description": "Task ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Metadata to update",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/files.UpdateFileMetadataRequest"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/files.SuccessResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/files.ErrorResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/files.ErrorResponse"
-                        }
-                    },
-                    "403": {
-                        "description": "Forbidden - not the file owner",
-                        "schema": {
-                            "$ref": "#/definitions/files.ErrorResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/files.ErrorResponse"
+                            "$ref": "#/definitions/utils.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/files.ErrorResponse"
+                            "$ref": "#/definitions/utils.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/groups": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Retrieves a paginated list of groups",
+        "/auth/email/verify": {
+            "post": {
+                "description": "Marks the user's email address as verified using the token from a verification email. The token can only be used once.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2541,47 +2719,50 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "groups"
+                    "auth"
                 ],
-                "summary": "List groups",
+                "summary": "Verify email address",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Items per page",
-                        "name": "limit",
-                        "in": "query"
+                        "description": "Verification token",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.VerifyEmailRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/groups.GroupListResponseWrapper"
+                            "$ref": "#/definitions/auth.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or token",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/auth/email/verify/resend": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Creates a new group with JSONB name and description",
+                "description": "Sends a new email verification link to the current user. Requests are rate limited per address.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2589,99 +2770,95 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "groups"
+                    "auth"
                 ],
-                "summary": "Create a new group",
-                "parameters": [
-                    {
-                        "description": "Group data",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
+                "summary": "Resend verification email",
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/groups.CreateGroupRequest"
+                            "$ref": "#/definitions/auth.SuccessResponse"
                         }
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "Created",
+                    },
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/groups.GroupResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "409": {
+                        "description": "Email is already verified",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests for this address",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Email delivery is not configured",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/groups/batch": {
-            "put": {
+        "/auth/impersonation/end": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates multiple groups in a single transaction",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Revokes the impersonation token of the request before it expires. The administrator's own session is not affected.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "groups"
-                ],
-                "summary": "Batch update groups",
-                "parameters": [
-                    {
-                        "description": "Batch update request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/groups.BatchUpdateGroupRequest"
-                        }
-                    }
+                    "auth"
                 ],
+                "summary": "End an impersonation",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/groups.BatchOperationResponse"
+                            "$ref": "#/definitions/auth.SuccessResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Not impersonating",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/auth/login": {
             "post": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Creates multiple groups in a single transaction",
+                "description": "Authenticates a user with login_id/email and password. Returns access token in response body and refresh token as HTTP-only cookie. If the user has two-factor authentication enabled, or their roles require it, no tokens are returned. Instead ` + "`" + `mfa` + "`" + ` contains a short-lived challenge token to complete via /auth/mfa/verify. If the password is older than the password policy allows, ` + "`" + `password_change` + "`" + ` contains a token to set a new password with via /auth/password/change first. Repeated failures delay further attempts and temporarily lock the login ID or client IP; the Retry-After header tells when to try again. If an LDAP directory is configured, directory users are checked against it and unknown login IDs are looked up there and provisioned on their first login.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2689,48 +2866,63 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "groups"
+                    "auth"
                 ],
-                "summary": "Batch create groups",
+                "summary": "User login",
                 "parameters": [
                     {
-                        "description": "Batch create request",
+                        "description": "Login credentials",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/groups.BatchCreateGroupRequest"
+                            "$ref": "#/definitions/auth.LoginRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/groups.BatchOperationResponse"
+                            "$ref": "#/definitions/auth.LoginResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid request",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Login locked or attempted too soon after a failure",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "LDAP directory unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Deletes multiple groups in a single transaction",
+            }
+        },
+        "/auth/logout": {
+            "post": {
+                "description": "Revokes the current refresh token and, if sent in the Authorization header, the access token, and clears the cookie.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2738,50 +2930,33 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "groups"
-                ],
-                "summary": "Batch delete groups",
-                "parameters": [
-                    {
-                        "description": "Batch delete request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/groups.BatchDeleteGroupRequest"
-                        }
-                    }
+                    "auth"
                 ],
+                "summary": "User logout",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/groups.BatchOperationResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.SuccessResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/groups/search": {
+        "/auth/logout-all": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Searches for groups based on name or description",
+                "description": "Revokes all refresh tokens and access tokens of the current user, logging them out from all devices.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2789,64 +2964,39 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "groups"
-                ],
-                "summary": "Search groups",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Items per page",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "description": "Search criteria",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/groups.SearchGroupRequest"
-                        }
-                    }
+                    "auth"
                 ],
+                "summary": "Logout from all devices",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/groups.GroupListResponseWrapper"
+                            "$ref": "#/definitions/auth.SuccessResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/groups/{publicId}": {
+        "/auth/me": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves a group by its public ID",
+                "description": "Returns the currently authenticated user's information and roles. While an administrator impersonates the user, impersonator identifies the administrator.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2854,46 +3004,39 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "groups"
-                ],
-                "summary": "Get group by public ID",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Group Public ID",
-                        "name": "publicId",
-                        "in": "path",
-                        "required": true
-                    }
+                    "auth"
                 ],
+                "summary": "Get current user info",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/groups.GroupResponse"
+                            "$ref": "#/definitions/auth.MeResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/auth/mfa": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates an existing group",
+                "description": "Returns whether two-factor authentication is enabled for the current user, whether their roles require it, and how many recovery codes are left.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2901,61 +3044,39 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "groups"
-                ],
-                "summary": "Update group",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Group Public ID",
-                        "name": "publicId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Group data to update",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/groups.UpdateGroupRequest"
-                        }
-                    }
+                    "auth"
                 ],
+                "summary": "Get two-factor authentication status",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/groups.GroupResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.MFAStatusResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/auth/mfa/disable": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Deletes a group by public ID",
+                "description": "Removes the TOTP secret and recovery codes after checking a current code or recovery code. Not allowed if the user's roles require two-factor authentication.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2963,48 +3084,62 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "groups"
+                    "auth"
                 ],
-                "summary": "Delete group",
+                "summary": "Disable two-factor authentication",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Group Public ID",
-                        "name": "publicId",
-                        "in": "path",
-                        "required": true
+                        "description": "Current code or recovery code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.MFACodeRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/groups.SuccessResponse"
+                            "$ref": "#/definitions/auth.SuccessResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Invalid request or MFA not enabled",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid verification code",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Two-factor authentication is required for your roles",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/groups/{publicId}/roles": {
-            "get": {
+        "/auth/mfa/enroll": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves all roles assigned to a group",
+                "description": "Generates a new TOTP secret and its otpauth:// provisioning URI (render it as a QR code for authenticator apps). Two-factor authentication is enabled once a code is confirmed. Authenticate with an access token, then confirm via /auth/mfa/enroll/confirm. Users whose roles require MFA authenticate with the enrollment challenge token from login instead, then confirm via /auth/mfa/verify.",
                 "consumes": [
                     "application/json"
                 ],
@@ -3012,49 +3147,45 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "groups"
-                ],
-                "summary": "Get group roles",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Group Public ID",
-                        "name": "publicId",
-                        "in": "path",
-                        "required": true
-                    }
+                    "auth"
                 ],
+                "summary": "Start two-factor authentication enrollment",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/groups.GroupRoleResponse"
-                            }
+                            "$ref": "#/definitions/auth.MFAEnrollmentResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Two-factor authentication is already enabled",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/auth/mfa/enroll/confirm": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Assigns roles to a group (replaces existing roles)",
+                "description": "Enables two-factor authentication with the first code from the authenticator app. Returns single-use recovery codes that are only shown once.",
                 "consumes": [
                     "application/json"
                 ],
@@ -3062,24 +3193,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "groups"
+                    "auth"
                 ],
-                "summary": "Assign roles to group",
+                "summary": "Confirm two-factor authentication enrollment",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Group Public ID",
-                        "name": "publicId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Role IDs to assign",
+                        "description": "Code from the authenticator app",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/groups.AssignRolesRequest"
+                            "$ref": "#/definitions/auth.MFACodeRequest"
                         }
                     }
                 ],
@@ -3087,38 +3211,44 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/groups.SuccessResponse"
+                            "$ref": "#/definitions/auth.RecoveryCodesResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid request or no pending enrollment",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Invalid verification code",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Two-factor authentication is already enabled",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/groups/{publicId}/roles/{roleId}": {
-            "delete": {
+        "/auth/mfa/recovery-codes": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Removes a role from a group",
+                "description": "Replaces all recovery codes after checking a current code or recovery code. The new codes are only shown once.",
                 "consumes": [
                     "application/json"
                 ],
@@ -3126,55 +3256,51 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "groups"
+                    "auth"
                 ],
-                "summary": "Remove role from group",
+                "summary": "Regenerate recovery codes",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Group Public ID",
-                        "name": "publicId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Role ID",
-                        "name": "roleId",
-                        "in": "path",
-                        "required": true
+                        "description": "Current code or recovery code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.MFACodeRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/groups.SuccessResponse"
+                            "$ref": "#/definitions/auth.RecoveryCodesResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Invalid request or MFA not enabled",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid verification code",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/groups/{publicId}/users": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Retrieves all users in a group",
+        "/auth/mfa/verify": {
+            "post": {
+                "description": "Exchanges the MFA challenge token returned by login for access and refresh tokens, using a code from the authenticator app or a single-use recovery code. Wrong codes count towards the login lockout, and the challenge is invalidated after AUTH_MFA_MAX_ATTEMPTS of them. For enrollment challenges (users whose roles require MFA), the code confirms the secret from /auth/mfa/enroll and the response also contains the new recovery codes.",
                 "consumes": [
                     "application/json"
                 ],
@@ -3182,113 +3308,95 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "groups"
+                    "auth"
                 ],
-                "summary": "Get group users",
+                "summary": "Complete login with a second factor",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Group Public ID",
-                        "name": "publicId",
-                        "in": "path",
-                        "required": true
+                        "description": "MFA challenge token and code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.VerifyMFARequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "integer"
-                            }
+                            "$ref": "#/definitions/auth.LoginResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Invalid request or MFA not set up",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "401": {
+                        "description": "Invalid or expired challenge token, or invalid code",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Login locked or attempted too soon after a failure",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
-                }
-            },
-            "put": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Assigns users to a group (replaces existing users)",
-                "consumes": [
-                    "application/json"
-                ],
+                }
+            }
+        },
+        "/auth/oidc/authorize": {
+            "get": {
+                "description": "Starts a login at the configured OpenID Connect provider (authorization code flow with PKCE). Returns the URL to send the user to and sets an HTTP-only cookie binding the login to this browser; the provider redirects back to the client with a code and state to post to /auth/oidc/callback.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "groups"
-                ],
-                "summary": "Assign users to group",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Group Public ID",
-                        "name": "publicId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "User IDs to assign",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/groups.AssignUsersRequest"
-                        }
-                    }
+                    "auth"
                 ],
+                "summary": "Start single sign-on login",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/groups.SuccessResponse"
+                            "$ref": "#/definitions/auth.OIDCAuthorizationResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "500": {
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "502": {
+                        "description": "Single sign-on provider unavailable",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "503": {
+                        "description": "Single sign-on is not configured",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/groups/{publicId}/users/{userId}": {
-            "delete": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Removes a user from a group",
+        "/auth/oidc/callback": {
+            "post": {
+                "description": "Completes a login started by /auth/oidc/authorize with the code and state the provider redirected back with. The user is matched by the verified email address of the provider (and created if provisioning is enabled), their mapped groups are synchronized, and tokens are returned like for /auth/login. A second factor used at the provider satisfies the MFA requirement.",
                 "consumes": [
                     "application/json"
                 ],
@@ -3296,50 +3404,69 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "groups"
+                    "auth"
                 ],
-                "summary": "Remove user from group",
+                "summary": "Complete single sign-on login",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Group Public ID",
-                        "name": "publicId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "User ID",
-                        "name": "userId",
-                        "in": "path",
-                        "required": true
+                        "description": "Authorization code and state",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.OIDCCallbackRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/groups.SuccessResponse"
+                            "$ref": "#/definitions/auth.LoginResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Invalid request",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Login state missing, expired or mismatched, or the provider rejected the login",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "No account for the provider's user",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Single sign-on provider unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Single sign-on is not configured",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/health": {
-            "get": {
-                "description": "Returns the health status of the service and database connection",
+        "/auth/password/change": {
+            "post": {
+                "description": "Replaces a password older than the policy's max_age_days using the token from login's ` + "`" + `password_change` + "`" + `, then completes the login like /auth/login: tokens are returned, or an MFA challenge if a second factor is needed. Other sessions of the user are logged out.",
                 "consumes": [
                     "application/json"
                 ],
@@ -3347,89 +3474,51 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "health"
-                ],
-                "summary": "Health Check",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    }
-                }
-            }
-        },
-        "/plugins/ews/attachment": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Retrieves the binary content of an email attachment",
-                "produces": [
-                    "application/octet-stream"
-                ],
-                "tags": [
-                    "plugins/ews"
+                    "auth"
                 ],
-                "summary": "Get attachment content",
+                "summary": "Change an expired password",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Email address",
-                        "name": "mailbox",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "EWS Attachment ID",
-                        "name": "attachment_id",
-                        "in": "query",
-                        "required": true
+                        "description": "Password change token and new password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.ChangeExpiredPasswordRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Attachment content",
+                        "description": "OK",
                         "schema": {
-                            "type": "file"
+                            "$ref": "#/definitions/auth.LoginResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid request parameters",
+                        "description": "Invalid request or password policy violations",
                         "schema": {
-                            "$ref": "#/definitions/ews.ErrorResponse"
+                            "$ref": "#/definitions/password.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "401": {
+                        "description": "Invalid or expired password change token",
                         "schema": {
-                            "$ref": "#/definitions/ews.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
-                    "503": {
-                        "description": "EWS service unavailable",
+                    "500": {
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/ews.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/plugins/ews/email": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Retrieve full details of a specific email including body content and conversation thread. Requires authentication.",
+        "/auth/password/forgot": {
+            "post": {
+                "description": "Emails a single-use password reset link if an account with the address exists. The response is the same whether or not it does. Requests are rate limited per address.",
                 "consumes": [
                     "application/json"
                 ],
@@ -3437,73 +3526,57 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "plugins/ews"
+                    "auth"
                 ],
-                "summary": "Get email details by ID",
+                "summary": "Request a password reset email",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Email Item ID (Exchange item ID)",
-                        "name": "item_id",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Email address of the mailbox to access",
-                        "name": "mailbox",
-                        "in": "query",
-                        "required": true
+                        "description": "Account email address",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.ForgotPasswordRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Email details retrieved successfully",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/ews.GetEmailDetailResponse"
+                            "$ref": "#/definitions/auth.SuccessResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid request parameters",
-                        "schema": {
-                            "$ref": "#/definitions/ews.ErrorResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized - Invalid or missing token",
+                        "description": "Invalid request or email format",
                         "schema": {
-                            "$ref": "#/definitions/ews.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Email not found",
+                    "429": {
+                        "description": "Too many requests for this address",
                         "schema": {
-                            "$ref": "#/definitions/ews.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/ews.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "503": {
-                        "description": "EWS service unavailable",
+                        "description": "Email delivery is not configured",
                         "schema": {
-                            "$ref": "#/definitions/ews.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/plugins/ews/emails": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Retrieve a list of emails from a specified Exchange mailbox and folder. Requires authentication.",
+        "/auth/password/reset": {
+            "post": {
+                "description": "Sets a new password using the token from a password reset email. The token can only be used once; it stays valid if the password violates the password policy. All sessions of the user are logged out.",
                 "consumes": [
                     "application/json"
                 ],
@@ -3511,76 +3584,86 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "plugins/ews"
+                    "auth"
                 ],
-                "summary": "List emails from Exchange mailbox",
+                "summary": "Reset password",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Email address of the mailbox to access",
-                        "name": "mailbox",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "default": "inbox",
-                        "description": "Folder name (inbox, sent, drafts, etc.)",
-                        "name": "folder",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 50,
-                        "description": "Number of emails to retrieve (max 100)",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 0,
-                        "description": "Pagination offset",
-                        "name": "offset",
-                        "in": "query"
+                        "description": "Reset token and new password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.ResetPasswordRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Emails retrieved successfully",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/ews.ListEmailsResponse"
+                            "$ref": "#/definitions/auth.SuccessResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid request parameters",
+                        "description": "Invalid request or token, or password policy violations",
                         "schema": {
-                            "$ref": "#/definitions/ews.ErrorResponse"
+                            "$ref": "#/definitions/password.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/refresh": {
+            "post": {
+                "description": "Uses the refresh token from HTTP-only cookie to generate new access and refresh tokens. Implements token rotation for security. The new tokens keep the MFA-verified state of the session. Sessions started without a second factor are ended once the user's roles require MFA. Presenting a refresh token that was already rotated ends its whole session, except within a short grace period after rotation, which is answered with 409 so concurrent refreshes of one client can retry.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Refresh access token",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/auth.TokenResponse"
                         }
                     },
                     "401": {
-                        "description": "Unauthorized - Invalid or missing token",
+                        "description": "Invalid, expired, revoked or reused refresh token",
                         "schema": {
-                            "$ref": "#/definitions/ews.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "409": {
+                        "description": "Refresh token was just rotated by a concurrent request",
                         "schema": {
-                            "$ref": "#/definitions/ews.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
-                    "503": {
-                        "description": "EWS service unavailable",
+                    "500": {
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/ews.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/plugins/ews/health": {
-            "get": {
-                "description": "Check if the Exchange Web Services connection is properly configured and accessible",
+        "/auth/register": {
+            "post": {
+                "description": "Creates a new user account with email, password, and name. The user is automatically added to the 'public' group. The password must meet the password policy; violations are listed per rule.",
                 "consumes": [
                     "application/json"
                 ],
@@ -3588,176 +3671,175 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "plugins/ews"
+                    "auth"
+                ],
+                "summary": "Register a new user",
+                "parameters": [
+                    {
+                        "description": "Registration data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.RegisterRequest"
+                        }
+                    }
                 ],
-                "summary": "Check EWS connection health",
                 "responses": {
-                    "200": {
-                        "description": "EWS connection is healthy",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/ews.HealthResponse"
+                            "$ref": "#/definitions/auth.RegisterResponse"
                         }
                     },
-                    "503": {
-                        "description": "EWS service unavailable",
+                    "400": {
+                        "description": "Invalid request, validation error or password policy violations",
                         "schema": {
-                            "$ref": "#/definitions/ews.ErrorResponse"
+                            "$ref": "#/definitions/password.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Email or login_id already exists",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/roles": {
+        "/auth/sessions": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves a paginated list of roles",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Lists the active sessions (refresh token families) of the current user with the client IP and user agent of their last login or refresh. The session of the refresh token cookie sent with the request is marked as current.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "roles"
-                ],
-                "summary": "List roles",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Items per page",
-                        "name": "limit",
-                        "in": "query"
-                    }
+                    "auth"
                 ],
+                "summary": "List my sessions",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/roles.RoleListResponseWrapper"
+                            "$ref": "#/definitions/auth.SessionListResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/auth/sessions/{id}": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Creates a new role with unique name",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Ends a session of the current user. Its refresh token stops working; access tokens already issued stay valid until they expire.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "roles"
+                    "auth"
                 ],
-                "summary": "Create a new role",
+                "summary": "Revoke one of my sessions",
                 "parameters": [
                     {
-                        "description": "Role data",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/roles.CreateRoleRequest"
-                        }
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/roles.RoleResponse"
+                            "$ref": "#/definitions/auth.SuccessResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
-                    "409": {
-                        "description": "Role name already exists",
+                    "404": {
+                        "description": "Session not found",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/roles/batch": {
-            "put": {
+        "/auth/tokens": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates multiple roles in a single transaction",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Lists the unrevoked personal access tokens of the current user, including expired ones. Secrets are never returned; the prefix identifies a token.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "roles"
-                ],
-                "summary": "Batch update roles",
-                "parameters": [
-                    {
-                        "description": "Batch update request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/roles.BatchUpdateRoleRequest"
-                        }
-                    }
+                    "auth"
                 ],
+                "summary": "List my personal access tokens",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/roles.BatchOperationResponse"
+                            "$ref": "#/definitions/auth.APIKeyListResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "API keys cannot manage API keys",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
@@ -3768,7 +3850,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Creates multiple roles in a single transaction",
+                "description": "Creates a personal access token for scripts and tools. It is sent as ` + "`" + `Authorization: Bearer kcp_...` + "`" + ` instead of an access token and grants only the requested scopes, which must be roles of the current user. The token is only returned once.",
                 "consumes": [
                     "application/json"
                 ],
@@ -3776,17 +3858,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "roles"
+                    "auth"
                 ],
-                "summary": "Batch create roles",
+                "summary": "Create a personal access token",
                 "parameters": [
                     {
-                        "description": "Batch create request",
+                        "description": "Token name, scopes and optional expiry",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/roles.BatchCreateRoleRequest"
+                            "$ref": "#/definitions/auth.CreateAPIKeyRequest"
                         }
                     }
                 ],
@@ -3794,81 +3876,102 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/roles.BatchOperationResponse"
+                            "$ref": "#/definitions/auth.CreateAPIKeyResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Invalid name, scopes or expiry",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
-                    }
-                }
-            },
-            "delete": {
-                "security": [
+                    },
+                    "403": {
+                        "description": "API keys cannot manage API keys",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/tokens/{id}": {
+            "delete": {
+                "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Deletes multiple roles in a single transaction",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Revokes a personal access token of the current user. Requests made with it are rejected immediately.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "roles"
+                    "auth"
                 ],
-                "summary": "Batch delete roles",
+                "summary": "Revoke a personal access token",
                 "parameters": [
                     {
-                        "description": "Batch delete request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/roles.BatchDeleteRoleRequest"
-                        }
+                        "type": "string",
+                        "description": "Token ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/roles.BatchOperationResponse"
+                            "$ref": "#/definitions/auth.SuccessResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "API keys cannot manage API keys",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Token not found",
+                        "schema": {
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/auth.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/roles/search": {
-            "post": {
+        "/common-codes": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Searches for roles based on name or description",
+                "description": "Retrieves a paginated list of common codes ordered by category and sort_order",
                 "consumes": [
                     "application/json"
                 ],
@@ -3876,9 +3979,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "roles"
+                    "common-codes"
                 ],
-                "summary": "Search roles",
+                "summary": "List common codes",
                 "parameters": [
                     {
                         "type": "integer",
@@ -3893,47 +3996,87 @@ const docTemplate = `{
                         "description": "Items per page",
                         "name": "limit",
                         "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/commoncodes.CommonCodeListResponseWrapper"
+                        }
                     },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
                     {
-                        "description": "Search criteria",
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a new common code. Category, code, and name are required. Category + code must be unique.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "common-codes"
+                ],
+                "summary": "Create a new common code",
+                "parameters": [
+                    {
+                        "description": "Common code data",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/roles.SearchRoleRequest"
+                            "$ref": "#/definitions/commoncodes.CreateCommonCodeRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/roles.RoleListResponseWrapper"
+                            "$ref": "#/definitions/commoncodes.CommonCodeResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Category and code combination already exists",
+                        "schema": {
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/roles/{id}": {
-            "get": {
+        "/common-codes/batch": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves a role by its ID",
+                "description": "Updates multiple common codes in a single transaction",
                 "consumes": [
                     "application/json"
                 ],
@@ -3941,46 +4084,48 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "roles"
+                    "common-codes"
                 ],
-                "summary": "Get role by ID",
+                "summary": "Batch update common codes",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Role ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Batch update request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/commoncodes.BatchUpdateRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/roles.RoleResponse"
+                            "$ref": "#/definitions/commoncodes.BatchOperationResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
                         }
                     }
                 }
             },
-            "put": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates an existing role",
+                "description": "Creates multiple common codes in a single transaction",
                 "consumes": [
                     "application/json"
                 ],
@@ -3988,56 +4133,37 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "roles"
+                    "common-codes"
                 ],
-                "summary": "Update role",
+                "summary": "Batch create common codes",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Role ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Role data to update",
+                        "description": "Batch create request",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/roles.UpdateRoleRequest"
+                            "$ref": "#/definitions/commoncodes.BatchCreateRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/roles.RoleResponse"
+                            "$ref": "#/definitions/commoncodes.BatchOperationResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
-                        }
-                    },
-                    "409": {
-                        "description": "Role name already exists",
-                        "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
                         }
                     }
                 }
@@ -4048,7 +4174,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Deletes a role by ID",
+                "description": "Deletes multiple common codes in a single transaction",
                 "consumes": [
                     "application/json"
                 ],
@@ -4056,48 +4182,50 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "roles"
+                    "common-codes"
                 ],
-                "summary": "Delete role",
+                "summary": "Batch delete common codes",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Role ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Batch delete request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/commoncodes.BatchDeleteRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/roles.SuccessResponse"
+                            "$ref": "#/definitions/commoncodes.BatchOperationResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/roles/{id}/users": {
+        "/common-codes/categories": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves all user IDs that have a specific role",
+                "description": "Retrieves all unique categories from common codes",
                 "consumes": [
                     "application/json"
                 ],
@@ -4105,45 +4233,36 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "roles"
-                ],
-                "summary": "Get users with role",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Role ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "common-codes"
                 ],
+                "summary": "List all categories",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "type": "integer"
+                                "$ref": "#/definitions/commoncodes.CategoryResponse"
                             }
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/tags": {
+        "/common-codes/categories/{category}": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves a paginated list of tags",
+                "description": "Retrieves all common codes in a specific category, ordered by sort_order",
                 "consumes": [
                     "application/json"
                 ],
@@ -4151,47 +4270,103 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tags"
+                    "common-codes"
                 ],
-                "summary": "List tags",
+                "summary": "Get codes by category",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
+                        "type": "string",
+                        "description": "Category name",
+                        "name": "category",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/commoncodes.CommonCodeResponse"
+                            }
+                        }
                     },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/common-codes/categories/{category}/reorder": {
+            "put": {
+                "security": [
                     {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Items per page",
-                        "name": "limit",
-                        "in": "query"
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates the sort_order of multiple codes in a category",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "common-codes"
+                ],
+                "summary": "Reorder codes in a category",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Category name",
+                        "name": "category",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reorder request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/commoncodes.ReorderRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/tickets.TagListResponseWrapper"
+                            "$ref": "#/definitions/commoncodes.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/common-codes/search": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Creates a new tag",
+                "description": "Searches for common codes based on category, code, name, or description",
                 "consumes": [
                     "application/json"
                 ],
@@ -4199,50 +4374,64 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tags"
+                    "common-codes"
                 ],
-                "summary": "Create a new tag",
+                "summary": "Search common codes",
                 "parameters": [
                     {
-                        "description": "Tag data",
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Search criteria",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/tickets.CreateTagRequest"
+                            "$ref": "#/definitions/commoncodes.SearchCommonCodeRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/tickets.TagResponse"
+                            "$ref": "#/definitions/commoncodes.CommonCodeListResponseWrapper"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/tags/{id}": {
+        "/common-codes/{id}": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves a tag by its ID",
+                "description": "Retrieves a common code by its ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -4250,13 +4439,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tags"
+                    "common-codes"
                 ],
-                "summary": "Get tag by ID",
+                "summary": "Get common code by ID",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Tag ID",
+                        "description": "Common Code ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -4266,19 +4455,19 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/tickets.TagResponse"
+                            "$ref": "#/definitions/commoncodes.CommonCodeResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
                         }
                     }
                 }
@@ -4289,7 +4478,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates an existing tag",
+                "description": "Updates an existing common code. Category + code must be unique.",
                 "consumes": [
                     "application/json"
                 ],
@@ -4297,24 +4486,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tags"
+                    "common-codes"
                 ],
-                "summary": "Update tag",
+                "summary": "Update common code",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Tag ID",
+                        "description": "Common Code ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Tag data to update",
+                        "description": "Common code data to update",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/tickets.UpdateTagRequest"
+                            "$ref": "#/definitions/commoncodes.UpdateCommonCodeRequest"
                         }
                     }
                 ],
@@ -4322,25 +4511,31 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/tickets.TagResponse"
+                            "$ref": "#/definitions/commoncodes.CommonCodeResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Category and code combination already exists",
+                        "schema": {
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
                         }
                     }
                 }
@@ -4351,7 +4546,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Performs a soft delete on a tag",
+                "description": "Deletes a common code by ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -4359,13 +4554,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tags"
+                    "common-codes"
                 ],
-                "summary": "Delete tag",
+                "summary": "Delete common code",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Tag ID",
+                        "description": "Common Code ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -4375,32 +4570,32 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/tickets.SuccessResponse"
+                            "$ref": "#/definitions/commoncodes.SuccessResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/commoncodes.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/tickets": {
+        "/departments": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves a paginated list of tickets with simplified response",
+                "description": "Retrieves a paginated list of departments",
                 "consumes": [
                     "application/json"
                 ],
@@ -4408,9 +4603,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tickets"
+                    "departments"
                 ],
-                "summary": "List tickets",
+                "summary": "List departments",
                 "parameters": [
                     {
                         "type": "integer",
@@ -4431,13 +4626,13 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/tickets.TicketListResponseWrapper"
+                            "$ref": "#/definitions/departments.DepartmentListResponseWrapper"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     }
                 }
@@ -4448,7 +4643,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Creates a new ticket with an initial entry. Title and initial_entry are required.",
+                "description": "Creates a new department with JSONB name and description",
                 "consumes": [
                     "application/json"
                 ],
@@ -4456,17 +4651,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tickets"
+                    "departments"
                 ],
-                "summary": "Create a new ticket",
+                "summary": "Create a new department",
                 "parameters": [
                     {
-                        "description": "Ticket data with initial entry",
+                        "description": "Department data",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/tickets.CreateTicketRequest"
+                            "$ref": "#/definitions/departments.CreateDepartmentRequest"
                         }
                     }
                 ],
@@ -4474,32 +4669,32 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/tickets.TicketDetailResponse"
+                            "$ref": "#/definitions/departments.DepartmentResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/tickets/search": {
-            "post": {
+        "/departments/batch": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Searches for tickets based on various criteria",
+                "description": "Updates multiple departments in a single transaction",
                 "consumes": [
                     "application/json"
                 ],
@@ -4507,31 +4702,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tickets"
+                    "departments"
                 ],
-                "summary": "Search tickets",
+                "summary": "Batch update departments",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Items per page",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "description": "Search criteria",
+                        "description": "Batch update request",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/tickets.SearchTicketRequest"
+                            "$ref": "#/definitions/departments.BatchUpdateDepartmentRequest"
                         }
                     }
                 ],
@@ -4539,32 +4720,30 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/tickets.TicketListResponseWrapper"
+                            "$ref": "#/definitions/departments.BatchOperationResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/tickets/{id}": {
-            "get": {
+            },
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves detailed ticket information including entries and tags",
+                "description": "Creates multiple departments in a single transaction",
                 "consumes": [
                     "application/json"
                 ],
@@ -4572,46 +4751,48 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tickets"
+                    "departments"
                 ],
-                "summary": "Get ticket by ID",
+                "summary": "Batch create departments",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Ticket Public ID (UUID)",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Batch create request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/departments.BatchCreateDepartmentRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/tickets.TicketDetailResponse"
+                            "$ref": "#/definitions/departments.BatchOperationResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     }
                 }
             },
-            "put": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates an existing ticket",
+                "description": "Soft-deletes multiple departments in a single transaction",
                 "consumes": [
                     "application/json"
                 ],
@@ -4619,24 +4800,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tickets"
+                    "departments"
                 ],
-                "summary": "Update ticket",
+                "summary": "Batch delete departments",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Ticket Public ID (UUID)",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Ticket data to update",
+                        "description": "Batch delete request",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/tickets.UpdateTicketRequest"
+                            "$ref": "#/definitions/departments.BatchDeleteDepartmentRequest"
                         }
                     }
                 ],
@@ -4644,36 +4818,32 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/tickets.TicketListResponse"
+                            "$ref": "#/definitions/departments.BatchOperationResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/departments/search": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Deletes a ticket and all its entries",
+                "description": "Searches for departments based on name or description",
                 "consumes": [
                     "application/json"
                 ],
@@ -4681,48 +4851,64 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tickets"
+                    "departments"
                 ],
-                "summary": "Delete ticket",
+                "summary": "Search departments",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Ticket Public ID (UUID)",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Search criteria",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/departments.SearchDepartmentRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/tickets.SuccessResponse"
+                            "$ref": "#/definitions/departments.DepartmentListResponseWrapper"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/tickets/{id}/entries": {
-            "post": {
+        "/departments/tree": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Creates a new entry for a ticket",
+                "description": "Retrieves the full department tree structure",
                 "consumes": [
                     "application/json"
                 ],
@@ -4730,63 +4916,83 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "entries"
+                    "departments"
                 ],
-                "summary": "Create a new entry",
+                "summary": "Get department tree",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/departments.DepartmentTreeResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/departments.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/departments/{publicId}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves a department by its public ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "departments"
+                ],
+                "summary": "Get department by public ID",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Ticket Public ID (UUID)",
-                        "name": "id",
+                        "description": "Department Public ID",
+                        "name": "publicId",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Entry data",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/tickets.CreateEntryRequest"
-                        }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/tickets.EntryDetailResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/departments.DepartmentResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/tickets/{id}/tags": {
-            "post": {
+            },
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Adds tags to a ticket",
+                "description": "Updates an existing department",
                 "consumes": [
                     "application/json"
                 ],
@@ -4794,24 +5000,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tickets"
+                    "departments"
                 ],
-                "summary": "Add tags to ticket",
+                "summary": "Update department",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Ticket Public ID (UUID)",
-                        "name": "id",
+                        "description": "Department Public ID",
+                        "name": "publicId",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Tags to add",
+                        "description": "Department data to update",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/tickets.AddTagRequest"
+                            "$ref": "#/definitions/departments.UpdateDepartmentRequest"
                         }
                     }
                 ],
@@ -4819,38 +5025,36 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/tickets.SuccessResponse"
+                            "$ref": "#/definitions/departments.DepartmentResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/tickets/{id}/tags/{tagId}": {
+            },
             "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Removes a tag from a ticket",
+                "description": "Soft-deletes a department by public ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -4858,21 +5062,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tickets"
+                    "departments"
                 ],
-                "summary": "Remove tag from ticket",
+                "summary": "Delete department",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Ticket Public ID (UUID)",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Tag ID",
-                        "name": "tagId",
+                        "description": "Department Public ID",
+                        "name": "publicId",
                         "in": "path",
                         "required": true
                     }
@@ -4881,32 +5078,38 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/tickets.SuccessResponse"
+                            "$ref": "#/definitions/departments.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/tickets.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/users": {
+        "/departments/{publicId}/children": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves a paginated list of users with simplified response",
+                "description": "Retrieves direct children of a department",
                 "consumes": [
                     "application/json"
                 ],
@@ -4914,47 +5117,51 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "departments"
                 ],
-                "summary": "List users",
+                "summary": "Get department children",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Items per page",
-                        "name": "limit",
-                        "in": "query"
+                        "type": "string",
+                        "description": "Department Public ID",
+                        "name": "publicId",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/users.UserListResponseWrapper"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/departments.DepartmentResponse"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/users.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/departments/{publicId}/move": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Creates a new user with the provided data. Email and name are required. If login_id is not provided, email is used as login_id.",
+                "description": "Moves a department to a new parent",
                 "consumes": [
                     "application/json"
                 ],
@@ -4962,56 +5169,63 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "departments"
                 ],
-                "summary": "Create a new user",
+                "summary": "Move department",
                 "parameters": [
                     {
-                        "description": "User data",
+                        "type": "string",
+                        "description": "Department Public ID",
+                        "name": "publicId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Move request",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/users.CreateUserRequest"
+                            "$ref": "#/definitions/departments.MoveDepartmentRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/users.UserListResponse"
+                            "$ref": "#/definitions/departments.SuccessResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/users.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     },
-                    "409": {
-                        "description": "Email or login_id already exists",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/users.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/users.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/users/search": {
-            "post": {
+        "/departments/{publicId}/tree": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Searches for users based on various criteria (name, email, mobile number, office number)",
+                "description": "Retrieves a subtree starting from a specific department",
                 "consumes": [
                     "application/json"
                 ],
@@ -5019,64 +5233,48 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "departments"
                 ],
-                "summary": "Search users",
+                "summary": "Get department subtree",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Items per page",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "description": "Search criteria",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/users.SearchUserRequest"
-                        }
+                        "type": "string",
+                        "description": "Department Public ID",
+                        "name": "publicId",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/users.UserListResponseWrapper"
+                            "$ref": "#/definitions/departments.DepartmentTreeResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/users.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/users.ErrorResponse"
+                            "$ref": "#/definitions/departments.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/users/{id}": {
+        "/entries/{id}": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves detailed user information by their public ID",
+                "description": "Retrieves detailed entry information including tags and references. Entries of tickets not visible to the current user (see GET /tickets) are not found.",
                 "consumes": [
                     "application/json"
                 ],
@@ -5084,13 +5282,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "entries"
                 ],
-                "summary": "Get user by ID",
+                "summary": "Get entry by ID",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User Public ID (UUID)",
+                        "type": "integer",
+                        "description": "Entry ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -5100,19 +5298,19 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/users.UserDetailResponse"
+                            "$ref": "#/definitions/tickets.EntryDetailResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/users.ErrorResponse"
+                            "$ref": "#/definitions/tickets.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/users.ErrorResponse"
+                            "$ref": "#/definitions/tickets.ErrorResponse"
                         }
                     }
                 }
@@ -5123,7 +5321,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates an existing user with the provided data",
+                "description": "Updates an existing entry",
                 "consumes": [
                     "application/json"
                 ],
@@ -5131,24 +5329,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "entries"
                 ],
-                "summary": "Update user",
+                "summary": "Update entry",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User Public ID (UUID)",
+                        "type": "integer",
+                        "description": "Entry ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "User data to update",
+                        "description": "Entry data to update",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/users.UpdateUserRequest"
+                            "$ref": "#/definitions/tickets.UpdateEntryRequest"
                         }
                     }
                 ],
@@ -5156,31 +5354,25 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/users.UserListResponse"
+                            "$ref": "#/definitions/tickets.EntryListResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/users.ErrorResponse"
+                            "$ref": "#/definitions/tickets.ErrorResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/users.ErrorResponse"
-                        }
-                    },
-                    "409": {
-                        "description": "Email or login_id already exists",
-                        "schema": {
-                            "$ref": "#/definitions/users.ErrorResponse"
+                            "$ref": "#/definitions/tickets.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/users.ErrorResponse"
+                            "$ref": "#/definitions/tickets.ErrorResponse"
                         }
                     }
                 }
@@ -5191,7 +5383,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Performs a soft delete on a user (sets is_deleted flag to true)",
+                "description": "Performs a soft delete on an entry",
                 "consumes": [
                     "application/json"
                 ],
@@ -5199,13 +5391,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "entries"
                 ],
-                "summary": "Delete user",
+                "summary": "Delete entry",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User Public ID (UUID)",
+                        "type": "integer",
+                        "description": "Entry ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -5215,32 +5407,32 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/users.SuccessResponse"
+                            "$ref": "#/definitions/tickets.SuccessResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/users.ErrorResponse"
+                            "$ref": "#/definitions/tickets.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/users.ErrorResponse"
+                            "$ref": "#/definitions/tickets.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/users/{userId}/groups": {
-            "get": {
+        "/entries/{id}/tags": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves all groups a user belongs to",
+                "description": "Adds tags to an entry",
                 "consumes": [
                     "application/json"
                 ],
@@ -5248,45 +5440,63 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "groups"
+                    "entries"
                 ],
-                "summary": "Get user groups",
+                "summary": "Add tags to entry",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "User ID",
-                        "name": "userId",
+                        "description": "Entry ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Tags to add",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tickets.AddTagRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/groups.GroupResponse"
-                            }
+                            "$ref": "#/definitions/tickets.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/groups.ErrorResponse"
+                            "$ref": "#/definitions/tickets.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/users/{userId}/roles": {
-            "get": {
+        "/entries/{id}/tags/{tagId}": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves all roles assigned to a user",
+                "description": "Removes a tag from an entry",
                 "consumes": [
                     "application/json"
                 ],
@@ -5294,14 +5504,21 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "roles"
+                    "entries"
                 ],
-                "summary": "Get user roles",
+                "summary": "Remove tag from entry",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "User ID",
-                        "name": "userId",
+                        "description": "Entry ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Tag ID",
+                        "name": "tagId",
                         "in": "path",
                         "required": true
                     }
@@ -5310,27 +5527,32 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/roles.UserRoleResponse"
-                            }
+                            "$ref": "#/definitions/tickets.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/tickets.ErrorResponse"
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/files": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Assigns roles to a user (replaces existing roles)",
+                "description": "Retrieves a paginated list of files uploaded by the current user",
                 "consumes": [
                     "application/json"
                 ],
@@ -5338,464 +5560,6333 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "roles"
+                    "files"
                 ],
-                "summary": "Assign roles to user",
+                "summary": "List my uploaded files",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "User ID",
-                        "name": "userId",
-                        "in": "path",
-                        "required": true
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
                     },
                     {
-                        "description": "Role IDs to assign",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/roles.AssignUserRolesRequest"
-                        }
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/roles.SuccessResponse"
+                            "$ref": "#/definitions/files.FileListResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/users/{userId}/roles/{roleId}": {
-            "delete": {
+            },
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Removes a specific role from a user",
+                "description": "Uploads a file to the default storage backend. Maximum file size is 100MB.",
                 "consumes": [
-                    "application/json"
+                    "multipart/form-data"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "roles"
+                    "files"
                 ],
-                "summary": "Remove role from user",
+                "summary": "Upload a file",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "User ID",
-                        "name": "userId",
-                        "in": "path",
+                        "type": "file",
+                        "description": "File to upload",
+                        "name": "file",
+                        "in": "formData",
                         "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "Role ID",
-                        "name": "roleId",
-                        "in": "path",
-                        "required": true
+                        "type": "string",
+                        "description": "Optional metadata JSON object",
+                        "name": "metadata",
+                        "in": "formData"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/roles.SuccessResponse"
+                            "$ref": "#/definitions/files.FileUploadResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "File too large or storage quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/roles.ErrorResponse"
+                            "$ref": "#/definitions/files.ErrorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "aiqueue.KeywordsRequest": {
-            "type": "object",
-            "required": [
-                "text"
-            ],
-            "properties": {
-                "max_keywords": {
-                    "type": "integer"
-                },
-                "text": {
-                    "type": "string"
-                }
-            }
-        },
-        "aiqueue.KeywordsResponse": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string"
-                },
-                "task_id": {
-                    "type": "string"
-                }
-            }
         },
-        "aiqueue.NormalizeRequest": {
-            "type": "object",
-            "required": [
-                "request",
-                "schema"
-            ],
-            "properties": {
-                "request": {
-                    "type": "string"
-                },
-                "schema": {
-                    "type": "object",
-                    "additionalProperties": true
+        "/files/archive": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Streams a ZIP archive of the given files. Duplicate file names are numbered, e.g. \"report (2).pdf\". Files that do not exist, that the caller may not read or that are blocked by malware scanning are left out; their count is returned in the X-Archive-Skipped header.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/zip"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Download files as a ZIP archive",
+                "parameters": [
+                    {
+                        "description": "Files to archive",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/files.CreateArchiveRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Empty or too long file list",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "None of the files can be downloaded",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "aiqueue.NormalizeResponse": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string"
-                },
-                "task_id": {
-                    "type": "string"
+        "/files/shared-with-me": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves a paginated list of files other users shared with the current user, directly or through one of their groups or their department",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "List files shared with me",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/files.FileListResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "aiqueue.SummarizeRequest": {
-            "type": "object",
-            "required": [
-                "text"
-            ],
-            "properties": {
-                "max_length": {
-                    "type": "integer"
-                },
-                "text": {
-                    "type": "string"
+        "/files/usage": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the storage used by the current user and the quotas that apply to them (user, department and group quotas). Uploads are rejected when any of these quotas would be exceeded.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Get my storage usage",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/files.StorageUsageResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "aiqueue.SummarizeResponse": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string"
-                },
-                "task_id": {
-                    "type": "string"
+        "/files/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves detailed information about a file without downloading it. Requires read access: the uploader, users the file is shared with (directly, through a group or a department), and readers of a ticket the file is attached to. Public files are readable by every user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Get file information",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/files.FileResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "File not found or not readable",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Performs a soft delete on a file. Requires write access: the file uploader or users the file is shared with at the WRITE level. The content is purged after the retention grace period. Files under legal hold cannot be deleted.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Delete a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/files.SuccessResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - read-only access",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "File not found or not readable",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "File under legal hold",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files/{id}/download": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Downloads the file content. Increments the download counter. Requires read access to the file (see GET /files/{id}). When malware scanning is enabled, files can only be downloaded once they have been scanned clean.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Download a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "File quarantined",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "File not found or not readable",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "File not scanned yet",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files/{id}/metadata": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates the metadata of a file. Requires write access: the file uploader or users the file is shared with at the WRITE level.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Update file metadata",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Metadata to update",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/files.UpdateFileMetadataRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/files.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - read-only access",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "File not found or not readable",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files/{id}/scan": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Scans an existing file again with the configured malware scanner and records the result. Infected files are quarantined; quarantined files that are now clean are released.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Rescan a file for malware",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/files.ScanResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Scanner failed",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Scanner not configured",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files/{id}/share-links": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists all share links (including expired and revoked ones) created for a file. Only the file uploader can list share links.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "List share links of a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/files.ShareLinkListResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - not the file owner",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates an HMAC-signed download link that can be used without authentication. Links expire (default 1 day, max 30 days) and may be limited by download count and protected by a password. Only the file uploader can create share links.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Create a share link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Share link options",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/files.CreateShareLinkRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/files.ShareLinkResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - not the file owner, or impersonating",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files/{id}/share-links/{linkId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revokes a share link so it can no longer be used. Only the file uploader can revoke share links.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Revoke a share link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Share Link Public ID (UUID)",
+                        "name": "linkId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/files.SuccessResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - not the file owner",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files/{id}/shares": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the users, groups and departments a file is shared with. Only the file uploader can list shares.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "List the shares of a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/files.FileShareListResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - not the file owner",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Grants a user, or every member of a group or department, READ (download and view) or WRITE (also update metadata) access to a file. Sharing again with the same grantee replaces its access level. Only the file uploader can share a file.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Share a file with a user, group or department",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Grantee and access level",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/files.ShareFileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/files.FileShareResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - not the file owner, or impersonating",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files/{id}/shares/{shareId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes the access a user, group or department was granted on a file. Only the file uploader can revoke shares.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Revoke a file share",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File Share Public ID (UUID)",
+                        "name": "shareId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/files.SuccessResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - not the file owner",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files/{id}/thumbnail": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns a JPEG thumbnail of an image (or the first page of a PDF, if enabled). Thumbnails are generated in the background after upload, so they may not be available immediately.",
+                "produces": [
+                    "image/jpeg"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Get a file thumbnail",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "small",
+                            "medium",
+                            "large"
+                        ],
+                        "type": "string",
+                        "default": "medium",
+                        "description": "Thumbnail size",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid thumbnail size",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "File quarantined",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "File or thumbnail not found, or file not readable",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "File not scanned yet",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/groups": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves a paginated list of groups",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "List groups",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/groups.GroupListResponseWrapper"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a new group with JSONB name and description",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Create a new group",
+                "parameters": [
+                    {
+                        "description": "Group data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/groups.CreateGroupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/groups.GroupResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/groups/batch": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates multiple groups in a single transaction",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Batch update groups",
+                "parameters": [
+                    {
+                        "description": "Batch update request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/groups.BatchUpdateGroupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/groups.BatchOperationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates multiple groups in a single transaction",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Batch create groups",
+                "parameters": [
+                    {
+                        "description": "Batch create request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/groups.BatchCreateGroupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/groups.BatchOperationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes multiple groups in a single transaction",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Batch delete groups",
+                "parameters": [
+                    {
+                        "description": "Batch delete request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/groups.BatchDeleteGroupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/groups.BatchOperationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/groups/search": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Searches for groups based on name or description",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Search groups",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Search criteria",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/groups.SearchGroupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/groups.GroupListResponseWrapper"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/groups/{publicId}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves a group by its public ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Get group by public ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group Public ID",
+                        "name": "publicId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/groups.GroupResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates an existing group",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Update group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group Public ID",
+                        "name": "publicId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Group data to update",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/groups.UpdateGroupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/groups.GroupResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes a group by public ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Delete group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group Public ID",
+                        "name": "publicId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/groups.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/groups/{publicId}/roles": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves all roles assigned to a group",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Get group roles",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group Public ID",
+                        "name": "publicId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/groups.GroupRoleResponse"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Assigns roles to a group (replaces existing roles)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Assign roles to group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group Public ID",
+                        "name": "publicId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Role IDs to assign",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/groups.AssignRolesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/groups.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/groups/{publicId}/roles/{roleId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes a role from a group",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Remove role from group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group Public ID",
+                        "name": "publicId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Role ID",
+                        "name": "roleId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/groups.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/groups/{publicId}/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves all users in a group",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Get group users",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group Public ID",
+                        "name": "publicId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Assigns users to a group (replaces existing users)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Assign users to group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group Public ID",
+                        "name": "publicId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "User IDs to assign",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/groups.AssignUsersRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/groups.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/groups/{publicId}/users/{userId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes a user from a group",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Remove user from group",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group Public ID",
+                        "name": "publicId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/groups.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Returns the health status of the service and database connection, and the version of the loaded permission rules",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Health Check",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/plugins/ews/attachment": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves the binary content of an email attachment",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "plugins/ews"
+                ],
+                "summary": "Get attachment content",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email address",
+                        "name": "mailbox",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "EWS Attachment ID",
+                        "name": "attachment_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Attachment content",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/ews.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ews.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "EWS service unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ews.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/plugins/ews/email": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve full details of a specific email including body content and conversation thread. Requires authentication.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "plugins/ews"
+                ],
+                "summary": "Get email details by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email Item ID (Exchange item ID)",
+                        "name": "item_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Email address of the mailbox to access",
+                        "name": "mailbox",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Email details retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/ews.GetEmailDetailResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/ews.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - Invalid or missing token",
+                        "schema": {
+                            "$ref": "#/definitions/ews.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Email not found",
+                        "schema": {
+                            "$ref": "#/definitions/ews.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ews.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "EWS service unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ews.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/plugins/ews/emails": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a list of emails from a specified Exchange mailbox and folder. Requires authentication.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "plugins/ews"
+                ],
+                "summary": "List emails from Exchange mailbox",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email address of the mailbox to access",
+                        "name": "mailbox",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "inbox",
+                        "description": "Folder name (inbox, sent, drafts, etc.)",
+                        "name": "folder",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Number of emails to retrieve (max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Pagination offset",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Emails retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/ews.ListEmailsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/ews.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - Invalid or missing token",
+                        "schema": {
+                            "$ref": "#/definitions/ews.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ews.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "EWS service unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ews.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/plugins/ews/health": {
+            "get": {
+                "description": "Check if the Exchange Web Services connection is properly configured and accessible",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "plugins/ews"
+                ],
+                "summary": "Check EWS connection health",
+                "responses": {
+                    "200": {
+                        "description": "EWS connection is healthy",
+                        "schema": {
+                            "$ref": "#/definitions/ews.HealthResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "EWS service unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ews.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/public/files/shared/{linkId}": {
+            "get": {
+                "description": "Downloads a file using a signed share link. No authentication is required. Password-protected links accept the password in the X-Share-Password header or, with POST, in the JSON body. Repeated wrong passwords lock the link for a while; the Retry-After header tells when to try again. Each successful download counts against the link's download limit.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Download a file through a share link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Share Link Public ID (UUID)",
+                        "name": "linkId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Expiry timestamp (Unix seconds)",
+                        "name": "expires",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "HMAC signature",
+                        "name": "signature",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Share link password",
+                        "name": "X-Share-Password",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Share link password (POST only)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/files.SharedFileDownloadRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "Password required or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Invalid signature or file quarantined",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "File not scanned yet",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "Link expired, revoked or exhausted",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Link locked after too many wrong passwords",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Downloads a file using a signed share link. No authentication is required. Password-protected links accept the password in the X-Share-Password header or, with POST, in the JSON body. Repeated wrong passwords lock the link for a while; the Retry-After header tells when to try again. Each successful download counts against the link's download limit.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Download a file through a share link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Share Link Public ID (UUID)",
+                        "name": "linkId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Expiry timestamp (Unix seconds)",
+                        "name": "expires",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "HMAC signature",
+                        "name": "signature",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Share link password",
+                        "name": "X-Share-Password",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Share link password (POST only)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/files.SharedFileDownloadRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "Password required or invalid",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Invalid signature or file quarantined",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "File not scanned yet",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "Link expired, revoked or exhausted",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Link locked after too many wrong passwords",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/public/files/{id}/download": {
+            "get": {
+                "description": "Downloads a file marked as public without authentication. Private files are reported as not found.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Download a public file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "File quarantined",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "File not scanned yet",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/files.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/roles": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves a paginated list of roles",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "roles"
+                ],
+                "summary": "List roles",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/roles.RoleListResponseWrapper"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a new role with unique name",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "roles"
+                ],
+                "summary": "Create a new role",
+                "parameters": [
+                    {
+                        "description": "Role data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/roles.CreateRoleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/roles.RoleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Role name already exists",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/roles/batch": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates multiple roles in a single transaction",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "roles"
+                ],
+                "summary": "Batch update roles",
+                "parameters": [
+                    {
+                        "description": "Batch update request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/roles.BatchUpdateRoleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/roles.BatchOperationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates multiple roles in a single transaction",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "roles"
+                ],
+                "summary": "Batch create roles",
+                "parameters": [
+                    {
+                        "description": "Batch create request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/roles.BatchCreateRoleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/roles.BatchOperationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes multiple roles in a single transaction",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "roles"
+                ],
+                "summary": "Batch delete roles",
+                "parameters": [
+                    {
+                        "description": "Batch delete request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/roles.BatchDeleteRoleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/roles.BatchOperationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/roles/search": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Searches for roles based on name or description",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "roles"
+                ],
+                "summary": "Search roles",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Search criteria",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/roles.SearchRoleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/roles.RoleListResponseWrapper"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/roles/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves a role by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "roles"
+                ],
+                "summary": "Get role by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Role ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/roles.RoleResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates an existing role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "roles"
+                ],
+                "summary": "Update role",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Role ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Role data to update",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/roles.UpdateRoleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/roles.RoleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Role name already exists",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes a role by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "roles"
+                ],
+                "summary": "Delete role",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Role ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/roles.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/roles/{id}/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves all user IDs that have a specific role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "roles"
+                ],
+                "summary": "Get users with role",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Role ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tags": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves a paginated list of tags",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "List tags",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.TagListResponseWrapper"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a new tag. Confidential tags hide tickets from users without the tickets:read:confidential permission, except their requester and assignee.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Create a new tag",
+                "parameters": [
+                    {
+                        "description": "Tag data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tickets.CreateTagRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.TagResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tags/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves a tag by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Get tag by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Tag ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.TagResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates an existing tag. Changing is_confidential requires the tickets:read:confidential permission.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Update tag",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Tag ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Tag data to update",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tickets.UpdateTagRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.TagResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Changing is_confidential without tickets:read:confidential",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Performs a soft delete on a tag. Deleting a confidential tag requires the tickets:read:confidential permission.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Delete tag",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Tag ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.SuccessResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Confidential tag without tickets:read:confidential",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves a paginated list of the tickets visible to the current user with simplified response. Users see the tickets they requested or are assigned to, the tickets of the departments they lead, and with the tickets:read:department or tickets:read:all permission those of their department or all tickets. Tickets with a confidential tag in this scope require tickets:read:confidential.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "List tickets",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.TicketListResponseWrapper"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a new ticket with an initial entry. Title and initial_entry are required. The current user becomes the requester and the ticket belongs to their department. Confidential tags require the tickets:read:confidential permission.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Create a new ticket",
+                "parameters": [
+                    {
+                        "description": "Ticket data with initial entry",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tickets.CreateTicketRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.TicketDetailResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Confidential tag without tickets:read:confidential",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets/search": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Searches the tickets visible to the current user (see GET /tickets) based on various criteria",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Search tickets",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Search criteria",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tickets.SearchTicketRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.TicketListResponseWrapper"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves detailed ticket information including entries and tags. Tickets not visible to the current user (see GET /tickets) are not found.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Get ticket by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Ticket Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.TicketDetailResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates an existing ticket",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Update ticket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Ticket Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Ticket data to update",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tickets.UpdateTicketRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.TicketListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes a ticket and all its entries",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Delete ticket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Ticket Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets/{id}/attachments.zip": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Streams a ZIP archive of the files attached to the ticket through FILE entries. Duplicate file names are numbered, e.g. \"report (2).pdf\". Files that no longer exist or are blocked by malware scanning are left out; their count is returned in the X-Archive-Skipped header. Tickets not visible to the current user (see GET /tickets) are not found.",
+                "produces": [
+                    "application/zip"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Download ticket attachments as a ZIP archive",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Ticket Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Ticket not found or no downloadable attachments",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets/{id}/entries": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a new entry for a ticket",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "entries"
+                ],
+                "summary": "Create a new entry",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Ticket Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Entry data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tickets.CreateEntryRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.EntryDetailResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets/{id}/tags": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Adds tags to a ticket. Adding a confidential tag requires the tickets:read:confidential permission.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Add tags to ticket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Ticket Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Tags to add",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tickets.AddTagRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Confidential tag without tickets:read:confidential",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets/{id}/tags/{tagId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes a tag from a ticket. Removing a confidential tag requires the tickets:read:confidential permission.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Remove tag from ticket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Ticket Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Tag ID",
+                        "name": "tagId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.SuccessResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Confidential tag without tickets:read:confidential",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/tickets.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves a paginated list of users with simplified response",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "List users",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/users.UserListResponseWrapper"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/users.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a new user with the provided data. Email and name are required. If login_id is not provided, email is used as login_id. A password must meet the password policy; violations are listed per rule. Instead of a password, a bcrypt, PBKDF2 or Argon2id password_hash exported from another system can be imported; it is replaced with an Argon2id hash on the user's first login.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Create a new user",
+                "parameters": [
+                    {
+                        "description": "User data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/users.CreateUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/users.UserListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or password policy violations",
+                        "schema": {
+                            "$ref": "#/definitions/password.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Email or login_id already exists",
+                        "schema": {
+                            "$ref": "#/definitions/users.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/users.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/search": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Searches for users based on various criteria (name, email, mobile number, office number)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Search users",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Search criteria",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/users.SearchUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/users.UserListResponseWrapper"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/users.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/users.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves detailed user information by their public ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/users.UserDetailResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/users.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/users.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates an existing user with the provided data. A new password must meet the password policy, may be given as an imported password_hash as on creation, and cannot be set while impersonating a user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "User data to update",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/users.UpdateUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/users.UserListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or password policy violations",
+                        "schema": {
+                            "$ref": "#/definitions/password.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Password change while impersonating",
+                        "schema": {
+                            "$ref": "#/definitions/users.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/users.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Email or login_id already exists",
+                        "schema": {
+                            "$ref": "#/definitions/users.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/users.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Performs a soft delete on a user (sets is_deleted flag to true)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Delete user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User Public ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/users.SuccessResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/users.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/users.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userId}/groups": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves all groups a user belongs to",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "groups"
+                ],
+                "summary": "Get user groups",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/groups.GroupResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/groups.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userId}/roles": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves all roles assigned to a user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "roles"
+                ],
+                "summary": "Get user roles",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/roles.UserRoleResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Assigns roles to a user (replaces existing roles)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "roles"
+                ],
+                "summary": "Assign roles to user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Role IDs to assign",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/roles.AssignUserRolesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/roles.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - impersonating",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userId}/roles/{roleId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes a specific role from a user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "roles"
+                ],
+                "summary": "Remove role from user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Role ID",
+                        "name": "roleId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/roles.SuccessResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - impersonating",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/roles.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "aiqueue.KeywordsRequest": {
+            "type": "object",
+            "required": [
+                "text"
+            ],
+            "properties": {
+                "max_keywords": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "aiqueue.KeywordsResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "task_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "aiqueue.NormalizeRequest": {
+            "type": "object",
+            "required": [
+                "request",
+                "schema"
+            ],
+            "properties": {
+                "request": {
+                    "type": "string"
+                },
+                "schema": {
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            }
+        },
+        "aiqueue.NormalizeResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "task_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "aiqueue.SummarizeRequest": {
+            "type": "object",
+            "required": [
+                "text"
+            ],
+            "properties": {
+                "max_length": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "aiqueue.SummarizeResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "task_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "aiqueue.TaskStatus": {
+            "type": "string",
+            "enum": [
+                "PENDING",
+                "STARTED",
+                "SUCCESS",
+                "FAILURE",
+                "RETRY",
+                "REVOKED"
+            ],
+            "x-enum-varnames": [
+                "TaskStatusPending",
+                "TaskStatusStarted",
+                "TaskStatusSuccess",
+                "TaskStatusFailure",
+                "TaskStatusRetry",
+                "TaskStatusRevoked"
+            ]
+        },
+        "aiqueue.TaskStatusResponse": {
+            "type": "object",
+            "properties": {
+                "completed_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "result": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/aiqueue.TaskStatus"
+                },
+                "task_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "auth.APIKeyKind": {
+            "type": "string",
+            "enum": [
+                "PERSONAL",
+                "SERVICE"
+            ],
+            "x-enum-varnames": [
+                "APIKeyKindPersonal",
+                "APIKeyKindService"
+            ]
+        },
+        "auth.APIKeyListResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/auth.APIKeyResponse"
+                    }
+                }
+            }
+        },
+        "auth.APIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "4c1f2e3d-5a6b-4c7d-8e9f-0a1b2c3d4e5f"
+                },
+                "kind": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/auth.APIKeyKind"
+                        }
+                    ],
+                    "example": "PERSONAL"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "last_used_ip": {
+                    "type": "string",
+                    "example": "203.0.113.7"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "CI pipeline"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "kcp_Xk3vQ9aB"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "user"
+                    ]
+                }
+            }
+        },
+        "auth.ChangeExpiredPasswordRequest": {
+            "type": "object",
+            "properties": {
+                "new_password": {
+                    "type": "string",
+                    "example": "newSecurePassword123"
+                },
+                "password_change_token": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                }
+            }
+        },
+        "auth.CreateAPIKeyRequest": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "CI pipeline"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "user"
+                    ]
+                }
+            }
+        },
+        "auth.CreateAPIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "$ref": "#/definitions/auth.APIKeyResponse"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "kcp_Xk3vQ9aBzR7mT2wY5uE8iO1pL4sD6fG0hJ3kN9cV2bM"
+                }
+            }
+        },
+        "auth.CreateServiceAccountRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "reporting@example.com"
+                },
+                "login_id": {
+                    "type": "string",
+                    "example": "svc-reporting"
+                },
+                "name": {
+                    "type": "object"
+                }
+            }
+        },
+        "auth.DirectoryMembershipDiff": {
+            "type": "object",
+            "properties": {
+                "group": {
+                    "type": "string",
+                    "example": "admins"
+                },
+                "login_id": {
+                    "type": "string",
+                    "example": "jdoe"
+                }
+            }
+        },
+        "auth.DirectorySyncReport": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/auth.DirectoryUserChange"
+                    }
+                },
+                "deleted": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "otimer"
+                    ]
+                },
+                "deletions_skipped": {
+                    "description": "entries without a login ID left the users to delete unknown",
+                    "type": "boolean",
+                    "example": false
+                },
+                "departments_created": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "Engineering/Backend"
+                    ]
+                },
+                "directory_users": {
+                    "type": "integer",
+                    "example": 250
+                },
+                "dry_run": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "failures": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "finished_at": {
+                    "type": "string",
+                    "example": "2024-12-05T00:00:02Z"
+                },
+                "memberships_added": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/auth.DirectoryMembershipDiff"
+                    }
+                },
+                "memberships_removed": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/auth.DirectoryMembershipDiff"
+                    }
+                },
+                "skipped": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/auth.DirectoryUserChange"
+                    }
+                },
+                "started_at": {
+                    "type": "string",
+                    "example": "2024-12-05T00:00:00Z"
+                },
+                "updated": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/auth.DirectoryUserChange"
+                    }
+                }
+            }
+        },
+        "auth.DirectoryUserChange": {
+            "type": "object",
+            "properties": {
+                "fields": {
+                    "description": "Fields lists the changed fields of updated users, or the reason a user was skipped",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "email",
+                        "department"
+                    ]
+                },
+                "login_id": {
+                    "type": "string",
+                    "example": "jdoe"
+                }
+            }
+        },
+        "auth.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Unauthorized"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Invalid credentials"
+                }
+            }
+        },
+        "auth.ForgotPasswordRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "john.doe@example.com"
+                }
+            }
+        },
+        "auth.ImpersonateRequest": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "example": "Ticket #4711: user cannot see the Sales board"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "01912345-6789-7abc-def0-123456789abc"
+                }
+            }
+        },
+        "auth.ImpersonatedRequestListResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/auth.ImpersonatedRequestResponse"
+                    }
+                },
+                "limit": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "page": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "total_count": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "total_pages": {
+                    "type": "integer",
+                    "example": 5
+                }
+            }
+        },
+        "auth.ImpersonatedRequestResponse": {
+            "type": "object",
+            "properties": {
+                "client_ip": {
+                    "type": "string",
+                    "example": "203.0.113.7"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "method": {
+                    "type": "string",
+                    "example": "GET"
+                },
+                "path": {
+                    "type": "string",
+                    "example": "/tickets/42"
+                },
+                "status_code": {
+                    "type": "integer",
+                    "example": 200
+                }
+            }
+        },
+        "auth.ImpersonationListResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/auth.ImpersonationResponse"
+                    }
+                },
+                "limit": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "page": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "total_count": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "total_pages": {
+                    "type": "integer",
+                    "example": 5
+                }
+            }
+        },
+        "auth.ImpersonationResponse": {
+            "type": "object",
+            "properties": {
+                "actor_id": {
+                    "type": "string",
+                    "example": "01912345-6789-7abc-def0-123456789abc"
+                },
+                "client_ip": {
+                    "type": "string",
+                    "example": "203.0.113.7"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "ended_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "Ticket #4711: user cannot see the Sales board"
+                },
+                "request_count": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "user_agent": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "01912345-6789-7abc-def0-123456789def"
+                }
+            }
+        },
+        "auth.ImpersonationTokenResponse": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                },
+                "expires_in": {
+                    "type": "integer",
+                    "example": 600
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "token_type": {
+                    "type": "string",
+                    "example": "Bearer"
+                },
+                "user": {
+                    "$ref": "#/definitions/auth.UserInfo"
+                }
+            }
+        },
+        "auth.JSONWebKey": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string",
+                    "example": "ES256"
+                },
+                "crv": {
+                    "type": "string",
+                    "example": "P-256"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string",
+                    "example": "3f9a1c2b7d4e8f60"
+                },
+                "kty": {
+                    "type": "string",
+                    "example": "EC"
+                },
+                "n": {
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string",
+                    "example": "sig"
+                },
+                "x": {
+                    "type": "string"
+                },
+                "y": {
+                    "type": "string"
+                }
+            }
+        },
+        "auth.JSONWebKeySet": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/auth.JSONWebKey"
+                    }
+                }
+            }
+        },
+        "auth.LockoutListResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/auth.LockoutResponse"
+                    }
+                }
+            }
+        },
+        "auth.LockoutResponse": {
+            "type": "object",
+            "properties": {
+                "locked_until": {
+                    "type": "string"
+                },
+                "lockout_count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "scope": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/auth.SecurityScope"
+                        }
+                    ],
+                    "example": "LOGIN_ID"
+                },
+                "subject": {
+                    "type": "string",
+                    "example": "john.doe"
+                }
+            }
+        },
+        "auth.LoginRequest": {
+            "type": "object",
+            "properties": {
+                "login_id": {
+                    "type": "string",
+                    "example": "john.doe@example.com"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "securePassword123"
+                }
+            }
+        },
+        "auth.LoginResponse": {
+            "type": "object",
+            "properties": {
+                "mfa": {
+                    "$ref": "#/definitions/auth.MFAChallengeResponse"
+                },
+                "password_change": {
+                    "description": "PasswordChange is set instead of tokens when the password expired and must be changed first",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/auth.PasswordChangeChallengeResponse"
+                        }
+                    ]
+                },
+                "recovery_codes": {
+                    "description": "RecoveryCodes is only set when the login completed a required MFA enrollment",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "k7m2p-x9q4t"
+                    ]
+                },
+                "tokens": {
+                    "$ref": "#/definitions/auth.TokenResponse"
+                },
+                "user": {
+                    "$ref": "#/definitions/auth.UserInfo"
+                }
+            }
+        },
+        "auth.MFAChallengeResponse": {
+            "type": "object",
+            "properties": {
+                "enrollment_required": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "expires_in": {
+                    "type": "integer",
+                    "example": 300
+                },
+                "required": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "token": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                }
+            }
+        },
+        "auth.MFACodeRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                },
+                "recovery_code": {
+                    "type": "string",
+                    "example": "k7m2p-x9q4t"
+                }
+            }
+        },
+        "auth.MFAEnrollmentResponse": {
+            "type": "object",
+            "properties": {
+                "provisioning_uri": {
+                    "type": "string",
+                    "example": "otpauth://totp/Knowledge%20Center:john.doe?algorithm=SHA1\u0026digits=6\u0026issuer=Knowledge+Center\u0026period=30\u0026secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+                },
+                "secret": {
+                    "type": "string",
+                    "example": "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+                }
+            }
+        },
+        "auth.MFAStatusResponse": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "recovery_codes_remaining": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "required": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "auth.MeResponse": {
+            "type": "object",
+            "properties": {
+                "impersonator": {
+                    "description": "Impersonator is set while an administrator acts as the user",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/auth.TokenActor"
+                        }
+                    ]
+                },
+                "permissions": {
+                    "description": "Permissions are the named permissions granted by the roles, sorted",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "user": {
+                    "$ref": "#/definitions/auth.UserInfo"
+                }
+            }
+        },
+        "auth.OIDCAuthorizationResponse": {
+            "type": "object",
+            "properties": {
+                "authorization_url": {
+                    "type": "string",
+                    "example": "https://idp.example.com/authorize?response_type=code\u0026client_id=kc-api\u0026state=..."
+                }
+            }
+        },
+        "auth.OIDCCallbackRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "SplxlOBeZQQYbYS6WxSbIA"
+                },
+                "state": {
+                    "type": "string",
+                    "example": "af0ifjsldkj"
+                }
+            }
+        },
+        "auth.PasswordChangeChallengeResponse": {
+            "type": "object",
+            "properties": {
+                "expires_in": {
+                    "type": "integer",
+                    "example": 600
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "expired"
+                },
+                "required": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "token": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                }
+            }
+        },
+        "auth.PasswordPolicyResponse": {
+            "type": "object",
+            "properties": {
+                "breach_list_loaded": {
+                    "description": "BreachListLoaded tells whether check_breached has an effect (AUTH_BREACHED_PASSWORDS_DIR)",
+                    "type": "boolean",
+                    "example": true
+                },
+                "policy": {
+                    "$ref": "#/definitions/password.Policy"
+                },
+                "updated_at": {
+                    "description": "UpdatedAt is not set while the default policy is in effect",
+                    "type": "string"
+                }
+            }
+        },
+        "auth.RecoveryCodesResponse": {
+            "type": "object",
+            "properties": {
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "k7m2p-x9q4t"
+                    ]
+                }
+            }
+        },
+        "auth.RegisterRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "john.doe@example.com"
+                },
+                "login_id": {
+                    "type": "string",
+                    "example": "john.doe"
+                },
+                "name": {
+                    "type": "object"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "securePassword123"
+                }
+            }
+        },
+        "auth.RegisterResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "User registered successfully"
+                },
+                "mfa": {
+                    "$ref": "#/definitions/auth.MFAChallengeResponse"
+                },
+                "tokens": {
+                    "$ref": "#/definitions/auth.TokenResponse"
+                },
+                "user": {
+                    "$ref": "#/definitions/auth.UserInfo"
+                }
+            }
+        },
+        "auth.ResetPasswordRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "newSecurePassword123"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "Zm9vYmFyYmF6cXV4..."
+                }
+            }
+        },
+        "auth.SecurityEventListResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/auth.SecurityEventResponse"
+                    }
+                },
+                "limit": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "page": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "total_count": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "total_pages": {
+                    "type": "integer",
+                    "example": 5
+                }
+            }
+        },
+        "auth.SecurityEventResponse": {
+            "type": "object",
+            "properties": {
+                "actor_id": {
+                    "type": "string"
+                },
+                "client_ip": {
+                    "type": "string",
+                    "example": "203.0.113.7"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "event_type": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/auth.SecurityEventType"
+                        }
+                    ],
+                    "example": "LOGIN_LOCKOUT"
+                },
+                "failed_attempts": {
+                    "type": "integer",
+                    "example": 5
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "locked_until": {
+                    "type": "string"
+                },
+                "login_id": {
+                    "type": "string",
+                    "example": "john.doe"
+                },
+                "scope": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/auth.SecurityScope"
+                        }
+                    ],
+                    "example": "IP"
+                },
+                "subject": {
+                    "type": "string",
+                    "example": "203.0.113.7"
+                },
+                "user_agent": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "01912345-6789-7abc-def0-123456789abc"
+                }
+            }
+        },
+        "auth.SecurityEventType": {
+            "type": "string",
+            "enum": [
+                "LOGIN_LOCKOUT",
+                "LOGIN_UNLOCK",
+                "REFRESH_TOKEN_REUSE"
+            ],
+            "x-enum-varnames": [
+                "SecurityEventLoginLockout",
+                "SecurityEventLoginUnlock",
+                "SecurityEventRefreshTokenReuse"
+            ]
+        },
+        "auth.SecurityScope": {
+            "type": "string",
+            "enum": [
+                "LOGIN_ID",
+                "IP",
+                "SESSION",
+                "MFA_CHALLENGE",
+                "SHARE_LINK"
+            ],
+            "x-enum-varnames": [
+                "SecurityScopeLoginID",
+                "SecurityScopeIP",
+                "SecurityScopeSession",
+                "SecurityScopeMFAChallenge",
+                "SecurityScopeShareLink"
+            ]
+        },
+        "auth.ServiceAccountListResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/auth.UserInfo"
+                    }
+                }
+            }
+        },
+        "auth.SessionListResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/auth.SessionResponse"
+                    }
+                }
+            }
+        },
+        "auth.SessionResponse": {
+            "type": "object",
+            "properties": {
+                "client_ip": {
+                    "type": "string",
+                    "example": "203.0.113.7"
+                },
+                "current": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "9b2f6c1e-3a4d-4f5e-8a7b-1c2d3e4f5a6b"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "mfa_verified": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string",
+                    "example": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0 Safari/537.36"
+                }
+            }
+        },
+        "auth.SigningKeyInfo": {
+            "type": "object",
+            "properties": {
+                "activates_at": {
+                    "type": "string",
+                    "example": "2024-12-01T00:00:00Z"
+                },
+                "alg": {
+                    "type": "string",
+                    "example": "ES256"
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2024-11-30T00:00:00Z"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "example": "2025-01-01T00:00:00Z"
+                },
+                "kid": {
+                    "type": "string",
+                    "example": "3f9a1c2b7d4e8f60"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "active",
+                        "next",
+                        "retired"
+                    ],
+                    "example": "active"
+                }
+            }
+        },
+        "auth.SigningKeyListResponse": {
+            "type": "object",
+            "properties": {
+                "algorithm": {
+                    "type": "string",
+                    "example": "ES256"
+                },
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/auth.SigningKeyInfo"
+                    }
+                }
+            }
+        },
+        "auth.SuccessResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "Operation completed successfully"
+                }
+            }
+        },
+        "auth.TokenActor": {
+            "type": "object",
+            "properties": {
+                "login_id": {
+                    "type": "string",
+                    "example": "support.agent"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "01912345-6789-7abc-def0-123456789abc"
+                }
+            }
+        },
+        "auth.TokenResponse": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                },
+                "expires_in": {
+                    "type": "integer",
+                    "example": 3600
+                },
+                "token_type": {
+                    "type": "string",
+                    "example": "Bearer"
+                }
+            }
+        },
+        "auth.UnlockLoginRequest": {
+            "type": "object",
+            "properties": {
+                "client_ip": {
+                    "type": "string",
+                    "example": "203.0.113.7"
+                },
+                "login_id": {
+                    "type": "string",
+                    "example": "john.doe"
+                }
+            }
+        },
+        "auth.UserInfo": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "john.doe@example.com"
+                },
+                "email_verified": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "id": {
+                    "type": "string",
+                    "example": "01912345-6789-7abc-def0-123456789abc"
+                },
+                "login_id": {
+                    "type": "string",
+                    "example": "john.doe"
+                },
+                "name": {
+                    "type": "object"
+                }
+            }
+        },
+        "auth.VerifyEmailRequest": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "type": "string",
+                    "example": "Zm9vYmFyYmF6cXV4..."
+                }
+            }
+        },
+        "auth.VerifyMFARequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                },
+                "mfa_token": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                },
+                "recovery_code": {
+                    "type": "string",
+                    "example": "k7m2p-x9q4t"
+                }
+            }
+        },
+        "commoncodes.BatchCreateRequest": {
+            "type": "object",
+            "properties": {
+                "codes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/commoncodes.CreateCommonCodeRequest"
+                    }
+                }
+            }
+        },
+        "commoncodes.BatchDeleteRequest": {
+            "type": "object",
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        1,
+                        2,
+                        3
+                    ]
+                }
+            }
+        },
+        "commoncodes.BatchOperationResponse": {
+            "type": "object",
+            "properties": {
+                "failed_count": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "failed_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "success_count": {
+                    "type": "integer",
+                    "example": 5
+                }
+            }
+        },
+        "commoncodes.BatchUpdateRequest": {
+            "type": "object",
+            "properties": {
+                "updates": {
+                    "type": "array",
+                    "items": {
+                        "type": "object",
+                        "properties": {
+                            "category": {
+                                "type": "string",
+                                "example": "rank"
+                            },
+                            "code": {
+                                "type": "string",
+                                "example": "MANAGER"
+                            },
+                            "description": {
+                                "type": "object"
+                            },
+                            "extra_payload": {
+                                "type": "object"
+                            },
+                            "id": {
+                                "type": "integer",
+                                "example": 1
+                            },
+                            "name": {
+                                "type": "object"
+                            },
+                            "sort_order": {
+                                "type": "integer",
+                                "example": 10
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "commoncodes.CategoryResponse": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string",
+                    "example": "rank"
+                }
+            }
+        },
+        "commoncodes.CommonCodeListResponseWrapper": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/commoncodes.CommonCodeResponse"
+                    }
+                },
+                "limit": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "page": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "total_count": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "total_pages": {
+                    "type": "integer",
+                    "example": 10
+                }
+            }
+        },
+        "commoncodes.CommonCodeResponse": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string",
+                    "example": "rank"
+                },
+                "code": {
+                    "type": "string",
+                    "example": "MANAGER"
+                },
+                "description": {
+                    "type": "object"
+                },
+                "extra_payload": {
+                    "type": "object"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "name": {
+                    "type": "object"
+                },
+                "sort_order": {
+                    "type": "integer",
+                    "example": 10
+                }
+            }
+        },
+        "commoncodes.CreateCommonCodeRequest": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string",
+                    "example": "rank"
+                },
+                "code": {
+                    "type": "string",
+                    "example": "MANAGER"
+                },
+                "description": {
+                    "type": "object"
+                },
+                "extra_payload": {
+                    "type": "object"
+                },
+                "name": {
+                    "type": "object"
+                },
+                "sort_order": {
+                    "type": "integer",
+                    "example": 10
+                }
+            }
+        },
+        "commoncodes.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Bad Request"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Invalid category or code"
+                }
+            }
+        },
+        "commoncodes.ReorderRequest": {
+            "type": "object",
+            "properties": {
+                "orders": {
+                    "type": "array",
+                    "items": {
+                        "type": "object",
+                        "properties": {
+                            "id": {
+                                "type": "integer",
+                                "example": 1
+                            },
+                            "sort_order": {
+                                "type": "integer",
+                                "example": 10
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "commoncodes.SearchCommonCodeRequest": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string",
+                    "example": "rank"
+                },
+                "code": {
+                    "type": "string",
+                    "example": "MANAGER"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "description text"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Manager"
+                }
+            }
+        },
+        "commoncodes.SuccessResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "Common code deleted successfully"
+                }
+            }
+        },
+        "commoncodes.UpdateCommonCodeRequest": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string",
+                    "example": "rank"
+                },
+                "code": {
+                    "type": "string",
+                    "example": "MANAGER"
+                },
+                "description": {
+                    "type": "object"
+                },
+                "extra_payload": {
+                    "type": "object"
+                },
+                "name": {
+                    "type": "object"
+                },
+                "sort_order": {
+                    "type": "integer",
+                    "example": 10
+                }
+            }
+        },
+        "departments.BatchCreateDepartmentRequest": {
+            "type": "object",
+            "properties": {
+                "departments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/departments.CreateDepartmentRequest"
+                    }
+                }
+            }
+        },
+        "departments.BatchDeleteDepartmentRequest": {
+            "type": "object",
+            "properties": {
+                "public_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "dept-abc123",
+                        "dept-xyz789"
+                    ]
+                }
+            }
+        },
+        "departments.BatchOperationResponse": {
+            "type": "object",
+            "properties": {
+                "failed_count": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "failed_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "success_count": {
+                    "type": "integer",
+                    "example": 5
+                }
+            }
+        },
+        "departments.BatchUpdateDepartmentRequest": {
+            "type": "object",
+            "properties": {
+                "updates": {
+                    "type": "array",
+                    "items": {
+                        "type": "object",
+                        "properties": {
+                            "email": {
+                                "type": "string",
+                                "example": "engineering@company.com"
+                            },
+                            "is_visible": {
+                                "type": "boolean",
+                                "example": true
+                            },
+                            "name": {
+                                "type": "object"
+                            },
+                            "parent_department_public_id": {
+                                "type": "string",
+                                "example": "dept-parent123"
+                            },
+                            "public_id": {
+                                "type": "string",
+                                "example": "dept-abc123"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "departments.CreateDepartmentRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "engineering@company.com"
+                },
+                "is_visible": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "name": {
+                    "type": "object"
+                },
+                "parent_department_public_id": {
+                    "type": "string",
+                    "example": "dept-parent123"
+                }
+            }
+        },
+        "departments.DepartmentListResponseWrapper": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/departments.DepartmentResponse"
+                    }
+                },
+                "limit": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "page": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "total_count": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "total_pages": {
+                    "type": "integer",
+                    "example": 10
+                }
+            }
+        },
+        "departments.DepartmentResponse": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "engineering@company.com"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "is_visible": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "name": {
+                    "type": "object"
+                },
+                "parent_department_public_id": {
+                    "type": "string",
+                    "example": "dept-parent123"
+                },
+                "public_id": {
+                    "type": "string",
+                    "example": "dept-abc123"
                 }
             }
         },
-        "aiqueue.TaskStatus": {
-            "type": "string",
-            "enum": [
-                "PENDING",
-                "STARTED",
-                "SUCCESS",
-                "FAILURE",
-                "RETRY",
-                "REVOKED"
-            ],
-            "x-enum-varnames": [
-                "TaskStatusPending",
-                "TaskStatusStarted",
-                "TaskStatusSuccess",
-                "TaskStatusFailure",
-                "TaskStatusRetry",
-                "TaskStatusRevoked"
-            ]
-        },
-        "aiqueue.TaskStatusResponse": {
+        "departments.DepartmentTreeResponse": {
             "type": "object",
             "properties": {
-                "completed_at": {
-                    "type": "string"
+                "children": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/departments.DepartmentTreeResponse"
+                    }
                 },
-                "error": {
-                    "type": "string"
+                "email": {
+                    "type": "string",
+                    "example": "engineering@company.com"
                 },
-                "result": {
-                    "type": "object",
-                    "additionalProperties": true
+                "id": {
+                    "type": "integer",
+                    "example": 1
                 },
-                "started_at": {
-                    "type": "string"
+                "is_visible": {
+                    "type": "boolean",
+                    "example": true
                 },
-                "status": {
-                    "$ref": "#/definitions/aiqueue.TaskStatus"
+                "name": {
+                    "type": "object"
                 },
-                "task_id": {
-                    "type": "string"
+                "parent_department_public_id": {
+                    "type": "string",
+                    "example": "dept-parent123"
+                },
+                "public_id": {
+                    "type": "string",
+                    "example": "dept-abc123"
                 }
             }
         },
-        "auth.ErrorResponse": {
+        "departments.ErrorResponse": {
             "type": "object",
             "properties": {
                 "error": {
                     "type": "string",
-                    "example": "Unauthorized"
+                    "example": "Bad Request"
                 },
                 "message": {
                     "type": "string",
-                    "example": "Invalid credentials"
+                    "example": "Invalid department name"
                 }
             }
         },
-        "auth.LoginRequest": {
+        "departments.MoveDepartmentRequest": {
             "type": "object",
             "properties": {
-                "login_id": {
-                    "type": "string",
-                    "example": "john.doe@example.com"
-                },
-                "password": {
+                "new_parent_department_public_id": {
                     "type": "string",
-                    "example": "securePassword123"
+                    "example": "dept-parent123"
                 }
             }
         },
-        "auth.LoginResponse": {
+        "departments.SearchDepartmentRequest": {
             "type": "object",
             "properties": {
-                "tokens": {
-                    "$ref": "#/definitions/auth.TokenResponse"
-                },
-                "user": {
-                    "$ref": "#/definitions/auth.UserInfo"
+                "name": {
+                    "type": "string",
+                    "example": "Engineering"
                 }
             }
         },
-        "auth.MeResponse": {
+        "departments.SuccessResponse": {
             "type": "object",
             "properties": {
-                "roles": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
-                },
-                "user": {
-                    "$ref": "#/definitions/auth.UserInfo"
+                "message": {
+                    "type": "string",
+                    "example": "Department deleted successfully"
                 }
             }
         },
-        "auth.RegisterRequest": {
+        "departments.UpdateDepartmentRequest": {
             "type": "object",
             "properties": {
                 "email": {
                     "type": "string",
-                    "example": "john.doe@example.com"
+                    "example": "engineering@company.com"
                 },
-                "login_id": {
-                    "type": "string",
-                    "example": "john.doe"
+                "is_visible": {
+                    "type": "boolean",
+                    "example": true
                 },
                 "name": {
                     "type": "object"
                 },
-                "password": {
+                "parent_department_public_id": {
                     "type": "string",
-                    "example": "securePassword123"
+                    "example": "dept-parent123"
                 }
             }
         },
-        "auth.RegisterResponse": {
+        "ews.AttachmentInfo": {
             "type": "object",
             "properties": {
-                "message": {
-                    "type": "string",
-                    "example": "User registered successfully"
+                "attachment_id": {
+                    "type": "string"
                 },
-                "tokens": {
-                    "$ref": "#/definitions/auth.TokenResponse"
+                "content_id": {
+                    "type": "string"
                 },
-                "user": {
-                    "$ref": "#/definitions/auth.UserInfo"
+                "content_type": {
+                    "type": "string"
+                },
+                "is_inline": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
                 }
             }
         },
-        "auth.SuccessResponse": {
+        "ews.EmailAddress": {
             "type": "object",
             "properties": {
-                "message": {
-                    "type": "string",
-                    "example": "Operation completed successfully"
+                "address": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
                 }
             }
         },
-        "auth.TokenResponse": {
+        "ews.EmailDetail": {
             "type": "object",
             "properties": {
-                "access_token": {
-                    "type": "string",
-                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                "attachments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/ews.AttachmentInfo"
+                    }
                 },
-                "expires_in": {
-                    "type": "integer",
-                    "example": 3600
+                "bcc_recipients": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/ews.EmailAddress"
+                    }
                 },
-                "token_type": {
+                "body": {
+                    "type": "string"
+                },
+                "body_type": {
+                    "description": "\"Text\" or \"HTML\"",
+                    "type": "string"
+                },
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "cc_recipients": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/ews.EmailAddress"
+                    }
+                },
+                "conversation_id": {
+                    "type": "string"
+                },
+                "from": {
+                    "$ref": "#/definitions/ews.EmailAddress"
+                },
+                "has_attachments": {
+                    "type": "boolean"
+                },
+                "importance": {
+                    "type": "string"
+                },
+                "internet_message_id": {
+                    "type": "string"
+                },
+                "is_read": {
+                    "type": "boolean"
+                },
+                "item_id": {
+                    "type": "string"
+                },
+                "received_date": {
+                    "type": "string"
+                },
+                "sent_date": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "to_recipients": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/ews.EmailAddress"
+                    }
+                }
+            }
+        },
+        "ews.EmailListItem": {
+            "type": "object",
+            "properties": {
+                "conversation_id": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "from_email": {
+                    "type": "string"
+                },
+                "has_attachments": {
+                    "type": "boolean"
+                },
+                "is_read": {
+                    "type": "boolean"
+                },
+                "item_id": {
+                    "type": "string"
+                },
+                "preview": {
+                    "type": "string"
+                },
+                "received_date": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                }
+            }
+        },
+        "ews.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Bad Request"
+                },
+                "message": {
                     "type": "string",
-                    "example": "Bearer"
+                    "example": "Invalid request body"
                 }
             }
         },
-        "auth.UserInfo": {
+        "ews.GetEmailDetailResponse": {
             "type": "object",
             "properties": {
                 "email": {
-                    "type": "string",
-                    "example": "john.doe@example.com"
+                    "$ref": "#/definitions/ews.EmailDetail"
                 },
-                "id": {
+                "thread": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/ews.EmailListItem"
+                    }
+                }
+            }
+        },
+        "ews.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
                     "type": "string",
-                    "example": "01912345-6789-7abc-def0-123456789abc"
+                    "example": "EWS client is configured and ready"
                 },
-                "login_id": {
+                "status": {
                     "type": "string",
-                    "example": "john.doe"
-                },
-                "name": {
-                    "type": "object"
+                    "example": "healthy"
                 }
             }
         },
-        "commoncodes.BatchCreateRequest": {
+        "ews.ListEmailsResponse": {
             "type": "object",
             "properties": {
-                "codes": {
+                "emails": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/commoncodes.CreateCommonCodeRequest"
+                        "$ref": "#/definitions/ews.EmailListItem"
                     }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
                 }
             }
         },
-        "commoncodes.BatchDeleteRequest": {
+        "files.AccessLevel": {
+            "type": "string",
+            "enum": [
+                "READ",
+                "WRITE"
+            ],
+            "x-enum-comments": {
+                "AccessLevelRead": "Download, thumbnails and file information",
+                "AccessLevelWrite": "READ plus metadata updates"
+            },
+            "x-enum-descriptions": [
+                "Download, thumbnails and file information",
+                "READ plus metadata updates"
+            ],
+            "x-enum-varnames": [
+                "AccessLevelRead",
+                "AccessLevelWrite"
+            ]
+        },
+        "files.CreateArchiveRequest": {
             "type": "object",
             "properties": {
-                "ids": {
+                "file_ids": {
                     "type": "array",
                     "items": {
-                        "type": "integer"
+                        "type": "string"
                     },
                     "example": [
-                        1,
-                        2,
-                        3
+                        "01912345-6789-7abc-def0-123456789abc",
+                        "01912345-6789-7abc-def0-123456789abd"
                     ]
                 }
             }
         },
-        "commoncodes.BatchOperationResponse": {
+        "files.CreateRetentionRuleRequest": {
             "type": "object",
             "properties": {
-                "failed_count": {
-                    "type": "integer",
-                    "example": 0
+                "metadata_key": {
+                    "type": "string",
+                    "example": "category"
                 },
-                "failed_ids": {
-                    "type": "array",
-                    "items": {
-                        "type": "integer"
-                    }
+                "metadata_value": {
+                    "description": "Requires metadata_key",
+                    "type": "string",
+                    "example": "export"
                 },
-                "success_count": {
+                "mime_type": {
+                    "description": "Exact type or wildcard such as \"image/*\"",
+                    "type": "string",
+                    "example": "application/zip"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Temporary exports"
+                },
+                "retain_days": {
                     "type": "integer",
-                    "example": 5
+                    "example": 90
                 }
             }
         },
-        "commoncodes.BatchUpdateRequest": {
+        "files.CreateShareLinkRequest": {
             "type": "object",
             "properties": {
-                "updates": {
-                    "type": "array",
-                    "items": {
-                        "type": "object",
-                        "properties": {
-                            "category": {
-                                "type": "string",
-                                "example": "rank"
-                            },
-                            "code": {
-                                "type": "string",
-                                "example": "MANAGER"
-                            },
-                            "description": {
-                                "type": "object"
-                            },
-                            "extra_payload": {
-                                "type": "object"
-                            },
-                            "id": {
-                                "type": "integer",
-                                "example": 1
-                            },
-                            "name": {
-                                "type": "object"
-                            },
-                            "sort_order": {
-                                "type": "integer",
-                                "example": 10
-                            }
-                        }
-                    }
+                "expires_in": {
+                    "description": "Lifetime in seconds (default 1 day, max 30 days)",
+                    "type": "integer",
+                    "example": 86400
+                },
+                "max_downloads": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "password": {
+                    "type": "string",
+                    "example": "s3cret"
                 }
             }
         },
-        "commoncodes.CategoryResponse": {
+        "files.ErrorResponse": {
             "type": "object",
             "properties": {
-                "category": {
+                "error": {
                     "type": "string",
-                    "example": "rank"
+                    "example": "Bad Request"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Invalid request"
                 }
             }
         },
-        "commoncodes.CommonCodeListResponseWrapper": {
+        "files.FileListResponse": {
             "type": "object",
             "properties": {
                 "data": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/commoncodes.CommonCodeResponse"
+                        "$ref": "#/definitions/files.FileResponse"
                     }
                 },
                 "limit": {
                     "type": "integer",
-                    "example": 10
+                    "example": 20
                 },
                 "page": {
                     "type": "integer",
@@ -5807,580 +11898,695 @@ const docTemplate = `{
                 },
                 "total_pages": {
                     "type": "integer",
-                    "example": 10
+                    "example": 5
                 }
             }
         },
-        "commoncodes.CommonCodeResponse": {
+        "files.FileResponse": {
             "type": "object",
             "properties": {
-                "category": {
+                "checksum_sha256": {
                     "type": "string",
-                    "example": "rank"
+                    "example": "abc123..."
                 },
-                "code": {
+                "created_at": {
                     "type": "string",
-                    "example": "MANAGER"
+                    "example": "2024-12-05T00:00:00Z"
                 },
-                "description": {
+                "download_count": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "download_url": {
+                    "type": "string",
+                    "example": "/files/01912345-6789-7abc-def0-123456789abc/download"
+                },
+                "file_size": {
+                    "type": "integer",
+                    "example": 1048576
+                },
+                "id": {
+                    "type": "string",
+                    "example": "01912345-6789-7abc-def0-123456789abc"
+                },
+                "is_public": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "last_accessed_at": {
+                    "type": "string",
+                    "example": "2024-12-05T00:00:00Z"
+                },
+                "metadata": {
                     "type": "object"
                 },
-                "extra_payload": {
+                "mime_type": {
+                    "type": "string",
+                    "example": "application/pdf"
+                },
+                "original_filename": {
+                    "type": "string",
+                    "example": "document.pdf"
+                },
+                "thumbnail_url": {
+                    "type": "string",
+                    "example": "/files/01912345-6789-7abc-def0-123456789abc/thumbnail"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "2024-12-05T00:00:00Z"
+                },
+                "uploaded_by": {
+                    "type": "string",
+                    "example": "01912345-6789-7abc-def0-123456789abc"
+                },
+                "uploaded_by_name": {
                     "type": "object"
+                }
+            }
+        },
+        "files.FileShareListResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/files.FileShareResponse"
+                    }
+                }
+            }
+        },
+        "files.FileShareResponse": {
+            "type": "object",
+            "properties": {
+                "access_level": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/files.AccessLevel"
+                        }
+                    ],
+                    "example": "READ"
                 },
-                "id": {
-                    "type": "integer",
-                    "example": 1
+                "created_at": {
+                    "type": "string",
+                    "example": "2024-12-05T00:00:00Z"
                 },
-                "name": {
+                "file_id": {
+                    "type": "string",
+                    "example": "01912345-6789-7abc-def0-123456789abc"
+                },
+                "grantee_id": {
+                    "type": "string",
+                    "example": "01912345-6789-7abc-def0-123456789abc"
+                },
+                "grantee_name": {
                     "type": "object"
                 },
-                "sort_order": {
-                    "type": "integer",
-                    "example": 10
+                "grantee_type": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/files.QuotaScope"
+                        }
+                    ],
+                    "example": "GROUP"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "01912345-6789-7abc-def0-123456789abc"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "2024-12-05T00:00:00Z"
                 }
             }
         },
-        "commoncodes.CreateCommonCodeRequest": {
+        "files.FileUploadResponse": {
             "type": "object",
             "properties": {
-                "category": {
+                "checksum_sha256": {
                     "type": "string",
-                    "example": "rank"
+                    "example": "abc123..."
                 },
-                "code": {
+                "download_url": {
                     "type": "string",
-                    "example": "MANAGER"
+                    "example": "/files/01912345-6789-7abc-def0-123456789abc/download"
+                },
+                "file_size": {
+                    "type": "integer",
+                    "example": 1048576
+                },
+                "id": {
+                    "type": "string",
+                    "example": "01912345-6789-7abc-def0-123456789abc"
                 },
-                "description": {
-                    "type": "object"
+                "message": {
+                    "type": "string",
+                    "example": "File uploaded successfully"
                 },
-                "extra_payload": {
-                    "type": "object"
+                "mime_type": {
+                    "type": "string",
+                    "example": "application/pdf"
                 },
-                "name": {
-                    "type": "object"
+                "original_filename": {
+                    "type": "string",
+                    "example": "document.pdf"
                 },
-                "sort_order": {
-                    "type": "integer",
-                    "example": 10
+                "scan_status": {
+                    "description": "Set when malware scanning is enabled",
+                    "type": "string",
+                    "example": "PENDING"
                 }
             }
         },
-        "commoncodes.ErrorResponse": {
+        "files.PurgeReport": {
             "type": "object",
             "properties": {
-                "error": {
+                "expired_files": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "failures": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "finished_at": {
                     "type": "string",
-                    "example": "Bad Request"
+                    "example": "2024-12-05T00:00:02Z"
                 },
-                "message": {
+                "purged_files": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "reclaimed_bytes": {
+                    "type": "integer",
+                    "example": 104857600
+                },
+                "started_at": {
                     "type": "string",
-                    "example": "Invalid category or code"
+                    "example": "2024-12-05T00:00:00Z"
                 }
             }
         },
-        "commoncodes.ReorderRequest": {
+        "files.QuotaListResponse": {
             "type": "object",
             "properties": {
-                "orders": {
+                "data": {
                     "type": "array",
                     "items": {
-                        "type": "object",
-                        "properties": {
-                            "id": {
-                                "type": "integer",
-                                "example": 1
-                            },
-                            "sort_order": {
-                                "type": "integer",
-                                "example": 10
-                            }
-                        }
+                        "$ref": "#/definitions/files.QuotaResponse"
                     }
                 }
             }
         },
-        "commoncodes.SearchCommonCodeRequest": {
+        "files.QuotaResponse": {
             "type": "object",
             "properties": {
-                "category": {
-                    "type": "string",
-                    "example": "rank"
+                "file_count": {
+                    "type": "integer",
+                    "example": 1234
                 },
-                "code": {
+                "id": {
+                    "description": "Empty for the default user quota",
                     "type": "string",
-                    "example": "MANAGER"
+                    "example": "01912345-6789-7abc-def0-123456789abc"
                 },
-                "description": {
-                    "type": "string",
-                    "example": "description text"
+                "is_default": {
+                    "type": "boolean",
+                    "example": false
                 },
-                "name": {
-                    "type": "string",
-                    "example": "Manager"
-                }
-            }
-        },
-        "commoncodes.SuccessResponse": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string",
-                    "example": "Common code deleted successfully"
-                }
-            }
-        },
-        "commoncodes.UpdateCommonCodeRequest": {
-            "type": "object",
-            "properties": {
-                "category": {
-                    "type": "string",
-                    "example": "rank"
+                "max_bytes": {
+                    "type": "integer",
+                    "example": 10737418240
                 },
-                "code": {
-                    "type": "string",
-                    "example": "MANAGER"
+                "max_files": {
+                    "type": "integer",
+                    "example": 10000
                 },
-                "description": {
-                    "type": "object"
+                "remaining_bytes": {
+                    "type": "integer",
+                    "example": 5368709120
                 },
-                "extra_payload": {
-                    "type": "object"
+                "scope_id": {
+                    "type": "string",
+                    "example": "01912345-6789-7abc-def0-123456789abc"
                 },
-                "name": {
+                "scope_name": {
                     "type": "object"
                 },
-                "sort_order": {
+                "scope_type": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/files.QuotaScope"
+                        }
+                    ],
+                    "example": "DEPARTMENT"
+                },
+                "used_bytes": {
                     "type": "integer",
-                    "example": 10
+                    "example": 5368709120
                 }
             }
         },
-        "departments.BatchCreateDepartmentRequest": {
-            "type": "object",
-            "properties": {
-                "departments": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/departments.CreateDepartmentRequest"
-                    }
-                }
-            }
+        "files.QuotaScope": {
+            "type": "string",
+            "enum": [
+                "USER",
+                "DEPARTMENT",
+                "GROUP"
+            ],
+            "x-enum-varnames": [
+                "QuotaScopeUser",
+                "QuotaScopeDepartment",
+                "QuotaScopeGroup"
+            ]
         },
-        "departments.BatchDeleteDepartmentRequest": {
+        "files.RetentionRuleListResponse": {
             "type": "object",
             "properties": {
-                "public_ids": {
+                "data": {
                     "type": "array",
                     "items": {
-                        "type": "string"
-                    },
-                    "example": [
-                        "dept-abc123",
-                        "dept-xyz789"
-                    ]
+                        "$ref": "#/definitions/files.RetentionRuleResponse"
+                    }
                 }
             }
         },
-        "departments.BatchOperationResponse": {
+        "files.RetentionRuleResponse": {
             "type": "object",
             "properties": {
-                "failed_count": {
-                    "type": "integer",
-                    "example": 0
+                "created_at": {
+                    "type": "string",
+                    "example": "2024-12-05T00:00:00Z"
                 },
-                "failed_ids": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
+                "id": {
+                    "type": "string",
+                    "example": "01912345-6789-7abc-def0-123456789abc"
                 },
-                "success_count": {
+                "is_active": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "metadata_key": {
+                    "type": "string",
+                    "example": "category"
+                },
+                "metadata_value": {
+                    "type": "string",
+                    "example": "export"
+                },
+                "mime_type": {
+                    "type": "string",
+                    "example": "application/zip"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Temporary exports"
+                },
+                "retain_days": {
                     "type": "integer",
-                    "example": 5
+                    "example": 90
                 }
             }
         },
-        "departments.BatchUpdateDepartmentRequest": {
+        "files.ScanResponse": {
             "type": "object",
             "properties": {
-                "updates": {
-                    "type": "array",
-                    "items": {
-                        "type": "object",
-                        "properties": {
-                            "description": {
-                                "type": "object"
-                            },
-                            "name": {
-                                "type": "object"
-                            },
-                            "parent_department_public_id": {
-                                "type": "string",
-                                "example": "dept-parent123"
-                            },
-                            "public_id": {
-                                "type": "string",
-                                "example": "dept-abc123"
-                            }
+                "engine": {
+                    "type": "string",
+                    "example": "ClamAV 1.3.1/27400/Mon Sep 30 08:35:30 2024"
+                },
+                "file_id": {
+                    "type": "string",
+                    "example": "01912345-6789-7abc-def0-123456789abc"
+                },
+                "scanned_at": {
+                    "type": "string",
+                    "example": "2024-12-05T00:00:00Z"
+                },
+                "signature": {
+                    "type": "string",
+                    "example": "Eicar-Test-Signature"
+                },
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/files.ScanStatus"
                         }
-                    }
+                    ],
+                    "example": "CLEAN"
                 }
             }
         },
-        "departments.CreateDepartmentRequest": {
+        "files.ScanStatus": {
+            "type": "string",
+            "enum": [
+                "PENDING",
+                "CLEAN",
+                "INFECTED",
+                "FAILED"
+            ],
+            "x-enum-comments": {
+                "ScanStatusClean": "No malware found, downloads allowed",
+                "ScanStatusFailed": "Scanner could not check the file, downloads blocked until rescanned",
+                "ScanStatusInfected": "Malware found, file quarantined",
+                "ScanStatusPending": "Uploaded, waiting for the scanner"
+            },
+            "x-enum-descriptions": [
+                "Uploaded, waiting for the scanner",
+                "No malware found, downloads allowed",
+                "Malware found, file quarantined",
+                "Scanner could not check the file, downloads blocked until rescanned"
+            ],
+            "x-enum-varnames": [
+                "ScanStatusPending",
+                "ScanStatusClean",
+                "ScanStatusInfected",
+                "ScanStatusFailed"
+            ]
+        },
+        "files.SetLegalHoldRequest": {
             "type": "object",
             "properties": {
-                "description": {
-                    "type": "object"
-                },
-                "name": {
-                    "type": "object"
-                },
-                "parent_department_public_id": {
-                    "type": "string",
-                    "example": "dept-parent123"
+                "legal_hold": {
+                    "type": "boolean",
+                    "example": true
                 }
             }
         },
-        "departments.DepartmentListResponseWrapper": {
+        "files.SetQuotaRequest": {
             "type": "object",
             "properties": {
-                "data": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/departments.DepartmentResponse"
-                    }
-                },
-                "limit": {
+                "max_bytes": {
                     "type": "integer",
-                    "example": 10
+                    "example": 10737418240
                 },
-                "page": {
+                "max_files": {
                     "type": "integer",
-                    "example": 1
+                    "example": 10000
                 },
-                "total_count": {
-                    "type": "integer",
-                    "example": 100
+                "scope_id": {
+                    "type": "string",
+                    "example": "01912345-6789-7abc-def0-123456789abc"
                 },
-                "total_pages": {
-                    "type": "integer",
-                    "example": 10
+                "scope_type": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/files.QuotaScope"
+                        }
+                    ],
+                    "example": "DEPARTMENT"
                 }
             }
         },
-        "departments.DepartmentResponse": {
+        "files.ShareFileRequest": {
             "type": "object",
             "properties": {
-                "description": {
-                    "type": "object"
-                },
-                "id": {
-                    "type": "integer",
-                    "example": 1
-                },
-                "name": {
-                    "type": "object"
-                },
-                "parent_department_public_id": {
-                    "type": "string",
-                    "example": "dept-parent123"
+                "access_level": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/files.AccessLevel"
+                        }
+                    ],
+                    "example": "READ"
                 },
-                "public_id": {
+                "grantee_id": {
                     "type": "string",
-                    "example": "dept-abc123"
+                    "example": "01912345-6789-7abc-def0-123456789abc"
+                },
+                "grantee_type": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/files.QuotaScope"
+                        }
+                    ],
+                    "example": "GROUP"
                 }
             }
         },
-        "departments.DepartmentTreeResponse": {
+        "files.ShareLinkListResponse": {
             "type": "object",
             "properties": {
-                "children": {
+                "data": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/departments.DepartmentTreeResponse"
+                        "$ref": "#/definitions/files.ShareLinkResponse"
                     }
+                }
+            }
+        },
+        "files.ShareLinkResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "example": "2024-12-05T00:00:00Z"
                 },
-                "description": {
-                    "type": "object"
-                },
-                "id": {
+                "download_count": {
                     "type": "integer",
-                    "example": 1
+                    "example": 3
                 },
-                "name": {
-                    "type": "object"
+                "expires_at": {
+                    "type": "string",
+                    "example": "2024-12-06T00:00:00Z"
                 },
-                "parent_department_public_id": {
+                "file_id": {
                     "type": "string",
-                    "example": "dept-parent123"
+                    "example": "01912345-6789-7abc-def0-123456789abc"
                 },
-                "public_id": {
+                "id": {
                     "type": "string",
-                    "example": "dept-abc123"
-                }
-            }
-        },
-        "departments.ErrorResponse": {
-            "type": "object",
-            "properties": {
-                "error": {
+                    "example": "01912345-6789-7abc-def0-123456789abc"
+                },
+                "is_revoked": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "last_used_at": {
                     "type": "string",
-                    "example": "Bad Request"
+                    "example": "2024-12-05T12:00:00Z"
                 },
-                "message": {
+                "max_downloads": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "password_protected": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "url": {
                     "type": "string",
-                    "example": "Invalid department name"
+                    "example": "/public/files/shared/01912345-6789-7abc-def0-123456789abc?expires=1733443200\u0026signature=abc123"
                 }
             }
         },
-        "departments.MoveDepartmentRequest": {
+        "files.SharedFileDownloadRequest": {
             "type": "object",
             "properties": {
-                "new_parent_department_public_id": {
+                "password": {
                     "type": "string",
-                    "example": "dept-parent123"
+                    "example": "s3cret"
                 }
             }
         },
-        "departments.SearchDepartmentRequest": {
+        "files.StorageUsageResponse": {
             "type": "object",
             "properties": {
-                "description": {
-                    "type": "string",
-                    "example": "tech"
+                "file_count": {
+                    "type": "integer",
+                    "example": 12
                 },
-                "name": {
-                    "type": "string",
-                    "example": "Engineering"
+                "quotas": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/files.QuotaResponse"
+                    }
+                },
+                "used_bytes": {
+                    "type": "integer",
+                    "example": 1048576
                 }
             }
         },
-        "departments.SuccessResponse": {
+        "files.SuccessResponse": {
             "type": "object",
             "properties": {
                 "message": {
                     "type": "string",
-                    "example": "Department deleted successfully"
+                    "example": "Operation completed successfully"
                 }
             }
         },
-        "departments.UpdateDepartmentRequest": {
+        "files.UpdateFileMetadataRequest": {
             "type": "object",
             "properties": {
-                "description": {
-                    "type": "object"
-                },
-                "name": {
+                "metadata": {
                     "type": "object"
-                },
-                "parent_department_public_id": {
-                    "type": "string",
-                    "example": "dept-parent123"
                 }
             }
         },
-        "ews.AttachmentInfo": {
+        "files.UsageReportEntry": {
             "type": "object",
             "properties": {
-                "attachment_id": {
-                    "type": "string"
+                "file_count": {
+                    "type": "integer",
+                    "example": 1234
                 },
-                "content_id": {
-                    "type": "string"
+                "max_bytes": {
+                    "type": "integer",
+                    "example": 10737418240
                 },
-                "content_type": {
-                    "type": "string"
+                "scope_id": {
+                    "type": "string",
+                    "example": "01912345-6789-7abc-def0-123456789abc"
                 },
-                "is_inline": {
-                    "type": "boolean"
+                "scope_name": {
+                    "type": "object"
                 },
-                "name": {
-                    "type": "string"
+                "scope_type": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/files.QuotaScope"
+                        }
+                    ],
+                    "example": "USER"
                 },
-                "size": {
-                    "type": "integer"
+                "used_bytes": {
+                    "type": "integer",
+                    "example": 5368709120
                 }
             }
         },
-        "ews.EmailAddress": {
+        "files.UsageReportResponse": {
             "type": "object",
             "properties": {
-                "address": {
-                    "type": "string"
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/files.UsageReportEntry"
+                    }
                 },
-                "name": {
-                    "type": "string"
+                "scope_type": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/files.QuotaScope"
+                        }
+                    ],
+                    "example": "USER"
                 }
             }
         },
-        "ews.EmailDetail": {
+        "groups.AssignRolesRequest": {
             "type": "object",
             "properties": {
-                "attachments": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/ews.AttachmentInfo"
-                    }
-                },
-                "bcc_recipients": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/ews.EmailAddress"
-                    }
-                },
-                "body": {
-                    "type": "string"
-                },
-                "body_type": {
-                    "description": "\"Text\" or \"HTML\"",
-                    "type": "string"
-                },
-                "categories": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
-                },
-                "cc_recipients": {
+                "role_ids": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/ews.EmailAddress"
-                    }
-                },
-                "conversation_id": {
-                    "type": "string"
-                },
-                "from": {
-                    "$ref": "#/definitions/ews.EmailAddress"
-                },
-                "has_attachments": {
-                    "type": "boolean"
-                },
-                "importance": {
-                    "type": "string"
-                },
-                "internet_message_id": {
-                    "type": "string"
-                },
-                "is_read": {
-                    "type": "boolean"
-                },
-                "item_id": {
-                    "type": "string"
-                },
-                "received_date": {
-                    "type": "string"
-                },
-                "sent_date": {
-                    "type": "string"
-                },
-                "subject": {
-                    "type": "string"
-                },
-                "to_recipients": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        1,
+                        2,
+                        3
+                    ]
+                }
+            }
+        },
+        "groups.AssignUsersRequest": {
+            "type": "object",
+            "properties": {
+                "user_ids": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/ews.EmailAddress"
-                    }
+                        "type": "integer"
+                    },
+                    "example": [
+                        1,
+                        2,
+                        3
+                    ]
                 }
             }
         },
-        "ews.EmailListItem": {
+        "groups.BatchCreateGroupRequest": {
             "type": "object",
             "properties": {
-                "conversation_id": {
-                    "type": "string"
-                },
-                "from": {
-                    "type": "string"
-                },
-                "from_email": {
-                    "type": "string"
-                },
-                "has_attachments": {
-                    "type": "boolean"
-                },
-                "is_read": {
-                    "type": "boolean"
-                },
-                "item_id": {
-                    "type": "string"
-                },
-                "preview": {
-                    "type": "string"
-                },
-                "received_date": {
-                    "type": "string"
-                },
-                "subject": {
-                    "type": "string"
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/groups.CreateGroupRequest"
+                    }
                 }
             }
         },
-        "ews.ErrorResponse": {
+        "groups.BatchDeleteGroupRequest": {
             "type": "object",
             "properties": {
-                "error": {
-                    "type": "string",
-                    "example": "Bad Request"
-                },
-                "message": {
-                    "type": "string",
-                    "example": "Invalid request body"
+                "public_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "grp-abc123",
+                        "grp-xyz789"
+                    ]
                 }
             }
         },
-        "ews.GetEmailDetailResponse": {
+        "groups.BatchOperationResponse": {
             "type": "object",
             "properties": {
-                "email": {
-                    "$ref": "#/definitions/ews.EmailDetail"
+                "failed_count": {
+                    "type": "integer",
+                    "example": 0
                 },
-                "thread": {
+                "failed_ids": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/ews.EmailListItem"
+                        "type": "string"
                     }
+                },
+                "success_count": {
+                    "type": "integer",
+                    "example": 5
                 }
             }
         },
-        "ews.HealthResponse": {
+        "groups.BatchUpdateGroupRequest": {
             "type": "object",
             "properties": {
-                "message": {
-                    "type": "string",
-                    "example": "EWS client is configured and ready"
-                },
-                "status": {
-                    "type": "string",
-                    "example": "healthy"
+                "updates": {
+                    "type": "array",
+                    "items": {
+                        "type": "object",
+                        "properties": {
+                            "description": {
+                                "type": "object"
+                            },
+                            "name": {
+                                "type": "object"
+                            },
+                            "public_id": {
+                                "type": "string",
+                                "example": "grp-abc123"
+                            }
+                        }
+                    }
                 }
             }
         },
-        "ews.ListEmailsResponse": {
+        "groups.CreateGroupRequest": {
             "type": "object",
             "properties": {
-                "emails": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/ews.EmailListItem"
-                    }
-                },
-                "limit": {
-                    "type": "integer"
-                },
-                "offset": {
-                    "type": "integer"
+                "description": {
+                    "type": "object"
                 },
-                "total": {
-                    "type": "integer"
+                "name": {
+                    "type": "object"
                 }
             }
         },
-        "files.ErrorResponse": {
+        "groups.ErrorResponse": {
             "type": "object",
             "properties": {
                 "error": {
@@ -6389,22 +12595,22 @@ const docTemplate = `{
                 },
                 "message": {
                     "type": "string",
-                    "example": "Invalid request"
+                    "example": "Invalid group name"
                 }
             }
         },
-        "files.FileListResponse": {
+        "groups.GroupListResponseWrapper": {
             "type": "object",
             "properties": {
                 "data": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/files.FileResponse"
+                        "$ref": "#/definitions/groups.GroupResponse"
                     }
                 },
                 "limit": {
                     "type": "integer",
-                    "example": 20
+                    "example": 10
                 },
                 "page": {
                     "type": "integer",
@@ -6416,255 +12622,376 @@ const docTemplate = `{
                 },
                 "total_pages": {
                     "type": "integer",
-                    "example": 5
+                    "example": 10
                 }
             }
         },
-        "files.FileResponse": {
+        "groups.GroupResponse": {
             "type": "object",
             "properties": {
-                "checksum_sha256": {
-                    "type": "string",
-                    "example": "abc123..."
-                },
-                "created_at": {
-                    "type": "string",
-                    "example": "2024-12-05T00:00:00Z"
+                "description": {
+                    "type": "object"
                 },
-                "download_count": {
+                "id": {
                     "type": "integer",
-                    "example": 42
-                },
-                "download_url": {
-                    "type": "string",
-                    "example": "/files/01912345-6789-7abc-def0-123456789abc/download"
+                    "example": 1
                 },
-                "file_size": {
-                    "type": "integer",
-                    "example": 1048576
+                "name": {
+                    "type": "object"
                 },
-                "id": {
+                "public_id": {
                     "type": "string",
-                    "example": "01912345-6789-7abc-def0-123456789abc"
-                },
-                "is_public": {
-                    "type": "boolean",
-                    "example": false
-                },
-                "last_accessed_at": {
+                    "example": "grp-abc123"
+                }
+            }
+        },
+        "groups.GroupRoleResponse": {
+            "type": "object",
+            "properties": {
+                "group_public_id": {
                     "type": "string",
-                    "example": "2024-12-05T00:00:00Z"
+                    "example": "grp-abc123"
                 },
-                "metadata": {
-                    "type": "object"
+                "role_id": {
+                    "type": "integer",
+                    "example": 1
                 },
-                "mime_type": {
+                "role_name": {
                     "type": "string",
-                    "example": "application/pdf"
-                },
-                "original_filename": {
+                    "example": "admin"
+                }
+            }
+        },
+        "groups.SearchGroupRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
                     "type": "string",
-                    "example": "document.pdf"
+                    "example": "administrator"
                 },
-                "updated_at": {
+                "name": {
                     "type": "string",
-                    "example": "2024-12-05T00:00:00Z"
-                },
-                "uploaded_by": {
+                    "example": "Admins"
+                }
+            }
+        },
+        "groups.SuccessResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
                     "type": "string",
-                    "example": "01912345-6789-7abc-def0-123456789abc"
+                    "example": "Group deleted successfully"
+                }
+            }
+        },
+        "groups.UpdateGroupRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "object"
                 },
-                "uploaded_by_name": {
+                "name": {
                     "type": "object"
                 }
             }
         },
-        "files.FileUploadResponse": {
+        "password.ErrorResponse": {
             "type": "object",
             "properties": {
-                "checksum_sha256": {
+                "error": {
                     "type": "string",
-                    "example": "abc123..."
+                    "example": "Bad Request"
                 },
-                "download_url": {
+                "message": {
                     "type": "string",
-                    "example": "/files/01912345-6789-7abc-def0-123456789abc/download"
+                    "example": "Password does not meet the password policy"
                 },
-                "file_size": {
+                "violations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/password.Violation"
+                    }
+                }
+            }
+        },
+        "password.Policy": {
+            "type": "object",
+            "properties": {
+                "check_breached": {
+                    "description": "CheckBreached rejects passwords found in the breached password list, if one is loaded",
+                    "type": "boolean",
+                    "example": true
+                },
+                "disallow_user_info": {
+                    "description": "DisallowUserInfo rejects passwords containing the login ID, the email or parts of them",
+                    "type": "boolean",
+                    "example": true
+                },
+                "history_count": {
+                    "description": "HistoryCount is how many of the user's previous passwords, including the current one, cannot be reused (0 allows reuse)",
                     "type": "integer",
-                    "example": 1048576
+                    "example": 5
                 },
-                "id": {
-                    "type": "string",
-                    "example": "01912345-6789-7abc-def0-123456789abc"
+                "max_age_days": {
+                    "description": "MaxAgeDays is how long a password may be used before it must be changed on the next login (0 never expires)",
+                    "type": "integer",
+                    "example": 90
                 },
-                "message": {
-                    "type": "string",
-                    "example": "File uploaded successfully"
+                "max_length": {
+                    "type": "integer",
+                    "example": 128
                 },
-                "mime_type": {
-                    "type": "string",
-                    "example": "application/pdf"
+                "min_length": {
+                    "type": "integer",
+                    "example": 12
                 },
-                "original_filename": {
-                    "type": "string",
-                    "example": "document.pdf"
+                "require_digit": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "require_lowercase": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "require_symbol": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "require_uppercase": {
+                    "type": "boolean",
+                    "example": true
                 }
             }
         },
-        "files.SuccessResponse": {
+        "password.Violation": {
             "type": "object",
             "properties": {
                 "message": {
                     "type": "string",
-                    "example": "Operation completed successfully"
-                }
-            }
-        },
-        "files.UpdateFileMetadataRequest": {
-            "type": "object",
-            "properties": {
-                "metadata": {
-                    "type": "object"
+                    "example": "Password must be at least 12 characters"
+                },
+                "rule": {
+                    "type": "string",
+                    "example": "min_length"
                 }
             }
         },
-        "groups.AssignRolesRequest": {
+        "rbac.CoverageReport": {
             "type": "object",
             "properties": {
-                "role_ids": {
+                "method_mismatches": {
+                    "description": "rules whose route is registered, but not with their method",
                     "type": "array",
                     "items": {
-                        "type": "integer"
-                    },
-                    "example": [
-                        1,
-                        2,
-                        3
-                    ]
+                        "$ref": "#/definitions/rbac.MethodMismatch"
+                    }
+                },
+                "route_count": {
+                    "type": "integer",
+                    "example": 120
+                },
+                "rule_count": {
+                    "type": "integer",
+                    "example": 95
+                },
+                "stale_rules": {
+                    "description": "rules whose path pattern is not a registered route",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/rbac.Route"
+                    }
+                },
+                "uncovered_routes": {
+                    "description": "routes without a rule for their method or \"*\"",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/rbac.Route"
+                    }
                 }
             }
         },
-        "groups.AssignUsersRequest": {
+        "rbac.CreateNamedPermissionRequest": {
             "type": "object",
             "properties": {
-                "user_ids": {
+                "description": {
+                    "type": "object"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "tickets:update"
+                },
+                "roles": {
                     "type": "array",
                     "items": {
-                        "type": "integer"
+                        "type": "string"
                     },
                     "example": [
-                        1,
-                        2,
-                        3
+                        "agent",
+                        "admin"
                     ]
                 }
             }
         },
-        "groups.BatchCreateGroupRequest": {
+        "rbac.CreatePermissionRuleRequest": {
             "type": "object",
             "properties": {
-                "groups": {
+                "description": {
+                    "type": "object"
+                },
+                "method": {
+                    "type": "string",
+                    "example": "GET"
+                },
+                "path_pattern": {
+                    "type": "string",
+                    "example": "/users/{id}"
+                },
+                "required_permissions": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/groups.CreateGroupRequest"
-                    }
-                }
-            }
-        },
-        "groups.BatchDeleteGroupRequest": {
-            "type": "object",
-            "properties": {
-                "public_ids": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "users:read"
+                    ]
+                },
+                "required_roles": {
                     "type": "array",
                     "items": {
                         "type": "string"
                     },
                     "example": [
-                        "grp-abc123",
-                        "grp-xyz789"
+                        "admin",
+                        "user"
                     ]
                 }
             }
         },
-        "groups.BatchOperationResponse": {
+        "rbac.ErrorResponse": {
             "type": "object",
             "properties": {
-                "failed_count": {
-                    "type": "integer",
-                    "example": 0
+                "error": {
+                    "type": "string"
                 },
-                "failed_ids": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "rbac.MethodMismatch": {
+            "type": "object",
+            "properties": {
+                "method": {
+                    "type": "string",
+                    "example": "PATCH"
+                },
+                "path_pattern": {
+                    "type": "string",
+                    "example": "/users/{id}"
+                },
+                "registered_methods": {
                     "type": "array",
                     "items": {
                         "type": "string"
-                    }
-                },
-                "success_count": {
-                    "type": "integer",
-                    "example": 5
+                    },
+                    "example": [
+                        "GET",
+                        "PUT",
+                        "DELETE"
+                    ]
                 }
             }
         },
-        "groups.BatchUpdateGroupRequest": {
+        "rbac.NamedPermissionListResponse": {
             "type": "object",
             "properties": {
-                "updates": {
+                "data": {
                     "type": "array",
                     "items": {
-                        "type": "object",
-                        "properties": {
-                            "description": {
-                                "type": "object"
-                            },
-                            "name": {
-                                "type": "object"
-                            },
-                            "public_id": {
-                                "type": "string",
-                                "example": "grp-abc123"
-                            }
-                        }
+                        "$ref": "#/definitions/rbac.NamedPermissionResponse"
                     }
                 }
             }
         },
-        "groups.CreateGroupRequest": {
+        "rbac.NamedPermissionResponse": {
             "type": "object",
             "properties": {
+                "created_at": {
+                    "type": "string"
+                },
                 "description": {
                     "type": "object"
                 },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
                 "name": {
-                    "type": "object"
+                    "type": "string",
+                    "example": "tickets:update"
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "agent",
+                        "admin"
+                    ]
+                },
+                "updated_at": {
+                    "type": "string"
                 }
             }
         },
-        "groups.ErrorResponse": {
+        "rbac.PermissionChange": {
             "type": "object",
             "properties": {
-                "error": {
+                "action": {
                     "type": "string",
-                    "example": "Bad Request"
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete"
+                    ],
+                    "example": "update"
                 },
-                "message": {
+                "after": {
+                    "$ref": "#/definitions/rbac.PermissionRuleSnapshot"
+                },
+                "before": {
+                    "$ref": "#/definitions/rbac.PermissionRuleSnapshot"
+                },
+                "changed_at": {
+                    "type": "string"
+                },
+                "changed_by": {
                     "type": "string",
-                    "example": "Invalid group name"
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "permission_id": {
+                    "type": "integer",
+                    "example": 12
                 }
             }
         },
-        "groups.GroupListResponseWrapper": {
+        "rbac.PermissionChangeListResponse": {
             "type": "object",
             "properties": {
                 "data": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/groups.GroupResponse"
+                        "$ref": "#/definitions/rbac.PermissionChange"
                     }
                 },
                 "limit": {
                     "type": "integer",
-                    "example": 10
+                    "example": 20
                 },
                 "page": {
                     "type": "integer",
@@ -6676,13 +13003,27 @@ const docTemplate = `{
                 },
                 "total_pages": {
                     "type": "integer",
-                    "example": 10
+                    "example": 5
                 }
             }
         },
-        "groups.GroupResponse": {
+        "rbac.PermissionRuleListResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/rbac.PermissionRuleResponse"
+                    }
+                }
+            }
+        },
+        "rbac.PermissionRuleResponse": {
             "type": "object",
             "properties": {
+                "created_at": {
+                    "type": "string"
+                },
                 "description": {
                     "type": "object"
                 },
@@ -6690,82 +13031,180 @@ const docTemplate = `{
                     "type": "integer",
                     "example": 1
                 },
-                "name": {
-                    "type": "object"
+                "method": {
+                    "type": "string",
+                    "example": "GET"
                 },
-                "public_id": {
+                "path_pattern": {
                     "type": "string",
-                    "example": "grp-abc123"
+                    "example": "/users/{id}"
+                },
+                "required_permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "users:read"
+                    ]
+                },
+                "required_roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "admin",
+                        "user"
+                    ]
+                },
+                "updated_at": {
+                    "type": "string"
                 }
             }
         },
-        "groups.GroupRoleResponse": {
+        "rbac.PermissionRuleSnapshot": {
             "type": "object",
             "properties": {
-                "group_public_id": {
+                "description": {
+                    "type": "object"
+                },
+                "method": {
                     "type": "string",
-                    "example": "grp-abc123"
+                    "example": "GET"
                 },
-                "role_id": {
+                "path_pattern": {
+                    "type": "string",
+                    "example": "/users/{id}"
+                },
+                "required_permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "users:read"
+                    ]
+                },
+                "required_roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "admin",
+                        "user"
+                    ]
+                }
+            }
+        },
+        "rbac.PermissionStatus": {
+            "type": "object",
+            "properties": {
+                "last_error": {
+                    "description": "error of the last reload, if it failed",
+                    "type": "string"
+                },
+                "loaded_at": {
+                    "type": "string"
+                },
+                "reloads": {
                     "type": "integer",
-                    "example": 1
+                    "example": 12
                 },
-                "role_name": {
+                "rule_count": {
+                    "type": "integer",
+                    "example": 95
+                },
+                "version": {
                     "type": "string",
-                    "example": "admin"
+                    "example": "9f86d081884c7d65"
                 }
             }
         },
-        "groups.SearchGroupRequest": {
+        "rbac.Route": {
             "type": "object",
             "properties": {
-                "description": {
+                "method": {
                     "type": "string",
-                    "example": "administrator"
+                    "example": "GET"
                 },
-                "name": {
+                "path_pattern": {
                     "type": "string",
-                    "example": "Admins"
+                    "example": "/users/{id}"
                 }
             }
         },
-        "groups.SuccessResponse": {
+        "rbac.RouteListResponse": {
             "type": "object",
             "properties": {
-                "message": {
-                    "type": "string",
-                    "example": "Group deleted successfully"
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/rbac.Route"
+                    }
                 }
             }
         },
-        "groups.UpdateGroupRequest": {
+        "rbac.SuccessResponse": {
             "type": "object",
             "properties": {
-                "description": {
-                    "type": "object"
-                },
-                "name": {
-                    "type": "object"
+                "message": {
+                    "type": "string",
+                    "example": "Permissions refreshed successfully"
                 }
             }
         },
-        "rbac.ErrorResponse": {
+        "rbac.UpdateNamedPermissionRequest": {
             "type": "object",
             "properties": {
-                "error": {
-                    "type": "string"
+                "description": {
+                    "type": "object"
                 },
-                "message": {
-                    "type": "string"
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "agent",
+                        "admin"
+                    ]
                 }
             }
         },
-        "rbac.SuccessResponse": {
+        "rbac.UpdatePermissionRuleRequest": {
             "type": "object",
             "properties": {
-                "message": {
+                "description": {
+                    "type": "object"
+                },
+                "method": {
                     "type": "string",
-                    "example": "Permissions refreshed successfully"
+                    "example": "GET"
+                },
+                "path_pattern": {
+                    "type": "string",
+                    "example": "/users/{id}"
+                },
+                "required_permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "users:read"
+                    ]
+                },
+                "required_roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "admin",
+                        "user"
+                    ]
                 }
             }
         },
@@ -7072,6 +13511,10 @@ const docTemplate = `{
                     "type": "string",
                     "example": "#FF0000"
                 },
+                "is_confidential": {
+                    "type": "boolean",
+                    "example": false
+                },
                 "name": {
                     "type": "string",
                     "example": "urgent"
@@ -7400,6 +13843,11 @@ const docTemplate = `{
                     "type": "integer",
                     "example": 1
                 },
+                "is_confidential": {
+                    "description": "IsConfidential restricts tickets with the tag to their requester, their assignee and\nusers with the tickets:read:confidential permission",
+                    "type": "boolean",
+                    "example": false
+                },
                 "name": {
                     "type": "string",
                     "example": "urgent"
@@ -7626,6 +14074,10 @@ const docTemplate = `{
                     "type": "string",
                     "example": "#FF5500"
                 },
+                "is_confidential": {
+                    "type": "boolean",
+                    "example": true
+                },
                 "name": {
                     "type": "string",
                     "example": "very-urgent"
@@ -7707,6 +14159,10 @@ const docTemplate = `{
                 "password": {
                     "type": "string"
                 },
+                "password_hash": {
+                    "type": "string",
+                    "example": "$2b$12$R9h/cIPz0gi.URNNX3kh2OPST9/PgBkqquzi.Ss7KIUgO2t0jWMUW"
+                },
                 "position_id": {
                     "type": "integer"
                 },
@@ -7806,6 +14262,10 @@ const docTemplate = `{
                 "password": {
                     "type": "string"
                 },
+                "password_hash": {
+                    "type": "string",
+                    "example": "$2b$12$R9h/cIPz0gi.URNNX3kh2OPST9/PgBkqquzi.Ss7KIUgO2t0jWMUW"
+                },
                 "position_id": {
                     "type": "integer"
                 },
@@ -79,6 +79,11 @@ func (c *Client) Close() error {
 	return c.redisClient.Close()
 }
 
+// Redis returns the underlying Redis connection, for sharing it with other domains
+func (c *Client) Redis() *redis.Client {
+	return c.redisClient
+}
+
 // SubmitTask submits a task to the Celery queue via Redis
 func (c *Client) SubmitTask(ctx context.Context, taskName string, kwargs map[string]interface{}) (string, error) {
 	// Generate unique task ID
@@ -0,0 +1,380 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// API key prefixes tell the middleware that a bearer token is an API key rather than a JWT
+const (
+	PersonalTokenPrefix = "kcp_"
+	ServiceKeyPrefix    = "kcs_"
+)
+
+const (
+	// apiKeyDisplayLength is how many leading characters of a key are stored in clear to tell keys apart
+	apiKeyDisplayLength = 12
+
+	// apiKeyNameMaxLength bounds the name of a key
+	apiKeyNameMaxLength = 100
+
+	// apiKeyTouchInterval limits how often the last use of a key is written
+	apiKeyTouchInterval = time.Minute
+)
+
+// isAPIKey reports whether a bearer token is a personal access token or service account key
+func isAPIKey(token string) bool {
+	return strings.HasPrefix(token, PersonalTokenPrefix) || strings.HasPrefix(token, ServiceKeyPrefix)
+}
+
+// ValidateAPIKey authenticates a request made with an API key. The claims carry the key's scopes
+// that the owner still has as roles, so RBAC treats the key like an access token of the owner.
+func (s *service) ValidateAPIKey(ctx context.Context, token, clientIP string) (*TokenClaims, error) {
+	if !isAPIKey(token) {
+		return nil, ErrInvalidToken
+	}
+
+	key, err := s.repo.GetAPIKeyByHash(ctx, s.hashToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil {
+		return nil, ErrTokenRevoked
+	}
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		return nil, ErrTokenExpired
+	}
+
+	user, err := s.getUserByInternalID(ctx, key.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// Roles removed from the owner after the key was created are no longer granted
+	roles, err := s.repo.GetAllUserRoles(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.repo.TouchAPIKey(ctx, key.ID, clientIP); err != nil {
+			log.Printf("[WARN] Failed to record use of API key %s: %v", key.PublicID, err)
+		}
+	}
+
+	return &TokenClaims{
+		UserID:   user.PublicID,
+		LoginID:  user.LoginID,
+		Email:    user.Email,
+		Roles:    intersectRoles(key.Scopes, roles),
+		IssuedAt: key.CreatedAt.Unix(),
+		Issuer:   TokenIssuer,
+		APIKeyID: key.PublicID,
+	}, nil
+}
+
+// ListAPIKeys lists the personal access tokens of a user
+func (s *service) ListAPIKeys(ctx context.Context, userPublicID string) (*APIKeyListResponse, error) {
+	userID, err := s.sessionUserID(ctx, userPublicID)
+	if err != nil {
+		return nil, err
+	}
+	return s.listAPIKeys(ctx, userID, APIKeyKindPersonal)
+}
+
+// CreateAPIKey creates a personal access token for a user
+func (s *service) CreateAPIKey(ctx context.Context, userPublicID string, req *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+	userID, err := s.sessionUserID(ctx, userPublicID)
+	if err != nil {
+		return nil, err
+	}
+	return s.createAPIKey(ctx, userID, APIKeyKindPersonal, userID, req)
+}
+
+// RevokeAPIKey revokes a personal access token of a user
+func (s *service) RevokeAPIKey(ctx context.Context, userPublicID, keyID string) error {
+	userID, err := s.sessionUserID(ctx, userPublicID)
+	if err != nil {
+		return err
+	}
+	return s.revokeAPIKey(ctx, userID, APIKeyKindPersonal, keyID)
+}
+
+// ListServiceAccounts lists all service accounts
+func (s *service) ListServiceAccounts(ctx context.Context) (*ServiceAccountListResponse, error) {
+	users, err := s.repo.ListServiceAccounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list service accounts: %w", err)
+	}
+
+	data := make([]UserInfo, 0, len(users))
+	for i := range users {
+		data = append(data, users[i].ToUserInfo())
+	}
+	return &ServiceAccountListResponse{Data: data}, nil
+}
+
+// CreateServiceAccount creates a user without a password for machine access.
+// It gets no groups; roles are assigned through the usual group and role management.
+func (s *service) CreateServiceAccount(ctx context.Context, req *CreateServiceAccountRequest) (*UserInfo, error) {
+	if !isValidEmail(req.Email) {
+		return nil, ErrInvalidEmail
+	}
+	if !hasAtLeastOneLocale(req.Name) {
+		return nil, ErrInvalidName
+	}
+
+	// Set login_id to email if not provided
+	loginID := strings.TrimSpace(req.LoginID)
+	if loginID == "" {
+		loginID = req.Email
+	}
+
+	_, err := s.repo.GetUserByEmail(ctx, req.Email)
+	if err == nil {
+		return nil, ErrEmailExists
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check email: %w", err)
+	}
+
+	_, err = s.repo.GetUserByLoginID(ctx, loginID)
+	if err == nil {
+		return nil, ErrLoginIDExists
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check login_id: %w", err)
+	}
+
+	user := &AuthUser{
+		LoginID: loginID,
+		Email:   req.Email,
+		Name:    req.Name,
+	}
+	if err := s.repo.CreateServiceAccount(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to create service account: %w", err)
+	}
+
+	info := user.ToUserInfo()
+	return &info, nil
+}
+
+// ListServiceAccountKeys lists the API keys of a service account
+func (s *service) ListServiceAccountKeys(ctx context.Context, accountID string) (*APIKeyListResponse, error) {
+	account, err := s.getServiceAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return s.listAPIKeys(ctx, account.ID, APIKeyKindService)
+}
+
+// CreateServiceAccountKey creates an API key for a service account. The caller must hold every
+// requested scope, so a key never grants roles of the account the caller doesn't have.
+func (s *service) CreateServiceAccountKey(ctx context.Context, actorPublicID, accountID string, req *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+	account, err := s.getServiceAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	actorID, err := s.repo.GetUserInternalID(ctx, actorPublicID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	actorRoles, err := s.repo.GetAllUserRoles(ctx, actorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		scopes = append(scopes, strings.TrimSpace(scope))
+	}
+	if role := missingRole(actorRoles, scopes); role != "" {
+		return nil, fmt.Errorf("%w: %q", ErrScopeNotHeld, role)
+	}
+
+	return s.createAPIKey(ctx, account.ID, APIKeyKindService, actorID, req)
+}
+
+// RevokeServiceAccountKey revokes an API key of a service account
+func (s *service) RevokeServiceAccountKey(ctx context.Context, accountID, keyID string) error {
+	account, err := s.getServiceAccount(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	return s.revokeAPIKey(ctx, account.ID, APIKeyKindService, keyID)
+}
+
+// listAPIKeys lists the unrevoked keys of a kind owned by a user
+func (s *service) listAPIKeys(ctx context.Context, userID int, kind APIKeyKind) (*APIKeyListResponse, error) {
+	keys, err := s.repo.ListAPIKeys(ctx, userID, kind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	data := make([]APIKeyResponse, 0, len(keys))
+	for i := range keys {
+		data = append(data, keys[i].ToResponse())
+	}
+	return &APIKeyListResponse{Data: data}, nil
+}
+
+// createAPIKey validates a key request against the owner's roles and stores the new key.
+// Personal access tokens always expire; service account keys only if an expiry is given.
+func (s *service) createAPIKey(ctx context.Context, ownerID int, kind APIKeyKind, createdBy int, req *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > apiKeyNameMaxLength {
+		return nil, fmt.Errorf("%w: name is required and may have at most %d characters", ErrInvalidKeyRequest, apiKeyNameMaxLength)
+	}
+
+	roles, err := s.repo.GetAllUserRoles(ctx, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+	scopes, err := validateScopes(req.Scopes, roles)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := req.ExpiresAt
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidKeyRequest)
+	}
+	prefix := ServiceKeyPrefix
+	if kind == APIKeyKindPersonal {
+		prefix = PersonalTokenPrefix
+		if expiresAt == nil {
+			defaultExpiry := now.Add(s.config.PersonalTokenTTL)
+			expiresAt = &defaultExpiry
+		}
+		if s.config.PersonalTokenMaxTTL > 0 && expiresAt.Sub(now) > s.config.PersonalTokenMaxTTL {
+			return nil, fmt.Errorf("%w: personal access tokens may be valid for at most %s", ErrInvalidKeyRequest, s.config.PersonalTokenMaxTTL)
+		}
+	}
+
+	token, err := generateAPIKey(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	key := &APIKey{
+		UserID:      ownerID,
+		Kind:        kind,
+		Name:        name,
+		TokenPrefix: token[:apiKeyDisplayLength],
+		TokenHash:   s.hashToken(token),
+		Scopes:      scopes,
+		ExpiresAt:   expiresAt,
+		CreatedBy:   &createdBy,
+	}
+	if err := s.repo.CreateAPIKey(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return &CreateAPIKeyResponse{Key: key.ToResponse(), Token: token}, nil
+}
+
+// revokeAPIKey revokes an unrevoked key of a kind owned by a user
+func (s *service) revokeAPIKey(ctx context.Context, userID int, kind APIKeyKind, keyID string) error {
+	if _, err := uuid.Parse(keyID); err != nil {
+		return ErrAPIKeyNotFound
+	}
+
+	revoked, err := s.repo.RevokeAPIKey(ctx, userID, kind, keyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// getServiceAccount resolves a service account by public ID
+func (s *service) getServiceAccount(ctx context.Context, publicID string) (*AuthUser, error) {
+	userID, err := s.sessionUserID(ctx, publicID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrNoServiceAccount
+		}
+		return nil, err
+	}
+
+	user, err := s.getUserByInternalID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoServiceAccount
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.IsServiceAccount {
+		return nil, ErrNoServiceAccount
+	}
+	return user, nil
+}
+
+// validateScopes checks that the requested scopes are roles the owner has and removes duplicates
+func validateScopes(scopes, roles []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidKeyRequest)
+	}
+
+	granted := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		granted[role] = true
+	}
+
+	seen := make(map[string]bool, len(scopes))
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !granted[scope] {
+			return nil, fmt.Errorf("%w: scope %q is not a role of the key owner", ErrInvalidKeyRequest, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
+
+// intersectRoles returns the scopes that are among the given roles
+func intersectRoles(scopes, roles []string) []string {
+	granted := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		granted[role] = true
+	}
+
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if granted[scope] {
+			result = append(result, scope)
+		}
+	}
+	return result
+}
+
+// generateAPIKey creates a secure random API key with the given prefix
+func generateAPIKey(prefix string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+// fakeAPIKeyRepository keeps users, their roles and API keys in memory. Repository methods the
+// key management doesn't use are left to the nil embedded interface.
+type fakeAPIKeyRepository struct {
+	Repository
+	users map[string]*AuthUser // by public ID
+	roles map[int][]string
+	keys  []APIKey
+}
+
+func (r *fakeAPIKeyRepository) GetUserInternalID(ctx context.Context, publicID string) (int, error) {
+	user, ok := r.users[publicID]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return user.ID, nil
+}
+
+func (r *fakeAPIKeyRepository) GetUserByID(ctx context.Context, userID int) (*AuthUser, error) {
+	for _, user := range r.users {
+		if user.ID == userID {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *fakeAPIKeyRepository) GetAllUserRoles(ctx context.Context, userID int) ([]string, error) {
+	return r.roles[userID], nil
+}
+
+func (r *fakeAPIKeyRepository) CreateAPIKey(ctx context.Context, key *APIKey) error {
+	r.keys = append(r.keys, *key)
+	return nil
+}
+
+func TestService_CreateServiceAccountKey(t *testing.T) {
+	const (
+		accountID = "01912345-6789-7abc-def0-000000000001"
+		adminID   = "01912345-6789-7abc-def0-000000000002"
+		rootID    = "01912345-6789-7abc-def0-000000000003"
+	)
+
+	tests := []struct {
+		name    string
+		actorID string
+		scopes  []string
+		wantErr error
+	}{
+		{name: "scope the caller holds", actorID: adminID, scopes: []string{"reporting"}},
+		{name: "scope the caller lacks", actorID: adminID, scopes: []string{"reporting", "full_access"}, wantErr: ErrScopeNotHeld},
+		{name: "caller with full_access", actorID: rootID, scopes: []string{"reporting", "full_access"}},
+		{name: "scope the account lacks", actorID: rootID, scopes: []string{"hr"}, wantErr: ErrInvalidKeyRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeAPIKeyRepository{
+				users: map[string]*AuthUser{
+					accountID: {ID: 1, PublicID: accountID, LoginID: "svc-reports", IsServiceAccount: true},
+					adminID:   {ID: 2, PublicID: adminID, LoginID: "admin"},
+					rootID:    {ID: 3, PublicID: rootID, LoginID: "root"},
+				},
+				roles: map[int][]string{
+					1: {"reporting", "full_access"},
+					2: {"admin", "reporting"},
+					3: {"full_access"},
+				},
+			}
+			s := &service{repo: repo, config: &Config{}}
+
+			_, err := s.CreateServiceAccountKey(context.Background(), tt.actorID, accountID, &CreateAPIKeyRequest{Name: "reports", Scopes: tt.scopes})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if created := len(repo.keys) > 0; created != (tt.wantErr == nil) {
+				t.Errorf("expected a key to be created: %v, got %d keys", tt.wantErr == nil, len(repo.keys))
+			}
+		})
+	}
+}
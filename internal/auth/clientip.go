@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientIPResolver determines the client address of a request. The X-Forwarded-For and X-Real-IP
+// headers are set by the client unless a proxy replaces them, so they are only believed when the
+// request comes from a trusted proxy. A nil resolver trusts no proxy.
+type ClientIPResolver struct {
+	trusted []netip.Prefix
+}
+
+// NewClientIPResolver creates a resolver trusting the given proxy addresses or CIDR ranges
+func NewClientIPResolver(trustedProxies []string) (*ClientIPResolver, error) {
+	resolver := &ClientIPResolver{}
+	for _, proxy := range trustedProxies {
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+			}
+			resolver.trusted = append(resolver.trusted, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		addr = addr.Unmap()
+		resolver.trusted = append(resolver.trusted, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return resolver, nil
+}
+
+// ClientIP returns the address of the client that sent the request. Behind trusted proxies it is
+// the last X-Forwarded-For entry not added by a trusted proxy, or X-Real-IP; otherwise RemoteAddr.
+func (c *ClientIPResolver) ClientIP(r *http.Request) string {
+	remote := remoteIP(r.RemoteAddr)
+	if !c.isTrusted(remote) {
+		return remote
+	}
+
+	// Each proxy appends the address it received the request from; walk back to the first untrusted one
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		entries := strings.Split(strings.Join(xff, ","), ",")
+		client := ""
+		for i := len(entries) - 1; i >= 0; i-- {
+			ip := cleanIP(strings.TrimSpace(entries[i]))
+			if ip == "" {
+				continue
+			}
+			client = ip
+			if !c.isTrusted(ip) {
+				break
+			}
+		}
+		if client != "" {
+			return client
+		}
+	}
+
+	if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); xri != "" {
+		return cleanIP(xri)
+	}
+
+	return remote
+}
+
+// isTrusted reports whether an address belongs to a trusted proxy
+func (c *ClientIPResolver) isTrusted(ip string) bool {
+	if c == nil {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range c.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteIP strips the port from a RemoteAddr such as "127.0.0.1:1234" or "[::1]:1234"
+func remoteIP(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return cleanIP(remoteAddr)
+}
+
+// cleanIP removes brackets and a port from a forwarded address
+func cleanIP(ip string) string {
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		return host
+	}
+	ip = strings.TrimPrefix(ip, "[")
+	ip = strings.TrimSuffix(ip, "]")
+	return ip
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIPResolver_ClientIP(t *testing.T) {
+	resolver, err := NewClientIPResolver([]string{"10.0.0.0/8", "192.0.2.1", "2001:db8::/32"})
+	if err != nil {
+		t.Fatalf("failed to create resolver: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		resolver   *ClientIPResolver
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{name: "direct request", resolver: resolver, remoteAddr: "203.0.113.7:51234", want: "203.0.113.7"},
+		{name: "spoofed X-Forwarded-For from a client", resolver: resolver, remoteAddr: "203.0.113.7:51234",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.9"}, want: "203.0.113.7"},
+		{name: "spoofed X-Real-IP from a client", resolver: resolver, remoteAddr: "203.0.113.7:51234",
+			headers: map[string]string{"X-Real-IP": "198.51.100.9"}, want: "203.0.113.7"},
+		{name: "no trusted proxies", resolver: nil, remoteAddr: "10.1.2.3:51234",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.9"}, want: "10.1.2.3"},
+		{name: "behind a trusted proxy", resolver: resolver, remoteAddr: "10.1.2.3:51234",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.7"}, want: "203.0.113.7"},
+		{name: "spoofed entry before the client's", resolver: resolver, remoteAddr: "10.1.2.3:51234",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.9, 203.0.113.7"}, want: "203.0.113.7"},
+		{name: "chain of trusted proxies", resolver: resolver, remoteAddr: "10.1.2.3:51234",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.7, 192.0.2.1, 10.4.5.6"}, want: "203.0.113.7"},
+		{name: "X-Real-IP from a trusted proxy", resolver: resolver, remoteAddr: "192.0.2.1:51234",
+			headers: map[string]string{"X-Real-IP": "203.0.113.7"}, want: "203.0.113.7"},
+		{name: "IPv6 behind a trusted proxy", resolver: resolver, remoteAddr: "[2001:db8::1]:51234",
+			headers: map[string]string{"X-Forwarded-For": "[2001:db9::7]:443"}, want: "2001:db9::7"},
+		{name: "trusted proxy without headers", resolver: resolver, remoteAddr: "10.1.2.3:51234", want: "10.1.2.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			if got := tt.resolver.ClientIP(req); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewClientIPResolver_InvalidProxy(t *testing.T) {
+	for _, proxy := range []string{"10.0.0.0/33", "proxy.internal"} {
+		if _, err := NewClientIPResolver([]string{proxy}); err == nil {
+			t.Errorf("expected an error for %q", proxy)
+		}
+	}
+}
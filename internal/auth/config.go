@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds auth domain configuration
+type Config struct {
+	// EncryptionKey is the key used to encrypt TOTP secrets at rest
+	EncryptionKey string
+
+	// MFAIssuer is the issuer shown in authenticator apps
+	MFAIssuer string
+
+	// MFARequiredRoles lists the roles whose members must use two-factor authentication
+	MFARequiredRoles []string
+
+	// MFAChallengeDuration is how long the MFA challenge token issued after a password check is valid
+	MFAChallengeDuration time.Duration
+
+	// MFAMaxAttempts is the number of wrong codes after which an MFA challenge is invalidated (0 disables)
+	MFAMaxAttempts int
+
+	// PasswordResetURL is the frontend page linked in password reset emails; the token is appended as ?token=
+	PasswordResetURL string
+
+	// EmailVerificationURL is the frontend page linked in verification emails; the token is appended as ?token=
+	EmailVerificationURL string
+
+	// PasswordResetTTL is how long a password reset link is valid
+	PasswordResetTTL time.Duration
+
+	// EmailVerificationTTL is how long an email verification link is valid
+	EmailVerificationTTL time.Duration
+
+	// MailRateLimit is the number of reset or verification emails sent to one address per MailRateWindow
+	MailRateLimit int
+
+	// MailRateWindow is the window for MailRateLimit
+	MailRateWindow time.Duration
+
+	// LockoutThreshold is the number of failed logins of one login ID that locks it (0 disables)
+	LockoutThreshold int
+
+	// LockoutIPThreshold is the number of failed logins from one client IP that locks it (0 disables)
+	LockoutIPThreshold int
+
+	// TrustedProxies lists the addresses or CIDR ranges of reverse proxies whose X-Forwarded-For and
+	// X-Real-IP headers are believed for the client IP used by lockouts and audit logs
+	TrustedProxies []string
+
+	// LockoutWindow is how long a failed login counts towards a lockout
+	LockoutWindow time.Duration
+
+	// LockoutDuration is how long a lockout lasts before it is lifted automatically
+	LockoutDuration time.Duration
+
+	// LoginDelay is the wait required after the first failed login of a login ID; it doubles with each further failure (0 disables)
+	LoginDelay time.Duration
+
+	// LoginDelayMax caps LoginDelay
+	LoginDelayMax time.Duration
+
+	// RefreshReuseGrace is how long a rotated refresh token is answered as a concurrent refresh instead of as reuse
+	RefreshReuseGrace time.Duration
+
+	// PersonalTokenTTL is the lifetime of a personal access token created without an expiry
+	PersonalTokenTTL time.Duration
+
+	// PersonalTokenMaxTTL is the longest lifetime a personal access token may be given (0 for no limit)
+	PersonalTokenMaxTTL time.Duration
+
+	// OIDCAutoProvision creates users signing in with single sign-on for the first time
+	OIDCAutoProvision bool
+
+	// OIDCRequireVerifiedEmail accepts single sign-on identities only if the provider verified their email
+	OIDCRequireVerifiedEmail bool
+
+	// OIDCGroupMapping maps provider groups to group public IDs whose membership follows the provider
+	OIDCGroupMapping map[string]string
+
+	// OIDCNameLocale is the locale the provider's name claim is stored under for provisioned users
+	OIDCNameLocale string
+
+	// OIDCStateTTL is how long a started single sign-on login may take to complete
+	OIDCStateTTL time.Duration
+
+	// OIDCTrustProviderMFA skips the local second factor if the provider reports one in the amr claim.
+	// Off by default, as the claim is only as trustworthy as the provider's configuration.
+	OIDCTrustProviderMFA bool
+
+	// LDAPDepartmentSource is where directory users' departments come from:
+	// "attribute" (the department attribute), "ou" (the OUs of their DN) or "none"
+	LDAPDepartmentSource string
+
+	// LDAPGroupMapping maps directory group names (CN) to group public IDs whose membership follows the directory
+	LDAPGroupMapping map[string]string
+
+	// LDAPNameLocale is the locale the directory's display name is stored under
+	LDAPNameLocale string
+
+	// LDAPSyncInterval is how often users are synchronized from the directory (0 disables the schedule)
+	LDAPSyncInterval time.Duration
+
+	// LDAPLinkLocalUsers lets the directory take over local users with the same login ID and
+	// the same, verified email. Their local password is removed.
+	LDAPLinkLocalUsers bool
+
+	// JWTAlgorithm signs access tokens: HS256 with the JWT secret, or RS256, ES256 or EdDSA with rotating keys
+	JWTAlgorithm string
+
+	// JWTKeyRotation is how long a signing key signs access tokens before it is replaced
+	JWTKeyRotation time.Duration
+
+	// JWTKeyOverlap is how long a new key is published before it signs, and a replaced key is kept after
+	JWTKeyOverlap time.Duration
+
+	// JWTAcceptHMAC keeps accepting HS256 access tokens signed with the JWT secret when using rotating keys
+	JWTAcceptHMAC bool
+
+	// ImpersonationRoles lists the roles whose members may act as other users (empty disables impersonation)
+	ImpersonationRoles []string
+
+	// ImpersonationDuration is how long an impersonation token is valid, at most AccessTokenDuration
+	ImpersonationDuration time.Duration
+
+	// BreachedPasswordsDir holds the Pwned Passwords range files new passwords are checked against (empty disables the check)
+	BreachedPasswordsDir string
+
+	// Argon2Time, Argon2Memory (KiB) and Argon2Threads are the Argon2id parameters of new password hashes.
+	// Weaker hashes are replaced on the next successful login.
+	Argon2Time    int
+	Argon2Memory  int
+	Argon2Threads int
+}
+
+// LoadConfig reads auth domain configuration from environment variables
+func LoadConfig() *Config {
+	return &Config{
+		EncryptionKey:        getEnv("AUTH_MFA_ENCRYPTION_KEY", ""),
+		MFAIssuer:            getEnv("AUTH_MFA_ISSUER", "Knowledge Center"),
+		MFARequiredRoles:     getListEnv("AUTH_MFA_REQUIRED_ROLES"),
+		MFAChallengeDuration: getDurationEnv("AUTH_MFA_CHALLENGE_DURATION", 5*time.Minute),
+		MFAMaxAttempts:       getIntEnv("AUTH_MFA_MAX_ATTEMPTS", 3),
+		PasswordResetURL:     getEnv("AUTH_PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
+		EmailVerificationURL: getEnv("AUTH_EMAIL_VERIFICATION_URL", "http://localhost:3000/verify-email"),
+		PasswordResetTTL:     getDurationEnv("AUTH_PASSWORD_RESET_TTL", time.Hour),
+		EmailVerificationTTL: getDurationEnv("AUTH_EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		MailRateLimit:        getIntEnv("AUTH_MAIL_RATE_LIMIT", 3),
+		MailRateWindow:       getDurationEnv("AUTH_MAIL_RATE_WINDOW", time.Hour),
+		LockoutThreshold:     getIntEnv("AUTH_LOCKOUT_THRESHOLD", 5),
+		LockoutIPThreshold:   getIntEnv("AUTH_LOCKOUT_IP_THRESHOLD", 50),
+		TrustedProxies:       getListEnv("AUTH_TRUSTED_PROXIES"),
+		LockoutWindow:        getDurationEnv("AUTH_LOCKOUT_WINDOW", 15*time.Minute),
+		LockoutDuration:      getDurationEnv("AUTH_LOCKOUT_DURATION", 15*time.Minute),
+		LoginDelay:           getDurationEnv("AUTH_LOGIN_DELAY", time.Second),
+		LoginDelayMax:        getDurationEnv("AUTH_LOGIN_DELAY_MAX", 30*time.Second),
+		RefreshReuseGrace:    getDurationEnv("AUTH_REFRESH_REUSE_GRACE", 10*time.Second),
+		PersonalTokenTTL:     getDurationEnv("AUTH_PERSONAL_TOKEN_TTL", 90*24*time.Hour),
+		PersonalTokenMaxTTL:  getDurationEnv("AUTH_PERSONAL_TOKEN_MAX_TTL", 365*24*time.Hour),
+
+		OIDCAutoProvision:        getBoolEnv("AUTH_OIDC_AUTO_PROVISION", false),
+		OIDCRequireVerifiedEmail: getBoolEnv("AUTH_OIDC_REQUIRE_VERIFIED_EMAIL", true),
+		OIDCGroupMapping:         getMapEnv("AUTH_OIDC_GROUP_MAPPING"),
+		OIDCNameLocale:           getEnv("AUTH_OIDC_NAME_LOCALE", "en-US"),
+		OIDCStateTTL:             getDurationEnv("AUTH_OIDC_STATE_TTL", 10*time.Minute),
+		OIDCTrustProviderMFA:     getBoolEnv("AUTH_OIDC_TRUST_PROVIDER_MFA", false),
+
+		LDAPDepartmentSource: getEnv("AUTH_LDAP_DEPARTMENT_SOURCE", DepartmentSourceAttribute),
+		LDAPGroupMapping:     getMapEnv("AUTH_LDAP_GROUP_MAPPING"),
+		LDAPNameLocale:       getEnv("AUTH_LDAP_NAME_LOCALE", "en-US"),
+		LDAPSyncInterval:     getDurationEnv("AUTH_LDAP_SYNC_INTERVAL", time.Hour),
+		LDAPLinkLocalUsers:   getBoolEnv("AUTH_LDAP_LINK_LOCAL_USERS", false),
+
+		JWTAlgorithm:   getEnv("AUTH_JWT_ALGORITHM", JWTAlgorithmHS256),
+		JWTKeyRotation: getDurationEnv("AUTH_JWT_KEY_ROTATION", 30*24*time.Hour),
+		JWTKeyOverlap:  getDurationEnv("AUTH_JWT_KEY_OVERLAP", 24*time.Hour),
+		JWTAcceptHMAC:  getBoolEnv("AUTH_JWT_ACCEPT_HMAC", true),
+
+		ImpersonationRoles:    getListEnv("AUTH_IMPERSONATION_ROLES"),
+		ImpersonationDuration: getDurationEnv("AUTH_IMPERSONATION_DURATION", 10*time.Minute),
+
+		BreachedPasswordsDir: getEnv("AUTH_BREACHED_PASSWORDS_DIR", ""),
+
+		Argon2Time:    getIntEnv("AUTH_ARGON2_TIME", 1),
+		Argon2Memory:  getIntEnv("AUTH_ARGON2_MEMORY", 64*1024),
+		Argon2Threads: getIntEnv("AUTH_ARGON2_THREADS", 4),
+	}
+}
+
+// Helper functions for environment variables
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+func getBoolEnv(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+// getListEnv reads a comma-separated list, skipping empty items
+func getListEnv(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getMapEnv reads a comma-separated list of key=value pairs, skipping malformed items
+func getMapEnv(key string) map[string]string {
+	values := make(map[string]string)
+	for _, item := range getListEnv(key) {
+		k, v, ok := strings.Cut(item, "=")
+		if k, v = strings.TrimSpace(k), strings.TrimSpace(v); ok && k != "" && v != "" {
+			values[k] = v
+		}
+	}
+	return values
+}
@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis keys of the token denylist
+const (
+	deniedTokenKeyPrefix = "kc:auth:denied:"       // + token ID (jti)
+	watermarkKeyPrefix   = "kc:auth:watermark-us:" // + user public ID; Unix microseconds
+)
+
+const (
+	// denylistPurgeInterval limits how often expired entries are removed from memory
+	denylistPurgeInterval = time.Minute
+
+	// denylistTimeout bounds Redis requests of the denylist
+	denylistTimeout = 2 * time.Second
+)
+
+// tokenDenylist rejects access tokens before they expire: single tokens by their ID (jti), and
+// all tokens of a user issued before a watermark, which is moved whenever the user's roles,
+// groups or status change. Entries are kept in memory and, if Redis is configured, shared with
+// the other instances through Redis. They are dropped once the tokens they reject have expired.
+type tokenDenylist struct {
+	redis *redis.Client // nil keeps entries in this instance only
+
+	mu         sync.Mutex
+	tokens     map[string]time.Time // token ID -> expiry of the token
+	watermarks map[string]watermark // user public ID -> watermark
+	purgedAt   time.Time
+}
+
+// watermark rejects the tokens of a user issued before a time
+type watermark struct {
+	issuedBefore int64 // Unix microseconds
+	expiresAt    time.Time
+}
+
+// newTokenDenylist creates a denylist, shared through Redis if a client is given
+func newTokenDenylist(rdb *redis.Client) *tokenDenylist {
+	return &tokenDenylist{
+		redis:      rdb,
+		tokens:     make(map[string]time.Time),
+		watermarks: make(map[string]watermark),
+		purgedAt:   time.Now(),
+	}
+}
+
+// DenyToken rejects a single access token until it expires
+func (d *tokenDenylist) DenyToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if tokenID == "" || ttl <= 0 {
+		return nil
+	}
+
+	d.mu.Lock()
+	d.tokens[tokenID] = expiresAt
+	d.purge()
+	d.mu.Unlock()
+
+	if d.redis == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, denylistTimeout)
+	defer cancel()
+	return d.redis.Set(ctx, deniedTokenKeyPrefix+tokenID, 1, ttl).Err()
+}
+
+// DenyUserTokens rejects the access tokens of a user issued before now. Tokens issued afterwards,
+// even within the same second, are accepted, so a user re-authenticating right away isn't rejected.
+func (d *tokenDenylist) DenyUserTokens(ctx context.Context, userID string, now time.Time) error {
+	issuedBefore := now.UnixMicro()
+	// Tokens issued before the watermark have expired by then
+	expiresAt := now.Add(AccessTokenDuration + time.Second)
+
+	d.mu.Lock()
+	if current, ok := d.watermarks[userID]; !ok || current.issuedBefore < issuedBefore {
+		d.watermarks[userID] = watermark{issuedBefore: issuedBefore, expiresAt: expiresAt}
+	}
+	d.purge()
+	d.mu.Unlock()
+
+	if d.redis == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, denylistTimeout)
+	defer cancel()
+	return d.redis.Set(ctx, watermarkKeyPrefix+userID, issuedBefore, time.Until(expiresAt)).Err()
+}
+
+// IsDenied reports whether an access token was revoked. If Redis cannot be reached,
+// only the entries of this instance are checked.
+func (d *tokenDenylist) IsDenied(ctx context.Context, claims *TokenClaims) bool {
+	if d.isDeniedLocally(claims) {
+		return true
+	}
+	if d.redis == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, denylistTimeout)
+	defer cancel()
+	values, err := d.redis.MGet(ctx, deniedTokenKeyPrefix+claims.TokenID, watermarkKeyPrefix+claims.UserID).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Printf("[WARN] Failed to check the token denylist in Redis: %v", err)
+		return false
+	}
+	if len(values) != 2 {
+		return false
+	}
+	if values[0] != nil && claims.TokenID != "" {
+		return true
+	}
+	if s, ok := values[1].(string); ok {
+		issuedBefore, err := strconv.ParseInt(s, 10, 64)
+		return err == nil && claims.issuedAtMicros() < issuedBefore
+	}
+	return false
+}
+
+func (d *tokenDenylist) isDeniedLocally(claims *TokenClaims) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.tokens[claims.TokenID]; ok && claims.TokenID != "" {
+		return true
+	}
+	if mark, ok := d.watermarks[claims.UserID]; ok && claims.issuedAtMicros() < mark.issuedBefore {
+		return true
+	}
+	return false
+}
+
+// issuedAtMicros returns the issue time of a token in Unix microseconds. Tokens without iat_us
+// count from the start of the second in iat, so a watermark set within that second rejects them.
+func (c *TokenClaims) issuedAtMicros() int64 {
+	if c.IssuedAtMicros != 0 {
+		return c.IssuedAtMicros
+	}
+	return c.IssuedAt * int64(time.Second/time.Microsecond)
+}
+
+// purge removes expired entries from memory, at most every denylistPurgeInterval. Callers hold mu.
+func (d *tokenDenylist) purge() {
+	now := time.Now()
+	if now.Sub(d.purgedAt) < denylistPurgeInterval {
+		return
+	}
+	d.purgedAt = now
+
+	for tokenID, expiresAt := range d.tokens {
+		if !expiresAt.After(now) {
+			delete(d.tokens, tokenID)
+		}
+	}
+	for userID, mark := range d.watermarks {
+		if !mark.expiresAt.After(now) {
+			delete(d.watermarks, userID)
+		}
+	}
+}
+
+// IsTokenRevoked reports whether an access token was revoked before its expiry, by logout
+// or because the user's roles, groups or status changed since it was issued. Impersonation
+// tokens are also revoked by changes to the impersonating administrator.
+func (s *service) IsTokenRevoked(ctx context.Context, claims *TokenClaims) bool {
+	if s.denylist.IsDenied(ctx, claims) {
+		return true
+	}
+	return claims.Actor != nil && s.denylist.IsDenied(ctx, &TokenClaims{UserID: claims.Actor.UserID, IssuedAt: claims.IssuedAt, IssuedAtMicros: claims.IssuedAtMicros})
+}
+
+// RevokeAccessToken revokes a single access token, e.g. on logout. Invalid tokens are ignored.
+func (s *service) RevokeAccessToken(ctx context.Context, tokenString string) error {
+	claims, err := s.ValidateAccessToken(tokenString)
+	if err != nil {
+		return nil
+	}
+	return s.denylist.DenyToken(ctx, claims.TokenID, time.Unix(claims.ExpireAt, 0))
+}
+
+// RevokeUserTokens revokes the access tokens issued so far to users whose authorization changed.
+// Their clients get new tokens with the current roles on the next refresh.
+func (s *service) RevokeUserTokens(ctx context.Context, userIDs ...int) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	publicIDs, err := s.repo.GetUserPublicIDs(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, publicID := range publicIDs {
+		if err := s.denylist.DenyUserTokens(ctx, publicID, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeUserTokens revokes the access tokens of a user, logging failures. It is used after
+// changes that already succeeded and should not fail because of the denylist.
+func (s *service) revokeUserTokens(ctx context.Context, userPublicID string) {
+	if err := s.denylist.DenyUserTokens(ctx, userPublicID, time.Now()); err != nil {
+		log.Printf("[WARN] Failed to revoke access tokens of user %s: %v", userPublicID, err)
+	}
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenDenylist(t *testing.T) {
+	ctx := context.Background()
+	d := newTokenDenylist(nil)
+	now := time.Now()
+
+	token := &TokenClaims{UserID: "user-1", TokenID: "token-1", IssuedAt: now.Unix()}
+	other := &TokenClaims{UserID: "user-1", TokenID: "token-2", IssuedAt: now.Unix()}
+	if d.IsDenied(ctx, token) {
+		t.Fatal("token denied before revocation")
+	}
+
+	if err := d.DenyToken(ctx, token.TokenID, now.Add(time.Minute)); err != nil {
+		t.Fatalf("DenyToken failed: %v", err)
+	}
+	if !d.IsDenied(ctx, token) {
+		t.Error("revoked token accepted")
+	}
+	if d.IsDenied(ctx, other) {
+		t.Error("another token of the user denied")
+	}
+
+	// Tokens without an ID are not matched by the entries of other tokens
+	if d.IsDenied(ctx, &TokenClaims{UserID: "user-2", IssuedAt: now.Unix()}) {
+		t.Error("token without ID denied")
+	}
+
+	// Already expired tokens are not stored
+	if err := d.DenyToken(ctx, "token-3", now.Add(-time.Second)); err != nil {
+		t.Fatalf("DenyToken failed: %v", err)
+	}
+	if _, ok := d.tokens["token-3"]; ok {
+		t.Error("expired token stored")
+	}
+}
+
+func TestTokenDenylist_Watermark(t *testing.T) {
+	ctx := context.Background()
+	d := newTokenDenylist(nil)
+	now := time.Unix(1700000000, 500_000_000)
+
+	if err := d.DenyUserTokens(ctx, "user-1", now); err != nil {
+		t.Fatalf("DenyUserTokens failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		claims   *TokenClaims
+		expected bool
+	}{
+		{"issued before", &TokenClaims{UserID: "user-1", IssuedAt: now.Unix() - 60}, true},
+		{"issued earlier in the same second", &TokenClaims{UserID: "user-1", IssuedAt: now.Unix(), IssuedAtMicros: now.UnixMicro() - 1}, true},
+		{"issued later in the same second", &TokenClaims{UserID: "user-1", IssuedAt: now.Unix(), IssuedAtMicros: now.UnixMicro() + 1}, false},
+		{"issued at the change", &TokenClaims{UserID: "user-1", IssuedAt: now.Unix(), IssuedAtMicros: now.UnixMicro()}, false},
+		{"legacy token issued in the same second", &TokenClaims{UserID: "user-1", IssuedAt: now.Unix()}, true},
+		{"issued after", &TokenClaims{UserID: "user-1", IssuedAt: now.Unix() + 1}, false},
+		{"other user", &TokenClaims{UserID: "user-2", IssuedAt: now.Unix() - 60}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.IsDenied(ctx, tt.claims); got != tt.expected {
+				t.Errorf("IsDenied = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+
+	// An older watermark does not move it back
+	if err := d.DenyUserTokens(ctx, "user-1", now.Add(-time.Hour)); err != nil {
+		t.Fatalf("DenyUserTokens failed: %v", err)
+	}
+	if !d.IsDenied(ctx, tests[1].claims) {
+		t.Error("watermark moved back")
+	}
+}
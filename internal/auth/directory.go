@@ -0,0 +1,540 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"kc-api/internal/ldap"
+)
+
+// SyncDirectory synchronizes users from the directory now and reports the changes
+func (s *service) SyncDirectory(ctx context.Context, dryRun bool) (*DirectorySyncReport, error) {
+	if s.syncer == nil {
+		return nil, ErrLDAPNotConfigured
+	}
+	return s.syncer.Run(ctx, dryRun)
+}
+
+// checkPassword verifies the password of a login and returns the authenticated user, or nil if the
+// credentials are invalid. Directory users, and unknown login IDs if a directory is configured,
+// are checked against the directory; other users against their local password, whose hash is
+// upgraded when it is weaker than the configured parameters.
+func (s *service) checkPassword(ctx context.Context, loginID, password string, user *AuthUser) (*AuthUser, error) {
+	if user != nil && user.IsServiceAccount {
+		return nil, nil
+	}
+
+	if s.directory != nil && (user == nil || user.DirectoryDN != "") {
+		return s.directoryLogin(ctx, loginID, password, user)
+	}
+
+	if user == nil || user.PasswordHash == "" || !s.verifyPassword(password, user.PasswordHash) {
+		return nil, nil
+	}
+	s.upgradePasswordHash(ctx, user, password)
+	return user, nil
+}
+
+// directoryLogin binds to the directory as the user. On success the user is brought up to date
+// with their directory entry, or created on their first login.
+func (s *service) directoryLogin(ctx context.Context, loginID, password string, user *AuthUser) (*AuthUser, error) {
+	// The login ID may have been an email address
+	if user != nil {
+		loginID = user.LoginID
+	}
+
+	entry, err := s.directory.Authenticate(ctx, loginID, password)
+	if errors.Is(err, ldap.ErrInvalidCredentials) {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("[WARN] Directory login failed: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrLDAPUnavailable, err)
+	}
+	if entry.Disabled {
+		return nil, nil
+	}
+
+	synced, err := s.syncer.SyncEntry(ctx, entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync directory user: %w", err)
+	}
+
+	authenticated, err := s.getUserByInternalID(ctx, synced.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return authenticated, nil
+}
+
+// directorySyncer synchronizes users, their departments and their group memberships
+// from the LDAP directory
+type directorySyncer struct {
+	repo      Repository
+	directory ldap.Directory
+	denylist  *tokenDenylist
+	config    *Config
+	running   sync.Mutex
+}
+
+// directoryRun holds the state of one sync
+type directoryRun struct {
+	dryRun      bool
+	revoke      bool // revoke the access tokens of users whose email or groups changed
+	report      *DirectorySyncReport
+	departments map[string]*int // by directory key; nil for departments a dry run would create
+	groups      map[string]*Group
+}
+
+// newDirectorySyncer creates a directory syncer
+func newDirectorySyncer(repo Repository, directory ldap.Directory, denylist *tokenDenylist, cfg *Config) *directorySyncer {
+	return &directorySyncer{repo: repo, directory: directory, denylist: denylist, config: cfg}
+}
+
+func newDirectoryRun(dryRun bool) *directoryRun {
+	return &directoryRun{
+		dryRun: dryRun,
+		report: &DirectorySyncReport{
+			DryRun:             dryRun,
+			Created:            []DirectoryUserChange{},
+			Updated:            []DirectoryUserChange{},
+			Deleted:            []string{},
+			Skipped:            []DirectoryUserChange{},
+			DepartmentsCreated: []string{},
+			MembershipsAdded:   []DirectoryMembershipDiff{},
+			MembershipsRemoved: []DirectoryMembershipDiff{},
+			StartedAt:          time.Now(),
+		},
+		departments: make(map[string]*int),
+		groups:      make(map[string]*Group),
+	}
+}
+
+// Start runs the sync every interval in the background
+func (d *directorySyncer) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			report, err := d.Run(ctx, false)
+			cancel()
+
+			if err != nil {
+				log.Printf("[WARN] Directory sync failed: %v", err)
+			}
+			if report != nil {
+				log.Printf("Directory sync: %d created, %d updated, %d deleted, %d skipped, %d failures",
+					len(report.Created), len(report.Updated), len(report.Deleted), len(report.Skipped), report.Failures)
+			}
+		}
+	}()
+}
+
+// Run synchronizes all users of the directory. Users no longer in the directory, or disabled there,
+// are soft-deleted and their sessions revoked, unless entries without a login ID make it unknown
+// who left. A dry run only reports the changes.
+func (d *directorySyncer) Run(ctx context.Context, dryRun bool) (*DirectorySyncReport, error) {
+	if !d.running.TryLock() {
+		return nil, ErrLDAPSyncRunning
+	}
+	defer d.running.Unlock()
+
+	entries, err := d.directory.Users(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLDAPUnavailable, err)
+	}
+	// An empty result is more likely a broken filter than an empty company; don't delete everyone
+	if len(entries) == 0 {
+		return nil, ErrLDAPDirectoryEmpty
+	}
+
+	existing, err := d.repo.ListDirectoryUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list directory users: %w", err)
+	}
+	byLoginID := make(map[string]*DirectoryUser, len(existing))
+	for i := range existing {
+		byLoginID[strings.ToLower(existing[i].LoginID)] = &existing[i]
+	}
+
+	run := newDirectoryRun(dryRun)
+	run.revoke = !dryRun
+	defer func() { run.report.FinishedAt = time.Now() }()
+	run.report.DirectoryUsers = len(entries)
+
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if entry.LoginID == "" {
+			run.report.Skipped = append(run.report.Skipped, DirectoryUserChange{LoginID: entry.DN, Fields: []string{"missing login ID"}})
+			run.report.DeletionsSkipped = true
+			continue
+		}
+		key := strings.ToLower(entry.LoginID)
+		if entry.Disabled || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if _, err := d.syncUser(ctx, run, entry, byLoginID[key]); err != nil {
+			log.Printf("[WARN] Failed to sync directory user %s: %v", entry.LoginID, err)
+			run.report.Failures++
+		}
+	}
+
+	if run.report.DeletionsSkipped {
+		log.Printf("[WARN] Directory entries without a login ID, not deleting users missing from the directory")
+		return run.report, nil
+	}
+
+	for key, user := range byLoginID {
+		if seen[key] {
+			continue
+		}
+		if err := d.deleteUser(ctx, run, user); err != nil {
+			log.Printf("[WARN] Failed to delete directory user %s: %v", user.LoginID, err)
+			run.report.Failures++
+		}
+	}
+
+	return run.report, nil
+}
+
+// SyncEntry brings a single user up to date with their directory entry, creating them if needed.
+// It is used when a directory user logs in, so other access tokens of the user are left alone.
+func (d *directorySyncer) SyncEntry(ctx context.Context, entry *ldap.Entry) (*DirectoryUser, error) {
+	run := newDirectoryRun(false)
+	user, err := d.syncUser(ctx, run, *entry, nil)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("directory user %s was skipped: %s", entry.LoginID, strings.Join(run.report.Skipped[0].Fields, ", "))
+	}
+	return user, nil
+}
+
+// syncUser creates or updates the user of a directory entry and their group memberships.
+// Without a known directory user, the user is looked up by login ID; a local user is only
+// taken over by the directory if linking is enabled and their verified email matches the
+// entry. Returns nil if the entry was skipped.
+func (d *directorySyncer) syncUser(ctx context.Context, run *directoryRun, entry ldap.Entry, user *DirectoryUser) (*DirectoryUser, error) {
+	email := strings.TrimSpace(entry.Email)
+	if !isValidEmail(email) {
+		d.skip(run, entry.LoginID, "missing or invalid email")
+		return nil, nil
+	}
+
+	if user == nil {
+		local, err := d.repo.GetDirectoryUser(ctx, entry.LoginID)
+		if err == nil {
+			user = local
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+	}
+	if user != nil && user.IsServiceAccount {
+		d.skip(run, entry.LoginID, "login ID belongs to a service account")
+		return nil, nil
+	}
+	if user != nil && user.DirectoryDN == "" && !d.linkable(user, email) {
+		d.skip(run, entry.LoginID, "login ID belongs to a local user")
+		return nil, nil
+	}
+
+	if user == nil || !strings.EqualFold(user.Email, email) {
+		other, err := d.repo.GetUserByEmail(ctx, email)
+		if err == nil && (user == nil || other.ID != user.ID) {
+			d.skip(run, entry.LoginID, "email used by another user")
+			return nil, nil
+		} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to check email: %w", err)
+		}
+	}
+
+	deptID, pendingDept, err := d.department(ctx, run, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	revoke := false
+	if user == nil {
+		user = &DirectoryUser{LoginID: entry.LoginID, Email: email, DirectoryDN: entry.DN, DeptID: deptID}
+		if user.Name, _, err = d.name(nil, entry); err != nil {
+			return nil, err
+		}
+		run.report.Created = append(run.report.Created, DirectoryUserChange{LoginID: entry.LoginID})
+		if !run.dryRun {
+			if err := d.repo.CreateDirectoryUser(ctx, user); err != nil {
+				return nil, fmt.Errorf("failed to create user: %w", err)
+			}
+
+			// Add user to 'public' group
+			publicGroup, err := d.repo.GetGroupByPublicID(ctx, PublicGroupID)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return nil, ErrPublicGroupNotFound
+				}
+				return nil, fmt.Errorf("failed to get public group: %w", err)
+			}
+			if err := d.repo.AddUserToGroup(ctx, user.ID, publicGroup.ID, nil); err != nil {
+				return nil, fmt.Errorf("failed to add user to public group: %w", err)
+			}
+		}
+	} else {
+		var changed []string
+		revoke = user.Email != email
+		if user.Email != email {
+			user.Email = email
+			changed = append(changed, "email")
+		}
+		name, nameChanged, err := d.name(user.Name, entry)
+		if err != nil {
+			return nil, err
+		}
+		if nameChanged {
+			user.Name = name
+			changed = append(changed, "name")
+		}
+		if d.config.LDAPDepartmentSource != DepartmentSourceNone && (pendingDept || !equalIntPtr(user.DeptID, deptID)) {
+			user.DeptID = deptID
+			changed = append(changed, "department")
+		}
+		if user.DirectoryDN != entry.DN {
+			user.DirectoryDN = entry.DN
+			changed = append(changed, "directory_dn")
+		}
+
+		if len(changed) > 0 {
+			run.report.Updated = append(run.report.Updated, DirectoryUserChange{LoginID: user.LoginID, Fields: changed})
+			if !run.dryRun {
+				if err := d.repo.UpdateDirectoryUser(ctx, user); err != nil {
+					return nil, fmt.Errorf("failed to update user: %w", err)
+				}
+			}
+		}
+	}
+
+	memberships := len(run.report.MembershipsAdded) + len(run.report.MembershipsRemoved)
+	if err := d.syncGroups(ctx, run, user, entry.Groups); err != nil {
+		return nil, err
+	}
+	if run.revoke && (revoke || len(run.report.MembershipsAdded)+len(run.report.MembershipsRemoved) > memberships) {
+		d.revokeTokens(ctx, user.PublicID)
+	}
+	return user, nil
+}
+
+// deleteUser soft-deletes a user who left the directory and ends their sessions
+func (d *directorySyncer) deleteUser(ctx context.Context, run *directoryRun, user *DirectoryUser) error {
+	run.report.Deleted = append(run.report.Deleted, user.LoginID)
+	if run.dryRun {
+		return nil
+	}
+
+	if err := d.repo.SoftDeleteUser(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := d.repo.RevokeAllUserTokens(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	d.revokeTokens(ctx, user.PublicID)
+	log.Printf("Deleted user %s who left the directory", user.PublicID)
+	return nil
+}
+
+// revokeTokens revokes the access tokens of a user whose claims or groups changed
+func (d *directorySyncer) revokeTokens(ctx context.Context, userPublicID string) {
+	if err := d.denylist.DenyUserTokens(ctx, userPublicID, time.Now()); err != nil {
+		log.Printf("[WARN] Failed to revoke access tokens of user %s: %v", userPublicID, err)
+	}
+}
+
+// department returns the department of a directory entry, creating missing departments.
+// With the OU source, nested OUs become nested departments. pending is set if a dry run
+// would create the department.
+func (d *directorySyncer) department(ctx context.Context, run *directoryRun, entry ldap.Entry) (id *int, pending bool, err error) {
+	var path []string
+	switch d.config.LDAPDepartmentSource {
+	case DepartmentSourceAttribute:
+		if department := strings.TrimSpace(entry.Department); department != "" {
+			path = []string{department}
+		}
+	case DepartmentSourceOU:
+		path = entry.OrganizationalUnits
+	}
+
+	for i := range path {
+		display := strings.Join(path[:i+1], "/")
+		key := d.config.LDAPDepartmentSource + ":" + strings.ToLower(display)
+
+		if cached, ok := run.departments[key]; ok {
+			id, pending = cached, cached == nil
+			continue
+		}
+
+		deptID, err := d.repo.GetDirectoryDepartmentID(ctx, key)
+		if errors.Is(err, sql.ErrNoRows) {
+			run.report.DepartmentsCreated = append(run.report.DepartmentsCreated, display)
+			if run.dryRun || pending {
+				run.departments[key] = nil
+				id, pending = nil, true
+				continue
+			}
+
+			name, err := json.Marshal(map[string]string{d.config.LDAPNameLocale: path[i]})
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to encode department name: %w", err)
+			}
+			deptID, err = d.repo.CreateDirectoryDepartment(ctx, key, name, id)
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to create department %s: %w", display, err)
+			}
+		} else if err != nil {
+			return nil, false, fmt.Errorf("failed to get department %s: %w", display, err)
+		}
+
+		run.departments[key] = &deptID
+		id = &deptID
+	}
+	return id, pending, nil
+}
+
+// name returns the user's name with the directory's display name set for the configured locale.
+// Names in other locales are kept.
+func (d *directorySyncer) name(current json.RawMessage, entry ldap.Entry) (json.RawMessage, bool, error) {
+	displayName := strings.TrimSpace(entry.Name)
+	if displayName == "" {
+		if current != nil {
+			return current, false, nil
+		}
+		displayName = entry.LoginID
+	}
+
+	names := make(map[string]string)
+	if current != nil {
+		if err := json.Unmarshal(current, &names); err != nil {
+			names = make(map[string]string)
+		}
+	}
+	if names[d.config.LDAPNameLocale] == displayName {
+		return current, false, nil
+	}
+	names[d.config.LDAPNameLocale] = displayName
+
+	name, err := json.Marshal(names)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encode name: %w", err)
+	}
+	return name, true, nil
+}
+
+// syncGroups makes the user a member of exactly those mapped groups whose directory groups
+// they belong to. Groups without a mapping are not touched.
+func (d *directorySyncer) syncGroups(ctx context.Context, run *directoryRun, user *DirectoryUser, directoryGroups []string) error {
+	if len(d.config.LDAPGroupMapping) == 0 {
+		return nil
+	}
+
+	// Several directory groups may map to the same group
+	member := make(map[string]bool, len(d.config.LDAPGroupMapping))
+	for directoryGroup, groupID := range d.config.LDAPGroupMapping {
+		member[groupID] = member[groupID] || containsFold(directoryGroups, directoryGroup)
+	}
+
+	current := make(map[string]bool)
+	if user.ID != 0 {
+		groups, err := d.repo.GetUserGroups(ctx, user.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get groups: %w", err)
+		}
+		for _, group := range groups {
+			current[group.PublicID] = true
+		}
+	}
+
+	for groupID, isMember := range member {
+		if current[groupID] == isMember {
+			continue
+		}
+
+		group, err := d.group(ctx, run, groupID)
+		if err != nil {
+			return err
+		}
+		if group == nil {
+			continue
+		}
+
+		diff := DirectoryMembershipDiff{LoginID: user.LoginID, Group: groupID}
+		if isMember {
+			run.report.MembershipsAdded = append(run.report.MembershipsAdded, diff)
+		} else {
+			run.report.MembershipsRemoved = append(run.report.MembershipsRemoved, diff)
+		}
+		if run.dryRun {
+			continue
+		}
+
+		if isMember {
+			err = d.repo.AddUserToGroup(ctx, user.ID, group.ID, nil)
+		} else {
+			err = d.repo.RemoveUserFromGroup(ctx, user.ID, group.ID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update membership of group %s: %w", groupID, err)
+		}
+	}
+	return nil
+}
+
+// group returns a mapped group, or nil if it does not exist
+func (d *directorySyncer) group(ctx context.Context, run *directoryRun, groupID string) (*Group, error) {
+	if group, ok := run.groups[groupID]; ok {
+		return group, nil
+	}
+
+	group, err := d.repo.GetGroupByPublicID(ctx, groupID)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Printf("[WARN] Group %q of AUTH_LDAP_GROUP_MAPPING does not exist", groupID)
+		group = nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+	run.groups[groupID] = group
+	return group, nil
+}
+
+// linkable reports whether a local user may be taken over by the directory entry with the email.
+// Anyone able to create a directory entry could otherwise take over a local account by its login ID.
+func (d *directorySyncer) linkable(user *DirectoryUser, email string) bool {
+	return d.config.LDAPLinkLocalUsers && user.EmailVerified && strings.EqualFold(user.Email, email)
+}
+
+func (d *directorySyncer) skip(run *directoryRun, loginID, reason string) {
+	run.report.Skipped = append(run.report.Skipped, DirectoryUserChange{LoginID: loginID, Fields: []string{reason}})
+}
+
+func equalIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func containsFold(values []string, s string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, s) {
+			return true
+		}
+	}
+	return false
+}
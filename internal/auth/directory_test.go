@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"kc-api/internal/ldap"
+)
+
+// fakeDirectoryRepository keeps users in memory for the directory sync. Repository methods
+// the sync doesn't use are left to the nil embedded interface.
+type fakeDirectoryRepository struct {
+	Repository
+	users   []*DirectoryUser
+	deleted []string // login IDs
+}
+
+func (r *fakeDirectoryRepository) ListDirectoryUsers(ctx context.Context) ([]DirectoryUser, error) {
+	var users []DirectoryUser
+	for _, user := range r.users {
+		if user.DirectoryDN != "" && !slices.Contains(r.deleted, user.LoginID) {
+			users = append(users, *user)
+		}
+	}
+	return users, nil
+}
+
+func (r *fakeDirectoryRepository) GetDirectoryUser(ctx context.Context, loginID string) (*DirectoryUser, error) {
+	for _, user := range r.users {
+		if strings.EqualFold(user.LoginID, loginID) && !slices.Contains(r.deleted, user.LoginID) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *fakeDirectoryRepository) GetUserByEmail(ctx context.Context, email string) (*AuthUser, error) {
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			return &AuthUser{ID: user.ID, LoginID: user.LoginID, Email: user.Email}, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *fakeDirectoryRepository) CreateDirectoryUser(ctx context.Context, user *DirectoryUser) error {
+	user.ID = len(r.users) + 1
+	user.PublicID = "user-" + user.LoginID
+	copied := *user
+	r.users = append(r.users, &copied)
+	return nil
+}
+
+func (r *fakeDirectoryRepository) UpdateDirectoryUser(ctx context.Context, user *DirectoryUser) error {
+	for i, existing := range r.users {
+		if existing.ID == user.ID {
+			copied := *user
+			r.users[i] = &copied
+		}
+	}
+	return nil
+}
+
+func (r *fakeDirectoryRepository) SoftDeleteUser(ctx context.Context, userID int) error {
+	for _, user := range r.users {
+		if user.ID == userID {
+			r.deleted = append(r.deleted, user.LoginID)
+		}
+	}
+	return nil
+}
+
+func (r *fakeDirectoryRepository) RevokeAllUserTokens(ctx context.Context, userID int) error {
+	return nil
+}
+
+func (r *fakeDirectoryRepository) GetGroupByPublicID(ctx context.Context, publicID string) (*Group, error) {
+	return &Group{ID: 1, PublicID: publicID}, nil
+}
+
+func (r *fakeDirectoryRepository) AddUserToGroup(ctx context.Context, userID, groupID int, assignedBy *int) error {
+	return nil
+}
+
+func (r *fakeDirectoryRepository) user(loginID string) *DirectoryUser {
+	for _, user := range r.users {
+		if user.LoginID == loginID {
+			return user
+		}
+	}
+	return nil
+}
+
+// fakeDirectory returns fixed entries
+type fakeDirectory struct {
+	entries []ldap.Entry
+	err     error
+}
+
+func (d *fakeDirectory) Authenticate(ctx context.Context, loginID, password string) (*ldap.Entry, error) {
+	return nil, ldap.ErrInvalidCredentials
+}
+
+func (d *fakeDirectory) Users(ctx context.Context) ([]ldap.Entry, error) {
+	return d.entries, d.err
+}
+
+// newTestDirectoryRepository returns the directory users jdoe and left, and the local users
+// alice (verified email) and bob (unverified email)
+func newTestDirectoryRepository() *fakeDirectoryRepository {
+	return &fakeDirectoryRepository{users: []*DirectoryUser{
+		{ID: 1, PublicID: "user-jdoe", LoginID: "jdoe", Email: "jane.doe@example.com", DirectoryDN: "cn=Jane Doe,dc=example,dc=com"},
+		{ID: 2, PublicID: "user-left", LoginID: "left", Email: "left@example.com", DirectoryDN: "cn=Left,dc=example,dc=com"},
+		{ID: 3, PublicID: "user-alice", LoginID: "alice", Email: "alice@example.com", EmailVerified: true},
+		{ID: 4, PublicID: "user-bob", LoginID: "bob", Email: "bob@example.com"},
+	}}
+}
+
+func testDirectoryEntries() []ldap.Entry {
+	return []ldap.Entry{
+		{DN: "cn=Jane Doe,dc=example,dc=com", LoginID: "jdoe", Email: "jane.doe@example.com", Name: "Jane Doe"},
+		{DN: "cn=Alice,dc=example,dc=com", LoginID: "ALICE", Email: "alice@example.com", Name: "Alice"},
+		{DN: "cn=Bob,dc=example,dc=com", LoginID: "bob", Email: "bob@example.com", Name: "Bob"},
+		{DN: "cn=New,dc=example,dc=com", LoginID: "new", Email: "new@example.com", Name: "New"},
+	}
+}
+
+func TestDirectorySyncer_Run(t *testing.T) {
+	tests := []struct {
+		name           string
+		linkLocalUsers bool
+		dryRun         bool
+		wantUpdated    []string
+		wantSkipped    []string
+		wantDeleted    []string
+	}{
+		{
+			name:        "local users are not taken over",
+			wantUpdated: []string{"jdoe"},
+			wantSkipped: []string{"ALICE", "bob"},
+			wantDeleted: []string{"left"},
+		},
+		{
+			name:           "linking takes over local users with a verified email",
+			linkLocalUsers: true,
+			wantUpdated:    []string{"jdoe", "alice"},
+			wantSkipped:    []string{"bob"},
+			wantDeleted:    []string{"left"},
+		},
+		{
+			name:        "dry run",
+			dryRun:      true,
+			wantUpdated: []string{"jdoe"},
+			wantSkipped: []string{"ALICE", "bob"},
+			wantDeleted: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestDirectoryRepository()
+			cfg := &Config{LDAPDepartmentSource: DepartmentSourceNone, LDAPNameLocale: "en-US", LDAPLinkLocalUsers: tt.linkLocalUsers}
+			syncer := newDirectorySyncer(repo, &fakeDirectory{entries: testDirectoryEntries()}, newTokenDenylist(nil), cfg)
+
+			report, err := syncer.Run(context.Background(), tt.dryRun)
+			if err != nil {
+				t.Fatalf("sync failed: %v", err)
+			}
+
+			var updated, skipped []string
+			for _, change := range report.Updated {
+				updated = append(updated, change.LoginID)
+			}
+			for _, change := range report.Skipped {
+				skipped = append(skipped, change.LoginID)
+			}
+			// jdoe gets a name; the fixtures have none
+			if !slices.Equal(updated, tt.wantUpdated) {
+				t.Errorf("expected updated %v, got %v", tt.wantUpdated, updated)
+			}
+			if !slices.Equal(skipped, tt.wantSkipped) {
+				t.Errorf("expected skipped %v, got %v", tt.wantSkipped, skipped)
+			}
+			if len(report.Created) != 1 || report.Created[0].LoginID != "new" {
+				t.Errorf("expected new to be created, got %v", report.Created)
+			}
+			if !slices.Equal(report.Deleted, []string{"left"}) {
+				t.Errorf("expected left to be reported as deleted, got %v", report.Deleted)
+			}
+			if !slices.Equal(repo.deleted, tt.wantDeleted) {
+				t.Errorf("expected deleted %v, got %v", tt.wantDeleted, repo.deleted)
+			}
+
+			// Skipped local users keep their account as it was
+			if bob := repo.user("bob"); bob.DirectoryDN != "" {
+				t.Errorf("expected bob to stay a local user, got DN %q", bob.DirectoryDN)
+			}
+			alice := repo.user("alice")
+			if linked := alice.DirectoryDN != ""; linked != (tt.linkLocalUsers && !tt.dryRun) {
+				t.Errorf("expected alice linked: %v, got DN %q", tt.linkLocalUsers && !tt.dryRun, alice.DirectoryDN)
+			}
+		})
+	}
+}
+
+func TestDirectorySyncer_RunIncomplete(t *testing.T) {
+	cfg := &Config{LDAPDepartmentSource: DepartmentSourceNone, LDAPNameLocale: "en-US"}
+
+	t.Run("entries without login ID", func(t *testing.T) {
+		repo := newTestDirectoryRepository()
+		entries := append(testDirectoryEntries(), ldap.Entry{DN: "cn=Left,dc=example,dc=com", Email: "left@example.com"})
+		syncer := newDirectorySyncer(repo, &fakeDirectory{entries: entries}, newTokenDenylist(nil), cfg)
+
+		report, err := syncer.Run(context.Background(), false)
+		if err != nil {
+			t.Fatalf("sync failed: %v", err)
+		}
+		if !report.DeletionsSkipped {
+			t.Error("expected deletions to be skipped")
+		}
+		if len(report.Deleted) != 0 || len(repo.deleted) != 0 {
+			t.Errorf("expected no deletions, got %v", repo.deleted)
+		}
+		if len(report.Created) != 1 {
+			t.Errorf("expected the other entries to be synced, got %v", report.Created)
+		}
+	})
+
+	t.Run("directory errors", func(t *testing.T) {
+		for _, directory := range []*fakeDirectory{
+			{err: ldap.ErrUnavailable},
+			{entries: testDirectoryEntries()[:2], err: ldap.ErrUnavailable}, // cut off by the server
+			{},
+		} {
+			repo := newTestDirectoryRepository()
+			syncer := newDirectorySyncer(repo, directory, newTokenDenylist(nil), cfg)
+
+			report, err := syncer.Run(context.Background(), false)
+			if !errors.Is(err, ErrLDAPUnavailable) && !errors.Is(err, ErrLDAPDirectoryEmpty) {
+				t.Errorf("expected the sync to fail, got %v", err)
+			}
+			if report != nil || len(repo.deleted) != 0 {
+				t.Errorf("expected no changes, got deleted %v", repo.deleted)
+			}
+		}
+	})
+}
@@ -5,21 +5,25 @@ import (
 	"errors"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"kc-api/internal/password"
 	"kc-api/internal/utils"
 )
 
 // Handler handles HTTP requests for authentication operations
 type Handler struct {
-	service Service
+	service   Service
+	clientIPs *ClientIPResolver
 }
 
-// NewHandler creates a new auth handler with the given service
-func NewHandler(service Service) *Handler {
-	return &Handler{service: service}
+// NewHandler creates a new auth handler with the given service.
+// The client IP resolver is optional; when nil, forwarding headers are ignored.
+func NewHandler(service Service, clientIPs *ClientIPResolver) *Handler {
+	return &Handler{service: service, clientIPs: clientIPs}
 }
 
 // RegisterRoutes registers auth routes on the given router
@@ -29,24 +33,75 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 		r.Post("/login", h.Login)
 		r.Post("/refresh", h.Refresh)
 		r.Post("/logout", h.Logout)
+		r.Post("/mfa/verify", h.VerifyMFA)
+		r.Post("/mfa/enroll", h.EnrollMFA)
+		r.Post("/password/forgot", h.ForgotPassword)
+		r.Post("/password/reset", h.ResetPassword)
+		r.Post("/password/change", h.ChangeExpiredPassword)
+		r.Post("/email/verify", h.VerifyEmail)
+		r.Get("/oidc/authorize", h.StartOIDCLogin)
+		r.Post("/oidc/callback", h.CompleteOIDCLogin)
 	})
+
+	r.Get("/.well-known/jwks.json", h.GetJWKS)
 }
 
 // RegisterProtectedRoutes registers auth routes that require authentication
 func (h *Handler) RegisterProtectedRoutes(r chi.Router) {
 	r.Get("/auth/me", h.Me)
-	r.Post("/auth/logout-all", h.LogoutAll)
+	r.Post("/auth/impersonation/end", h.EndImpersonation)
+	r.Get("/auth/mfa", h.GetMFAStatus)
+	r.Post("/auth/email/verify/resend", h.ResendVerificationEmail)
+	r.Get("/auth/sessions", h.ListSessions)
+
+	// Credentials, sessions, tokens and administration are off limits while impersonating a user
+	r.Group(func(r chi.Router) {
+		r.Use(DenyImpersonation)
+
+		r.Post("/auth/logout-all", h.LogoutAll)
+		r.Post("/auth/mfa/enroll/confirm", h.ConfirmMFA)
+		r.Post("/auth/mfa/disable", h.DisableMFA)
+		r.Post("/auth/mfa/recovery-codes", h.RegenerateRecoveryCodes)
+		r.Delete("/auth/sessions/{id}", h.RevokeSession)
+		r.Get("/auth/tokens", h.ListAPIKeys)
+		r.Post("/auth/tokens", h.CreateAPIKey)
+		r.Delete("/auth/tokens/{id}", h.RevokeAPIKey)
+
+		// Session, lockout, service account and impersonation administration routes
+		r.Route("/admin/auth", func(r chi.Router) {
+			r.Use(RequireAdmin)
+
+			r.Get("/users/{id}/sessions", h.ListUserSessions)
+			r.Delete("/users/{id}/sessions/{sessionId}", h.RevokeUserSession)
+			r.Get("/lockouts", h.ListLockouts)
+			r.Post("/lockouts/unlock", h.UnlockLogin)
+			r.Get("/security-events", h.ListSecurityEvents)
+			r.Get("/service-accounts", h.ListServiceAccounts)
+			r.Post("/service-accounts", h.CreateServiceAccount)
+			r.Get("/service-accounts/{id}/api-keys", h.ListServiceAccountKeys)
+			r.Post("/service-accounts/{id}/api-keys", h.CreateServiceAccountKey)
+			r.Delete("/service-accounts/{id}/api-keys/{keyId}", h.RevokeServiceAccountKey)
+			r.Post("/directory/sync", h.SyncDirectory)
+			r.Get("/signing-keys", h.ListSigningKeys)
+			r.Post("/signing-keys/rotate", h.RotateSigningKey)
+			r.Get("/password-policy", h.GetPasswordPolicy)
+			r.Put("/password-policy", h.UpdatePasswordPolicy)
+			r.Post("/impersonate", h.Impersonate)
+			r.Get("/impersonations", h.ListImpersonations)
+			r.Get("/impersonations/{id}/requests", h.ListImpersonatedRequests)
+		})
+	})
 }
 
 // Register godoc
 // @Summary      Register a new user
-// @Description  Creates a new user account with email, password, and name. The user is automatically added to the 'public' group.
+// @Description  Creates a new user account with email, password, and name. The user is automatically added to the 'public' group. The password must meet the password policy; violations are listed per rule.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        request  body      RegisterRequest  true  "Registration data"
 // @Success      201      {object}  RegisterResponse
-// @Failure      400      {object}  ErrorResponse  "Invalid request or validation error"
+// @Failure      400      {object}  password.ErrorResponse  "Invalid request, validation error or password policy violations"
 // @Failure      409      {object}  ErrorResponse  "Email or login_id already exists"
 // @Failure      500      {object}  ErrorResponse  "Internal server error"
 // @Router       /auth/register [post]
@@ -57,18 +112,21 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	clientIP := getClientIP(r)
+	clientIP := h.clientIPs.ClientIP(r)
 	userAgent := r.UserAgent()
 
 	result, refreshToken, err := h.service.Register(r.Context(), &req, clientIP, userAgent)
 	if err != nil {
+		var policyErr *password.PolicyError
 		switch {
 		case errors.Is(err, ErrInvalidEmail):
 			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid email format")
 		case errors.Is(err, ErrInvalidName):
 			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Name must have at least one locale value")
+		case errors.As(err, &policyErr):
+			utils.RespondJSON(w, http.StatusBadRequest, password.NewErrorResponse(policyErr))
 		case errors.Is(err, ErrInvalidPassword):
-			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Password must be at least 8 characters")
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Password does not meet the password policy")
 		case errors.Is(err, ErrEmailExists):
 			utils.RespondError(w, r, http.StatusConflict, "Conflict", "Email already exists")
 		case errors.Is(err, ErrLoginIDExists):
@@ -81,15 +139,17 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Set refresh token as HTTP-only cookie
-	setRefreshTokenCookie(w, refreshToken)
+	// Set refresh token as HTTP-only cookie (not issued yet if MFA enrollment is required)
+	if refreshToken != "" {
+		setRefreshTokenCookie(w, refreshToken)
+	}
 
 	utils.RespondJSON(w, http.StatusCreated, result)
 }
 
 // Login godoc
 // @Summary      User login
-// @Description  Authenticates a user with login_id/email and password. Returns access token in response body and refresh token as HTTP-only cookie.
+// @Description  Authenticates a user with login_id/email and password. Returns access token in response body and refresh token as HTTP-only cookie. If the user has two-factor authentication enabled, or their roles require it, no tokens are returned. Instead `mfa` contains a short-lived challenge token to complete via /auth/mfa/verify. If the password is older than the password policy allows, `password_change` contains a token to set a new password with via /auth/password/change first. Repeated failures delay further attempts and temporarily lock the login ID or client IP; the Retry-After header tells when to try again. If an LDAP directory is configured, directory users are checked against it and unknown login IDs are looked up there and provisioned on their first login.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -97,7 +157,9 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 // @Success      200      {object}  LoginResponse
 // @Failure      400      {object}  ErrorResponse  "Invalid request"
 // @Failure      401      {object}  ErrorResponse  "Invalid credentials"
+// @Failure      429      {object}  ErrorResponse  "Login locked or attempted too soon after a failure"
 // @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Failure      502      {object}  ErrorResponse  "LDAP directory unavailable"
 // @Router       /auth/login [post]
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
@@ -106,33 +168,134 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	clientIP := getClientIP(r)
+	clientIP := h.clientIPs.ClientIP(r)
 	userAgent := r.UserAgent()
 
 	result, refreshToken, err := h.service.Login(r.Context(), &req, clientIP, userAgent)
 	if err != nil {
-		if errors.Is(err, ErrInvalidCredentials) {
+		var blocked *LoginBlockedError
+		switch {
+		case errors.Is(err, ErrInvalidCredentials):
 			utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Invalid credentials")
-			return
+		case errors.Is(err, ErrLDAPUnavailable):
+			utils.RespondError(w, r, http.StatusBadGateway, "Bad Gateway", "Directory server is unavailable")
+		case errors.As(err, &blocked):
+			setRetryAfter(w, blocked.RetryAfter)
+			if blocked.Locked {
+				utils.RespondError(w, r, http.StatusTooManyRequests, "Too Many Requests", "Too many failed login attempts. Please try again later.")
+			} else {
+				utils.RespondError(w, r, http.StatusTooManyRequests, "Too Many Requests", "Please wait before trying again")
+			}
+		default:
+			utils.RespondInternalError(w, r, err, "Failed to login")
 		}
-		utils.RespondInternalError(w, r, err, "Failed to login")
 		return
 	}
 
-	// Set refresh token as HTTP-only cookie
-	setRefreshTokenCookie(w, refreshToken)
+	// Set refresh token as HTTP-only cookie (not issued yet if a second factor is needed)
+	if refreshToken != "" {
+		setRefreshTokenCookie(w, refreshToken)
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// StartOIDCLogin godoc
+// @Summary      Start single sign-on login
+// @Description  Starts a login at the configured OpenID Connect provider (authorization code flow with PKCE). Returns the URL to send the user to and sets an HTTP-only cookie binding the login to this browser; the provider redirects back to the client with a code and state to post to /auth/oidc/callback.
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  OIDCAuthorizationResponse
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Failure      502  {object}  ErrorResponse  "Single sign-on provider unavailable"
+// @Failure      503  {object}  ErrorResponse  "Single sign-on is not configured"
+// @Router       /auth/oidc/authorize [get]
+func (h *Handler) StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	result, stateToken, err := h.service.StartOIDCLogin(r.Context())
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrOIDCNotConfigured):
+			utils.RespondError(w, r, http.StatusServiceUnavailable, "Service Unavailable", "Single sign-on is not configured")
+		case errors.Is(err, ErrOIDCUnavailable):
+			utils.RespondError(w, r, http.StatusBadGateway, "Bad Gateway", "Single sign-on provider is unavailable")
+		default:
+			utils.RespondInternalError(w, r, err, "Failed to start single sign-on")
+		}
+		return
+	}
+
+	setOIDCStateCookie(w, stateToken)
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// CompleteOIDCLogin godoc
+// @Summary      Complete single sign-on login
+// @Description  Completes a login started by /auth/oidc/authorize with the code and state the provider redirected back with. The user is matched by the verified email address of the provider (and created if provisioning is enabled), their mapped groups are synchronized, and tokens are returned like for /auth/login. A second factor used at the provider satisfies the MFA requirement.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      OIDCCallbackRequest  true  "Authorization code and state"
+// @Success      200      {object}  LoginResponse
+// @Failure      400      {object}  ErrorResponse  "Invalid request"
+// @Failure      401      {object}  ErrorResponse  "Login state missing, expired or mismatched, or the provider rejected the login"
+// @Failure      403      {object}  ErrorResponse  "No account for the provider's user"
+// @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Failure      502      {object}  ErrorResponse  "Single sign-on provider unavailable"
+// @Failure      503      {object}  ErrorResponse  "Single sign-on is not configured"
+// @Router       /auth/oidc/callback [post]
+func (h *Handler) CompleteOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	var req OIDCCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid request body")
+		return
+	}
+
+	// The state cookie is good for one attempt
+	var stateToken string
+	if cookie, err := r.Cookie(OIDCStateCookieName); err == nil {
+		stateToken = cookie.Value
+	}
+	clearOIDCStateCookie(w)
+
+	clientIP := h.clientIPs.ClientIP(r)
+	userAgent := r.UserAgent()
+
+	result, refreshToken, err := h.service.CompleteOIDCLogin(r.Context(), &req, stateToken, clientIP, userAgent)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrOIDCNotConfigured):
+			utils.RespondError(w, r, http.StatusServiceUnavailable, "Service Unavailable", "Single sign-on is not configured")
+		case errors.Is(err, ErrOIDCUnavailable):
+			utils.RespondError(w, r, http.StatusBadGateway, "Bad Gateway", "Single sign-on provider is unavailable")
+		case errors.Is(err, ErrOIDCLoginFailed):
+			utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Single sign-on failed. Please try again.")
+		case errors.Is(err, ErrOIDCNoAccount):
+			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "No account exists for this user")
+		case errors.Is(err, ErrPublicGroupNotFound):
+			utils.RespondInternalError(w, r, err, "System configuration error")
+		default:
+			utils.RespondInternalError(w, r, err, "Failed to complete single sign-on")
+		}
+		return
+	}
+
+	// Set refresh token as HTTP-only cookie (not issued yet if a second factor is needed)
+	if refreshToken != "" {
+		setRefreshTokenCookie(w, refreshToken)
+	}
 
 	utils.RespondJSON(w, http.StatusOK, result)
 }
 
 // Refresh godoc
 // @Summary      Refresh access token
-// @Description  Uses the refresh token from HTTP-only cookie to generate new access and refresh tokens. Implements token rotation for security.
+// @Description  Uses the refresh token from HTTP-only cookie to generate new access and refresh tokens. Implements token rotation for security. The new tokens keep the MFA-verified state of the session. Sessions started without a second factor are ended once the user's roles require MFA. Presenting a refresh token that was already rotated ends its whole session, except within a short grace period after rotation, which is answered with 409 so concurrent refreshes of one client can retry.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Success      200  {object}  TokenResponse
-// @Failure      401  {object}  ErrorResponse  "Invalid or expired refresh token"
+// @Failure      401  {object}  ErrorResponse  "Invalid, expired, revoked or reused refresh token"
+// @Failure      409  {object}  ErrorResponse  "Refresh token was just rotated by a concurrent request"
 // @Failure      500  {object}  ErrorResponse  "Internal server error"
 // @Router       /auth/refresh [post]
 func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
@@ -143,7 +306,7 @@ func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	clientIP := getClientIP(r)
+	clientIP := h.clientIPs.ClientIP(r)
 	userAgent := r.UserAgent()
 
 	result, newRefreshToken, err := h.service.Refresh(r.Context(), cookie.Value, clientIP, userAgent)
@@ -155,9 +318,18 @@ func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
 		case errors.Is(err, ErrTokenRevoked):
 			clearRefreshTokenCookie(w)
 			utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Token has been revoked. Please login again.")
+		case errors.Is(err, ErrTokenReused):
+			clearRefreshTokenCookie(w)
+			utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Refresh token was already used. The session has been ended, please login again.")
+		case errors.Is(err, ErrTokenRotated):
+			// Keep the cookie: it may already hold the replacement set by the concurrent request
+			utils.RespondError(w, r, http.StatusConflict, "Conflict", "Refresh token was just rotated by another request. Retry with the new refresh token.")
 		case errors.Is(err, ErrTokenExpired):
 			clearRefreshTokenCookie(w)
 			utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Refresh token has expired. Please login again.")
+		case errors.Is(err, ErrMFARequired):
+			clearRefreshTokenCookie(w)
+			utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Two-factor authentication is required. Please login again.")
 		default:
 			utils.RespondInternalError(w, r, err, "Failed to refresh token")
 		}
@@ -172,7 +344,7 @@ func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
 
 // Logout godoc
 // @Summary      User logout
-// @Description  Revokes the current refresh token and clears the cookie.
+// @Description  Revokes the current refresh token and, if sent in the Authorization header, the access token, and clears the cookie.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -185,6 +357,9 @@ func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
 	if err == nil && cookie.Value != "" {
 		_ = h.service.Logout(r.Context(), cookie.Value)
 	}
+	if token := bearerToken(r); token != "" && !isAPIKey(token) {
+		_ = h.service.RevokeAccessToken(r.Context(), token)
+	}
 
 	// Clear the cookie regardless of logout result
 	clearRefreshTokenCookie(w)
@@ -194,7 +369,7 @@ func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
 
 // LogoutAll godoc
 // @Summary      Logout from all devices
-// @Description  Revokes all refresh tokens for the current user, logging them out from all devices.
+// @Description  Revokes all refresh tokens and access tokens of the current user, logging them out from all devices.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -223,7 +398,7 @@ func (h *Handler) LogoutAll(w http.ResponseWriter, r *http.Request) {
 
 // Me godoc
 // @Summary      Get current user info
-// @Description  Returns the currently authenticated user's information and roles.
+// @Description  Returns the currently authenticated user's information and roles. While an administrator impersonates the user, impersonator identifies the administrator.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -244,85 +419,1368 @@ func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
 		utils.RespondInternalError(w, r, err, "Failed to retrieve user information")
 		return
 	}
+	if claims := GetClaimsFromContext(r.Context()); claims != nil {
+		result.Impersonator = claims.Actor
+	}
 
 	utils.RespondJSON(w, http.StatusOK, result)
 }
 
-// setRefreshTokenCookie sets the refresh token as an HTTP-only cookie
-func setRefreshTokenCookie(w http.ResponseWriter, token string) {
-	secure := os.Getenv("APP_ENV") != "local"
+// VerifyMFA godoc
+// @Summary      Complete login with a second factor
+// @Description  Exchanges the MFA challenge token returned by login for access and refresh tokens, using a code from the authenticator app or a single-use recovery code. Wrong codes count towards the login lockout, and the challenge is invalidated after AUTH_MFA_MAX_ATTEMPTS of them. For enrollment challenges (users whose roles require MFA), the code confirms the secret from /auth/mfa/enroll and the response also contains the new recovery codes.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      VerifyMFARequest  true  "MFA challenge token and code"
+// @Success      200      {object}  LoginResponse
+// @Failure      400      {object}  ErrorResponse  "Invalid request or MFA not set up"
+// @Failure      401      {object}  ErrorResponse  "Invalid or expired challenge token, or invalid code"
+// @Failure      429      {object}  ErrorResponse  "Login locked or attempted too soon after a failure"
+// @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Router       /auth/mfa/verify [post]
+func (h *Handler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	var req VerifyMFARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid request body")
+		return
+	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     RefreshTokenCookieName,
-		Value:    token,
-		Path:     RefreshTokenCookiePath,
-		HttpOnly: true,
-		Secure:   secure,
-		SameSite: http.SameSiteStrictMode,
-		MaxAge:   int(RefreshTokenDuration.Seconds()),
-		Expires:  time.Now().Add(RefreshTokenDuration),
-	})
-}
+	if req.MFAToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "mfa_token and either code or recovery_code are required")
+		return
+	}
 
-// clearRefreshTokenCookie clears the refresh token cookie
-func clearRefreshTokenCookie(w http.ResponseWriter) {
-	http.SetCookie(w, &http.Cookie{
-		Name:     RefreshTokenCookieName,
-		Value:    "",
-		Path:     RefreshTokenCookiePath,
-		HttpOnly: true,
-		Secure:   os.Getenv("APP_ENV") != "local",
-		SameSite: http.SameSiteStrictMode,
-		MaxAge:   -1,
-		Expires:  time.Unix(0, 0),
-	})
+	clientIP := h.clientIPs.ClientIP(r)
+	userAgent := r.UserAgent()
+
+	result, refreshToken, err := h.service.VerifyMFA(r.Context(), &req, clientIP, userAgent)
+	if err != nil {
+		var blocked *LoginBlockedError
+		switch {
+		case errors.Is(err, ErrInvalidToken):
+			utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Invalid or expired MFA token. Please login again.")
+		case errors.Is(err, ErrInvalidMFACode):
+			utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Invalid verification code")
+		case errors.Is(err, ErrMFANotEnabled):
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Two-factor authentication is not set up")
+		case errors.As(err, &blocked):
+			setRetryAfter(w, blocked.RetryAfter)
+			if blocked.Locked {
+				utils.RespondError(w, r, http.StatusTooManyRequests, "Too Many Requests", "Too many failed login attempts. Please try again later.")
+			} else {
+				utils.RespondError(w, r, http.StatusTooManyRequests, "Too Many Requests", "Please wait before trying again")
+			}
+		default:
+			utils.RespondInternalError(w, r, err, "Failed to verify code")
+		}
+		return
+	}
+
+	setRefreshTokenCookie(w, refreshToken)
+
+	utils.RespondJSON(w, http.StatusOK, result)
 }
 
-// getClientIP extracts the client IP from the request
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header first (for proxied requests)
-	xff := r.Header.Get("X-Forwarded-For")
-	if xff != "" {
-		// Take the first IP in the list
-		ips := strings.Split(xff, ",")
-		if len(ips) > 0 {
-			return cleanIP(strings.TrimSpace(ips[0]))
+// EnrollMFA godoc
+// @Summary      Start two-factor authentication enrollment
+// @Description  Generates a new TOTP secret and its otpauth:// provisioning URI (render it as a QR code for authenticator apps). Two-factor authentication is enabled once a code is confirmed. Authenticate with an access token, then confirm via /auth/mfa/enroll/confirm. Users whose roles require MFA authenticate with the enrollment challenge token from login instead, then confirm via /auth/mfa/verify.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  MFAEnrollmentResponse
+// @Failure      401  {object}  ErrorResponse  "Unauthorized"
+// @Failure      409  {object}  ErrorResponse  "Two-factor authentication is already enabled"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /auth/mfa/enroll [post]
+func (h *Handler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	userID := h.enrollingUserID(r)
+	if userID == "" {
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Invalid or expired token")
+		return
+	}
+
+	result, err := h.service.EnrollMFA(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, ErrMFAAlreadyEnabled) {
+			utils.RespondError(w, r, http.StatusConflict, "Conflict", "Two-factor authentication is already enabled")
+			return
 		}
+		utils.RespondInternalError(w, r, err, "Failed to start two-factor authentication enrollment")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// ConfirmMFA godoc
+// @Summary      Confirm two-factor authentication enrollment
+// @Description  Enables two-factor authentication with the first code from the authenticator app. Returns single-use recovery codes that are only shown once.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      MFACodeRequest  true  "Code from the authenticator app"
+// @Success      200      {object}  RecoveryCodesResponse
+// @Failure      400      {object}  ErrorResponse  "Invalid request or no pending enrollment"
+// @Failure      401      {object}  ErrorResponse  "Invalid verification code"
+// @Failure      409      {object}  ErrorResponse  "Two-factor authentication is already enabled"
+// @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /auth/mfa/enroll/confirm [post]
+func (h *Handler) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserIDFromContext(r.Context())
+	if userID == "" {
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
 	}
 
-	// Check X-Real-IP header
-	xri := r.Header.Get("X-Real-IP")
-	if xri != "" {
-		return cleanIP(xri)
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "code is required")
+		return
 	}
 
-	// Fall back to RemoteAddr
-	ip := r.RemoteAddr
+	result, err := h.service.ConfirmMFA(r.Context(), userID, &req)
+	if err != nil {
+		h.respondMFAError(w, r, err, "Failed to enable two-factor authentication")
+		return
+	}
 
-	// Handle IPv6 addresses with brackets (e.g., [::1]:port)
-	if strings.HasPrefix(ip, "[") {
-		if idx := strings.Index(ip, "]"); idx != -1 {
-			ip = ip[1:idx] // Extract IP from brackets
-		}
-	} else {
-		// Handle IPv4 addresses (e.g., 127.0.0.1:port)
-		if idx := strings.LastIndex(ip, ":"); idx != -1 {
-			ip = ip[:idx]
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// GetMFAStatus godoc
+// @Summary      Get two-factor authentication status
+// @Description  Returns whether two-factor authentication is enabled for the current user, whether their roles require it, and how many recovery codes are left.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  MFAStatusResponse
+// @Failure      401  {object}  ErrorResponse  "Unauthorized"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /auth/mfa [get]
+func (h *Handler) GetMFAStatus(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserIDFromContext(r.Context())
+	if userID == "" {
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	result, err := h.service.GetMFAStatus(r.Context(), userID)
+	if err != nil {
+		utils.RespondInternalError(w, r, err, "Failed to retrieve two-factor authentication status")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// DisableMFA godoc
+// @Summary      Disable two-factor authentication
+// @Description  Removes the TOTP secret and recovery codes after checking a current code or recovery code. Not allowed if the user's roles require two-factor authentication.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      MFACodeRequest  true  "Current code or recovery code"
+// @Success      200      {object}  SuccessResponse
+// @Failure      400      {object}  ErrorResponse  "Invalid request or MFA not enabled"
+// @Failure      401      {object}  ErrorResponse  "Invalid verification code"
+// @Failure      403      {object}  ErrorResponse  "Two-factor authentication is required for your roles"
+// @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /auth/mfa/disable [post]
+func (h *Handler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserIDFromContext(r.Context())
+	if userID == "" {
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.Code == "" && req.RecoveryCode == "") {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "code or recovery_code is required")
+		return
+	}
+
+	if err := h.service.DisableMFA(r.Context(), userID, &req); err != nil {
+		h.respondMFAError(w, r, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, SuccessResponse{Message: "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary      Regenerate recovery codes
+// @Description  Replaces all recovery codes after checking a current code or recovery code. The new codes are only shown once.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      MFACodeRequest  true  "Current code or recovery code"
+// @Success      200      {object}  RecoveryCodesResponse
+// @Failure      400      {object}  ErrorResponse  "Invalid request or MFA not enabled"
+// @Failure      401      {object}  ErrorResponse  "Invalid verification code"
+// @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /auth/mfa/recovery-codes [post]
+func (h *Handler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserIDFromContext(r.Context())
+	if userID == "" {
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.Code == "" && req.RecoveryCode == "") {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "code or recovery_code is required")
+		return
+	}
+
+	result, err := h.service.RegenerateRecoveryCodes(r.Context(), userID, &req)
+	if err != nil {
+		h.respondMFAError(w, r, err, "Failed to regenerate recovery codes")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// ForgotPassword godoc
+// @Summary      Request a password reset email
+// @Description  Emails a single-use password reset link if an account with the address exists. The response is the same whether or not it does. Requests are rate limited per address.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      ForgotPasswordRequest  true  "Account email address"
+// @Success      202      {object}  SuccessResponse
+// @Failure      400      {object}  ErrorResponse  "Invalid request or email format"
+// @Failure      429      {object}  ErrorResponse  "Too many requests for this address"
+// @Failure      503      {object}  ErrorResponse  "Email delivery is not configured"
+// @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Router       /auth/password/forgot [post]
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid request body")
+		return
+	}
+
+	if err := h.service.ForgotPassword(r.Context(), req.Email); err != nil {
+		h.respondMailError(w, r, err, "Failed to request password reset")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusAccepted, SuccessResponse{Message: "If an account with this email exists, a password reset link has been sent"})
+}
+
+// ResetPassword godoc
+// @Summary      Reset password
+// @Description  Sets a new password using the token from a password reset email. The token can only be used once; it stays valid if the password violates the password policy. All sessions of the user are logged out.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      ResetPasswordRequest  true  "Reset token and new password"
+// @Success      200      {object}  SuccessResponse
+// @Failure      400      {object}  password.ErrorResponse  "Invalid request or token, or password policy violations"
+// @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Router       /auth/password/reset [post]
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid request body")
+		return
+	}
+
+	if req.Token == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "token is required")
+		return
+	}
+
+	if err := h.service.ResetPassword(r.Context(), &req); err != nil {
+		var policyErr *password.PolicyError
+		switch {
+		case errors.As(err, &policyErr):
+			utils.RespondJSON(w, http.StatusBadRequest, password.NewErrorResponse(policyErr))
+		case errors.Is(err, ErrInvalidPassword):
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Password does not meet the password policy")
+		case errors.Is(err, ErrInvalidToken):
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid or expired reset link")
+		default:
+			utils.RespondInternalError(w, r, err, "Failed to reset password")
 		}
+		return
 	}
 
-	return ip
+	// Existing sessions were revoked, including the one in this browser if any
+	clearRefreshTokenCookie(w)
+
+	utils.RespondJSON(w, http.StatusOK, SuccessResponse{Message: "Password has been reset. Please login with your new password."})
 }
 
-// cleanIP removes brackets from IPv6 addresses if present
-func cleanIP(ip string) string {
-	ip = strings.TrimPrefix(ip, "[")
-	ip = strings.TrimSuffix(ip, "]")
-	// Also remove port if present after bracket removal
-	if idx := strings.LastIndex(ip, ":"); idx != -1 && strings.Count(ip, ":") == 1 {
-		// Only remove if it looks like a port (single colon for IPv4)
-		ip = ip[:idx]
+// ChangeExpiredPassword godoc
+// @Summary      Change an expired password
+// @Description  Replaces a password older than the policy's max_age_days using the token from login's `password_change`, then completes the login like /auth/login: tokens are returned, or an MFA challenge if a second factor is needed. Other sessions of the user are logged out.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      ChangeExpiredPasswordRequest  true  "Password change token and new password"
+// @Success      200      {object}  LoginResponse
+// @Failure      400      {object}  password.ErrorResponse  "Invalid request or password policy violations"
+// @Failure      401      {object}  ErrorResponse  "Invalid or expired password change token"
+// @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Router       /auth/password/change [post]
+func (h *Handler) ChangeExpiredPassword(w http.ResponseWriter, r *http.Request) {
+	var req ChangeExpiredPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid request body")
+		return
+	}
+
+	if req.PasswordChangeToken == "" || req.NewPassword == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "password_change_token and new_password are required")
+		return
+	}
+
+	result, refreshToken, err := h.service.ChangeExpiredPassword(r.Context(), &req, h.clientIPs.ClientIP(r), r.UserAgent())
+	if err != nil {
+		var policyErr *password.PolicyError
+		switch {
+		case errors.As(err, &policyErr):
+			utils.RespondJSON(w, http.StatusBadRequest, password.NewErrorResponse(policyErr))
+		case errors.Is(err, ErrInvalidToken):
+			utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Invalid or expired password change token. Please login again.")
+		default:
+			utils.RespondInternalError(w, r, err, "Failed to change password")
+		}
+		return
 	}
-	return ip
+
+	// Not issued yet if a second factor is needed
+	if refreshToken != "" {
+		setRefreshTokenCookie(w, refreshToken)
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// VerifyEmail godoc
+// @Summary      Verify email address
+// @Description  Marks the user's email address as verified using the token from a verification email. The token can only be used once.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      VerifyEmailRequest  true  "Verification token"
+// @Success      200      {object}  SuccessResponse
+// @Failure      400      {object}  ErrorResponse  "Invalid request or token"
+// @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Router       /auth/email/verify [post]
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid request body")
+		return
+	}
+
+	if req.Token == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "token is required")
+		return
+	}
+
+	if err := h.service.VerifyEmail(r.Context(), req.Token); err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid or expired verification link")
+			return
+		}
+		utils.RespondInternalError(w, r, err, "Failed to verify email")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, SuccessResponse{Message: "Email verified successfully"})
+}
+
+// ResendVerificationEmail godoc
+// @Summary      Resend verification email
+// @Description  Sends a new email verification link to the current user. Requests are rate limited per address.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Success      202  {object}  SuccessResponse
+// @Failure      401  {object}  ErrorResponse  "Unauthorized"
+// @Failure      409  {object}  ErrorResponse  "Email is already verified"
+// @Failure      429  {object}  ErrorResponse  "Too many requests for this address"
+// @Failure      503  {object}  ErrorResponse  "Email delivery is not configured"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /auth/email/verify/resend [post]
+func (h *Handler) ResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserIDFromContext(r.Context())
+	if userID == "" {
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	if err := h.service.ResendVerificationEmail(r.Context(), userID); err != nil {
+		if errors.Is(err, ErrAlreadyVerified) {
+			utils.RespondError(w, r, http.StatusConflict, "Conflict", "Email is already verified")
+			return
+		}
+		h.respondMailError(w, r, err, "Failed to send verification email")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusAccepted, SuccessResponse{Message: "Verification email sent"})
+}
+
+// ListSessions godoc
+// @Summary      List my sessions
+// @Description  Lists the active sessions (refresh token families) of the current user with the client IP and user agent of their last login or refresh. The session of the refresh token cookie sent with the request is marked as current.
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  SessionListResponse
+// @Failure      401  {object}  ErrorResponse  "Unauthorized"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /auth/sessions [get]
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserIDFromContext(r.Context())
+	if userID == "" {
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	currentRefreshToken := ""
+	if cookie, err := r.Cookie(RefreshTokenCookieName); err == nil {
+		currentRefreshToken = cookie.Value
+	}
+
+	result, err := h.service.ListSessions(r.Context(), userID, currentRefreshToken)
+	if err != nil {
+		utils.RespondInternalError(w, r, err, "Failed to retrieve sessions")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// RevokeSession godoc
+// @Summary      Revoke one of my sessions
+// @Description  Ends a session of the current user. Its refresh token stops working; access tokens already issued stay valid until they expire.
+// @Tags         auth
+// @Produce      json
+// @Param        id   path      string  true  "Session ID"
+// @Success      200  {object}  SuccessResponse
+// @Failure      401  {object}  ErrorResponse  "Unauthorized"
+// @Failure      404  {object}  ErrorResponse  "Session not found"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /auth/sessions/{id} [delete]
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserIDFromContext(r.Context())
+	if userID == "" {
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	if err := h.service.RevokeSession(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		h.respondSessionError(w, r, err)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, SuccessResponse{Message: "Session revoked"})
+}
+
+// ListUserSessions godoc
+// @Summary      List sessions of a user
+// @Description  Lists the active sessions (refresh token families) of any user.
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  SessionListResponse
+// @Failure      401  {object}  ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  ErrorResponse  "Forbidden"
+// @Failure      404  {object}  ErrorResponse  "User not found"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/auth/users/{id}/sessions [get]
+func (h *Handler) ListUserSessions(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.ListSessions(r.Context(), chi.URLParam(r, "id"), "")
+	if err != nil {
+		h.respondSessionError(w, r, err)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// RevokeUserSession godoc
+// @Summary      Revoke a session of a user
+// @Description  Ends a session of any user. Its refresh token stops working; access tokens already issued stay valid until they expire.
+// @Tags         admin
+// @Produce      json
+// @Param        id         path      string  true  "User ID"
+// @Param        sessionId  path      string  true  "Session ID"
+// @Success      200        {object}  SuccessResponse
+// @Failure      401        {object}  ErrorResponse  "Unauthorized"
+// @Failure      403        {object}  ErrorResponse  "Forbidden"
+// @Failure      404        {object}  ErrorResponse  "User or session not found"
+// @Failure      500        {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/auth/users/{id}/sessions/{sessionId} [delete]
+func (h *Handler) RevokeUserSession(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.RevokeSession(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "sessionId")); err != nil {
+		h.respondSessionError(w, r, err)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, SuccessResponse{Message: "Session revoked"})
+}
+
+// respondSessionError maps session management errors to HTTP responses
+func (h *Handler) respondSessionError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		utils.RespondError(w, r, http.StatusNotFound, "Not Found", "User not found")
+	case errors.Is(err, ErrSessionNotFound):
+		utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Session not found")
+	default:
+		utils.RespondInternalError(w, r, err, "Failed to manage sessions")
+	}
+}
+
+// ListLockouts godoc
+// @Summary      List login lockouts
+// @Description  Lists login IDs and client IPs that are currently locked after too many failed logins.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  LockoutListResponse
+// @Failure      401  {object}  ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  ErrorResponse  "Forbidden"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/auth/lockouts [get]
+func (h *Handler) ListLockouts(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.ListLockouts(r.Context())
+	if err != nil {
+		utils.RespondInternalError(w, r, err, "Failed to retrieve lockouts")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// UnlockLogin godoc
+// @Summary      Unlock a login
+// @Description  Lifts the lockout and clears the failed logins of a login ID (or email) and/or a client IP. The unlock is recorded as a security event.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request  body      UnlockLoginRequest  true  "Login ID and/or client IP"
+// @Success      200      {object}  SuccessResponse
+// @Failure      400      {object}  ErrorResponse  "Invalid request"
+// @Failure      401      {object}  ErrorResponse  "Unauthorized"
+// @Failure      403      {object}  ErrorResponse  "Forbidden"
+// @Failure      404      {object}  ErrorResponse  "No failed logins tracked"
+// @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/auth/lockouts/unlock [post]
+func (h *Handler) UnlockLogin(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserIDFromContext(r.Context())
+	if userID == "" {
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	var req UnlockLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid request body")
+		return
+	}
+
+	if strings.TrimSpace(req.LoginID) == "" && strings.TrimSpace(req.ClientIP) == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "login_id or client_ip is required")
+		return
+	}
+
+	if err := h.service.UnlockLogin(r.Context(), userID, &req, h.clientIPs.ClientIP(r), r.UserAgent()); err != nil {
+		if errors.Is(err, ErrLockoutNotFound) {
+			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "No failed logins tracked for this login ID or client IP")
+			return
+		}
+		utils.RespondInternalError(w, r, err, "Failed to unlock login")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, SuccessResponse{Message: "Login unlocked"})
+}
+
+// ListSecurityEvents godoc
+// @Summary      List security events
+// @Description  Lists recorded security events such as login lockouts, unlocks and refresh token reuse, newest first. Many lockouts of different login IDs from few client IPs indicate credential stuffing.
+// @Tags         admin
+// @Produce      json
+// @Param        event_type  query     string  false  "Event type filter"  Enums(LOGIN_LOCKOUT, LOGIN_UNLOCK, REFRESH_TOKEN_REUSE)
+// @Param        page        query     int     false  "Page number"  default(1)
+// @Param        limit       query     int     false  "Items per page"  default(20)
+// @Success      200         {object}  SecurityEventListResponse
+// @Failure      401         {object}  ErrorResponse  "Unauthorized"
+// @Failure      403         {object}  ErrorResponse  "Forbidden"
+// @Failure      500         {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/auth/security-events [get]
+func (h *Handler) ListSecurityEvents(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	result, err := h.service.ListSecurityEvents(r.Context(), r.URL.Query().Get("event_type"), page, limit)
+	if err != nil {
+		utils.RespondInternalError(w, r, err, "Failed to retrieve security events")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// ListAPIKeys godoc
+// @Summary      List my personal access tokens
+// @Description  Lists the unrevoked personal access tokens of the current user, including expired ones. Secrets are never returned; the prefix identifies a token.
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  APIKeyListResponse
+// @Failure      401  {object}  ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  ErrorResponse  "API keys cannot manage API keys"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /auth/tokens [get]
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.keyManagerID(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.ListAPIKeys(r.Context(), userID)
+	if err != nil {
+		h.respondAPIKeyError(w, r, err, "Failed to retrieve personal access tokens")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// CreateAPIKey godoc
+// @Summary      Create a personal access token
+// @Description  Creates a personal access token for scripts and tools. It is sent as `Authorization: Bearer kcp_...` instead of an access token and grants only the requested scopes, which must be roles of the current user. The token is only returned once.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      CreateAPIKeyRequest  true  "Token name, scopes and optional expiry"
+// @Success      201      {object}  CreateAPIKeyResponse
+// @Failure      400      {object}  ErrorResponse  "Invalid name, scopes or expiry"
+// @Failure      401      {object}  ErrorResponse  "Unauthorized"
+// @Failure      403      {object}  ErrorResponse  "API keys cannot manage API keys"
+// @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /auth/tokens [post]
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.keyManagerID(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid request body")
+		return
+	}
+
+	result, err := h.service.CreateAPIKey(r.Context(), userID, &req)
+	if err != nil {
+		h.respondAPIKeyError(w, r, err, "Failed to create personal access token")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, result)
+}
+
+// RevokeAPIKey godoc
+// @Summary      Revoke a personal access token
+// @Description  Revokes a personal access token of the current user. Requests made with it are rejected immediately.
+// @Tags         auth
+// @Produce      json
+// @Param        id   path      string  true  "Token ID"
+// @Success      200  {object}  SuccessResponse
+// @Failure      401  {object}  ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  ErrorResponse  "API keys cannot manage API keys"
+// @Failure      404  {object}  ErrorResponse  "Token not found"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /auth/tokens/{id} [delete]
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.keyManagerID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.RevokeAPIKey(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		h.respondAPIKeyError(w, r, err, "Failed to revoke personal access token")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, SuccessResponse{Message: "Personal access token revoked"})
+}
+
+// ListServiceAccounts godoc
+// @Summary      List service accounts
+// @Description  Lists the service accounts, which are users without a password that authenticate with API keys.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  ServiceAccountListResponse
+// @Failure      401  {object}  ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  ErrorResponse  "Forbidden"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/auth/service-accounts [get]
+func (h *Handler) ListServiceAccounts(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.ListServiceAccounts(r.Context())
+	if err != nil {
+		utils.RespondInternalError(w, r, err, "Failed to retrieve service accounts")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// CreateServiceAccount godoc
+// @Summary      Create a service account
+// @Description  Creates a user for machine access that cannot log in and authenticates with API keys only. It belongs to no groups; grant it roles through user and group management before creating keys.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request  body      CreateServiceAccountRequest  true  "Service account data"
+// @Success      201      {object}  UserInfo
+// @Failure      400      {object}  ErrorResponse  "Invalid request or validation error"
+// @Failure      401      {object}  ErrorResponse  "Unauthorized"
+// @Failure      403      {object}  ErrorResponse  "Forbidden"
+// @Failure      409      {object}  ErrorResponse  "Email or login_id already exists"
+// @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/auth/service-accounts [post]
+func (h *Handler) CreateServiceAccount(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.keyManagerID(w, r); !ok {
+		return
+	}
+
+	var req CreateServiceAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid request body")
+		return
+	}
+
+	result, err := h.service.CreateServiceAccount(r.Context(), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidEmail):
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid email format")
+		case errors.Is(err, ErrInvalidName):
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Name must have at least one locale value")
+		case errors.Is(err, ErrEmailExists):
+			utils.RespondError(w, r, http.StatusConflict, "Conflict", "Email already exists")
+		case errors.Is(err, ErrLoginIDExists):
+			utils.RespondError(w, r, http.StatusConflict, "Conflict", "Login ID already exists")
+		default:
+			h.respondAPIKeyError(w, r, err, "Failed to create service account")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, result)
+}
+
+// ListServiceAccountKeys godoc
+// @Summary      List API keys of a service account
+// @Description  Lists the unrevoked API keys of a service account, including expired ones.
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "Service account ID"
+// @Success      200  {object}  APIKeyListResponse
+// @Failure      401  {object}  ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  ErrorResponse  "Forbidden"
+// @Failure      404  {object}  ErrorResponse  "Service account not found"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/auth/service-accounts/{id}/api-keys [get]
+func (h *Handler) ListServiceAccountKeys(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.ListServiceAccountKeys(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondAPIKeyError(w, r, err, "Failed to retrieve API keys")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// CreateServiceAccountKey godoc
+// @Summary      Create an API key for a service account
+// @Description  Creates an API key for a service account. It is sent as `Authorization: Bearer kcs_...` and grants only the requested scopes, which must be roles of the service account that the caller holds as well (full_access holds every role). Without expires_at the key does not expire. The key is only returned once.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string               true  "Service account ID"
+// @Param        request  body      CreateAPIKeyRequest  true  "Key name, scopes and optional expiry"
+// @Success      201      {object}  CreateAPIKeyResponse
+// @Failure      400      {object}  ErrorResponse  "Invalid name, scopes or expiry"
+// @Failure      401      {object}  ErrorResponse  "Unauthorized"
+// @Failure      403      {object}  ErrorResponse  "Forbidden, e.g. a scope the caller doesn't hold"
+// @Failure      404      {object}  ErrorResponse  "Service account not found"
+// @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/auth/service-accounts/{id}/api-keys [post]
+func (h *Handler) CreateServiceAccountKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.keyManagerID(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid request body")
+		return
+	}
+
+	result, err := h.service.CreateServiceAccountKey(r.Context(), userID, chi.URLParam(r, "id"), &req)
+	if err != nil {
+		h.respondAPIKeyError(w, r, err, "Failed to create API key")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, result)
+}
+
+// RevokeServiceAccountKey godoc
+// @Summary      Revoke an API key of a service account
+// @Description  Revokes an API key of a service account. Requests made with it are rejected immediately.
+// @Tags         admin
+// @Produce      json
+// @Param        id     path      string  true  "Service account ID"
+// @Param        keyId  path      string  true  "API key ID"
+// @Success      200    {object}  SuccessResponse
+// @Failure      401    {object}  ErrorResponse  "Unauthorized"
+// @Failure      403    {object}  ErrorResponse  "Forbidden"
+// @Failure      404    {object}  ErrorResponse  "Service account or API key not found"
+// @Failure      500    {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/auth/service-accounts/{id}/api-keys/{keyId} [delete]
+func (h *Handler) RevokeServiceAccountKey(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.RevokeServiceAccountKey(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "keyId")); err != nil {
+		h.respondAPIKeyError(w, r, err, "Failed to revoke API key")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, SuccessResponse{Message: "API key revoked"})
+}
+
+// SyncDirectory godoc
+// @Summary      Synchronize users from the LDAP directory
+// @Description  Runs the directory sync now: creates and updates users and departments from the directory, soft-deletes users that are disabled or gone from it, and reconciles mapped group memberships. With dry_run=true the changes are only reported. The sync is refused if the directory returns no users at all.
+// @Tags         admin
+// @Produce      json
+// @Param        dry_run  query     bool  false  "Report the changes without applying them"  default(false)
+// @Success      200      {object}  DirectorySyncReport
+// @Failure      400      {object}  ErrorResponse  "Invalid request"
+// @Failure      401      {object}  ErrorResponse  "Unauthorized"
+// @Failure      403      {object}  ErrorResponse  "Forbidden"
+// @Failure      409      {object}  ErrorResponse  "A sync is already running"
+// @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Failure      502      {object}  ErrorResponse  "LDAP directory unavailable or returned no users"
+// @Failure      503      {object}  ErrorResponse  "LDAP directory is not configured"
+// @Security     BearerAuth
+// @Router       /admin/auth/directory/sync [post]
+func (h *Handler) SyncDirectory(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "dry_run must be true or false")
+			return
+		}
+		dryRun = parsed
+	}
+
+	report, err := h.service.SyncDirectory(r.Context(), dryRun)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrLDAPNotConfigured):
+			utils.RespondError(w, r, http.StatusServiceUnavailable, "Service Unavailable", "LDAP directory is not configured")
+		case errors.Is(err, ErrLDAPSyncRunning):
+			utils.RespondError(w, r, http.StatusConflict, "Conflict", "A directory sync is already running")
+		case errors.Is(err, ErrLDAPDirectoryEmpty):
+			utils.RespondError(w, r, http.StatusBadGateway, "Bad Gateway", "Directory returned no users; sync refused")
+		case errors.Is(err, ErrLDAPUnavailable):
+			utils.RespondError(w, r, http.StatusBadGateway, "Bad Gateway", "Directory server is unavailable")
+		default:
+			utils.RespondInternalError(w, r, err, "Failed to sync directory")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, report)
+}
+
+// GetJWKS godoc
+// @Summary      Get the access token signing keys
+// @Description  Returns the public keys verifying access tokens as a JWK set (RFC 7517), including the next key before it signs. Services verify a token with the key named by its kid header. Empty while access tokens are signed with JWT_SECRET (HS256).
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  JSONWebKeySet
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Router       /.well-known/jwks.json [get]
+func (h *Handler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.GetJWKS(r.Context())
+	if err != nil {
+		utils.RespondInternalError(w, r, err, "Failed to retrieve signing keys")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// ListSigningKeys godoc
+// @Summary      List access token signing keys
+// @Description  Lists the unexpired access token signing keys with their status: the active key signing new tokens, the next key published ahead of its activation, and retired keys still verifying the tokens they signed.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  SigningKeyListResponse
+// @Failure      401  {object}  ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  ErrorResponse  "Forbidden"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/auth/signing-keys [get]
+func (h *Handler) ListSigningKeys(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.ListSigningKeys(r.Context())
+	if err != nil {
+		utils.RespondInternalError(w, r, err, "Failed to retrieve signing keys")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// RotateSigningKey godoc
+// @Summary      Rotate the access token signing key
+// @Description  Creates a signing key that signs new access tokens right away, e.g. after the active key was exposed. Replaced keys keep verifying the tokens they signed until AUTH_JWT_KEY_OVERLAP has passed, or stop right away with retire=true, which makes every client refresh its access token.
+// @Tags         admin
+// @Produce      json
+// @Param        retire  query     bool  false  "Stop verifying tokens signed by the replaced keys right away"  default(false)
+// @Success      200  {object}  SigningKeyInfo
+// @Failure      400  {object}  ErrorResponse  "Invalid request"
+// @Failure      401  {object}  ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  ErrorResponse  "Forbidden"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Failure      503  {object}  ErrorResponse  "Access tokens are signed with JWT_SECRET"
+// @Security     BearerAuth
+// @Router       /admin/auth/signing-keys/rotate [post]
+func (h *Handler) RotateSigningKey(w http.ResponseWriter, r *http.Request) {
+	retire := false
+	if value := r.URL.Query().Get("retire"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "retire must be true or false")
+			return
+		}
+		retire = parsed
+	}
+
+	result, err := h.service.RotateSigningKey(r.Context(), retire)
+	if err != nil {
+		if errors.Is(err, ErrSigningKeysDisabled) {
+			utils.RespondError(w, r, http.StatusServiceUnavailable, "Service Unavailable", "Access tokens are signed with JWT_SECRET; set AUTH_JWT_ALGORITHM to use signing keys")
+			return
+		}
+		utils.RespondInternalError(w, r, err, "Failed to rotate signing key")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// GetPasswordPolicy godoc
+// @Summary      Get the password policy
+// @Description  Returns the rules new passwords must follow and whether the breached password list is loaded. The default policy is returned until one is saved.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  PasswordPolicyResponse
+// @Failure      401  {object}  ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  ErrorResponse  "Forbidden"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/auth/password-policy [get]
+func (h *Handler) GetPasswordPolicy(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.GetPasswordPolicy(r.Context())
+	if err != nil {
+		utils.RespondInternalError(w, r, err, "Failed to retrieve password policy")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// UpdatePasswordPolicy godoc
+// @Summary      Update the password policy
+// @Description  Replaces the password policy for all instances, effective immediately; omitted settings take their default. It applies to passwords set from now on; existing passwords are only affected by max_age_days, which makes users change older passwords on their next login.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request  body      password.Policy  true  "Password policy"
+// @Success      200      {object}  PasswordPolicyResponse
+// @Failure      400      {object}  ErrorResponse  "Invalid policy"
+// @Failure      401      {object}  ErrorResponse  "Unauthorized"
+// @Failure      403      {object}  ErrorResponse  "Forbidden"
+// @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/auth/password-policy [put]
+func (h *Handler) UpdatePasswordPolicy(w http.ResponseWriter, r *http.Request) {
+	userID := GetUserIDFromContext(r.Context())
+	if userID == "" {
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	req := password.DefaultPolicy()
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid request body")
+		return
+	}
+
+	result, err := h.service.UpdatePasswordPolicy(r.Context(), userID, &req)
+	if err != nil {
+		if errors.Is(err, password.ErrInvalidPolicy) {
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", err.Error())
+			return
+		}
+		utils.RespondInternalError(w, r, err, "Failed to update password policy")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// Impersonate godoc
+// @Summary      Impersonate a user
+// @Description  Issues a short-lived access token acting as another user to reproduce what they see, for members of AUTH_IMPERSONATION_ROLES. The token names the administrator in its act claim, cannot be refreshed, cannot change passwords, MFA, sessions or tokens, and every request made with it is recorded.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request  body      ImpersonateRequest  true  "User to impersonate and reason"
+// @Success      201      {object}  ImpersonationTokenResponse
+// @Failure      400      {object}  ErrorResponse  "Invalid request"
+// @Failure      401      {object}  ErrorResponse  "Unauthorized"
+// @Failure      403      {object}  ErrorResponse  "Impersonation not allowed"
+// @Failure      404      {object}  ErrorResponse  "User not found"
+// @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/auth/impersonate [post]
+func (h *Handler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	claims := GetClaimsFromContext(r.Context())
+	if claims == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+	if IsAPIKeyAuthenticated(r.Context()) {
+		utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "API keys cannot be used to impersonate users")
+		return
+	}
+
+	var req ImpersonateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid request body")
+		return
+	}
+
+	if strings.TrimSpace(req.UserID) == "" || strings.TrimSpace(req.Reason) == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "user_id and reason are required")
+		return
+	}
+
+	result, err := h.service.Impersonate(r.Context(), claims, &req, h.clientIPs.ClientIP(r), r.UserAgent())
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrImpersonationDenied):
+			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", err.Error())
+		case errors.Is(err, ErrUserNotFound):
+			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "User not found")
+		default:
+			utils.RespondInternalError(w, r, err, "Failed to impersonate user")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, result)
+}
+
+// EndImpersonation godoc
+// @Summary      End an impersonation
+// @Description  Revokes the impersonation token of the request before it expires. The administrator's own session is not affected.
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse  "Not impersonating"
+// @Failure      401  {object}  ErrorResponse  "Unauthorized"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /auth/impersonation/end [post]
+func (h *Handler) EndImpersonation(w http.ResponseWriter, r *http.Request) {
+	claims := GetClaimsFromContext(r.Context())
+	if claims == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return
+	}
+
+	if err := h.service.EndImpersonation(r.Context(), claims); err != nil {
+		if errors.Is(err, ErrNotImpersonating) {
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "The request is not made while impersonating a user")
+			return
+		}
+		utils.RespondInternalError(w, r, err, "Failed to end impersonation")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, SuccessResponse{Message: "Impersonation ended"})
+}
+
+// ListImpersonations godoc
+// @Summary      List impersonations
+// @Description  Lists impersonations with their reason and number of recorded requests, newest first, optionally filtered by the administrator and the impersonated user.
+// @Tags         admin
+// @Produce      json
+// @Param        actor_id  query     string  false  "Public ID of the impersonating administrator"
+// @Param        user_id   query     string  false  "Public ID of the impersonated user"
+// @Param        page      query     int     false  "Page number"  default(1)
+// @Param        limit     query     int     false  "Items per page"  default(20)
+// @Success      200       {object}  ImpersonationListResponse
+// @Failure      401       {object}  ErrorResponse  "Unauthorized"
+// @Failure      403       {object}  ErrorResponse  "Forbidden"
+// @Failure      500       {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/auth/impersonations [get]
+func (h *Handler) ListImpersonations(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	result, err := h.service.ListImpersonations(r.Context(), r.URL.Query().Get("actor_id"), r.URL.Query().Get("user_id"), page, limit)
+	if err != nil {
+		utils.RespondInternalError(w, r, err, "Failed to retrieve impersonations")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// ListImpersonatedRequests godoc
+// @Summary      List the requests of an impersonation
+// @Description  Lists the requests made with an impersonation token, oldest first, with their status codes.
+// @Tags         admin
+// @Produce      json
+// @Param        id     path      int  true   "Impersonation ID"
+// @Param        page   query     int  false  "Page number"  default(1)
+// @Param        limit  query     int  false  "Items per page"  default(50)
+// @Success      200    {object}  ImpersonatedRequestListResponse
+// @Failure      400    {object}  ErrorResponse  "Invalid impersonation ID"
+// @Failure      401    {object}  ErrorResponse  "Unauthorized"
+// @Failure      403    {object}  ErrorResponse  "Forbidden"
+// @Failure      404    {object}  ErrorResponse  "Impersonation not found"
+// @Failure      500    {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/auth/impersonations/{id}/requests [get]
+func (h *Handler) ListImpersonatedRequests(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id < 1 {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid impersonation ID")
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	result, err := h.service.ListImpersonatedRequests(r.Context(), id, page, limit)
+	if err != nil {
+		if errors.Is(err, ErrNoImpersonation) {
+			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Impersonation not found")
+			return
+		}
+		utils.RespondInternalError(w, r, err, "Failed to retrieve impersonated requests")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// keyManagerID returns the ID of the user managing API keys. Requests authenticated with
+// an API key are rejected, so a leaked key cannot be used to mint further keys.
+func (h *Handler) keyManagerID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID := GetUserIDFromContext(r.Context())
+	if userID == "" {
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "User not authenticated")
+		return "", false
+	}
+	if IsAPIKeyAuthenticated(r.Context()) {
+		utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "API keys cannot be used to manage API keys")
+		return "", false
+	}
+	return userID, true
+}
+
+// respondAPIKeyError maps API key and service account management errors to HTTP responses
+func (h *Handler) respondAPIKeyError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, ErrInvalidKeyRequest):
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", err.Error())
+	case errors.Is(err, ErrUserNotFound):
+		utils.RespondError(w, r, http.StatusNotFound, "Not Found", "User not found")
+	case errors.Is(err, ErrNoServiceAccount):
+		utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Service account not found")
+	case errors.Is(err, ErrScopeNotHeld):
+		utils.RespondError(w, r, http.StatusForbidden, "Forbidden", err.Error())
+	case errors.Is(err, ErrAPIKeyNotFound):
+		utils.RespondError(w, r, http.StatusNotFound, "Not Found", "API key not found")
+	default:
+		utils.RespondInternalError(w, r, err, message)
+	}
+}
+
+// respondMailError maps errors of email-sending endpoints to HTTP responses
+func (h *Handler) respondMailError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, ErrInvalidEmail):
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid email format")
+	case errors.Is(err, ErrTooManyRequests):
+		utils.RespondError(w, r, http.StatusTooManyRequests, "Too Many Requests", "Too many emails requested for this address. Please try again later.")
+	case errors.Is(err, ErrMailNotConfigured):
+		utils.RespondError(w, r, http.StatusServiceUnavailable, "Service Unavailable", "Email delivery is not configured")
+	default:
+		utils.RespondInternalError(w, r, err, message)
+	}
+}
+
+// respondMFAError maps MFA management errors to HTTP responses
+func (h *Handler) respondMFAError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, ErrInvalidMFACode):
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Invalid verification code")
+	case errors.Is(err, ErrMFANotEnabled):
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Two-factor authentication is not set up")
+	case errors.Is(err, ErrMFAAlreadyEnabled):
+		utils.RespondError(w, r, http.StatusConflict, "Conflict", "Two-factor authentication is already enabled")
+	case errors.Is(err, ErrMFAEnforced):
+		utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "Two-factor authentication is required for your roles")
+	default:
+		utils.RespondInternalError(w, r, err, message)
+	}
+}
+
+// enrollingUserID returns the user ID from the bearer token of an enrollment request.
+// Both access tokens and MFA enrollment challenge tokens are accepted.
+func (h *Handler) enrollingUserID(r *http.Request) string {
+	token := bearerToken(r)
+	if token == "" {
+		return ""
+	}
+
+	if claims, err := h.service.ValidateAccessToken(token); err == nil {
+		// Second factors are enrolled by users themselves, not while impersonated
+		if claims.Actor != nil || h.service.IsTokenRevoked(r.Context(), claims) {
+			return ""
+		}
+		return claims.UserID
+	}
+	if userID, err := h.service.ValidateMFAEnrollmentToken(token); err == nil {
+		return userID
+	}
+	return ""
+}
+
+// setRefreshTokenCookie sets the refresh token as an HTTP-only cookie
+func setRefreshTokenCookie(w http.ResponseWriter, token string) {
+	secure := os.Getenv("APP_ENV") != "local"
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     RefreshTokenCookieName,
+		Value:    token,
+		Path:     RefreshTokenCookiePath,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   int(RefreshTokenDuration.Seconds()),
+		Expires:  time.Now().Add(RefreshTokenDuration),
+	})
+}
+
+// clearRefreshTokenCookie clears the refresh token cookie
+func clearRefreshTokenCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     RefreshTokenCookieName,
+		Value:    "",
+		Path:     RefreshTokenCookiePath,
+		HttpOnly: true,
+		Secure:   os.Getenv("APP_ENV") != "local",
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   -1,
+		Expires:  time.Unix(0, 0),
+	})
+}
+
+// setOIDCStateCookie sets the single sign-on state cookie. It lives for the browser session;
+// the state token itself expires after AUTH_OIDC_STATE_TTL.
+func setOIDCStateCookie(w http.ResponseWriter, stateToken string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     OIDCStateCookieName,
+		Value:    stateToken,
+		Path:     OIDCStateCookiePath,
+		HttpOnly: true,
+		Secure:   os.Getenv("APP_ENV") != "local",
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// clearOIDCStateCookie clears the single sign-on state cookie
+func clearOIDCStateCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     OIDCStateCookieName,
+		Value:    "",
+		Path:     OIDCStateCookiePath,
+		HttpOnly: true,
+		Secure:   os.Getenv("APP_ENV") != "local",
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   -1,
+		Expires:  time.Unix(0, 0),
+	})
+}
+
+// setRetryAfter sets the Retry-After header in whole seconds, rounded up
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	seconds := int((d + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
 }
 
@@ -3,12 +3,19 @@ package auth
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"kc-api/internal/password"
 )
 
 // MockService is a mock implementation of the Service interface for testing
@@ -20,6 +27,63 @@ type MockService struct {
 	LogoutAllFunc           func(ctx context.Context, userID string) error
 	GetMeFunc               func(ctx context.Context, userID string) (*MeResponse, error)
 	ValidateAccessTokenFunc func(tokenString string) (*TokenClaims, error)
+	ValidateAPIKeyFunc      func(ctx context.Context, token, clientIP string) (*TokenClaims, error)
+	IsTokenRevokedFunc      func(ctx context.Context, claims *TokenClaims) bool
+	RevokeAccessTokenFunc   func(ctx context.Context, tokenString string) error
+	RevokeUserTokensFunc    func(ctx context.Context, userIDs ...int) error
+
+	VerifyMFAFunc                  func(ctx context.Context, req *VerifyMFARequest, clientIP, userAgent string) (*LoginResponse, string, error)
+	ValidateMFAEnrollmentTokenFunc func(tokenString string) (string, error)
+	GetMFAStatusFunc               func(ctx context.Context, userID string) (*MFAStatusResponse, error)
+	EnrollMFAFunc                  func(ctx context.Context, userID string) (*MFAEnrollmentResponse, error)
+	ConfirmMFAFunc                 func(ctx context.Context, userID string, req *MFACodeRequest) (*RecoveryCodesResponse, error)
+	DisableMFAFunc                 func(ctx context.Context, userID string, req *MFACodeRequest) error
+	RegenerateRecoveryCodesFunc    func(ctx context.Context, userID string, req *MFACodeRequest) (*RecoveryCodesResponse, error)
+
+	ForgotPasswordFunc          func(ctx context.Context, email string) error
+	ResetPasswordFunc           func(ctx context.Context, req *ResetPasswordRequest) error
+	VerifyEmailFunc             func(ctx context.Context, token string) error
+	ResendVerificationEmailFunc func(ctx context.Context, userID string) error
+	ChangeExpiredPasswordFunc   func(ctx context.Context, req *ChangeExpiredPasswordRequest, clientIP, userAgent string) (*LoginResponse, string, error)
+
+	GetPasswordPolicyFunc    func(ctx context.Context) (*PasswordPolicyResponse, error)
+	UpdatePasswordPolicyFunc func(ctx context.Context, actorID string, policy *password.Policy) (*PasswordPolicyResponse, error)
+	CheckNewPasswordFunc     func(ctx context.Context, userID int, loginID, email, newPassword string) error
+	PasswordChangedFunc      func(ctx context.Context, userID int, passwordHash string) error
+	HashPasswordFunc         func(plain string) (string, error)
+	VerifyPasswordHashFunc   func(plain, encodedHash string) bool
+
+	ShareLinkLockedForFunc     func(ctx context.Context, linkPublicID string) (time.Duration, error)
+	RecordShareLinkFailureFunc func(ctx context.Context, linkPublicID string) error
+
+	ListSessionsFunc  func(ctx context.Context, userID, currentRefreshToken string) (*SessionListResponse, error)
+	RevokeSessionFunc func(ctx context.Context, userID, sessionID string) error
+
+	UnlockLoginFunc        func(ctx context.Context, actorID string, req *UnlockLoginRequest, clientIP, userAgent string) error
+	ListLockoutsFunc       func(ctx context.Context) (*LockoutListResponse, error)
+	ListSecurityEventsFunc func(ctx context.Context, eventType string, page, limit int) (*SecurityEventListResponse, error)
+
+	ListAPIKeysFunc             func(ctx context.Context, userID string) (*APIKeyListResponse, error)
+	CreateAPIKeyFunc            func(ctx context.Context, userID string, req *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error)
+	RevokeAPIKeyFunc            func(ctx context.Context, userID, keyID string) error
+	ListServiceAccountsFunc     func(ctx context.Context) (*ServiceAccountListResponse, error)
+	CreateServiceAccountFunc    func(ctx context.Context, req *CreateServiceAccountRequest) (*UserInfo, error)
+	ListServiceAccountKeysFunc  func(ctx context.Context, accountID string) (*APIKeyListResponse, error)
+	CreateServiceAccountKeyFunc func(ctx context.Context, actorID, accountID string, req *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error)
+	RevokeServiceAccountKeyFunc func(ctx context.Context, accountID, keyID string) error
+
+	StartOIDCLoginFunc    func(ctx context.Context) (*OIDCAuthorizationResponse, string, error)
+	CompleteOIDCLoginFunc func(ctx context.Context, req *OIDCCallbackRequest, stateToken, clientIP, userAgent string) (*LoginResponse, string, error)
+	SyncDirectoryFunc     func(ctx context.Context, dryRun bool) (*DirectorySyncReport, error)
+	GetJWKSFunc           func(ctx context.Context) (*JSONWebKeySet, error)
+	ListSigningKeysFunc   func(ctx context.Context) (*SigningKeyListResponse, error)
+	RotateSigningKeyFunc  func(ctx context.Context, retire bool) (*SigningKeyInfo, error)
+
+	ImpersonateFunc               func(ctx context.Context, actor *TokenClaims, req *ImpersonateRequest, clientIP, userAgent string) (*ImpersonationTokenResponse, error)
+	EndImpersonationFunc          func(ctx context.Context, claims *TokenClaims) error
+	RecordImpersonatedRequestFunc func(ctx context.Context, claims *TokenClaims, method, path string, statusCode int, clientIP string)
+	ListImpersonationsFunc        func(ctx context.Context, actorID, userID string, page, limit int) (*ImpersonationListResponse, error)
+	ListImpersonatedRequestsFunc  func(ctx context.Context, impersonationID int64, page, limit int) (*ImpersonatedRequestListResponse, error)
 }
 
 func (m *MockService) Register(ctx context.Context, req *RegisterRequest, clientIP, userAgent string) (*RegisterResponse, string, error) {
@@ -71,6 +135,341 @@ func (m *MockService) ValidateAccessToken(tokenString string) (*TokenClaims, err
 	return nil, nil
 }
 
+func (m *MockService) VerifyMFA(ctx context.Context, req *VerifyMFARequest, clientIP, userAgent string) (*LoginResponse, string, error) {
+	if m.VerifyMFAFunc != nil {
+		return m.VerifyMFAFunc(ctx, req, clientIP, userAgent)
+	}
+	return nil, "", nil
+}
+
+func (m *MockService) ValidateMFAEnrollmentToken(tokenString string) (string, error) {
+	if m.ValidateMFAEnrollmentTokenFunc != nil {
+		return m.ValidateMFAEnrollmentTokenFunc(tokenString)
+	}
+	return "", ErrInvalidToken
+}
+
+func (m *MockService) GetMFAStatus(ctx context.Context, userID string) (*MFAStatusResponse, error) {
+	if m.GetMFAStatusFunc != nil {
+		return m.GetMFAStatusFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *MockService) EnrollMFA(ctx context.Context, userID string) (*MFAEnrollmentResponse, error) {
+	if m.EnrollMFAFunc != nil {
+		return m.EnrollMFAFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *MockService) ConfirmMFA(ctx context.Context, userID string, req *MFACodeRequest) (*RecoveryCodesResponse, error) {
+	if m.ConfirmMFAFunc != nil {
+		return m.ConfirmMFAFunc(ctx, userID, req)
+	}
+	return nil, nil
+}
+
+func (m *MockService) DisableMFA(ctx context.Context, userID string, req *MFACodeRequest) error {
+	if m.DisableMFAFunc != nil {
+		return m.DisableMFAFunc(ctx, userID, req)
+	}
+	return nil
+}
+
+func (m *MockService) RegenerateRecoveryCodes(ctx context.Context, userID string, req *MFACodeRequest) (*RecoveryCodesResponse, error) {
+	if m.RegenerateRecoveryCodesFunc != nil {
+		return m.RegenerateRecoveryCodesFunc(ctx, userID, req)
+	}
+	return nil, nil
+}
+
+func (m *MockService) ForgotPassword(ctx context.Context, email string) error {
+	if m.ForgotPasswordFunc != nil {
+		return m.ForgotPasswordFunc(ctx, email)
+	}
+	return nil
+}
+
+func (m *MockService) ResetPassword(ctx context.Context, req *ResetPasswordRequest) error {
+	if m.ResetPasswordFunc != nil {
+		return m.ResetPasswordFunc(ctx, req)
+	}
+	return nil
+}
+
+func (m *MockService) ChangeExpiredPassword(ctx context.Context, req *ChangeExpiredPasswordRequest, clientIP, userAgent string) (*LoginResponse, string, error) {
+	if m.ChangeExpiredPasswordFunc != nil {
+		return m.ChangeExpiredPasswordFunc(ctx, req, clientIP, userAgent)
+	}
+	return nil, "", nil
+}
+
+func (m *MockService) GetPasswordPolicy(ctx context.Context) (*PasswordPolicyResponse, error) {
+	if m.GetPasswordPolicyFunc != nil {
+		return m.GetPasswordPolicyFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *MockService) UpdatePasswordPolicy(ctx context.Context, actorID string, policy *password.Policy) (*PasswordPolicyResponse, error) {
+	if m.UpdatePasswordPolicyFunc != nil {
+		return m.UpdatePasswordPolicyFunc(ctx, actorID, policy)
+	}
+	return nil, nil
+}
+
+func (m *MockService) CheckNewPassword(ctx context.Context, userID int, loginID, email, newPassword string) error {
+	if m.CheckNewPasswordFunc != nil {
+		return m.CheckNewPasswordFunc(ctx, userID, loginID, email, newPassword)
+	}
+	return nil
+}
+
+func (m *MockService) PasswordChanged(ctx context.Context, userID int, passwordHash string) error {
+	if m.PasswordChangedFunc != nil {
+		return m.PasswordChangedFunc(ctx, userID, passwordHash)
+	}
+	return nil
+}
+
+func (m *MockService) HashPassword(plain string) (string, error) {
+	if m.HashPasswordFunc != nil {
+		return m.HashPasswordFunc(plain)
+	}
+	return "", nil
+}
+
+func (m *MockService) VerifyPasswordHash(plain, encodedHash string) bool {
+	if m.VerifyPasswordHashFunc != nil {
+		return m.VerifyPasswordHashFunc(plain, encodedHash)
+	}
+	return false
+}
+
+func (m *MockService) ShareLinkLockedFor(ctx context.Context, linkPublicID string) (time.Duration, error) {
+	if m.ShareLinkLockedForFunc != nil {
+		return m.ShareLinkLockedForFunc(ctx, linkPublicID)
+	}
+	return 0, nil
+}
+
+func (m *MockService) RecordShareLinkFailure(ctx context.Context, linkPublicID string) error {
+	if m.RecordShareLinkFailureFunc != nil {
+		return m.RecordShareLinkFailureFunc(ctx, linkPublicID)
+	}
+	return nil
+}
+
+func (m *MockService) VerifyEmail(ctx context.Context, token string) error {
+	if m.VerifyEmailFunc != nil {
+		return m.VerifyEmailFunc(ctx, token)
+	}
+	return nil
+}
+
+func (m *MockService) ResendVerificationEmail(ctx context.Context, userID string) error {
+	if m.ResendVerificationEmailFunc != nil {
+		return m.ResendVerificationEmailFunc(ctx, userID)
+	}
+	return nil
+}
+
+func (m *MockService) ListSessions(ctx context.Context, userID, currentRefreshToken string) (*SessionListResponse, error) {
+	if m.ListSessionsFunc != nil {
+		return m.ListSessionsFunc(ctx, userID, currentRefreshToken)
+	}
+	return &SessionListResponse{Data: []SessionResponse{}}, nil
+}
+
+func (m *MockService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	if m.RevokeSessionFunc != nil {
+		return m.RevokeSessionFunc(ctx, userID, sessionID)
+	}
+	return nil
+}
+
+func (m *MockService) UnlockLogin(ctx context.Context, actorID string, req *UnlockLoginRequest, clientIP, userAgent string) error {
+	if m.UnlockLoginFunc != nil {
+		return m.UnlockLoginFunc(ctx, actorID, req, clientIP, userAgent)
+	}
+	return nil
+}
+
+func (m *MockService) ListLockouts(ctx context.Context) (*LockoutListResponse, error) {
+	if m.ListLockoutsFunc != nil {
+		return m.ListLockoutsFunc(ctx)
+	}
+	return &LockoutListResponse{Data: []LockoutResponse{}}, nil
+}
+
+func (m *MockService) ListSecurityEvents(ctx context.Context, eventType string, page, limit int) (*SecurityEventListResponse, error) {
+	if m.ListSecurityEventsFunc != nil {
+		return m.ListSecurityEventsFunc(ctx, eventType, page, limit)
+	}
+	return &SecurityEventListResponse{Data: []SecurityEventResponse{}, Page: page, Limit: limit}, nil
+}
+
+func (m *MockService) ValidateAPIKey(ctx context.Context, token, clientIP string) (*TokenClaims, error) {
+	if m.ValidateAPIKeyFunc != nil {
+		return m.ValidateAPIKeyFunc(ctx, token, clientIP)
+	}
+	return nil, ErrInvalidToken
+}
+
+func (m *MockService) IsTokenRevoked(ctx context.Context, claims *TokenClaims) bool {
+	if m.IsTokenRevokedFunc != nil {
+		return m.IsTokenRevokedFunc(ctx, claims)
+	}
+	return false
+}
+
+func (m *MockService) RevokeAccessToken(ctx context.Context, tokenString string) error {
+	if m.RevokeAccessTokenFunc != nil {
+		return m.RevokeAccessTokenFunc(ctx, tokenString)
+	}
+	return nil
+}
+
+func (m *MockService) RevokeUserTokens(ctx context.Context, userIDs ...int) error {
+	if m.RevokeUserTokensFunc != nil {
+		return m.RevokeUserTokensFunc(ctx, userIDs...)
+	}
+	return nil
+}
+
+func (m *MockService) ListAPIKeys(ctx context.Context, userID string) (*APIKeyListResponse, error) {
+	if m.ListAPIKeysFunc != nil {
+		return m.ListAPIKeysFunc(ctx, userID)
+	}
+	return &APIKeyListResponse{Data: []APIKeyResponse{}}, nil
+}
+
+func (m *MockService) CreateAPIKey(ctx context.Context, userID string, req *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+	if m.CreateAPIKeyFunc != nil {
+		return m.CreateAPIKeyFunc(ctx, userID, req)
+	}
+	return nil, nil
+}
+
+func (m *MockService) RevokeAPIKey(ctx context.Context, userID, keyID string) error {
+	if m.RevokeAPIKeyFunc != nil {
+		return m.RevokeAPIKeyFunc(ctx, userID, keyID)
+	}
+	return nil
+}
+
+func (m *MockService) ListServiceAccounts(ctx context.Context) (*ServiceAccountListResponse, error) {
+	if m.ListServiceAccountsFunc != nil {
+		return m.ListServiceAccountsFunc(ctx)
+	}
+	return &ServiceAccountListResponse{Data: []UserInfo{}}, nil
+}
+
+func (m *MockService) CreateServiceAccount(ctx context.Context, req *CreateServiceAccountRequest) (*UserInfo, error) {
+	if m.CreateServiceAccountFunc != nil {
+		return m.CreateServiceAccountFunc(ctx, req)
+	}
+	return nil, nil
+}
+
+func (m *MockService) ListServiceAccountKeys(ctx context.Context, accountID string) (*APIKeyListResponse, error) {
+	if m.ListServiceAccountKeysFunc != nil {
+		return m.ListServiceAccountKeysFunc(ctx, accountID)
+	}
+	return &APIKeyListResponse{Data: []APIKeyResponse{}}, nil
+}
+
+func (m *MockService) CreateServiceAccountKey(ctx context.Context, actorID, accountID string, req *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+	if m.CreateServiceAccountKeyFunc != nil {
+		return m.CreateServiceAccountKeyFunc(ctx, actorID, accountID, req)
+	}
+	return nil, nil
+}
+
+func (m *MockService) RevokeServiceAccountKey(ctx context.Context, accountID, keyID string) error {
+	if m.RevokeServiceAccountKeyFunc != nil {
+		return m.RevokeServiceAccountKeyFunc(ctx, accountID, keyID)
+	}
+	return nil
+}
+
+func (m *MockService) StartOIDCLogin(ctx context.Context) (*OIDCAuthorizationResponse, string, error) {
+	if m.StartOIDCLoginFunc != nil {
+		return m.StartOIDCLoginFunc(ctx)
+	}
+	return nil, "", nil
+}
+
+func (m *MockService) CompleteOIDCLogin(ctx context.Context, req *OIDCCallbackRequest, stateToken, clientIP, userAgent string) (*LoginResponse, string, error) {
+	if m.CompleteOIDCLoginFunc != nil {
+		return m.CompleteOIDCLoginFunc(ctx, req, stateToken, clientIP, userAgent)
+	}
+	return nil, "", nil
+}
+
+func (m *MockService) SyncDirectory(ctx context.Context, dryRun bool) (*DirectorySyncReport, error) {
+	if m.SyncDirectoryFunc != nil {
+		return m.SyncDirectoryFunc(ctx, dryRun)
+	}
+	return nil, nil
+}
+
+func (m *MockService) GetJWKS(ctx context.Context) (*JSONWebKeySet, error) {
+	if m.GetJWKSFunc != nil {
+		return m.GetJWKSFunc(ctx)
+	}
+	return &JSONWebKeySet{Keys: []JSONWebKey{}}, nil
+}
+
+func (m *MockService) ListSigningKeys(ctx context.Context) (*SigningKeyListResponse, error) {
+	if m.ListSigningKeysFunc != nil {
+		return m.ListSigningKeysFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *MockService) RotateSigningKey(ctx context.Context, retire bool) (*SigningKeyInfo, error) {
+	if m.RotateSigningKeyFunc != nil {
+		return m.RotateSigningKeyFunc(ctx, retire)
+	}
+	return nil, nil
+}
+
+func (m *MockService) Impersonate(ctx context.Context, actor *TokenClaims, req *ImpersonateRequest, clientIP, userAgent string) (*ImpersonationTokenResponse, error) {
+	if m.ImpersonateFunc != nil {
+		return m.ImpersonateFunc(ctx, actor, req, clientIP, userAgent)
+	}
+	return nil, nil
+}
+
+func (m *MockService) EndImpersonation(ctx context.Context, claims *TokenClaims) error {
+	if m.EndImpersonationFunc != nil {
+		return m.EndImpersonationFunc(ctx, claims)
+	}
+	return nil
+}
+
+func (m *MockService) RecordImpersonatedRequest(ctx context.Context, claims *TokenClaims, method, path string, statusCode int, clientIP string) {
+	if m.RecordImpersonatedRequestFunc != nil {
+		m.RecordImpersonatedRequestFunc(ctx, claims, method, path, statusCode, clientIP)
+	}
+}
+
+func (m *MockService) ListImpersonations(ctx context.Context, actorID, userID string, page, limit int) (*ImpersonationListResponse, error) {
+	if m.ListImpersonationsFunc != nil {
+		return m.ListImpersonationsFunc(ctx, actorID, userID, page, limit)
+	}
+	return nil, nil
+}
+
+func (m *MockService) ListImpersonatedRequests(ctx context.Context, impersonationID int64, page, limit int) (*ImpersonatedRequestListResponse, error) {
+	if m.ListImpersonatedRequestsFunc != nil {
+		return m.ListImpersonatedRequestsFunc(ctx, impersonationID, page, limit)
+	}
+	return nil, nil
+}
+
 func TestHandler_Register(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -94,7 +493,7 @@ func TestHandler_Register(t *testing.T) {
 					Name:    json.RawMessage(`{"en-US": "Test User"}`),
 					Email:   "test@example.com",
 				},
-				Tokens: TokenResponse{
+				Tokens: &TokenResponse{
 					AccessToken: "test-access-token",
 					TokenType:   "Bearer",
 					ExpiresIn:   900,
@@ -159,7 +558,7 @@ func TestHandler_Register(t *testing.T) {
 				},
 			}
 
-			handler := NewHandler(mockService)
+			handler := NewHandler(mockService, nil)
 			r := chi.NewRouter()
 			handler.RegisterRoutes(r)
 
@@ -220,7 +619,7 @@ func TestHandler_Login(t *testing.T) {
 					Name:    json.RawMessage(`{"en-US": "Test User"}`),
 					Email:   "test@example.com",
 				},
-				Tokens: TokenResponse{
+				Tokens: &TokenResponse{
 					AccessToken: "test-access-token",
 					TokenType:   "Bearer",
 					ExpiresIn:   900,
@@ -241,6 +640,33 @@ func TestHandler_Login(t *testing.T) {
 			mockError:      ErrInvalidCredentials,
 			expectedStatus: http.StatusUnauthorized,
 		},
+		{
+			name: "locked out",
+			requestBody: LoginRequest{
+				LoginID:  "test@example.com",
+				Password: "password123",
+			},
+			mockError:      &LoginBlockedError{Locked: true, RetryAfter: 10 * time.Minute},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name: "too soon after a failure",
+			requestBody: LoginRequest{
+				LoginID:  "test@example.com",
+				Password: "password123",
+			},
+			mockError:      &LoginBlockedError{RetryAfter: 1500 * time.Millisecond},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name: "directory unavailable",
+			requestBody: LoginRequest{
+				LoginID:  "jdoe",
+				Password: "password123",
+			},
+			mockError:      ErrLDAPUnavailable,
+			expectedStatus: http.StatusBadGateway,
+		},
 		{
 			name:           "invalid request body",
 			requestBody:    "invalid json",
@@ -259,7 +685,7 @@ func TestHandler_Login(t *testing.T) {
 				},
 			}
 
-			handler := NewHandler(mockService)
+			handler := NewHandler(mockService, nil)
 			r := chi.NewRouter()
 			handler.RegisterRoutes(r)
 
@@ -337,6 +763,35 @@ func TestHandler_Refresh(t *testing.T) {
 			mockError:      ErrTokenRevoked,
 			expectedStatus: http.StatusUnauthorized,
 		},
+		{
+			name: "session without required MFA",
+			cookie: &http.Cookie{
+				Name:  RefreshTokenCookieName,
+				Value: "password-only-token",
+			},
+			mockReturn:     nil,
+			mockRefresh:    "",
+			mockError:      ErrMFARequired,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "reused token",
+			cookie: &http.Cookie{
+				Name:  RefreshTokenCookieName,
+				Value: "stolen-token",
+			},
+			mockError:      ErrTokenReused,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "concurrent refresh",
+			cookie: &http.Cookie{
+				Name:  RefreshTokenCookieName,
+				Value: "just-rotated-token",
+			},
+			mockError:      ErrTokenRotated,
+			expectedStatus: http.StatusConflict,
+		},
 	}
 
 	for _, tt := range tests {
@@ -347,7 +802,7 @@ func TestHandler_Refresh(t *testing.T) {
 				},
 			}
 
-			handler := NewHandler(mockService)
+			handler := NewHandler(mockService, nil)
 			r := chi.NewRouter()
 			handler.RegisterRoutes(r)
 
@@ -362,31 +817,138 @@ func TestHandler_Refresh(t *testing.T) {
 			if rec.Code != tt.expectedStatus {
 				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
 			}
+
+			// A concurrent refresh must not clear the replacement cookie set by the other request
+			if tt.mockError == ErrTokenRotated && rec.Header().Get("Set-Cookie") != "" {
+				t.Errorf("expected no cookie change, got %q", rec.Header().Get("Set-Cookie"))
+			}
 		})
 	}
 }
 
-func TestHandler_Logout(t *testing.T) {
+// reuseRepository records the revocations and security events of revokedTokenRefresh
+type reuseRepository struct {
+	Repository
+	revokedSessions []string
+	revokedAll      bool
+	events          []SecurityEvent
+}
+
+func (r *reuseRepository) RevokeSession(ctx context.Context, userID int, sessionID string) (bool, error) {
+	r.revokedSessions = append(r.revokedSessions, sessionID)
+	return true, nil
+}
+
+func (r *reuseRepository) RevokeAllUserTokens(ctx context.Context, userID int) error {
+	r.revokedAll = true
+	return nil
+}
+
+func (r *reuseRepository) GetUserPublicIDs(ctx context.Context, userIDs []int) ([]string, error) {
+	publicIDs := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		publicIDs = append(publicIDs, fmt.Sprintf("user-%d", id))
+	}
+	return publicIDs, nil
+}
+
+func (r *reuseRepository) CreateSecurityEvent(ctx context.Context, event *SecurityEvent) error {
+	r.events = append(r.events, *event)
+	return nil
+}
+
+func TestRevokedTokenRefresh(t *testing.T) {
+	justNow := time.Now().Add(-2 * time.Second)
+	longAgo := time.Now().Add(-time.Hour)
+	replacementID := int64(2)
+
 	tests := []struct {
-		name           string
-		cookie         *http.Cookie
-		mockError      error
-		expectedStatus int
+		name              string
+		token             UserToken
+		expectedErr       error
+		expectedRevoke    bool
+		expectedRevokeAll bool
+		expectedEvent     bool
 	}{
 		{
-			name: "successful logout with cookie",
-			cookie: &http.Cookie{
-				Name:  RefreshTokenCookieName,
-				Value: "valid-token",
-			},
-			mockError:      nil,
-			expectedStatus: http.StatusOK,
+			name:        "rotated within grace period",
+			token:       UserToken{ID: 1, UserID: 7, SessionID: "session-1", RotatedAt: &justNow, ReplacedByTokenID: &replacementID},
+			expectedErr: ErrTokenRotated,
 		},
 		{
-			name:           "logout without cookie",
-			cookie:         nil,
-			mockError:      nil,
-			expectedStatus: http.StatusOK, // Should still succeed
+			name:           "rotated before grace period",
+			token:          UserToken{ID: 1, UserID: 7, SessionID: "session-1", RotatedAt: &longAgo, ReplacedByTokenID: &replacementID},
+			expectedErr:    ErrTokenReused,
+			expectedRevoke: true,
+			expectedEvent:  true,
+		},
+		{
+			name:        "revoked by logout",
+			token:       UserToken{ID: 1, UserID: 7, SessionID: "session-1"},
+			expectedErr: ErrTokenRevoked,
+		},
+		{
+			name:        "revoked by logout without a session",
+			token:       UserToken{ID: 1, UserID: 7},
+			expectedErr: ErrTokenRevoked,
+		},
+		{
+			name:              "rotated without a session before grace period",
+			token:             UserToken{ID: 1, UserID: 7, RotatedAt: &longAgo, ReplacedByTokenID: &replacementID},
+			expectedErr:       ErrTokenReused,
+			expectedRevokeAll: true,
+			expectedEvent:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &reuseRepository{}
+			s := &service{repo: repo, config: &Config{RefreshReuseGrace: 10 * time.Second}, denylist: newTokenDenylist(nil)}
+
+			err := s.revokedTokenRefresh(context.Background(), &tt.token, "203.0.113.7", "test-agent")
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if revoked := len(repo.revokedSessions) == 1 && repo.revokedSessions[0] == "session-1"; revoked != tt.expectedRevoke {
+				t.Errorf("expected session revoked %v, got %v", tt.expectedRevoke, repo.revokedSessions)
+			}
+			if repo.revokedAll != tt.expectedRevokeAll {
+				t.Errorf("expected all tokens of the user revoked %v, got %v", tt.expectedRevokeAll, repo.revokedAll)
+			}
+			if recorded := len(repo.events) == 1 && repo.events[0].EventType == SecurityEventRefreshTokenReuse; recorded != tt.expectedEvent {
+				t.Errorf("expected reuse event %v, got %+v", tt.expectedEvent, repo.events)
+			}
+			// The access tokens of a reused session are revoked with it
+			accessToken := &TokenClaims{UserID: "user-7", IssuedAt: time.Now().Unix() - 1}
+			if denied := s.IsTokenRevoked(context.Background(), accessToken); denied != tt.expectedEvent {
+				t.Errorf("expected access tokens revoked %v, got %v", tt.expectedEvent, denied)
+			}
+		})
+	}
+}
+
+func TestHandler_Logout(t *testing.T) {
+	tests := []struct {
+		name           string
+		cookie         *http.Cookie
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name: "successful logout with cookie",
+			cookie: &http.Cookie{
+				Name:  RefreshTokenCookieName,
+				Value: "valid-token",
+			},
+			mockError:      nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "logout without cookie",
+			cookie:         nil,
+			mockError:      nil,
+			expectedStatus: http.StatusOK, // Should still succeed
 		},
 	}
 
@@ -398,7 +960,7 @@ func TestHandler_Logout(t *testing.T) {
 				},
 			}
 
-			handler := NewHandler(mockService)
+			handler := NewHandler(mockService, nil)
 			r := chi.NewRouter()
 			handler.RegisterRoutes(r)
 
@@ -427,130 +989,243 @@ func TestHandler_Logout(t *testing.T) {
 	}
 }
 
-func TestMiddleware_Authenticate(t *testing.T) {
+func TestHandler_LogoutRevokesAccessToken(t *testing.T) {
+	var revoked []string
+	mockService := &MockService{
+		RevokeAccessTokenFunc: func(ctx context.Context, tokenString string) error {
+			revoked = append(revoked, tokenString)
+			return nil
+		},
+	}
+
+	handler := NewHandler(mockService, nil)
+	r := chi.NewRouter()
+	handler.RegisterRoutes(r)
+
+	for _, authHeader := range []string{"Bearer access-token", "Bearer kcp_token", ""} {
+		req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+		if authHeader != "" {
+			req.Header.Set("Authorization", authHeader)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+	}
+
+	// API keys are revoked through their own endpoints
+	if len(revoked) != 1 || revoked[0] != "access-token" {
+		t.Errorf("expected only the access token to be revoked, got %v", revoked)
+	}
+}
+
+func TestHandler_LoginMFAChallenge(t *testing.T) {
+	mockService := &MockService{
+		LoginFunc: func(ctx context.Context, req *LoginRequest, clientIP, userAgent string) (*LoginResponse, string, error) {
+			return &LoginResponse{
+				User: UserInfo{ID: "01912345-6789-7abc-def0-123456789abc"},
+				MFA:  &MFAChallengeResponse{Required: true, Token: "mfa-token", ExpiresIn: 300},
+			}, "", nil
+		},
+	}
+
+	handler := NewHandler(mockService, nil)
+	r := chi.NewRouter()
+	handler.RegisterRoutes(r)
+
+	body, _ := json.Marshal(LoginRequest{LoginID: "test@example.com", Password: "password123"})
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if cookies := rec.Result().Cookies(); len(cookies) != 0 {
+		t.Errorf("expected no refresh token cookie before MFA, got %v", cookies)
+	}
+
+	var resp map[string]json.RawMessage
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if _, ok := resp["tokens"]; ok {
+		t.Error("expected no tokens before MFA")
+	}
+	if _, ok := resp["mfa"]; !ok {
+		t.Error("expected MFA challenge in response")
+	}
+}
+
+func TestHandler_VerifyMFA(t *testing.T) {
 	tests := []struct {
 		name           string
-		authHeader     string
-		mockClaims     *TokenClaims
+		requestBody    interface{}
 		mockError      error
 		expectedStatus int
+		expectCookie   bool
 	}{
 		{
-			name:       "valid token",
-			authHeader: "Bearer valid-token",
-			mockClaims: &TokenClaims{
-				UserID:  "user-123",
-				LoginID: "test@example.com",
-				Email:   "test@example.com",
-				Roles:   []string{"user"},
-			},
-			mockError:      nil,
+			name:           "valid code",
+			requestBody:    VerifyMFARequest{MFAToken: "mfa-token", Code: "123456"},
 			expectedStatus: http.StatusOK,
+			expectCookie:   true,
 		},
 		{
-			name:           "missing authorization header",
-			authHeader:     "",
-			mockClaims:     nil,
-			mockError:      nil,
-			expectedStatus: http.StatusUnauthorized,
+			name:           "valid recovery code",
+			requestBody:    VerifyMFARequest{MFAToken: "mfa-token", RecoveryCode: "k7m2p-x9q4t"},
+			expectedStatus: http.StatusOK,
+			expectCookie:   true,
 		},
 		{
-			name:           "invalid header format",
-			authHeader:     "InvalidFormat",
-			mockClaims:     nil,
-			mockError:      nil,
+			name:           "invalid code",
+			requestBody:    VerifyMFARequest{MFAToken: "mfa-token", Code: "000000"},
+			mockError:      ErrInvalidMFACode,
 			expectedStatus: http.StatusUnauthorized,
 		},
 		{
-			name:           "invalid token",
-			authHeader:     "Bearer invalid-token",
-			mockClaims:     nil,
+			name:           "expired challenge",
+			requestBody:    VerifyMFARequest{MFAToken: "expired-token", Code: "123456"},
 			mockError:      ErrInvalidToken,
 			expectedStatus: http.StatusUnauthorized,
 		},
+		{
+			name:           "login locked",
+			requestBody:    VerifyMFARequest{MFAToken: "mfa-token", Code: "123456"},
+			mockError:      &LoginBlockedError{Locked: true, RetryAfter: time.Minute},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:           "missing code",
+			requestBody:    VerifyMFARequest{MFAToken: "mfa-token"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid request body",
+			requestBody:    "invalid json",
+			expectedStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockService := &MockService{
-				ValidateAccessTokenFunc: func(tokenString string) (*TokenClaims, error) {
-					return tt.mockClaims, tt.mockError
+				VerifyMFAFunc: func(ctx context.Context, req *VerifyMFARequest, clientIP, userAgent string) (*LoginResponse, string, error) {
+					if tt.mockError != nil {
+						return nil, "", tt.mockError
+					}
+					return &LoginResponse{
+						Tokens: &TokenResponse{AccessToken: "test-access-token", TokenType: "Bearer", ExpiresIn: 900},
+					}, "test-refresh-token", nil
 				},
 			}
 
-			middleware := NewMiddleware(mockService)
-
-			// Create a test handler that just returns 200 OK
-			testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				w.WriteHeader(http.StatusOK)
-			})
+			handler := NewHandler(mockService, nil)
+			r := chi.NewRouter()
+			handler.RegisterRoutes(r)
 
-			req := httptest.NewRequest(http.MethodGet, "/test", nil)
-			if tt.authHeader != "" {
-				req.Header.Set("Authorization", tt.authHeader)
+			var body []byte
+			if str, ok := tt.requestBody.(string); ok {
+				body = []byte(str)
+			} else {
+				body, _ = json.Marshal(tt.requestBody)
 			}
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/mfa/verify", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
 			rec := httptest.NewRecorder()
 
-			middleware.Authenticate(testHandler).ServeHTTP(rec, req)
+			r.ServeHTTP(rec, req)
 
 			if rec.Code != tt.expectedStatus {
 				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
 			}
+
+			hasCookie := false
+			for _, cookie := range rec.Result().Cookies() {
+				if cookie.Name == RefreshTokenCookieName && cookie.Value != "" {
+					hasCookie = true
+				}
+			}
+			if hasCookie != tt.expectCookie {
+				t.Errorf("expected refresh token cookie %v, got %v", tt.expectCookie, hasCookie)
+			}
 		})
 	}
 }
 
-func TestMiddleware_RequireRoles(t *testing.T) {
+func TestHandler_EnrollMFA(t *testing.T) {
 	tests := []struct {
 		name           string
-		userRoles      []string
-		requiredRoles  []string
+		authHeader     string
+		mockError      error
 		expectedStatus int
 	}{
 		{
-			name:           "user has required role",
-			userRoles:      []string{"admin", "user"},
-			requiredRoles:  []string{"admin"},
+			name:           "access token",
+			authHeader:     "Bearer access-token",
 			expectedStatus: http.StatusOK,
 		},
 		{
-			name:           "user has one of required roles",
-			userRoles:      []string{"user"},
-			requiredRoles:  []string{"admin", "user"},
+			name:           "enrollment challenge token",
+			authHeader:     "Bearer enroll-token",
 			expectedStatus: http.StatusOK,
 		},
 		{
-			name:           "user missing required role",
-			userRoles:      []string{"user"},
-			requiredRoles:  []string{"admin"},
-			expectedStatus: http.StatusForbidden,
+			name:           "verify challenge token",
+			authHeader:     "Bearer verify-token",
+			expectedStatus: http.StatusUnauthorized,
 		},
 		{
-			name:           "no user roles",
-			userRoles:      nil,
-			requiredRoles:  []string{"admin"},
-			expectedStatus: http.StatusForbidden,
+			name:           "missing authorization",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "already enabled",
+			authHeader:     "Bearer access-token",
+			mockError:      ErrMFAAlreadyEnabled,
+			expectedStatus: http.StatusConflict,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockService := &MockService{}
-			middleware := NewMiddleware(mockService)
-
-			testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				w.WriteHeader(http.StatusOK)
-			})
-
-			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			mockService := &MockService{
+				ValidateAccessTokenFunc: func(tokenString string) (*TokenClaims, error) {
+					if tokenString == "access-token" {
+						return &TokenClaims{UserID: "user-123"}, nil
+					}
+					return nil, ErrInvalidToken
+				},
+				ValidateMFAEnrollmentTokenFunc: func(tokenString string) (string, error) {
+					if tokenString == "enroll-token" {
+						return "user-123", nil
+					}
+					return "", ErrInvalidToken
+				},
+				EnrollMFAFunc: func(ctx context.Context, userID string) (*MFAEnrollmentResponse, error) {
+					if userID != "user-123" {
+						t.Errorf("expected user-123, got %s", userID)
+					}
+					if tt.mockError != nil {
+						return nil, tt.mockError
+					}
+					return &MFAEnrollmentResponse{Secret: "JBSWY3DPEHPK3PXP", ProvisioningURI: "otpauth://totp/test"}, nil
+				},
+			}
 
-			// Add roles to context
-			ctx := context.WithValue(req.Context(), userRolesKey, tt.userRoles)
-			req = req.WithContext(ctx)
+			handler := NewHandler(mockService, nil)
+			r := chi.NewRouter()
+			handler.RegisterRoutes(r)
 
+			req := httptest.NewRequest(http.MethodPost, "/auth/mfa/enroll", nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
 			rec := httptest.NewRecorder()
 
-			middleware.RequireRoles(tt.requiredRoles...)(testHandler).ServeHTTP(rec, req)
+			r.ServeHTTP(rec, req)
 
 			if rec.Code != tt.expectedStatus {
 				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
@@ -559,51 +1234,1764 @@ func TestMiddleware_RequireRoles(t *testing.T) {
 	}
 }
 
-func TestContextHelpers(t *testing.T) {
-	t.Run("GetUserIDFromContext", func(t *testing.T) {
-		ctx := context.WithValue(context.Background(), userIDKey, "test-user-id")
-		userID := GetUserIDFromContext(ctx)
-		if userID != "test-user-id" {
-			t.Errorf("expected 'test-user-id', got '%s'", userID)
-		}
-
-		// Test with empty context
-		emptyUserID := GetUserIDFromContext(context.Background())
-		if emptyUserID != "" {
-			t.Errorf("expected empty string, got '%s'", emptyUserID)
-		}
-	})
+func TestHandler_DisableMFA(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    interface{}
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "successful disable",
+			requestBody:    MFACodeRequest{Code: "123456"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "required by role",
+			requestBody:    MFACodeRequest{Code: "123456"},
+			mockError:      ErrMFAEnforced,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "invalid code",
+			requestBody:    MFACodeRequest{Code: "000000"},
+			mockError:      ErrInvalidMFACode,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "not enabled",
+			requestBody:    MFACodeRequest{Code: "123456"},
+			mockError:      ErrMFANotEnabled,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing code",
+			requestBody:    MFACodeRequest{},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
 
-	t.Run("GetUserRolesFromContext", func(t *testing.T) {
-		roles := []string{"admin", "user"}
-		ctx := context.WithValue(context.Background(), userRolesKey, roles)
-		result := GetUserRolesFromContext(ctx)
-		if len(result) != 2 {
-			t.Errorf("expected 2 roles, got %d", len(result))
-		}
-	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{
+				DisableMFAFunc: func(ctx context.Context, userID string, req *MFACodeRequest) error {
+					return tt.mockError
+				},
+			}
 
-	t.Run("IsAuthenticated", func(t *testing.T) {
-		ctx := context.WithValue(context.Background(), userIDKey, "test-user-id")
-		if !IsAuthenticated(ctx) {
-			t.Error("expected IsAuthenticated to return true")
-		}
+			handler := NewHandler(mockService, nil)
+			r := chi.NewRouter()
+			r.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					ctx := context.WithValue(r.Context(), userIDKey, "user-123")
+					next.ServeHTTP(w, r.WithContext(ctx))
+				})
+			})
+			handler.RegisterProtectedRoutes(r)
 
-		if IsAuthenticated(context.Background()) {
-			t.Error("expected IsAuthenticated to return false for empty context")
-		}
-	})
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest(http.MethodPost, "/auth/mfa/disable", bytes.NewReader(body))
+			rec := httptest.NewRecorder()
 
-	t.Run("HasRole", func(t *testing.T) {
-		roles := []string{"admin", "user"}
-		ctx := context.WithValue(context.Background(), userRolesKey, roles)
+			r.ServeHTTP(rec, req)
 
-		if !HasRole(ctx, "admin") {
-			t.Error("expected HasRole to return true for 'admin'")
-		}
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
 
-		if HasRole(ctx, "superadmin") {
+func TestHandler_ForgotPassword(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    interface{}
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "known or unknown address",
+			requestBody:    ForgotPasswordRequest{Email: "test@example.com"},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "invalid email",
+			requestBody:    ForgotPasswordRequest{Email: "invalid"},
+			mockError:      ErrInvalidEmail,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "rate limited",
+			requestBody:    ForgotPasswordRequest{Email: "test@example.com"},
+			mockError:      ErrTooManyRequests,
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:           "mail not configured",
+			requestBody:    ForgotPasswordRequest{Email: "test@example.com"},
+			mockError:      ErrMailNotConfigured,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "invalid request body",
+			requestBody:    "invalid json",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{
+				ForgotPasswordFunc: func(ctx context.Context, email string) error {
+					return tt.mockError
+				},
+			}
+
+			handler := NewHandler(mockService, nil)
+			r := chi.NewRouter()
+			handler.RegisterRoutes(r)
+
+			var body []byte
+			if str, ok := tt.requestBody.(string); ok {
+				body = []byte(str)
+			} else {
+				body, _ = json.Marshal(tt.requestBody)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/password/forgot", bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_ResetPassword(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    interface{}
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "successful reset",
+			requestBody:    ResetPasswordRequest{Token: "reset-token", Password: "newPassword123"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "expired or used token",
+			requestBody:    ResetPasswordRequest{Token: "used-token", Password: "newPassword123"},
+			mockError:      ErrInvalidToken,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "weak password",
+			requestBody:    ResetPasswordRequest{Token: "reset-token", Password: "short"},
+			mockError:      ErrInvalidPassword,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing token",
+			requestBody:    ResetPasswordRequest{Password: "newPassword123"},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{
+				ResetPasswordFunc: func(ctx context.Context, req *ResetPasswordRequest) error {
+					return tt.mockError
+				},
+			}
+
+			handler := NewHandler(mockService, nil)
+			r := chi.NewRouter()
+			handler.RegisterRoutes(r)
+
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest(http.MethodPost, "/auth/password/reset", bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_VerifyEmail(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    interface{}
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "successful verification",
+			requestBody:    VerifyEmailRequest{Token: "verify-token"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid token",
+			requestBody:    VerifyEmailRequest{Token: "invalid-token"},
+			mockError:      ErrInvalidToken,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing token",
+			requestBody:    VerifyEmailRequest{},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{
+				VerifyEmailFunc: func(ctx context.Context, token string) error {
+					return tt.mockError
+				},
+			}
+
+			handler := NewHandler(mockService, nil)
+			r := chi.NewRouter()
+			handler.RegisterRoutes(r)
+
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest(http.MethodPost, "/auth/email/verify", bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_ListSessions(t *testing.T) {
+	var gotUser, gotToken string
+	mockService := &MockService{
+		ListSessionsFunc: func(ctx context.Context, userID, currentRefreshToken string) (*SessionListResponse, error) {
+			gotUser, gotToken = userID, currentRefreshToken
+			return &SessionListResponse{Data: []SessionResponse{{ID: "9b2f6c1e-3a4d-4f5e-8a7b-1c2d3e4f5a6b", Current: true}}}, nil
+		},
+	}
+
+	handler := NewHandler(mockService, nil)
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), userIDKey, "user-123")
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	handler.RegisterProtectedRoutes(r)
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/sessions", nil)
+	req.AddCookie(&http.Cookie{Name: RefreshTokenCookieName, Value: "current-refresh-token"})
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if gotUser != "user-123" || gotToken != "current-refresh-token" {
+		t.Errorf("got user %q, refresh token %q", gotUser, gotToken)
+	}
+
+	var resp SessionListResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || len(resp.Data) != 1 || !resp.Data[0].Current {
+		t.Errorf("unexpected response %s", rec.Body.String())
+	}
+}
+
+func TestHandler_RevokeSession(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		mockError      error
+		expectedUser   string
+		expectedStatus int
+	}{
+		{
+			name:           "revoke own session",
+			path:           "/auth/sessions/9b2f6c1e-3a4d-4f5e-8a7b-1c2d3e4f5a6b",
+			expectedUser:   "user-123",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown session",
+			path:           "/auth/sessions/9b2f6c1e-3a4d-4f5e-8a7b-1c2d3e4f5a6b",
+			mockError:      ErrSessionNotFound,
+			expectedUser:   "user-123",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "admin revokes session of another user",
+			path:           "/admin/auth/users/01912345-6789-7abc-def0-123456789abc/sessions/9b2f6c1e-3a4d-4f5e-8a7b-1c2d3e4f5a6b",
+			expectedUser:   "01912345-6789-7abc-def0-123456789abc",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "admin with unknown user",
+			path:           "/admin/auth/users/unknown/sessions/9b2f6c1e-3a4d-4f5e-8a7b-1c2d3e4f5a6b",
+			mockError:      ErrUserNotFound,
+			expectedUser:   "unknown",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUser, gotSession string
+			mockService := &MockService{
+				RevokeSessionFunc: func(ctx context.Context, userID, sessionID string) error {
+					gotUser, gotSession = userID, sessionID
+					return tt.mockError
+				},
+			}
+
+			handler := NewHandler(mockService, nil)
+			r := chi.NewRouter()
+			r.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					ctx := context.WithValue(r.Context(), userIDKey, "user-123")
+					next.ServeHTTP(w, r.WithContext(ctx))
+				})
+			})
+			handler.RegisterProtectedRoutes(r)
+
+			req := httptest.NewRequest(http.MethodDelete, tt.path, nil)
+			req = asAdmin(req)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if gotUser != tt.expectedUser || gotSession != "9b2f6c1e-3a4d-4f5e-8a7b-1c2d3e4f5a6b" {
+				t.Errorf("revoked session %q of user %q", gotSession, gotUser)
+			}
+		})
+	}
+}
+
+func TestHandler_LoginRetryAfter(t *testing.T) {
+	mockService := &MockService{
+		LoginFunc: func(ctx context.Context, req *LoginRequest, clientIP, userAgent string) (*LoginResponse, string, error) {
+			return nil, "", &LoginBlockedError{RetryAfter: 1500 * time.Millisecond}
+		},
+	}
+
+	handler := NewHandler(mockService, nil)
+	r := chi.NewRouter()
+	handler.RegisterRoutes(r)
+
+	body, _ := json.Marshal(LoginRequest{LoginID: "john.doe", Password: "password123"})
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("expected Retry-After 2, got %q", got)
+	}
+}
+
+func TestHandler_LoginSpoofedForwardedFor(t *testing.T) {
+	var gotIP string
+	mockService := &MockService{
+		LoginFunc: func(ctx context.Context, req *LoginRequest, clientIP, userAgent string) (*LoginResponse, string, error) {
+			gotIP = clientIP
+			return nil, "", ErrInvalidCredentials
+		},
+	}
+
+	clientIPs, err := NewClientIPResolver([]string{"10.0.0.1"})
+	if err != nil {
+		t.Fatalf("failed to create resolver: %v", err)
+	}
+	handler := NewHandler(mockService, clientIPs)
+	r := chi.NewRouter()
+	handler.RegisterRoutes(r)
+
+	// A client rotating X-Forwarded-For is still counted under its own address
+	for _, spoofed := range []string{"198.51.100.1", "198.51.100.2"} {
+		body, _ := json.Marshal(LoginRequest{LoginID: "john.doe", Password: "wrong"})
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(body))
+		req.RemoteAddr = "203.0.113.7:51234"
+		req.Header.Set("X-Forwarded-For", spoofed)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		if gotIP != "203.0.113.7" {
+			t.Errorf("expected the lockout to see 203.0.113.7, got %s", gotIP)
+		}
+	}
+}
+
+func TestHandler_UnlockLogin(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    interface{}
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "unlock login ID",
+			requestBody:    UnlockLoginRequest{LoginID: "john.doe"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unlock client IP",
+			requestBody:    UnlockLoginRequest{ClientIP: "203.0.113.7"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "nothing tracked",
+			requestBody:    UnlockLoginRequest{LoginID: "jane.doe"},
+			mockError:      ErrLockoutNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "missing login ID and client IP",
+			requestBody:    UnlockLoginRequest{},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var actor string
+			mockService := &MockService{
+				UnlockLoginFunc: func(ctx context.Context, actorID string, req *UnlockLoginRequest, clientIP, userAgent string) error {
+					actor = actorID
+					return tt.mockError
+				},
+			}
+
+			handler := NewHandler(mockService, nil)
+			r := chi.NewRouter()
+			r.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					ctx := context.WithValue(r.Context(), userIDKey, "admin-123")
+					next.ServeHTTP(w, r.WithContext(ctx))
+				})
+			})
+			handler.RegisterProtectedRoutes(r)
+
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest(http.MethodPost, "/admin/auth/lockouts/unlock", bytes.NewReader(body))
+			req = asAdmin(req)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedStatus == http.StatusOK && actor != "admin-123" {
+				t.Errorf("expected unlock by admin-123, got %q", actor)
+			}
+		})
+	}
+}
+
+func TestHandler_ListSecurityEvents(t *testing.T) {
+	var gotType string
+	var gotPage, gotLimit int
+	mockService := &MockService{
+		ListSecurityEventsFunc: func(ctx context.Context, eventType string, page, limit int) (*SecurityEventListResponse, error) {
+			gotType, gotPage, gotLimit = eventType, page, limit
+			return &SecurityEventListResponse{Data: []SecurityEventResponse{}, Page: page, Limit: limit}, nil
+		},
+	}
+
+	handler := NewHandler(mockService, nil)
+	r := chi.NewRouter()
+	handler.RegisterProtectedRoutes(r)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/auth/security-events?event_type=LOGIN_LOCKOUT&limit=500", nil)
+	req = asAdmin(req)
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if gotType != "LOGIN_LOCKOUT" || gotPage != 1 || gotLimit != 20 {
+		t.Errorf("got event_type=%q page=%d limit=%d", gotType, gotPage, gotLimit)
+	}
+}
+
+func TestHandler_CreateAPIKey(t *testing.T) {
+	tests := []struct {
+		name           string
+		claims         *TokenClaims
+		mockError      error
+		expectedStatus int
+		expectCall     bool
+	}{
+		{
+			name:           "create personal access token",
+			claims:         &TokenClaims{UserID: "user-123"},
+			expectedStatus: http.StatusCreated,
+			expectCall:     true,
+		},
+		{
+			name:           "scope the user does not have",
+			claims:         &TokenClaims{UserID: "user-123"},
+			mockError:      fmt.Errorf("%w: scope %q is not a role of the key owner", ErrInvalidKeyRequest, "admin"),
+			expectedStatus: http.StatusBadRequest,
+			expectCall:     true,
+		},
+		{
+			name:           "request authenticated with an API key",
+			claims:         &TokenClaims{UserID: "user-123", APIKeyID: "4c1f2e3d-5a6b-4c7d-8e9f-0a1b2c3d4e5f"},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			mockService := &MockService{
+				CreateAPIKeyFunc: func(ctx context.Context, userID string, req *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+					called = true
+					if tt.mockError != nil {
+						return nil, tt.mockError
+					}
+					return &CreateAPIKeyResponse{
+						Key:   APIKeyResponse{ID: "4c1f2e3d-5a6b-4c7d-8e9f-0a1b2c3d4e5f", Name: req.Name, Scopes: req.Scopes},
+						Token: "kcp_secret",
+					}, nil
+				},
+			}
+
+			handler := NewHandler(mockService, nil)
+			r := chi.NewRouter()
+			r.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					ctx := context.WithValue(r.Context(), userIDKey, tt.claims.UserID)
+					ctx = context.WithValue(ctx, claimsKey, tt.claims)
+					next.ServeHTTP(w, r.WithContext(ctx))
+				})
+			})
+			handler.RegisterProtectedRoutes(r)
+
+			body, _ := json.Marshal(CreateAPIKeyRequest{Name: "CI pipeline", Scopes: []string{"user"}})
+			req := httptest.NewRequest(http.MethodPost, "/auth/tokens", bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if called != tt.expectCall {
+				t.Errorf("expected service call %v, got %v", tt.expectCall, called)
+			}
+			if tt.expectedStatus == http.StatusCreated {
+				var resp CreateAPIKeyResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if resp.Token != "kcp_secret" {
+					t.Errorf("expected the token in the response, got %q", resp.Token)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_CreateServiceAccountKey(t *testing.T) {
+	tests := []struct {
+		name           string
+		roles          []string
+		mockError      error
+		expectedStatus int
+		expectedCalled bool
+	}{
+		{name: "admin", roles: []string{"admin"}, expectedStatus: http.StatusCreated, expectedCalled: true},
+		{name: "scope the admin lacks", roles: []string{"admin"}, mockError: ErrScopeNotHeld, expectedStatus: http.StatusForbidden, expectedCalled: true},
+		{name: "non-admin caller", roles: []string{"user"}, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			mockService := &MockService{
+				CreateServiceAccountKeyFunc: func(ctx context.Context, actorID, accountID string, req *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+					called = true
+					if tt.mockError != nil {
+						return nil, tt.mockError
+					}
+					return &CreateAPIKeyResponse{Token: "kcs_token"}, nil
+				},
+			}
+
+			handler := NewHandler(mockService, nil)
+			r := chi.NewRouter()
+			handler.RegisterProtectedRoutes(r)
+
+			body := `{"name":"reports","scopes":["full_access"]}`
+			req := httptest.NewRequest(http.MethodPost, "/admin/auth/service-accounts/01912345-6789-7abc-def0-123456789abc/api-keys", strings.NewReader(body))
+			ctx := context.WithValue(req.Context(), userIDKey, "user-123")
+			req = req.WithContext(context.WithValue(ctx, userRolesKey, tt.roles))
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if called != tt.expectedCalled {
+				t.Errorf("expected the service to be called: %v, got %v", tt.expectedCalled, called)
+			}
+		})
+	}
+}
+
+func TestHandler_RevokeServiceAccountKey(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockError      error
+		expectedStatus int
+	}{
+		{"revoke key", nil, http.StatusOK},
+		{"not a service account", ErrNoServiceAccount, http.StatusNotFound},
+		{"unknown key", ErrAPIKeyNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotAccount, gotKey string
+			mockService := &MockService{
+				RevokeServiceAccountKeyFunc: func(ctx context.Context, accountID, keyID string) error {
+					gotAccount, gotKey = accountID, keyID
+					return tt.mockError
+				},
+			}
+
+			handler := NewHandler(mockService, nil)
+			r := chi.NewRouter()
+			handler.RegisterProtectedRoutes(r)
+
+			req := httptest.NewRequest(http.MethodDelete, "/admin/auth/service-accounts/01912345-6789-7abc-def0-123456789abc/api-keys/4c1f2e3d-5a6b-4c7d-8e9f-0a1b2c3d4e5f", nil)
+			req = asAdmin(req)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if gotAccount != "01912345-6789-7abc-def0-123456789abc" || gotKey != "4c1f2e3d-5a6b-4c7d-8e9f-0a1b2c3d4e5f" {
+				t.Errorf("revoked key %q of account %q", gotKey, gotAccount)
+			}
+		})
+	}
+}
+
+func TestHandler_StartOIDCLogin(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockError      error
+		expectedStatus int
+		expectCookie   bool
+	}{
+		{"start login", nil, http.StatusOK, true},
+		{"not configured", ErrOIDCNotConfigured, http.StatusServiceUnavailable, false},
+		{"provider unavailable", ErrOIDCUnavailable, http.StatusBadGateway, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{
+				StartOIDCLoginFunc: func(ctx context.Context) (*OIDCAuthorizationResponse, string, error) {
+					if tt.mockError != nil {
+						return nil, "", tt.mockError
+					}
+					return &OIDCAuthorizationResponse{AuthorizationURL: "https://idp.example.com/authorize?state=abc"}, "state-token", nil
+				},
+			}
+
+			handler := NewHandler(mockService, nil)
+			r := chi.NewRouter()
+			handler.RegisterRoutes(r)
+
+			req := httptest.NewRequest(http.MethodGet, "/auth/oidc/authorize", nil)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+
+			var stateCookie *http.Cookie
+			for _, c := range rec.Result().Cookies() {
+				if c.Name == OIDCStateCookieName {
+					stateCookie = c
+				}
+			}
+			if tt.expectCookie {
+				if stateCookie == nil || stateCookie.Value != "state-token" || !stateCookie.HttpOnly || stateCookie.Path != OIDCStateCookiePath {
+					t.Errorf("expected HTTP-only state cookie, got %+v", stateCookie)
+				}
+			} else if stateCookie != nil {
+				t.Error("expected no state cookie")
+			}
+		})
+	}
+}
+
+func TestHandler_CompleteOIDCLogin(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockResponse   *LoginResponse
+		mockRefresh    string
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "successful login",
+			mockResponse:   &LoginResponse{User: UserInfo{ID: "01912345-6789-7abc-def0-123456789abc"}, Tokens: &TokenResponse{AccessToken: "access-token"}},
+			mockRefresh:    "refresh-token",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "MFA challenge",
+			mockResponse:   &LoginResponse{User: UserInfo{ID: "01912345-6789-7abc-def0-123456789abc"}, MFA: &MFAChallengeResponse{Required: true, Token: "challenge"}},
+			expectedStatus: http.StatusOK,
+		},
+		{"login failed", nil, "", ErrOIDCLoginFailed, http.StatusUnauthorized},
+		{"no account", nil, "", ErrOIDCNoAccount, http.StatusForbidden},
+		{"provider unavailable", nil, "", ErrOIDCUnavailable, http.StatusBadGateway},
+		{"not configured", nil, "", ErrOIDCNotConfigured, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotReq *OIDCCallbackRequest
+			var gotState string
+			mockService := &MockService{
+				CompleteOIDCLoginFunc: func(ctx context.Context, req *OIDCCallbackRequest, stateToken, clientIP, userAgent string) (*LoginResponse, string, error) {
+					gotReq, gotState = req, stateToken
+					return tt.mockResponse, tt.mockRefresh, tt.mockError
+				},
+			}
+
+			handler := NewHandler(mockService, nil)
+			r := chi.NewRouter()
+			handler.RegisterRoutes(r)
+
+			body, _ := json.Marshal(OIDCCallbackRequest{Code: "auth-code", State: "abc"})
+			req := httptest.NewRequest(http.MethodPost, "/auth/oidc/callback", bytes.NewReader(body))
+			req.AddCookie(&http.Cookie{Name: OIDCStateCookieName, Value: "state-token"})
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if gotReq == nil || gotReq.Code != "auth-code" || gotReq.State != "abc" || gotState != "state-token" {
+				t.Errorf("service called with %+v and state token %q", gotReq, gotState)
+			}
+
+			var stateCleared, refreshSet bool
+			for _, c := range rec.Result().Cookies() {
+				switch c.Name {
+				case OIDCStateCookieName:
+					stateCleared = c.MaxAge < 0
+				case RefreshTokenCookieName:
+					refreshSet = c.Value == tt.mockRefresh
+				}
+			}
+			if !stateCleared {
+				t.Error("expected the state cookie to be cleared")
+			}
+			if refreshSet != (tt.mockRefresh != "") {
+				t.Errorf("refresh cookie set = %v, want %v", refreshSet, tt.mockRefresh != "")
+			}
+		})
+	}
+}
+
+func TestValidateScopes(t *testing.T) {
+	roles := []string{"user", "editor"}
+
+	scopes, err := validateScopes([]string{"editor", " editor", "user"}, roles)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(scopes) != 2 || scopes[0] != "editor" || scopes[1] != "user" {
+		t.Errorf("expected deduplicated scopes [editor user], got %v", scopes)
+	}
+
+	if _, err := validateScopes(nil, roles); !errors.Is(err, ErrInvalidKeyRequest) {
+		t.Errorf("expected ErrInvalidKeyRequest for no scopes, got %v", err)
+	}
+	if _, err := validateScopes([]string{"admin"}, roles); !errors.Is(err, ErrInvalidKeyRequest) {
+		t.Errorf("expected ErrInvalidKeyRequest for a scope the owner lacks, got %v", err)
+	}
+
+	if granted := intersectRoles([]string{"editor", "admin"}, roles); len(granted) != 1 || granted[0] != "editor" {
+		t.Errorf("expected only scopes the owner still has, got %v", granted)
+	}
+}
+
+func TestLoginDelay(t *testing.T) {
+	s := &service{config: &Config{LoginDelay: time.Second, LoginDelayMax: 5 * time.Second}}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{20, 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := s.loginDelay(tt.failures); got != tt.want {
+			t.Errorf("loginDelay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestEmailRateLimiter(t *testing.T) {
+	limiter := newEmailRateLimiter(2, time.Hour)
+	now := time.Now()
+
+	if !limiter.Allow("reset", "john@example.com", now) || !limiter.Allow("reset", "John@Example.com ", now) {
+		t.Fatal("expected the first two emails to be allowed")
+	}
+	if limiter.Allow("reset", "john@example.com", now) {
+		t.Error("expected the third email within the window to be limited")
+	}
+	if !limiter.Allow("verify", "john@example.com", now) {
+		t.Error("expected other kinds of email to have their own limit")
+	}
+	if !limiter.Allow("reset", "john@example.com", now.Add(time.Hour+time.Second)) {
+		t.Error("expected the limit to reset after the window")
+	}
+}
+
+func TestMiddleware_Authenticate(t *testing.T) {
+	tests := []struct {
+		name           string
+		authHeader     string
+		mockClaims     *TokenClaims
+		mockError      error
+		revoked        bool
+		expectedStatus int
+	}{
+		{
+			name:       "valid token",
+			authHeader: "Bearer valid-token",
+			mockClaims: &TokenClaims{
+				UserID:  "user-123",
+				LoginID: "test@example.com",
+				Email:   "test@example.com",
+				Roles:   []string{"user"},
+			},
+			mockError:      nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing authorization header",
+			authHeader:     "",
+			mockClaims:     nil,
+			mockError:      nil,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "invalid header format",
+			authHeader:     "InvalidFormat",
+			mockClaims:     nil,
+			mockError:      nil,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "invalid token",
+			authHeader:     "Bearer invalid-token",
+			mockClaims:     nil,
+			mockError:      ErrInvalidToken,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "revoked token",
+			authHeader:     "Bearer revoked-token",
+			mockClaims:     &TokenClaims{UserID: "user-123", Roles: []string{"admin"}},
+			mockError:      nil,
+			revoked:        true,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{
+				ValidateAccessTokenFunc: func(tokenString string) (*TokenClaims, error) {
+					return tt.mockClaims, tt.mockError
+				},
+				IsTokenRevokedFunc: func(ctx context.Context, claims *TokenClaims) bool {
+					return tt.revoked
+				},
+			}
+
+			middleware := NewMiddleware(mockService, nil)
+
+			// Create a test handler that just returns 200 OK
+			testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			rec := httptest.NewRecorder()
+
+			middleware.Authenticate(testHandler).ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestMiddleware_AuthenticateAPIKey(t *testing.T) {
+	tests := []struct {
+		name           string
+		authHeader     string
+		mockError      error
+		expectedStatus int
+	}{
+		{"personal access token", "Bearer kcp_token", nil, http.StatusOK},
+		{"service account key", "Bearer kcs_token", nil, http.StatusOK},
+		{"revoked key", "Bearer kcp_token", ErrTokenRevoked, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{
+				ValidateAccessTokenFunc: func(tokenString string) (*TokenClaims, error) {
+					t.Error("API keys must not be validated as JWTs")
+					return nil, ErrInvalidToken
+				},
+				ValidateAPIKeyFunc: func(ctx context.Context, token, clientIP string) (*TokenClaims, error) {
+					if tt.mockError != nil {
+						return nil, tt.mockError
+					}
+					return &TokenClaims{UserID: "user-123", Roles: []string{"user"}, APIKeyID: "key-1"}, nil
+				},
+			}
+
+			middleware := NewMiddleware(mockService, nil)
+
+			var gotRoles []string
+			var viaAPIKey bool
+			testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotRoles = GetUserRolesFromContext(r.Context())
+				viaAPIKey = IsAPIKeyAuthenticated(r.Context())
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", tt.authHeader)
+			rec := httptest.NewRecorder()
+
+			middleware.Authenticate(testHandler).ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedStatus == http.StatusOK && (len(gotRoles) != 1 || gotRoles[0] != "user" || !viaAPIKey) {
+				t.Errorf("expected the key's roles in the context, got %v (API key %v)", gotRoles, viaAPIKey)
+			}
+		})
+	}
+}
+
+func TestMiddleware_RequireRoles(t *testing.T) {
+	tests := []struct {
+		name           string
+		userRoles      []string
+		requiredRoles  []string
+		expectedStatus int
+	}{
+		{
+			name:           "user has required role",
+			userRoles:      []string{"admin", "user"},
+			requiredRoles:  []string{"admin"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "user has one of required roles",
+			userRoles:      []string{"user"},
+			requiredRoles:  []string{"admin", "user"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "user missing required role",
+			userRoles:      []string{"user"},
+			requiredRoles:  []string{"admin"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "no user roles",
+			userRoles:      nil,
+			requiredRoles:  []string{"admin"},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{}
+			middleware := NewMiddleware(mockService, nil)
+
+			testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+
+			// Add roles to context
+			ctx := context.WithValue(req.Context(), userRolesKey, tt.userRoles)
+			req = req.WithContext(ctx)
+
+			rec := httptest.NewRecorder()
+
+			middleware.RequireRoles(tt.requiredRoles...)(testHandler).ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
+
+// asAdmin adds the admin role to the context of a request to an administration route
+func asAdmin(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), userRolesKey, []string{"admin"}))
+}
+
+func TestRequireAdmin(t *testing.T) {
+	tests := []struct {
+		name           string
+		roles          []string
+		expectedStatus int
+	}{
+		{name: "admin", roles: []string{"user", "admin"}, expectedStatus: http.StatusOK},
+		{name: "full access", roles: []string{"full_access"}, expectedStatus: http.StatusOK},
+		{name: "regular user", roles: []string{"user"}, expectedStatus: http.StatusForbidden},
+		{name: "no roles", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			mockService := &MockService{
+				UnlockLoginFunc: func(ctx context.Context, actorID string, req *UnlockLoginRequest, clientIP, userAgent string) error {
+					called = true
+					return nil
+				},
+			}
+
+			handler := NewHandler(mockService, nil)
+			r := chi.NewRouter()
+			handler.RegisterProtectedRoutes(r)
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/auth/lockouts/unlock", strings.NewReader(`{"login_id":"jdoe"}`))
+			ctx := context.WithValue(req.Context(), userIDKey, "admin-123")
+			req = req.WithContext(context.WithValue(ctx, userRolesKey, tt.roles))
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if called != (tt.expectedStatus == http.StatusOK) {
+				t.Errorf("expected the service to be called: %v, got %v", tt.expectedStatus == http.StatusOK, called)
+			}
+		})
+	}
+}
+
+func TestContextHelpers(t *testing.T) {
+	t.Run("GetUserIDFromContext", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), userIDKey, "test-user-id")
+		userID := GetUserIDFromContext(ctx)
+		if userID != "test-user-id" {
+			t.Errorf("expected 'test-user-id', got '%s'", userID)
+		}
+
+		// Test with empty context
+		emptyUserID := GetUserIDFromContext(context.Background())
+		if emptyUserID != "" {
+			t.Errorf("expected empty string, got '%s'", emptyUserID)
+		}
+	})
+
+	t.Run("GetUserRolesFromContext", func(t *testing.T) {
+		roles := []string{"admin", "user"}
+		ctx := context.WithValue(context.Background(), userRolesKey, roles)
+		result := GetUserRolesFromContext(ctx)
+		if len(result) != 2 {
+			t.Errorf("expected 2 roles, got %d", len(result))
+		}
+	})
+
+	t.Run("IsAuthenticated", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), userIDKey, "test-user-id")
+		if !IsAuthenticated(ctx) {
+			t.Error("expected IsAuthenticated to return true")
+		}
+
+		if IsAuthenticated(context.Background()) {
+			t.Error("expected IsAuthenticated to return false for empty context")
+		}
+	})
+
+	t.Run("HasRole", func(t *testing.T) {
+		roles := []string{"admin", "user"}
+		ctx := context.WithValue(context.Background(), userRolesKey, roles)
+
+		if !HasRole(ctx, "admin") {
+			t.Error("expected HasRole to return true for 'admin'")
+		}
+
+		if HasRole(ctx, "superadmin") {
 			t.Error("expected HasRole to return false for 'superadmin'")
 		}
-	})
+	})
+}
+
+func TestHandler_SyncDirectory(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		mockError      error
+		expectedStatus int
+		expectedDryRun bool
+	}{
+		{name: "sync", expectedStatus: http.StatusOK},
+		{name: "dry run", query: "?dry_run=true", expectedStatus: http.StatusOK, expectedDryRun: true},
+		{name: "invalid dry run", query: "?dry_run=maybe", expectedStatus: http.StatusBadRequest},
+		{name: "not configured", mockError: ErrLDAPNotConfigured, expectedStatus: http.StatusServiceUnavailable},
+		{name: "already running", mockError: ErrLDAPSyncRunning, expectedStatus: http.StatusConflict},
+		{name: "empty directory", mockError: ErrLDAPDirectoryEmpty, expectedStatus: http.StatusBadGateway},
+		{name: "directory unavailable", mockError: ErrLDAPUnavailable, expectedStatus: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotDryRun bool
+			mockService := &MockService{
+				SyncDirectoryFunc: func(ctx context.Context, dryRun bool) (*DirectorySyncReport, error) {
+					gotDryRun = dryRun
+					if tt.mockError != nil {
+						return nil, tt.mockError
+					}
+					return &DirectorySyncReport{DryRun: dryRun, DirectoryUsers: 3}, nil
+				},
+			}
+
+			handler := NewHandler(mockService, nil)
+			r := chi.NewRouter()
+			handler.RegisterProtectedRoutes(r)
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/auth/directory/sync"+tt.query, nil)
+			req = asAdmin(req)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if rec.Code == http.StatusOK {
+				var report DirectorySyncReport
+				if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if gotDryRun != tt.expectedDryRun || report.DryRun != tt.expectedDryRun {
+					t.Errorf("expected dry run %v, got %v", tt.expectedDryRun, gotDryRun)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_GetJWKS(t *testing.T) {
+	mockService := &MockService{
+		GetJWKSFunc: func(ctx context.Context) (*JSONWebKeySet, error) {
+			return &JSONWebKeySet{Keys: []JSONWebKey{{Kty: "OKP", Kid: "key-1", Use: "sig", Alg: JWTAlgorithmEdDSA, Crv: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}}}, nil
+		},
+	}
+
+	handler := NewHandler(mockService, nil)
+	r := chi.NewRouter()
+	handler.RegisterRoutes(r)
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if rec.Header().Get("Cache-Control") == "" {
+		t.Error("expected the key set to be cacheable")
+	}
+
+	var set JSONWebKeySet
+	if err := json.Unmarshal(rec.Body.Bytes(), &set); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(set.Keys) != 1 || set.Keys[0].Kid != "key-1" || set.Keys[0].N != "" {
+		t.Errorf("unexpected key set %+v", set)
+	}
+}
+
+func TestHandler_RotateSigningKey(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		mockError      error
+		expectedStatus int
+		expectedRetire bool
+	}{
+		{name: "rotated", expectedStatus: http.StatusOK},
+		{name: "rotated and retired", query: "?retire=true", expectedStatus: http.StatusOK, expectedRetire: true},
+		{name: "invalid retire", query: "?retire=now", expectedStatus: http.StatusBadRequest},
+		{name: "signing with JWT secret", mockError: ErrSigningKeysDisabled, expectedStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var retired bool
+			mockService := &MockService{
+				RotateSigningKeyFunc: func(ctx context.Context, retire bool) (*SigningKeyInfo, error) {
+					retired = retire
+					if tt.mockError != nil {
+						return nil, tt.mockError
+					}
+					return &SigningKeyInfo{KeyID: "key-2", Algorithm: JWTAlgorithmES256, Status: SigningKeyStatusActive}, nil
+				},
+			}
+
+			handler := NewHandler(mockService, nil)
+			r := chi.NewRouter()
+			handler.RegisterProtectedRoutes(r)
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/auth/signing-keys/rotate"+tt.query, nil)
+			req = asAdmin(req)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if retired != tt.expectedRetire {
+				t.Errorf("expected retire %v, got %v", tt.expectedRetire, retired)
+			}
+		})
+	}
+}
+
+func TestHandler_Impersonate(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    interface{}
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "impersonated",
+			requestBody:    ImpersonateRequest{UserID: "user-456", Reason: "Ticket 1234: user cannot see department"},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "missing reason",
+			requestBody:    ImpersonateRequest{UserID: "user-456"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "not allowed",
+			requestBody:    ImpersonateRequest{UserID: "user-456", Reason: "Support"},
+			mockError:      fmt.Errorf("%w: cannot impersonate service accounts", ErrImpersonationDenied),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "user not found",
+			requestBody:    ImpersonateRequest{UserID: "missing", Reason: "Support"},
+			mockError:      ErrUserNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var actor string
+			mockService := &MockService{
+				ImpersonateFunc: func(ctx context.Context, claims *TokenClaims, req *ImpersonateRequest, clientIP, userAgent string) (*ImpersonationTokenResponse, error) {
+					actor = claims.UserID
+					if tt.mockError != nil {
+						return nil, tt.mockError
+					}
+					return &ImpersonationTokenResponse{AccessToken: "impersonation-token", TokenType: "Bearer", ExpiresIn: 600}, nil
+				},
+			}
+
+			handler := NewHandler(mockService, nil)
+			r := chi.NewRouter()
+			r.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					claims := &TokenClaims{UserID: "admin-123", Roles: []string{"admin"}}
+					ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
+					ctx = context.WithValue(ctx, claimsKey, claims)
+					next.ServeHTTP(w, r.WithContext(ctx))
+				})
+			})
+			handler.RegisterProtectedRoutes(r)
+
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest(http.MethodPost, "/admin/auth/impersonate", bytes.NewReader(body))
+			req = asAdmin(req)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedStatus == http.StatusCreated && actor != "admin-123" {
+				t.Errorf("expected impersonation by admin-123, got %q", actor)
+			}
+		})
+	}
+}
+
+func TestMiddleware_Impersonation(t *testing.T) {
+	impersonation := &TokenClaims{
+		UserID: "user-456",
+		Roles:  []string{"user"},
+		Actor:  &TokenActor{UserID: "admin-123", LoginID: "admin"},
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{name: "me shows impersonator", method: http.MethodGet, path: "/auth/me", expectedStatus: http.StatusOK},
+		{name: "tokens blocked", method: http.MethodGet, path: "/auth/tokens", expectedStatus: http.StatusForbidden},
+		{name: "MFA blocked", method: http.MethodPost, path: "/auth/mfa/disable", expectedStatus: http.StatusForbidden},
+		{name: "administration blocked", method: http.MethodGet, path: "/admin/auth/impersonations", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var recorded []string
+			mockService := &MockService{
+				ValidateAccessTokenFunc: func(tokenString string) (*TokenClaims, error) {
+					return impersonation, nil
+				},
+				GetMeFunc: func(ctx context.Context, userID string) (*MeResponse, error) {
+					return &MeResponse{User: UserInfo{ID: userID}}, nil
+				},
+				RecordImpersonatedRequestFunc: func(ctx context.Context, claims *TokenClaims, method, path string, statusCode int, clientIP string) {
+					recorded = append(recorded, fmt.Sprintf("%s %s %d", method, path, statusCode))
+				},
+			}
+
+			handler := NewHandler(mockService, nil)
+			r := chi.NewRouter()
+			r.Use(NewMiddleware(mockService, nil).Authenticate)
+			handler.RegisterProtectedRoutes(r)
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer impersonation-token")
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			want := fmt.Sprintf("%s %s %d", tt.method, tt.path, tt.expectedStatus)
+			if len(recorded) != 1 || recorded[0] != want {
+				t.Errorf("expected recorded request %q, got %v", want, recorded)
+			}
+
+			if tt.path == "/auth/me" {
+				var me MeResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &me); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if me.Impersonator == nil || me.Impersonator.UserID != "admin-123" {
+					t.Errorf("expected impersonator admin-123, got %+v", me.Impersonator)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_PasswordPolicyViolations(t *testing.T) {
+	violations := &password.PolicyError{Violations: []password.Violation{
+		{Rule: password.RuleMinLength, Message: "Password must be at least 12 characters"},
+		{Rule: password.RuleBreached, Message: "Password appears in a data breach and must not be used"},
+	}}
+
+	mockService := &MockService{
+		RegisterFunc: func(ctx context.Context, req *RegisterRequest, clientIP, userAgent string) (*RegisterResponse, string, error) {
+			return nil, "", fmt.Errorf("%w: %w", ErrInvalidPassword, violations)
+		},
+	}
+
+	handler := NewHandler(mockService, nil)
+	r := chi.NewRouter()
+	handler.RegisterRoutes(r)
+
+	body, _ := json.Marshal(RegisterRequest{Email: "john.doe@example.com", Password: "password", Name: json.RawMessage(`{"en-US":"John Doe"}`)})
+	req := httptest.NewRequest(http.MethodPost, "/auth/register", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	var response password.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Violations) != 2 || response.Violations[0].Rule != password.RuleMinLength || response.Violations[1].Rule != password.RuleBreached {
+		t.Errorf("unexpected violations %+v", response.Violations)
+	}
+}
+
+func TestHandler_ChangeExpiredPassword(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    interface{}
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "changed",
+			requestBody:    ChangeExpiredPasswordRequest{PasswordChangeToken: "change-token", NewPassword: "correct horse battery staple"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing new password",
+			requestBody:    ChangeExpiredPasswordRequest{PasswordChangeToken: "change-token"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "reused password",
+			requestBody:    ChangeExpiredPasswordRequest{PasswordChangeToken: "change-token", NewPassword: "old password"},
+			mockError:      fmt.Errorf("%w: %w", ErrInvalidPassword, &password.PolicyError{Violations: []password.Violation{{Rule: password.RuleHistory}}}),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid token",
+			requestBody:    ChangeExpiredPasswordRequest{PasswordChangeToken: "used-token", NewPassword: "correct horse battery staple"},
+			mockError:      ErrInvalidToken,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{
+				ChangeExpiredPasswordFunc: func(ctx context.Context, req *ChangeExpiredPasswordRequest, clientIP, userAgent string) (*LoginResponse, string, error) {
+					if tt.mockError != nil {
+						return nil, "", tt.mockError
+					}
+					return &LoginResponse{Tokens: &TokenResponse{AccessToken: "access-token"}}, "refresh-token", nil
+				},
+			}
+
+			handler := NewHandler(mockService, nil)
+			r := chi.NewRouter()
+			handler.RegisterRoutes(r)
+
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest(http.MethodPost, "/auth/password/change", bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedStatus == http.StatusOK && len(rec.Result().Cookies()) == 0 {
+				t.Error("expected refresh token cookie")
+			}
+		})
+	}
+}
+
+func TestHandler_UpdatePasswordPolicy(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    string
+		expectedStatus int
+	}{
+		{name: "valid policy", requestBody: `{"min_length": 12, "require_digit": true, "history_count": 5}`, expectedStatus: http.StatusOK},
+		{name: "max length below min length", requestBody: `{"min_length": 12, "max_length": 10}`, expectedStatus: http.StatusBadRequest},
+		{name: "history too long", requestBody: `{"history_count": 100}`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var saved *password.Policy
+			mockService := &MockService{
+				UpdatePasswordPolicyFunc: func(ctx context.Context, actorID string, policy *password.Policy) (*PasswordPolicyResponse, error) {
+					if err := policy.Validate(); err != nil {
+						return nil, err
+					}
+					saved = policy
+					return &PasswordPolicyResponse{Policy: *policy}, nil
+				},
+			}
+
+			handler := NewHandler(mockService, nil)
+			r := chi.NewRouter()
+			r.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					ctx := context.WithValue(r.Context(), userIDKey, "admin-123")
+					next.ServeHTTP(w, r.WithContext(ctx))
+				})
+			})
+			handler.RegisterProtectedRoutes(r)
+
+			req := httptest.NewRequest(http.MethodPut, "/admin/auth/password-policy", strings.NewReader(tt.requestBody))
+			req = asAdmin(req)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			// Omitted settings keep their default
+			if tt.expectedStatus == http.StatusOK && (saved.MaxLength != password.DefaultPolicy().MaxLength || !saved.CheckBreached) {
+				t.Errorf("expected defaults for omitted settings, got %+v", saved)
+			}
+		})
+	}
+}
+
+// policyRepository serves a password policy and history to the service
+type policyRepository struct {
+	Repository
+	policy  *password.Policy
+	history []string
+}
+
+func (r *policyRepository) GetPasswordPolicy(ctx context.Context) (*password.Policy, time.Time, error) {
+	if r.policy == nil {
+		return nil, time.Time{}, sql.ErrNoRows
+	}
+	return r.policy, time.Now(), nil
+}
+
+func (r *policyRepository) GetPasswordHistory(ctx context.Context, userID, limit int) ([]string, error) {
+	return r.history[:min(limit, len(r.history))], nil
+}
+
+// breachList lists breached passwords in memory
+type breachList []string
+
+func (l breachList) Contains(pw string) (bool, error) {
+	return slices.Contains(l, pw), nil
+}
+
+func TestCheckNewPassword(t *testing.T) {
+	s := &service{hashParams: password.DefaultParams()}
+	hash := func(pw string) string {
+		h, err := s.hashPassword(pw)
+		if err != nil {
+			t.Fatalf("failed to hash password: %v", err)
+		}
+		return h
+	}
+	current := hash("Current password 3")
+	history := []string{current, hash("Previous password 2"), hash("Oldest password 1")}
+
+	policy := password.DefaultPolicy()
+	policy.HistoryCount = 2
+	policy.RequireDigit = true
+
+	tests := []struct {
+		name     string
+		user     *AuthUser
+		password string
+		rules    []string
+	}{
+		{name: "valid", user: &AuthUser{ID: 1, LoginID: "jdoe", PasswordHash: current}, password: "Brand new password 4"},
+		{name: "current password", user: &AuthUser{ID: 1, LoginID: "jdoe", PasswordHash: current}, password: "Current password 3", rules: []string{password.RuleHistory}},
+		{name: "previous password", user: &AuthUser{ID: 1, LoginID: "jdoe", PasswordHash: current}, password: "Previous password 2", rules: []string{password.RuleHistory}},
+		{name: "outside the history", user: &AuthUser{ID: 1, LoginID: "jdoe", PasswordHash: current}, password: "Oldest password 1"},
+		{name: "new user", user: &AuthUser{LoginID: "jdoe"}, password: "Current password 3"},
+		{name: "breached", user: &AuthUser{LoginID: "jdoe"}, password: "P@ssw0rd1", rules: []string{password.RuleBreached}},
+		{name: "several rules", user: &AuthUser{LoginID: "jdoe"}, password: "jdoe", rules: []string{password.RuleMinLength, password.RuleDigit, password.RuleUserInfo}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &service{
+				repo:       &policyRepository{policy: &policy, history: history},
+				breachList: breachList{"P@ssw0rd1"},
+			}
+
+			err := s.checkNewPassword(context.Background(), tt.user, tt.password)
+			if len(tt.rules) == 0 {
+				if err != nil {
+					t.Fatalf("expected no violations, got %v", err)
+				}
+				return
+			}
+
+			var policyErr *password.PolicyError
+			if !errors.Is(err, ErrInvalidPassword) || !errors.As(err, &policyErr) {
+				t.Fatalf("expected a policy error, got %v", err)
+			}
+			var rules []string
+			for _, v := range policyErr.Violations {
+				rules = append(rules, v.Rule)
+			}
+			if !slices.Equal(rules, tt.rules) {
+				t.Errorf("expected rules %v, got %v", tt.rules, rules)
+			}
+		})
+	}
+}
+
+// rehashRepository records password hash upgrades
+type rehashRepository struct {
+	Repository
+	current string
+	calls   int
+}
+
+func (r *rehashRepository) RehashPassword(ctx context.Context, userID int, oldHash, newHash string) (bool, error) {
+	r.calls++
+	if oldHash != r.current {
+		return false, nil
+	}
+	r.current = newHash
+	return true, nil
+}
+
+func TestUpgradePasswordHash(t *testing.T) {
+	params := password.Params{Time: 2, Memory: 1024, Threads: 1}
+	weak, err := password.Hash("Kettle-Sun-42", password.Params{Time: 1, Memory: 1024, Threads: 1})
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	strong, err := password.Hash("Kettle-Sun-42", password.Params{Time: 3, Memory: 1024, Threads: 1})
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		hash    string
+		stored  string
+		upgrade bool
+	}{
+		{name: "weaker parameters", hash: weak, stored: weak, upgrade: true},
+		{name: "imported PBKDF2 hash", hash: "pbkdf2_sha256$1000$seasalt$TJUl5ej5vzB/wFyiG1I9DSpiGKo/Co2GhB70QN0G5io=", upgrade: true},
+		{name: "stronger parameters", hash: strong, stored: strong},
+		{name: "changed in the meantime", hash: weak, stored: strong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := tt.stored
+			if stored == "" {
+				stored = tt.hash
+			}
+			repo := &rehashRepository{current: stored}
+			s := &service{repo: repo, hashParams: params}
+			user := &AuthUser{ID: 1, PasswordHash: tt.hash}
+
+			plain := "Kettle-Sun-42"
+			if strings.HasPrefix(tt.hash, "pbkdf2") {
+				plain = "legacy-secret"
+			}
+			if !s.verifyPassword(plain, user.PasswordHash) {
+				t.Fatal("expected the password to match")
+			}
+			s.upgradePasswordHash(context.Background(), user, plain)
+
+			if upgraded := user.PasswordHash != tt.hash; upgraded != tt.upgrade {
+				t.Fatalf("expected upgrade %v, got hash %s", tt.upgrade, user.PasswordHash)
+			}
+			if tt.upgrade {
+				if repo.current != user.PasswordHash || params.NeedsRehash(user.PasswordHash) {
+					t.Errorf("expected the stored hash to use the current parameters, got %s", repo.current)
+				}
+				if !s.verifyPassword(plain, user.PasswordHash) {
+					t.Error("expected the password to match the new hash")
+				}
+			}
+		})
+	}
+}
+
+func TestPasswordChangeToken(t *testing.T) {
+	s := &service{jwtSecret: []byte("test-secret")}
+	user := &AuthUser{PublicID: "user-123", PasswordHash: "$argon2id$v=19$m=65536,t=1,p=4$c2FsdA$aGFzaA"}
+
+	challenge, err := s.newPasswordChangeChallenge(user)
+	if err != nil {
+		t.Fatalf("failed to create challenge: %v", err)
+	}
+	if !challenge.Required || challenge.Reason != passwordChangeReasonExpired {
+		t.Errorf("unexpected challenge %+v", challenge)
+	}
+
+	userID, fingerprint, err := s.parsePasswordChangeToken(challenge.Token)
+	if err != nil {
+		t.Fatalf("failed to parse token: %v", err)
+	}
+	if userID != "user-123" || fingerprint != s.hashToken(user.PasswordHash) {
+		t.Errorf("unexpected user %q or fingerprint %q", userID, fingerprint)
+	}
+
+	// Never accepted as access token or MFA challenge
+	if _, err := s.ValidateAccessToken(challenge.Token); err == nil {
+		t.Error("expected password change token to be rejected as access token")
+	}
+	if _, err := s.parseMFAToken(challenge.Token); err == nil {
+		t.Error("expected password change token to be rejected as MFA token")
+	}
 }
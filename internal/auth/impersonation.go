@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Impersonate issues a short-lived access token acting as another user for members of the
+// impersonation roles. The token carries the actor in its act claim, cannot be refreshed and
+// is recorded together with the reason; requests made with it are recorded by the middleware.
+// Service accounts, users holding an impersonation role and users with roles the actor doesn't
+// hold cannot be impersonated.
+func (s *service) Impersonate(ctx context.Context, actor *TokenClaims, req *ImpersonateRequest, clientIP, userAgent string) (*ImpersonationTokenResponse, error) {
+	actorID, err := s.repo.GetUserInternalID(ctx, actor.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	actorRoles, err := s.repo.GetAllUserRoles(ctx, actorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+	if !s.impersonationRole(actorRoles) {
+		return nil, fmt.Errorf("%w: requires one of the roles %s", ErrImpersonationDenied, strings.Join(s.config.ImpersonationRoles, ", "))
+	}
+
+	targetID, err := s.repo.GetUserInternalID(ctx, req.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if targetID == actorID {
+		return nil, fmt.Errorf("%w: cannot impersonate yourself", ErrImpersonationDenied)
+	}
+	target, err := s.getUserByInternalID(ctx, targetID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if target.IsServiceAccount {
+		return nil, fmt.Errorf("%w: cannot impersonate service accounts", ErrImpersonationDenied)
+	}
+
+	roles, err := s.repo.GetAllUserRoles(ctx, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+	// Administrators could otherwise act with each other's privileges
+	if s.impersonationRole(roles) {
+		return nil, fmt.Errorf("%w: cannot impersonate users who may impersonate", ErrImpersonationDenied)
+	}
+	// Impersonation must not widen the actor's privileges
+	if role := missingRole(actorRoles, roles); role != "" {
+		return nil, fmt.Errorf("%w: cannot impersonate users with roles you don't have (%s)", ErrImpersonationDenied, role)
+	}
+
+	now := time.Now()
+	tokenID, err := s.generateTokenID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token ID: %w", err)
+	}
+	expiresAt := now.Add(s.config.ImpersonationDuration)
+
+	impersonation := &Impersonation{
+		TokenID:     tokenID,
+		ActorUserID: actorID,
+		UserID:      targetID,
+		Reason:      strings.TrimSpace(req.Reason),
+		ClientIP:    clientIP,
+		UserAgent:   userAgent,
+		ExpiresAt:   expiresAt,
+	}
+	if err := s.repo.CreateImpersonation(ctx, impersonation); err != nil {
+		return nil, fmt.Errorf("failed to record impersonation: %w", err)
+	}
+
+	accessToken, err := s.signAccessToken(ctx, jwt.MapClaims{
+		"user_id":  target.PublicID,
+		"login_id": target.LoginID,
+		"email":    target.Email,
+		"roles":    roles,
+		"jti":      tokenID,
+		"iat":      now.Unix(),
+		"iat_us":   now.UnixMicro(),
+		"exp":      expiresAt.Unix(),
+		"iss":      TokenIssuer,
+		"mfa":      actor.MFAVerified,
+		"act": map[string]interface{}{
+			"user_id":  actor.UserID,
+			"login_id": actor.LoginID,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	log.Printf("User %s started impersonating user %s (impersonation %d)", actor.UserID, target.PublicID, impersonation.ID)
+
+	if roles == nil {
+		roles = []string{}
+	}
+	return &ImpersonationTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.config.ImpersonationDuration.Seconds()),
+		User:        target.ToUserInfo(),
+		Roles:       roles,
+	}, nil
+}
+
+// EndImpersonation revokes the impersonation token of the request before it expires
+func (s *service) EndImpersonation(ctx context.Context, claims *TokenClaims) error {
+	if claims == nil || claims.Actor == nil {
+		return ErrNotImpersonating
+	}
+
+	if err := s.denylist.DenyToken(ctx, claims.TokenID, time.Unix(claims.ExpireAt, 0)); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	if err := s.repo.EndImpersonation(ctx, claims.TokenID); err != nil {
+		return fmt.Errorf("failed to record end of impersonation: %w", err)
+	}
+	return nil
+}
+
+// RecordImpersonatedRequest adds a request made with an impersonation token to the audit log.
+// Failures are logged, as the request has already been served.
+func (s *service) RecordImpersonatedRequest(ctx context.Context, claims *TokenClaims, method, path string, statusCode int, clientIP string) {
+	request := &ImpersonatedRequest{
+		Method:     method,
+		Path:       path,
+		StatusCode: statusCode,
+		ClientIP:   clientIP,
+	}
+	if err := s.repo.RecordImpersonatedRequest(ctx, claims.TokenID, request); err != nil {
+		log.Printf("[WARN] Failed to record request %s %s of user %s impersonating user %s: %v",
+			method, path, claims.Actor.UserID, claims.UserID, err)
+	}
+}
+
+// ListImpersonations lists impersonations, newest first, optionally filtered by the
+// impersonating administrator and the impersonated user (public IDs)
+func (s *service) ListImpersonations(ctx context.Context, actorID, userID string, page, limit int) (*ImpersonationListResponse, error) {
+	offset := (page - 1) * limit
+
+	impersonations, totalCount, err := s.repo.ListImpersonations(ctx, actorID, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list impersonations: %w", err)
+	}
+
+	data := make([]ImpersonationResponse, 0, len(impersonations))
+	for i := range impersonations {
+		data = append(data, impersonations[i].ToResponse())
+	}
+
+	return &ImpersonationListResponse{
+		Data:       data,
+		Page:       page,
+		Limit:      limit,
+		TotalCount: totalCount,
+		TotalPages: (totalCount + limit - 1) / limit,
+	}, nil
+}
+
+// ListImpersonatedRequests lists the requests made during an impersonation, oldest first
+func (s *service) ListImpersonatedRequests(ctx context.Context, impersonationID int64, page, limit int) (*ImpersonatedRequestListResponse, error) {
+	offset := (page - 1) * limit
+
+	requests, totalCount, err := s.repo.ListImpersonatedRequests(ctx, impersonationID, limit, offset)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoImpersonation
+		}
+		return nil, fmt.Errorf("failed to list impersonated requests: %w", err)
+	}
+
+	data := make([]ImpersonatedRequestResponse, 0, len(requests))
+	for i := range requests {
+		data = append(data, requests[i].ToResponse())
+	}
+
+	return &ImpersonatedRequestListResponse{
+		Data:       data,
+		Page:       page,
+		Limit:      limit,
+		TotalCount: totalCount,
+		TotalPages: (totalCount + limit - 1) / limit,
+	}, nil
+}
+
+// impersonationRole reports whether any of the roles may impersonate other users
+func (s *service) impersonationRole(roles []string) bool {
+	for _, role := range roles {
+		if slices.Contains(s.config.ImpersonationRoles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// missingRole returns a role of the target the actor doesn't hold, or "" if the target's roles are
+// a subset of the actor's. full_access holds every privilege.
+func missingRole(actorRoles, targetRoles []string) string {
+	if slices.Contains(actorRoles, "full_access") {
+		return ""
+	}
+	for _, role := range targetRoles {
+		if !slices.Contains(actorRoles, role) {
+			return role
+		}
+	}
+	return ""
+}
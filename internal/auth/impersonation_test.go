@@ -0,0 +1,26 @@
+package auth
+
+import "testing"
+
+func TestMissingRole(t *testing.T) {
+	tests := []struct {
+		name        string
+		actorRoles  []string
+		targetRoles []string
+		want        string
+	}{
+		{name: "subset", actorRoles: []string{"support", "user", "hr"}, targetRoles: []string{"user", "hr"}, want: ""},
+		{name: "no roles", actorRoles: []string{"support"}, targetRoles: nil, want: ""},
+		{name: "role the actor lacks", actorRoles: []string{"support", "user"}, targetRoles: []string{"user", "admin"}, want: "admin"},
+		{name: "full_access holds every role", actorRoles: []string{"full_access"}, targetRoles: []string{"admin", "hr"}, want: ""},
+		{name: "target with full_access", actorRoles: []string{"support", "admin"}, targetRoles: []string{"full_access"}, want: "full_access"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := missingRole(tt.actorRoles, tt.targetRoles); got != tt.want {
+				t.Errorf("expected missing role %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// LoginBlockedError is returned when a login is rejected before the password is checked,
+// because the login ID or client IP is locked or the previous failure was too recent
+type LoginBlockedError struct {
+	Locked     bool
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("login locked, retry after %s", e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("login throttled, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+// Unwrap lets callers match ErrLoginLocked or ErrLoginThrottled with errors.Is
+func (e *LoginBlockedError) Unwrap() error {
+	if e.Locked {
+		return ErrLoginLocked
+	}
+	return ErrLoginThrottled
+}
+
+// UnlockLogin lifts the lockout and clears the failed logins of a login ID and/or client IP
+func (s *service) UnlockLogin(ctx context.Context, actorPublicID string, req *UnlockLoginRequest, clientIP, userAgent string) error {
+	actorID, err := s.repo.GetUserInternalID(ctx, actorPublicID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	unlocked := false
+	if req.LoginID != "" {
+		user, err := s.findLoginUser(ctx, req.LoginID)
+		if err != nil {
+			return err
+		}
+		event := &SecurityEvent{
+			Scope:       SecurityScopeLoginID,
+			Subject:     loginSubject(req.LoginID, user),
+			LoginID:     req.LoginID,
+			ActorUserID: &actorID,
+		}
+		if user != nil {
+			event.UserID = &user.ID
+		}
+		ok, err := s.unlock(ctx, event, clientIP, userAgent)
+		if err != nil {
+			return err
+		}
+		unlocked = unlocked || ok
+	}
+	if req.ClientIP != "" {
+		event := &SecurityEvent{
+			Scope:       SecurityScopeIP,
+			Subject:     strings.TrimSpace(req.ClientIP),
+			ActorUserID: &actorID,
+		}
+		ok, err := s.unlock(ctx, event, clientIP, userAgent)
+		if err != nil {
+			return err
+		}
+		unlocked = unlocked || ok
+	}
+
+	if !unlocked {
+		return ErrLockoutNotFound
+	}
+	return nil
+}
+
+// ListLockouts lists login IDs and client IPs that are currently locked
+func (s *service) ListLockouts(ctx context.Context) (*LockoutListResponse, error) {
+	attempts, err := s.repo.ListActiveLockouts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lockouts: %w", err)
+	}
+
+	data := make([]LockoutResponse, 0, len(attempts))
+	for i := range attempts {
+		data = append(data, attempts[i].ToResponse())
+	}
+	return &LockoutListResponse{Data: data}, nil
+}
+
+// ListSecurityEvents lists recorded security events, newest first
+func (s *service) ListSecurityEvents(ctx context.Context, eventType string, page, limit int) (*SecurityEventListResponse, error) {
+	offset := (page - 1) * limit
+
+	events, totalCount, err := s.repo.ListSecurityEvents(ctx, SecurityEventType(strings.ToUpper(eventType)), limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list security events: %w", err)
+	}
+
+	data := make([]SecurityEventResponse, 0, len(events))
+	for i := range events {
+		data = append(data, events[i].ToResponse())
+	}
+
+	totalPages := totalCount / limit
+	if totalCount%limit > 0 {
+		totalPages++
+	}
+
+	return &SecurityEventListResponse{
+		Data:       data,
+		Page:       page,
+		Limit:      limit,
+		TotalCount: totalCount,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// ShareLinkLockedFor returns how long a file share link stays locked after too many wrong passwords,
+// or zero if it is not locked
+func (s *service) ShareLinkLockedFor(ctx context.Context, linkPublicID string) (time.Duration, error) {
+	attempt, err := s.repo.GetLoginAttempt(ctx, SecurityScopeShareLink, linkPublicID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get share link attempts: %w", err)
+	}
+
+	if attempt.LockedUntil == nil {
+		return 0, nil
+	}
+	return max(time.Until(*attempt.LockedUntil), 0), nil
+}
+
+// RecordShareLinkFailure counts a wrong password for a file share link and locks the link for
+// LockoutDuration once LockoutThreshold failures happened within LockoutWindow
+func (s *service) RecordShareLinkFailure(ctx context.Context, linkPublicID string) error {
+	now := time.Now()
+	attempt, err := s.repo.RecordLoginFailure(ctx, SecurityScopeShareLink, linkPublicID, now, now.Add(-s.config.LockoutWindow))
+	if err != nil {
+		return fmt.Errorf("failed to record share link failure: %w", err)
+	}
+	if s.config.LockoutThreshold <= 0 || attempt.FailedCount < s.config.LockoutThreshold {
+		return nil
+	}
+
+	lockedUntil := now.Add(s.config.LockoutDuration)
+	if err := s.repo.LockLogin(ctx, SecurityScopeShareLink, linkPublicID, lockedUntil); err != nil {
+		return fmt.Errorf("failed to lock share link: %w", err)
+	}
+
+	log.Printf("[WARN] Share link %s locked until %s after %d wrong passwords",
+		linkPublicID, lockedUntil.Format(time.RFC3339), attempt.FailedCount)
+
+	s.recordSecurityEvent(ctx, &SecurityEvent{
+		EventType:      SecurityEventLoginLockout,
+		Scope:          SecurityScopeShareLink,
+		Subject:        linkPublicID,
+		FailedAttempts: attempt.FailedCount,
+		LockedUntil:    &lockedUntil,
+	})
+	return nil
+}
+
+// findLoginUser looks a user up by login ID or email. Returns nil if there is none.
+func (s *service) findLoginUser(ctx context.Context, loginID string) (*AuthUser, error) {
+	user, err := s.repo.GetUserByLoginID(ctx, loginID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Try by email
+		user, err = s.repo.GetUserByEmail(ctx, loginID)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+// loginSubject returns the key failed logins are counted by. Logins with the login ID
+// and the email of a user share one count; unknown login IDs are counted as entered.
+func loginSubject(loginID string, user *AuthUser) string {
+	if user != nil {
+		return strings.ToLower(user.LoginID)
+	}
+	return strings.ToLower(strings.TrimSpace(loginID))
+}
+
+// checkLoginAllowed rejects a login while the login ID or client IP is locked,
+// or before the progressive delay after the last failure of the login ID has passed
+func (s *service) checkLoginAllowed(ctx context.Context, subject, clientIP string, now time.Time) error {
+	attempts, err := s.repo.GetLoginAttempts(ctx, subject, clientIP)
+	if err != nil {
+		return fmt.Errorf("failed to get login attempts: %w", err)
+	}
+
+	var lockedFor, throttledFor time.Duration
+	for _, attempt := range attempts {
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			lockedFor = max(lockedFor, attempt.LockedUntil.Sub(now))
+			continue
+		}
+		if attempt.Scope == SecurityScopeLoginID && attempt.LastFailedAt != nil &&
+			now.Sub(*attempt.LastFailedAt) < s.config.LockoutWindow {
+			throttledFor = max(throttledFor, attempt.LastFailedAt.Add(s.loginDelay(attempt.FailedCount)).Sub(now))
+		}
+	}
+
+	if lockedFor > 0 {
+		return &LoginBlockedError{Locked: true, RetryAfter: lockedFor}
+	}
+	if throttledFor > 0 {
+		return &LoginBlockedError{RetryAfter: throttledFor}
+	}
+	return nil
+}
+
+// loginDelay returns the wait required after the given number of consecutive failures
+func (s *service) loginDelay(failures int) time.Duration {
+	if s.config.LoginDelay <= 0 || failures < 1 {
+		return 0
+	}
+
+	delay := s.config.LoginDelay
+	for i := 1; i < failures && delay < s.config.LoginDelayMax; i++ {
+		delay *= 2
+	}
+	if s.config.LoginDelayMax > 0 && delay > s.config.LoginDelayMax {
+		delay = s.config.LoginDelayMax
+	}
+	return delay
+}
+
+// recordLoginFailure counts a failed login against the login ID and the client IP
+// and locks whichever reached its threshold
+func (s *service) recordLoginFailure(ctx context.Context, subject, loginID string, user *AuthUser, clientIP, userAgent string, now time.Time) error {
+	counters := []struct {
+		scope     SecurityScope
+		subject   string
+		threshold int
+	}{
+		{SecurityScopeLoginID, subject, s.config.LockoutThreshold},
+		{SecurityScopeIP, clientIP, s.config.LockoutIPThreshold},
+	}
+
+	for _, counter := range counters {
+		if counter.subject == "" {
+			continue
+		}
+
+		attempt, err := s.repo.RecordLoginFailure(ctx, counter.scope, counter.subject, now, now.Add(-s.config.LockoutWindow))
+		if err != nil {
+			return fmt.Errorf("failed to record login failure: %w", err)
+		}
+		if counter.threshold <= 0 || attempt.FailedCount < counter.threshold {
+			continue
+		}
+
+		lockedUntil := now.Add(s.config.LockoutDuration)
+		if err := s.repo.LockLogin(ctx, counter.scope, counter.subject, lockedUntil); err != nil {
+			return fmt.Errorf("failed to lock login: %w", err)
+		}
+
+		log.Printf("[WARN] Login locked until %s: %s %q after %d failed attempts (last from %s)",
+			lockedUntil.Format(time.RFC3339), counter.scope, counter.subject, attempt.FailedCount, clientIP)
+
+		event := &SecurityEvent{
+			EventType:      SecurityEventLoginLockout,
+			Scope:          counter.scope,
+			Subject:        counter.subject,
+			LoginID:        loginID,
+			ClientIP:       clientIP,
+			UserAgent:      userAgent,
+			FailedAttempts: attempt.FailedCount,
+			LockedUntil:    &lockedUntil,
+		}
+		if user != nil {
+			event.UserID = &user.ID
+		}
+		s.recordSecurityEvent(ctx, event)
+	}
+
+	return nil
+}
+
+// clearLoginFailures forgets the failed logins of a user's login ID once a login completed.
+// Failures from the client IP keep counting, so one valid account can't reset them.
+func (s *service) clearLoginFailures(ctx context.Context, user *AuthUser) error {
+	if _, err := s.repo.ClearLoginAttempts(ctx, SecurityScopeLoginID, loginSubject(user.LoginID, user)); err != nil {
+		return fmt.Errorf("failed to clear login attempts: %w", err)
+	}
+	return nil
+}
+
+// unlock clears a tracked login ID or client IP and records the unlock if there was anything to clear
+func (s *service) unlock(ctx context.Context, event *SecurityEvent, clientIP, userAgent string) (bool, error) {
+	ok, err := s.repo.ClearLoginAttempts(ctx, event.Scope, event.Subject)
+	if err != nil {
+		return false, fmt.Errorf("failed to unlock login: %w", err)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	event.EventType = SecurityEventLoginUnlock
+	event.ClientIP = clientIP
+	event.UserAgent = userAgent
+	s.recordSecurityEvent(ctx, event)
+	return true, nil
+}
+
+// recordSecurityEvent writes a security event (best effort)
+func (s *service) recordSecurityEvent(ctx context.Context, event *SecurityEvent) {
+	if err := s.repo.CreateSecurityEvent(ctx, event); err != nil {
+		log.Printf("[WARN] Failed to record %s security event for %s %q: %v", event.EventType, event.Scope, event.Subject, err)
+	}
+}
@@ -0,0 +1,584 @@
+package auth
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// mfaTokenUse marks MFA challenge tokens so they are never accepted as access tokens
+	mfaTokenUse = "mfa_challenge"
+
+	// Challenge purposes: verify an enrolled factor, or enroll one because the user's roles require it
+	mfaPurposeVerify = "verify"
+	mfaPurposeEnroll = "enroll"
+)
+
+// mfaChallenge is a parsed MFA challenge token
+type mfaChallenge struct {
+	UserID    string
+	Purpose   string
+	TokenID   string
+	IssuedAt  int64
+	ExpiresAt int64
+
+	IssuedAtMicros int64
+}
+
+// claims returns what the token denylist checks a challenge by
+func (c *mfaChallenge) claims() *TokenClaims {
+	return &TokenClaims{UserID: c.UserID, TokenID: c.TokenID, IssuedAt: c.IssuedAt, IssuedAtMicros: c.IssuedAtMicros}
+}
+
+// VerifyMFA completes a login that was answered with an MFA challenge and issues the real tokens.
+// For enrollment challenges the code confirms the pending secret and recovery codes are returned.
+// Wrong codes count towards the lockout of the login ID and client IP like wrong passwords,
+// and a challenge is invalidated after MFAMaxAttempts of them.
+func (s *service) VerifyMFA(ctx context.Context, req *VerifyMFARequest, clientIP, userAgent string) (*LoginResponse, string, error) {
+	challenge, err := s.parseMFAToken(req.MFAToken)
+	if err != nil {
+		return nil, "", err
+	}
+	if s.denylist.IsDenied(ctx, challenge.claims()) {
+		return nil, "", ErrInvalidToken
+	}
+
+	user, err := s.getUserByPublicID(ctx, challenge.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", ErrInvalidToken
+		}
+		return nil, "", err
+	}
+
+	now := time.Now()
+	subject := loginSubject(user.LoginID, user)
+	if err := s.checkLoginAllowed(ctx, subject, clientIP, now); err != nil {
+		return nil, "", err
+	}
+
+	mfa, err := s.repo.GetUserMFA(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", ErrMFANotEnabled
+		}
+		return nil, "", fmt.Errorf("failed to get MFA enrollment: %w", err)
+	}
+
+	response := &LoginResponse{User: user.ToUserInfo()}
+
+	switch {
+	case mfa.IsEnabled:
+		err = s.verifySecondFactor(ctx, mfa, req.Code, req.RecoveryCode)
+	case challenge.Purpose == mfaPurposeEnroll:
+		response.RecoveryCodes, err = s.confirmEnrollment(ctx, mfa, req.Code)
+	default:
+		err = ErrMFANotEnabled
+	}
+	if errors.Is(err, ErrInvalidMFACode) {
+		return nil, "", s.recordMFAFailure(ctx, challenge, subject, user, clientIP, userAgent, now)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := s.clearLoginFailures(ctx, user); err != nil {
+		return nil, "", err
+	}
+	if _, err := s.repo.ClearLoginAttempts(ctx, SecurityScopeMFAChallenge, challenge.TokenID); err != nil {
+		return nil, "", fmt.Errorf("failed to clear MFA failures: %w", err)
+	}
+
+	roles, err := s.repo.GetAllUserRoles(ctx, user.ID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get user roles: %w", err)
+	}
+
+	tokens, refreshToken, err := s.issueTokens(ctx, user, roles, nil, true, clientIP, userAgent)
+	if err != nil {
+		return nil, "", err
+	}
+	response.Tokens = tokens
+
+	return response, refreshToken, nil
+}
+
+// ValidateMFAEnrollmentToken validates an enrollment challenge token and returns the user's public ID.
+// It lets users whose roles require MFA enroll before they can get an access token.
+func (s *service) ValidateMFAEnrollmentToken(tokenString string) (string, error) {
+	challenge, err := s.parseMFAToken(tokenString)
+	if err != nil {
+		return "", err
+	}
+	if challenge.Purpose != mfaPurposeEnroll {
+		return "", ErrInvalidToken
+	}
+	return challenge.UserID, nil
+}
+
+// GetMFAStatus returns whether MFA is enabled and required for a user
+func (s *service) GetMFAStatus(ctx context.Context, userPublicID string) (*MFAStatusResponse, error) {
+	userID, err := s.repo.GetUserInternalID(ctx, userPublicID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	roles, err := s.repo.GetAllUserRoles(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+
+	status := &MFAStatusResponse{Required: s.mfaRequired(roles)}
+
+	enabled, err := s.mfaEnabled(ctx, userID)
+	if err != nil || !enabled {
+		return status, err
+	}
+	status.Enabled = true
+
+	codes, err := s.repo.ListUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recovery codes: %w", err)
+	}
+	status.RecoveryCodesRemaining = len(codes)
+
+	return status, nil
+}
+
+// EnrollMFA generates a new TOTP secret for the user. MFA is enabled once a code is confirmed.
+func (s *service) EnrollMFA(ctx context.Context, userPublicID string) (*MFAEnrollmentResponse, error) {
+	user, err := s.getUserByPublicID(ctx, userPublicID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	enabled, err := s.mfaEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+
+	encrypted, err := s.encryptSecret(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+
+	if err := s.repo.SavePendingMFA(ctx, user.ID, encrypted); err != nil {
+		return nil, fmt.Errorf("failed to save MFA enrollment: %w", err)
+	}
+
+	return &MFAEnrollmentResponse{
+		Secret:          totpEncoding.EncodeToString(secret),
+		ProvisioningURI: totpProvisioningURI(s.config.MFAIssuer, user.LoginID, secret),
+	}, nil
+}
+
+// ConfirmMFA enables a pending enrollment with the first code from the authenticator app
+func (s *service) ConfirmMFA(ctx context.Context, userPublicID string, req *MFACodeRequest) (*RecoveryCodesResponse, error) {
+	userID, err := s.repo.GetUserInternalID(ctx, userPublicID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	mfa, err := s.repo.GetUserMFA(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMFANotEnabled
+		}
+		return nil, fmt.Errorf("failed to get MFA enrollment: %w", err)
+	}
+	if mfa.IsEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	codes, err := s.confirmEnrollment(ctx, mfa, req.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableMFA removes the user's second factor after checking a current code.
+// Users whose roles require MFA cannot disable it.
+func (s *service) DisableMFA(ctx context.Context, userPublicID string, req *MFACodeRequest) error {
+	userID, mfa, err := s.getEnabledMFA(ctx, userPublicID)
+	if err != nil {
+		return err
+	}
+
+	roles, err := s.repo.GetAllUserRoles(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user roles: %w", err)
+	}
+	if s.mfaRequired(roles) {
+		return ErrMFAEnforced
+	}
+
+	if err := s.verifySecondFactor(ctx, mfa, req.Code, req.RecoveryCode); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteUserMFA(ctx, userID)
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after checking a current code
+func (s *service) RegenerateRecoveryCodes(ctx context.Context, userPublicID string, req *MFACodeRequest) (*RecoveryCodesResponse, error) {
+	userID, mfa, err := s.getEnabledMFA(ctx, userPublicID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.verifySecondFactor(ctx, mfa, req.Code, req.RecoveryCode); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := s.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to save recovery codes: %w", err)
+	}
+
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// confirmEnrollment checks the first TOTP code of a pending enrollment, enables it and
+// returns the new recovery codes
+func (s *service) confirmEnrollment(ctx context.Context, mfa *UserMFA, code string) ([]string, error) {
+	secret, err := s.decryptSecret(mfa.SecretEncrypted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+
+	step, ok := validateTOTP(secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := s.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.EnableMFA(ctx, mfa.UserID, step, hashes); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, fmt.Errorf("failed to enable MFA: %w", err)
+	}
+
+	return codes, nil
+}
+
+// verifySecondFactor checks a TOTP code or, if none is given, a recovery code.
+// Each TOTP time step and each recovery code is accepted only once.
+func (s *service) verifySecondFactor(ctx context.Context, mfa *UserMFA, code, recoveryCode string) error {
+	if code != "" {
+		secret, err := s.decryptSecret(mfa.SecretEncrypted)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt secret: %w", err)
+		}
+
+		step, ok := validateTOTP(secret, code, time.Now())
+		if !ok {
+			return ErrInvalidMFACode
+		}
+
+		fresh, err := s.repo.UseMFAStep(ctx, mfa.UserID, step)
+		if err != nil {
+			return fmt.Errorf("failed to record MFA code: %w", err)
+		}
+		if !fresh {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	normalized := normalizeRecoveryCode(recoveryCode)
+	if normalized == "" {
+		return ErrInvalidMFACode
+	}
+
+	// Codes generated before they were keyed were stored as plain SHA-256 digests
+	for _, digest := range []string{s.recoveryCodeDigest(normalized), s.hashToken(normalized)} {
+		used, err := s.repo.UseRecoveryCodeByHash(ctx, mfa.UserID, digest)
+		if err != nil {
+			return fmt.Errorf("failed to use recovery code: %w", err)
+		}
+		if used {
+			return nil
+		}
+	}
+
+	// Older codes still have Argon2id hashes
+	codes, err := s.repo.ListUnusedRecoveryCodes(ctx, mfa.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to list recovery codes: %w", err)
+	}
+
+	for _, stored := range codes {
+		if !strings.HasPrefix(stored.CodeHash, "$argon2") || !s.verifyPassword(normalized, stored.CodeHash) {
+			continue
+		}
+
+		used, err := s.repo.UseRecoveryCode(ctx, stored.ID)
+		if err != nil {
+			return fmt.Errorf("failed to use recovery code: %w", err)
+		}
+		if !used {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	return ErrInvalidMFACode
+}
+
+// generateRecoveryCodes creates a new set of recovery codes and their digests (see recoveryCodeDigest)
+func (s *service) generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for range recoveryCodeCount {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+
+		codes = append(codes, code)
+		hashes = append(hashes, s.recoveryCodeDigest(normalizeRecoveryCode(code)))
+	}
+
+	return codes, hashes, nil
+}
+
+// recoveryCodeDigest returns the stored form of a normalized recovery code: HMAC-SHA256 keyed with
+// the MFA encryption key. Unlike passwords the codes are random (50 bits), so no slow hash is needed
+// against guessing, and a digest can be looked up directly instead of running Argon2id over every
+// code of the user on each attempt. The key keeps leaked digests from being brute-forced offline
+// without also stealing the key, which protects the TOTP secrets as well.
+func (s *service) recoveryCodeDigest(normalized string) string {
+	mac := hmac.New(sha256.New, s.encryptionKey)
+	mac.Write([]byte("mfa-recovery-code:" + normalized))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// getEnabledMFA resolves a user and their enabled MFA enrollment
+func (s *service) getEnabledMFA(ctx context.Context, userPublicID string) (int, *UserMFA, error) {
+	userID, err := s.repo.GetUserInternalID(ctx, userPublicID)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	mfa, err := s.repo.GetUserMFA(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil, ErrMFANotEnabled
+		}
+		return 0, nil, fmt.Errorf("failed to get MFA enrollment: %w", err)
+	}
+	if !mfa.IsEnabled {
+		return 0, nil, ErrMFANotEnabled
+	}
+
+	return userID, mfa, nil
+}
+
+// mfaEnabled reports whether the user has a confirmed TOTP enrollment
+func (s *service) mfaEnabled(ctx context.Context, userID int) (bool, error) {
+	mfa, err := s.repo.GetUserMFA(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get MFA enrollment: %w", err)
+	}
+	return mfa.IsEnabled, nil
+}
+
+// mfaRequired reports whether any of the roles is configured to require MFA
+func (s *service) mfaRequired(roles []string) bool {
+	for _, role := range roles {
+		if slices.Contains(s.config.MFARequiredRoles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// newMFAChallenge creates the short-lived token exchanged for real tokens via VerifyMFA
+func (s *service) newMFAChallenge(user *AuthUser, purpose string) (*MFAChallengeResponse, error) {
+	now := time.Now()
+	tokenID, err := s.generateTokenID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate MFA token: %w", err)
+	}
+
+	claims := jwt.MapClaims{
+		"user_id":   user.PublicID,
+		"token_use": mfaTokenUse,
+		"purpose":   purpose,
+		"jti":       tokenID,
+		"iat":       now.Unix(),
+		"iat_us":    now.UnixMicro(),
+		"exp":       now.Add(s.config.MFAChallengeDuration).Unix(),
+		"iss":       TokenIssuer,
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.jwtSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign MFA token: %w", err)
+	}
+
+	return &MFAChallengeResponse{
+		Required:           true,
+		EnrollmentRequired: purpose == mfaPurposeEnroll,
+		Token:              token,
+		ExpiresIn:          int64(s.config.MFAChallengeDuration.Seconds()),
+	}, nil
+}
+
+// parseMFAToken validates an MFA challenge token
+func (s *service) parseMFAToken(tokenString string) (*mfaChallenge, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return s.jwtSecret, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	use, _ := claims["token_use"].(string)
+	userID, _ := claims["user_id"].(string)
+	purpose, _ := claims["purpose"].(string)
+	tokenID, _ := claims["jti"].(string)
+	issuedAt, _ := claims["iat"].(float64)
+	issuedAtMicros, _ := claims["iat_us"].(float64)
+	expiresAt, _ := claims["exp"].(float64)
+	if use != mfaTokenUse || userID == "" || tokenID == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return &mfaChallenge{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenID:   tokenID,
+		IssuedAt:  int64(issuedAt),
+		ExpiresAt: int64(expiresAt),
+
+		IssuedAtMicros: int64(issuedAtMicros),
+	}, nil
+}
+
+// recordMFAFailure counts a wrong code against the login ID and client IP, and against the
+// challenge itself. Once the challenge reached MFAMaxAttempts it is denied and ErrInvalidToken
+// is returned, so the user has to log in again; otherwise ErrInvalidMFACode.
+func (s *service) recordMFAFailure(ctx context.Context, challenge *mfaChallenge, subject string, user *AuthUser, clientIP, userAgent string, now time.Time) error {
+	if err := s.recordLoginFailure(ctx, subject, user.LoginID, user, clientIP, userAgent, now); err != nil {
+		return err
+	}
+	if s.config.MFAMaxAttempts <= 0 {
+		return ErrInvalidMFACode
+	}
+
+	attempt, err := s.repo.RecordLoginFailure(ctx, SecurityScopeMFAChallenge, challenge.TokenID, now, now.Add(-s.config.MFAChallengeDuration))
+	if err != nil {
+		return fmt.Errorf("failed to record MFA failure: %w", err)
+	}
+	if attempt.FailedCount < s.config.MFAMaxAttempts {
+		return ErrInvalidMFACode
+	}
+
+	if err := s.denylist.DenyToken(ctx, challenge.TokenID, time.Unix(challenge.ExpiresAt, 0)); err != nil {
+		return fmt.Errorf("failed to invalidate MFA challenge: %w", err)
+	}
+	return ErrInvalidToken
+}
+
+// getUserByPublicID retrieves a user by public ID
+func (s *service) getUserByPublicID(ctx context.Context, publicID string) (*AuthUser, error) {
+	userID, err := s.repo.GetUserInternalID(ctx, publicID)
+	if err != nil {
+		return nil, err
+	}
+	return s.getUserByInternalID(ctx, userID)
+}
+
+// encryptSecret encrypts a TOTP secret using AES-256-GCM
+func (s *service) encryptSecret(secret []byte) (string, error) {
+	block, err := aes.NewCipher(s.encryptionKey)
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	ciphertext := gcm.Seal(nonce, nonce, secret, nil)
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// decryptSecret decrypts a TOTP secret encrypted by encryptSecret
+func (s *service) decryptSecret(ciphertext string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(s.encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonceSize := gcm.NonceSize()
+	if len(data) < nonceSize {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, sealed := data[:nonceSize], data[nonceSize:]
+	return gcm.Open(nil, nonce, sealed, nil)
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"kc-api/internal/password"
+)
+
+// fakeMFARepository keeps login attempts and recovery codes in memory. Repository methods
+// the MFA checks don't use are left to the nil embedded interface.
+type fakeMFARepository struct {
+	Repository
+	attempts map[SecurityScope]map[string]int
+	locked   map[SecurityScope][]string
+	codes    []RecoveryCode
+}
+
+func newFakeMFARepository(codes ...RecoveryCode) *fakeMFARepository {
+	return &fakeMFARepository{
+		attempts: make(map[SecurityScope]map[string]int),
+		locked:   make(map[SecurityScope][]string),
+		codes:    codes,
+	}
+}
+
+func (r *fakeMFARepository) RecordLoginFailure(ctx context.Context, scope SecurityScope, subject string, at, windowStart time.Time) (*LoginAttempt, error) {
+	if r.attempts[scope] == nil {
+		r.attempts[scope] = make(map[string]int)
+	}
+	r.attempts[scope][subject]++
+	return &LoginAttempt{Scope: scope, Subject: subject, FailedCount: r.attempts[scope][subject], LastFailedAt: &at}, nil
+}
+
+func (r *fakeMFARepository) LockLogin(ctx context.Context, scope SecurityScope, subject string, until time.Time) error {
+	r.locked[scope] = append(r.locked[scope], subject)
+	r.attempts[scope][subject] = 0
+	return nil
+}
+
+func (r *fakeMFARepository) CreateSecurityEvent(ctx context.Context, event *SecurityEvent) error {
+	return nil
+}
+
+func (r *fakeMFARepository) UseRecoveryCodeByHash(ctx context.Context, userID int, codeHash string) (bool, error) {
+	for i := range r.codes {
+		if r.codes[i].UserID == userID && r.codes[i].CodeHash == codeHash && r.codes[i].UsedAt == nil {
+			now := time.Now()
+			r.codes[i].UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeMFARepository) ListUnusedRecoveryCodes(ctx context.Context, userID int) ([]RecoveryCode, error) {
+	var codes []RecoveryCode
+	for _, code := range r.codes {
+		if code.UserID == userID && code.UsedAt == nil {
+			codes = append(codes, code)
+		}
+	}
+	return codes, nil
+}
+
+func (r *fakeMFARepository) UseRecoveryCode(ctx context.Context, codeID int64) (bool, error) {
+	for i := range r.codes {
+		if r.codes[i].ID == codeID && r.codes[i].UsedAt == nil {
+			now := time.Now()
+			r.codes[i].UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestService_RecordMFAFailure(t *testing.T) {
+	repo := newFakeMFARepository()
+	s := &service{
+		repo:     repo,
+		config:   &Config{MFAMaxAttempts: 3, MFAChallengeDuration: 5 * time.Minute, LockoutThreshold: 5, LockoutWindow: 15 * time.Minute, LockoutDuration: 15 * time.Minute},
+		denylist: newTokenDenylist(nil),
+	}
+	user := &AuthUser{ID: 1, PublicID: "user-jdoe", LoginID: "jdoe"}
+	now := time.Now()
+	challenge := &mfaChallenge{UserID: user.PublicID, TokenID: "challenge-1", IssuedAt: now.Unix(), ExpiresAt: now.Add(5 * time.Minute).Unix()}
+
+	for i := 1; i <= 2; i++ {
+		if err := s.recordMFAFailure(context.Background(), challenge, "jdoe", user, "10.0.0.1", "test", now); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("failure %d: expected ErrInvalidMFACode, got %v", i, err)
+		}
+		if s.denylist.IsDenied(context.Background(), challenge.claims()) {
+			t.Fatalf("failure %d: expected the challenge to stay valid", i)
+		}
+	}
+
+	if err := s.recordMFAFailure(context.Background(), challenge, "jdoe", user, "10.0.0.1", "test", now); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected the last failure to invalidate the challenge, got %v", err)
+	}
+	if !s.denylist.IsDenied(context.Background(), challenge.claims()) {
+		t.Error("expected the challenge to be denied")
+	}
+
+	// The failures count towards the lockout of the login ID and client IP like wrong passwords
+	if got := repo.attempts[SecurityScopeLoginID]["jdoe"]; got != 3 {
+		t.Errorf("expected 3 failures of the login ID, got %d", got)
+	}
+	if got := repo.attempts[SecurityScopeIP]["10.0.0.1"]; got != 3 {
+		t.Errorf("expected 3 failures of the client IP, got %d", got)
+	}
+
+	// A new challenge gets its own attempts until the login ID is locked
+	next := &mfaChallenge{UserID: user.PublicID, TokenID: "challenge-2", IssuedAt: now.Unix(), ExpiresAt: now.Add(5 * time.Minute).Unix()}
+	for range 2 {
+		if err := s.recordMFAFailure(context.Background(), next, "jdoe", user, "10.0.0.1", "test", now); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("expected ErrInvalidMFACode, got %v", err)
+		}
+	}
+	if len(repo.locked[SecurityScopeLoginID]) != 1 {
+		t.Errorf("expected the login ID to be locked after 5 failures, got %v", repo.locked)
+	}
+}
+
+func TestService_VerifyRecoveryCode(t *testing.T) {
+	s := &service{config: &Config{}, encryptionKey: []byte("0123456789abcdef0123456789abcdef")}
+	legacyHash, err := password.Hash("legacycode", password.Params{Time: 1, Memory: 64, Threads: 1})
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+
+	repo := newFakeMFARepository(
+		RecoveryCode{ID: 1, UserID: 1, CodeHash: s.recoveryCodeDigest("k7m2px9q4t")},
+		RecoveryCode{ID: 2, UserID: 1, CodeHash: legacyHash},
+		RecoveryCode{ID: 3, UserID: 2, CodeHash: s.recoveryCodeDigest("othercode1")},
+		RecoveryCode{ID: 4, UserID: 1, CodeHash: s.hashToken("unkeyedcd1")},
+	)
+	s.repo = repo
+	mfa := &UserMFA{UserID: 1, IsEnabled: true}
+
+	tests := []struct {
+		name    string
+		code    string
+		wantErr error
+	}{
+		{name: "digest match, case and separator insensitive", code: "K7M2P-X9Q4T"},
+		{name: "used only once", code: "k7m2p-x9q4t", wantErr: ErrInvalidMFACode},
+		{name: "legacy Argon2id hash", code: "legac-ycode"},
+		{name: "unkeyed SHA-256 digest", code: "unkey-edcd1"},
+		{name: "code of another user", code: "other-code1", wantErr: ErrInvalidMFACode},
+		{name: "empty", code: " - ", wantErr: ErrInvalidMFACode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.verifySecondFactor(context.Background(), mfa, "", tt.code)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// The stored digest depends on the key, so a leaked table can't be checked without it
+	other := &service{encryptionKey: []byte("fedcba9876543210fedcba9876543210")}
+	if other.recoveryCodeDigest("k7m2px9q4t") == s.recoveryCodeDigest("k7m2px9q4t") {
+		t.Error("expected the digest to depend on the encryption key")
+	}
+}
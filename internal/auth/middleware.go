@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/go-chi/chi/v5/middleware"
+
 	"kc-api/internal/utils"
 )
 
@@ -17,17 +19,19 @@ const (
 	claimsKey    contextKey = "claims"
 )
 
-// Middleware provides JWT authentication middleware
+// Middleware provides JWT and API key authentication middleware
 type Middleware struct {
-	service Service
+	service   Service
+	clientIPs *ClientIPResolver
 }
 
-// NewMiddleware creates a new auth middleware
-func NewMiddleware(service Service) *Middleware {
-	return &Middleware{service: service}
+// NewMiddleware creates a new auth middleware.
+// The client IP resolver is optional; when nil, forwarding headers are ignored.
+func NewMiddleware(service Service, clientIPs *ClientIPResolver) *Middleware {
+	return &Middleware{service: service, clientIPs: clientIPs}
 }
 
-// Authenticate is a middleware that validates JWT tokens from the Authorization header
+// Authenticate is a middleware that validates JWT tokens or API keys from the Authorization header
 func (m *Middleware) Authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Get token from Authorization header
@@ -38,16 +42,14 @@ func (m *Middleware) Authenticate(next http.Handler) http.Handler {
 		}
 
 		// Check Bearer prefix
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		tokenString := bearerToken(r)
+		if tokenString == "" {
 			utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Invalid authorization header format")
 			return
 		}
 
-		tokenString := parts[1]
-
 		// Validate token
-		claims, err := m.service.ValidateAccessToken(tokenString)
+		claims, err := m.validateToken(r, tokenString)
 		if err != nil {
 			utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Invalid or expired token")
 			return
@@ -59,30 +61,25 @@ func (m *Middleware) Authenticate(next http.Handler) http.Handler {
 		ctx = context.WithValue(ctx, userRolesKey, claims.Roles)
 		ctx = context.WithValue(ctx, claimsKey, claims)
 
-		next.ServeHTTP(w, r.WithContext(ctx))
+		m.serve(next, w, r.WithContext(ctx), claims)
 	})
 }
 
-// OptionalAuthenticate is a middleware that validates JWT tokens if present, but doesn't require them
+// OptionalAuthenticate is a middleware that validates JWT tokens or API keys if present, but doesn't require them
 func (m *Middleware) OptionalAuthenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Get token from Authorization header
-		authHeader := r.Header.Get("Authorization")
-		if authHeader != "" {
-			parts := strings.SplitN(authHeader, " ", 2)
-			if len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") {
-				tokenString := parts[1]
-
-				// Validate token
-				claims, err := m.service.ValidateAccessToken(tokenString)
-				if err == nil {
-					// Add user info to context
-					ctx := r.Context()
-					ctx = context.WithValue(ctx, userIDKey, claims.UserID)
-					ctx = context.WithValue(ctx, userRolesKey, claims.Roles)
-					ctx = context.WithValue(ctx, claimsKey, claims)
-					r = r.WithContext(ctx)
-				}
+		if tokenString := bearerToken(r); tokenString != "" {
+			// Validate token
+			claims, err := m.validateToken(r, tokenString)
+			if err == nil {
+				// Add user info to context
+				ctx := r.Context()
+				ctx = context.WithValue(ctx, userIDKey, claims.UserID)
+				ctx = context.WithValue(ctx, userRolesKey, claims.Roles)
+				ctx = context.WithValue(ctx, claimsKey, claims)
+				m.serve(next, w, r.WithContext(ctx), claims)
+				return
 			}
 		}
 
@@ -90,8 +87,74 @@ func (m *Middleware) OptionalAuthenticate(next http.Handler) http.Handler {
 	})
 }
 
-// RequireRoles is a middleware that checks if the user has at least one of the required roles
-func (m *Middleware) RequireRoles(roles ...string) func(http.Handler) http.Handler {
+// serve passes an authenticated request on. Requests made with an impersonation token
+// are recorded in the impersonation audit log together with their response status.
+func (m *Middleware) serve(next http.Handler, w http.ResponseWriter, r *http.Request, claims *TokenClaims) {
+	if claims.Actor == nil {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+	next.ServeHTTP(ww, r)
+
+	status := ww.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	m.service.RecordImpersonatedRequest(context.WithoutCancel(r.Context()), claims, r.Method, r.URL.Path, status, m.clientIPs.ClientIP(r))
+}
+
+// validateToken validates a bearer token, which is either a JWT access token or an API key
+// (personal access token or service account key). Both yield the same claims. Access tokens
+// revoked before their expiry are rejected; API keys carry the current roles anyway.
+func (m *Middleware) validateToken(r *http.Request, tokenString string) (*TokenClaims, error) {
+	if isAPIKey(tokenString) {
+		return m.service.ValidateAPIKey(r.Context(), tokenString, m.clientIPs.ClientIP(r))
+	}
+
+	claims, err := m.service.ValidateAccessToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if m.service.IsTokenRevoked(r.Context(), claims) {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// bearerToken returns the token of a Bearer Authorization header, or "" if there is none
+func bearerToken(r *http.Request) string {
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return ""
+	}
+	return parts[1]
+}
+
+// DenyImpersonation is a middleware that rejects requests made with an impersonation token.
+// It guards operations only users themselves may perform, such as changing their credentials.
+func DenyImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsImpersonating(r.Context()) {
+			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "Not allowed while impersonating a user")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AdminRoles are the roles allowed on the administration routes. full_access holds every role.
+var AdminRoles = []string{"admin", "full_access"}
+
+// RequireAdmin is a middleware that restricts administration routes to AdminRoles. It applies in
+// addition to the permission rules, so routes without a rule are not open to every user.
+func RequireAdmin(next http.Handler) http.Handler {
+	return RequireAnyRole(AdminRoles...)(next)
+}
+
+// RequireAnyRole is a middleware that checks if the roles of the request include at least one of the required roles
+func RequireAnyRole(roles ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			userRoles := GetUserRolesFromContext(r.Context())
@@ -124,6 +187,11 @@ func (m *Middleware) RequireRoles(roles ...string) func(http.Handler) http.Handl
 	}
 }
 
+// RequireRoles is a middleware that checks if the user has at least one of the required roles
+func (m *Middleware) RequireRoles(roles ...string) func(http.Handler) http.Handler {
+	return RequireAnyRole(roles...)
+}
+
 // RequireAllRoles is a middleware that checks if the user has all of the required roles
 func (m *Middleware) RequireAllRoles(roles ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -181,6 +249,23 @@ func GetClaimsFromContext(ctx context.Context) *TokenClaims {
 	return nil
 }
 
+// SetClaimsInContext sets the token claims in the context (for testing purposes)
+func SetClaimsInContext(ctx context.Context, claims *TokenClaims) context.Context {
+	return context.WithValue(ctx, claimsKey, claims)
+}
+
+// IsAPIKeyAuthenticated checks if the current request was authenticated with an API key
+func IsAPIKeyAuthenticated(ctx context.Context) bool {
+	claims := GetClaimsFromContext(ctx)
+	return claims != nil && claims.APIKeyID != ""
+}
+
+// IsImpersonating checks if the current request was made by an administrator acting as the user
+func IsImpersonating(ctx context.Context) bool {
+	claims := GetClaimsFromContext(ctx)
+	return claims != nil && claims.Actor != nil
+}
+
 // IsAuthenticated checks if the current request has a valid authenticated user
 func IsAuthenticated(ctx context.Context) bool {
 	return GetUserIDFromContext(ctx) != ""
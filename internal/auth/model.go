@@ -3,6 +3,8 @@ package auth
 import (
 	"encoding/json"
 	"time"
+
+	"kc-api/internal/password"
 )
 
 // RegisterRequest represents the request body for user registration
@@ -37,6 +39,8 @@ type UserInfo struct {
 	LoginID string          `json:"login_id" example:"john.doe"`
 	Name    json.RawMessage `json:"name" swaggertype:"object"`
 	Email   string          `json:"email" example:"john.doe@example.com"`
+
+	EmailVerified bool `json:"email_verified" example:"true"`
 }
 
 // TokenClaims represents JWT claims
@@ -49,6 +53,25 @@ type TokenClaims struct {
 	IssuedAt int64    `json:"iat"`
 	ExpireAt int64    `json:"exp"`
 	Issuer   string   `json:"iss"`
+
+	// IssuedAtMicros is the issue time in Unix microseconds, which the token denylist compares
+	// watermarks with. Zero for tokens issued before the claim existed.
+	IssuedAtMicros int64 `json:"iat_us,omitempty"`
+
+	// MFAVerified is true if the session was started with a second factor
+	MFAVerified bool `json:"mfa"`
+
+	// APIKeyID is set when the request was authenticated with an API key instead of an access token
+	APIKeyID string `json:"api_key_id,omitempty"`
+
+	// Actor is the administrator acting as the user, set for impersonation tokens
+	Actor *TokenActor `json:"act,omitempty"`
+}
+
+// TokenActor identifies the administrator impersonating the user of a token (RFC 8693 act claim)
+type TokenActor struct {
+	UserID  string `json:"user_id" example:"01912345-6789-7abc-def0-123456789abc"`
+	LoginID string `json:"login_id" example:"support.agent"`
 }
 
 // UserToken represents a stored refresh token in the database
@@ -59,13 +82,150 @@ type UserToken struct {
 	ExpiresAt         time.Time
 	IsRevoked         bool
 	ReplacedByTokenID *int64
+	RotatedAt         *time.Time // When the token was exchanged for its replacement
 	ParentTokenID     *int64
+	SessionID         string // Shared by all tokens of a rotation family
 	ClientIP          *string
 	UserAgent         *string
+	MFAVerified       bool
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
 }
 
+// Session is an active refresh-token family, represented by its current token
+type Session struct {
+	ID          string
+	TokenHash   string
+	ClientIP    *string
+	UserAgent   *string
+	MFAVerified bool
+	StartedAt   time.Time // Creation of the first token of the family (login)
+	LastUsedAt  time.Time // Creation of the current token (last login or refresh)
+	ExpiresAt   time.Time
+}
+
+// ActionTokenPurpose is what a single-use emailed token may be used for
+type ActionTokenPurpose string
+
+const (
+	ActionTokenPasswordReset     ActionTokenPurpose = "PASSWORD_RESET"
+	ActionTokenEmailVerification ActionTokenPurpose = "EMAIL_VERIFICATION"
+)
+
+// ActionToken represents a stored single-use token sent by email.
+// Only the SHA-256 hash of the token is stored.
+type ActionToken struct {
+	ID        int64
+	UserID    int
+	Purpose   ActionTokenPurpose
+	TokenHash string
+	Email     string // Address the token was sent to
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// UserMFA represents the TOTP enrollment of a user.
+// The secret is stored AES-GCM encrypted; IsEnabled is false until the first code is confirmed.
+type UserMFA struct {
+	UserID          int
+	SecretEncrypted string
+	IsEnabled       bool
+	EnabledAt       *time.Time
+	LastUsedStep    *int64
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// RecoveryCode represents a stored single-use MFA recovery code
+type RecoveryCode struct {
+	ID       int64
+	UserID   int
+	CodeHash string
+	UsedAt   *time.Time
+}
+
+// SecurityScope is what failed login attempts are counted by, or what a security event concerns
+type SecurityScope string
+
+const (
+	SecurityScopeLoginID SecurityScope = "LOGIN_ID"
+	SecurityScopeIP      SecurityScope = "IP"
+	SecurityScopeSession SecurityScope = "SESSION"
+
+	// SecurityScopeMFAChallenge counts the wrong codes sent with one MFA challenge token (by token ID)
+	SecurityScopeMFAChallenge SecurityScope = "MFA_CHALLENGE"
+
+	// SecurityScopeShareLink counts the wrong passwords sent for one file share link (by link public ID)
+	SecurityScopeShareLink SecurityScope = "SHARE_LINK"
+)
+
+// LoginAttempt tracks recent failed logins of a login ID or client IP
+type LoginAttempt struct {
+	Scope        SecurityScope
+	Subject      string // Lowercased login ID or client IP
+	FailedCount  int    // Failures since the last lockout, counted within the lockout window
+	LastFailedAt *time.Time
+	LockedUntil  *time.Time
+	LockoutCount int
+}
+
+// SecurityEventType identifies the kind of a recorded security event
+type SecurityEventType string
+
+const (
+	SecurityEventLoginLockout      SecurityEventType = "LOGIN_LOCKOUT"
+	SecurityEventLoginUnlock       SecurityEventType = "LOGIN_UNLOCK"
+	SecurityEventRefreshTokenReuse SecurityEventType = "REFRESH_TOKEN_REUSE"
+)
+
+// SecurityEvent is an audit record of a security-relevant event
+type SecurityEvent struct {
+	ID             int64
+	EventType      SecurityEventType
+	Scope          SecurityScope
+	Subject        string
+	LoginID        string // Login ID as entered in the triggering request
+	ClientIP       string
+	UserAgent      string
+	UserID         *int // Affected user, if the login ID belongs to one
+	ActorUserID    *int // Administrator who performed the action
+	FailedAttempts int
+	LockedUntil    *time.Time
+	CreatedAt      time.Time
+
+	// Populated when listing
+	UserPublicID  *string
+	ActorPublicID *string
+}
+
+// APIKeyKind distinguishes personal access tokens from service account API keys
+type APIKeyKind string
+
+const (
+	APIKeyKindPersonal APIKeyKind = "PERSONAL"
+	APIKeyKindService  APIKeyKind = "SERVICE"
+)
+
+// APIKey represents a stored personal access token or service account API key.
+// Only the SHA-256 hash of the key is stored.
+type APIKey struct {
+	ID          int64
+	PublicID    string
+	UserID      int
+	Kind        APIKeyKind
+	Name        string
+	TokenPrefix string // Leading characters of the key, shown to tell keys apart
+	TokenHash   string
+	Scopes      []string // Roles the key may use, a subset of the owner's roles
+	ExpiresAt   *time.Time
+	LastUsedAt  *time.Time
+	LastUsedIP  *string
+	CreatedBy   *int
+	RevokedAt   *time.Time
+	CreatedAt   time.Time
+}
+
 // Group represents a user group
 type Group struct {
 	ID          int
@@ -87,13 +247,16 @@ type Role struct {
 
 // AuthUser represents user data needed for authentication
 type AuthUser struct {
-	ID           int
-	PublicID     string
-	LoginID      string
-	Email        string
-	Name         json.RawMessage
-	PasswordHash string
-	IsDeleted    bool
+	ID               int
+	PublicID         string
+	LoginID          string
+	Email            string
+	Name             json.RawMessage
+	PasswordHash     string
+	EmailVerifiedAt  *time.Time
+	IsServiceAccount bool   // Service accounts cannot log in; they authenticate with API keys
+	DirectoryDN      string // Set for users managed by the LDAP directory, who log in with their directory password
+	IsDeleted        bool
 }
 
 // ErrorResponse represents an error response
@@ -107,23 +270,433 @@ type SuccessResponse struct {
 	Message string `json:"message" example:"Operation completed successfully"`
 }
 
-// RegisterResponse represents the response after successful registration
+// RegisterResponse represents the response after successful registration.
+// Tokens is omitted when the new user's roles require MFA enrollment first.
 type RegisterResponse struct {
-	User    UserInfo      `json:"user"`
-	Tokens  TokenResponse `json:"tokens"`
-	Message string        `json:"message" example:"User registered successfully"`
+	User    UserInfo              `json:"user"`
+	Tokens  *TokenResponse        `json:"tokens,omitempty"`
+	MFA     *MFAChallengeResponse `json:"mfa,omitempty"`
+	Message string                `json:"message" example:"User registered successfully"`
 }
 
-// LoginResponse represents the response after successful login
+// LoginResponse represents the response after successful login.
+// When a second factor is needed, Tokens is omitted and MFA holds the challenge to complete.
 type LoginResponse struct {
-	User   UserInfo      `json:"user"`
-	Tokens TokenResponse `json:"tokens"`
+	User   UserInfo              `json:"user"`
+	Tokens *TokenResponse        `json:"tokens,omitempty"`
+	MFA    *MFAChallengeResponse `json:"mfa,omitempty"`
+
+	// PasswordChange is set instead of tokens when the password expired and must be changed first
+	PasswordChange *PasswordChangeChallengeResponse `json:"password_change,omitempty"`
+
+	// RecoveryCodes is only set when the login completed a required MFA enrollment
+	RecoveryCodes []string `json:"recovery_codes,omitempty" example:"k7m2p-x9q4t"`
+}
+
+// ForgotPasswordRequest represents the request to send a password reset email
+type ForgotPasswordRequest struct {
+	Email string `json:"email" example:"john.doe@example.com"`
+}
+
+// ResetPasswordRequest represents the request to set a new password with a reset token
+type ResetPasswordRequest struct {
+	Token    string `json:"token" example:"Zm9vYmFyYmF6cXV4..."`
+	Password string `json:"password" example:"newSecurePassword123"`
+}
+
+// ChangeExpiredPasswordRequest represents the request to replace an expired password after login
+type ChangeExpiredPasswordRequest struct {
+	PasswordChangeToken string `json:"password_change_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	NewPassword         string `json:"new_password" example:"newSecurePassword123"`
+}
+
+// PasswordChangeChallengeResponse is returned by login when the password was correct but has expired
+type PasswordChangeChallengeResponse struct {
+	Required  bool   `json:"required" example:"true"`
+	Reason    string `json:"reason" example:"expired"`
+	Token     string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	ExpiresIn int64  `json:"expires_in" example:"600"`
+}
+
+// PasswordPolicyResponse represents the password policy in effect
+type PasswordPolicyResponse struct {
+	Policy password.Policy `json:"policy"`
+
+	// BreachListLoaded tells whether check_breached has an effect (AUTH_BREACHED_PASSWORDS_DIR)
+	BreachListLoaded bool `json:"breach_list_loaded" example:"true"`
+
+	// UpdatedAt is not set while the default policy is in effect
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// VerifyEmailRequest represents the request to verify an email address
+type VerifyEmailRequest struct {
+	Token string `json:"token" example:"Zm9vYmFyYmF6cXV4..."`
+}
+
+// MFAChallengeResponse is returned by login when the password was correct but a second factor is needed
+type MFAChallengeResponse struct {
+	Required           bool   `json:"required" example:"true"`
+	EnrollmentRequired bool   `json:"enrollment_required" example:"false"`
+	Token              string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	ExpiresIn          int64  `json:"expires_in" example:"300"`
+}
+
+// VerifyMFARequest represents the second login step. Either code or recovery_code is required.
+type VerifyMFARequest struct {
+	MFAToken     string `json:"mfa_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	Code         string `json:"code,omitempty" example:"123456"`
+	RecoveryCode string `json:"recovery_code,omitempty" example:"k7m2p-x9q4t"`
+}
+
+// MFACodeRequest confirms an MFA change with a TOTP code or a recovery code
+type MFACodeRequest struct {
+	Code         string `json:"code,omitempty" example:"123456"`
+	RecoveryCode string `json:"recovery_code,omitempty" example:"k7m2p-x9q4t"`
+}
+
+// MFAEnrollmentResponse contains the new TOTP secret to add to an authenticator app
+type MFAEnrollmentResponse struct {
+	Secret          string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	ProvisioningURI string `json:"provisioning_uri" example:"otpauth://totp/Knowledge%20Center:john.doe?algorithm=SHA1&digits=6&issuer=Knowledge+Center&period=30&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+}
+
+// RecoveryCodesResponse contains newly generated recovery codes. They are only shown once.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"k7m2p-x9q4t"`
+}
+
+// MFAStatusResponse represents the two-factor authentication state of the current user
+type MFAStatusResponse struct {
+	Enabled                bool `json:"enabled" example:"true"`
+	Required               bool `json:"required" example:"false"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining" example:"10"`
+}
+
+// SessionResponse represents an active session of a user
+type SessionResponse struct {
+	ID          string    `json:"id" example:"9b2f6c1e-3a4d-4f5e-8a7b-1c2d3e4f5a6b"`
+	ClientIP    *string   `json:"client_ip,omitempty" example:"203.0.113.7"`
+	UserAgent   *string   `json:"user_agent,omitempty" example:"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0 Safari/537.36"`
+	MFAVerified bool      `json:"mfa_verified" example:"true"`
+	Current     bool      `json:"current" example:"true"`
+	StartedAt   time.Time `json:"started_at"`
+	LastUsedAt  time.Time `json:"last_used_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// SessionListResponse represents the list of active sessions of a user
+type SessionListResponse struct {
+	Data []SessionResponse `json:"data"`
+}
+
+// UnlockLoginRequest represents a request to lift a login lockout.
+// At least one of login_id and client_ip is required.
+type UnlockLoginRequest struct {
+	LoginID  string `json:"login_id,omitempty" example:"john.doe"`
+	ClientIP string `json:"client_ip,omitempty" example:"203.0.113.7"`
+}
+
+// LockoutResponse represents an active login lockout
+type LockoutResponse struct {
+	Scope        SecurityScope `json:"scope" example:"LOGIN_ID"`
+	Subject      string        `json:"subject" example:"john.doe"`
+	LockedUntil  time.Time     `json:"locked_until"`
+	LockoutCount int           `json:"lockout_count" example:"1"`
+}
+
+// LockoutListResponse represents the list of active login lockouts
+type LockoutListResponse struct {
+	Data []LockoutResponse `json:"data"`
+}
+
+// SecurityEventResponse represents a recorded security event
+type SecurityEventResponse struct {
+	ID             int64             `json:"id" example:"1"`
+	EventType      SecurityEventType `json:"event_type" example:"LOGIN_LOCKOUT"`
+	Scope          SecurityScope     `json:"scope" example:"IP"`
+	Subject        string            `json:"subject" example:"203.0.113.7"`
+	LoginID        string            `json:"login_id,omitempty" example:"john.doe"`
+	ClientIP       string            `json:"client_ip,omitempty" example:"203.0.113.7"`
+	UserAgent      string            `json:"user_agent,omitempty"`
+	UserID         *string           `json:"user_id,omitempty" example:"01912345-6789-7abc-def0-123456789abc"`
+	ActorID        *string           `json:"actor_id,omitempty"`
+	FailedAttempts int               `json:"failed_attempts" example:"5"`
+	LockedUntil    *time.Time        `json:"locked_until,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+}
+
+// SecurityEventListResponse represents a paginated list of security events
+type SecurityEventListResponse struct {
+	Data       []SecurityEventResponse `json:"data"`
+	Page       int                     `json:"page" example:"1"`
+	Limit      int                     `json:"limit" example:"20"`
+	TotalCount int                     `json:"total_count" example:"100"`
+	TotalPages int                     `json:"total_pages" example:"5"`
+}
+
+// CreateAPIKeyRequest represents a request to create a personal access token or service account API key.
+// Scopes must be roles of the owner; expires_at defaults to the configured lifetime for personal access tokens.
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" example:"CI pipeline"`
+	Scopes    []string   `json:"scopes" example:"user"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIKeyResponse represents a personal access token or service account API key without its secret
+type APIKeyResponse struct {
+	ID         string     `json:"id" example:"4c1f2e3d-5a6b-4c7d-8e9f-0a1b2c3d4e5f"`
+	Kind       APIKeyKind `json:"kind" example:"PERSONAL"`
+	Name       string     `json:"name" example:"CI pipeline"`
+	Prefix     string     `json:"prefix" example:"kcp_Xk3vQ9aB"`
+	Scopes     []string   `json:"scopes" example:"user"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP *string    `json:"last_used_ip,omitempty" example:"203.0.113.7"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateAPIKeyResponse contains a newly created key. The token is only shown once.
+type CreateAPIKeyResponse struct {
+	Key   APIKeyResponse `json:"key"`
+	Token string         `json:"token" example:"kcp_Xk3vQ9aBzR7mT2wY5uE8iO1pL4sD6fG0hJ3kN9cV2bM"`
+}
+
+// APIKeyListResponse represents the list of active keys of a user or service account
+type APIKeyListResponse struct {
+	Data []APIKeyResponse `json:"data"`
+}
+
+// CreateServiceAccountRequest represents a request to create a service account. login_id defaults to the email.
+type CreateServiceAccountRequest struct {
+	LoginID string          `json:"login_id,omitempty" example:"svc-reporting"`
+	Name    json.RawMessage `json:"name" swaggertype:"object"`
+	Email   string          `json:"email" example:"reporting@example.com"`
+}
+
+// ServiceAccountListResponse represents the list of service accounts
+type ServiceAccountListResponse struct {
+	Data []UserInfo `json:"data"`
+}
+
+// OIDCAuthorizationResponse contains the URL of the OpenID provider to send the user to for signing in
+type OIDCAuthorizationResponse struct {
+	AuthorizationURL string `json:"authorization_url" example:"https://idp.example.com/authorize?response_type=code&client_id=kc-api&state=..."`
+}
+
+// OIDCCallbackRequest contains the parameters the OpenID provider redirected back with
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" example:"SplxlOBeZQQYbYS6WxSbIA"`
+	State string `json:"state" example:"af0ifjsldkj"`
+}
+
+// Department sources of directory users (AUTH_LDAP_DEPARTMENT_SOURCE)
+const (
+	DepartmentSourceAttribute = "attribute"
+	DepartmentSourceOU        = "ou"
+	DepartmentSourceNone      = "none"
+)
+
+// DirectoryUser is a user as seen by the directory sync. DirectoryDN is empty for local users.
+type DirectoryUser struct {
+	ID               int
+	PublicID         string
+	LoginID          string
+	Email            string
+	Name             json.RawMessage
+	DirectoryDN      string
+	DeptID           *int
+	IsServiceAccount bool
+	EmailVerified    bool
+}
+
+// DirectorySyncReport lists the changes of a directory sync. In a dry run they are only reported.
+type DirectorySyncReport struct {
+	DryRun             bool                      `json:"dry_run" example:"false"`
+	DirectoryUsers     int                       `json:"directory_users" example:"250"`
+	Created            []DirectoryUserChange     `json:"created"`
+	Updated            []DirectoryUserChange     `json:"updated"`
+	Deleted            []string                  `json:"deleted" example:"otimer"`
+	Skipped            []DirectoryUserChange     `json:"skipped"`
+	DepartmentsCreated []string                  `json:"departments_created" example:"Engineering/Backend"`
+	MembershipsAdded   []DirectoryMembershipDiff `json:"memberships_added"`
+	MembershipsRemoved []DirectoryMembershipDiff `json:"memberships_removed"`
+	Failures           int                       `json:"failures" example:"0"`
+	DeletionsSkipped   bool                      `json:"deletions_skipped" example:"false"` // entries without a login ID left the users to delete unknown
+	StartedAt          time.Time                 `json:"started_at" example:"2024-12-05T00:00:00Z"`
+	FinishedAt         time.Time                 `json:"finished_at" example:"2024-12-05T00:00:02Z"`
+}
+
+// DirectoryUserChange is a user created, updated or skipped by a directory sync
+type DirectoryUserChange struct {
+	LoginID string `json:"login_id" example:"jdoe"`
+	// Fields lists the changed fields of updated users, or the reason a user was skipped
+	Fields []string `json:"fields,omitempty" example:"email,department"`
+}
+
+// DirectoryMembershipDiff is a group membership added or removed by a directory sync
+type DirectoryMembershipDiff struct {
+	LoginID string `json:"login_id" example:"jdoe"`
+	Group   string `json:"group" example:"admins"`
+}
+
+// Access token signing algorithms (AUTH_JWT_ALGORITHM)
+const (
+	JWTAlgorithmHS256 = "HS256" // HMAC with JWT_SECRET
+	JWTAlgorithmRS256 = "RS256"
+	JWTAlgorithmES256 = "ES256"
+	JWTAlgorithmEdDSA = "EdDSA" // Ed25519
+)
+
+// Signing key statuses
+const (
+	SigningKeyStatusActive  = "active"  // Signs new access tokens
+	SigningKeyStatusNext    = "next"    // Published, signs once activated
+	SigningKeyStatusRetired = "retired" // Verifies tokens it signed until it expires
+)
+
+// SigningKey represents a stored access token signing key
+type SigningKey struct {
+	ID                  int64
+	KeyID               string
+	Algorithm           string
+	PrivateKeyEncrypted string // PKCS #8, encrypted with AES-256-GCM
+	ActivatesAt         time.Time
+	ExpiresAt           *time.Time // Set when a successor is created
+	CreatedAt           time.Time
+}
+
+// SigningKeyInfo describes an access token signing key
+type SigningKeyInfo struct {
+	KeyID       string     `json:"kid" example:"3f9a1c2b7d4e8f60"`
+	Algorithm   string     `json:"alg" example:"ES256"`
+	Status      string     `json:"status" example:"active" enums:"active,next,retired"`
+	ActivatesAt time.Time  `json:"activates_at" example:"2024-12-01T00:00:00Z"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" example:"2025-01-01T00:00:00Z"`
+	CreatedAt   time.Time  `json:"created_at" example:"2024-11-30T00:00:00Z"`
+}
+
+// SigningKeyListResponse represents the list of access token signing keys
+type SigningKeyListResponse struct {
+	Algorithm string           `json:"algorithm" example:"ES256"`
+	Keys      []SigningKeyInfo `json:"keys"`
+}
+
+// JSONWebKey is a public key of the JWK set (RFC 7517)
+type JSONWebKey struct {
+	Kty string `json:"kty" example:"EC"`
+	Kid string `json:"kid" example:"3f9a1c2b7d4e8f60"`
+	Use string `json:"use" example:"sig"`
+	Alg string `json:"alg" example:"ES256"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty" example:"P-256"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet contains the public keys that verify access tokens
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// ImpersonateRequest represents a request to act as another user. The reason is recorded in the audit log.
+type ImpersonateRequest struct {
+	UserID string `json:"user_id" example:"01912345-6789-7abc-def0-123456789abc"`
+	Reason string `json:"reason" example:"Ticket #4711: user cannot see the Sales board"`
+}
+
+// ImpersonationTokenResponse contains the short-lived access token acting as the user.
+// It cannot be refreshed; a new impersonation is started once it expires.
+type ImpersonationTokenResponse struct {
+	AccessToken string   `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	TokenType   string   `json:"token_type" example:"Bearer"`
+	ExpiresIn   int64    `json:"expires_in" example:"600"`
+	User        UserInfo `json:"user"`
+	Roles       []string `json:"roles"`
+}
+
+// Impersonation is the audit record of an impersonation token
+type Impersonation struct {
+	ID          int64
+	TokenID     string
+	ActorUserID int
+	UserID      int
+	Reason      string
+	ClientIP    string
+	UserAgent   string
+	ExpiresAt   time.Time
+	EndedAt     *time.Time
+	CreatedAt   time.Time
+
+	// Populated when listing
+	ActorPublicID string
+	UserPublicID  string
+	RequestCount  int
+}
+
+// ImpersonatedRequest is the audit record of a request made with an impersonation token
+type ImpersonatedRequest struct {
+	ID         int64
+	Method     string
+	Path       string
+	StatusCode int
+	ClientIP   string
+	CreatedAt  time.Time
+}
+
+// ImpersonationResponse represents a recorded impersonation
+type ImpersonationResponse struct {
+	ID           int64      `json:"id" example:"1"`
+	ActorID      string     `json:"actor_id" example:"01912345-6789-7abc-def0-123456789abc"`
+	UserID       string     `json:"user_id" example:"01912345-6789-7abc-def0-123456789def"`
+	Reason       string     `json:"reason" example:"Ticket #4711: user cannot see the Sales board"`
+	ClientIP     string     `json:"client_ip,omitempty" example:"203.0.113.7"`
+	UserAgent    string     `json:"user_agent,omitempty"`
+	RequestCount int        `json:"request_count" example:"12"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	EndedAt      *time.Time `json:"ended_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// ImpersonationListResponse represents a paginated list of impersonations
+type ImpersonationListResponse struct {
+	Data       []ImpersonationResponse `json:"data"`
+	Page       int                     `json:"page" example:"1"`
+	Limit      int                     `json:"limit" example:"20"`
+	TotalCount int                     `json:"total_count" example:"100"`
+	TotalPages int                     `json:"total_pages" example:"5"`
+}
+
+// ImpersonatedRequestResponse represents a request made while impersonating
+type ImpersonatedRequestResponse struct {
+	ID         int64     `json:"id" example:"1"`
+	Method     string    `json:"method" example:"GET"`
+	Path       string    `json:"path" example:"/tickets/42"`
+	StatusCode int       `json:"status_code" example:"200"`
+	ClientIP   string    `json:"client_ip,omitempty" example:"203.0.113.7"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ImpersonatedRequestListResponse represents a paginated list of requests made while impersonating
+type ImpersonatedRequestListResponse struct {
+	Data       []ImpersonatedRequestResponse `json:"data"`
+	Page       int                           `json:"page" example:"1"`
+	Limit      int                           `json:"limit" example:"20"`
+	TotalCount int                           `json:"total_count" example:"100"`
+	TotalPages int                           `json:"total_pages" example:"5"`
 }
 
 // MeResponse represents the current user information response
 type MeResponse struct {
 	User  UserInfo `json:"user"`
 	Roles []string `json:"roles"`
+
+	// Permissions are the named permissions granted by the roles, sorted
+	Permissions []string `json:"permissions"`
+
+	// Impersonator is set while an administrator acts as the user
+	Impersonator *TokenActor `json:"impersonator,omitempty"`
 }
 
 // Token configuration constants
@@ -138,4 +711,94 @@ const (
 const (
 	RefreshTokenCookieName = "refresh_token"
 	RefreshTokenCookiePath = "/api/auth"
+	OIDCStateCookieName    = "oidc_state"
+	OIDCStateCookiePath    = "/api/auth/oidc"
 )
+
+// ToResponse converts a LoginAttempt with an active lockout to a LockoutResponse
+func (a *LoginAttempt) ToResponse() LockoutResponse {
+	resp := LockoutResponse{
+		Scope:        a.Scope,
+		Subject:      a.Subject,
+		LockoutCount: a.LockoutCount,
+	}
+	if a.LockedUntil != nil {
+		resp.LockedUntil = *a.LockedUntil
+	}
+	return resp
+}
+
+// ToResponse converts a SecurityEvent to a SecurityEventResponse
+func (e *SecurityEvent) ToResponse() SecurityEventResponse {
+	return SecurityEventResponse{
+		ID:             e.ID,
+		EventType:      e.EventType,
+		Scope:          e.Scope,
+		Subject:        e.Subject,
+		LoginID:        e.LoginID,
+		ClientIP:       e.ClientIP,
+		UserAgent:      e.UserAgent,
+		UserID:         e.UserPublicID,
+		ActorID:        e.ActorPublicID,
+		FailedAttempts: e.FailedAttempts,
+		LockedUntil:    e.LockedUntil,
+		CreatedAt:      e.CreatedAt,
+	}
+}
+
+// ToResponse converts a Session to a SessionResponse
+func (s *Session) ToResponse(current bool) SessionResponse {
+	return SessionResponse{
+		ID:          s.ID,
+		ClientIP:    s.ClientIP,
+		UserAgent:   s.UserAgent,
+		MFAVerified: s.MFAVerified,
+		Current:     current,
+		StartedAt:   s.StartedAt,
+		LastUsedAt:  s.LastUsedAt,
+		ExpiresAt:   s.ExpiresAt,
+	}
+}
+
+// ToResponse converts an APIKey to an APIKeyResponse
+func (k *APIKey) ToResponse() APIKeyResponse {
+	return APIKeyResponse{
+		ID:         k.PublicID,
+		Kind:       k.Kind,
+		Name:       k.Name,
+		Prefix:     k.TokenPrefix,
+		Scopes:     k.Scopes,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		LastUsedIP: k.LastUsedIP,
+		CreatedAt:  k.CreatedAt,
+	}
+}
+
+// ToResponse converts an Impersonation to an ImpersonationResponse
+func (i *Impersonation) ToResponse() ImpersonationResponse {
+	return ImpersonationResponse{
+		ID:           i.ID,
+		ActorID:      i.ActorPublicID,
+		UserID:       i.UserPublicID,
+		Reason:       i.Reason,
+		ClientIP:     i.ClientIP,
+		UserAgent:    i.UserAgent,
+		RequestCount: i.RequestCount,
+		ExpiresAt:    i.ExpiresAt,
+		EndedAt:      i.EndedAt,
+		CreatedAt:    i.CreatedAt,
+	}
+}
+
+// ToResponse converts an ImpersonatedRequest to an ImpersonatedRequestResponse
+func (r *ImpersonatedRequest) ToResponse() ImpersonatedRequestResponse {
+	return ImpersonatedRequestResponse{
+		ID:         r.ID,
+		Method:     r.Method,
+		Path:       r.Path,
+		StatusCode: r.StatusCode,
+		ClientIP:   r.ClientIP,
+		CreatedAt:  r.CreatedAt,
+	}
+}
@@ -0,0 +1,327 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"kc-api/internal/password"
+)
+
+const (
+	// passwordChangeTokenUse marks password change challenge tokens so they are never accepted as access tokens
+	passwordChangeTokenUse = "password_change"
+
+	// passwordChangeTokenDuration is how long a user has to replace an expired password after login
+	passwordChangeTokenDuration = 10 * time.Minute
+
+	// passwordChangeReasonExpired is the reason of challenges for passwords older than max_age_days
+	passwordChangeReasonExpired = "expired"
+)
+
+// GetPasswordPolicy returns the password policy in effect
+func (s *service) GetPasswordPolicy(ctx context.Context) (*PasswordPolicyResponse, error) {
+	policy, updatedAt, err := s.repo.GetPasswordPolicy(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get password policy: %w", err)
+	}
+
+	response := &PasswordPolicyResponse{
+		Policy:           password.DefaultPolicy(),
+		BreachListLoaded: s.breachList != nil,
+	}
+	if err == nil {
+		response.Policy = *policy
+		response.UpdatedAt = &updatedAt
+	}
+	return response, nil
+}
+
+// UpdatePasswordPolicy replaces the password policy. It applies to passwords set from now on;
+// existing passwords are only affected by max_age_days.
+func (s *service) UpdatePasswordPolicy(ctx context.Context, actorID string, policy *password.Policy) (*PasswordPolicyResponse, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	actor, err := s.repo.GetUserInternalID(ctx, actorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	updatedAt, err := s.repo.SavePasswordPolicy(ctx, policy, actor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save password policy: %w", err)
+	}
+	log.Printf("User %s updated the password policy", actorID)
+
+	return &PasswordPolicyResponse{
+		Policy:           *policy,
+		BreachListLoaded: s.breachList != nil,
+		UpdatedAt:        &updatedAt,
+	}, nil
+}
+
+// CheckNewPassword checks a password about to be set for a user against the password policy.
+// userID is 0 for users that do not exist yet. Violations are returned as *password.PolicyError
+// wrapped with ErrInvalidPassword.
+func (s *service) CheckNewPassword(ctx context.Context, userID int, loginID, email, newPassword string) error {
+	user := &AuthUser{ID: userID, LoginID: loginID, Email: email}
+	if userID != 0 {
+		stored, err := s.getUserByInternalID(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+		user.PasswordHash = stored.PasswordHash
+	}
+	return s.checkNewPassword(ctx, user, newPassword)
+}
+
+// PasswordChanged records a password set outside the auth service, e.g. by an administrator,
+// in the password history
+func (s *service) PasswordChanged(ctx context.Context, userID int, passwordHash string) error {
+	if err := s.repo.RecordPasswordChange(ctx, userID, passwordHash); err != nil {
+		return fmt.Errorf("failed to record password change: %w", err)
+	}
+	return nil
+}
+
+// HashPassword hashes a password set outside the auth service with the configured parameters
+func (s *service) HashPassword(plain string) (string, error) {
+	return s.hashPassword(plain)
+}
+
+// VerifyPasswordHash verifies a password against a hash created by HashPassword. Hashes costing
+// much more than the configured parameters are rejected without being computed.
+func (s *service) VerifyPasswordHash(plain, encodedHash string) bool {
+	return s.hashParams.Verify(plain, encodedHash)
+}
+
+// ChangeExpiredPassword replaces an expired password using the challenge token returned by
+// login and continues the login, which may still ask for a second factor
+func (s *service) ChangeExpiredPassword(ctx context.Context, req *ChangeExpiredPasswordRequest, clientIP, userAgent string) (*LoginResponse, string, error) {
+	userID, fingerprint, err := s.parsePasswordChangeToken(req.PasswordChangeToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	user, err := s.getUserByPublicID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", ErrInvalidToken
+		}
+		return nil, "", err
+	}
+
+	// The token is bound to the expired password, so it cannot be used once that was replaced
+	if user.IsDeleted || subtle.ConstantTimeCompare([]byte(s.hashToken(user.PasswordHash)), []byte(fingerprint)) != 1 {
+		return nil, "", ErrInvalidToken
+	}
+
+	if err := s.checkNewPassword(ctx, user, req.NewPassword); err != nil {
+		return nil, "", err
+	}
+
+	passwordHash, err := s.hashPassword(req.NewPassword)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	if err := s.repo.UpdatePassword(ctx, user.ID, passwordHash); err != nil {
+		return nil, "", fmt.Errorf("failed to update password: %w", err)
+	}
+	if err := s.repo.RecordPasswordChange(ctx, user.ID, passwordHash); err != nil {
+		return nil, "", fmt.Errorf("failed to record password change: %w", err)
+	}
+	user.PasswordHash = passwordHash
+
+	// Sessions started with the expired password end. Their access tokens are left to expire,
+	// as revoking them would also reject the tokens issued below within the same second.
+	if err := s.repo.RevokeAllUserTokens(ctx, user.ID); err != nil {
+		return nil, "", fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return s.completeLogin(ctx, user, false, clientIP, userAgent)
+}
+
+// upgradePasswordHash replaces the hash of a password that was just verified if it is weaker than
+// hashes created with the configured parameters, e.g. after they were raised or for a hash imported
+// from a legacy system. Failures are logged; the login goes on with the old hash.
+func (s *service) upgradePasswordHash(ctx context.Context, user *AuthUser, plain string) {
+	if !s.hashParams.NeedsRehash(user.PasswordHash) {
+		return
+	}
+
+	passwordHash, err := s.hashPassword(plain)
+	if err != nil {
+		log.Printf("[WARN] Failed to rehash the password of user %d: %v", user.ID, err)
+		return
+	}
+	updated, err := s.repo.RehashPassword(ctx, user.ID, user.PasswordHash, passwordHash)
+	if err != nil {
+		log.Printf("[WARN] Failed to rehash the password of user %d: %v", user.ID, err)
+		return
+	}
+	if updated {
+		user.PasswordHash = passwordHash
+	}
+}
+
+// passwordPolicy returns the configured password policy, or the default policy
+func (s *service) passwordPolicy(ctx context.Context) (*password.Policy, error) {
+	policy, _, err := s.repo.GetPasswordPolicy(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			defaults := password.DefaultPolicy()
+			return &defaults, nil
+		}
+		return nil, fmt.Errorf("failed to get password policy: %w", err)
+	}
+	return policy, nil
+}
+
+// checkNewPassword checks a new password of a user against all rules of the policy, including
+// the password history for existing users (ID set) and the breached password list
+func (s *service) checkNewPassword(ctx context.Context, user *AuthUser, newPassword string) error {
+	policy, err := s.passwordPolicy(ctx)
+	if err != nil {
+		return err
+	}
+
+	violations := policy.Check(newPassword, password.User{LoginID: user.LoginID, Email: user.Email})
+
+	if policy.CheckBreached && s.breachList != nil {
+		breached, err := s.breachList.Contains(newPassword)
+		if err != nil {
+			// An unreadable list does not block password changes
+			log.Printf("[WARN] Failed to check the breached password list: %v", err)
+		} else if breached {
+			violations = append(violations, password.Violation{
+				Rule:    password.RuleBreached,
+				Message: "Password appears in a data breach and must not be used",
+			})
+		}
+	}
+
+	if policy.HistoryCount > 0 && user.ID != 0 {
+		reused, err := s.passwordReused(ctx, user, newPassword, policy.HistoryCount)
+		if err != nil {
+			return err
+		}
+		if reused {
+			violations = append(violations, password.Violation{
+				Rule:    password.RuleHistory,
+				Message: fmt.Sprintf("Password must not be one of the last %d passwords", policy.HistoryCount),
+			})
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidPassword, &password.PolicyError{Violations: violations})
+	}
+	return nil
+}
+
+// passwordReused reports whether a password matches the current password of a user or one of
+// the previous ones, up to count passwords in total
+func (s *service) passwordReused(ctx context.Context, user *AuthUser, newPassword string, count int) (bool, error) {
+	hashes, err := s.repo.GetPasswordHistory(ctx, user.ID, count)
+	if err != nil {
+		return false, fmt.Errorf("failed to get password history: %w", err)
+	}
+
+	// Passwords set before the history was kept are only known from the user
+	if user.PasswordHash != "" && !slices.Contains(hashes, user.PasswordHash) {
+		hashes = append([]string{user.PasswordHash}, hashes...)
+	}
+	if len(hashes) > count {
+		hashes = hashes[:count]
+	}
+
+	for _, hash := range hashes {
+		if s.verifyPassword(newPassword, hash) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// passwordExpired reports whether the password of a user is older than the policy allows.
+// Directory users change their password in the directory.
+func (s *service) passwordExpired(ctx context.Context, user *AuthUser) (bool, error) {
+	if user.DirectoryDN != "" || user.PasswordHash == "" {
+		return false, nil
+	}
+
+	policy, err := s.passwordPolicy(ctx)
+	if err != nil {
+		return false, err
+	}
+	if policy.MaxAgeDays == 0 {
+		return false, nil
+	}
+
+	changedAt, err := s.repo.GetPasswordChangedAt(ctx, user.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get password age: %w", err)
+	}
+	return time.Since(changedAt) > time.Duration(policy.MaxAgeDays)*24*time.Hour, nil
+}
+
+// newPasswordChangeChallenge creates the token a user replaces their expired password with
+func (s *service) newPasswordChangeChallenge(user *AuthUser) (*PasswordChangeChallengeResponse, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"user_id":   user.PublicID,
+		"token_use": passwordChangeTokenUse,
+		"pwd":       s.hashToken(user.PasswordHash),
+		"iat":       now.Unix(),
+		"exp":       now.Add(passwordChangeTokenDuration).Unix(),
+		"iss":       TokenIssuer,
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.jwtSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign password change token: %w", err)
+	}
+
+	return &PasswordChangeChallengeResponse{
+		Required:  true,
+		Reason:    passwordChangeReasonExpired,
+		Token:     token,
+		ExpiresIn: int64(passwordChangeTokenDuration.Seconds()),
+	}, nil
+}
+
+// parsePasswordChangeToken validates a password change token and returns the user's public ID
+// and the fingerprint of the password it was issued for
+func (s *service) parsePasswordChangeToken(tokenString string) (string, string, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return s.jwtSecret, nil
+	})
+	if err != nil || !token.Valid {
+		return "", "", ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", "", ErrInvalidToken
+	}
+
+	use, _ := claims["token_use"].(string)
+	userID, _ := claims["user_id"].(string)
+	fingerprint, _ := claims["pwd"].(string)
+	if use != passwordChangeTokenUse || userID == "" || fingerprint == "" {
+		return "", "", ErrInvalidToken
+	}
+
+	return userID, fingerprint, nil
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// emailRateLimiter limits how many emails are sent to one address within a sliding window.
+// State is kept in memory, so each API instance enforces the limit separately.
+type emailRateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	sent   map[string][]time.Time
+
+	lastPrune time.Time
+}
+
+// newEmailRateLimiter creates a limiter allowing limit emails per window. A limit below 1 disables it.
+func newEmailRateLimiter(limit int, window time.Duration) *emailRateLimiter {
+	return &emailRateLimiter{
+		limit:  limit,
+		window: window,
+		sent:   make(map[string][]time.Time),
+	}
+}
+
+// Allow records an email of the given kind to the address and reports whether it is within the limit
+func (l *emailRateLimiter) Allow(kind, email string, now time.Time) bool {
+	if l.limit < 1 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := kind + ":" + strings.ToLower(strings.TrimSpace(email))
+	cutoff := now.Add(-l.window)
+
+	recent := l.sent[key][:0]
+	for _, at := range l.sent[key] {
+		if at.After(cutoff) {
+			recent = append(recent, at)
+		}
+	}
+
+	if len(recent) >= l.limit {
+		l.sent[key] = recent
+		return false
+	}
+
+	l.sent[key] = append(recent, now)
+	l.prune(cutoff)
+	return true
+}
+
+// prune drops addresses without recent emails so the map does not grow without bound.
+// It runs at most once per window.
+func (l *emailRateLimiter) prune(cutoff time.Time) {
+	if l.lastPrune.After(cutoff) {
+		return
+	}
+	l.lastPrune = cutoff.Add(l.window)
+
+	for key, times := range l.sent {
+		if len(times) == 0 || !times[len(times)-1].After(cutoff) {
+			delete(l.sent, key)
+		}
+	}
+}
@@ -4,201 +4,1609 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"kc-api/internal/password"
 )
 
-// Repository defines the interface for auth data access operations
-type Repository interface {
-	// User operations
-	GetUserByLoginID(ctx context.Context, loginID string) (*AuthUser, error)
-	GetUserByEmail(ctx context.Context, email string) (*AuthUser, error)
-	CreateUser(ctx context.Context, user *AuthUser) error
-	GetUserInternalID(ctx context.Context, publicID string) (int, error)
+// Repository defines the interface for auth data access operations
+type Repository interface {
+	// User operations
+	GetUserByLoginID(ctx context.Context, loginID string) (*AuthUser, error)
+	GetUserByEmail(ctx context.Context, email string) (*AuthUser, error)
+	GetUserByID(ctx context.Context, userID int) (*AuthUser, error)
+	CreateUser(ctx context.Context, user *AuthUser) error
+	GetUserInternalID(ctx context.Context, publicID string) (int, error)
+	GetUserPublicIDs(ctx context.Context, userIDs []int) ([]string, error)
+	UpdatePassword(ctx context.Context, userID int, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, userID int, email string) error
+	CreateServiceAccount(ctx context.Context, user *AuthUser) error
+	ListServiceAccounts(ctx context.Context) ([]AuthUser, error)
+
+	// Directory operations
+	ListDirectoryUsers(ctx context.Context) ([]DirectoryUser, error)
+	GetDirectoryUser(ctx context.Context, loginID string) (*DirectoryUser, error)
+	CreateDirectoryUser(ctx context.Context, user *DirectoryUser) error
+	UpdateDirectoryUser(ctx context.Context, user *DirectoryUser) error
+	SoftDeleteUser(ctx context.Context, userID int) error
+	GetDirectoryDepartmentID(ctx context.Context, key string) (int, error)
+	CreateDirectoryDepartment(ctx context.Context, key string, name json.RawMessage, parentID *int) (int, error)
+
+	// Token operations
+	CreateToken(ctx context.Context, token *UserToken) error
+	GetTokenByHash(ctx context.Context, tokenHash string) (*UserToken, error)
+	RevokeToken(ctx context.Context, tokenID int64) error
+	RevokeAllUserTokens(ctx context.Context, userID int) error
+	UpdateTokenReplacement(ctx context.Context, oldTokenID, newTokenID int64) error
+	ClaimTokenRotation(ctx context.Context, tokenID int64) (bool, error)
+
+	// Session operations
+	ListActiveSessions(ctx context.Context, userID int) ([]Session, error)
+	RevokeSession(ctx context.Context, userID int, sessionID string) (bool, error)
+
+	// Action token operations (password reset, email verification)
+	CreateActionToken(ctx context.Context, token *ActionToken) error
+	GetActionToken(ctx context.Context, tokenHash string, purpose ActionTokenPurpose) (*ActionToken, error)
+	ConsumeActionToken(ctx context.Context, tokenHash string, purpose ActionTokenPurpose) (*ActionToken, error)
+	InvalidateActionTokens(ctx context.Context, userID int, purpose ActionTokenPurpose) error
+	ResetPassword(ctx context.Context, tokenHash string, userID int, passwordHash string) error
+
+	// Login attempt and security event operations
+	GetLoginAttempts(ctx context.Context, loginSubject, ipSubject string) ([]LoginAttempt, error)
+	GetLoginAttempt(ctx context.Context, scope SecurityScope, subject string) (*LoginAttempt, error)
+	RecordLoginFailure(ctx context.Context, scope SecurityScope, subject string, at, windowStart time.Time) (*LoginAttempt, error)
+	LockLogin(ctx context.Context, scope SecurityScope, subject string, until time.Time) error
+	ClearLoginAttempts(ctx context.Context, scope SecurityScope, subject string) (bool, error)
+	ListActiveLockouts(ctx context.Context) ([]LoginAttempt, error)
+	CreateSecurityEvent(ctx context.Context, event *SecurityEvent) error
+	ListSecurityEvents(ctx context.Context, eventType SecurityEventType, limit, offset int) ([]SecurityEvent, int, error)
+
+	// Password policy operations
+	GetPasswordPolicy(ctx context.Context) (*password.Policy, time.Time, error)
+	SavePasswordPolicy(ctx context.Context, policy *password.Policy, updatedBy int) (time.Time, error)
+	GetPasswordHistory(ctx context.Context, userID, limit int) ([]string, error)
+	RecordPasswordChange(ctx context.Context, userID int, passwordHash string) error
+	GetPasswordChangedAt(ctx context.Context, userID int) (time.Time, error)
+	RehashPassword(ctx context.Context, userID int, oldHash, newHash string) (bool, error)
+
+	// Impersonation audit operations
+	CreateImpersonation(ctx context.Context, impersonation *Impersonation) error
+	EndImpersonation(ctx context.Context, tokenID string) error
+	RecordImpersonatedRequest(ctx context.Context, tokenID string, request *ImpersonatedRequest) error
+	ListImpersonations(ctx context.Context, actorPublicID, userPublicID string, limit, offset int) ([]Impersonation, int, error)
+	ListImpersonatedRequests(ctx context.Context, impersonationID int64, limit, offset int) ([]ImpersonatedRequest, int, error)
+
+	// API key operations (personal access tokens and service account keys)
+	CreateAPIKey(ctx context.Context, key *APIKey) error
+	GetAPIKeyByHash(ctx context.Context, tokenHash string) (*APIKey, error)
+	ListAPIKeys(ctx context.Context, userID int, kind APIKeyKind) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID int, kind APIKeyKind, publicID string) (bool, error)
+	TouchAPIKey(ctx context.Context, keyID int64, clientIP string) error
+
+	// Signing key operations
+	ListSigningKeys(ctx context.Context) ([]SigningKey, error)
+	CreateSigningKey(ctx context.Context, key *SigningKey, newestKeyID string, retireAt time.Time) (bool, error)
+	DeleteExpiredSigningKeys(ctx context.Context) error
+
+	// MFA operations
+	GetUserMFA(ctx context.Context, userID int) (*UserMFA, error)
+	SavePendingMFA(ctx context.Context, userID int, secretEncrypted string) error
+	EnableMFA(ctx context.Context, userID int, step int64, codeHashes []string) error
+	UseMFAStep(ctx context.Context, userID int, step int64) (bool, error)
+	DeleteUserMFA(ctx context.Context, userID int) error
+	ListUnusedRecoveryCodes(ctx context.Context, userID int) ([]RecoveryCode, error)
+	UseRecoveryCode(ctx context.Context, codeID int64) (bool, error)
+	UseRecoveryCodeByHash(ctx context.Context, userID int, codeHash string) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error
+
+	// Group operations
+	GetGroupByPublicID(ctx context.Context, publicID string) (*Group, error)
+	AddUserToGroup(ctx context.Context, userID, groupID int, assignedBy *int) error
+	RemoveUserFromGroup(ctx context.Context, userID, groupID int) error
+	GetUserGroups(ctx context.Context, userID int) ([]Group, error)
+
+	// Role operations
+	GetUserRoles(ctx context.Context, userID int) ([]string, error)
+	GetGroupRoles(ctx context.Context, groupID int) ([]string, error)
+	GetAllUserRoles(ctx context.Context, userID int) ([]string, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+// NewRepository creates a new auth repository
+func NewRepository(db *sql.DB) Repository {
+	return &repository{db: db}
+}
+
+// GetUserByLoginID retrieves a user by their login ID
+func (r *repository) GetUserByLoginID(ctx context.Context, loginID string) (*AuthUser, error) {
+	query := `
+		SELECT id, public_id, login_id, email, name, password_hash, email_verified_at, is_service_account, directory_dn, is_deleted
+		FROM organizations.users
+		WHERE login_id = $1 AND is_deleted = false`
+
+	user := &AuthUser{}
+	var passwordHash, directoryDN sql.NullString
+	var emailVerifiedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, loginID).Scan(
+		&user.ID,
+		&user.PublicID,
+		&user.LoginID,
+		&user.Email,
+		&user.Name,
+		&passwordHash,
+		&emailVerifiedAt,
+		&user.IsServiceAccount,
+		&directoryDN,
+		&user.IsDeleted,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if passwordHash.Valid {
+		user.PasswordHash = passwordHash.String
+	}
+	user.DirectoryDN = directoryDN.String
+	if emailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+	return user, nil
+}
+
+// GetUserByEmail retrieves a user by their email
+func (r *repository) GetUserByEmail(ctx context.Context, email string) (*AuthUser, error) {
+	query := `
+		SELECT id, public_id, login_id, email, name, password_hash, email_verified_at, is_service_account, directory_dn, is_deleted
+		FROM organizations.users
+		WHERE email = $1 AND is_deleted = false`
+
+	user := &AuthUser{}
+	var passwordHash, directoryDN sql.NullString
+	var emailVerifiedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, email).Scan(
+		&user.ID,
+		&user.PublicID,
+		&user.LoginID,
+		&user.Email,
+		&user.Name,
+		&passwordHash,
+		&emailVerifiedAt,
+		&user.IsServiceAccount,
+		&directoryDN,
+		&user.IsDeleted,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if passwordHash.Valid {
+		user.PasswordHash = passwordHash.String
+	}
+	user.DirectoryDN = directoryDN.String
+	if emailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+	return user, nil
+}
+
+// CreateUser creates a new user and returns the created user with ID.
+// Users without a password hash (single sign-on) get none.
+func (r *repository) CreateUser(ctx context.Context, user *AuthUser) error {
+	query := `
+		INSERT INTO organizations.users (login_id, email, name, password_hash, is_visible, is_deleted)
+		VALUES ($1, $2, $3, NULLIF($4, ''), true, false)
+		RETURNING id, public_id`
+
+	return r.db.QueryRowContext(ctx, query,
+		user.LoginID,
+		user.Email,
+		user.Name,
+		user.PasswordHash,
+	).Scan(&user.ID, &user.PublicID)
+}
+
+// CreateServiceAccount creates a user without a password that authenticates with API keys only
+func (r *repository) CreateServiceAccount(ctx context.Context, user *AuthUser) error {
+	query := `
+		INSERT INTO organizations.users (login_id, email, name, is_visible, is_service_account, is_deleted)
+		VALUES ($1, $2, $3, false, true, false)
+		RETURNING id, public_id`
+
+	user.IsServiceAccount = true
+	return r.db.QueryRowContext(ctx, query,
+		user.LoginID,
+		user.Email,
+		user.Name,
+	).Scan(&user.ID, &user.PublicID)
+}
+
+// ListServiceAccounts lists all service accounts, ordered by login ID
+func (r *repository) ListServiceAccounts(ctx context.Context) ([]AuthUser, error) {
+	query := `
+		SELECT id, public_id, login_id, email, name
+		FROM organizations.users
+		WHERE is_service_account = true AND is_deleted = false
+		ORDER BY login_id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []AuthUser
+	for rows.Next() {
+		user := AuthUser{IsServiceAccount: true}
+		if err := rows.Scan(&user.ID, &user.PublicID, &user.LoginID, &user.Email, &user.Name); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// directoryUserColumns are the columns scanned by scanDirectoryUser
+const directoryUserColumns = `id, public_id, login_id, email, name, COALESCE(directory_dn, ''), dept_id, is_service_account, email_verified_at IS NOT NULL`
+
+// scanDirectoryUser scans a row selected with directoryUserColumns
+func scanDirectoryUser(scanner interface{ Scan(...interface{}) error }) (*DirectoryUser, error) {
+	user := &DirectoryUser{}
+	err := scanner.Scan(&user.ID, &user.PublicID, &user.LoginID, &user.Email, &user.Name, &user.DirectoryDN, &user.DeptID, &user.IsServiceAccount, &user.EmailVerified)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ListDirectoryUsers lists the users managed by the directory
+func (r *repository) ListDirectoryUsers(ctx context.Context) ([]DirectoryUser, error) {
+	query := `
+		SELECT ` + directoryUserColumns + `
+		FROM organizations.users
+		WHERE directory_dn IS NOT NULL AND is_deleted = false
+		ORDER BY login_id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []DirectoryUser
+	for rows.Next() {
+		user, err := scanDirectoryUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+// GetDirectoryUser retrieves a user by login ID for the directory sync, whether or not
+// the directory manages them yet. Login IDs are compared case-insensitively like in the directory.
+func (r *repository) GetDirectoryUser(ctx context.Context, loginID string) (*DirectoryUser, error) {
+	query := `
+		SELECT ` + directoryUserColumns + `
+		FROM organizations.users
+		WHERE LOWER(login_id) = LOWER($1) AND is_deleted = false
+		ORDER BY (login_id = $1) DESC, id
+		LIMIT 1`
+
+	return scanDirectoryUser(r.db.QueryRowContext(ctx, query, loginID))
+}
+
+// CreateDirectoryUser creates a user managed by the directory. Directory users have no password
+// and their email counts as verified.
+func (r *repository) CreateDirectoryUser(ctx context.Context, user *DirectoryUser) error {
+	query := `
+		INSERT INTO organizations.users (login_id, email, name, directory_dn, dept_id, email_verified_at, is_visible, is_deleted)
+		VALUES ($1, $2, $3, $4, $5, NOW(), true, false)
+		RETURNING id, public_id`
+
+	return r.db.QueryRowContext(ctx, query,
+		user.LoginID,
+		user.Email,
+		user.Name,
+		user.DirectoryDN,
+		user.DeptID,
+	).Scan(&user.ID, &user.PublicID)
+}
+
+// UpdateDirectoryUser updates a user from the directory. A local user linked to the directory becomes
+// managed by it; their password is removed so it cannot be used anymore.
+func (r *repository) UpdateDirectoryUser(ctx context.Context, user *DirectoryUser) error {
+	query := `
+		UPDATE organizations.users
+		SET email = $2, name = $3, directory_dn = $4, dept_id = $5, password_hash = NULL,
+			email_verified_at = CASE WHEN email = $2 THEN COALESCE(email_verified_at, NOW()) ELSE NOW() END,
+			updated_at = NOW()
+		WHERE id = $1 AND is_deleted = false`
+
+	_, err := r.db.ExecContext(ctx, query, user.ID, user.Email, user.Name, user.DirectoryDN, user.DeptID)
+	return err
+}
+
+// SoftDeleteUser marks a user as deleted
+func (r *repository) SoftDeleteUser(ctx context.Context, userID int) error {
+	query := `UPDATE organizations.users SET is_deleted = true, updated_at = NOW() WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
+
+// GetDirectoryDepartmentID retrieves the department created for a directory department key
+func (r *repository) GetDirectoryDepartmentID(ctx context.Context, key string) (int, error) {
+	var id int
+	query := `SELECT id FROM organizations.departments WHERE directory_key = $1 AND is_deleted = false`
+	err := r.db.QueryRowContext(ctx, query, key).Scan(&id)
+	return id, err
+}
+
+// CreateDirectoryDepartment creates a department for a directory department key
+func (r *repository) CreateDirectoryDepartment(ctx context.Context, key string, name json.RawMessage, parentID *int) (int, error) {
+	query := `
+		INSERT INTO organizations.departments (public_id, name, parent_department_id, is_visible, directory_key)
+		VALUES ($1, $2, $3, true, $4)
+		RETURNING id`
+
+	var id int
+	err := r.db.QueryRowContext(ctx, query, uuid.New().String(), name, parentID, key).Scan(&id)
+	return id, err
+}
+
+// GetUserInternalID retrieves the internal ID from a public UUID
+func (r *repository) GetUserInternalID(ctx context.Context, publicID string) (int, error) {
+	var id int
+	query := `SELECT id FROM organizations.users WHERE public_id = $1 AND is_deleted = false`
+	err := r.db.QueryRowContext(ctx, query, publicID).Scan(&id)
+	return id, err
+}
+
+// GetUserPublicIDs retrieves the public UUIDs of users by internal ID, including deleted users
+func (r *repository) GetUserPublicIDs(ctx context.Context, userIDs []int) ([]string, error) {
+	query := `SELECT public_id FROM organizations.users WHERE id = ANY($1)`
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var publicIDs []string
+	for rows.Next() {
+		var publicID string
+		if err := rows.Scan(&publicID); err != nil {
+			return nil, err
+		}
+		publicIDs = append(publicIDs, publicID)
+	}
+	return publicIDs, rows.Err()
+}
+
+// UpdatePassword sets a new password hash for a user
+func (r *repository) UpdatePassword(ctx context.Context, userID int, passwordHash string) error {
+	query := `UPDATE organizations.users SET password_hash = $1, updated_at = NOW() WHERE id = $2 AND is_deleted = false`
+	_, err := r.db.ExecContext(ctx, query, passwordHash, userID)
+	return err
+}
+
+// MarkEmailVerified marks the email of a user as verified, as long as it is still the given address
+func (r *repository) MarkEmailVerified(ctx context.Context, userID int, email string) error {
+	query := `
+		UPDATE organizations.users
+		SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+		WHERE id = $1 AND email = $2 AND is_deleted = false`
+
+	result, err := r.db.ExecContext(ctx, query, userID, email)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CreateToken stores a new refresh token
+func (r *repository) CreateToken(ctx context.Context, token *UserToken) error {
+	query := `
+		INSERT INTO organizations.user_tokens (user_id, token_hash, expires_at, is_revoked, parent_token_id, session_id, client_ip, user_agent, mfa_verified)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		token.UserID,
+		token.TokenHash,
+		token.ExpiresAt,
+		token.IsRevoked,
+		token.ParentTokenID,
+		token.SessionID,
+		token.ClientIP,
+		token.UserAgent,
+		token.MFAVerified,
+	).Scan(&token.ID, &token.CreatedAt, &token.UpdatedAt)
+}
+
+// GetTokenByHash retrieves a token by its hash
+func (r *repository) GetTokenByHash(ctx context.Context, tokenHash string) (*UserToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, expires_at, is_revoked, replaced_by_token_id, rotated_at, parent_token_id, session_id, client_ip, user_agent, mfa_verified, created_at, updated_at
+		FROM organizations.user_tokens
+		WHERE token_hash = $1`
+
+	token := &UserToken{}
+	var sessionID, clientIP, userAgent sql.NullString
+	var replacedBy, parentID sql.NullInt64
+	var rotatedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.IsRevoked,
+		&replacedBy,
+		&rotatedAt,
+		&parentID,
+		&sessionID,
+		&clientIP,
+		&userAgent,
+		&token.MFAVerified,
+		&token.CreatedAt,
+		&token.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	token.SessionID = sessionID.String
+
+	if clientIP.Valid {
+		token.ClientIP = &clientIP.String
+	}
+	if userAgent.Valid {
+		token.UserAgent = &userAgent.String
+	}
+	if replacedBy.Valid {
+		token.ReplacedByTokenID = &replacedBy.Int64
+	}
+	if rotatedAt.Valid {
+		token.RotatedAt = &rotatedAt.Time
+	}
+	if parentID.Valid {
+		token.ParentTokenID = &parentID.Int64
+	}
+
+	return token, nil
+}
+
+// RevokeToken marks a token as revoked
+func (r *repository) RevokeToken(ctx context.Context, tokenID int64) error {
+	query := `UPDATE organizations.user_tokens SET is_revoked = true, updated_at = NOW() WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, tokenID)
+	return err
+}
+
+// RevokeAllUserTokens revokes all tokens for a user
+func (r *repository) RevokeAllUserTokens(ctx context.Context, userID int) error {
+	query := `UPDATE organizations.user_tokens SET is_revoked = true, updated_at = NOW() WHERE user_id = $1 AND is_revoked = false`
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
+
+// UpdateTokenReplacement marks a token as replaced by a new token
+func (r *repository) UpdateTokenReplacement(ctx context.Context, oldTokenID, newTokenID int64) error {
+	query := `UPDATE organizations.user_tokens SET replaced_by_token_id = $1, is_revoked = true, updated_at = NOW() WHERE id = $2`
+	_, err := r.db.ExecContext(ctx, query, newTokenID, oldTokenID)
+	return err
+}
+
+// ClaimTokenRotation revokes an active token for rotation and records when.
+// Returns false if the token was already revoked, e.g. by a concurrent refresh.
+func (r *repository) ClaimTokenRotation(ctx context.Context, tokenID int64) (bool, error) {
+	query := `
+		UPDATE organizations.user_tokens
+		SET is_revoked = true, rotated_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND is_revoked = false`
+
+	result, err := r.db.ExecContext(ctx, query, tokenID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// ListActiveSessions lists the unrevoked, unexpired refresh tokens of a user, one per session,
+// most recently used first
+func (r *repository) ListActiveSessions(ctx context.Context, userID int) ([]Session, error) {
+	query := `
+		SELECT
+			t.session_id, t.token_hash, t.client_ip, t.user_agent, t.mfa_verified,
+			(SELECT MIN(f.created_at) FROM organizations.user_tokens f WHERE f.session_id = t.session_id),
+			t.created_at, t.expires_at
+		FROM organizations.user_tokens t
+		WHERE t.user_id = $1 AND t.is_revoked = false AND t.expires_at > NOW() AND t.session_id IS NOT NULL
+		ORDER BY t.created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		var session Session
+		var clientIP, userAgent sql.NullString
+		if err := rows.Scan(
+			&session.ID,
+			&session.TokenHash,
+			&clientIP,
+			&userAgent,
+			&session.MFAVerified,
+			&session.StartedAt,
+			&session.LastUsedAt,
+			&session.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		if clientIP.Valid {
+			session.ClientIP = &clientIP.String
+		}
+		if userAgent.Valid {
+			session.UserAgent = &userAgent.String
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeSession revokes the active tokens of one session of a user.
+// Returns false if the session has no active tokens.
+func (r *repository) RevokeSession(ctx context.Context, userID int, sessionID string) (bool, error) {
+	query := `
+		UPDATE organizations.user_tokens
+		SET is_revoked = true, updated_at = NOW()
+		WHERE user_id = $1 AND session_id = $2 AND is_revoked = false AND expires_at > NOW()`
+
+	result, err := r.db.ExecContext(ctx, query, userID, sessionID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// CreateActionToken stores a new single-use email token
+func (r *repository) CreateActionToken(ctx context.Context, token *ActionToken) error {
+	query := `
+		INSERT INTO organizations.user_action_tokens (user_id, purpose, token_hash, email, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		token.UserID,
+		token.Purpose,
+		token.TokenHash,
+		token.Email,
+		token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+}
+
+// GetActionToken returns an unused, unexpired token without using it up.
+// Returns sql.ErrNoRows if there is no such token.
+func (r *repository) GetActionToken(ctx context.Context, tokenHash string, purpose ActionTokenPurpose) (*ActionToken, error) {
+	query := `
+		SELECT id, user_id, purpose, token_hash, email, expires_at, used_at, created_at
+		FROM organizations.user_action_tokens
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()`
+
+	return scanActionToken(r.db.QueryRowContext(ctx, query, tokenHash, purpose))
+}
+
+// ConsumeActionToken marks an unused, unexpired token as used and returns it.
+// Returns sql.ErrNoRows if the token does not exist, has expired or was already used.
+func (r *repository) ConsumeActionToken(ctx context.Context, tokenHash string, purpose ActionTokenPurpose) (*ActionToken, error) {
+	query := `
+		UPDATE organizations.user_action_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, user_id, purpose, token_hash, email, expires_at, used_at, created_at`
+
+	return scanActionToken(r.db.QueryRowContext(ctx, query, tokenHash, purpose))
+}
+
+// scanActionToken reads an action token row
+func scanActionToken(row *sql.Row) (*ActionToken, error) {
+	token := &ActionToken{}
+	var usedAt sql.NullTime
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Purpose,
+		&token.TokenHash,
+		&token.Email,
+		&token.ExpiresAt,
+		&usedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+	return token, nil
+}
+
+// InvalidateActionTokens marks all unused tokens of a user for a purpose as used
+func (r *repository) InvalidateActionTokens(ctx context.Context, userID int, purpose ActionTokenPurpose) error {
+	query := `
+		UPDATE organizations.user_action_tokens
+		SET used_at = NOW()
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+
+	_, err := r.db.ExecContext(ctx, query, userID, purpose)
+	return err
+}
+
+// ResetPassword uses up a password reset token and sets the new password of its user in one
+// transaction, recording it in the password history, invalidating the user's other reset tokens
+// and revoking their refresh tokens. Returns sql.ErrNoRows if the token is used up or expired.
+func (r *repository) ResetPassword(ctx context.Context, tokenHash string, userID int, passwordHash string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE organizations.user_action_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND user_id = $2 AND purpose = $3 AND used_at IS NULL AND expires_at > NOW()`
+
+	result, err := tx.ExecContext(ctx, query, tokenHash, userID, ActionTokenPasswordReset)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	query = `
+		UPDATE organizations.user_action_tokens
+		SET used_at = NOW()
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+	if _, err := tx.ExecContext(ctx, query, userID, ActionTokenPasswordReset); err != nil {
+		return err
+	}
+
+	query = `UPDATE organizations.users SET password_hash = $1, updated_at = NOW() WHERE id = $2 AND is_deleted = false`
+	if _, err := tx.ExecContext(ctx, query, passwordHash, userID); err != nil {
+		return err
+	}
+	if err := recordPasswordChange(ctx, tx, userID, passwordHash); err != nil {
+		return err
+	}
+
+	query = `UPDATE organizations.user_tokens SET is_revoked = true, updated_at = NOW() WHERE user_id = $1 AND is_revoked = false`
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// loginAttemptColumns are the columns scanned by scanLoginAttempt
+const loginAttemptColumns = `scope, subject, failed_count, last_failed_at, locked_until, lockout_count`
+
+// scanLoginAttempt scans a row selected with loginAttemptColumns
+func scanLoginAttempt(scanner interface{ Scan(...interface{}) error }) (*LoginAttempt, error) {
+	attempt := &LoginAttempt{}
+	var lastFailedAt, lockedUntil sql.NullTime
+	if err := scanner.Scan(
+		&attempt.Scope,
+		&attempt.Subject,
+		&attempt.FailedCount,
+		&lastFailedAt,
+		&lockedUntil,
+		&attempt.LockoutCount,
+	); err != nil {
+		return nil, err
+	}
+	if lastFailedAt.Valid {
+		attempt.LastFailedAt = &lastFailedAt.Time
+	}
+	if lockedUntil.Valid {
+		attempt.LockedUntil = &lockedUntil.Time
+	}
+	return attempt, nil
+}
+
+// GetLoginAttempts retrieves the tracked failures of a login ID and a client IP (either may be missing)
+func (r *repository) GetLoginAttempts(ctx context.Context, loginSubject, ipSubject string) ([]LoginAttempt, error) {
+	query := `
+		SELECT ` + loginAttemptColumns + `
+		FROM organizations.login_attempts
+		WHERE (scope = $1 AND subject = $2) OR (scope = $3 AND subject = $4)`
+
+	rows, err := r.db.QueryContext(ctx, query, SecurityScopeLoginID, loginSubject, SecurityScopeIP, ipSubject)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []LoginAttempt
+	for rows.Next() {
+		attempt, err := scanLoginAttempt(rows)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, *attempt)
+	}
+	return attempts, rows.Err()
+}
+
+// GetLoginAttempt retrieves the tracked failures of one subject. Returns sql.ErrNoRows if there are none.
+func (r *repository) GetLoginAttempt(ctx context.Context, scope SecurityScope, subject string) (*LoginAttempt, error) {
+	query := `
+		SELECT ` + loginAttemptColumns + `
+		FROM organizations.login_attempts
+		WHERE scope = $1 AND subject = $2`
+
+	return scanLoginAttempt(r.db.QueryRowContext(ctx, query, scope, subject))
+}
+
+// RecordLoginFailure counts a failed login. The count restarts if the previous failure
+// happened before windowStart.
+func (r *repository) RecordLoginFailure(ctx context.Context, scope SecurityScope, subject string, at, windowStart time.Time) (*LoginAttempt, error) {
+	query := `
+		INSERT INTO organizations.login_attempts (scope, subject, failed_count, last_failed_at)
+		VALUES ($1, $2, 1, $3)
+		ON CONFLICT (scope, subject) DO UPDATE SET
+			failed_count = CASE
+				WHEN login_attempts.last_failed_at IS NULL OR login_attempts.last_failed_at < $4 THEN 1
+				ELSE login_attempts.failed_count + 1
+			END,
+			last_failed_at = $3,
+			updated_at = NOW()
+		RETURNING ` + loginAttemptColumns
+
+	return scanLoginAttempt(r.db.QueryRowContext(ctx, query, scope, subject, at, windowStart))
+}
+
+// LockLogin locks a login ID or client IP until the given time and restarts its failure count
+func (r *repository) LockLogin(ctx context.Context, scope SecurityScope, subject string, until time.Time) error {
+	query := `
+		UPDATE organizations.login_attempts
+		SET locked_until = $3, failed_count = 0, lockout_count = lockout_count + 1, updated_at = NOW()
+		WHERE scope = $1 AND subject = $2`
+
+	_, err := r.db.ExecContext(ctx, query, scope, subject, until)
+	return err
+}
+
+// ClearLoginAttempts removes the tracked failures and any lockout of a login ID or client IP.
+// Returns false if nothing was tracked.
+func (r *repository) ClearLoginAttempts(ctx context.Context, scope SecurityScope, subject string) (bool, error) {
+	query := `DELETE FROM organizations.login_attempts WHERE scope = $1 AND subject = $2`
+
+	result, err := r.db.ExecContext(ctx, query, scope, subject)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// ListActiveLockouts lists login IDs and client IPs that are currently locked
+func (r *repository) ListActiveLockouts(ctx context.Context) ([]LoginAttempt, error) {
+	query := `
+		SELECT ` + loginAttemptColumns + `
+		FROM organizations.login_attempts
+		WHERE locked_until > NOW()
+		ORDER BY locked_until DESC`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []LoginAttempt
+	for rows.Next() {
+		attempt, err := scanLoginAttempt(rows)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, *attempt)
+	}
+	return attempts, rows.Err()
+}
+
+// CreateSecurityEvent records a security event
+func (r *repository) CreateSecurityEvent(ctx context.Context, event *SecurityEvent) error {
+	query := `
+		INSERT INTO organizations.security_events
+			(event_type, scope, subject, login_id, client_ip, user_agent, user_id, actor_user_id, failed_attempts, locked_until)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7, $8, $9, $10)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		event.EventType,
+		event.Scope,
+		event.Subject,
+		event.LoginID,
+		event.ClientIP,
+		event.UserAgent,
+		event.UserID,
+		event.ActorUserID,
+		event.FailedAttempts,
+		event.LockedUntil,
+	).Scan(&event.ID, &event.CreatedAt)
+}
+
+// ListSecurityEvents lists security events, newest first, optionally filtered by type
+func (r *repository) ListSecurityEvents(ctx context.Context, eventType SecurityEventType, limit, offset int) ([]SecurityEvent, int, error) {
+	var totalCount int
+	countQuery := `SELECT COUNT(*) FROM organizations.security_events WHERE ($1 = '' OR event_type = $1)`
+	if err := r.db.QueryRowContext(ctx, countQuery, eventType).Scan(&totalCount); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT
+			e.id, e.event_type, e.scope, e.subject, COALESCE(e.login_id, ''), COALESCE(e.client_ip, ''),
+			COALESCE(e.user_agent, ''), e.failed_attempts, e.locked_until, e.created_at,
+			u.public_id, a.public_id
+		FROM organizations.security_events e
+		LEFT JOIN organizations.users u ON e.user_id = u.id
+		LEFT JOIN organizations.users a ON e.actor_user_id = a.id
+		WHERE ($1 = '' OR e.event_type = $1)
+		ORDER BY e.created_at DESC, e.id DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, eventType, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var events []SecurityEvent
+	for rows.Next() {
+		var event SecurityEvent
+		var lockedUntil sql.NullTime
+		var userPublicID, actorPublicID sql.NullString
+		if err := rows.Scan(
+			&event.ID,
+			&event.EventType,
+			&event.Scope,
+			&event.Subject,
+			&event.LoginID,
+			&event.ClientIP,
+			&event.UserAgent,
+			&event.FailedAttempts,
+			&lockedUntil,
+			&event.CreatedAt,
+			&userPublicID,
+			&actorPublicID,
+		); err != nil {
+			return nil, 0, err
+		}
+		if lockedUntil.Valid {
+			event.LockedUntil = &lockedUntil.Time
+		}
+		if userPublicID.Valid {
+			event.UserPublicID = &userPublicID.String
+		}
+		if actorPublicID.Valid {
+			event.ActorPublicID = &actorPublicID.String
+		}
+		events = append(events, event)
+	}
+	return events, totalCount, rows.Err()
+}
+
+// GetPasswordPolicy returns the configured password policy and when it was last changed.
+// Returns sql.ErrNoRows if no policy was configured.
+func (r *repository) GetPasswordPolicy(ctx context.Context) (*password.Policy, time.Time, error) {
+	query := `SELECT policy, updated_at FROM organizations.password_policy WHERE id = 1`
+
+	var raw []byte
+	var updatedAt time.Time
+	if err := r.db.QueryRowContext(ctx, query).Scan(&raw, &updatedAt); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	policy := password.DefaultPolicy()
+	if err := json.Unmarshal(raw, &policy); err != nil {
+		return nil, time.Time{}, err
+	}
+	return &policy, updatedAt, nil
+}
+
+// SavePasswordPolicy replaces the password policy and returns when it was changed
+func (r *repository) SavePasswordPolicy(ctx context.Context, policy *password.Policy, updatedBy int) (time.Time, error) {
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	query := `
+		INSERT INTO organizations.password_policy (id, policy, updated_by, updated_at)
+		VALUES (1, $1, $2, NOW())
+		ON CONFLICT (id) DO UPDATE SET policy = EXCLUDED.policy, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+		RETURNING updated_at`
+
+	var updatedAt time.Time
+	err = r.db.QueryRowContext(ctx, query, raw, updatedBy).Scan(&updatedAt)
+	return updatedAt, err
+}
+
+// GetPasswordHistory returns the hashes of the latest passwords of a user, newest first
+func (r *repository) GetPasswordHistory(ctx context.Context, userID, limit int) ([]string, error) {
+	query := `
+		SELECT password_hash FROM organizations.password_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+// RecordPasswordChange adds a new password to the history of a user, keeping the latest
+// password.MaxHistoryCount entries, and restarts the password's age
+func (r *repository) RecordPasswordChange(ctx context.Context, userID int, passwordHash string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := recordPasswordChange(ctx, tx, userID, passwordHash); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// recordPasswordChange updates the password age and history of a user within a transaction
+func recordPasswordChange(ctx context.Context, tx *sql.Tx, userID int, passwordHash string) error {
+	if _, err := tx.ExecContext(ctx, `UPDATE organizations.users SET password_changed_at = NOW() WHERE id = $1`, userID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO organizations.password_history (user_id, password_hash) VALUES ($1, $2)`
+	if _, err := tx.ExecContext(ctx, query, userID, passwordHash); err != nil {
+		return err
+	}
+
+	query = `
+		DELETE FROM organizations.password_history
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM organizations.password_history
+			WHERE user_id = $1
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2
+		)`
+	_, err := tx.ExecContext(ctx, query, userID, password.MaxHistoryCount)
+	return err
+}
+
+// GetPasswordChangedAt returns when the password of a user was last changed
+func (r *repository) GetPasswordChangedAt(ctx context.Context, userID int) (time.Time, error) {
+	query := `SELECT password_changed_at FROM organizations.users WHERE id = $1`
+
+	var changedAt time.Time
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&changedAt)
+	return changedAt, err
+}
+
+// RehashPassword replaces the hash of an unchanged password with a stronger hash of the same
+// password, in the user and in the password history. The password's age is kept. It reports
+// false if the password was changed in the meantime.
+func (r *repository) RehashPassword(ctx context.Context, userID int, oldHash, newHash string) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE organizations.users SET password_hash = $1 WHERE id = $2 AND password_hash = $3 AND is_deleted = false`
+	result, err := tx.ExecContext(ctx, query, newHash, userID, oldHash)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil || rows == 0 {
+		return false, err
+	}
+
+	query = `UPDATE organizations.password_history SET password_hash = $1 WHERE user_id = $2 AND password_hash = $3`
+	if _, err := tx.ExecContext(ctx, query, newHash, userID, oldHash); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// CreateImpersonation records an issued impersonation token
+func (r *repository) CreateImpersonation(ctx context.Context, impersonation *Impersonation) error {
+	query := `
+		INSERT INTO organizations.impersonations
+			(token_id, actor_user_id, user_id, reason, client_ip, user_agent, expires_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		impersonation.TokenID,
+		impersonation.ActorUserID,
+		impersonation.UserID,
+		impersonation.Reason,
+		impersonation.ClientIP,
+		impersonation.UserAgent,
+		impersonation.ExpiresAt,
+	).Scan(&impersonation.ID, &impersonation.CreatedAt)
+}
+
+// EndImpersonation records that an impersonation token was revoked before it expired
+func (r *repository) EndImpersonation(ctx context.Context, tokenID string) error {
+	query := `UPDATE organizations.impersonations SET ended_at = NOW() WHERE token_id = $1 AND ended_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, tokenID)
+	return err
+}
+
+// RecordImpersonatedRequest records a request made with an impersonation token.
+// Returns sql.ErrNoRows if the token's impersonation was not recorded.
+func (r *repository) RecordImpersonatedRequest(ctx context.Context, tokenID string, request *ImpersonatedRequest) error {
+	query := `
+		INSERT INTO organizations.impersonated_requests (impersonation_id, method, path, status_code, client_ip)
+		SELECT id, $2, $3, $4, NULLIF($5, '')
+		FROM organizations.impersonations
+		WHERE token_id = $1
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query, tokenID, request.Method, request.Path, request.StatusCode, request.ClientIP).
+		Scan(&request.ID, &request.CreatedAt)
+}
+
+// ListImpersonations lists impersonations, newest first, optionally filtered by the public IDs
+// of the impersonating administrator and the impersonated user
+func (r *repository) ListImpersonations(ctx context.Context, actorPublicID, userPublicID string, limit, offset int) ([]Impersonation, int, error) {
+	filter := `
+		FROM organizations.impersonations i
+		JOIN organizations.users a ON i.actor_user_id = a.id
+		JOIN organizations.users u ON i.user_id = u.id
+		WHERE ($1 = '' OR a.public_id::text = $1) AND ($2 = '' OR u.public_id::text = $2)`
+
+	var totalCount int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*)`+filter, actorPublicID, userPublicID).Scan(&totalCount); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT
+			i.id, i.token_id, i.actor_user_id, i.user_id, i.reason, COALESCE(i.client_ip, ''), COALESCE(i.user_agent, ''),
+			i.expires_at, i.ended_at, i.created_at, a.public_id, u.public_id,
+			(SELECT COUNT(*) FROM organizations.impersonated_requests q WHERE q.impersonation_id = i.id)` + filter + `
+		ORDER BY i.created_at DESC, i.id DESC
+		LIMIT $3 OFFSET $4`
+
+	rows, err := r.db.QueryContext(ctx, query, actorPublicID, userPublicID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var impersonations []Impersonation
+	for rows.Next() {
+		var impersonation Impersonation
+		var endedAt sql.NullTime
+		if err := rows.Scan(
+			&impersonation.ID,
+			&impersonation.TokenID,
+			&impersonation.ActorUserID,
+			&impersonation.UserID,
+			&impersonation.Reason,
+			&impersonation.ClientIP,
+			&impersonation.UserAgent,
+			&impersonation.ExpiresAt,
+			&endedAt,
+			&impersonation.CreatedAt,
+			&impersonation.ActorPublicID,
+			&impersonation.UserPublicID,
+			&impersonation.RequestCount,
+		); err != nil {
+			return nil, 0, err
+		}
+		if endedAt.Valid {
+			impersonation.EndedAt = &endedAt.Time
+		}
+		impersonations = append(impersonations, impersonation)
+	}
+	return impersonations, totalCount, rows.Err()
+}
+
+// ListImpersonatedRequests lists the requests of an impersonation, oldest first.
+// Returns sql.ErrNoRows if the impersonation does not exist.
+func (r *repository) ListImpersonatedRequests(ctx context.Context, impersonationID int64, limit, offset int) ([]ImpersonatedRequest, int, error) {
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM organizations.impersonations WHERE id = $1)`, impersonationID).Scan(&exists); err != nil {
+		return nil, 0, err
+	}
+	if !exists {
+		return nil, 0, sql.ErrNoRows
+	}
+
+	var totalCount int
+	countQuery := `SELECT COUNT(*) FROM organizations.impersonated_requests WHERE impersonation_id = $1`
+	if err := r.db.QueryRowContext(ctx, countQuery, impersonationID).Scan(&totalCount); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, method, path, status_code, COALESCE(client_ip, ''), created_at
+		FROM organizations.impersonated_requests
+		WHERE impersonation_id = $1
+		ORDER BY created_at, id
+		LIMIT $2 OFFSET $3`
 
-	// Token operations
-	CreateToken(ctx context.Context, token *UserToken) error
-	GetTokenByHash(ctx context.Context, tokenHash string) (*UserToken, error)
-	RevokeToken(ctx context.Context, tokenID int64) error
-	RevokeAllUserTokens(ctx context.Context, userID int) error
-	UpdateTokenReplacement(ctx context.Context, oldTokenID, newTokenID int64) error
+	rows, err := r.db.QueryContext(ctx, query, impersonationID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
 
-	// Group operations
-	GetGroupByPublicID(ctx context.Context, publicID string) (*Group, error)
-	AddUserToGroup(ctx context.Context, userID, groupID int, assignedBy *int) error
-	GetUserGroups(ctx context.Context, userID int) ([]Group, error)
+	var requests []ImpersonatedRequest
+	for rows.Next() {
+		var request ImpersonatedRequest
+		if err := rows.Scan(&request.ID, &request.Method, &request.Path, &request.StatusCode, &request.ClientIP, &request.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		requests = append(requests, request)
+	}
+	return requests, totalCount, rows.Err()
+}
 
-	// Role operations
-	GetUserRoles(ctx context.Context, userID int) ([]string, error)
-	GetGroupRoles(ctx context.Context, groupID int) ([]string, error)
-	GetAllUserRoles(ctx context.Context, userID int) ([]string, error)
+// apiKeyColumns are the columns scanned by scanAPIKey
+const apiKeyColumns = `k.id, k.public_id, k.user_id, k.kind, k.name, k.token_prefix, k.token_hash, k.scopes,
+			k.expires_at, k.last_used_at, k.last_used_ip, k.created_by, k.revoked_at, k.created_at`
+
+// scanAPIKey scans a row selected with apiKeyColumns
+func scanAPIKey(scanner interface{ Scan(...interface{}) error }) (*APIKey, error) {
+	key := &APIKey{}
+	var scopes pq.StringArray
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	var lastUsedIP sql.NullString
+	var createdBy sql.NullInt64
+	if err := scanner.Scan(
+		&key.ID,
+		&key.PublicID,
+		&key.UserID,
+		&key.Kind,
+		&key.Name,
+		&key.TokenPrefix,
+		&key.TokenHash,
+		&scopes,
+		&expiresAt,
+		&lastUsedAt,
+		&lastUsedIP,
+		&createdBy,
+		&revokedAt,
+		&key.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	key.Scopes = []string(scopes)
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if lastUsedIP.Valid {
+		key.LastUsedIP = &lastUsedIP.String
+	}
+	if createdBy.Valid {
+		id := int(createdBy.Int64)
+		key.CreatedBy = &id
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return key, nil
 }
 
-type repository struct {
-	db *sql.DB
+// CreateAPIKey stores a new API key
+func (r *repository) CreateAPIKey(ctx context.Context, key *APIKey) error {
+	query := `
+		INSERT INTO organizations.api_keys (user_id, kind, name, token_prefix, token_hash, scopes, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, public_id, created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		key.UserID,
+		key.Kind,
+		key.Name,
+		key.TokenPrefix,
+		key.TokenHash,
+		pq.Array(key.Scopes),
+		key.ExpiresAt,
+		key.CreatedBy,
+	).Scan(&key.ID, &key.PublicID, &key.CreatedAt)
 }
 
-// NewRepository creates a new auth repository
-func NewRepository(db *sql.DB) Repository {
-	return &repository{db: db}
+// GetAPIKeyByHash retrieves an API key of an existing user by its hash, including revoked and expired keys
+func (r *repository) GetAPIKeyByHash(ctx context.Context, tokenHash string) (*APIKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM organizations.api_keys k
+		JOIN organizations.users u ON k.user_id = u.id AND u.is_deleted = false
+		WHERE k.token_hash = $1`
+
+	return scanAPIKey(r.db.QueryRowContext(ctx, query, tokenHash))
 }
 
-// GetUserByLoginID retrieves a user by their login ID
-func (r *repository) GetUserByLoginID(ctx context.Context, loginID string) (*AuthUser, error) {
+// ListAPIKeys lists the unrevoked keys of a kind owned by a user, newest first.
+// Expired keys are included so they can be recognized and revoked.
+func (r *repository) ListAPIKeys(ctx context.Context, userID int, kind APIKeyKind) ([]APIKey, error) {
 	query := `
-		SELECT id, public_id, login_id, email, name, password_hash, is_deleted
-		FROM organizations.users
-		WHERE login_id = $1 AND is_deleted = false`
+		SELECT ` + apiKeyColumns + `
+		FROM organizations.api_keys k
+		WHERE k.user_id = $1 AND k.kind = $2 AND k.revoked_at IS NULL
+		ORDER BY k.created_at DESC, k.id DESC`
 
-	user := &AuthUser{}
-	var passwordHash sql.NullString
-	err := r.db.QueryRowContext(ctx, query, loginID).Scan(
-		&user.ID,
-		&user.PublicID,
-		&user.LoginID,
-		&user.Email,
-		&user.Name,
-		&passwordHash,
-		&user.IsDeleted,
-	)
+	rows, err := r.db.QueryContext(ctx, query, userID, kind)
 	if err != nil {
 		return nil, err
 	}
-	if passwordHash.Valid {
-		user.PasswordHash = passwordHash.String
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
 	}
-	return user, nil
+	return keys, rows.Err()
 }
 
-// GetUserByEmail retrieves a user by their email
-func (r *repository) GetUserByEmail(ctx context.Context, email string) (*AuthUser, error) {
+// RevokeAPIKey revokes a key of a kind owned by a user.
+// Returns false if the user has no such unrevoked key.
+func (r *repository) RevokeAPIKey(ctx context.Context, userID int, kind APIKeyKind, publicID string) (bool, error) {
 	query := `
-		SELECT id, public_id, login_id, email, name, password_hash, is_deleted
-		FROM organizations.users
-		WHERE email = $1 AND is_deleted = false`
+		UPDATE organizations.api_keys
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND kind = $2 AND public_id = $3 AND revoked_at IS NULL`
 
-	user := &AuthUser{}
-	var passwordHash sql.NullString
-	err := r.db.QueryRowContext(ctx, query, email).Scan(
-		&user.ID,
-		&user.PublicID,
-		&user.LoginID,
-		&user.Email,
-		&user.Name,
-		&passwordHash,
-		&user.IsDeleted,
-	)
+	result, err := r.db.ExecContext(ctx, query, userID, kind, publicID)
 	if err != nil {
-		return nil, err
+		return false, err
 	}
-	if passwordHash.Valid {
-		user.PasswordHash = passwordHash.String
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
 	}
-	return user, nil
+	return affected > 0, nil
 }
 
-// CreateUser creates a new user and returns the created user with ID
-func (r *repository) CreateUser(ctx context.Context, user *AuthUser) error {
+// TouchAPIKey records the use of an API key
+func (r *repository) TouchAPIKey(ctx context.Context, keyID int64, clientIP string) error {
 	query := `
-		INSERT INTO organizations.users (login_id, email, name, password_hash, is_visible, is_deleted)
-		VALUES ($1, $2, $3, $4, true, false)
-		RETURNING id, public_id`
+		UPDATE organizations.api_keys
+		SET last_used_at = NOW(), last_used_ip = NULLIF($2, '')
+		WHERE id = $1`
 
-	return r.db.QueryRowContext(ctx, query,
-		user.LoginID,
-		user.Email,
-		user.Name,
-		user.PasswordHash,
-	).Scan(&user.ID, &user.PublicID)
+	_, err := r.db.ExecContext(ctx, query, keyID, clientIP)
+	return err
 }
 
-// GetUserInternalID retrieves the internal ID from a public UUID
-func (r *repository) GetUserInternalID(ctx context.Context, publicID string) (int, error) {
-	var id int
-	query := `SELECT id FROM organizations.users WHERE public_id = $1 AND is_deleted = false`
-	err := r.db.QueryRowContext(ctx, query, publicID).Scan(&id)
-	return id, err
+// ListSigningKeys lists the unexpired signing keys in order of activation
+func (r *repository) ListSigningKeys(ctx context.Context) ([]SigningKey, error) {
+	query := `
+		SELECT id, kid, algorithm, private_key_encrypted, activates_at, expires_at, created_at
+		FROM organizations.signing_keys
+		WHERE expires_at IS NULL OR expires_at > NOW()
+		ORDER BY activates_at, id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []SigningKey
+	for rows.Next() {
+		var key SigningKey
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&key.ID, &key.KeyID, &key.Algorithm, &key.PrivateKeyEncrypted, &key.ActivatesAt, &expiresAt, &key.CreatedAt); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			key.ExpiresAt = &expiresAt.Time
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
 }
 
-// CreateToken stores a new refresh token
-func (r *repository) CreateToken(ctx context.Context, token *UserToken) error {
+// CreateSigningKey stores a new signing key and sets the expiry of the keys it replaces to retireAt,
+// unless they expire earlier. A key that was not active yet expires when the new key activates, as
+// it never signed a token.
+// The key is only created if newestKeyID (empty for none) is still the newest key, so concurrent
+// rotations by several instances create one key. Returns false if another key was created first.
+func (r *repository) CreateSigningKey(ctx context.Context, key *SigningKey, newestKeyID string, retireAt time.Time) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('organizations.signing_keys'))`); err != nil {
+		return false, err
+	}
+
+	var newest string
+	err = tx.QueryRowContext(ctx, `SELECT kid FROM organizations.signing_keys ORDER BY id DESC LIMIT 1`).Scan(&newest)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+	if newest != newestKeyID {
+		return false, nil
+	}
+
 	query := `
-		INSERT INTO organizations.user_tokens (user_id, token_hash, expires_at, is_revoked, parent_token_id, client_ip, user_agent)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, created_at, updated_at`
+		UPDATE organizations.signing_keys
+		SET expires_at = CASE WHEN activates_at > $2 THEN $2 ELSE $1 END
+		WHERE expires_at IS NULL OR expires_at > $1`
+	if _, err := tx.ExecContext(ctx, query, retireAt, key.ActivatesAt); err != nil {
+		return false, err
+	}
 
-	return r.db.QueryRowContext(ctx, query,
-		token.UserID,
-		token.TokenHash,
-		token.ExpiresAt,
-		token.IsRevoked,
-		token.ParentTokenID,
-		token.ClientIP,
-		token.UserAgent,
-	).Scan(&token.ID, &token.CreatedAt, &token.UpdatedAt)
+	query = `
+		INSERT INTO organizations.signing_keys (kid, algorithm, private_key_encrypted, activates_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+	err = tx.QueryRowContext(ctx, query, key.KeyID, key.Algorithm, key.PrivateKeyEncrypted, key.ActivatesAt).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
 }
 
-// GetTokenByHash retrieves a token by its hash
-func (r *repository) GetTokenByHash(ctx context.Context, tokenHash string) (*UserToken, error) {
+// DeleteExpiredSigningKeys removes signing keys that can no longer verify any token
+func (r *repository) DeleteExpiredSigningKeys(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM organizations.signing_keys WHERE expires_at <= NOW()`)
+	return err
+}
+
+// GetUserMFA retrieves the TOTP enrollment of a user
+func (r *repository) GetUserMFA(ctx context.Context, userID int) (*UserMFA, error) {
 	query := `
-		SELECT id, user_id, token_hash, expires_at, is_revoked, replaced_by_token_id, parent_token_id, client_ip, user_agent, created_at, updated_at
-		FROM organizations.user_tokens
-		WHERE token_hash = $1`
+		SELECT user_id, secret_encrypted, is_enabled, enabled_at, last_used_step, created_at, updated_at
+		FROM organizations.user_mfa
+		WHERE user_id = $1`
 
-	token := &UserToken{}
-	var clientIP, userAgent sql.NullString
-	var replacedBy, parentID sql.NullInt64
-	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
-		&token.ID,
-		&token.UserID,
-		&token.TokenHash,
-		&token.ExpiresAt,
-		&token.IsRevoked,
-		&replacedBy,
-		&parentID,
-		&clientIP,
-		&userAgent,
-		&token.CreatedAt,
-		&token.UpdatedAt,
+	mfa := &UserMFA{}
+	var enabledAt sql.NullTime
+	var lastUsedStep sql.NullInt64
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&mfa.UserID,
+		&mfa.SecretEncrypted,
+		&mfa.IsEnabled,
+		&enabledAt,
+		&lastUsedStep,
+		&mfa.CreatedAt,
+		&mfa.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	if clientIP.Valid {
-		token.ClientIP = &clientIP.String
+	if enabledAt.Valid {
+		mfa.EnabledAt = &enabledAt.Time
 	}
-	if userAgent.Valid {
-		token.UserAgent = &userAgent.String
+	if lastUsedStep.Valid {
+		mfa.LastUsedStep = &lastUsedStep.Int64
 	}
-	if replacedBy.Valid {
-		token.ReplacedByTokenID = &replacedBy.Int64
+
+	return mfa, nil
+}
+
+// SavePendingMFA stores a new, not yet confirmed TOTP secret. An enabled enrollment is never overwritten.
+func (r *repository) SavePendingMFA(ctx context.Context, userID int, secretEncrypted string) error {
+	query := `
+		INSERT INTO organizations.user_mfa (user_id, secret_encrypted, is_enabled)
+		VALUES ($1, $2, false)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_encrypted = EXCLUDED.secret_encrypted, last_used_step = NULL, updated_at = NOW()
+		WHERE organizations.user_mfa.is_enabled = false`
+
+	_, err := r.db.ExecContext(ctx, query, userID, secretEncrypted)
+	return err
+}
+
+// EnableMFA confirms a pending enrollment and stores its recovery codes in a single transaction
+func (r *repository) EnableMFA(ctx context.Context, userID int, step int64, codeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
 	}
-	if parentID.Valid {
-		token.ParentTokenID = &parentID.Int64
+	defer tx.Rollback()
+
+	query := `
+		UPDATE organizations.user_mfa
+		SET is_enabled = true, enabled_at = NOW(), last_used_step = $2, updated_at = NOW()
+		WHERE user_id = $1 AND is_enabled = false`
+
+	result, err := tx.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return sql.ErrNoRows
 	}
 
-	return token, nil
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-// RevokeToken marks a token as revoked
-func (r *repository) RevokeToken(ctx context.Context, tokenID int64) error {
-	query := `UPDATE organizations.user_tokens SET is_revoked = true, updated_at = NOW() WHERE id = $1`
-	_, err := r.db.ExecContext(ctx, query, tokenID)
-	return err
+// UseMFAStep records a TOTP time step as used. Returns false if the step (or a later one)
+// was already used, so every code is accepted only once.
+func (r *repository) UseMFAStep(ctx context.Context, userID int, step int64) (bool, error) {
+	query := `
+		UPDATE organizations.user_mfa
+		SET last_used_step = $2, updated_at = NOW()
+		WHERE user_id = $1 AND (last_used_step IS NULL OR last_used_step < $2)`
+
+	result, err := r.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
 }
 
-// RevokeAllUserTokens revokes all tokens for a user
-func (r *repository) RevokeAllUserTokens(ctx context.Context, userID int) error {
-	query := `UPDATE organizations.user_tokens SET is_revoked = true, updated_at = NOW() WHERE user_id = $1 AND is_revoked = false`
-	_, err := r.db.ExecContext(ctx, query, userID)
-	return err
+// DeleteUserMFA removes the TOTP enrollment and recovery codes of a user
+func (r *repository) DeleteUserMFA(ctx context.Context, userID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM organizations.user_mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM organizations.user_mfa WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-// UpdateTokenReplacement marks a token as replaced by a new token
-func (r *repository) UpdateTokenReplacement(ctx context.Context, oldTokenID, newTokenID int64) error {
-	query := `UPDATE organizations.user_tokens SET replaced_by_token_id = $1, is_revoked = true, updated_at = NOW() WHERE id = $2`
-	_, err := r.db.ExecContext(ctx, query, newTokenID, oldTokenID)
-	return err
+// ListUnusedRecoveryCodes retrieves the recovery codes of a user that have not been used yet
+func (r *repository) ListUnusedRecoveryCodes(ctx context.Context, userID int) ([]RecoveryCode, error) {
+	query := `
+		SELECT id, user_id, code_hash
+		FROM organizations.user_mfa_recovery_codes
+		WHERE user_id = $1 AND used_at IS NULL
+		ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []RecoveryCode
+	for rows.Next() {
+		var code RecoveryCode
+		if err := rows.Scan(&code.ID, &code.UserID, &code.CodeHash); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, rows.Err()
+}
+
+// UseRecoveryCode marks a recovery code as used. Returns false if it was already used.
+func (r *repository) UseRecoveryCode(ctx context.Context, codeID int64) (bool, error) {
+	query := `UPDATE organizations.user_mfa_recovery_codes SET used_at = NOW() WHERE id = $1 AND used_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, codeID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// UseRecoveryCodeByHash marks the unused recovery code of a user with the given hash as used.
+// Returns false if there is none.
+func (r *repository) UseRecoveryCodeByHash(ctx context.Context, userID int, codeHash string) (bool, error) {
+	query := `
+		UPDATE organizations.user_mfa_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// ReplaceRecoveryCodes replaces all recovery codes of a user
+func (r *repository) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// replaceRecoveryCodes deletes the recovery codes of a user and inserts new ones within a transaction
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM organizations.user_mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	for _, codeHash := range codeHashes {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO organizations.user_mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`,
+			userID, codeHash,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // GetGroupByPublicID retrieves a group by its public ID
@@ -234,6 +1642,13 @@ func (r *repository) AddUserToGroup(ctx context.Context, userID, groupID int, as
 	return err
 }
 
+// RemoveUserFromGroup removes a user from a group
+func (r *repository) RemoveUserFromGroup(ctx context.Context, userID, groupID int) error {
+	query := `DELETE FROM organizations.group_users WHERE group_id = $1 AND user_id = $2`
+	_, err := r.db.ExecContext(ctx, query, groupID, userID)
+	return err
+}
+
 // GetUserGroups retrieves all groups a user belongs to
 func (r *repository) GetUserGroups(ctx context.Context, userID int) ([]Group, error) {
 	query := `
@@ -357,12 +1772,13 @@ func (r *repository) GetAllUserRoles(ctx context.Context, userID int) ([]string,
 // GetUserByID retrieves a user by their internal ID
 func (r *repository) GetUserByID(ctx context.Context, userID int) (*AuthUser, error) {
 	query := `
-		SELECT id, public_id, login_id, email, name, password_hash, is_deleted
+		SELECT id, public_id, login_id, email, name, password_hash, email_verified_at, is_service_account, directory_dn, is_deleted
 		FROM organizations.users
 		WHERE id = $1 AND is_deleted = false`
 
 	user := &AuthUser{}
-	var passwordHash sql.NullString
+	var passwordHash, directoryDN sql.NullString
+	var emailVerifiedAt sql.NullTime
 	err := r.db.QueryRowContext(ctx, query, userID).Scan(
 		&user.ID,
 		&user.PublicID,
@@ -370,6 +1786,9 @@ func (r *repository) GetUserByID(ctx context.Context, userID int) (*AuthUser, er
 		&user.Email,
 		&user.Name,
 		&passwordHash,
+		&emailVerifiedAt,
+		&user.IsServiceAccount,
+		&directoryDN,
 		&user.IsDeleted,
 	)
 	if err != nil {
@@ -378,6 +1797,10 @@ func (r *repository) GetUserByID(ctx context.Context, userID int) (*AuthUser, er
 	if passwordHash.Valid {
 		user.PasswordHash = passwordHash.String
 	}
+	user.DirectoryDN = directoryDN.String
+	if emailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
 	return user, nil
 }
 
@@ -388,6 +1811,8 @@ func (u *AuthUser) ToUserInfo() UserInfo {
 		LoginID: u.LoginID,
 		Name:    u.Name,
 		Email:   u.Email,
+
+		EmailVerified: u.EmailVerifiedAt != nil,
 	}
 }
 
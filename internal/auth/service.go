@@ -4,17 +4,22 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
-	"crypto/subtle"
 	"database/sql"
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/argon2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"kc-api/internal/ldap"
+	"kc-api/internal/mail"
+	"kc-api/internal/oidc"
+	"kc-api/internal/password"
 )
 
 var (
@@ -24,20 +29,41 @@ var (
 	ErrLoginIDExists       = errors.New("login_id already exists")
 	ErrInvalidEmail        = errors.New("invalid email format")
 	ErrInvalidName         = errors.New("name must have at least one locale value")
-	ErrInvalidPassword     = errors.New("password must be at least 8 characters")
+	ErrInvalidPassword     = errors.New("password does not meet the password policy")
 	ErrInvalidToken        = errors.New("invalid or expired token")
 	ErrTokenRevoked        = errors.New("token has been revoked")
 	ErrTokenExpired        = errors.New("token has expired")
+	ErrTokenReused         = errors.New("refresh token reuse detected")
+	ErrTokenRotated        = errors.New("refresh token was just rotated by another request")
 	ErrPublicGroupNotFound = errors.New("public group not found")
-)
-
-// Argon2 parameters (must match users service)
-const (
-	argon2Time    = 1
-	argon2Memory  = 64 * 1024
-	argon2Threads = 4
-	argon2KeyLen  = 32
-	argon2SaltLen = 16
+	ErrMFARequired         = errors.New("multi-factor authentication required")
+	ErrMFAAlreadyEnabled   = errors.New("multi-factor authentication is already enabled")
+	ErrMFANotEnabled       = errors.New("multi-factor authentication is not enabled")
+	ErrMFAEnforced         = errors.New("multi-factor authentication is required for the user's roles")
+	ErrInvalidMFACode      = errors.New("invalid verification code")
+	ErrTooManyRequests     = errors.New("too many requests")
+	ErrMailNotConfigured   = errors.New("email delivery is not configured")
+	ErrAlreadyVerified     = errors.New("email is already verified")
+	ErrLoginLocked         = errors.New("too many failed login attempts")
+	ErrLoginThrottled      = errors.New("login attempted too soon after a failure")
+	ErrLockoutNotFound     = errors.New("no failed logins tracked")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrInvalidKeyRequest   = errors.New("invalid API key request")
+	ErrAPIKeyNotFound      = errors.New("API key not found")
+	ErrNoServiceAccount    = errors.New("service account not found")
+	ErrScopeNotHeld        = errors.New("scope is not a role of the caller")
+	ErrOIDCNotConfigured   = errors.New("single sign-on is not configured")
+	ErrOIDCUnavailable     = errors.New("single sign-on provider is unavailable")
+	ErrOIDCLoginFailed     = errors.New("single sign-on failed")
+	ErrOIDCNoAccount       = errors.New("no account for the single sign-on identity")
+	ErrLDAPNotConfigured   = errors.New("directory is not configured")
+	ErrLDAPUnavailable     = errors.New("directory server is unavailable")
+	ErrLDAPSyncRunning     = errors.New("directory sync is already running")
+	ErrLDAPDirectoryEmpty  = errors.New("directory returned no users")
+	ErrSigningKeysDisabled = errors.New("access tokens are signed with the JWT secret")
+	ErrImpersonationDenied = errors.New("impersonation is not allowed")
+	ErrNotImpersonating    = errors.New("the request is not impersonating a user")
+	ErrNoImpersonation     = errors.New("impersonation not found")
 )
 
 // Service defines the interface for authentication business logic
@@ -49,19 +75,165 @@ type Service interface {
 	LogoutAll(ctx context.Context, userID string) error
 	GetMe(ctx context.Context, userID string) (*MeResponse, error)
 	ValidateAccessToken(tokenString string) (*TokenClaims, error)
+	ValidateAPIKey(ctx context.Context, token, clientIP string) (*TokenClaims, error)
+
+	// Access token revocation
+	IsTokenRevoked(ctx context.Context, claims *TokenClaims) bool
+	RevokeAccessToken(ctx context.Context, tokenString string) error
+	RevokeUserTokens(ctx context.Context, userIDs ...int) error
+
+	// Multi-factor authentication
+	VerifyMFA(ctx context.Context, req *VerifyMFARequest, clientIP, userAgent string) (*LoginResponse, string, error)
+	ValidateMFAEnrollmentToken(tokenString string) (string, error)
+	GetMFAStatus(ctx context.Context, userID string) (*MFAStatusResponse, error)
+	EnrollMFA(ctx context.Context, userID string) (*MFAEnrollmentResponse, error)
+	ConfirmMFA(ctx context.Context, userID string, req *MFACodeRequest) (*RecoveryCodesResponse, error)
+	DisableMFA(ctx context.Context, userID string, req *MFACodeRequest) error
+	RegenerateRecoveryCodes(ctx context.Context, userID string, req *MFACodeRequest) (*RecoveryCodesResponse, error)
+
+	// Password reset and email verification
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, req *ResetPasswordRequest) error
+	ChangeExpiredPassword(ctx context.Context, req *ChangeExpiredPasswordRequest, clientIP, userAgent string) (*LoginResponse, string, error)
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerificationEmail(ctx context.Context, userID string) error
+
+	// Password policy
+	GetPasswordPolicy(ctx context.Context) (*PasswordPolicyResponse, error)
+	UpdatePasswordPolicy(ctx context.Context, actorID string, policy *password.Policy) (*PasswordPolicyResponse, error)
+	CheckNewPassword(ctx context.Context, userID int, loginID, email, newPassword string) error
+	PasswordChanged(ctx context.Context, userID int, passwordHash string) error
+	HashPassword(plain string) (string, error)
+	VerifyPasswordHash(plain, encodedHash string) bool
+	ShareLinkLockedFor(ctx context.Context, linkPublicID string) (time.Duration, error)
+	RecordShareLinkFailure(ctx context.Context, linkPublicID string) error
+
+	// Single sign-on (OpenID Connect)
+	StartOIDCLogin(ctx context.Context) (*OIDCAuthorizationResponse, string, error)
+	CompleteOIDCLogin(ctx context.Context, req *OIDCCallbackRequest, stateToken, clientIP, userAgent string) (*LoginResponse, string, error)
+
+	// LDAP directory
+	SyncDirectory(ctx context.Context, dryRun bool) (*DirectorySyncReport, error)
+
+	// Access token signing keys
+	GetJWKS(ctx context.Context) (*JSONWebKeySet, error)
+	ListSigningKeys(ctx context.Context) (*SigningKeyListResponse, error)
+	RotateSigningKey(ctx context.Context, retire bool) (*SigningKeyInfo, error)
+
+	// Impersonation
+	Impersonate(ctx context.Context, actor *TokenClaims, req *ImpersonateRequest, clientIP, userAgent string) (*ImpersonationTokenResponse, error)
+	EndImpersonation(ctx context.Context, claims *TokenClaims) error
+	RecordImpersonatedRequest(ctx context.Context, claims *TokenClaims, method, path string, statusCode int, clientIP string)
+	ListImpersonations(ctx context.Context, actorID, userID string, page, limit int) (*ImpersonationListResponse, error)
+	ListImpersonatedRequests(ctx context.Context, impersonationID int64, page, limit int) (*ImpersonatedRequestListResponse, error)
+
+	// Session management
+	ListSessions(ctx context.Context, userID, currentRefreshToken string) (*SessionListResponse, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+
+	// Login lockout administration
+	UnlockLogin(ctx context.Context, actorID string, req *UnlockLoginRequest, clientIP, userAgent string) error
+	ListLockouts(ctx context.Context) (*LockoutListResponse, error)
+	ListSecurityEvents(ctx context.Context, eventType string, page, limit int) (*SecurityEventListResponse, error)
+
+	// Personal access tokens
+	ListAPIKeys(ctx context.Context, userID string) (*APIKeyListResponse, error)
+	CreateAPIKey(ctx context.Context, userID string, req *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error)
+	RevokeAPIKey(ctx context.Context, userID, keyID string) error
+
+	// Service accounts and their API keys
+	ListServiceAccounts(ctx context.Context) (*ServiceAccountListResponse, error)
+	CreateServiceAccount(ctx context.Context, req *CreateServiceAccountRequest) (*UserInfo, error)
+	ListServiceAccountKeys(ctx context.Context, accountID string) (*APIKeyListResponse, error)
+	CreateServiceAccountKey(ctx context.Context, actorID, accountID string, req *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error)
+	RevokeServiceAccountKey(ctx context.Context, accountID, keyID string) error
+}
+
+// PermissionResolver expands roles into the named permissions they grant. It is implemented by
+// the RBAC permission manager.
+type PermissionResolver interface {
+	EffectivePermissions(roles []string) []string
 }
 
 type service struct {
-	repo      Repository
-	jwtSecret []byte
+	repo          Repository
+	jwtSecret     []byte
+	config        *Config
+	encryptionKey []byte
+	mailer        mail.Sender // nil disables password reset and verification emails
+	mailLimiter   *emailRateLimiter
+	sso           oidc.Provider    // nil disables single sign-on
+	directory     ldap.Directory   // nil disables directory logins and sync
+	syncer        *directorySyncer // nil without a directory
+	keys          *keyring         // nil when access tokens are signed with the JWT secret (HS256)
+	denylist      *tokenDenylist
+	breachList    password.BreachList // nil disables breached password checks
+	hashParams    password.Params
+	permissions   PermissionResolver // nil leaves the permissions of the current user empty
 }
 
-// NewService creates a new auth service
-func NewService(repo Repository, jwtSecret string) Service {
-	return &service{
-		repo:      repo,
-		jwtSecret: []byte(jwtSecret),
+// NewService creates a new auth service. The mailer, the single sign-on provider and the directory
+// may be nil if email delivery, single sign-on or the LDAP directory are not configured. Without
+// a Redis client, revoked access tokens are only rejected by this instance. The permission resolver
+// reports the named permissions of the current user.
+func NewService(repo Repository, jwtSecret string, cfg *Config, mailer mail.Sender, sso oidc.Provider, directory ldap.Directory, rdb *redis.Client, permissions PermissionResolver) Service {
+	key := sha256.Sum256([]byte(cfg.EncryptionKey))
+	s := &service{
+		repo:          repo,
+		jwtSecret:     []byte(jwtSecret),
+		config:        cfg,
+		encryptionKey: key[:],
+		mailer:        mailer,
+		mailLimiter:   newEmailRateLimiter(cfg.MailRateLimit, cfg.MailRateWindow),
+		sso:           sso,
+		directory:     directory,
+		denylist:      newTokenDenylist(rdb),
+		permissions:   permissions,
+	}
+
+	if cfg.JWTAlgorithm != JWTAlgorithmHS256 {
+		if signingMethod(cfg.JWTAlgorithm) == nil {
+			log.Printf("[WARN] Unsupported AUTH_JWT_ALGORITHM %q, signing access tokens with %s", cfg.JWTAlgorithm, JWTAlgorithmHS256)
+			cfg.JWTAlgorithm = JWTAlgorithmHS256
+		} else {
+			// Replaced keys must outlive the tokens they signed, and keys must sign before they are replaced
+			cfg.JWTKeyOverlap = max(cfg.JWTKeyOverlap, AccessTokenDuration)
+			cfg.JWTKeyRotation = max(cfg.JWTKeyRotation, 2*cfg.JWTKeyOverlap)
+			s.keys = newKeyring(repo, cfg, s.encryptSecret, s.decryptSecret)
+			s.keys.Start()
+		}
+	}
+
+	// Impersonation tokens must not outlive the watermarks revoking them
+	cfg.ImpersonationDuration = min(cfg.ImpersonationDuration, AccessTokenDuration)
+
+	s.hashParams = password.Params{
+		Time:    uint32(max(cfg.Argon2Time, 0)),
+		Memory:  uint32(max(cfg.Argon2Memory, 0)),
+		Threads: uint8(min(max(cfg.Argon2Threads, 0), 255)),
+	}
+	if err := s.hashParams.Validate(); err != nil {
+		log.Printf("[WARN] Ignoring AUTH_ARGON2_* settings, hashing passwords with the default parameters: %v", err)
+		s.hashParams = password.DefaultParams()
 	}
+
+	if cfg.BreachedPasswordsDir != "" {
+		breachList, err := password.NewRangeDirectory(cfg.BreachedPasswordsDir)
+		if err != nil {
+			log.Printf("[WARN] Breached password checks are disabled: %v", err)
+		} else {
+			s.breachList = breachList
+		}
+	}
+
+	if directory != nil {
+		s.syncer = newDirectorySyncer(repo, directory, s.denylist, cfg)
+		if cfg.LDAPSyncInterval > 0 {
+			s.syncer.Start(cfg.LDAPSyncInterval)
+		}
+	}
+
+	return s
 }
 
 // Register creates a new user account and returns tokens
@@ -76,9 +248,15 @@ func (s *service) Register(ctx context.Context, req *RegisterRequest, clientIP,
 		return nil, "", ErrInvalidName
 	}
 
+	// Set login_id to email if not provided
+	loginID := req.Email
+	if req.LoginID != nil && *req.LoginID != "" {
+		loginID = *req.LoginID
+	}
+
 	// Validate password
-	if len(req.Password) < 8 {
-		return nil, "", ErrInvalidPassword
+	if err := s.checkNewPassword(ctx, &AuthUser{LoginID: loginID, Email: req.Email}, req.Password); err != nil {
+		return nil, "", err
 	}
 
 	// Check if email already exists
@@ -89,12 +267,6 @@ func (s *service) Register(ctx context.Context, req *RegisterRequest, clientIP,
 		return nil, "", fmt.Errorf("failed to check email: %w", err)
 	}
 
-	// Set login_id to email if not provided
-	loginID := req.Email
-	if req.LoginID != nil && *req.LoginID != "" {
-		loginID = *req.LoginID
-	}
-
 	// Check if login_id already exists
 	_, err = s.repo.GetUserByLoginID(ctx, loginID)
 	if err == nil {
@@ -120,6 +292,9 @@ func (s *service) Register(ctx context.Context, req *RegisterRequest, clientIP,
 	if err := s.repo.CreateUser(ctx, user); err != nil {
 		return nil, "", fmt.Errorf("failed to create user: %w", err)
 	}
+	if err := s.repo.RecordPasswordChange(ctx, user.ID, passwordHash); err != nil {
+		return nil, "", fmt.Errorf("failed to record password: %w", err)
+	}
 
 	// Add user to 'public' group
 	publicGroup, err := s.repo.GetGroupByPublicID(ctx, PublicGroupID)
@@ -134,35 +309,41 @@ func (s *service) Register(ctx context.Context, req *RegisterRequest, clientIP,
 		return nil, "", fmt.Errorf("failed to add user to public group: %w", err)
 	}
 
+	// Ask the user to verify their address (best effort)
+	if s.mailer != nil {
+		if err := s.sendVerificationEmail(ctx, user); err != nil {
+			log.Printf("[WARN] Failed to send verification email to user %s: %v", user.PublicID, err)
+		}
+	}
+
 	// Get user roles for token
 	roles, err := s.repo.GetAllUserRoles(ctx, user.ID)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to get user roles: %w", err)
 	}
 
-	// Generate tokens
-	accessToken, err := s.generateAccessToken(user, roles)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to generate access token: %w", err)
+	// Users in roles that require MFA must enroll before they get tokens
+	if s.mfaRequired(roles) {
+		challenge, err := s.newMFAChallenge(user, mfaPurposeEnroll)
+		if err != nil {
+			return nil, "", err
+		}
+		return &RegisterResponse{
+			User:    user.ToUserInfo(),
+			MFA:     challenge,
+			Message: "User registered successfully",
+		}, "", nil
 	}
 
-	refreshToken, err := s.generateRefreshToken()
+	// Generate tokens
+	tokens, refreshToken, err := s.issueTokens(ctx, user, roles, nil, false, clientIP, userAgent)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to generate refresh token: %w", err)
-	}
-
-	// Store refresh token
-	if err := s.storeRefreshToken(ctx, user.ID, refreshToken, nil, clientIP, userAgent); err != nil {
-		return nil, "", fmt.Errorf("failed to store refresh token: %w", err)
+		return nil, "", err
 	}
 
 	return &RegisterResponse{
-		User: user.ToUserInfo(),
-		Tokens: TokenResponse{
-			AccessToken: accessToken,
-			TokenType:   "Bearer",
-			ExpiresIn:   int64(AccessTokenDuration.Seconds()),
-		},
+		User:    user.ToUserInfo(),
+		Tokens:  tokens,
 		Message: "User registered successfully",
 	}, refreshToken, nil
 }
@@ -170,56 +351,89 @@ func (s *service) Register(ctx context.Context, req *RegisterRequest, clientIP,
 // Login authenticates a user and returns tokens
 func (s *service) Login(ctx context.Context, req *LoginRequest, clientIP, userAgent string) (*LoginResponse, string, error) {
 	// Get user by login_id (can be email or login_id)
-	user, err := s.repo.GetUserByLoginID(ctx, req.LoginID)
-	if errors.Is(err, sql.ErrNoRows) {
-		// Try by email
-		user, err = s.repo.GetUserByEmail(ctx, req.LoginID)
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, "", ErrInvalidCredentials
-		}
-	}
+	user, err := s.findLoginUser(ctx, req.LoginID)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get user: %w", err)
+		return nil, "", err
+	}
+
+	// Failed logins of unknown login IDs are tracked too, so lockouts don't reveal which exist
+	now := time.Now()
+	subject := loginSubject(req.LoginID, user)
+	if err := s.checkLoginAllowed(ctx, subject, clientIP, now); err != nil {
+		return nil, "", err
 	}
 
 	// Verify password
-	if user.PasswordHash == "" {
+	authenticated, err := s.checkPassword(ctx, req.LoginID, req.Password, user)
+	if err != nil {
+		return nil, "", err
+	}
+	if authenticated == nil {
+		if err := s.recordLoginFailure(ctx, subject, req.LoginID, user, clientIP, userAgent, now); err != nil {
+			return nil, "", err
+		}
 		return nil, "", ErrInvalidCredentials
 	}
+	user = authenticated
 
-	if !s.verifyPassword(req.Password, user.PasswordHash) {
-		return nil, "", ErrInvalidCredentials
+	// Expired passwords are replaced before any tokens or MFA challenges are issued
+	expired, err := s.passwordExpired(ctx, user)
+	if err != nil {
+		return nil, "", err
+	}
+	if expired {
+		challenge, err := s.newPasswordChangeChallenge(user)
+		if err != nil {
+			return nil, "", err
+		}
+		return &LoginResponse{User: user.ToUserInfo(), PasswordChange: challenge}, "", nil
 	}
 
+	return s.completeLogin(ctx, user, false, clientIP, userAgent)
+}
+
+// completeLogin issues tokens to an authenticated user, or an MFA challenge if a second factor is needed.
+// mfaVerified is set if the user already used a second factor, e.g. at the single sign-on provider.
+func (s *service) completeLogin(ctx context.Context, user *AuthUser, mfaVerified bool, clientIP, userAgent string) (*LoginResponse, string, error) {
 	// Get user roles for token
 	roles, err := s.repo.GetAllUserRoles(ctx, user.ID)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to get user roles: %w", err)
 	}
 
-	// Generate tokens
-	accessToken, err := s.generateAccessToken(user, roles)
+	// A second factor is needed if the user enrolled or their roles require it.
+	// The tokens are then issued by VerifyMFA.
+	mfaEnabled, err := s.mfaEnabled(ctx, user.ID)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to generate access token: %w", err)
+		return nil, "", err
+	}
+	if !mfaVerified && (mfaEnabled || s.mfaRequired(roles)) {
+		purpose := mfaPurposeVerify
+		if !mfaEnabled {
+			purpose = mfaPurposeEnroll
+		}
+		challenge, err := s.newMFAChallenge(user, purpose)
+		if err != nil {
+			return nil, "", err
+		}
+		return &LoginResponse{User: user.ToUserInfo(), MFA: challenge}, "", nil
 	}
 
-	refreshToken, err := s.generateRefreshToken()
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to generate refresh token: %w", err)
+	// Failures of the login ID are only forgotten once the login is complete, so wrong
+	// MFA codes after a correct password keep counting towards the lockout
+	if err := s.clearLoginFailures(ctx, user); err != nil {
+		return nil, "", err
 	}
 
-	// Store refresh token
-	if err := s.storeRefreshToken(ctx, user.ID, refreshToken, nil, clientIP, userAgent); err != nil {
-		return nil, "", fmt.Errorf("failed to store refresh token: %w", err)
+	// Generate tokens
+	tokens, refreshToken, err := s.issueTokens(ctx, user, roles, nil, mfaVerified, clientIP, userAgent)
+	if err != nil {
+		return nil, "", err
 	}
 
 	return &LoginResponse{
-		User: user.ToUserInfo(),
-		Tokens: TokenResponse{
-			AccessToken: accessToken,
-			TokenType:   "Bearer",
-			ExpiresIn:   int64(AccessTokenDuration.Seconds()),
-		},
+		User:   user.ToUserInfo(),
+		Tokens: tokens,
 	}, refreshToken, nil
 }
 
@@ -239,9 +453,7 @@ func (s *service) Refresh(ctx context.Context, refreshToken, clientIP, userAgent
 
 	// Check if token is revoked
 	if storedToken.IsRevoked {
-		// Token reuse detected - revoke all user tokens
-		_ = s.repo.RevokeAllUserTokens(ctx, storedToken.UserID)
-		return nil, "", ErrTokenRevoked
+		return nil, "", s.revokedTokenRefresh(ctx, storedToken, clientIP, userAgent)
 	}
 
 	// Check if token is expired
@@ -261,21 +473,26 @@ func (s *service) Refresh(ctx context.Context, refreshToken, clientIP, userAgent
 		return nil, "", fmt.Errorf("failed to get user roles: %w", err)
 	}
 
-	// Generate new tokens
-	newAccessToken, err := s.generateAccessToken(user, roles)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to generate access token: %w", err)
+	// Sessions started without a second factor end once the user's roles require MFA
+	if !storedToken.MFAVerified && s.mfaRequired(roles) {
+		_ = s.repo.RevokeToken(ctx, storedToken.ID)
+		return nil, "", ErrMFARequired
 	}
 
-	newRefreshToken, err := s.generateRefreshToken()
+	// Only one request can rotate a token. The loser of a race between two refreshes is
+	// answered like a refresh within the reuse grace period.
+	claimed, err := s.repo.ClaimTokenRotation(ctx, storedToken.ID)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to generate refresh token: %w", err)
+		return nil, "", fmt.Errorf("failed to rotate token: %w", err)
+	}
+	if !claimed {
+		return nil, "", ErrTokenRotated
 	}
 
-	// Store new refresh token with parent reference
-	parentID := storedToken.ID
-	if err := s.storeRefreshToken(ctx, user.ID, newRefreshToken, &parentID, clientIP, userAgent); err != nil {
-		return nil, "", fmt.Errorf("failed to store refresh token: %w", err)
+	// Generate new tokens, carrying over the session and its MFA state
+	tokens, newRefreshToken, err := s.issueTokens(ctx, user, roles, storedToken, storedToken.MFAVerified, clientIP, userAgent)
+	if err != nil {
+		return nil, "", err
 	}
 
 	// Get new token ID
@@ -290,11 +507,7 @@ func (s *service) Refresh(ctx context.Context, refreshToken, clientIP, userAgent
 		return nil, "", fmt.Errorf("failed to update token replacement: %w", err)
 	}
 
-	return &TokenResponse{
-		AccessToken: newAccessToken,
-		TokenType:   "Bearer",
-		ExpiresIn:   int64(AccessTokenDuration.Seconds()),
-	}, newRefreshToken, nil
+	return tokens, newRefreshToken, nil
 }
 
 // Logout revokes the current refresh token
@@ -312,17 +525,20 @@ func (s *service) Logout(ctx context.Context, refreshToken string) error {
 	return s.repo.RevokeToken(ctx, storedToken.ID)
 }
 
-// LogoutAll revokes all refresh tokens for a user
+// LogoutAll revokes all refresh tokens and the access tokens issued so far for a user
 func (s *service) LogoutAll(ctx context.Context, userPublicID string) error {
 	userID, err := s.repo.GetUserInternalID(ctx, userPublicID)
 	if err != nil {
 		return fmt.Errorf("failed to get user: %w", err)
 	}
 
-	return s.repo.RevokeAllUserTokens(ctx, userID)
+	if err := s.repo.RevokeAllUserTokens(ctx, userID); err != nil {
+		return err
+	}
+	return s.denylist.DenyUserTokens(ctx, userPublicID, time.Now())
 }
 
-// GetMe returns the current user information and roles
+// GetMe returns the current user information, roles and the named permissions granted by them
 func (s *service) GetMe(ctx context.Context, userPublicID string) (*MeResponse, error) {
 	userID, err := s.repo.GetUserInternalID(ctx, userPublicID)
 	if err != nil {
@@ -339,21 +555,21 @@ func (s *service) GetMe(ctx context.Context, userPublicID string) (*MeResponse,
 		return nil, fmt.Errorf("failed to get user roles: %w", err)
 	}
 
+	permissions := []string{}
+	if s.permissions != nil {
+		permissions = append(permissions, s.permissions.EffectivePermissions(roles)...)
+	}
+
 	return &MeResponse{
-		User:  user.ToUserInfo(),
-		Roles: roles,
+		User:        user.ToUserInfo(),
+		Roles:       roles,
+		Permissions: permissions,
 	}, nil
 }
 
 // ValidateAccessToken validates and parses an access token
 func (s *service) ValidateAccessToken(tokenString string) (*TokenClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return s.jwtSecret, nil
-	})
-
+	token, err := jwt.Parse(tokenString, s.accessTokenKey)
 	if err != nil {
 		return nil, ErrInvalidToken
 	}
@@ -367,6 +583,11 @@ func (s *service) ValidateAccessToken(tokenString string) (*TokenClaims, error)
 		return nil, ErrInvalidToken
 	}
 
+	// Challenge and state tokens are signed with the same key but never grant access
+	if use, _ := claims["token_use"].(string); use != "" {
+		return nil, ErrInvalidToken
+	}
+
 	// Extract claims
 	tokenClaims := &TokenClaims{
 		Issuer: TokenIssuer,
@@ -387,9 +608,24 @@ func (s *service) ValidateAccessToken(tokenString string) (*TokenClaims, error)
 	if iat, ok := claims["iat"].(float64); ok {
 		tokenClaims.IssuedAt = int64(iat)
 	}
+	// Microseconds since the epoch are exact in a float64
+	if iatMicros, ok := claims["iat_us"].(float64); ok {
+		tokenClaims.IssuedAtMicros = int64(iatMicros)
+	}
 	if exp, ok := claims["exp"].(float64); ok {
 		tokenClaims.ExpireAt = int64(exp)
 	}
+	if mfa, ok := claims["mfa"].(bool); ok {
+		tokenClaims.MFAVerified = mfa
+	}
+	if act, ok := claims["act"].(map[string]interface{}); ok {
+		actorID, _ := act["user_id"].(string)
+		actorLoginID, _ := act["login_id"].(string)
+		if actorID == "" {
+			return nil, ErrInvalidToken
+		}
+		tokenClaims.Actor = &TokenActor{UserID: actorID, LoginID: actorLoginID}
+	}
 	if rolesInterface, ok := claims["roles"].([]interface{}); ok {
 		for _, r := range rolesInterface {
 			if role, ok := r.(string); ok {
@@ -401,8 +637,32 @@ func (s *service) ValidateAccessToken(tokenString string) (*TokenClaims, error)
 	return tokenClaims, nil
 }
 
+// issueTokens generates an access token and stores a new refresh token.
+// With a parent token the refresh token continues the parent's session, otherwise it starts a new one.
+func (s *service) issueTokens(ctx context.Context, user *AuthUser, roles []string, parent *UserToken, mfaVerified bool, clientIP, userAgent string) (*TokenResponse, string, error) {
+	accessToken, err := s.generateAccessToken(ctx, user, roles, mfaVerified)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	refreshToken, err := s.generateRefreshToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	if err := s.storeRefreshToken(ctx, user.ID, refreshToken, parent, mfaVerified, clientIP, userAgent); err != nil {
+		return nil, "", fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return &TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(AccessTokenDuration.Seconds()),
+	}, refreshToken, nil
+}
+
 // generateAccessToken creates a new JWT access token
-func (s *service) generateAccessToken(user *AuthUser, roles []string) (string, error) {
+func (s *service) generateAccessToken(ctx context.Context, user *AuthUser, roles []string, mfaVerified bool) (string, error) {
 	now := time.Now()
 	tokenID, err := s.generateTokenID()
 	if err != nil {
@@ -416,12 +676,13 @@ func (s *service) generateAccessToken(user *AuthUser, roles []string) (string, e
 		"roles":    roles,
 		"jti":      tokenID,
 		"iat":      now.Unix(),
+		"iat_us":   now.UnixMicro(),
 		"exp":      now.Add(AccessTokenDuration).Unix(),
 		"iss":      TokenIssuer,
+		"mfa":      mfaVerified,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.jwtSecret)
+	return s.signAccessToken(ctx, claims)
 }
 
 // generateRefreshToken creates a secure random refresh token
@@ -449,15 +710,24 @@ func (s *service) hashToken(token string) string {
 }
 
 // storeRefreshToken stores a refresh token in the database
-func (s *service) storeRefreshToken(ctx context.Context, userID int, token string, parentTokenID *int64, clientIP, userAgent string) error {
+func (s *service) storeRefreshToken(ctx context.Context, userID int, token string, parent *UserToken, mfaVerified bool, clientIP, userAgent string) error {
 	tokenHash := s.hashToken(token)
 
 	userToken := &UserToken{
-		UserID:        userID,
-		TokenHash:     tokenHash,
-		ExpiresAt:     time.Now().Add(RefreshTokenDuration),
-		IsRevoked:     false,
-		ParentTokenID: parentTokenID,
+		UserID:      userID,
+		TokenHash:   tokenHash,
+		ExpiresAt:   time.Now().Add(RefreshTokenDuration),
+		IsRevoked:   false,
+		SessionID:   uuid.New().String(),
+		MFAVerified: mfaVerified,
+	}
+
+	if parent != nil {
+		userToken.ParentTokenID = &parent.ID
+		// Tokens issued before sessions were tracked start a new session on their next refresh
+		if parent.SessionID != "" {
+			userToken.SessionID = parent.SessionID
+		}
 	}
 
 	if clientIP != "" {
@@ -470,67 +740,20 @@ func (s *service) storeRefreshToken(ctx context.Context, userID int, token strin
 	return s.repo.CreateToken(ctx, userToken)
 }
 
-// hashPassword hashes a password using Argon2id
-func (s *service) hashPassword(password string) (string, error) {
-	salt := make([]byte, argon2SaltLen)
-	if _, err := rand.Read(salt); err != nil {
-		return "", err
-	}
-
-	hash := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
-
-	b64Salt := base64.RawStdEncoding.EncodeToString(salt)
-	b64Hash := base64.RawStdEncoding.EncodeToString(hash)
-
-	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
-		argon2.Version, argon2Memory, argon2Time, argon2Threads, b64Salt, b64Hash), nil
+// hashPassword hashes a password using Argon2id with the configured parameters
+func (s *service) hashPassword(plain string) (string, error) {
+	return password.Hash(plain, s.hashParams)
 }
 
-// verifyPassword verifies a password against an Argon2id hash
-func (s *service) verifyPassword(password, encodedHash string) bool {
-	// Parse the encoded hash
-	parts := strings.Split(encodedHash, "$")
-	if len(parts) != 6 {
-		return false
-	}
-
-	var version int
-	var memory, time uint32
-	var threads uint8
-	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads)
-	if err != nil {
-		return false
-	}
-	_, err = fmt.Sscanf(parts[2], "v=%d", &version)
-	if err != nil {
-		return false
-	}
-
-	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
-	if err != nil {
-		return false
-	}
-
-	decodedHash, err := base64.RawStdEncoding.DecodeString(parts[5])
-	if err != nil {
-		return false
-	}
-
-	// Compute hash with the same parameters
-	computedHash := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(decodedHash)))
-
-	// Constant-time comparison
-	return subtle.ConstantTimeCompare(decodedHash, computedHash) == 1
+// verifyPassword verifies a password against a hash created with any Argon2id parameters or
+// imported from a legacy system
+func (s *service) verifyPassword(plain, encodedHash string) bool {
+	return password.Verify(plain, encodedHash)
 }
 
 // getUserByInternalID retrieves a user by internal ID using repository interface
 func (s *service) getUserByInternalID(ctx context.Context, userID int) (*AuthUser, error) {
-	// Use the repository's GetUserByID method
-	repo, ok := s.repo.(*repository)
-	if !ok {
-		return nil, errors.New("invalid repository type")
-	}
-	return repo.GetUserByID(ctx, userID)
+	return s.repo.GetUserByID(ctx, userID)
 }
 
 // isValidEmail performs basic email validation
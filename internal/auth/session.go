@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ListSessions lists the active sessions of a user. If currentRefreshToken is set,
+// the session it belongs to is marked as current.
+func (s *service) ListSessions(ctx context.Context, userPublicID, currentRefreshToken string) (*SessionListResponse, error) {
+	userID, err := s.sessionUserID(ctx, userPublicID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.repo.ListActiveSessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	currentHash := ""
+	if currentRefreshToken != "" {
+		currentHash = s.hashToken(currentRefreshToken)
+	}
+
+	data := make([]SessionResponse, 0, len(sessions))
+	for i := range sessions {
+		data = append(data, sessions[i].ToResponse(currentHash != "" && sessions[i].TokenHash == currentHash))
+	}
+	return &SessionListResponse{Data: data}, nil
+}
+
+// RevokeSession ends one session of a user. Its refresh token stops working;
+// access tokens already issued stay valid until they expire.
+func (s *service) RevokeSession(ctx context.Context, userPublicID, sessionID string) error {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return ErrSessionNotFound
+	}
+
+	userID, err := s.sessionUserID(ctx, userPublicID)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := s.repo.RevokeSession(ctx, userID, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// revokedTokenRefresh handles a refresh with a revoked token. A token revoked without being rotated
+// (logout, session revocation) is simply rejected; retrying a refresh after logout ends nothing else.
+// A token rotated within the grace period is most likely a concurrent refresh by the same client,
+// whose cookie now holds the replacement. Otherwise the rotated token was copied, so its whole
+// session is ended and the event recorded.
+func (s *service) revokedTokenRefresh(ctx context.Context, token *UserToken, clientIP, userAgent string) error {
+	if token.RotatedAt == nil && token.ReplacedByTokenID == nil {
+		return ErrTokenRevoked
+	}
+
+	if token.RotatedAt != nil && time.Since(*token.RotatedAt) <= s.config.RefreshReuseGrace {
+		return ErrTokenRotated
+	}
+
+	// Sessions from before session tracking have no ID to revoke by
+	if token.SessionID != "" {
+		if _, err := s.repo.RevokeSession(ctx, token.UserID, token.SessionID); err != nil {
+			return fmt.Errorf("failed to revoke session: %w", err)
+		}
+	} else if err := s.repo.RevokeAllUserTokens(ctx, token.UserID); err != nil {
+		return fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+
+	log.Printf("[WARN] Refresh token reuse detected: session %s of user %d revoked (request from %s)", token.SessionID, token.UserID, clientIP)
+
+	// Access tokens do not name their session, so all of the user's are revoked
+	if err := s.RevokeUserTokens(ctx, token.UserID); err != nil {
+		log.Printf("[WARN] Failed to revoke access tokens of user %d: %v", token.UserID, err)
+	}
+
+	userID := token.UserID
+	s.recordSecurityEvent(ctx, &SecurityEvent{
+		EventType: SecurityEventRefreshTokenReuse,
+		Scope:     SecurityScopeSession,
+		Subject:   token.SessionID,
+		ClientIP:  clientIP,
+		UserAgent: userAgent,
+		UserID:    &userID,
+	})
+	return ErrTokenReused
+}
+
+// sessionUserID resolves the internal ID of the user whose sessions are managed
+func (s *service) sessionUserID(ctx context.Context, userPublicID string) (int, error) {
+	if _, err := uuid.Parse(userPublicID); err != nil {
+		return 0, ErrUserNotFound
+	}
+
+	userID, err := s.repo.GetUserInternalID(ctx, userPublicID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrUserNotFound
+		}
+		return 0, fmt.Errorf("failed to get user: %w", err)
+	}
+	return userID, nil
+}
@@ -0,0 +1,451 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// keyringRefreshInterval is how often keys are reloaded and rotated if due
+	keyringRefreshInterval = time.Minute
+
+	// keyringReloadInterval limits how often keys are reloaded for an unknown key ID,
+	// e.g. one created by another instance
+	keyringReloadInterval = 10 * time.Second
+
+	// keyringTimeout bounds loading and creating keys outside of a request
+	keyringTimeout = 10 * time.Second
+
+	// rsaKeyBits is the size of generated RSA keys
+	rsaKeyBits = 2048
+)
+
+// signingKey is a loaded access token signing key
+type signingKey struct {
+	id          string
+	algorithm   string
+	private     crypto.Signer
+	activatesAt time.Time
+	expiresAt   *time.Time
+	createdAt   time.Time
+}
+
+// keyring holds the signing keys of access tokens. Keys are stored encrypted in the database,
+// so all instances sign with the same key. A successor is published JWTKeyOverlap before it
+// signs, so verifiers caching the JWK set know it in time, and a replaced key verifies the
+// tokens it signed for another JWTKeyOverlap.
+type keyring struct {
+	repo    Repository
+	config  *Config
+	encrypt func(secret []byte) (string, error)
+	decrypt func(ciphertext string) ([]byte, error)
+
+	mu       sync.RWMutex
+	keys     []*signingKey // in order of activation
+	loadedAt time.Time
+}
+
+// newKeyring creates a keyring for the configured algorithm
+func newKeyring(repo Repository, cfg *Config, encrypt func([]byte) (string, error), decrypt func(string) ([]byte, error)) *keyring {
+	return &keyring{repo: repo, config: cfg, encrypt: encrypt, decrypt: decrypt}
+}
+
+// Start loads the keys and rotates them when due in the background
+func (k *keyring) Start() {
+	go func() {
+		ticker := time.NewTicker(keyringRefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), keyringTimeout)
+			if err := k.refresh(ctx); err != nil {
+				log.Printf("[WARN] Failed to refresh signing keys: %v", err)
+			}
+			cancel()
+			<-ticker.C
+		}
+	}()
+}
+
+// refresh reloads the keys, creates a successor if the active key is due for rotation
+// and removes expired keys
+func (k *keyring) refresh(ctx context.Context) error {
+	if err := k.load(ctx); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	newest := k.newest()
+	switch {
+	case newest == nil || newest.algorithm != k.config.JWTAlgorithm:
+		// First start or a changed algorithm: sign with a new key right away
+		if _, err := k.create(ctx, newest, now, now.Add(k.config.JWTKeyOverlap)); err != nil {
+			return err
+		}
+	case !now.Before(newest.activatesAt.Add(k.config.JWTKeyRotation - k.config.JWTKeyOverlap)):
+		activatesAt := now.Add(k.config.JWTKeyOverlap)
+		if _, err := k.create(ctx, newest, activatesAt, activatesAt.Add(k.config.JWTKeyOverlap)); err != nil {
+			return err
+		}
+	}
+
+	return k.repo.DeleteExpiredSigningKeys(ctx)
+}
+
+// Rotate replaces the signing keys with a new key that signs right away. The replaced keys
+// verify the tokens they signed until they expire after JWTKeyOverlap, or right away with
+// retire, e.g. after a key was exposed. Other instances drop them on their next refresh.
+func (k *keyring) Rotate(ctx context.Context, retire bool) (*signingKey, error) {
+	if err := k.load(ctx); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	retireAt := now.Add(k.config.JWTKeyOverlap)
+	if retire {
+		retireAt = now
+	}
+	return k.create(ctx, k.newest(), now, retireAt)
+}
+
+// create generates and stores a key succeeding newest, retiring the keys it replaces at
+// retireAt, and reloads the keys. If another instance created a successor first, that one
+// is used instead.
+func (k *keyring) create(ctx context.Context, newest *signingKey, activatesAt, retireAt time.Time) (*signingKey, error) {
+	private, err := generateSigningKey(k.config.JWTAlgorithm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode signing key: %w", err)
+	}
+	encrypted, err := k.encrypt(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt signing key: %w", err)
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{
+		KeyID:               hex.EncodeToString(b),
+		Algorithm:           k.config.JWTAlgorithm,
+		PrivateKeyEncrypted: encrypted,
+		ActivatesAt:         activatesAt,
+	}
+	var newestID string
+	if newest != nil {
+		newestID = newest.id
+	}
+	created, err := k.repo.CreateSigningKey(ctx, key, newestID, retireAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store signing key: %w", err)
+	}
+	if created {
+		log.Printf("Created %s signing key %s, active from %s", key.Algorithm, key.KeyID, activatesAt.Format(time.RFC3339))
+	}
+
+	if err := k.load(ctx); err != nil {
+		return nil, err
+	}
+	return k.newest(), nil
+}
+
+// load replaces the keys with the unexpired keys of the database
+func (k *keyring) load(ctx context.Context) error {
+	stored, err := k.repo.ListSigningKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list signing keys: %w", err)
+	}
+
+	keys := make([]*signingKey, 0, len(stored))
+	for _, key := range stored {
+		der, err := k.decrypt(key.PrivateKeyEncrypted)
+		if err != nil {
+			log.Printf("[WARN] Failed to decrypt signing key %s: %v", key.KeyID, err)
+			continue
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			log.Printf("[WARN] Failed to parse signing key %s: %v", key.KeyID, err)
+			continue
+		}
+		private, ok := parsed.(crypto.Signer)
+		if !ok || signingMethod(key.Algorithm) == nil {
+			log.Printf("[WARN] Unsupported signing key %s (%s)", key.KeyID, key.Algorithm)
+			continue
+		}
+		keys = append(keys, &signingKey{
+			id:          key.KeyID,
+			algorithm:   key.Algorithm,
+			private:     private,
+			activatesAt: key.ActivatesAt,
+			expiresAt:   key.ExpiresAt,
+			createdAt:   key.CreatedAt,
+		})
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = keys
+	k.loadedAt = time.Now()
+	return nil
+}
+
+// newest returns the most recently created key, which may not be active yet
+func (k *keyring) newest() *signingKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if len(k.keys) == 0 {
+		return nil
+	}
+	return k.keys[len(k.keys)-1]
+}
+
+// active returns the key that signs new tokens, the most recently activated one
+func (k *keyring) active(now time.Time) *signingKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		if !k.keys[i].activatesAt.After(now) {
+			return k.keys[i]
+		}
+	}
+	return nil
+}
+
+// signer returns the key that signs new tokens, loading the keys if none is active yet
+func (k *keyring) signer(ctx context.Context) (*signingKey, error) {
+	if key := k.active(time.Now()); key != nil {
+		return key, nil
+	}
+	if err := k.refresh(ctx); err != nil {
+		return nil, err
+	}
+	if key := k.active(time.Now()); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no active signing key")
+}
+
+// verifier returns the unexpired key with the given ID. Unknown IDs cause a reload,
+// at most every keyringReloadInterval.
+func (k *keyring) verifier(keyID string) (*signingKey, bool) {
+	if key, ok := k.lookup(keyID); ok {
+		return key, true
+	}
+
+	k.mu.RLock()
+	stale := time.Since(k.loadedAt) >= keyringReloadInterval
+	k.mu.RUnlock()
+	if !stale {
+		return nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), keyringTimeout)
+	defer cancel()
+	if err := k.load(ctx); err != nil {
+		log.Printf("[WARN] Failed to reload signing keys: %v", err)
+		return nil, false
+	}
+	return k.lookup(keyID)
+}
+
+func (k *keyring) lookup(keyID string) (*signingKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	now := time.Now()
+	for _, key := range k.keys {
+		if key.id == keyID && (key.expiresAt == nil || key.expiresAt.After(now)) {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// list returns the loaded keys, oldest first
+func (k *keyring) list() []*signingKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return append([]*signingKey(nil), k.keys...)
+}
+
+// status returns whether the key is the active, next or a retired key
+func (k *keyring) status(key *signingKey, now time.Time) string {
+	switch {
+	case key.activatesAt.After(now):
+		return SigningKeyStatusNext
+	case key == k.active(now):
+		return SigningKeyStatusActive
+	default:
+		return SigningKeyStatusRetired
+	}
+}
+
+// jwk returns the public key in JWK format (RFC 7517, RFC 8037 for Ed25519)
+func (key *signingKey) jwk() JSONWebKey {
+	jwk := JSONWebKey{Kid: key.id, Use: "sig", Alg: key.algorithm}
+	switch public := key.private.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (public.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = public.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(public.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(public.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+// generateSigningKey generates a private key for an asymmetric algorithm
+func generateSigningKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case JWTAlgorithmRS256:
+		return rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case JWTAlgorithmES256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case JWTAlgorithmEdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+}
+
+// signingMethod returns the JWT signing method of an asymmetric algorithm, or nil
+func signingMethod(algorithm string) jwt.SigningMethod {
+	switch algorithm {
+	case JWTAlgorithmRS256:
+		return jwt.SigningMethodRS256
+	case JWTAlgorithmES256:
+		return jwt.SigningMethodES256
+	case JWTAlgorithmEdDSA:
+		return jwt.SigningMethodEdDSA
+	default:
+		return nil
+	}
+}
+
+// signAccessToken signs access token claims with the active key, or with the JWT secret
+// if access tokens are signed with HS256
+func (s *service) signAccessToken(ctx context.Context, claims jwt.MapClaims) (string, error) {
+	if s.keys == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.jwtSecret)
+	}
+
+	key, err := s.keys.signer(ctx)
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(signingMethod(key.algorithm), claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.private)
+}
+
+// accessTokenKey returns the key verifying an access token: the JWT secret for HS256 tokens
+// (while they are accepted), otherwise the public key named by the kid header
+func (s *service) accessTokenKey(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if s.keys != nil && !s.config.JWTAcceptHMAC {
+			return nil, fmt.Errorf("HMAC signed access tokens are no longer accepted")
+		}
+		return s.jwtSecret, nil
+	}
+
+	if s.keys == nil {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	keyID, _ := token.Header["kid"].(string)
+	key, ok := s.keys.verifier(keyID)
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", keyID)
+	}
+	if token.Method.Alg() != key.algorithm {
+		return nil, fmt.Errorf("signing method %v does not match key %q", token.Header["alg"], keyID)
+	}
+	return key.private.Public(), nil
+}
+
+// GetJWKS returns the public keys verifying access tokens, including the next key
+func (s *service) GetJWKS(ctx context.Context) (*JSONWebKeySet, error) {
+	set := &JSONWebKeySet{Keys: []JSONWebKey{}}
+	if s.keys == nil {
+		return set, nil
+	}
+
+	if _, err := s.keys.signer(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load signing keys: %w", err)
+	}
+	for _, key := range s.keys.list() {
+		set.Keys = append(set.Keys, key.jwk())
+	}
+	return set, nil
+}
+
+// ListSigningKeys lists the signing keys of access tokens
+func (s *service) ListSigningKeys(ctx context.Context) (*SigningKeyListResponse, error) {
+	resp := &SigningKeyListResponse{Algorithm: s.config.JWTAlgorithm, Keys: []SigningKeyInfo{}}
+	if s.keys == nil {
+		return resp, nil
+	}
+
+	if err := s.keys.load(ctx); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, key := range s.keys.list() {
+		resp.Keys = append(resp.Keys, SigningKeyInfo{
+			KeyID:       key.id,
+			Algorithm:   key.algorithm,
+			Status:      s.keys.status(key, now),
+			ActivatesAt: key.activatesAt,
+			ExpiresAt:   key.expiresAt,
+			CreatedAt:   key.createdAt,
+		})
+	}
+	return resp, nil
+}
+
+// RotateSigningKey replaces the active signing key right away, e.g. after it was exposed.
+// With retire, the replaced keys stop verifying tokens right away as well.
+func (s *service) RotateSigningKey(ctx context.Context, retire bool) (*SigningKeyInfo, error) {
+	if s.keys == nil {
+		return nil, ErrSigningKeysDisabled
+	}
+
+	key, err := s.keys.Rotate(ctx, retire)
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKeyInfo{
+		KeyID:       key.id,
+		Algorithm:   key.algorithm,
+		Status:      SigningKeyStatusActive,
+		ActivatesAt: key.activatesAt,
+		ExpiresAt:   key.expiresAt,
+		CreatedAt:   key.createdAt,
+	}, nil
+}
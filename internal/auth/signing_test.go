@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newTestKeyring returns a keyring holding a retired, an active and a next key, as if loaded
+func newTestKeyring(t *testing.T, cfg *Config) *keyring {
+	t.Helper()
+
+	now := time.Now()
+	retireAt := now.Add(time.Hour)
+	k := newKeyring(nil, cfg, nil, nil)
+	for i, activatesAt := range []time.Time{now.Add(-48 * time.Hour), now.Add(-time.Hour), now.Add(time.Hour)} {
+		private, err := generateSigningKey(cfg.JWTAlgorithm)
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		key := &signingKey{id: []string{"retired", "active", "next"}[i], algorithm: cfg.JWTAlgorithm, private: private, activatesAt: activatesAt}
+		if i < 2 {
+			key.expiresAt = &retireAt
+		}
+		k.keys = append(k.keys, key)
+	}
+	k.loadedAt = now
+	return k
+}
+
+func TestAccessTokenSigning(t *testing.T) {
+	for _, algorithm := range []string{JWTAlgorithmRS256, JWTAlgorithmES256, JWTAlgorithmEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			cfg := &Config{JWTAlgorithm: algorithm, JWTAcceptHMAC: true}
+			s := &service{jwtSecret: []byte("secret"), config: cfg, keys: newTestKeyring(t, cfg)}
+			user := &AuthUser{PublicID: "01912345-6789-7abc-def0-123456789abc", LoginID: "jdoe", Email: "jdoe@example.com"}
+
+			token, err := s.generateAccessToken(context.Background(), user, []string{"user"}, false)
+			if err != nil {
+				t.Fatalf("generateAccessToken failed: %v", err)
+			}
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+			if err != nil {
+				t.Fatalf("failed to parse token: %v", err)
+			}
+			if parsed.Header["kid"] != "active" || parsed.Header["alg"] != algorithm {
+				t.Errorf("unexpected header %v", parsed.Header)
+			}
+
+			claims, err := s.ValidateAccessToken(token)
+			if err != nil {
+				t.Fatalf("ValidateAccessToken failed: %v", err)
+			}
+			if claims.UserID != user.PublicID || claims.LoginID != "jdoe" {
+				t.Errorf("unexpected claims %+v", claims)
+			}
+
+			// A token signed with a retired key verifies until the key expires
+			retired := s.keys.keys[0]
+			old := jwt.NewWithClaims(signingMethod(algorithm), jwt.MapClaims{"user_id": user.PublicID, "exp": time.Now().Add(time.Minute).Unix()})
+			old.Header["kid"] = retired.id
+			signed, _ := old.SignedString(retired.private)
+			if _, err := s.ValidateAccessToken(signed); err != nil {
+				t.Errorf("token of the retired key rejected: %v", err)
+			}
+			expired := time.Now().Add(-time.Second)
+			retired.expiresAt = &expired
+			if _, err := s.ValidateAccessToken(signed); err == nil {
+				t.Error("token of an expired key accepted")
+			}
+
+			// Unknown key IDs and keys of another algorithm are rejected
+			other := newTestKeyring(t, cfg)
+			forged := jwt.NewWithClaims(signingMethod(algorithm), jwt.MapClaims{"user_id": user.PublicID, "exp": time.Now().Add(time.Minute).Unix()})
+			forged.Header["kid"] = "unknown"
+			signed, _ = forged.SignedString(other.keys[1].private)
+			if _, err := s.ValidateAccessToken(signed); err == nil {
+				t.Error("token with an unknown key ID accepted")
+			}
+			forged.Header["kid"] = "active"
+			signed, _ = forged.SignedString(other.keys[1].private)
+			if _, err := s.ValidateAccessToken(signed); err == nil {
+				t.Error("token signed with another key accepted")
+			}
+		})
+	}
+}
+
+func TestAccessTokenSigning_HMACMigration(t *testing.T) {
+	user := &AuthUser{PublicID: "01912345-6789-7abc-def0-123456789abc", LoginID: "jdoe"}
+	legacy := &service{jwtSecret: []byte("secret"), config: &Config{JWTAlgorithm: JWTAlgorithmHS256}}
+	token, err := legacy.generateAccessToken(context.Background(), user, nil, false)
+	if err != nil {
+		t.Fatalf("generateAccessToken failed: %v", err)
+	}
+	if _, err := legacy.ValidateAccessToken(token); err != nil {
+		t.Fatalf("HS256 token rejected: %v", err)
+	}
+
+	cfg := &Config{JWTAlgorithm: JWTAlgorithmES256, JWTAcceptHMAC: true}
+	s := &service{jwtSecret: []byte("secret"), config: cfg, keys: newTestKeyring(t, cfg)}
+	if _, err := s.ValidateAccessToken(token); err != nil {
+		t.Errorf("HS256 token rejected during migration: %v", err)
+	}
+
+	cfg.JWTAcceptHMAC = false
+	if _, err := s.ValidateAccessToken(token); err == nil {
+		t.Error("HS256 token accepted after migration")
+	}
+
+	// The JWT secret does not verify asymmetric tokens
+	if _, err := legacy.ValidateAccessToken(mustSign(t, s, user)); err == nil {
+		t.Error("ES256 token accepted without signing keys")
+	}
+}
+
+func TestSigningKeyJWK(t *testing.T) {
+	cfg := &Config{JWTAlgorithm: JWTAlgorithmRS256}
+	key := newTestKeyring(t, cfg).keys[1]
+	jwk := key.jwk()
+	public := key.private.Public().(*rsa.PublicKey)
+	if jwk.Kty != "RSA" || jwk.Kid != "active" || jwk.Alg != JWTAlgorithmRS256 || jwk.Use != "sig" {
+		t.Errorf("unexpected JWK %+v", jwk)
+	}
+	if n := decodeTestBigInt(t, jwk.N); n.Cmp(public.N) != 0 {
+		t.Error("modulus does not match")
+	}
+	if e := decodeTestBigInt(t, jwk.E); e.Int64() != int64(public.E) {
+		t.Errorf("exponent = %d", e.Int64())
+	}
+
+	cfg.JWTAlgorithm = JWTAlgorithmES256
+	key = newTestKeyring(t, cfg).keys[1]
+	jwk = key.jwk()
+	ec := key.private.Public().(*ecdsa.PublicKey)
+	if jwk.Kty != "EC" || jwk.Crv != "P-256" || len(jwk.X) != 43 || len(jwk.Y) != 43 {
+		t.Errorf("unexpected JWK %+v", jwk)
+	}
+	if x := decodeTestBigInt(t, jwk.X); x.Cmp(ec.X) != 0 {
+		t.Error("x does not match")
+	}
+
+	cfg.JWTAlgorithm = JWTAlgorithmEdDSA
+	jwk = newTestKeyring(t, cfg).keys[1].jwk()
+	if jwk.Kty != "OKP" || jwk.Crv != "Ed25519" || len(jwk.X) != 43 {
+		t.Errorf("unexpected JWK %+v", jwk)
+	}
+}
+
+func TestKeyringStatus(t *testing.T) {
+	k := newTestKeyring(t, &Config{JWTAlgorithm: JWTAlgorithmEdDSA})
+	now := time.Now()
+	want := []string{SigningKeyStatusRetired, SigningKeyStatusActive, SigningKeyStatusNext}
+	for i, key := range k.keys {
+		if got := k.status(key, now); got != want[i] {
+			t.Errorf("status of %s = %s, want %s", key.id, got, want[i])
+		}
+	}
+}
+
+func mustSign(t *testing.T, s *service, user *AuthUser) string {
+	t.Helper()
+	token, err := s.generateAccessToken(context.Background(), user, nil, false)
+	if err != nil {
+		t.Fatalf("generateAccessToken failed: %v", err)
+	}
+	return token
+}
+
+func decodeTestBigInt(t *testing.T, s string) *big.Int {
+	t.Helper()
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatalf("invalid base64url %q: %v", s, err)
+	}
+	return new(big.Int).SetBytes(b)
+}
@@ -0,0 +1,261 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"kc-api/internal/oidc"
+)
+
+// oidcStateUse marks single sign-on state tokens so they are never accepted as other tokens
+const oidcStateUse = "oidc_state"
+
+// oidcState is a parsed single sign-on state token
+type oidcState struct {
+	State        string
+	Nonce        string
+	CodeVerifier string
+}
+
+// StartOIDCLogin starts a single sign-on login. It returns the provider URL to send the user to
+// and a state token that binds the login to the browser (stored in a cookie by the handler).
+func (s *service) StartOIDCLogin(ctx context.Context) (*OIDCAuthorizationResponse, string, error) {
+	if s.sso == nil {
+		return nil, "", ErrOIDCNotConfigured
+	}
+
+	state, err := s.generateTokenID()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate state: %w", err)
+	}
+	nonce, err := s.generateTokenID()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+
+	authURL, err := s.sso.AuthorizationURL(ctx, state, nonce, oidc.CodeChallengeS256(verifier))
+	if err != nil {
+		log.Printf("[WARN] Single sign-on provider unavailable: %v", err)
+		return nil, "", fmt.Errorf("%w: %v", ErrOIDCUnavailable, err)
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"token_use":     oidcStateUse,
+		"state":         state,
+		"nonce":         nonce,
+		"code_verifier": verifier,
+		"iat":           now.Unix(),
+		"exp":           now.Add(s.config.OIDCStateTTL).Unix(),
+		"iss":           TokenIssuer,
+	}
+	stateToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.jwtSecret)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to sign state token: %w", err)
+	}
+
+	return &OIDCAuthorizationResponse{AuthorizationURL: authURL}, stateToken, nil
+}
+
+// CompleteOIDCLogin finishes a single sign-on login with the authorization code the provider
+// redirected back with. The user is found by email (or provisioned), their mapped groups are
+// synchronized and the login completes like a password login.
+func (s *service) CompleteOIDCLogin(ctx context.Context, req *OIDCCallbackRequest, stateToken, clientIP, userAgent string) (*LoginResponse, string, error) {
+	if s.sso == nil {
+		return nil, "", ErrOIDCNotConfigured
+	}
+
+	pending, err := s.parseOIDCState(stateToken)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: missing or expired login state", ErrOIDCLoginFailed)
+	}
+	if req.Code == "" || subtle.ConstantTimeCompare([]byte(req.State), []byte(pending.State)) != 1 {
+		return nil, "", fmt.Errorf("%w: state mismatch", ErrOIDCLoginFailed)
+	}
+
+	identity, err := s.sso.Exchange(ctx, req.Code, pending.CodeVerifier, pending.Nonce)
+	if err != nil {
+		log.Printf("[WARN] Single sign-on failed (request from %s): %v", clientIP, err)
+		if errors.Is(err, oidc.ErrDiscovery) {
+			return nil, "", fmt.Errorf("%w: %v", ErrOIDCUnavailable, err)
+		}
+		return nil, "", fmt.Errorf("%w: %v", ErrOIDCLoginFailed, err)
+	}
+
+	user, err := s.oidcUser(ctx, identity)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := s.syncOIDCGroups(ctx, user, identity.Groups); err != nil {
+		return nil, "", err
+	}
+
+	return s.completeLogin(ctx, user, s.providerMFA(identity), clientIP, userAgent)
+}
+
+// providerMFA reports whether a second factor used at the provider counts as one here.
+// Unless OIDCTrustProviderMFA is set, users with MFA still have to enter a local code.
+func (s *service) providerMFA(identity *oidc.Identity) bool {
+	return s.config.OIDCTrustProviderMFA && slices.Contains(identity.AuthMethods, "mfa")
+}
+
+// oidcUser finds the user of a single sign-on identity by email, or provisions one if enabled
+func (s *service) oidcUser(ctx context.Context, identity *oidc.Identity) (*AuthUser, error) {
+	email := strings.TrimSpace(identity.Email)
+	if !isValidEmail(email) {
+		return nil, fmt.Errorf("%w: the provider did not return an email address", ErrOIDCLoginFailed)
+	}
+	if s.config.OIDCRequireVerifiedEmail && !identity.EmailVerified {
+		return nil, fmt.Errorf("%w: the provider has not verified %s", ErrOIDCLoginFailed, email)
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err == nil {
+		if user.IsServiceAccount {
+			return nil, ErrOIDCNoAccount
+		}
+		if user.EmailVerifiedAt == nil && identity.EmailVerified {
+			if err := s.repo.MarkEmailVerified(ctx, user.ID, user.Email); err != nil {
+				return nil, fmt.Errorf("failed to mark email verified: %w", err)
+			}
+		}
+		return user, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if !s.config.OIDCAutoProvision {
+		return nil, ErrOIDCNoAccount
+	}
+	return s.provisionOIDCUser(ctx, identity, email)
+}
+
+// provisionOIDCUser creates a user without a password for a single sign-on identity
+func (s *service) provisionOIDCUser(ctx context.Context, identity *oidc.Identity, email string) (*AuthUser, error) {
+	// The email is the login ID; it may already be taken as the login ID of another user
+	_, err := s.repo.GetUserByLoginID(ctx, email)
+	if err == nil {
+		return nil, fmt.Errorf("%w: login ID %s is taken", ErrOIDCNoAccount, email)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check login_id: %w", err)
+	}
+
+	displayName := strings.TrimSpace(identity.Name)
+	if displayName == "" {
+		displayName, _, _ = strings.Cut(email, "@")
+	}
+	name, err := json.Marshal(map[string]string{s.config.OIDCNameLocale: displayName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode name: %w", err)
+	}
+
+	user := &AuthUser{
+		LoginID: email,
+		Email:   email,
+		Name:    name,
+	}
+	if err := s.repo.CreateUser(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	// Add user to 'public' group
+	publicGroup, err := s.repo.GetGroupByPublicID(ctx, PublicGroupID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPublicGroupNotFound
+		}
+		return nil, fmt.Errorf("failed to get public group: %w", err)
+	}
+	if err := s.repo.AddUserToGroup(ctx, user.ID, publicGroup.ID, nil); err != nil {
+		return nil, fmt.Errorf("failed to add user to public group: %w", err)
+	}
+
+	if identity.EmailVerified {
+		if err := s.repo.MarkEmailVerified(ctx, user.ID, email); err != nil {
+			return nil, fmt.Errorf("failed to mark email verified: %w", err)
+		}
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	log.Printf("Provisioned user %s for single sign-on subject %q", user.PublicID, identity.Subject)
+	return user, nil
+}
+
+// syncOIDCGroups makes the user a member of exactly those mapped groups whose provider groups
+// they belong to. Groups without a mapping are not touched.
+func (s *service) syncOIDCGroups(ctx context.Context, user *AuthUser, providerGroups []string) error {
+	if len(s.config.OIDCGroupMapping) == 0 {
+		return nil
+	}
+
+	// Several provider groups may map to the same group
+	member := make(map[string]bool, len(s.config.OIDCGroupMapping))
+	for providerGroup, groupID := range s.config.OIDCGroupMapping {
+		member[groupID] = member[groupID] || slices.Contains(providerGroups, providerGroup)
+	}
+
+	for groupID, isMember := range member {
+		group, err := s.repo.GetGroupByPublicID(ctx, groupID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				log.Printf("[WARN] Group %q of AUTH_OIDC_GROUP_MAPPING does not exist", groupID)
+				continue
+			}
+			return fmt.Errorf("failed to get group: %w", err)
+		}
+
+		if isMember {
+			err = s.repo.AddUserToGroup(ctx, user.ID, group.ID, nil)
+		} else {
+			err = s.repo.RemoveUserFromGroup(ctx, user.ID, group.ID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update membership of group %s: %w", groupID, err)
+		}
+	}
+	return nil
+}
+
+// parseOIDCState validates a single sign-on state token
+func (s *service) parseOIDCState(tokenString string) (*oidcState, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return s.jwtSecret, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	use, _ := claims["token_use"].(string)
+	state, _ := claims["state"].(string)
+	nonce, _ := claims["nonce"].(string)
+	verifier, _ := claims["code_verifier"].(string)
+	if use != oidcStateUse || state == "" || nonce == "" || verifier == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return &oidcState{State: state, Nonce: nonce, CodeVerifier: verifier}, nil
+}
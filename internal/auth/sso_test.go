@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"testing"
+
+	"kc-api/internal/oidc"
+)
+
+func TestService_ProviderMFA(t *testing.T) {
+	tests := []struct {
+		name        string
+		trust       bool
+		authMethods []string
+		want        bool
+	}{
+		{name: "local MFA by default", authMethods: []string{"pwd", "mfa"}, want: false},
+		{name: "trusted provider MFA", trust: true, authMethods: []string{"pwd", "mfa"}, want: true},
+		{name: "trusted provider without MFA", trust: true, authMethods: []string{"pwd"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &service{config: &Config{OIDCTrustProviderMFA: tt.trust}}
+			if got := s.providerMFA(&oidc.Identity{AuthMethods: tt.authMethods}); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B test vectors (SHA-1), truncated to 6 digits
+func TestTOTPCode(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+	}
+
+	for _, tt := range tests {
+		if got := totpCode(secret, totpStep(time.Unix(tt.unix, 0))); got != tt.want {
+			t.Errorf("totpCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	now := time.Unix(1234567890, 0)
+	current := totpStep(now)
+
+	tests := []struct {
+		name     string
+		code     string
+		wantOK   bool
+		wantStep int64
+	}{
+		{name: "current step", code: totpCode(secret, current), wantOK: true, wantStep: current},
+		{name: "previous step", code: totpCode(secret, current-1), wantOK: true, wantStep: current - 1},
+		{name: "next step", code: totpCode(secret, current+1), wantOK: true, wantStep: current + 1},
+		{name: "with spaces", code: "005 924", wantOK: true, wantStep: current},
+		{name: "too old", code: totpCode(secret, current-2), wantOK: false},
+		{name: "wrong length", code: "12345", wantOK: false},
+		{name: "empty", code: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := validateTOTP(secret, tt.code, now)
+			if ok != tt.wantOK {
+				t.Fatalf("validateTOTP() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && step != tt.wantStep {
+				t.Errorf("validateTOTP() step = %d, want %d", step, tt.wantStep)
+			}
+		})
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := totpProvisioningURI("Knowledge Center", "john.doe", []byte("12345678901234567890"))
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("invalid URI %q: %v", uri, err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" {
+		t.Errorf("unexpected URI prefix: %s", uri)
+	}
+	if parsed.Path != "/Knowledge Center:john.doe" {
+		t.Errorf("label = %q, want %q", parsed.Path, "/Knowledge Center:john.doe")
+	}
+
+	query := parsed.Query()
+	if got := query.Get("secret"); got != "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" {
+		t.Errorf("secret = %q", got)
+	}
+	if got := query.Get("issuer"); got != "Knowledge Center" {
+		t.Errorf("issuer = %q", got)
+	}
+}
+
+func TestRecoveryCode(t *testing.T) {
+	code, err := generateRecoveryCode()
+	if err != nil {
+		t.Fatalf("generateRecoveryCode() error = %v", err)
+	}
+	if len(code) != 11 || code[5] != '-' {
+		t.Errorf("unexpected recovery code format %q", code)
+	}
+
+	if got := normalizeRecoveryCode(" " + strings.ToUpper(code) + " "); got != strings.ReplaceAll(code, "-", "") {
+		t.Errorf("normalizeRecoveryCode() = %q", got)
+	}
+}
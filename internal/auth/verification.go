@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"kc-api/internal/mail"
+)
+
+// mailSendTimeout bounds the background delivery of a single email
+const mailSendTimeout = time.Minute
+
+// ForgotPassword emails a password reset link if an account with the address exists.
+// The result does not reveal whether it does.
+func (s *service) ForgotPassword(ctx context.Context, email string) error {
+	if s.mailer == nil {
+		return ErrMailNotConfigured
+	}
+	if !isValidEmail(email) {
+		return ErrInvalidEmail
+	}
+
+	// Limit by address whether or not it exists, so the limit does not reveal accounts either
+	if !s.mailLimiter.Allow(string(ActionTokenPasswordReset), email, time.Now()) {
+		return ErrTooManyRequests
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	// Service accounts have no password to reset; directory users change theirs in the directory
+	if user.IsServiceAccount || user.DirectoryDN != "" {
+		return nil
+	}
+
+	link, err := s.createActionLink(ctx, user, ActionTokenPasswordReset, s.config.PasswordResetTTL, s.config.PasswordResetURL)
+	if err != nil {
+		return err
+	}
+
+	s.sendMail(user.Email, "Reset your password", fmt.Sprintf(
+		"Hello %s,\n\n"+
+			"We received a request to reset the password of your Knowledge Center account.\n"+
+			"Open the following link to choose a new password:\n\n%s\n\n"+
+			"The link is valid for %s and can only be used once.\n"+
+			"If you did not request a password reset, you can ignore this email.\n",
+		user.LoginID, link, s.config.PasswordResetTTL))
+
+	return nil
+}
+
+// ResetPassword sets a new password using a reset token and ends all sessions of the user.
+// The link stays usable if the password does not meet the policy.
+func (s *service) ResetPassword(ctx context.Context, req *ResetPasswordRequest) error {
+	tokenHash := s.hashToken(req.Token)
+	token, err := s.repo.GetActionToken(ctx, tokenHash, ActionTokenPasswordReset)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		return fmt.Errorf("failed to get reset token: %w", err)
+	}
+
+	user, err := s.getUserByInternalID(ctx, token.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// The link proves control of the address it was sent to, which must still be the user's
+	if user.Email != token.Email {
+		return ErrInvalidToken
+	}
+
+	if err := s.checkNewPassword(ctx, user, req.Password); err != nil {
+		return err
+	}
+
+	passwordHash, err := s.hashPassword(req.Password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	// The token is used up together with the password change, other reset links and all
+	// existing sessions stop working
+	if err := s.repo.ResetPassword(ctx, tokenHash, user.ID, passwordHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+	s.revokeUserTokens(ctx, user.PublicID)
+
+	// Receiving the link also verifies the address
+	if err := s.repo.MarkEmailVerified(ctx, user.ID, token.Email); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("[WARN] Failed to mark email of user %s as verified: %v", user.PublicID, err)
+	}
+
+	return nil
+}
+
+// VerifyEmail marks the address a verification token was sent to as verified
+func (s *service) VerifyEmail(ctx context.Context, tokenString string) error {
+	token, err := s.repo.ConsumeActionToken(ctx, s.hashToken(tokenString), ActionTokenEmailVerification)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		return fmt.Errorf("failed to consume verification token: %w", err)
+	}
+
+	// Fails if the user changed their address since the link was sent
+	if err := s.repo.MarkEmailVerified(ctx, token.UserID, token.Email); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	return s.repo.InvalidateActionTokens(ctx, token.UserID, ActionTokenEmailVerification)
+}
+
+// ResendVerificationEmail sends a new verification link to the current user
+func (s *service) ResendVerificationEmail(ctx context.Context, userPublicID string) error {
+	if s.mailer == nil {
+		return ErrMailNotConfigured
+	}
+
+	user, err := s.getUserByPublicID(ctx, userPublicID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return ErrAlreadyVerified
+	}
+
+	if !s.mailLimiter.Allow(string(ActionTokenEmailVerification), user.Email, time.Now()) {
+		return ErrTooManyRequests
+	}
+
+	return s.sendVerificationEmail(ctx, user)
+}
+
+// sendVerificationEmail emails an email verification link to the user
+func (s *service) sendVerificationEmail(ctx context.Context, user *AuthUser) error {
+	link, err := s.createActionLink(ctx, user, ActionTokenEmailVerification, s.config.EmailVerificationTTL, s.config.EmailVerificationURL)
+	if err != nil {
+		return err
+	}
+
+	s.sendMail(user.Email, "Verify your email address", fmt.Sprintf(
+		"Hello %s,\n\n"+
+			"Please confirm that %s is the email address of your Knowledge Center account by opening the following link:\n\n%s\n\n"+
+			"The link is valid for %s.\n",
+		user.LoginID, user.Email, link, s.config.EmailVerificationTTL))
+
+	return nil
+}
+
+// createActionLink stores a new single-use token for the user and returns the link containing it
+func (s *service) createActionLink(ctx context.Context, user *AuthUser, purpose ActionTokenPurpose, ttl time.Duration, baseURL string) (string, error) {
+	token, err := s.generateRefreshToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	link, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid link URL: %w", err)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	if err := s.repo.CreateActionToken(ctx, &ActionToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: s.hashToken(token),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		return "", fmt.Errorf("failed to store token: %w", err)
+	}
+
+	return link.String(), nil
+}
+
+// sendMail delivers an email in the background so response times don't depend on the mail server
+// (or reveal whether a message was sent at all)
+func (s *service) sendMail(to, subject, body string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+		defer cancel()
+
+		msg := &mail.Message{To: []string{to}, Subject: subject, Body: body}
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			log.Printf("[WARN] Failed to send %q email: %v", subject, err)
+		}
+	}()
+}
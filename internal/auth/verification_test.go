@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"kc-api/internal/mail"
+	"kc-api/internal/password"
+)
+
+// fakeResetRepository keeps one user and its action tokens in memory. Repository methods
+// the password reset doesn't use are left to the nil embedded interface.
+type fakeResetRepository struct {
+	Repository
+	user   AuthUser
+	tokens []ActionToken
+	resets int
+}
+
+func (r *fakeResetRepository) GetUserByEmail(ctx context.Context, email string) (*AuthUser, error) {
+	if email != r.user.Email {
+		return nil, sql.ErrNoRows
+	}
+	user := r.user
+	return &user, nil
+}
+
+func (r *fakeResetRepository) GetUserByID(ctx context.Context, userID int) (*AuthUser, error) {
+	if userID != r.user.ID {
+		return nil, sql.ErrNoRows
+	}
+	user := r.user
+	return &user, nil
+}
+
+func (r *fakeResetRepository) CreateActionToken(ctx context.Context, token *ActionToken) error {
+	token.ID = int64(len(r.tokens) + 1)
+	r.tokens = append(r.tokens, *token)
+	return nil
+}
+
+func (r *fakeResetRepository) GetActionToken(ctx context.Context, tokenHash string, purpose ActionTokenPurpose) (*ActionToken, error) {
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash && token.Purpose == purpose && token.UsedAt == nil && token.ExpiresAt.After(time.Now()) {
+			return &token, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *fakeResetRepository) GetPasswordPolicy(ctx context.Context) (*password.Policy, time.Time, error) {
+	return nil, time.Time{}, sql.ErrNoRows
+}
+
+func (r *fakeResetRepository) GetPasswordHistory(ctx context.Context, userID, limit int) ([]string, error) {
+	return nil, nil
+}
+
+func (r *fakeResetRepository) ResetPassword(ctx context.Context, tokenHash string, userID int, passwordHash string) error {
+	if _, err := r.GetActionToken(ctx, tokenHash, ActionTokenPasswordReset); err != nil {
+		return err
+	}
+	now := time.Now()
+	for i := range r.tokens {
+		if r.tokens[i].UserID == userID && r.tokens[i].Purpose == ActionTokenPasswordReset && r.tokens[i].UsedAt == nil {
+			r.tokens[i].UsedAt = &now
+		}
+	}
+	r.user.PasswordHash = passwordHash
+	r.resets++
+	return nil
+}
+
+func (r *fakeResetRepository) MarkEmailVerified(ctx context.Context, userID int, email string) error {
+	now := time.Now()
+	r.user.EmailVerifiedAt = &now
+	return nil
+}
+
+var resetLinkPattern = regexp.MustCompile(`https?://\S+`)
+
+// waitForResetToken waits for the reset email sent in the background and returns the token of its link
+func waitForResetToken(t *testing.T, sender *mail.MemorySender, count int) string {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(sender.Messages()) < count {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d reset emails, got %d", count, len(sender.Messages()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	msg := sender.Messages()[count-1]
+	link, err := url.Parse(resetLinkPattern.FindString(msg.Body))
+	if err != nil {
+		t.Fatalf("failed to parse the reset link: %v", err)
+	}
+	token := link.Query().Get("token")
+	if token == "" {
+		t.Fatalf("expected a token in the reset link, got %q", msg.Body)
+	}
+	return token
+}
+
+func TestService_ResetPassword(t *testing.T) {
+	repo := &fakeResetRepository{user: AuthUser{ID: 1, PublicID: "user-jdoe", LoginID: "jdoe", Email: "jane.doe@example.com"}}
+	sender := mail.NewMemorySender()
+	s := &service{
+		repo:        repo,
+		config:      &Config{PasswordResetURL: "http://localhost:3000/reset-password", PasswordResetTTL: time.Hour},
+		mailer:      sender,
+		mailLimiter: newEmailRateLimiter(5, time.Hour),
+		denylist:    newTokenDenylist(nil),
+		hashParams:  password.Params{Time: 1, Memory: 64, Threads: 1},
+	}
+	ctx := context.Background()
+
+	if err := s.ForgotPassword(ctx, "jane.doe@example.com"); err != nil {
+		t.Fatalf("forgot password failed: %v", err)
+	}
+	token := waitForResetToken(t, sender, 1)
+	if to := sender.Messages()[0].To; len(to) != 1 || to[0] != "jane.doe@example.com" {
+		t.Errorf("expected the email to go to the user, got %v", to)
+	}
+
+	// A rejected password leaves the link usable
+	err := s.ResetPassword(ctx, &ResetPasswordRequest{Token: token, Password: "short"})
+	if !errors.Is(err, ErrInvalidPassword) {
+		t.Fatalf("expected ErrInvalidPassword, got %v", err)
+	}
+	if repo.resets != 0 {
+		t.Fatalf("expected no reset, got %d", repo.resets)
+	}
+
+	if err := s.ResetPassword(ctx, &ResetPasswordRequest{Token: token, Password: "Correct-Horse-42-Battery"}); err != nil {
+		t.Fatalf("reset failed: %v", err)
+	}
+	if repo.resets != 1 {
+		t.Errorf("expected one reset, got %d", repo.resets)
+	}
+	if !s.verifyPassword("Correct-Horse-42-Battery", repo.user.PasswordHash) {
+		t.Error("expected the new password to be stored")
+	}
+	if repo.user.EmailVerifiedAt == nil {
+		t.Error("expected the email to be verified")
+	}
+
+	// The link works only once
+	err = s.ResetPassword(ctx, &ResetPasswordRequest{Token: token, Password: "Another-Horse-43-Battery"})
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken, got %v", err)
+	}
+
+	// Unknown addresses get no email
+	if err := s.ForgotPassword(ctx, "nobody@example.com"); err != nil {
+		t.Fatalf("forgot password failed: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := len(sender.Messages()); got != 1 {
+		t.Errorf("expected no email for an unknown address, got %d emails", got)
+	}
+}
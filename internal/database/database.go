@@ -47,8 +47,7 @@ func New() Service {
 	if dbInstance != nil {
 		return dbInstance
 	}
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable&search_path=%s", username, password, host, port, database, schema)
-	db, err := sql.Open("pgx", connStr)
+	db, err := sql.Open("pgx", ConnString())
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -58,6 +57,12 @@ func New() Service {
 	return dbInstance
 }
 
+// ConnString returns the connection URL of the database, for components that need a dedicated
+// connection such as LISTEN/NOTIFY subscribers
+func ConnString() string {
+	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable&search_path=%s", username, password, host, port, database, schema)
+}
+
 // Health checks the health of the database connection by pinging the database.
 // It returns a map with keys indicating various health statistics.
 func (s *service) Health() map[string]string {
@@ -0,0 +1,133 @@
+package files
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+// maxArchiveFiles is the maximum number of files in a single ZIP archive
+const maxArchiveFiles = 1000
+
+// Archive is a ZIP archive of files that is built on the fly while it is written.
+// File contents are streamed from storage one at a time, so the archive is never
+// held in memory or on disk.
+type Archive struct {
+	repo    Repository
+	storage Storage
+	entries []archiveEntry
+	skipped []string
+}
+
+// archiveEntry is a file in an archive with its deduplicated name
+type archiveEntry struct {
+	name string
+	file *File
+}
+
+// FileCount returns the number of files that will be written to the archive
+func (a *Archive) FileCount() int {
+	return len(a.entries)
+}
+
+// Skipped returns the public IDs of requested files left out of the archive
+func (a *Archive) Skipped() []string {
+	return a.skipped
+}
+
+// WriteTo streams the archive to w. Files whose content can no longer be opened are
+// left out; an error means the archive was cut short and is unusable.
+func (a *Archive) WriteTo(ctx context.Context, w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	for _, entry := range a.entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		reader, err := a.storage.Get(ctx, entry.file.RelativePath)
+		if err != nil {
+			log.Printf("[WARN] Leaving file %s out of archive: %v", entry.file.PublicID, err)
+			continue
+		}
+
+		err = a.writeEntry(zw, entry, reader)
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write %s to archive: %w", entry.file.PublicID, err)
+		}
+
+		// Count the download (best effort)
+		publicID := entry.file.PublicID
+		go func() {
+			_ = a.repo.IncrementDownloadCount(context.Background(), publicID)
+		}()
+	}
+
+	return zw.Close()
+}
+
+// writeEntry adds a single file to the archive
+func (a *Archive) writeEntry(zw *zip.Writer, entry archiveEntry, reader io.Reader) error {
+	header := &zip.FileHeader{
+		Name:     entry.name,
+		Method:   archiveMethod(entry.file.MimeType),
+		Modified: entry.file.CreatedAt,
+	}
+
+	dst, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, reader)
+	return err
+}
+
+// archiveMethod picks the ZIP compression method for a MIME type.
+// Content that is already compressed is stored as is to save CPU time.
+func archiveMethod(mimeType string) uint16 {
+	switch {
+	case strings.HasPrefix(mimeType, "image/") && mimeType != "image/svg+xml" && mimeType != "image/bmp":
+		return zip.Store
+	case strings.HasPrefix(mimeType, "video/"), strings.HasPrefix(mimeType, "audio/"):
+		return zip.Store
+	}
+
+	switch mimeType {
+	case "application/zip", "application/gzip", "application/x-gzip",
+		"application/x-7z-compressed", "application/x-rar-compressed",
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"application/vnd.openxmlformats-officedocument.presentationml.presentation":
+		return zip.Store
+	}
+
+	return zip.Deflate
+}
+
+// uniqueArchiveName returns a safe name for a file in an archive that does not clash
+// (case-insensitively) with the names already used, e.g. "report.pdf" -> "report (2).pdf"
+func uniqueArchiveName(filename, fallback string, used map[string]bool) string {
+	// Archive entries must not contain directories or escape the extraction folder
+	name := strings.ReplaceAll(filename, "\\", "/")
+	name = name[strings.LastIndex(name, "/")+1:]
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." {
+		name = fallback
+	}
+
+	candidate := name
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 2; used[strings.ToLower(candidate)]; i++ {
+		candidate = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
@@ -0,0 +1,137 @@
+package files
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+// memoryStorage is an in-memory Storage for tests
+type memoryStorage map[string][]byte
+
+func (m memoryStorage) Save(ctx context.Context, reader io.Reader, relativePath string) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	m[relativePath] = data
+	return nil
+}
+
+func (m memoryStorage) Get(ctx context.Context, relativePath string) (io.ReadCloser, error) {
+	data, ok := m[relativePath]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m memoryStorage) Delete(ctx context.Context, relativePath string) error {
+	delete(m, relativePath)
+	return nil
+}
+
+// countingRepository is a Repository that only records download counts
+type countingRepository struct {
+	Repository
+	downloads chan string
+}
+
+func (r *countingRepository) IncrementDownloadCount(ctx context.Context, publicID string) error {
+	r.downloads <- publicID
+	return nil
+}
+
+func TestUniqueArchiveName(t *testing.T) {
+	used := map[string]bool{}
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{filename: "report.pdf", want: "report.pdf"},
+		{filename: "Report.PDF", want: "Report (2).PDF"},
+		{filename: "report.pdf", want: "report (3).pdf"},
+		{filename: "../../etc/passwd", want: "passwd"},
+		{filename: `C:\Users\me\notes.txt`, want: "notes.txt"},
+		{filename: "", want: "fallback-id"},
+		{filename: "..", want: "fallback-id (2)"},
+		{filename: "README", want: "README"},
+		{filename: "README", want: "README (2)"},
+	}
+
+	for _, tt := range tests {
+		if got := uniqueArchiveName(tt.filename, "fallback-id", used); got != tt.want {
+			t.Errorf("uniqueArchiveName(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestArchive_WriteTo(t *testing.T) {
+	storage := memoryStorage{
+		"2024/12/05/a.txt": []byte("hello world"),
+		"2024/12/05/b.png": bytes.Repeat([]byte{0x89}, 1024),
+	}
+	repo := &countingRepository{downloads: make(chan string, 3)}
+	created := time.Date(2024, 12, 5, 10, 0, 0, 0, time.UTC)
+
+	archive := &Archive{
+		repo:    repo,
+		storage: storage,
+		entries: []archiveEntry{
+			{name: "a.txt", file: &File{PublicID: "a", RelativePath: "2024/12/05/a.txt", MimeType: "text/plain", CreatedAt: created}},
+			{name: "missing.txt", file: &File{PublicID: "missing", RelativePath: "2024/12/05/missing.txt", MimeType: "text/plain", CreatedAt: created}},
+			{name: "b.png", file: &File{PublicID: "b", RelativePath: "2024/12/05/b.png", MimeType: "image/png", CreatedAt: created}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := archive.WriteTo(context.Background(), &buf); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+
+	want := map[string]uint16{"a.txt": zip.Deflate, "b.png": zip.Store}
+	if len(zr.File) != len(want) {
+		t.Fatalf("archive has %d entries, want %d", len(zr.File), len(want))
+	}
+
+	for _, f := range zr.File {
+		method, ok := want[f.Name]
+		if !ok {
+			t.Errorf("unexpected archive entry %q", f.Name)
+			continue
+		}
+		if f.Method != method {
+			t.Errorf("%s: method = %d, want %d", f.Name, f.Method, method)
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("%s: open error = %v", f.Name, err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+
+		source := storage["2024/12/05/"+f.Name]
+		if !bytes.Equal(content, source) {
+			t.Errorf("%s: content mismatch", f.Name)
+		}
+	}
+
+	// Only the files actually written count as downloads
+	for i := 0; i < 2; i++ {
+		select {
+		case <-repo.downloads:
+		case <-time.After(time.Second):
+			t.Fatal("download count was not incremented")
+		}
+	}
+}
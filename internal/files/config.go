@@ -0,0 +1,120 @@
+package files
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// Config holds file domain configuration
+type Config struct {
+	// StoragePath is the base path of the local storage backend
+	StoragePath string
+
+	// ShareSecret is the HMAC key used to sign share link URLs
+	ShareSecret string
+
+	// ThumbnailWorkers is the number of background workers generating thumbnails
+	ThumbnailWorkers int
+
+	// ThumbnailQueueSize is the number of pending thumbnail jobs accepted before new jobs are dropped
+	ThumbnailQueueSize int
+
+	// ThumbnailPDF enables first-page previews of PDF files (requires pdftoppm in PATH)
+	ThumbnailPDF bool
+
+	// DefaultUserQuota is the storage limit in bytes for users without an explicit user quota.
+	// Zero means unlimited.
+	DefaultUserQuota int64
+
+	// DeleteGracePeriod is how long the blob of a soft-deleted file is kept before it is purged
+	DeleteGracePeriod time.Duration
+
+	// PurgeInterval is how often retention rules are applied and deleted blobs are purged.
+	// Zero disables the scheduled purger.
+	PurgeInterval time.Duration
+
+	// PurgeBatchSize is the number of files processed per purge query
+	PurgeBatchSize int
+
+	// ClamdAddress is the clamd endpoint used for malware scanning ("tcp://host:port" or "unix:///path").
+	// Scanning is disabled when empty.
+	ClamdAddress string
+
+	// ScanTimeout bounds a single clamd request
+	ScanTimeout time.Duration
+
+	// ScanWorkers is the number of background workers scanning uploads
+	ScanWorkers int
+
+	// ScanQueueSize is the number of pending scan jobs accepted before new jobs are dropped
+	ScanQueueSize int
+
+	// ScanRetryInterval is how often files still pending or failed are queued for scanning again,
+	// so dropped jobs and scanner outages don't block downloads for good. Zero disables retries.
+	ScanRetryInterval time.Duration
+}
+
+// LoadConfig reads file domain configuration from environment variables
+func LoadConfig() *Config {
+	return &Config{
+		StoragePath:        getEnv("FILE_STORAGE_PATH", "./uploads"),
+		ShareSecret:        getEnv("FILE_SHARE_SECRET", ""),
+		ThumbnailWorkers:   getIntEnv("FILE_THUMBNAIL_WORKERS", 2),
+		ThumbnailQueueSize: getIntEnv("FILE_THUMBNAIL_QUEUE_SIZE", 100),
+		ThumbnailPDF:       getBoolEnv("FILE_THUMBNAIL_PDF", false),
+		DefaultUserQuota:   getInt64Env("FILE_DEFAULT_USER_QUOTA_BYTES", 0),
+		DeleteGracePeriod:  getDurationEnv("FILE_DELETE_GRACE_PERIOD", 30*24*time.Hour),
+		PurgeInterval:      getDurationEnv("FILE_PURGE_INTERVAL", time.Hour),
+		PurgeBatchSize:     getIntEnv("FILE_PURGE_BATCH_SIZE", 500),
+		ClamdAddress:       getEnv("FILE_SCAN_CLAMD_ADDRESS", ""),
+		ScanTimeout:        getDurationEnv("FILE_SCAN_TIMEOUT", 2*time.Minute),
+		ScanWorkers:        getIntEnv("FILE_SCAN_WORKERS", 2),
+		ScanQueueSize:      getIntEnv("FILE_SCAN_QUEUE_SIZE", 100),
+		ScanRetryInterval:  getDurationEnv("FILE_SCAN_RETRY_INTERVAL", 10*time.Minute),
+	}
+}
+
+// Helper functions for environment variables
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
+}
+
+func getInt64Env(key string, defaultValue int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+func getBoolEnv(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
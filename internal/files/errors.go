@@ -1,6 +1,10 @@
 package files
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
 	// ErrFileNotFound is returned when a file is not found
@@ -17,4 +21,96 @@ var (
 
 	// ErrStorageNotAvailable is returned when the storage backend is not available
 	ErrStorageNotAvailable = errors.New("storage backend is not available")
+
+	// ErrInvalidMetadata is returned when file metadata is not a JSON object
+	ErrInvalidMetadata = errors.New("metadata must be a JSON object")
+
+	// ErrFileScanPending is returned when a file has not (yet) been scanned clean
+	ErrFileScanPending = errors.New("file has not passed malware scanning")
+
+	// ErrFileInfected is returned when a file has been quarantined by the malware scanner
+	ErrFileInfected = errors.New("file is quarantined")
+
+	// ErrScannerNotConfigured is returned when a scan is requested but no scanner is configured
+	ErrScannerNotConfigured = errors.New("malware scanner is not configured")
+
+	// ErrScanFailed is returned when the malware scanner could not check a file
+	ErrScanFailed = errors.New("malware scan failed")
+
+	// ErrQuotaExceeded is returned when an upload would exceed a storage quota
+	ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+	// ErrQuotaNotFound is returned when a storage quota does not exist
+	ErrQuotaNotFound = errors.New("storage quota not found")
+
+	// ErrInvalidQuotaRequest is returned when quota parameters are invalid
+	ErrInvalidQuotaRequest = errors.New("invalid quota request")
+
+	// ErrLegalHold is returned when deleting a file that is under legal hold
+	ErrLegalHold = errors.New("file is under legal hold")
+
+	// ErrRetentionRuleNotFound is returned when a retention rule does not exist
+	ErrRetentionRuleNotFound = errors.New("retention rule not found")
+
+	// ErrInvalidRetentionRule is returned when retention rule parameters are invalid
+	ErrInvalidRetentionRule = errors.New("invalid retention rule")
+
+	// ErrFileShareNotFound is returned when a file access grant does not exist
+	ErrFileShareNotFound = errors.New("file share not found")
+
+	// ErrInvalidFileShareRequest is returned when file share parameters are invalid
+	ErrInvalidFileShareRequest = errors.New("invalid file share request")
+
+	// ErrInvalidArchiveRequest is returned when the file list of an archive is empty or too long
+	ErrInvalidArchiveRequest = errors.New("invalid archive request")
+
+	// ErrArchiveEmpty is returned when none of the files requested for an archive can be read
+	ErrArchiveEmpty = errors.New("none of the requested files can be downloaded")
+
+	// ErrThumbnailNotFound is returned when no thumbnail is available for a file
+	ErrThumbnailNotFound = errors.New("thumbnail not available")
+
+	// ErrInvalidThumbnailSize is returned when an unknown thumbnail size is requested
+	ErrInvalidThumbnailSize = errors.New("invalid thumbnail size")
+
+	// ErrShareLinkNotFound is returned when a share link does not exist
+	ErrShareLinkNotFound = errors.New("share link not found")
+
+	// ErrShareLinkExpired is returned when a share link has passed its expiry time
+	ErrShareLinkExpired = errors.New("share link has expired")
+
+	// ErrShareLinkRevoked is returned when a share link has been revoked
+	ErrShareLinkRevoked = errors.New("share link has been revoked")
+
+	// ErrShareLinkExhausted is returned when a share link has reached its maximum download count
+	ErrShareLinkExhausted = errors.New("share link download limit reached")
+
+	// ErrInvalidShareSignature is returned when a share link signature does not match
+	ErrInvalidShareSignature = errors.New("invalid share link signature")
+
+	// ErrSharePasswordRequired is returned when a password-protected share link is used without a password
+	ErrSharePasswordRequired = errors.New("share link password required")
+
+	// ErrInvalidSharePassword is returned when the password for a share link is wrong
+	ErrInvalidSharePassword = errors.New("invalid share link password")
+
+	// ErrShareLinkLocked is returned when a share link is locked after too many wrong passwords
+	ErrShareLinkLocked = errors.New("share link locked after too many wrong passwords")
+
+	// ErrInvalidShareLinkRequest is returned when share link parameters are out of range
+	ErrInvalidShareLinkRequest = errors.New("invalid share link request")
 )
+
+// ShareLinkLockedError is returned while a share link is locked after too many wrong passwords
+type ShareLinkLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ShareLinkLockedError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrShareLinkLocked, e.RetryAfter.Round(time.Second))
+}
+
+// Unwrap lets callers match ErrShareLinkLocked with errors.Is
+func (e *ShareLinkLockedError) Unwrap() error {
+	return ErrShareLinkLocked
+}
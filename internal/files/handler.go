@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"kc-api/internal/auth"
@@ -32,10 +34,53 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 	r.Route("/files", func(r chi.Router) {
 		r.Post("/", h.UploadFile)
 		r.Get("/", h.ListMyFiles)
+		r.Get("/usage", h.GetMyStorageUsage)
+		r.Get("/shared-with-me", h.ListSharedWithMe)
+		r.Post("/archive", h.CreateArchive)
 		r.Get("/{id}", h.GetFileInfo)
 		r.Get("/{id}/download", h.DownloadFile)
+		r.Get("/{id}/thumbnail", h.GetThumbnail)
+		r.With(auth.RequireAdmin).Post("/{id}/scan", h.RescanFile)
 		r.Put("/{id}/metadata", h.UpdateFileMetadata)
 		r.Delete("/{id}", h.DeleteFile)
+
+		// Access control routes
+		// Sharing is off limits while impersonating a user
+		r.With(auth.DenyImpersonation).Post("/{id}/shares", h.ShareFile)
+		r.Get("/{id}/shares", h.ListFileShares)
+		r.Delete("/{id}/shares/{shareId}", h.RevokeFileShare)
+
+		// Share link routes
+		r.With(auth.DenyImpersonation).Post("/{id}/share-links", h.CreateShareLink)
+		r.Get("/{id}/share-links", h.ListShareLinks)
+		r.Delete("/{id}/share-links/{linkId}", h.RevokeShareLink)
+	})
+
+	// Quota, retention and legal hold administration routes
+	r.Route("/admin/files", func(r chi.Router) {
+		r.Use(auth.RequireAdmin)
+
+		r.Get("/quotas", h.ListQuotas)
+		r.Put("/quotas", h.SetQuota)
+		r.Delete("/quotas/{id}", h.DeleteQuota)
+		r.Get("/usage", h.GetUsageReport)
+
+		// Retention
+		r.Get("/retention-rules", h.ListRetentionRules)
+		r.Post("/retention-rules", h.CreateRetentionRule)
+		r.Delete("/retention-rules/{id}", h.DeleteRetentionRule)
+		r.Put("/{id}/legal-hold", h.SetLegalHold)
+		r.Post("/purge", h.RunPurge)
+	})
+}
+
+// RegisterPublicRoutes registers file routes that do not require authentication
+// (public files and signed share links)
+func (h *Handler) RegisterPublicRoutes(r chi.Router) {
+	r.Route("/public/files", func(r chi.Router) {
+		r.Get("/{id}/download", h.DownloadPublicFile)
+		r.Get("/shared/{linkId}", h.DownloadSharedFile)
+		r.Post("/shared/{linkId}", h.DownloadSharedFile)
 	})
 }
 
@@ -51,7 +96,7 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 // @Param        metadata  formData  string  false  "Optional metadata JSON object"
 // @Success      201       {object}  FileUploadResponse
 // @Failure      400       {object}  ErrorResponse
-// @Failure      413       {object}  ErrorResponse  "File too large"
+// @Failure      413       {object}  ErrorResponse  "File too large or storage quota exceeded"
 // @Failure      500       {object}  ErrorResponse
 // @Security     BearerAuth
 // @Router       /files [post]
@@ -97,10 +142,18 @@ func (h *Handler) UploadFile(w http.ResponseWriter, r *http.Request) {
 			utils.RespondError(w, r, http.StatusRequestEntityTooLarge, "File too large", err.Error())
 			return
 		}
+		if errors.Is(err, ErrQuotaExceeded) {
+			utils.RespondError(w, r, http.StatusRequestEntityTooLarge, "Quota exceeded", err.Error())
+			return
+		}
 		if errors.Is(err, ErrInvalidMimeType) {
 			utils.RespondError(w, r, http.StatusBadRequest, "Invalid MIME type", err.Error())
 			return
 		}
+		if errors.Is(err, ErrInvalidMetadata) {
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", err.Error())
+			return
+		}
 		utils.RespondInternalError(w, r, err, "Failed to upload file")
 		return
 	}
@@ -110,13 +163,13 @@ func (h *Handler) UploadFile(w http.ResponseWriter, r *http.Request) {
 
 // GetFileInfo godoc
 // @Summary      Get file information
-// @Description  Retrieves detailed information about a file without downloading it
+// @Description  Retrieves detailed information about a file without downloading it. Requires read access: the uploader, users the file is shared with (directly, through a group or a department), and readers of a ticket the file is attached to. Public files are readable by every user.
 // @Tags         files
 // @Accept       json
 // @Produce      json
 // @Param        id   path      string  true  "File Public ID (UUID)"
 // @Success      200  {object}  FileResponse
-// @Failure      404  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse  "File not found or not readable"
 // @Failure      500  {object}  ErrorResponse
 // @Security     BearerAuth
 // @Router       /files/{id} [get]
@@ -127,7 +180,9 @@ func (h *Handler) GetFileInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.service.GetFileInfo(r.Context(), id)
+	requesterID := auth.GetUserIDFromContext(r.Context())
+
+	result, err := h.service.GetFileInfo(r.Context(), id, requesterID)
 	if err != nil {
 		if errors.Is(err, ErrFileNotFound) {
 			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "File not found")
@@ -142,12 +197,14 @@ func (h *Handler) GetFileInfo(w http.ResponseWriter, r *http.Request) {
 
 // DownloadFile godoc
 // @Summary      Download a file
-// @Description  Downloads the file content. Increments the download counter.
+// @Description  Downloads the file content. Increments the download counter. Requires read access to the file (see GET /files/{id}). When malware scanning is enabled, files can only be downloaded once they have been scanned clean.
 // @Tags         files
 // @Produce      octet-stream
 // @Param        id   path      string  true  "File Public ID (UUID)"
 // @Success      200  {file}    binary
-// @Failure      404  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse  "File quarantined"
+// @Failure      404  {object}  ErrorResponse  "File not found or not readable"
+// @Failure      409  {object}  ErrorResponse  "File not scanned yet"
 // @Failure      500  {object}  ErrorResponse
 // @Security     BearerAuth
 // @Router       /files/{id}/download [get]
@@ -158,31 +215,173 @@ func (h *Handler) DownloadFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	reader, file, err := h.service.GetFileForDownload(r.Context(), id)
+	requesterID := auth.GetUserIDFromContext(r.Context())
+
+	reader, file, err := h.service.GetFileForDownload(r.Context(), id, requesterID)
 	if err != nil {
-		if errors.Is(err, ErrFileNotFound) {
+		switch {
+		case errors.Is(err, ErrFileNotFound):
 			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "File not found")
-			return
+		case errors.Is(err, ErrFileInfected):
+			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", err.Error())
+		case errors.Is(err, ErrFileScanPending):
+			utils.RespondError(w, r, http.StatusConflict, "Conflict", err.Error())
+		default:
+			utils.RespondInternalError(w, r, err, "Internal server error")
 		}
-		utils.RespondInternalError(w, r, err, "Internal server error")
 		return
 	}
 	defer reader.Close()
 
-	// Set response headers
-	w.Header().Set("Content-Type", file.MimeType)
-	w.Header().Set("Content-Disposition", "attachment; filename=\""+file.OriginalFilename+"\"")
-	w.Header().Set("Content-Length", strconv.FormatInt(file.FileSize, 10))
-	w.Header().Set("X-Content-SHA256", file.ChecksumSHA256)
+	writeFileContent(w, reader, file)
+}
+
+// CreateArchive godoc
+// @Summary      Download files as a ZIP archive
+// @Description  Streams a ZIP archive of the given files. Duplicate file names are numbered, e.g. "report (2).pdf". Files that do not exist, that the caller may not read or that are blocked by malware scanning are left out; their count is returned in the X-Archive-Skipped header.
+// @Tags         files
+// @Accept       json
+// @Produce      application/zip
+// @Param        request  body      CreateArchiveRequest  true  "Files to archive"
+// @Success      200      {file}    binary
+// @Failure      400      {object}  ErrorResponse  "Empty or too long file list"
+// @Failure      404      {object}  ErrorResponse  "None of the files can be downloaded"
+// @Failure      500      {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /files/archive [post]
+func (h *Handler) CreateArchive(w http.ResponseWriter, r *http.Request) {
+	var req CreateArchiveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid request body")
+		return
+	}
+
+	h.ServeArchive(w, r, req.FileIDs, "files.zip")
+}
+
+// ServeArchive streams a ZIP archive of the given files as an attachment with the given filename.
+// It is shared with other domains offering bulk downloads, e.g. ticket attachments.
+func (h *Handler) ServeArchive(w http.ResponseWriter, r *http.Request, fileIDs []string, filename string) {
+	requesterID := auth.GetUserIDFromContext(r.Context())
+
+	archive, err := h.service.PrepareArchive(r.Context(), fileIDs, requesterID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidArchiveRequest):
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", err.Error())
+		case errors.Is(err, ErrArchiveEmpty):
+			utils.RespondError(w, r, http.StatusNotFound, "Not Found", err.Error())
+		default:
+			utils.RespondInternalError(w, r, err, "Internal server error")
+		}
+		return
+	}
+
+	// Large archives take longer than the server write timeout to stream
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+	w.Header().Set("X-Archive-Skipped", strconv.Itoa(len(archive.Skipped())))
+
+	if err := archive.WriteTo(r.Context(), w); err != nil {
+		// Headers are already sent, the client sees a truncated archive
+		log.Printf("[WARN] Failed to stream archive: %v", err)
+	}
+}
+
+// GetThumbnail godoc
+// @Summary      Get a file thumbnail
+// @Description  Returns a JPEG thumbnail of an image (or the first page of a PDF, if enabled). Thumbnails are generated in the background after upload, so they may not be available immediately.
+// @Tags         files
+// @Produce      jpeg
+// @Param        id    path      string  true   "File Public ID (UUID)"
+// @Param        size  query     string  false  "Thumbnail size"  Enums(small, medium, large)  default(medium)
+// @Success      200   {file}    binary
+// @Failure      400   {object}  ErrorResponse  "Invalid thumbnail size"
+// @Failure      403   {object}  ErrorResponse  "File quarantined"
+// @Failure      404   {object}  ErrorResponse  "File or thumbnail not found, or file not readable"
+// @Failure      409   {object}  ErrorResponse  "File not scanned yet"
+// @Failure      500   {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /files/{id}/thumbnail [get]
+func (h *Handler) GetThumbnail(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "File ID is required")
+		return
+	}
+
+	requesterID := auth.GetUserIDFromContext(r.Context())
+
+	reader, thumbnail, err := h.service.GetThumbnail(r.Context(), id, r.URL.Query().Get("size"), requesterID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidThumbnailSize):
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Size must be one of small, medium, large")
+		case errors.Is(err, ErrFileNotFound):
+			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "File not found")
+		case errors.Is(err, ErrThumbnailNotFound):
+			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Thumbnail not available")
+		case errors.Is(err, ErrFileInfected):
+			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", err.Error())
+		case errors.Is(err, ErrFileScanPending):
+			utils.RespondError(w, r, http.StatusConflict, "Conflict", err.Error())
+		default:
+			utils.RespondInternalError(w, r, err, "Internal server error")
+		}
+		return
+	}
+	defer reader.Close()
+
+	w.Header().Set("Content-Type", thumbnail.MimeType)
+	w.Header().Set("Content-Length", strconv.FormatInt(thumbnail.FileSize, 10))
+	w.Header().Set("Cache-Control", "private, max-age=86400")
 
-	// Stream file to response
 	if _, err := io.Copy(w, reader); err != nil {
 		// Can't send error response here as headers are already sent
-		// Log the error (in production, use proper logging)
 		return
 	}
 }
 
+// RescanFile godoc
+// @Summary      Rescan a file for malware
+// @Description  Scans an existing file again with the configured malware scanner and records the result. Infected files are quarantined; quarantined files that are now clean are released.
+// @Tags         files
+// @Produce      json
+// @Param        id   path      string  true  "File Public ID (UUID)"
+// @Success      200  {object}  ScanResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      502  {object}  ErrorResponse  "Scanner failed"
+// @Failure      503  {object}  ErrorResponse  "Scanner not configured"
+// @Security     BearerAuth
+// @Router       /files/{id}/scan [post]
+func (h *Handler) RescanFile(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "File ID is required")
+		return
+	}
+
+	result, err := h.service.RescanFile(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrFileNotFound):
+			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "File not found")
+		case errors.Is(err, ErrScannerNotConfigured):
+			utils.RespondError(w, r, http.StatusServiceUnavailable, "Service Unavailable", err.Error())
+		case errors.Is(err, ErrScanFailed):
+			utils.RespondError(w, r, http.StatusBadGateway, "Bad Gateway", err.Error())
+		default:
+			utils.RespondInternalError(w, r, err, "Internal server error")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
 // ListMyFiles godoc
 // @Summary      List my uploaded files
 // @Description  Retrieves a paginated list of files uploaded by the current user
@@ -223,9 +422,74 @@ func (h *Handler) ListMyFiles(w http.ResponseWriter, r *http.Request) {
 	utils.RespondJSON(w, http.StatusOK, result)
 }
 
+// ListSharedWithMe godoc
+// @Summary      List files shared with me
+// @Description  Retrieves a paginated list of files other users shared with the current user, directly or through one of their groups or their department
+// @Tags         files
+// @Accept       json
+// @Produce      json
+// @Param        page   query     int  false  "Page number"     default(1)
+// @Param        limit  query     int  false  "Items per page"  default(20)
+// @Success      200    {object}  FileListResponse
+// @Failure      401    {object}  ErrorResponse
+// @Failure      500    {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /files/shared-with-me [get]
+func (h *Handler) ListSharedWithMe(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Authentication required")
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	result, err := h.service.ListSharedWithMe(r.Context(), userID, page, limit)
+	if err != nil {
+		utils.RespondInternalError(w, r, err, "Failed to retrieve files")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// GetMyStorageUsage godoc
+// @Summary      Get my storage usage
+// @Description  Returns the storage used by the current user and the quotas that apply to them (user, department and group quotas). Uploads are rejected when any of these quotas would be exceeded.
+// @Tags         files
+// @Produce      json
+// @Success      200  {object}  StorageUsageResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /files/usage [get]
+func (h *Handler) GetMyStorageUsage(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+	if userID == "" {
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Authentication required")
+		return
+	}
+
+	result, err := h.service.GetMyStorageUsage(r.Context(), userID)
+	if err != nil {
+		utils.RespondInternalError(w, r, err, "Failed to retrieve storage usage")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
 // UpdateFileMetadata godoc
 // @Summary      Update file metadata
-// @Description  Updates the metadata of a file. Only the file uploader can update metadata.
+// @Description  Updates the metadata of a file. Requires write access: the file uploader or users the file is shared with at the WRITE level.
 // @Tags         files
 // @Accept       json
 // @Produce      json
@@ -234,8 +498,8 @@ func (h *Handler) ListMyFiles(w http.ResponseWriter, r *http.Request) {
 // @Success      200      {object}  SuccessResponse
 // @Failure      400      {object}  ErrorResponse
 // @Failure      401      {object}  ErrorResponse
-// @Failure      403      {object}  ErrorResponse  "Forbidden - not the file owner"
-// @Failure      404      {object}  ErrorResponse
+// @Failure      403      {object}  ErrorResponse  "Forbidden - read-only access"
+// @Failure      404      {object}  ErrorResponse  "File not found or not readable"
 // @Failure      500      {object}  ErrorResponse
 // @Security     BearerAuth
 // @Router       /files/{id}/metadata [put]
@@ -247,8 +511,8 @@ func (h *Handler) UpdateFileMetadata(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get current user ID from context
-	uploaderID := auth.GetUserIDFromContext(r.Context())
-	if uploaderID == "" {
+	requesterID := auth.GetUserIDFromContext(r.Context())
+	if requesterID == "" {
 		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Authentication required")
 		return
 	}
@@ -259,13 +523,17 @@ func (h *Handler) UpdateFileMetadata(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.UpdateFileMetadata(r.Context(), id, uploaderID, req.Metadata); err != nil {
+	if err := h.service.UpdateFileMetadata(r.Context(), id, requesterID, req.Metadata); err != nil {
 		if errors.Is(err, ErrFileNotFound) {
 			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "File not found")
 			return
 		}
 		if errors.Is(err, ErrUnauthorized) {
-			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "You can only update your own files")
+			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "You don't have write access to this file")
+			return
+		}
+		if errors.Is(err, ErrInvalidMetadata) {
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", err.Error())
 			return
 		}
 		utils.RespondInternalError(w, r, err, "Internal server error")
@@ -277,15 +545,16 @@ func (h *Handler) UpdateFileMetadata(w http.ResponseWriter, r *http.Request) {
 
 // DeleteFile godoc
 // @Summary      Delete a file
-// @Description  Performs a soft delete on a file. Only the file uploader can delete the file.
+// @Description  Performs a soft delete on a file. Requires write access: the file uploader or users the file is shared with at the WRITE level. The content is purged after the retention grace period. Files under legal hold cannot be deleted.
 // @Tags         files
 // @Accept       json
 // @Produce      json
 // @Param        id   path      string  true  "File Public ID (UUID)"
 // @Success      200  {object}  SuccessResponse
 // @Failure      401  {object}  ErrorResponse
-// @Failure      403  {object}  ErrorResponse  "Forbidden - not the file owner"
-// @Failure      404  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse  "Forbidden - read-only access"
+// @Failure      404  {object}  ErrorResponse  "File not found or not readable"
+// @Failure      409  {object}  ErrorResponse  "File under legal hold"
 // @Failure      500  {object}  ErrorResponse
 // @Security     BearerAuth
 // @Router       /files/{id} [delete]
@@ -309,7 +578,11 @@ func (h *Handler) DeleteFile(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if errors.Is(err, ErrUnauthorized) {
-			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "You can only delete your own files")
+			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "You don't have write access to this file")
+			return
+		}
+		if errors.Is(err, ErrLegalHold) {
+			utils.RespondError(w, r, http.StatusConflict, "Conflict", err.Error())
 			return
 		}
 		utils.RespondInternalError(w, r, err, "Internal server error")
@@ -319,4 +592,693 @@ func (h *Handler) DeleteFile(w http.ResponseWriter, r *http.Request) {
 	utils.RespondJSON(w, http.StatusOK, SuccessResponse{Message: "File deleted successfully"})
 }
 
-// -------------------- Helper Functions --------------------
+// -------------------- Access Control Handlers --------------------
+
+// ShareFile godoc
+// @Summary      Share a file with a user, group or department
+// @Description  Grants a user, or every member of a group or department, READ (download and view) or WRITE (also update metadata) access to a file. Sharing again with the same grantee replaces its access level. Only the file uploader can share a file.
+// @Tags         files
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string            true  "File Public ID (UUID)"
+// @Param        request  body      ShareFileRequest  true  "Grantee and access level"
+// @Success      201      {object}  FileShareResponse
+// @Failure      400      {object}  ErrorResponse
+// @Failure      401      {object}  ErrorResponse
+// @Failure      403      {object}  ErrorResponse  "Forbidden - not the file owner, or impersonating"
+// @Failure      404      {object}  ErrorResponse
+// @Failure      500      {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /files/{id}/shares [post]
+func (h *Handler) ShareFile(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "File ID is required")
+		return
+	}
+
+	requesterID := auth.GetUserIDFromContext(r.Context())
+	if requesterID == "" {
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Authentication required")
+		return
+	}
+
+	var req ShareFileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	result, err := h.service.ShareFile(r.Context(), id, requesterID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrFileNotFound):
+			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "File not found")
+		case errors.Is(err, ErrUnauthorized):
+			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "You can only share your own files")
+		case errors.Is(err, ErrInvalidFileShareRequest):
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", err.Error())
+		default:
+			utils.RespondInternalError(w, r, err, "Failed to share file")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, result)
+}
+
+// ListFileShares godoc
+// @Summary      List the shares of a file
+// @Description  Lists the users, groups and departments a file is shared with. Only the file uploader can list shares.
+// @Tags         files
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "File Public ID (UUID)"
+// @Success      200  {object}  FileShareListResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse  "Forbidden - not the file owner"
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /files/{id}/shares [get]
+func (h *Handler) ListFileShares(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "File ID is required")
+		return
+	}
+
+	requesterID := auth.GetUserIDFromContext(r.Context())
+	if requesterID == "" {
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Authentication required")
+		return
+	}
+
+	result, err := h.service.ListFileShares(r.Context(), id, requesterID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrFileNotFound):
+			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "File not found")
+		case errors.Is(err, ErrUnauthorized):
+			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "You can only list shares of your own files")
+		default:
+			utils.RespondInternalError(w, r, err, "Failed to list file shares")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// RevokeFileShare godoc
+// @Summary      Revoke a file share
+// @Description  Removes the access a user, group or department was granted on a file. Only the file uploader can revoke shares.
+// @Tags         files
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string  true  "File Public ID (UUID)"
+// @Param        shareId  path      string  true  "File Share Public ID (UUID)"
+// @Success      200      {object}  SuccessResponse
+// @Failure      401      {object}  ErrorResponse
+// @Failure      403      {object}  ErrorResponse  "Forbidden - not the file owner"
+// @Failure      404      {object}  ErrorResponse
+// @Failure      500      {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /files/{id}/shares/{shareId} [delete]
+func (h *Handler) RevokeFileShare(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	shareID := chi.URLParam(r, "shareId")
+	if id == "" || shareID == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "File ID and share ID are required")
+		return
+	}
+
+	requesterID := auth.GetUserIDFromContext(r.Context())
+	if requesterID == "" {
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Authentication required")
+		return
+	}
+
+	if err := h.service.RevokeFileShare(r.Context(), id, shareID, requesterID); err != nil {
+		switch {
+		case errors.Is(err, ErrFileNotFound):
+			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "File not found")
+		case errors.Is(err, ErrFileShareNotFound):
+			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "File share not found")
+		case errors.Is(err, ErrUnauthorized):
+			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "You can only revoke shares of your own files")
+		default:
+			utils.RespondInternalError(w, r, err, "Failed to revoke file share")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, SuccessResponse{Message: "File share revoked successfully"})
+}
+
+// -------------------- Share Link Handlers --------------------
+
+// CreateShareLink godoc
+// @Summary      Create a share link
+// @Description  Creates an HMAC-signed download link that can be used without authentication. Links expire (default 1 day, max 30 days) and may be limited by download count and protected by a password. Only the file uploader can create share links.
+// @Tags         files
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                  true  "File Public ID (UUID)"
+// @Param        request  body      CreateShareLinkRequest  true  "Share link options"
+// @Success      201      {object}  ShareLinkResponse
+// @Failure      400      {object}  ErrorResponse
+// @Failure      401      {object}  ErrorResponse
+// @Failure      403      {object}  ErrorResponse  "Forbidden - not the file owner, or impersonating"
+// @Failure      404      {object}  ErrorResponse
+// @Failure      500      {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /files/{id}/share-links [post]
+func (h *Handler) CreateShareLink(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "File ID is required")
+		return
+	}
+
+	requesterID := auth.GetUserIDFromContext(r.Context())
+	if requesterID == "" {
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Authentication required")
+		return
+	}
+
+	var req CreateShareLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondError(w, r, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	result, err := h.service.CreateShareLink(r.Context(), id, requesterID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrFileNotFound):
+			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "File not found")
+		case errors.Is(err, ErrUnauthorized):
+			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "You can only share your own files")
+		case errors.Is(err, ErrInvalidShareLinkRequest):
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", err.Error())
+		default:
+			utils.RespondInternalError(w, r, err, "Failed to create share link")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, result)
+}
+
+// ListShareLinks godoc
+// @Summary      List share links of a file
+// @Description  Lists all share links (including expired and revoked ones) created for a file. Only the file uploader can list share links.
+// @Tags         files
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "File Public ID (UUID)"
+// @Success      200  {object}  ShareLinkListResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse  "Forbidden - not the file owner"
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /files/{id}/share-links [get]
+func (h *Handler) ListShareLinks(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "File ID is required")
+		return
+	}
+
+	requesterID := auth.GetUserIDFromContext(r.Context())
+	if requesterID == "" {
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Authentication required")
+		return
+	}
+
+	result, err := h.service.ListShareLinks(r.Context(), id, requesterID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrFileNotFound):
+			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "File not found")
+		case errors.Is(err, ErrUnauthorized):
+			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "You can only view share links of your own files")
+		default:
+			utils.RespondInternalError(w, r, err, "Failed to retrieve share links")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// RevokeShareLink godoc
+// @Summary      Revoke a share link
+// @Description  Revokes a share link so it can no longer be used. Only the file uploader can revoke share links.
+// @Tags         files
+// @Accept       json
+// @Produce      json
+// @Param        id      path      string  true  "File Public ID (UUID)"
+// @Param        linkId  path      string  true  "Share Link Public ID (UUID)"
+// @Success      200     {object}  SuccessResponse
+// @Failure      401     {object}  ErrorResponse
+// @Failure      403     {object}  ErrorResponse  "Forbidden - not the file owner"
+// @Failure      404     {object}  ErrorResponse
+// @Failure      500     {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /files/{id}/share-links/{linkId} [delete]
+func (h *Handler) RevokeShareLink(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	linkID := chi.URLParam(r, "linkId")
+	if id == "" || linkID == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "File ID and share link ID are required")
+		return
+	}
+
+	requesterID := auth.GetUserIDFromContext(r.Context())
+	if requesterID == "" {
+		utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", "Authentication required")
+		return
+	}
+
+	if err := h.service.RevokeShareLink(r.Context(), id, linkID, requesterID); err != nil {
+		switch {
+		case errors.Is(err, ErrFileNotFound):
+			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "File not found")
+		case errors.Is(err, ErrShareLinkNotFound):
+			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Share link not found or already revoked")
+		case errors.Is(err, ErrUnauthorized):
+			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "You can only revoke share links of your own files")
+		default:
+			utils.RespondInternalError(w, r, err, "Failed to revoke share link")
+		}
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, SuccessResponse{Message: "Share link revoked successfully"})
+}
+
+// -------------------- Public Handlers --------------------
+
+// DownloadSharedFile godoc
+// @Summary      Download a file through a share link
+// @Description  Downloads a file using a signed share link. No authentication is required. Password-protected links accept the password in the X-Share-Password header or, with POST, in the JSON body. Repeated wrong passwords lock the link for a while; the Retry-After header tells when to try again. Each successful download counts against the link's download limit.
+// @Tags         files
+// @Accept       json
+// @Produce      octet-stream
+// @Param        linkId            path      string                     true   "Share Link Public ID (UUID)"
+// @Param        expires           query     int                        true   "Expiry timestamp (Unix seconds)"
+// @Param        signature         query     string                     true   "HMAC signature"
+// @Param        X-Share-Password  header    string                     false  "Share link password"
+// @Param        request           body      SharedFileDownloadRequest  false  "Share link password (POST only)"
+// @Success      200               {file}    binary
+// @Failure      401               {object}  ErrorResponse  "Password required or invalid"
+// @Failure      403               {object}  ErrorResponse  "Invalid signature or file quarantined"
+// @Failure      404               {object}  ErrorResponse
+// @Failure      409               {object}  ErrorResponse  "File not scanned yet"
+// @Failure      410               {object}  ErrorResponse  "Link expired, revoked or exhausted"
+// @Failure      429               {object}  ErrorResponse  "Link locked after too many wrong passwords"
+// @Failure      500               {object}  ErrorResponse
+// @Router       /public/files/shared/{linkId} [get]
+// @Router       /public/files/shared/{linkId} [post]
+func (h *Handler) DownloadSharedFile(w http.ResponseWriter, r *http.Request) {
+	linkID := chi.URLParam(r, "linkId")
+	if linkID == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Share link ID is required")
+		return
+	}
+
+	query := r.URL.Query()
+	password := r.Header.Get(SharePasswordHeader)
+	if password == "" && r.Method == http.MethodPost {
+		var req SharedFileDownloadRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			utils.RespondError(w, r, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+		password = req.Password
+	}
+
+	reader, file, err := h.service.GetSharedFileForDownload(r.Context(), linkID, query.Get("expires"), query.Get("signature"), password)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidShareSignature):
+			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "Invalid share link")
+		case errors.Is(err, ErrShareLinkNotFound), errors.Is(err, ErrFileNotFound):
+			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "File not found")
+		case errors.Is(err, ErrShareLinkExpired), errors.Is(err, ErrShareLinkRevoked), errors.Is(err, ErrShareLinkExhausted):
+			utils.RespondError(w, r, http.StatusGone, "Gone", err.Error())
+		case errors.Is(err, ErrSharePasswordRequired), errors.Is(err, ErrInvalidSharePassword):
+			utils.RespondError(w, r, http.StatusUnauthorized, "Unauthorized", err.Error())
+		case errors.Is(err, ErrShareLinkLocked):
+			var locked *ShareLinkLockedError
+			if errors.As(err, &locked) {
+				seconds := int((locked.RetryAfter + time.Second - 1) / time.Second)
+				w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+			}
+			utils.RespondError(w, r, http.StatusTooManyRequests, "Too Many Requests", err.Error())
+		case errors.Is(err, ErrFileInfected):
+			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", err.Error())
+		case errors.Is(err, ErrFileScanPending):
+			utils.RespondError(w, r, http.StatusConflict, "Conflict", err.Error())
+		default:
+			utils.RespondInternalError(w, r, err, "Internal server error")
+		}
+		return
+	}
+	defer reader.Close()
+
+	writeFileContent(w, reader, file)
+}
+
+// DownloadPublicFile godoc
+// @Summary      Download a public file
+// @Description  Downloads a file marked as public without authentication. Private files are reported as not found.
+// @Tags         files
+// @Produce      octet-stream
+// @Param        id   path      string  true  "File Public ID (UUID)"
+// @Success      200  {file}    binary
+// @Failure      403  {object}  ErrorResponse  "File quarantined"
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse  "File not scanned yet"
+// @Failure      500  {object}  ErrorResponse
+// @Router       /public/files/{id}/download [get]
+func (h *Handler) DownloadPublicFile(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "File ID is required")
+		return
+	}
+
+	reader, file, err := h.service.GetPublicFileForDownload(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrFileNotFound):
+			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "File not found")
+		case errors.Is(err, ErrFileInfected):
+			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", err.Error())
+		case errors.Is(err, ErrFileScanPending):
+			utils.RespondError(w, r, http.StatusConflict, "Conflict", err.Error())
+		default:
+			utils.RespondInternalError(w, r, err, "Internal server error")
+		}
+		return
+	}
+	defer reader.Close()
+
+	writeFileContent(w, reader, file)
+}
+
+// -------------------- Quota Administration Handlers --------------------
+
+// ListQuotas godoc
+// @Summary      List storage quotas
+// @Description  Lists storage quotas with the current usage of their user, department or group
+// @Tags         admin
+// @Produce      json
+// @Param        scope_type  query     string  false  "Filter by scope type"  Enums(USER, DEPARTMENT, GROUP)
+// @Success      200         {object}  QuotaListResponse
+// @Failure      400         {object}  ErrorResponse
+// @Failure      403         {object}  ErrorResponse
+// @Failure      500         {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/files/quotas [get]
+func (h *Handler) ListQuotas(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.ListQuotas(r.Context(), r.URL.Query().Get("scope_type"))
+	if err != nil {
+		if errors.Is(err, ErrInvalidQuotaRequest) {
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", err.Error())
+			return
+		}
+		utils.RespondInternalError(w, r, err, "Failed to retrieve quotas")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// SetQuota godoc
+// @Summary      Set a storage quota
+// @Description  Creates or replaces the storage quota of a user, department or group. Department and group usage is the sum of the usage of their current members.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request  body      SetQuotaRequest  true  "Quota"
+// @Success      200      {object}  QuotaResponse
+// @Failure      400      {object}  ErrorResponse
+// @Failure      403      {object}  ErrorResponse
+// @Failure      500      {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/files/quotas [put]
+func (h *Handler) SetQuota(w http.ResponseWriter, r *http.Request) {
+	var req SetQuotaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	result, err := h.service.SetQuota(r.Context(), &req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidQuotaRequest) {
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", err.Error())
+			return
+		}
+		utils.RespondInternalError(w, r, err, "Failed to save quota")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// DeleteQuota godoc
+// @Summary      Delete a storage quota
+// @Description  Removes a storage quota. The scope falls back to no limit (or the default user quota for users).
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "Quota ID (UUID)"
+// @Success      200  {object}  SuccessResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/files/quotas/{id} [delete]
+func (h *Handler) DeleteQuota(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Quota ID is required")
+		return
+	}
+
+	if err := h.service.DeleteQuota(r.Context(), id); err != nil {
+		if errors.Is(err, ErrQuotaNotFound) {
+			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Quota not found")
+			return
+		}
+		utils.RespondInternalError(w, r, err, "Failed to delete quota")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, SuccessResponse{Message: "Quota deleted successfully"})
+}
+
+// GetUsageReport godoc
+// @Summary      Get top storage consumers
+// @Description  Returns the users, departments or groups using the most storage, with their quota if any
+// @Tags         admin
+// @Produce      json
+// @Param        scope_type  query     string  false  "Consumer type"  Enums(USER, DEPARTMENT, GROUP)  default(USER)
+// @Param        limit       query     int     false  "Number of consumers"  default(20)
+// @Success      200         {object}  UsageReportResponse
+// @Failure      400         {object}  ErrorResponse
+// @Failure      403         {object}  ErrorResponse
+// @Failure      500         {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/files/usage [get]
+func (h *Handler) GetUsageReport(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	result, err := h.service.GetUsageReport(r.Context(), r.URL.Query().Get("scope_type"), limit)
+	if err != nil {
+		if errors.Is(err, ErrInvalidQuotaRequest) {
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", err.Error())
+			return
+		}
+		utils.RespondInternalError(w, r, err, "Failed to retrieve usage report")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// -------------------- Retention Administration Handlers --------------------
+
+// ListRetentionRules godoc
+// @Summary      List retention rules
+// @Description  Lists file retention rules. Files matching an active rule are deleted once they are older than the longest matching retention.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  RetentionRuleListResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/files/retention-rules [get]
+func (h *Handler) ListRetentionRules(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.ListRetentionRules(r.Context())
+	if err != nil {
+		utils.RespondInternalError(w, r, err, "Failed to retrieve retention rules")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// CreateRetentionRule godoc
+// @Summary      Create a retention rule
+// @Description  Creates an age-based retention rule matching files by MIME type (exact or wildcard such as image/*) and/or a metadata key and value. A rule without conditions matches every file.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request  body      CreateRetentionRuleRequest  true  "Retention rule"
+// @Success      201      {object}  RetentionRuleResponse
+// @Failure      400      {object}  ErrorResponse
+// @Failure      403      {object}  ErrorResponse
+// @Failure      500      {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/files/retention-rules [post]
+func (h *Handler) CreateRetentionRule(w http.ResponseWriter, r *http.Request) {
+	var req CreateRetentionRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	result, err := h.service.CreateRetentionRule(r.Context(), &req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidRetentionRule) {
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", err.Error())
+			return
+		}
+		utils.RespondInternalError(w, r, err, "Failed to create retention rule")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, result)
+}
+
+// DeleteRetentionRule godoc
+// @Summary      Delete a retention rule
+// @Description  Deletes a retention rule. Files already expired by the rule are not restored.
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "Retention rule ID (UUID)"
+// @Success      200  {object}  SuccessResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/files/retention-rules/{id} [delete]
+func (h *Handler) DeleteRetentionRule(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Retention rule ID is required")
+		return
+	}
+
+	if err := h.service.DeleteRetentionRule(r.Context(), id); err != nil {
+		if errors.Is(err, ErrRetentionRuleNotFound) {
+			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Retention rule not found")
+			return
+		}
+		utils.RespondInternalError(w, r, err, "Failed to delete retention rule")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, SuccessResponse{Message: "Retention rule deleted successfully"})
+}
+
+// SetLegalHold godoc
+// @Summary      Place or release a legal hold
+// @Description  A file under legal hold cannot be deleted by its owner, is never expired by retention rules and its content is never purged. Also applies to soft-deleted files whose content has not been purged yet.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string               true  "File Public ID (UUID)"
+// @Param        request  body      SetLegalHoldRequest  true  "Legal hold"
+// @Success      200      {object}  SuccessResponse
+// @Failure      400      {object}  ErrorResponse
+// @Failure      403      {object}  ErrorResponse
+// @Failure      404      {object}  ErrorResponse
+// @Failure      500      {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/files/{id}/legal-hold [put]
+func (h *Handler) SetLegalHold(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "File ID is required")
+		return
+	}
+
+	var req SetLegalHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := h.service.SetLegalHold(r.Context(), id, req.LegalHold); err != nil {
+		if errors.Is(err, ErrFileNotFound) {
+			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "File not found")
+			return
+		}
+		utils.RespondInternalError(w, r, err, "Failed to set legal hold")
+		return
+	}
+
+	message := "Legal hold released"
+	if req.LegalHold {
+		message = "Legal hold placed"
+	}
+	utils.RespondJSON(w, http.StatusOK, SuccessResponse{Message: message})
+}
+
+// RunPurge godoc
+// @Summary      Run the retention purger
+// @Description  Expires files according to the retention rules and purges the content of files deleted longer ago than the grace period, then reports the reclaimed bytes. The purger also runs on a schedule (FILE_PURGE_INTERVAL).
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  PurgeReport
+// @Failure      403  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/files/purge [post]
+func (h *Handler) RunPurge(w http.ResponseWriter, r *http.Request) {
+	report, err := h.service.RunPurge(r.Context())
+	if err != nil {
+		utils.RespondInternalError(w, r, err, "Failed to purge files")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, report)
+}
+
+// -------------------- Helper Functions --------------------
+
+// writeFileContent sets download headers and streams the file content to the response
+func writeFileContent(w http.ResponseWriter, reader io.Reader, file *File) {
+	// Set response headers
+	w.Header().Set("Content-Type", file.MimeType)
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+file.OriginalFilename+"\"")
+	w.Header().Set("Content-Length", strconv.FormatInt(file.FileSize, 10))
+	w.Header().Set("X-Content-SHA256", file.ChecksumSHA256)
+
+	// Stream file to response
+	if _, err := io.Copy(w, reader); err != nil {
+		// Can't send error response here as headers are already sent
+		// Log the error (in production, use proper logging)
+		return
+	}
+}
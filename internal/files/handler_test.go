@@ -0,0 +1,44 @@
+package files
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"kc-api/internal/auth"
+)
+
+func TestHandler_SharingWhileImpersonating(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{method: http.MethodPost, path: "/files/file-123/shares"},
+		{method: http.MethodPost, path: "/files/file-123/share-links"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			// The service is never reached
+			r := chi.NewRouter()
+			r.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					claims := &auth.TokenClaims{UserID: "user-456", Actor: &auth.TokenActor{UserID: "admin-123"}}
+					next.ServeHTTP(w, req.WithContext(auth.SetClaimsInContext(req.Context(), claims)))
+				})
+			})
+			NewHandler(nil).RegisterRoutes(r)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{}`))
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+			}
+		})
+	}
+}
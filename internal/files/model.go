@@ -58,12 +58,222 @@ type File struct {
 	DownloadCount    int64          `json:"download_count"`
 	LastAccessedAt   sql.NullTime   `json:"-"`
 	IsPublic         bool           `json:"is_public"`
+	LegalHold        bool           `json:"legal_hold"`
 	IsDeleted        bool           `json:"-"`
 	DeletedAt        sql.NullTime   `json:"-"`
 	CreatedAt        time.Time      `json:"created_at"`
 	UpdatedAt        time.Time      `json:"updated_at"`
 }
 
+// FileShareLink represents a signed, expiring download link for a file
+type FileShareLink struct {
+	ID            int64          `json:"-"`
+	PublicID      string         `json:"id"`
+	FileID        int64          `json:"-"`
+	CreatedBy     sql.NullInt64  `json:"-"`
+	ExpiresAt     time.Time      `json:"expires_at"`
+	MaxDownloads  sql.NullInt64  `json:"-"`
+	DownloadCount int64          `json:"download_count"`
+	PasswordHash  sql.NullString `json:"-"`
+	IsRevoked     bool           `json:"is_revoked"`
+	RevokedAt     sql.NullTime   `json:"-"`
+	LastUsedAt    sql.NullTime   `json:"-"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// AccessLevel represents the level of access granted on a file
+type AccessLevel string
+
+const (
+	AccessLevelRead  AccessLevel = "READ"  // Download, thumbnails and file information
+	AccessLevelWrite AccessLevel = "WRITE" // READ plus metadata updates
+)
+
+// IsValid reports whether the level is a known access level
+func (l AccessLevel) IsValid() bool {
+	return l == AccessLevelRead || l == AccessLevelWrite
+}
+
+// Allows reports whether the level includes the required level
+func (l AccessLevel) Allows(required AccessLevel) bool {
+	switch l {
+	case AccessLevelWrite:
+		return required == AccessLevelRead || required == AccessLevelWrite
+	case AccessLevelRead:
+		return required == AccessLevelRead
+	}
+	return false
+}
+
+// FileShare grants a user, or every member of a group or department, access to a file.
+// Grantees are the same kinds of principals as storage quota scopes.
+type FileShare struct {
+	ID              int64           `json:"-"`
+	PublicID        string          `json:"id"`
+	FileID          int64           `json:"-"`
+	GranteeType     QuotaScope      `json:"grantee_type"`
+	GranteeID       int64           `json:"-"`
+	GranteePublicID string          `json:"grantee_id"`
+	GranteeName     json.RawMessage `json:"grantee_name"`
+	AccessLevel     AccessLevel     `json:"access_level"`
+	CreatedBy       sql.NullInt64   `json:"-"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+// DerivativeKind represents the kind of a file derived from an uploaded file
+type DerivativeKind string
+
+const (
+	DerivativeKindThumbnail DerivativeKind = "THUMBNAIL"
+)
+
+// FileDerivative represents a file generated from an uploaded file (e.g. a thumbnail).
+// Derivatives are stored through the same Storage as their parent file.
+type FileDerivative struct {
+	ID           int64          `json:"-"`
+	FileID       int64          `json:"-"`
+	Kind         DerivativeKind `json:"kind"`
+	Variant      string         `json:"variant"`
+	RelativePath string         `json:"-"`
+	MimeType     string         `json:"mime_type"`
+	FileSize     int64          `json:"file_size"`
+	Width        int            `json:"width"`
+	Height       int            `json:"height"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// ThumbnailSize defines a named thumbnail variant and its bounding box in pixels
+type ThumbnailSize struct {
+	Name         string
+	MaxDimension int
+}
+
+// Thumbnail sizes generated for every supported upload
+var ThumbnailSizes = []ThumbnailSize{
+	{Name: "small", MaxDimension: 128},
+	{Name: "medium", MaxDimension: 320},
+	{Name: "large", MaxDimension: 800},
+}
+
+// DefaultThumbnailSize is used when no size is requested
+const DefaultThumbnailSize = "medium"
+
+// ScanStatus represents the malware scan state of a file
+type ScanStatus string
+
+const (
+	ScanStatusPending  ScanStatus = "PENDING"  // Uploaded, waiting for the scanner
+	ScanStatusClean    ScanStatus = "CLEAN"    // No malware found, downloads allowed
+	ScanStatusInfected ScanStatus = "INFECTED" // Malware found, file quarantined
+	ScanStatusFailed   ScanStatus = "FAILED"   // Scanner could not check the file, downloads blocked until rescanned
+)
+
+// ScanInfo is the malware scan record stored under the "malware_scan" key of File.Metadata
+type ScanInfo struct {
+	Status    ScanStatus `json:"status"`
+	Engine    string     `json:"engine,omitempty"`
+	Signature string     `json:"signature,omitempty"`
+	ScannedAt *time.Time `json:"scanned_at,omitempty"`
+}
+
+// QuotaScope represents the kind of principal a storage quota applies to
+type QuotaScope string
+
+const (
+	QuotaScopeUser       QuotaScope = "USER"
+	QuotaScopeDepartment QuotaScope = "DEPARTMENT"
+	QuotaScopeGroup      QuotaScope = "GROUP"
+)
+
+// IsValid reports whether the scope is a known quota scope
+func (s QuotaScope) IsValid() bool {
+	switch s {
+	case QuotaScopeUser, QuotaScopeDepartment, QuotaScopeGroup:
+		return true
+	}
+	return false
+}
+
+// StorageQuota represents a storage limit for a user, department or group.
+// Department and group usage is the sum of the usage of their current members.
+type StorageQuota struct {
+	ID            int64           `json:"-"`
+	PublicID      string          `json:"id"`
+	ScopeType     QuotaScope      `json:"scope_type"`
+	ScopeID       int64           `json:"-"`
+	ScopePublicID string          `json:"scope_id"`
+	ScopeName     json.RawMessage `json:"scope_name"`
+	MaxBytes      int64           `json:"max_bytes"`
+	MaxFiles      sql.NullInt64   `json:"-"`
+	UsedBytes     int64           `json:"used_bytes"` // Current usage of the scope
+	FileCount     int64           `json:"file_count"` // Current file count of the scope
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// StorageUsage represents the storage accounted to a user, department or group
+type StorageUsage struct {
+	ScopeType     QuotaScope
+	ScopePublicID string
+	ScopeName     json.RawMessage
+	UsedBytes     int64
+	FileCount     int64
+	MaxBytes      sql.NullInt64 // Quota of the scope, if any
+}
+
+// RetentionRule expires files of a MIME type and/or with a metadata key after a number of days.
+// When several rules match a file, the longest retention applies.
+type RetentionRule struct {
+	ID            int64          `json:"-"`
+	PublicID      string         `json:"id"`
+	Name          string         `json:"name"`
+	MimeType      sql.NullString `json:"-"` // Exact type or wildcard such as "image/*"; NULL matches any type
+	MetadataKey   sql.NullString `json:"-"` // NULL matches any metadata
+	MetadataValue sql.NullString `json:"-"` // NULL matches any value of MetadataKey
+	RetainDays    int            `json:"retain_days"`
+	IsActive      bool           `json:"is_active"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// PurgeAction represents the kind of a retention audit record
+type PurgeAction string
+
+const (
+	PurgeActionExpired PurgeAction = "EXPIRED" // Soft-deleted by a retention rule
+	PurgeActionPurged  PurgeAction = "PURGED"  // Blob removed from storage after the grace period
+	PurgeActionFailed  PurgeAction = "FAILED"  // Blob could not be removed
+)
+
+// PurgeCandidate is a file selected for expiry or purge
+type PurgeCandidate struct {
+	FileID       int64
+	PublicID     string
+	RelativePath string
+	FileSize     int64
+	RuleID       sql.NullInt64 // Retention rule that expired the file
+}
+
+// FilePurgeLog is an audit record of the retention subsystem
+type FilePurgeLog struct {
+	ID             int64
+	FileID         int64
+	Action         PurgeAction
+	RuleID         sql.NullInt64
+	ReclaimedBytes int64
+	Detail         sql.NullString
+	CreatedAt      time.Time
+}
+
+// Share link configuration constants
+const (
+	DefaultShareLinkDuration = 24 * time.Hour      // Share links are valid for 1 day unless specified
+	MaxShareLinkDuration     = 30 * 24 * time.Hour // Share links can be valid for at most 30 days
+	SharePasswordHeader      = "X-Share-Password"
+)
+
 // -------------------- Response DTOs --------------------
 
 // FileResponse represents a file response for API
@@ -74,6 +284,7 @@ type FileResponse struct {
 	FileSize         int64           `json:"file_size" example:"1048576"`
 	ChecksumSHA256   string          `json:"checksum_sha256" example:"abc123..."`
 	DownloadURL      string          `json:"download_url" example:"/files/01912345-6789-7abc-def0-123456789abc/download"`
+	ThumbnailURL     string          `json:"thumbnail_url,omitempty" example:"/files/01912345-6789-7abc-def0-123456789abc/thumbnail"`
 	DownloadCount    int64           `json:"download_count" example:"42"`
 	IsPublic         bool            `json:"is_public" example:"false"`
 	Metadata         json.RawMessage `json:"metadata,omitempty" swaggertype:"object"`
@@ -92,6 +303,7 @@ type FileUploadResponse struct {
 	FileSize         int64  `json:"file_size" example:"1048576"`
 	ChecksumSHA256   string `json:"checksum_sha256" example:"abc123..."`
 	DownloadURL      string `json:"download_url" example:"/files/01912345-6789-7abc-def0-123456789abc/download"`
+	ScanStatus       string `json:"scan_status,omitempty" example:"PENDING"` // Set when malware scanning is enabled
 	Message          string `json:"message" example:"File uploaded successfully"`
 }
 
@@ -115,6 +327,120 @@ type SuccessResponse struct {
 	Message string `json:"message" example:"Operation completed successfully"`
 }
 
+// ScanResponse represents the result of a malware scan of a file
+type ScanResponse struct {
+	FileID    string     `json:"file_id" example:"01912345-6789-7abc-def0-123456789abc"`
+	Status    ScanStatus `json:"status" example:"CLEAN"`
+	Engine    string     `json:"engine,omitempty" example:"ClamAV 1.3.1/27400/Mon Sep 30 08:35:30 2024"`
+	Signature string     `json:"signature,omitempty" example:"Eicar-Test-Signature"`
+	ScannedAt *time.Time `json:"scanned_at,omitempty" example:"2024-12-05T00:00:00Z"`
+}
+
+// RetentionRuleResponse represents a retention rule response for API
+type RetentionRuleResponse struct {
+	ID            string    `json:"id" example:"01912345-6789-7abc-def0-123456789abc"`
+	Name          string    `json:"name" example:"Temporary exports"`
+	MimeType      *string   `json:"mime_type,omitempty" example:"application/zip"`
+	MetadataKey   *string   `json:"metadata_key,omitempty" example:"category"`
+	MetadataValue *string   `json:"metadata_value,omitempty" example:"export"`
+	RetainDays    int       `json:"retain_days" example:"90"`
+	IsActive      bool      `json:"is_active" example:"true"`
+	CreatedAt     time.Time `json:"created_at" example:"2024-12-05T00:00:00Z"`
+}
+
+// RetentionRuleListResponse represents the list of retention rules
+type RetentionRuleListResponse struct {
+	Data []RetentionRuleResponse `json:"data"`
+}
+
+// PurgeReport summarizes a run of the retention purger
+type PurgeReport struct {
+	ExpiredFiles   int       `json:"expired_files" example:"3"`
+	PurgedFiles    int       `json:"purged_files" example:"12"`
+	ReclaimedBytes int64     `json:"reclaimed_bytes" example:"104857600"`
+	Failures       int       `json:"failures" example:"0"`
+	StartedAt      time.Time `json:"started_at" example:"2024-12-05T00:00:00Z"`
+	FinishedAt     time.Time `json:"finished_at" example:"2024-12-05T00:00:02Z"`
+}
+
+// QuotaResponse represents a storage quota and the current usage of its scope
+type QuotaResponse struct {
+	ID             string          `json:"id,omitempty" example:"01912345-6789-7abc-def0-123456789abc"` // Empty for the default user quota
+	ScopeType      QuotaScope      `json:"scope_type" example:"DEPARTMENT"`
+	ScopeID        string          `json:"scope_id" example:"01912345-6789-7abc-def0-123456789abc"`
+	ScopeName      json.RawMessage `json:"scope_name,omitempty" swaggertype:"object"`
+	MaxBytes       int64           `json:"max_bytes" example:"10737418240"`
+	MaxFiles       *int64          `json:"max_files,omitempty" example:"10000"`
+	UsedBytes      int64           `json:"used_bytes" example:"5368709120"`
+	FileCount      int64           `json:"file_count" example:"1234"`
+	RemainingBytes int64           `json:"remaining_bytes" example:"5368709120"`
+	IsDefault      bool            `json:"is_default,omitempty" example:"false"`
+}
+
+// QuotaListResponse represents a list of storage quotas
+type QuotaListResponse struct {
+	Data []QuotaResponse `json:"data"`
+}
+
+// StorageUsageResponse represents the storage usage of the current user and the quotas that apply to them
+type StorageUsageResponse struct {
+	UsedBytes int64           `json:"used_bytes" example:"1048576"`
+	FileCount int64           `json:"file_count" example:"12"`
+	Quotas    []QuotaResponse `json:"quotas"`
+}
+
+// UsageReportEntry represents one consumer in the storage usage report
+type UsageReportEntry struct {
+	ScopeType QuotaScope      `json:"scope_type" example:"USER"`
+	ScopeID   string          `json:"scope_id" example:"01912345-6789-7abc-def0-123456789abc"`
+	ScopeName json.RawMessage `json:"scope_name,omitempty" swaggertype:"object"`
+	UsedBytes int64           `json:"used_bytes" example:"5368709120"`
+	FileCount int64           `json:"file_count" example:"1234"`
+	MaxBytes  *int64          `json:"max_bytes,omitempty" example:"10737418240"`
+}
+
+// UsageReportResponse represents the top storage consumers of a scope type
+type UsageReportResponse struct {
+	ScopeType QuotaScope         `json:"scope_type" example:"USER"`
+	Data      []UsageReportEntry `json:"data"`
+}
+
+// ShareLinkResponse represents a share link response for API
+type ShareLinkResponse struct {
+	ID                string     `json:"id" example:"01912345-6789-7abc-def0-123456789abc"`
+	FileID            string     `json:"file_id" example:"01912345-6789-7abc-def0-123456789abc"`
+	URL               string     `json:"url,omitempty" example:"/public/files/shared/01912345-6789-7abc-def0-123456789abc?expires=1733443200&signature=abc123"`
+	ExpiresAt         time.Time  `json:"expires_at" example:"2024-12-06T00:00:00Z"`
+	MaxDownloads      *int64     `json:"max_downloads,omitempty" example:"10"`
+	DownloadCount     int64      `json:"download_count" example:"3"`
+	PasswordProtected bool       `json:"password_protected" example:"false"`
+	IsRevoked         bool       `json:"is_revoked" example:"false"`
+	LastUsedAt        *time.Time `json:"last_used_at,omitempty" example:"2024-12-05T12:00:00Z"`
+	CreatedAt         time.Time  `json:"created_at" example:"2024-12-05T00:00:00Z"`
+}
+
+// FileShareResponse represents an access grant on a file
+type FileShareResponse struct {
+	ID          string          `json:"id" example:"01912345-6789-7abc-def0-123456789abc"`
+	FileID      string          `json:"file_id" example:"01912345-6789-7abc-def0-123456789abc"`
+	GranteeType QuotaScope      `json:"grantee_type" example:"GROUP"`
+	GranteeID   string          `json:"grantee_id" example:"01912345-6789-7abc-def0-123456789abc"`
+	GranteeName json.RawMessage `json:"grantee_name,omitempty" swaggertype:"object"`
+	AccessLevel AccessLevel     `json:"access_level" example:"READ"`
+	CreatedAt   time.Time       `json:"created_at" example:"2024-12-05T00:00:00Z"`
+	UpdatedAt   time.Time       `json:"updated_at" example:"2024-12-05T00:00:00Z"`
+}
+
+// FileShareListResponse represents the access grants of a file
+type FileShareListResponse struct {
+	Data []FileShareResponse `json:"data"`
+}
+
+// ShareLinkListResponse represents the list of share links of a file
+type ShareLinkListResponse struct {
+	Data []ShareLinkResponse `json:"data"`
+}
+
 // -------------------- Request DTOs --------------------
 
 // UpdateFileMetadataRequest represents the request to update file metadata
@@ -122,8 +448,115 @@ type UpdateFileMetadataRequest struct {
 	Metadata json.RawMessage `json:"metadata" swaggertype:"object"`
 }
 
+// CreateShareLinkRequest represents the request to create a share link
+type CreateShareLinkRequest struct {
+	ExpiresIn    *int64  `json:"expires_in,omitempty" example:"86400"` // Lifetime in seconds (default 1 day, max 30 days)
+	MaxDownloads *int64  `json:"max_downloads,omitempty" example:"10"`
+	Password     *string `json:"password,omitempty" example:"s3cret"`
+}
+
+// ShareFileRequest represents the request to grant a user, group or department access to a file.
+// Sharing again with the same grantee replaces its access level.
+type ShareFileRequest struct {
+	GranteeType QuotaScope  `json:"grantee_type" example:"GROUP"`
+	GranteeID   string      `json:"grantee_id" example:"01912345-6789-7abc-def0-123456789abc"`
+	AccessLevel AccessLevel `json:"access_level" example:"READ"`
+}
+
+// CreateArchiveRequest represents the request to download several files as a ZIP archive
+type CreateArchiveRequest struct {
+	FileIDs []string `json:"file_ids" example:"01912345-6789-7abc-def0-123456789abc,01912345-6789-7abc-def0-123456789abd"`
+}
+
+// SetQuotaRequest represents the request to create or replace the quota of a user, department or group
+type SetQuotaRequest struct {
+	ScopeType QuotaScope `json:"scope_type" example:"DEPARTMENT"`
+	ScopeID   string     `json:"scope_id" example:"01912345-6789-7abc-def0-123456789abc"`
+	MaxBytes  int64      `json:"max_bytes" example:"10737418240"`
+	MaxFiles  *int64     `json:"max_files,omitempty" example:"10000"`
+}
+
+// CreateRetentionRuleRequest represents the request to create a retention rule
+type CreateRetentionRuleRequest struct {
+	Name          string  `json:"name" example:"Temporary exports"`
+	MimeType      *string `json:"mime_type,omitempty" example:"application/zip"` // Exact type or wildcard such as "image/*"
+	MetadataKey   *string `json:"metadata_key,omitempty" example:"category"`
+	MetadataValue *string `json:"metadata_value,omitempty" example:"export"` // Requires metadata_key
+	RetainDays    int     `json:"retain_days" example:"90"`
+}
+
+// SetLegalHoldRequest represents the request to place or release a legal hold on a file
+type SetLegalHoldRequest struct {
+	LegalHold bool `json:"legal_hold" example:"true"`
+}
+
+// SharedFileDownloadRequest represents the request body for downloading a password-protected shared file
+type SharedFileDownloadRequest struct {
+	Password string `json:"password" example:"s3cret"`
+}
+
 // -------------------- Conversion Methods --------------------
 
+// ToResponse converts a StorageQuota to QuotaResponse
+func (q *StorageQuota) ToResponse() QuotaResponse {
+	resp := QuotaResponse{
+		ID:             q.PublicID,
+		ScopeType:      q.ScopeType,
+		ScopeID:        q.ScopePublicID,
+		ScopeName:      q.ScopeName,
+		MaxBytes:       q.MaxBytes,
+		UsedBytes:      q.UsedBytes,
+		FileCount:      q.FileCount,
+		RemainingBytes: max(q.MaxBytes-q.UsedBytes, 0),
+	}
+
+	if q.MaxFiles.Valid {
+		resp.MaxFiles = &q.MaxFiles.Int64
+	}
+
+	return resp
+}
+
+// ToResponse converts a RetentionRule to RetentionRuleResponse
+func (r *RetentionRule) ToResponse() RetentionRuleResponse {
+	resp := RetentionRuleResponse{
+		ID:         r.PublicID,
+		Name:       r.Name,
+		RetainDays: r.RetainDays,
+		IsActive:   r.IsActive,
+		CreatedAt:  r.CreatedAt,
+	}
+
+	if r.MimeType.Valid {
+		resp.MimeType = &r.MimeType.String
+	}
+	if r.MetadataKey.Valid {
+		resp.MetadataKey = &r.MetadataKey.String
+	}
+	if r.MetadataValue.Valid {
+		resp.MetadataValue = &r.MetadataValue.String
+	}
+
+	return resp
+}
+
+// ToReportEntry converts a StorageUsage to UsageReportEntry
+func (u *StorageUsage) ToReportEntry() UsageReportEntry {
+	entry := UsageReportEntry{
+		ScopeType: u.ScopeType,
+		ScopeID:   u.ScopePublicID,
+		ScopeName: u.ScopeName,
+		UsedBytes: u.UsedBytes,
+		FileCount: u.FileCount,
+	}
+
+	if u.MaxBytes.Valid {
+		entry.MaxBytes = &u.MaxBytes.Int64
+	}
+
+	return entry
+}
+
 // ToResponse converts a File to FileResponse
 func (f *File) ToResponse(uploaderPublicID *string, uploaderName json.RawMessage) FileResponse {
 	resp := FileResponse{
@@ -166,3 +599,41 @@ func (f *File) ToUploadResponse() FileUploadResponse {
 		Message:          "File uploaded successfully",
 	}
 }
+
+// ToResponse converts a FileShare to FileShareResponse
+func (s *FileShare) ToResponse(filePublicID string) FileShareResponse {
+	return FileShareResponse{
+		ID:          s.PublicID,
+		FileID:      filePublicID,
+		GranteeType: s.GranteeType,
+		GranteeID:   s.GranteePublicID,
+		GranteeName: s.GranteeName,
+		AccessLevel: s.AccessLevel,
+		CreatedAt:   s.CreatedAt,
+		UpdatedAt:   s.UpdatedAt,
+	}
+}
+
+// ToResponse converts a FileShareLink to ShareLinkResponse with the given signed URL
+func (l *FileShareLink) ToResponse(filePublicID, url string) ShareLinkResponse {
+	resp := ShareLinkResponse{
+		ID:                l.PublicID,
+		FileID:            filePublicID,
+		URL:               url,
+		ExpiresAt:         l.ExpiresAt,
+		DownloadCount:     l.DownloadCount,
+		PasswordProtected: l.PasswordHash.Valid,
+		IsRevoked:         l.IsRevoked,
+		CreatedAt:         l.CreatedAt,
+	}
+
+	if l.MaxDownloads.Valid {
+		resp.MaxDownloads = &l.MaxDownloads.Int64
+	}
+
+	if l.LastUsedAt.Valid {
+		resp.LastUsedAt = &l.LastUsedAt.Time
+	}
+
+	return resp
+}
@@ -0,0 +1,62 @@
+package files
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// jobQueue runs file jobs in a bounded pool of background workers.
+// Jobs are dropped (with a warning) when the queue is full so callers never block.
+type jobQueue struct {
+	name    string
+	jobs    chan *File
+	timeout time.Duration
+	handle  func(ctx context.Context, file *File) error
+}
+
+// newJobQueue creates a job queue and starts its workers
+func newJobQueue(name string, workers, queueSize int, timeout time.Duration, handle func(ctx context.Context, file *File) error) *jobQueue {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 1 {
+		queueSize = 1
+	}
+
+	q := &jobQueue{
+		name:    name,
+		jobs:    make(chan *File, queueSize),
+		timeout: timeout,
+		handle:  handle,
+	}
+
+	for i := 0; i < workers; i++ {
+		go q.worker()
+	}
+
+	return q
+}
+
+// Enqueue schedules a job for a file without blocking.
+// Returns false if the queue is full.
+func (q *jobQueue) Enqueue(file *File) bool {
+	select {
+	case q.jobs <- file:
+		return true
+	default:
+		log.Printf("[WARN] %s queue full, skipping file %s", q.name, file.PublicID)
+		return false
+	}
+}
+
+// worker processes jobs until the queue is closed
+func (q *jobQueue) worker() {
+	for file := range q.jobs {
+		ctx, cancel := context.WithTimeout(context.Background(), q.timeout)
+		if err := q.handle(ctx, file); err != nil {
+			log.Printf("[WARN] %s job failed for file %s: %v", q.name, file.PublicID, err)
+		}
+		cancel()
+	}
+}
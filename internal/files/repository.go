@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/lib/pq"
 )
@@ -16,14 +17,55 @@ type Repository interface {
 	GetStorageByID(ctx context.Context, id int64) (*FileStorage, error)
 
 	// File operations
-	CreateFile(ctx context.Context, file *File) error
+	CreateFile(ctx context.Context, file *File, checkQuota QuotaCheck) error
 	GetFileByPublicID(ctx context.Context, publicID string) (*File, error)
 	GetFileByID(ctx context.Context, id int64) (*File, error)
 	GetFileDetailByPublicID(ctx context.Context, publicID string) (*FileResponse, error)
 	ListFilesByUploader(ctx context.Context, uploaderID int64, page, limit int) ([]FileResponse, int, error)
+	ListFilesSharedWithUser(ctx context.Context, userID int64, page, limit int) ([]FileResponse, int, error)
 	UpdateFileMetadata(ctx context.Context, publicID string, metadata json.RawMessage) error
 	IncrementDownloadCount(ctx context.Context, publicID string) error
 	SoftDeleteFile(ctx context.Context, publicID string) error
+	UpdateFileScanStatus(ctx context.Context, id int64, relativePath string, scanInfo json.RawMessage) error
+	ListUnscannedFiles(ctx context.Context, createdBefore time.Time, limit int) ([]File, error)
+
+	// Derivative operations
+	UpsertFileDerivative(ctx context.Context, derivative *FileDerivative) error
+	GetFileDerivative(ctx context.Context, fileID int64, kind DerivativeKind, variant string) (*FileDerivative, error)
+	ListFileDerivatives(ctx context.Context, fileID int64) ([]FileDerivative, error)
+	DeleteFileDerivatives(ctx context.Context, fileID int64) error
+
+	// Quota operations
+	GetUserStorageUsage(ctx context.Context, userID int64) (usedBytes, fileCount int64, err error)
+	ListQuotasForUser(ctx context.Context, userID int64) ([]StorageQuota, error)
+	ListQuotas(ctx context.Context, scopeType QuotaScope) ([]StorageQuota, error)
+	UpsertQuota(ctx context.Context, quota *StorageQuota) error
+	DeleteQuota(ctx context.Context, publicID string) error
+	ListTopStorageConsumers(ctx context.Context, scopeType QuotaScope, limit int) ([]StorageUsage, error)
+	GetScopeInternalID(ctx context.Context, scopeType QuotaScope, publicID string) (int64, error)
+
+	// Retention operations
+	SetLegalHold(ctx context.Context, publicID string, hold bool) error
+	CreateRetentionRule(ctx context.Context, rule *RetentionRule) error
+	ListRetentionRules(ctx context.Context) ([]RetentionRule, error)
+	DeleteRetentionRule(ctx context.Context, publicID string) error
+	ListExpiredFiles(ctx context.Context, limit int) ([]PurgeCandidate, error)
+	ClaimPurgeableFiles(ctx context.Context, deletedBefore time.Time, limit int) ([]PurgeCandidate, error)
+	UnclaimPurgedFile(ctx context.Context, fileID int64) error
+	CreatePurgeLog(ctx context.Context, entry *FilePurgeLog) error
+
+	// Access control operations
+	UpsertFileShare(ctx context.Context, share *FileShare) error
+	ListFileShares(ctx context.Context, fileID int64) ([]FileShare, error)
+	DeleteFileShare(ctx context.Context, fileID int64, publicID string) error
+	GetSharedAccessLevel(ctx context.Context, fileID int64, userID int64) (AccessLevel, error)
+
+	// Share link operations
+	CreateShareLink(ctx context.Context, link *FileShareLink) error
+	GetShareLinkByPublicID(ctx context.Context, publicID string) (*FileShareLink, error)
+	ListShareLinksByFileID(ctx context.Context, fileID int64) ([]FileShareLink, error)
+	RevokeShareLink(ctx context.Context, fileID int64, publicID string) error
+	IncrementShareLinkDownloadCount(ctx context.Context, id int64) error
 
 	// Helper operations
 	GetUserInternalID(ctx context.Context, publicID string) (int64, error)
@@ -111,7 +153,31 @@ func (r *repository) GetStorageByID(ctx context.Context, id int64) (*FileStorage
 
 // -------------------- File Operations --------------------
 
-func (r *repository) CreateFile(ctx context.Context, file *File) error {
+// QuotaCheck returns ErrQuotaExceeded if a new file does not fit the storage usage of its uploader
+// and the quotas applying to them
+type QuotaCheck func(usedBytes, fileCount int64, quotas []StorageQuota) error
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// CreateFile inserts a file record and adds its size to the uploader's storage usage.
+// If a quota check is given, the uploader's usage row and quotas are locked and the check runs
+// in the same transaction, so concurrent uploads cannot together exceed a quota.
+func (r *repository) CreateFile(ctx context.Context, file *File, checkQuota QuotaCheck) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if file.UploadedBy.Valid && checkQuota != nil {
+		if err := r.checkQuotaForUpdate(ctx, tx, file.UploadedBy.Int64, checkQuota); err != nil {
+			return err
+		}
+	}
+
 	query := `
 		INSERT INTO managements.files (
 			storage_id, relative_path, original_filename, mime_type, file_size,
@@ -119,7 +185,7 @@ func (r *repository) CreateFile(ctx context.Context, file *File) error {
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id, public_id, download_count, created_at, updated_at`
 
-	return r.db.QueryRowContext(ctx, query,
+	err = tx.QueryRowContext(ctx, query,
 		file.StorageID,
 		file.RelativePath,
 		file.OriginalFilename,
@@ -130,13 +196,68 @@ func (r *repository) CreateFile(ctx context.Context, file *File) error {
 		file.Metadata,
 		file.IsPublic,
 	).Scan(&file.ID, &file.PublicID, &file.DownloadCount, &file.CreatedAt, &file.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if file.UploadedBy.Valid {
+		usageQuery := `
+			INSERT INTO managements.file_usage (user_id, used_bytes, file_count)
+			VALUES ($1, $2, 1)
+			ON CONFLICT (user_id) DO UPDATE
+			SET used_bytes = managements.file_usage.used_bytes + EXCLUDED.used_bytes,
+			    file_count = managements.file_usage.file_count + 1,
+			    updated_at = CURRENT_TIMESTAMP`
+
+		if _, err := tx.ExecContext(ctx, usageQuery, file.UploadedBy.Int64, file.FileSize); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// checkQuotaForUpdate locks the storage usage of a user and the quotas applying to them, then runs the check.
+// Uploads of the same user, department or group wait for each other until the transaction ends.
+func (r *repository) checkQuotaForUpdate(ctx context.Context, tx *sql.Tx, userID int64, checkQuota QuotaCheck) error {
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO managements.file_usage (user_id, used_bytes, file_count)
+		VALUES ($1, 0, 0)
+		ON CONFLICT (user_id) DO NOTHING`, userID); err != nil {
+		return err
+	}
+
+	var usedBytes, fileCount int64
+	err := tx.QueryRowContext(ctx, `
+		SELECT used_bytes, file_count FROM managements.file_usage WHERE user_id = $1 FOR UPDATE`,
+		userID).Scan(&usedBytes, &fileCount)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		SELECT q.id FROM managements.file_quotas q
+		WHERE `+userQuotaCondition+`
+		ORDER BY q.id
+		FOR UPDATE`, userID); err != nil {
+		return err
+	}
+
+	// Usage of departments and groups is read after the locks, so it includes every committed upload
+	quotas, err := r.queryQuotas(ctx, tx, quotaSelectQuery+`
+	WHERE `+userQuotaCondition, userID)
+	if err != nil {
+		return err
+	}
+
+	return checkQuota(usedBytes, fileCount, quotas)
 }
 
 func (r *repository) GetFileByPublicID(ctx context.Context, publicID string) (*File, error) {
 	query := `
 		SELECT id, public_id, storage_id, relative_path, original_filename, mime_type, file_size,
 		       checksum_sha256, uploaded_by, metadata, download_count, last_accessed_at,
-		       is_public, is_deleted, deleted_at, created_at, updated_at
+		       is_public, legal_hold, is_deleted, deleted_at, created_at, updated_at
 		FROM managements.files
 		WHERE public_id = $1 AND is_deleted = false`
 
@@ -155,6 +276,7 @@ func (r *repository) GetFileByPublicID(ctx context.Context, publicID string) (*F
 		&file.DownloadCount,
 		&file.LastAccessedAt,
 		&file.IsPublic,
+		&file.LegalHold,
 		&file.IsDeleted,
 		&file.DeletedAt,
 		&file.CreatedAt,
@@ -171,7 +293,7 @@ func (r *repository) GetFileByID(ctx context.Context, id int64) (*File, error) {
 	query := `
 		SELECT id, public_id, storage_id, relative_path, original_filename, mime_type, file_size,
 		       checksum_sha256, uploaded_by, metadata, download_count, last_accessed_at,
-		       is_public, is_deleted, deleted_at, created_at, updated_at
+		       is_public, legal_hold, is_deleted, deleted_at, created_at, updated_at
 		FROM managements.files
 		WHERE id = $1 AND is_deleted = false`
 
@@ -190,6 +312,7 @@ func (r *repository) GetFileByID(ctx context.Context, id int64) (*File, error) {
 		&file.DownloadCount,
 		&file.LastAccessedAt,
 		&file.IsPublic,
+		&file.LegalHold,
 		&file.IsDeleted,
 		&file.DeletedAt,
 		&file.CreatedAt,
@@ -274,10 +397,63 @@ func (r *repository) ListFilesByUploader(ctx context.Context, uploaderID int64,
 		ORDER BY f.created_at DESC
 		LIMIT $2 OFFSET $3`
 
-	rows, err := r.db.QueryContext(ctx, query, uploaderID, limit, offset)
+	files, err := r.queryFileResponses(ctx, query, uploaderID, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
+
+	return files, totalCount, nil
+}
+
+// shareGranteeCondition matches file shares s granted to user $1 directly, through one of their groups
+// or through their department
+const shareGranteeCondition = `(
+	(s.grantee_type = 'USER' AND s.grantee_id = $1)
+	OR (s.grantee_type = 'GROUP' AND s.grantee_id IN (SELECT group_id FROM organizations.group_users WHERE user_id = $1))
+	OR (s.grantee_type = 'DEPARTMENT' AND s.grantee_id = (SELECT dept_id FROM organizations.users WHERE id = $1))
+)`
+
+// sharedWithUserCondition matches files f shared with user $1
+const sharedWithUserCondition = `
+	EXISTS (SELECT 1 FROM managements.file_shares s WHERE s.file_id = f.id AND ` + shareGranteeCondition + `)`
+
+// ListFilesSharedWithUser lists the files other users shared with the user, directly or through a group or department
+func (r *repository) ListFilesSharedWithUser(ctx context.Context, userID int64, page, limit int) ([]FileResponse, int, error) {
+	offset := (page - 1) * limit
+
+	var totalCount int
+	countQuery := `
+		SELECT COUNT(*) FROM managements.files f
+		WHERE f.is_deleted = false AND f.uploaded_by IS DISTINCT FROM $1 AND` + sharedWithUserCondition
+	if err := r.db.QueryRowContext(ctx, countQuery, userID).Scan(&totalCount); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT
+			f.public_id, f.original_filename, f.mime_type, f.file_size, f.checksum_sha256,
+			f.download_count, f.is_public, f.metadata, f.last_accessed_at, f.created_at, f.updated_at,
+			u.public_id, u.name
+		FROM managements.files f
+		LEFT JOIN organizations.users u ON f.uploaded_by = u.id
+		WHERE f.is_deleted = false AND f.uploaded_by IS DISTINCT FROM $1 AND` + sharedWithUserCondition + `
+		ORDER BY f.created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	files, err := r.queryFileResponses(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return files, totalCount, nil
+}
+
+// queryFileResponses runs a file list query selecting the FileResponse columns and the uploader
+func (r *repository) queryFileResponses(ctx context.Context, query string, args ...interface{}) ([]FileResponse, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	var files []FileResponse
@@ -302,7 +478,7 @@ func (r *repository) ListFilesByUploader(ctx context.Context, uploaderID int64,
 			&uploaderPublicID,
 			&uploaderName,
 		); err != nil {
-			return nil, 0, err
+			return nil, err
 		}
 
 		resp.DownloadURL = "/files/" + resp.ID + "/download"
@@ -321,7 +497,7 @@ func (r *repository) ListFilesByUploader(ctx context.Context, uploaderID int64,
 		files = append(files, resp)
 	}
 
-	return files, totalCount, rows.Err()
+	return files, rows.Err()
 }
 
 func (r *repository) UpdateFileMetadata(ctx context.Context, publicID string, metadata json.RawMessage) error {
@@ -369,12 +545,241 @@ func (r *repository) IncrementDownloadCount(ctx context.Context, publicID string
 	return nil
 }
 
+// SoftDeleteFile marks a file as deleted and releases its size from the uploader's storage usage
 func (r *repository) SoftDeleteFile(ctx context.Context, publicID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE managements.files
 		SET is_deleted = true,
 		    deleted_at = CURRENT_TIMESTAMP
-		WHERE public_id = $1 AND is_deleted = false`
+		WHERE public_id = $1 AND is_deleted = false
+		RETURNING uploaded_by, file_size`
+
+	var uploadedBy sql.NullInt64
+	var fileSize int64
+	if err := tx.QueryRowContext(ctx, query, publicID).Scan(&uploadedBy, &fileSize); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("file not found or already deleted")
+		}
+		return err
+	}
+
+	if uploadedBy.Valid {
+		usageQuery := `
+			UPDATE managements.file_usage
+			SET used_bytes = GREATEST(used_bytes - $2, 0),
+			    file_count = GREATEST(file_count - 1, 0),
+			    updated_at = CURRENT_TIMESTAMP
+			WHERE user_id = $1`
+
+		if _, err := tx.ExecContext(ctx, usageQuery, uploadedBy.Int64, fileSize); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UpdateFileScanStatus records the malware scan result in the file metadata and the
+// (possibly quarantined) storage path in a single statement, leaving other metadata keys untouched
+func (r *repository) UpdateFileScanStatus(ctx context.Context, id int64, relativePath string, scanInfo json.RawMessage) error {
+	query := `
+		UPDATE managements.files
+		SET relative_path = $2,
+		    metadata = jsonb_set(COALESCE(metadata::jsonb, '{}'::jsonb), '{malware_scan}', $3::jsonb)
+		WHERE id = $1 AND is_deleted = false`
+
+	result, err := r.db.ExecContext(ctx, query, id, relativePath, scanInfo)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ListUnscannedFiles returns live files uploaded before the given time whose malware scan is
+// still pending or has failed. Files never tried come first, then the longest since their last attempt.
+func (r *repository) ListUnscannedFiles(ctx context.Context, createdBefore time.Time, limit int) ([]File, error) {
+	query := `
+		SELECT id, public_id, storage_id, relative_path, original_filename, mime_type, file_size,
+		       checksum_sha256, uploaded_by, metadata, download_count, last_accessed_at,
+		       is_public, legal_hold, is_deleted, deleted_at, created_at, updated_at
+		FROM managements.files
+		WHERE is_deleted = false
+		  AND created_at < $1
+		  AND metadata::jsonb->'malware_scan'->>'status' IN ('PENDING', 'FAILED')
+		ORDER BY metadata::jsonb->'malware_scan'->>'scanned_at' NULLS FIRST, id
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, createdBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []File
+	for rows.Next() {
+		var file File
+		if err := rows.Scan(
+			&file.ID,
+			&file.PublicID,
+			&file.StorageID,
+			&file.RelativePath,
+			&file.OriginalFilename,
+			&file.MimeType,
+			&file.FileSize,
+			&file.ChecksumSHA256,
+			&file.UploadedBy,
+			&file.Metadata,
+			&file.DownloadCount,
+			&file.LastAccessedAt,
+			&file.IsPublic,
+			&file.LegalHold,
+			&file.IsDeleted,
+			&file.DeletedAt,
+			&file.CreatedAt,
+			&file.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, rows.Err()
+}
+
+// -------------------- Quota Operations --------------------
+
+// quotaSelectQuery selects quotas with the name of their scope and the current usage of the scope.
+// Department and group usage is the sum of the usage of their current members.
+// userQuotaCondition matches the quotas applying to the user $1: their own, their department's and their groups'
+const userQuotaCondition = `(q.scope_type = 'USER' AND q.scope_id = $1)
+	   OR (q.scope_type = 'DEPARTMENT' AND q.scope_id = (SELECT dept_id FROM organizations.users WHERE id = $1))
+	   OR (q.scope_type = 'GROUP' AND q.scope_id IN (SELECT group_id FROM organizations.group_users WHERE user_id = $1))`
+
+const quotaSelectQuery = `
+	SELECT q.id, q.public_id, q.scope_type, q.scope_id, q.max_bytes, q.max_files, q.created_at, q.updated_at,
+	       COALESCE(u.public_id::text, d.public_id::text, g.public_id::text, ''),
+	       COALESCE(u.name::text, d.name::text, g.name::text),
+	       usage.used_bytes, usage.file_count
+	FROM managements.file_quotas q
+	LEFT JOIN organizations.users u ON q.scope_type = 'USER' AND u.id = q.scope_id
+	LEFT JOIN organizations.departments d ON q.scope_type = 'DEPARTMENT' AND d.id = q.scope_id
+	LEFT JOIN organizations.groups g ON q.scope_type = 'GROUP' AND g.id = q.scope_id
+	CROSS JOIN LATERAL (
+		SELECT COALESCE(SUM(fu.used_bytes), 0), COALESCE(SUM(fu.file_count), 0)
+		FROM managements.file_usage fu
+		WHERE (q.scope_type = 'USER' AND fu.user_id = q.scope_id)
+		   OR (q.scope_type = 'DEPARTMENT' AND fu.user_id IN (SELECT id FROM organizations.users WHERE dept_id = q.scope_id))
+		   OR (q.scope_type = 'GROUP' AND fu.user_id IN (SELECT user_id FROM organizations.group_users WHERE group_id = q.scope_id))
+	) AS usage(used_bytes, file_count)`
+
+func (r *repository) GetUserStorageUsage(ctx context.Context, userID int64) (int64, int64, error) {
+	query := `SELECT used_bytes, file_count FROM managements.file_usage WHERE user_id = $1`
+
+	var usedBytes, fileCount int64
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&usedBytes, &fileCount)
+	if err == sql.ErrNoRows {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return usedBytes, fileCount, nil
+}
+
+// ListQuotasForUser returns the quotas of the user, their department and their groups
+func (r *repository) ListQuotasForUser(ctx context.Context, userID int64) ([]StorageQuota, error) {
+	query := quotaSelectQuery + `
+	WHERE ` + userQuotaCondition + `
+	ORDER BY q.scope_type, q.id`
+
+	return r.queryQuotas(ctx, r.db, query, userID)
+}
+
+// ListQuotas returns all quotas, optionally filtered by scope type
+func (r *repository) ListQuotas(ctx context.Context, scopeType QuotaScope) ([]StorageQuota, error) {
+	query := quotaSelectQuery + `
+	WHERE ($1 = '' OR q.scope_type = $1)
+	ORDER BY q.scope_type, usage.used_bytes DESC`
+
+	return r.queryQuotas(ctx, r.db, query, string(scopeType))
+}
+
+func (r *repository) queryQuotas(ctx context.Context, q querier, query string, args ...interface{}) ([]StorageQuota, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var quotas []StorageQuota
+	for rows.Next() {
+		var quota StorageQuota
+		var scopeName sql.NullString
+
+		if err := rows.Scan(
+			&quota.ID,
+			&quota.PublicID,
+			&quota.ScopeType,
+			&quota.ScopeID,
+			&quota.MaxBytes,
+			&quota.MaxFiles,
+			&quota.CreatedAt,
+			&quota.UpdatedAt,
+			&quota.ScopePublicID,
+			&scopeName,
+			&quota.UsedBytes,
+			&quota.FileCount,
+		); err != nil {
+			return nil, err
+		}
+
+		if scopeName.Valid {
+			quota.ScopeName = json.RawMessage(scopeName.String)
+		}
+
+		quotas = append(quotas, quota)
+	}
+
+	return quotas, rows.Err()
+}
+
+// UpsertQuota creates the quota of a scope or replaces its limits if one already exists
+func (r *repository) UpsertQuota(ctx context.Context, quota *StorageQuota) error {
+	query := `
+		INSERT INTO managements.file_quotas (scope_type, scope_id, max_bytes, max_files)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (scope_type, scope_id) DO UPDATE
+		SET max_bytes = EXCLUDED.max_bytes,
+		    max_files = EXCLUDED.max_files,
+		    updated_at = CURRENT_TIMESTAMP
+		RETURNING id, public_id, created_at, updated_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		quota.ScopeType,
+		quota.ScopeID,
+		quota.MaxBytes,
+		quota.MaxFiles,
+	).Scan(&quota.ID, &quota.PublicID, &quota.CreatedAt, &quota.UpdatedAt)
+}
+
+func (r *repository) DeleteQuota(ctx context.Context, publicID string) error {
+	query := `DELETE FROM managements.file_quotas WHERE public_id = $1`
 
 	result, err := r.db.ExecContext(ctx, query, publicID)
 	if err != nil {
@@ -386,7 +791,635 @@ func (r *repository) SoftDeleteFile(ctx context.Context, publicID string) error
 		return err
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("file not found or already deleted")
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ListTopStorageConsumers returns the users, departments or groups using the most storage
+func (r *repository) ListTopStorageConsumers(ctx context.Context, scopeType QuotaScope, limit int) ([]StorageUsage, error) {
+	var query string
+	switch scopeType {
+	case QuotaScopeUser:
+		query = `
+			SELECT u.public_id::text, u.name::text, fu.used_bytes, fu.file_count, q.max_bytes
+			FROM managements.file_usage fu
+			JOIN organizations.users u ON u.id = fu.user_id
+			LEFT JOIN managements.file_quotas q ON q.scope_type = 'USER' AND q.scope_id = u.id
+			WHERE fu.used_bytes > 0
+			ORDER BY fu.used_bytes DESC
+			LIMIT $1`
+	case QuotaScopeDepartment:
+		query = `
+			SELECT d.public_id::text, d.name::text, SUM(fu.used_bytes), SUM(fu.file_count), MAX(q.max_bytes)
+			FROM managements.file_usage fu
+			JOIN organizations.users u ON u.id = fu.user_id
+			JOIN organizations.departments d ON d.id = u.dept_id
+			LEFT JOIN managements.file_quotas q ON q.scope_type = 'DEPARTMENT' AND q.scope_id = d.id
+			GROUP BY d.id
+			HAVING SUM(fu.used_bytes) > 0
+			ORDER BY SUM(fu.used_bytes) DESC
+			LIMIT $1`
+	case QuotaScopeGroup:
+		query = `
+			SELECT g.public_id::text, g.name::text, SUM(fu.used_bytes), SUM(fu.file_count), MAX(q.max_bytes)
+			FROM managements.file_usage fu
+			JOIN organizations.group_users gu ON gu.user_id = fu.user_id
+			JOIN organizations.groups g ON g.id = gu.group_id
+			LEFT JOIN managements.file_quotas q ON q.scope_type = 'GROUP' AND q.scope_id = g.id
+			GROUP BY g.id
+			HAVING SUM(fu.used_bytes) > 0
+			ORDER BY SUM(fu.used_bytes) DESC
+			LIMIT $1`
+	default:
+		return nil, fmt.Errorf("unknown quota scope: %s", scopeType)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var consumers []StorageUsage
+	for rows.Next() {
+		usage := StorageUsage{ScopeType: scopeType}
+		var scopeName sql.NullString
+
+		if err := rows.Scan(
+			&usage.ScopePublicID,
+			&scopeName,
+			&usage.UsedBytes,
+			&usage.FileCount,
+			&usage.MaxBytes,
+		); err != nil {
+			return nil, err
+		}
+
+		if scopeName.Valid {
+			usage.ScopeName = json.RawMessage(scopeName.String)
+		}
+
+		consumers = append(consumers, usage)
+	}
+
+	return consumers, rows.Err()
+}
+
+// GetScopeInternalID resolves the public ID of a user, department or group to its internal ID
+func (r *repository) GetScopeInternalID(ctx context.Context, scopeType QuotaScope, publicID string) (int64, error) {
+	var query string
+	switch scopeType {
+	case QuotaScopeUser:
+		query = `SELECT id FROM organizations.users WHERE public_id = $1 AND is_deleted = false`
+	case QuotaScopeDepartment:
+		query = `SELECT id FROM organizations.departments WHERE public_id = $1 AND is_deleted = false`
+	case QuotaScopeGroup:
+		query = `SELECT id FROM organizations.groups WHERE public_id = $1`
+	default:
+		return 0, fmt.Errorf("unknown quota scope: %s", scopeType)
+	}
+
+	var id int64
+	if err := r.db.QueryRowContext(ctx, query, publicID).Scan(&id); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// -------------------- Retention Operations --------------------
+
+// SetLegalHold places or releases a legal hold on a file, including soft-deleted files that are not purged yet
+func (r *repository) SetLegalHold(ctx context.Context, publicID string, hold bool) error {
+	query := `
+		UPDATE managements.files
+		SET legal_hold = $2
+		WHERE public_id = $1 AND purged_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, publicID, hold)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *repository) CreateRetentionRule(ctx context.Context, rule *RetentionRule) error {
+	query := `
+		INSERT INTO managements.file_retention_rules (name, mime_type, metadata_key, metadata_value, retain_days, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, public_id, created_at, updated_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		rule.Name,
+		rule.MimeType,
+		rule.MetadataKey,
+		rule.MetadataValue,
+		rule.RetainDays,
+		rule.IsActive,
+	).Scan(&rule.ID, &rule.PublicID, &rule.CreatedAt, &rule.UpdatedAt)
+}
+
+func (r *repository) ListRetentionRules(ctx context.Context) ([]RetentionRule, error) {
+	query := `
+		SELECT id, public_id, name, mime_type, metadata_key, metadata_value, retain_days, is_active, created_at, updated_at
+		FROM managements.file_retention_rules
+		ORDER BY created_at`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []RetentionRule
+	for rows.Next() {
+		var rule RetentionRule
+		if err := rows.Scan(
+			&rule.ID,
+			&rule.PublicID,
+			&rule.Name,
+			&rule.MimeType,
+			&rule.MetadataKey,
+			&rule.MetadataValue,
+			&rule.RetainDays,
+			&rule.IsActive,
+			&rule.CreatedAt,
+			&rule.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+func (r *repository) DeleteRetentionRule(ctx context.Context, publicID string) error {
+	query := `DELETE FROM managements.file_retention_rules WHERE public_id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, publicID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ListExpiredFiles returns live files older than the longest retention of the active rules matching them.
+// Files under legal hold and files matching no rule never expire.
+func (r *repository) ListExpiredFiles(ctx context.Context, limit int) ([]PurgeCandidate, error) {
+	query := `
+		SELECT f.id, f.public_id, f.relative_path, f.file_size, rule.id
+		FROM managements.files f
+		CROSS JOIN LATERAL (
+			SELECT rr.id, rr.retain_days
+			FROM managements.file_retention_rules rr
+			WHERE rr.is_active
+			  AND (rr.mime_type IS NULL
+			       OR rr.mime_type = f.mime_type
+			       OR (rr.mime_type LIKE '%/*' AND f.mime_type LIKE replace(rr.mime_type, '*', '%')))
+			  AND (rr.metadata_key IS NULL
+			       OR (f.metadata::jsonb ? rr.metadata_key
+			           AND (rr.metadata_value IS NULL OR f.metadata::jsonb ->> rr.metadata_key = rr.metadata_value)))
+			ORDER BY rr.retain_days DESC
+			LIMIT 1
+		) rule
+		WHERE f.is_deleted = false
+		  AND f.legal_hold = false
+		  AND f.created_at < CURRENT_TIMESTAMP - make_interval(days => rule.retain_days)
+		ORDER BY f.created_at
+		LIMIT $1`
+
+	return r.queryPurgeCandidates(ctx, query, limit)
+}
+
+// ClaimPurgeableFiles marks soft-deleted files whose grace period has passed as purged and returns them.
+// Rows are locked with SKIP LOCKED so concurrent purgers never claim the same file.
+func (r *repository) ClaimPurgeableFiles(ctx context.Context, deletedBefore time.Time, limit int) ([]PurgeCandidate, error) {
+	query := `
+		UPDATE managements.files
+		SET purged_at = CURRENT_TIMESTAMP
+		WHERE id IN (
+			SELECT id FROM managements.files
+			WHERE is_deleted = true
+			  AND purged_at IS NULL
+			  AND legal_hold = false
+			  AND deleted_at < $1
+			ORDER BY deleted_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, public_id, relative_path, file_size, NULL::bigint`
+
+	return r.queryPurgeCandidates(ctx, query, deletedBefore, limit)
+}
+
+// UnclaimPurgedFile clears the purge mark of a file whose blob could not be removed
+func (r *repository) UnclaimPurgedFile(ctx context.Context, fileID int64) error {
+	query := `UPDATE managements.files SET purged_at = NULL WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, fileID)
+	return err
+}
+
+func (r *repository) queryPurgeCandidates(ctx context.Context, query string, args ...interface{}) ([]PurgeCandidate, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []PurgeCandidate
+	for rows.Next() {
+		var candidate PurgeCandidate
+		if err := rows.Scan(
+			&candidate.FileID,
+			&candidate.PublicID,
+			&candidate.RelativePath,
+			&candidate.FileSize,
+			&candidate.RuleID,
+		); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates, rows.Err()
+}
+
+func (r *repository) CreatePurgeLog(ctx context.Context, entry *FilePurgeLog) error {
+	query := `
+		INSERT INTO managements.file_purge_log (file_id, action, rule_id, reclaimed_bytes, detail)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		entry.FileID,
+		entry.Action,
+		entry.RuleID,
+		entry.ReclaimedBytes,
+		entry.Detail,
+	).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+// -------------------- Derivative Operations --------------------
+
+func (r *repository) UpsertFileDerivative(ctx context.Context, derivative *FileDerivative) error {
+	query := `
+		INSERT INTO managements.file_derivatives (
+			file_id, kind, variant, relative_path, mime_type, file_size, width, height
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (file_id, kind, variant) DO UPDATE
+		SET relative_path = EXCLUDED.relative_path,
+		    mime_type = EXCLUDED.mime_type,
+		    file_size = EXCLUDED.file_size,
+		    width = EXCLUDED.width,
+		    height = EXCLUDED.height,
+		    created_at = CURRENT_TIMESTAMP
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		derivative.FileID,
+		derivative.Kind,
+		derivative.Variant,
+		derivative.RelativePath,
+		derivative.MimeType,
+		derivative.FileSize,
+		derivative.Width,
+		derivative.Height,
+	).Scan(&derivative.ID, &derivative.CreatedAt)
+}
+
+func (r *repository) GetFileDerivative(ctx context.Context, fileID int64, kind DerivativeKind, variant string) (*FileDerivative, error) {
+	query := `
+		SELECT id, file_id, kind, variant, relative_path, mime_type, file_size, width, height, created_at
+		FROM managements.file_derivatives
+		WHERE file_id = $1 AND kind = $2 AND variant = $3`
+
+	derivative := &FileDerivative{}
+	err := r.db.QueryRowContext(ctx, query, fileID, kind, variant).Scan(
+		&derivative.ID,
+		&derivative.FileID,
+		&derivative.Kind,
+		&derivative.Variant,
+		&derivative.RelativePath,
+		&derivative.MimeType,
+		&derivative.FileSize,
+		&derivative.Width,
+		&derivative.Height,
+		&derivative.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return derivative, nil
+}
+
+func (r *repository) ListFileDerivatives(ctx context.Context, fileID int64) ([]FileDerivative, error) {
+	query := `
+		SELECT id, file_id, kind, variant, relative_path, mime_type, file_size, width, height, created_at
+		FROM managements.file_derivatives
+		WHERE file_id = $1
+		ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var derivatives []FileDerivative
+	for rows.Next() {
+		var derivative FileDerivative
+		if err := rows.Scan(
+			&derivative.ID,
+			&derivative.FileID,
+			&derivative.Kind,
+			&derivative.Variant,
+			&derivative.RelativePath,
+			&derivative.MimeType,
+			&derivative.FileSize,
+			&derivative.Width,
+			&derivative.Height,
+			&derivative.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		derivatives = append(derivatives, derivative)
+	}
+
+	return derivatives, rows.Err()
+}
+
+func (r *repository) DeleteFileDerivatives(ctx context.Context, fileID int64) error {
+	query := `DELETE FROM managements.file_derivatives WHERE file_id = $1`
+	_, err := r.db.ExecContext(ctx, query, fileID)
+	return err
+}
+
+// -------------------- Access Control Operations --------------------
+
+// UpsertFileShare grants a principal access to a file, or replaces the access level of an existing grant
+func (r *repository) UpsertFileShare(ctx context.Context, share *FileShare) error {
+	query := `
+		INSERT INTO managements.file_shares (file_id, grantee_type, grantee_id, access_level, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (file_id, grantee_type, grantee_id) DO UPDATE
+		SET access_level = EXCLUDED.access_level,
+		    updated_at = CURRENT_TIMESTAMP
+		RETURNING id, public_id, created_at, updated_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		share.FileID,
+		share.GranteeType,
+		share.GranteeID,
+		share.AccessLevel,
+		share.CreatedBy,
+	).Scan(&share.ID, &share.PublicID, &share.CreatedAt, &share.UpdatedAt)
+}
+
+func (r *repository) ListFileShares(ctx context.Context, fileID int64) ([]FileShare, error) {
+	query := `
+		SELECT s.id, s.public_id, s.file_id, s.grantee_type, s.grantee_id, s.access_level, s.created_by,
+		       s.created_at, s.updated_at,
+		       COALESCE(u.public_id::text, d.public_id::text, g.public_id::text, ''),
+		       COALESCE(u.name::text, d.name::text, g.name::text)
+		FROM managements.file_shares s
+		LEFT JOIN organizations.users u ON s.grantee_type = 'USER' AND u.id = s.grantee_id
+		LEFT JOIN organizations.departments d ON s.grantee_type = 'DEPARTMENT' AND d.id = s.grantee_id
+		LEFT JOIN organizations.groups g ON s.grantee_type = 'GROUP' AND g.id = s.grantee_id
+		WHERE s.file_id = $1
+		ORDER BY s.created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shares []FileShare
+	for rows.Next() {
+		var share FileShare
+		var granteeName sql.NullString
+
+		if err := rows.Scan(
+			&share.ID,
+			&share.PublicID,
+			&share.FileID,
+			&share.GranteeType,
+			&share.GranteeID,
+			&share.AccessLevel,
+			&share.CreatedBy,
+			&share.CreatedAt,
+			&share.UpdatedAt,
+			&share.GranteePublicID,
+			&granteeName,
+		); err != nil {
+			return nil, err
+		}
+
+		if granteeName.Valid {
+			share.GranteeName = json.RawMessage(granteeName.String)
+		}
+
+		shares = append(shares, share)
+	}
+
+	return shares, rows.Err()
+}
+
+func (r *repository) DeleteFileShare(ctx context.Context, fileID int64, publicID string) error {
+	query := `DELETE FROM managements.file_shares WHERE file_id = $1 AND public_id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, fileID, publicID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetSharedAccessLevel returns the highest access level shared with the user on a file, directly or
+// through one of their groups or their department. Returns "" if nothing is shared with the user.
+func (r *repository) GetSharedAccessLevel(ctx context.Context, fileID int64, userID int64) (AccessLevel, error) {
+	// 'WRITE' sorts after 'READ', so MAX picks the highest level
+	query := `
+		SELECT COALESCE(MAX(s.access_level), '')
+		FROM managements.file_shares s
+		WHERE s.file_id = $2 AND ` + shareGranteeCondition
+
+	var level AccessLevel
+	if err := r.db.QueryRowContext(ctx, query, userID, fileID).Scan(&level); err != nil {
+		return "", err
+	}
+
+	return level, nil
+}
+
+// -------------------- Share Link Operations --------------------
+
+func (r *repository) CreateShareLink(ctx context.Context, link *FileShareLink) error {
+	query := `
+		INSERT INTO managements.file_share_links (
+			file_id, created_by, expires_at, max_downloads, password_hash
+		) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, public_id, download_count, is_revoked, created_at, updated_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		link.FileID,
+		link.CreatedBy,
+		link.ExpiresAt,
+		link.MaxDownloads,
+		link.PasswordHash,
+	).Scan(&link.ID, &link.PublicID, &link.DownloadCount, &link.IsRevoked, &link.CreatedAt, &link.UpdatedAt)
+}
+
+func (r *repository) GetShareLinkByPublicID(ctx context.Context, publicID string) (*FileShareLink, error) {
+	query := `
+		SELECT id, public_id, file_id, created_by, expires_at, max_downloads, download_count,
+		       password_hash, is_revoked, revoked_at, last_used_at, created_at, updated_at
+		FROM managements.file_share_links
+		WHERE public_id = $1`
+
+	link := &FileShareLink{}
+	err := r.db.QueryRowContext(ctx, query, publicID).Scan(
+		&link.ID,
+		&link.PublicID,
+		&link.FileID,
+		&link.CreatedBy,
+		&link.ExpiresAt,
+		&link.MaxDownloads,
+		&link.DownloadCount,
+		&link.PasswordHash,
+		&link.IsRevoked,
+		&link.RevokedAt,
+		&link.LastUsedAt,
+		&link.CreatedAt,
+		&link.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+func (r *repository) ListShareLinksByFileID(ctx context.Context, fileID int64) ([]FileShareLink, error) {
+	query := `
+		SELECT id, public_id, file_id, created_by, expires_at, max_downloads, download_count,
+		       password_hash, is_revoked, revoked_at, last_used_at, created_at, updated_at
+		FROM managements.file_share_links
+		WHERE file_id = $1
+		ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []FileShareLink
+	for rows.Next() {
+		var link FileShareLink
+		if err := rows.Scan(
+			&link.ID,
+			&link.PublicID,
+			&link.FileID,
+			&link.CreatedBy,
+			&link.ExpiresAt,
+			&link.MaxDownloads,
+			&link.DownloadCount,
+			&link.PasswordHash,
+			&link.IsRevoked,
+			&link.RevokedAt,
+			&link.LastUsedAt,
+			&link.CreatedAt,
+			&link.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+func (r *repository) RevokeShareLink(ctx context.Context, fileID int64, publicID string) error {
+	query := `
+		UPDATE managements.file_share_links
+		SET is_revoked = true,
+		    revoked_at = CURRENT_TIMESTAMP,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE file_id = $1 AND public_id = $2 AND is_revoked = false`
+
+	result, err := r.db.ExecContext(ctx, query, fileID, publicID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// IncrementShareLinkDownloadCount consumes one use of a share link.
+// The update only applies while the link is still usable, so concurrent downloads
+// cannot exceed max_downloads. Returns sql.ErrNoRows when the link is no longer usable.
+func (r *repository) IncrementShareLinkDownloadCount(ctx context.Context, id int64) error {
+	query := `
+		UPDATE managements.file_share_links
+		SET download_count = download_count + 1,
+		    last_used_at = CURRENT_TIMESTAMP,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		  AND is_revoked = false
+		  AND expires_at > CURRENT_TIMESTAMP
+		  AND (max_downloads IS NULL OR download_count < max_downloads)`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
 	}
 
 	return nil
@@ -0,0 +1,189 @@
+package files
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// purger applies retention rules and removes the blobs of soft-deleted files
+// once their grace period has passed
+type purger struct {
+	repo        Repository
+	storage     Storage
+	gracePeriod time.Duration
+	batchSize   int
+}
+
+// newPurger creates a purger
+func newPurger(repo Repository, storage Storage, gracePeriod time.Duration, batchSize int) *purger {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	return &purger{
+		repo:        repo,
+		storage:     storage,
+		gracePeriod: gracePeriod,
+		batchSize:   batchSize,
+	}
+}
+
+// Start runs the purger every interval in the background
+func (p *purger) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			report, err := p.Run(ctx)
+			cancel()
+
+			if err != nil {
+				log.Printf("[WARN] File purge failed: %v", err)
+			}
+			if report.ExpiredFiles > 0 || report.PurgedFiles > 0 || report.Failures > 0 {
+				log.Printf("File purge: %d expired, %d purged, %d bytes reclaimed, %d failures",
+					report.ExpiredFiles, report.PurgedFiles, report.ReclaimedBytes, report.Failures)
+			}
+		}
+	}()
+}
+
+// Run soft-deletes files expired by retention rules, then purges the blobs of files
+// deleted longer ago than the grace period. Files under legal hold are skipped.
+func (p *purger) Run(ctx context.Context) (*PurgeReport, error) {
+	report := &PurgeReport{StartedAt: time.Now()}
+	defer func() { report.FinishedAt = time.Now() }()
+
+	if err := p.expire(ctx, report); err != nil {
+		return report, err
+	}
+
+	if err := p.purge(ctx, report); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// expire soft-deletes files whose retention has passed. Their blobs are purged after the grace period
+// like any other deleted file.
+func (p *purger) expire(ctx context.Context, report *PurgeReport) error {
+	for {
+		candidates, err := p.repo.ListExpiredFiles(ctx, p.batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to list expired files: %w", err)
+		}
+
+		failed := false
+		for _, candidate := range candidates {
+			if err := p.repo.SoftDeleteFile(ctx, candidate.PublicID); err != nil {
+				log.Printf("[WARN] Failed to expire file %s: %v", candidate.PublicID, err)
+				report.Failures++
+				failed = true
+				continue
+			}
+
+			report.ExpiredFiles++
+			p.audit(ctx, &FilePurgeLog{
+				FileID: candidate.FileID,
+				Action: PurgeActionExpired,
+				RuleID: candidate.RuleID,
+			})
+		}
+
+		// Stop on a short batch, or on failures so the same files aren't retried in a loop
+		if len(candidates) < p.batchSize || failed {
+			return nil
+		}
+	}
+}
+
+// purge removes the blobs and derivatives of files deleted before the grace period
+func (p *purger) purge(ctx context.Context, report *PurgeReport) error {
+	for {
+		deletedBefore := time.Now().Add(-p.gracePeriod)
+		candidates, err := p.repo.ClaimPurgeableFiles(ctx, deletedBefore, p.batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to claim purgeable files: %w", err)
+		}
+
+		failed := false
+		for _, candidate := range candidates {
+			reclaimed, err := p.purgeFile(ctx, candidate)
+			if err != nil {
+				log.Printf("[WARN] Failed to purge file %s: %v", candidate.PublicID, err)
+				report.Failures++
+				failed = true
+
+				_ = p.repo.UnclaimPurgedFile(ctx, candidate.FileID)
+				p.audit(ctx, &FilePurgeLog{
+					FileID: candidate.FileID,
+					Action: PurgeActionFailed,
+					Detail: sql.NullString{String: err.Error(), Valid: true},
+				})
+				continue
+			}
+
+			report.PurgedFiles++
+			report.ReclaimedBytes += reclaimed
+			p.audit(ctx, &FilePurgeLog{
+				FileID:         candidate.FileID,
+				Action:         PurgeActionPurged,
+				ReclaimedBytes: reclaimed,
+			})
+		}
+
+		if len(candidates) < p.batchSize || failed {
+			return nil
+		}
+	}
+}
+
+// purgeFile deletes the blob and derivatives of a file and returns the number of bytes reclaimed.
+// Blobs that are already gone are not an error.
+func (p *purger) purgeFile(ctx context.Context, candidate PurgeCandidate) (int64, error) {
+	var reclaimed int64
+
+	derivatives, err := p.repo.ListFileDerivatives(ctx, candidate.FileID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list derivatives: %w", err)
+	}
+
+	for _, derivative := range derivatives {
+		if err := p.storage.Delete(ctx, derivative.RelativePath); err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return 0, err
+			}
+			continue
+		}
+		reclaimed += derivative.FileSize
+	}
+
+	if err := p.repo.DeleteFileDerivatives(ctx, candidate.FileID); err != nil {
+		return 0, fmt.Errorf("failed to delete derivative records: %w", err)
+	}
+
+	if err := p.storage.Delete(ctx, candidate.RelativePath); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return 0, err
+		}
+	} else {
+		reclaimed += candidate.FileSize
+	}
+
+	return reclaimed, nil
+}
+
+// audit writes a retention audit record (best effort)
+func (p *purger) audit(ctx context.Context, entry *FilePurgeLog) {
+	if err := p.repo.CreatePurgeLog(ctx, entry); err != nil {
+		log.Printf("[WARN] Failed to write purge audit record for file %d: %v", entry.FileID, err)
+	}
+}
@@ -0,0 +1,284 @@
+package files
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// scanJobTimeout bounds the time spent scanning a single file in the background
+	scanJobTimeout = 5 * time.Minute
+
+	// scanMetadataKey is the reserved File.Metadata key holding the malware scan result
+	scanMetadataKey = "malware_scan"
+
+	// quarantinePrefix is the storage path prefix infected files are moved under
+	quarantinePrefix = "quarantine/"
+
+	// clamdChunkSize is the size of INSTREAM chunks sent to clamd
+	clamdChunkSize = 64 * 1024
+)
+
+// Scanner defines the interface for malware scanning engines
+type Scanner interface {
+	// Scan reads the content to its end and reports whether it is infected.
+	// An error means the content could not be scanned, not that it is infected.
+	Scan(ctx context.Context, reader io.Reader) (*ScanResult, error)
+}
+
+// ScanResult is the verdict of a scanner for a single piece of content
+type ScanResult struct {
+	Infected  bool
+	Signature string // Name of the detected signature, empty if clean
+	Engine    string // Engine and signature database version
+}
+
+// -------------------- clamd Scanner --------------------
+
+// ClamdScanner implements Scanner using the clamd INSTREAM command
+type ClamdScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamdScanner creates a scanner talking to clamd at the given address.
+// The address is either "tcp://host:port", "unix:///path/to/clamd.sock" or a bare "host:port".
+func NewClamdScanner(address string, timeout time.Duration) (*ClamdScanner, error) {
+	network, addr := "tcp", address
+	switch {
+	case strings.HasPrefix(address, "tcp://"):
+		addr = strings.TrimPrefix(address, "tcp://")
+	case strings.HasPrefix(address, "unix://"):
+		network, addr = "unix", strings.TrimPrefix(address, "unix://")
+	}
+
+	if addr == "" {
+		return nil, fmt.Errorf("invalid clamd address: %q", address)
+	}
+
+	return &ClamdScanner{
+		network: network,
+		address: addr,
+		timeout: timeout,
+	}, nil
+}
+
+// Scan streams the content to clamd and parses its verdict
+func (c *ClamdScanner) Scan(ctx context.Context, reader io.Reader) (*ScanResult, error) {
+	engine, err := c.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := c.stream(conn, reader); err != nil {
+		// clamd replies and closes the connection early when the stream exceeds StreamMaxLength,
+		// so prefer its reply over the write error if there is one
+		reply, readErr := readClamdReply(conn)
+		if readErr != nil || reply == "" {
+			return nil, err
+		}
+		return parseClamdScanReply(reply, engine)
+	}
+
+	reply, err := readClamdReply(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+
+	return parseClamdScanReply(reply, engine)
+}
+
+// Version returns the clamd engine and signature database version,
+// e.g. "ClamAV 1.3.1/27400/Mon Sep 30 08:35:30 2024"
+func (c *ClamdScanner) Version(ctx context.Context) (string, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zVERSION\x00")); err != nil {
+		return "", fmt.Errorf("failed to send clamd command: %w", err)
+	}
+
+	reply, err := readClamdReply(conn)
+	if err != nil {
+		return "", fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	if strings.HasSuffix(reply, "ERROR") {
+		return "", fmt.Errorf("clamd error: %s", reply)
+	}
+
+	return reply, nil
+}
+
+// dial opens a connection to clamd bounded by the scanner timeout and the context deadline
+func (c *ClamdScanner) dial(ctx context.Context) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+
+	deadline := time.Now().Add(c.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// stream sends the content as INSTREAM chunks: a 4-byte big-endian length followed by the data,
+// terminated by a zero-length chunk
+func (c *ClamdScanner) stream(conn net.Conn, reader io.Reader) error {
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return fmt.Errorf("failed to send clamd command: %w", err)
+	}
+
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, err := reader.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, werr := conn.Write(buf[:4+n]); werr != nil {
+				return fmt.Errorf("failed to stream to clamd: %w", werr)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read content: %w", err)
+		}
+	}
+
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return fmt.Errorf("failed to stream to clamd: %w", err)
+	}
+
+	return nil
+}
+
+// readClamdReply reads a NUL-terminated reply (or until EOF) from clamd
+func readClamdReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimRight(reply, "\x00")), nil
+}
+
+// parseClamdScanReply parses an INSTREAM reply such as "stream: OK" or "stream: Eicar-Signature FOUND"
+func parseClamdScanReply(reply, engine string) (*ScanResult, error) {
+	result := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+
+	switch {
+	case result == "OK":
+		return &ScanResult{Engine: engine}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return &ScanResult{
+			Infected:  true,
+			Signature: strings.TrimSuffix(result, " FOUND"),
+			Engine:    engine,
+		}, nil
+	case strings.HasSuffix(result, "ERROR"):
+		return nil, fmt.Errorf("clamd error: %s", result)
+	default:
+		return nil, fmt.Errorf("unexpected clamd reply: %q", reply)
+	}
+}
+
+// -------------------- Scan Metadata --------------------
+
+// scanInfoFromMetadata extracts the malware scan record from file metadata.
+// Returns nil if the file has never been submitted for scanning.
+func scanInfoFromMetadata(metadata json.RawMessage) *ScanInfo {
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(metadata, &fields); err != nil {
+		return nil
+	}
+
+	raw, ok := fields[scanMetadataKey]
+	if !ok {
+		return nil
+	}
+
+	var info ScanInfo
+	if err := json.Unmarshal(raw, &info); err != nil || info.Status == "" {
+		return nil
+	}
+
+	return &info
+}
+
+// withScanInfo returns a copy of the metadata with the malware scan record set
+func withScanInfo(metadata json.RawMessage, info *ScanInfo) (json.RawMessage, error) {
+	fields := map[string]json.RawMessage{}
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &fields); err != nil {
+			return nil, ErrInvalidMetadata
+		}
+		if fields == nil {
+			fields = map[string]json.RawMessage{}
+		}
+	}
+
+	raw, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	fields[scanMetadataKey] = raw
+
+	return json.Marshal(fields)
+}
+
+// preserveScanInfo replaces any malware scan record in user-supplied metadata
+// with the one currently stored, so clients cannot forge a clean verdict
+func preserveScanInfo(metadata, stored json.RawMessage) (json.RawMessage, error) {
+	info := scanInfoFromMetadata(stored)
+
+	fields := map[string]json.RawMessage{}
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &fields); err != nil || fields == nil {
+			// Non-object metadata is only acceptable if there is no scan record to keep
+			if info == nil {
+				return metadata, nil
+			}
+			return nil, ErrInvalidMetadata
+		}
+	}
+
+	delete(fields, scanMetadataKey)
+	if info != nil {
+		raw, err := json.Marshal(info)
+		if err != nil {
+			return nil, err
+		}
+		fields[scanMetadataKey] = raw
+	}
+
+	return json.Marshal(fields)
+}
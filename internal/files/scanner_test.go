@@ -0,0 +1,277 @@
+package files
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+const fakeClamdVersion = "ClamAV 1.3.1/27400/Mon Sep 30 08:35:30 2024"
+
+// fakeClamd is a minimal clamd speaking the VERSION and INSTREAM commands over a local socket.
+// Streams containing "EICAR" are reported as infected.
+type fakeClamd struct {
+	listener net.Listener
+	received chan []byte
+	reply    string // Overrides the INSTREAM reply if set
+}
+
+func newFakeClamd(t *testing.T) *fakeClamd {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	f := &fakeClamd{listener: listener, received: make(chan []byte, 10)}
+	t.Cleanup(func() { listener.Close() })
+
+	go f.serve()
+
+	return f
+}
+
+func (f *fakeClamd) address() string {
+	return "tcp://" + f.listener.Addr().String()
+}
+
+func (f *fakeClamd) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeClamd) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	command, err := reader.ReadString(0)
+	if err != nil {
+		return
+	}
+
+	switch command {
+	case "zVERSION\x00":
+		conn.Write([]byte(fakeClamdVersion + "\x00"))
+	case "zINSTREAM\x00":
+		var content bytes.Buffer
+		for {
+			var size uint32
+			if err := binary.Read(reader, binary.BigEndian, &size); err != nil {
+				return
+			}
+			if size == 0 {
+				break
+			}
+			if _, err := io.CopyN(&content, reader, int64(size)); err != nil {
+				return
+			}
+		}
+		f.received <- content.Bytes()
+
+		reply := "stream: OK"
+		if f.reply != "" {
+			reply = f.reply
+		} else if bytes.Contains(content.Bytes(), []byte("EICAR")) {
+			reply = "stream: Eicar-Test-Signature FOUND"
+		}
+		conn.Write([]byte(reply + "\x00"))
+	default:
+		conn.Write([]byte("UNKNOWN COMMAND\x00"))
+	}
+}
+
+func TestClamdScanner_Scan(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       []byte
+		reply         string
+		wantInfected  bool
+		wantSignature string
+		wantErr       bool
+	}{
+		{
+			name:    "clean content",
+			content: []byte("hello world"),
+		},
+		{
+			name:          "infected content",
+			content:       []byte("X5O!P%@AP[4\\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*"),
+			wantInfected:  true,
+			wantSignature: "Eicar-Test-Signature",
+		},
+		{
+			name:    "content larger than one chunk",
+			content: bytes.Repeat([]byte("a"), clamdChunkSize*2+123),
+		},
+		{
+			name:    "empty content",
+			content: []byte{},
+		},
+		{
+			name:    "clamd error",
+			content: []byte("hello world"),
+			reply:   "INSTREAM size limit exceeded. ERROR",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clamd := newFakeClamd(t)
+			clamd.reply = tt.reply
+
+			scanner, err := NewClamdScanner(clamd.address(), 5*time.Second)
+			if err != nil {
+				t.Fatalf("NewClamdScanner() error = %v", err)
+			}
+
+			result, err := scanner.Scan(context.Background(), bytes.NewReader(tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Scan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			received := <-clamd.received
+			if !bytes.Equal(received, tt.content) {
+				t.Errorf("clamd received %d bytes, want %d", len(received), len(tt.content))
+			}
+
+			if tt.wantErr {
+				return
+			}
+			if result.Infected != tt.wantInfected {
+				t.Errorf("Infected = %v, want %v", result.Infected, tt.wantInfected)
+			}
+			if result.Signature != tt.wantSignature {
+				t.Errorf("Signature = %q, want %q", result.Signature, tt.wantSignature)
+			}
+			if result.Engine != fakeClamdVersion {
+				t.Errorf("Engine = %q, want %q", result.Engine, fakeClamdVersion)
+			}
+		})
+	}
+}
+
+func TestClamdScanner_Unavailable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	scanner, err := NewClamdScanner(address, time.Second)
+	if err != nil {
+		t.Fatalf("NewClamdScanner() error = %v", err)
+	}
+
+	if _, err := scanner.Scan(context.Background(), strings.NewReader("hello")); err == nil {
+		t.Error("Scan() expected error when clamd is unavailable")
+	}
+}
+
+func TestNewClamdScanner_Address(t *testing.T) {
+	tests := []struct {
+		address     string
+		wantNetwork string
+		wantAddress string
+		wantErr     bool
+	}{
+		{address: "tcp://localhost:3310", wantNetwork: "tcp", wantAddress: "localhost:3310"},
+		{address: "unix:///var/run/clamav/clamd.ctl", wantNetwork: "unix", wantAddress: "/var/run/clamav/clamd.ctl"},
+		{address: "clamav:3310", wantNetwork: "tcp", wantAddress: "clamav:3310"},
+		{address: "unix://", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			scanner, err := NewClamdScanner(tt.address, time.Second)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewClamdScanner() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if scanner.network != tt.wantNetwork || scanner.address != tt.wantAddress {
+				t.Errorf("got %s %s, want %s %s", scanner.network, scanner.address, tt.wantNetwork, tt.wantAddress)
+			}
+		})
+	}
+}
+
+func TestScanMetadata(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	metadata, err := withScanInfo(json.RawMessage(`{"category":"invoice"}`), &ScanInfo{Status: ScanStatusClean, ScannedAt: &now})
+	if err != nil {
+		t.Fatalf("withScanInfo() error = %v", err)
+	}
+
+	info := scanInfoFromMetadata(metadata)
+	if info == nil || info.Status != ScanStatusClean {
+		t.Fatalf("scanInfoFromMetadata() = %+v, want CLEAN", info)
+	}
+
+	// A client trying to overwrite the scan record keeps the stored one
+	stored := metadata
+	metadata, err = preserveScanInfo(json.RawMessage(`{"category":"receipt","malware_scan":{"status":"INFECTED"}}`), stored)
+	if err != nil {
+		t.Fatalf("preserveScanInfo() error = %v", err)
+	}
+	if info := scanInfoFromMetadata(metadata); info == nil || info.Status != ScanStatusClean {
+		t.Errorf("preserveScanInfo() scan record = %+v, want CLEAN", info)
+	}
+	if !strings.Contains(string(metadata), "receipt") {
+		t.Errorf("preserveScanInfo() dropped client fields: %s", metadata)
+	}
+
+	// Forged records are dropped when nothing is stored
+	metadata, err = preserveScanInfo(json.RawMessage(`{"malware_scan":{"status":"CLEAN"}}`), nil)
+	if err != nil {
+		t.Fatalf("preserveScanInfo() error = %v", err)
+	}
+	if info := scanInfoFromMetadata(metadata); info != nil {
+		t.Errorf("preserveScanInfo() kept forged scan record: %s", metadata)
+	}
+
+	// Non-object metadata can't carry a scan record
+	if _, err := preserveScanInfo(json.RawMessage(`[1,2,3]`), stored); !errors.Is(err, ErrInvalidMetadata) {
+		t.Errorf("preserveScanInfo() error = %v, want ErrInvalidMetadata", err)
+	}
+}
+
+func TestCheckScanStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata string
+		wantErr  error
+	}{
+		{name: "not scanned", metadata: `{}`, wantErr: nil},
+		{name: "clean", metadata: `{"malware_scan":{"status":"CLEAN"}}`, wantErr: nil},
+		{name: "pending", metadata: `{"malware_scan":{"status":"PENDING"}}`, wantErr: ErrFileScanPending},
+		{name: "failed", metadata: `{"malware_scan":{"status":"FAILED"}}`, wantErr: ErrFileScanPending},
+		{name: "infected", metadata: `{"malware_scan":{"status":"INFECTED"}}`, wantErr: ErrFileInfected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkScanStatus(&File{Metadata: json.RawMessage(tt.metadata)})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkScanStatus() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
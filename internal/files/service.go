@@ -2,21 +2,27 @@ package files
 
 import (
 	"context"
+	"crypto/hmac"
 	"crypto/sha256"
 	"database/sql"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"kc-api/internal/password"
 )
 
 // Storage defines the interface for file storage operations
@@ -26,27 +32,119 @@ type Storage interface {
 	Delete(ctx context.Context, relativePath string) error
 }
 
+// AttachmentAccessChecker reports whether a user can read a file through a ticket it is attached to.
+// It is implemented by the tickets domain.
+type AttachmentAccessChecker interface {
+	CanReadAttachment(ctx context.Context, filePublicID string, userPublicID string) (bool, error)
+}
+
+// PasswordHasher hashes share link passwords and verifies them with the configured Argon2id
+// parameters. It is implemented by the auth service.
+type PasswordHasher interface {
+	HashPassword(plain string) (string, error)
+	VerifyPasswordHash(plain, encodedHash string) bool
+}
+
+// SharePasswordLimiter counts wrong share link passwords and locks a link after too many of them.
+// It is implemented by the auth service.
+type SharePasswordLimiter interface {
+	ShareLinkLockedFor(ctx context.Context, linkPublicID string) (time.Duration, error)
+	RecordShareLinkFailure(ctx context.Context, linkPublicID string) error
+}
+
 // Service defines the interface for file business logic operations
 type Service interface {
 	UploadFile(ctx context.Context, file multipart.File, header *multipart.FileHeader, uploaderID *string, metadata json.RawMessage) (*FileUploadResponse, error)
-	GetFileForDownload(ctx context.Context, publicID string) (io.ReadCloser, *File, error)
-	GetFileInfo(ctx context.Context, publicID string) (*FileResponse, error)
+	GetFileForDownload(ctx context.Context, publicID string, requesterID string) (io.ReadCloser, *File, error)
+	GetFileInfo(ctx context.Context, publicID string, requesterID string) (*FileResponse, error)
 	ListMyFiles(ctx context.Context, uploaderID string, page, limit int) (*FileListResponse, error)
-	UpdateFileMetadata(ctx context.Context, publicID string, uploaderID string, metadata json.RawMessage) error
+	ListSharedWithMe(ctx context.Context, userID string, page, limit int) (*FileListResponse, error)
+	UpdateFileMetadata(ctx context.Context, publicID string, requesterID string, metadata json.RawMessage) error
 	DeleteFile(ctx context.Context, publicID string, requesterID string) error
+	GetThumbnail(ctx context.Context, publicID string, size string, requesterID string) (io.ReadCloser, *FileDerivative, error)
+	RescanFile(ctx context.Context, publicID string) (*ScanResponse, error)
+	PrepareArchive(ctx context.Context, fileIDs []string, requesterID string) (*Archive, error)
+
+	// Access control
+	ShareFile(ctx context.Context, filePublicID string, requesterID string, req *ShareFileRequest) (*FileShareResponse, error)
+	ListFileShares(ctx context.Context, filePublicID string, requesterID string) (*FileShareListResponse, error)
+	RevokeFileShare(ctx context.Context, filePublicID string, sharePublicID string, requesterID string) error
+
+	// Storage quotas
+	GetMyStorageUsage(ctx context.Context, userID string) (*StorageUsageResponse, error)
+	ListQuotas(ctx context.Context, scopeType string) (*QuotaListResponse, error)
+	SetQuota(ctx context.Context, req *SetQuotaRequest) (*QuotaResponse, error)
+	DeleteQuota(ctx context.Context, quotaID string) error
+	GetUsageReport(ctx context.Context, scopeType string, limit int) (*UsageReportResponse, error)
+
+	// Retention
+	SetLegalHold(ctx context.Context, publicID string, hold bool) error
+	CreateRetentionRule(ctx context.Context, req *CreateRetentionRuleRequest) (*RetentionRuleResponse, error)
+	ListRetentionRules(ctx context.Context) (*RetentionRuleListResponse, error)
+	DeleteRetentionRule(ctx context.Context, ruleID string) error
+	RunPurge(ctx context.Context) (*PurgeReport, error)
+
+	// Share links
+	CreateShareLink(ctx context.Context, filePublicID string, requesterID string, req *CreateShareLinkRequest) (*ShareLinkResponse, error)
+	ListShareLinks(ctx context.Context, filePublicID string, requesterID string) (*ShareLinkListResponse, error)
+	RevokeShareLink(ctx context.Context, filePublicID string, linkPublicID string, requesterID string) error
+	GetSharedFileForDownload(ctx context.Context, linkPublicID, expires, signature, password string) (io.ReadCloser, *File, error)
+	GetPublicFileForDownload(ctx context.Context, publicID string) (io.ReadCloser, *File, error)
 }
 
 type service struct {
-	repo    Repository
-	storage Storage
+	repo        Repository
+	storage     Storage
+	shareSecret []byte
+	thumbnailer *thumbnailer
+	scanner     Scanner   // nil if malware scanning is disabled
+	scanQueue   *jobQueue // nil if malware scanning is disabled
+	purger      *purger
+	attachments AttachmentAccessChecker // nil if ticket attachments grant no access
+	passwords   PasswordHasher          // nil hashes share link passwords with the default parameters
+	attempts    SharePasswordLimiter    // nil leaves share link password guesses unlimited
+
+	defaultUserQuota int64 // 0 means unlimited
 }
 
-// NewService creates a new file service
-func NewService(repo Repository, storageBasePath string) Service {
-	return &service{
-		repo:    repo,
-		storage: NewLocalStorage(storageBasePath),
+// NewService creates a new file service with the given configuration.
+// The scanner is optional; when nil, uploads are not scanned for malware.
+// The attachment checker is optional; when nil, files are not readable through tickets.
+// The password hasher is optional; when nil, share link passwords use the default Argon2id parameters.
+// The password limiter is optional; when nil, wrong share link passwords are not limited.
+func NewService(repo Repository, cfg *Config, scanner Scanner, attachments AttachmentAccessChecker, passwords PasswordHasher, attempts SharePasswordLimiter) Service {
+	storage := NewLocalStorage(cfg.StoragePath)
+	s := &service{
+		repo:        repo,
+		storage:     storage,
+		shareSecret: []byte(cfg.ShareSecret),
+		thumbnailer: newThumbnailer(repo, storage, cfg.ThumbnailWorkers, cfg.ThumbnailQueueSize, cfg.ThumbnailPDF),
+		scanner:     scanner,
+		attachments: attachments,
+		passwords:   passwords,
+		attempts:    attempts,
+		purger:      newPurger(repo, storage, cfg.DeleteGracePeriod, cfg.PurgeBatchSize),
+
+		defaultUserQuota: cfg.DefaultUserQuota,
+	}
+
+	if scanner != nil {
+		s.scanQueue = newJobQueue("Malware scan", cfg.ScanWorkers, cfg.ScanQueueSize, scanJobTimeout,
+			func(ctx context.Context, file *File) error {
+				_, err := s.scanFile(ctx, file)
+				return err
+			})
 	}
+
+	if scanner != nil && cfg.ScanRetryInterval > 0 {
+		s.startScanRetry(cfg.ScanRetryInterval, cfg.ScanQueueSize)
+	}
+
+	if cfg.PurgeInterval > 0 {
+		s.purger.Start(cfg.PurgeInterval)
+	}
+
+	return s
 }
 
 // -------------------- Storage Implementations --------------------
@@ -145,6 +243,37 @@ func (s *service) UploadFile(ctx context.Context, file multipart.File, header *m
 		}
 	}
 
+	// Convert uploader public ID to internal ID
+	var uploaderInternalID sql.NullInt64
+	if uploaderID != nil {
+		internalID, err := s.repo.GetUserInternalID(ctx, *uploaderID)
+		if err != nil {
+			// If user not found, still allow upload but without uploader reference
+			uploaderInternalID = sql.NullInt64{Valid: false}
+		} else {
+			uploaderInternalID = sql.NullInt64{Int64: internalID, Valid: true}
+		}
+	}
+
+	// Enforce the storage quotas of the uploader, their department and groups.
+	// This only avoids storing content that is bound to be rejected.
+	if uploaderInternalID.Valid {
+		if err := s.checkQuota(ctx, uploaderInternalID.Int64, header.Size); err != nil {
+			return nil, err
+		}
+	}
+
+	// Mark the file as pending until the malware scanner has checked it.
+	// Client-supplied scan records are always discarded.
+	if s.scanner != nil {
+		metadata, err = withScanInfo(metadata, &ScanInfo{Status: ScanStatusPending})
+	} else {
+		metadata, err = preserveScanInfo(metadata, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
 	// Reset file pointer to beginning
 	if _, err := file.Seek(0, io.SeekStart); err != nil {
 		return nil, fmt.Errorf("failed to reset file pointer: %w", err)
@@ -166,18 +295,6 @@ func (s *service) UploadFile(ctx context.Context, file multipart.File, header *m
 	// Sanitize original filename
 	sanitizedFilename := sanitizeFilename(header.Filename)
 
-	// Convert uploader public ID to internal ID
-	var uploaderInternalID sql.NullInt64
-	if uploaderID != nil {
-		internalID, err := s.repo.GetUserInternalID(ctx, *uploaderID)
-		if err != nil {
-			// If user not found, still allow upload but without uploader reference
-			uploaderInternalID = sql.NullInt64{Valid: false}
-		} else {
-			uploaderInternalID = sql.NullInt64{Int64: internalID, Valid: true}
-		}
-	}
-
 	// Set default metadata if nil
 	if metadata == nil {
 		metadata = json.RawMessage("{}")
@@ -196,12 +313,26 @@ func (s *service) UploadFile(ctx context.Context, file multipart.File, header *m
 		IsPublic:         false, // Default to private
 	}
 
-	if err := s.repo.CreateFile(ctx, fileRecord); err != nil {
+	// The quotas are checked again under lock; concurrent uploads may have used them up meanwhile
+	if err := s.repo.CreateFile(ctx, fileRecord, s.quotaCheck(header.Size)); err != nil {
 		// Rollback: delete the saved file
 		_ = s.storage.Delete(ctx, relativePath)
+		if errors.Is(err, ErrQuotaExceeded) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to create file record: %w", err)
 	}
 
+	// Scan in the background; thumbnails are generated once the file is clean.
+	// A job dropped on a full queue is picked up again by the scan retry.
+	scanStatus := ""
+	if s.scanner != nil {
+		scanStatus = string(ScanStatusPending)
+		s.scanQueue.Enqueue(fileRecord)
+	} else {
+		s.thumbnailer.Enqueue(fileRecord)
+	}
+
 	return &FileUploadResponse{
 		ID:               fileRecord.PublicID,
 		OriginalFilename: fileRecord.OriginalFilename,
@@ -209,37 +340,43 @@ func (s *service) UploadFile(ctx context.Context, file multipart.File, header *m
 		FileSize:         fileRecord.FileSize,
 		ChecksumSHA256:   fileRecord.ChecksumSHA256,
 		DownloadURL:      "/files/" + fileRecord.PublicID + "/download",
+		ScanStatus:       scanStatus,
 		Message:          "File uploaded successfully",
 	}, nil
 }
 
-func (s *service) GetFileForDownload(ctx context.Context, publicID string) (io.ReadCloser, *File, error) {
+func (s *service) GetFileForDownload(ctx context.Context, publicID string, requesterID string) (io.ReadCloser, *File, error) {
 	// Get file metadata
 	file, err := s.repo.GetFileByPublicID(ctx, publicID)
 	if err != nil {
 		return nil, nil, ErrFileNotFound
 	}
 
-	// Get file from storage
-	reader, err := s.storage.Get(ctx, file.RelativePath)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to retrieve file: %w", err)
+	if err := s.checkAccess(ctx, file, requesterID, AccessLevelRead); err != nil {
+		return nil, nil, err
 	}
 
-	// Increment download count asynchronously (best effort)
-	go func() {
-		_ = s.repo.IncrementDownloadCount(context.Background(), publicID)
-	}()
-
-	return reader, file, nil
+	// Get file from storage and record the download
+	return s.openForDownload(ctx, file)
 }
 
-func (s *service) GetFileInfo(ctx context.Context, publicID string) (*FileResponse, error) {
+func (s *service) GetFileInfo(ctx context.Context, publicID string, requesterID string) (*FileResponse, error) {
+	file, err := s.repo.GetFileByPublicID(ctx, publicID)
+	if err != nil {
+		return nil, ErrFileNotFound
+	}
+
+	if err := s.checkAccess(ctx, file, requesterID, AccessLevelRead); err != nil {
+		return nil, err
+	}
+
 	resp, err := s.repo.GetFileDetailByPublicID(ctx, publicID)
 	if err != nil {
 		return nil, ErrFileNotFound
 	}
 
+	s.setThumbnailURL(resp)
+
 	return resp, nil
 }
 
@@ -256,6 +393,10 @@ func (s *service) ListMyFiles(ctx context.Context, uploaderID string, page, limi
 		return nil, fmt.Errorf("failed to list files: %w", err)
 	}
 
+	for i := range files {
+		s.setThumbnailURL(&files[i])
+	}
+
 	// Calculate total pages
 	totalPages := (totalCount + limit - 1) / limit
 
@@ -268,23 +409,47 @@ func (s *service) ListMyFiles(ctx context.Context, uploaderID string, page, limi
 	}, nil
 }
 
-func (s *service) UpdateFileMetadata(ctx context.Context, publicID string, uploaderID string, metadata json.RawMessage) error {
-	// Get file to check ownership
+func (s *service) ListSharedWithMe(ctx context.Context, userID string, page, limit int) (*FileListResponse, error) {
+	internalID, err := s.repo.GetUserInternalID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	files, totalCount, err := s.repo.ListFilesSharedWithUser(ctx, internalID, page, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shared files: %w", err)
+	}
+
+	for i := range files {
+		s.setThumbnailURL(&files[i])
+	}
+
+	totalPages := (totalCount + limit - 1) / limit
+
+	return &FileListResponse{
+		Data:       files,
+		Page:       page,
+		Limit:      limit,
+		TotalCount: totalCount,
+		TotalPages: totalPages,
+	}, nil
+}
+
+func (s *service) UpdateFileMetadata(ctx context.Context, publicID string, requesterID string, metadata json.RawMessage) error {
+	// Get file to check access
 	file, err := s.repo.GetFileByPublicID(ctx, publicID)
 	if err != nil {
 		return ErrFileNotFound
 	}
 
-	// Check ownership
-	if file.UploadedBy.Valid {
-		uploaderInternalID, err := s.repo.GetUserInternalID(ctx, uploaderID)
-		if err != nil {
-			return fmt.Errorf("user not found: %w", err)
-		}
+	if err := s.checkAccess(ctx, file, requesterID, AccessLevelWrite); err != nil {
+		return err
+	}
 
-		if file.UploadedBy.Int64 != uploaderInternalID {
-			return ErrUnauthorized
-		}
+	// Keep the malware scan record out of the client's control
+	metadata, err = preserveScanInfo(metadata, file.Metadata)
+	if err != nil {
+		return err
 	}
 
 	// Update metadata
@@ -296,37 +461,1060 @@ func (s *service) UpdateFileMetadata(ctx context.Context, publicID string, uploa
 }
 
 func (s *service) DeleteFile(ctx context.Context, publicID string, requesterID string) error {
-	// Get file to check ownership
+	// Get file to check access
 	file, err := s.repo.GetFileByPublicID(ctx, publicID)
 	if err != nil {
 		return ErrFileNotFound
 	}
 
-	// Check ownership
-	if file.UploadedBy.Valid {
-		requesterInternalID, err := s.repo.GetUserInternalID(ctx, requesterID)
+	if err := s.checkAccess(ctx, file, requesterID, AccessLevelWrite); err != nil {
+		return err
+	}
+
+	if file.LegalHold {
+		return ErrLegalHold
+	}
+
+	// Soft delete in database; the blob is purged after the grace period
+	if err := s.repo.SoftDeleteFile(ctx, publicID); err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	return nil
+}
+
+func (s *service) GetThumbnail(ctx context.Context, publicID string, size string, requesterID string) (io.ReadCloser, *FileDerivative, error) {
+	if size == "" {
+		size = DefaultThumbnailSize
+	}
+	if _, ok := findThumbnailSize(size); !ok {
+		return nil, nil, ErrInvalidThumbnailSize
+	}
+
+	file, err := s.repo.GetFileByPublicID(ctx, publicID)
+	if err != nil {
+		return nil, nil, ErrFileNotFound
+	}
+
+	if err := s.checkAccess(ctx, file, requesterID, AccessLevelRead); err != nil {
+		return nil, nil, err
+	}
+
+	if err := checkScanStatus(file); err != nil {
+		return nil, nil, err
+	}
+
+	derivative, err := s.repo.GetFileDerivative(ctx, file.ID, DerivativeKindThumbnail, size)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrThumbnailNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to get thumbnail: %w", err)
+	}
+
+	reader, err := s.storage.Get(ctx, derivative.RelativePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve thumbnail: %w", err)
+	}
+
+	return reader, derivative, nil
+}
+
+func (s *service) RescanFile(ctx context.Context, publicID string) (*ScanResponse, error) {
+	if s.scanner == nil {
+		return nil, ErrScannerNotConfigured
+	}
+
+	file, err := s.repo.GetFileByPublicID(ctx, publicID)
+	if err != nil {
+		return nil, ErrFileNotFound
+	}
+
+	info, err := s.scanFile(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScanResponse{
+		FileID:    file.PublicID,
+		Status:    info.Status,
+		Engine:    info.Engine,
+		Signature: info.Signature,
+		ScannedAt: info.ScannedAt,
+	}, nil
+}
+
+// PrepareArchive resolves the files of a ZIP archive. Files that are missing, deleted, not readable
+// by the requester or blocked by malware scanning are skipped rather than failing the whole archive.
+func (s *service) PrepareArchive(ctx context.Context, fileIDs []string, requesterID string) (*Archive, error) {
+	if len(fileIDs) == 0 {
+		return nil, fmt.Errorf("%w: at least one file ID is required", ErrInvalidArchiveRequest)
+	}
+	if len(fileIDs) > maxArchiveFiles {
+		return nil, fmt.Errorf("%w: at most %d files can be archived at once", ErrInvalidArchiveRequest, maxArchiveFiles)
+	}
+
+	requesterInternalID, err := s.repo.GetUserInternalID(ctx, requesterID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	archive := &Archive{repo: s.repo, storage: s.storage}
+	requested := make(map[string]bool, len(fileIDs))
+	names := make(map[string]bool, len(fileIDs))
+
+	for _, publicID := range fileIDs {
+		if requested[publicID] {
+			continue
+		}
+		requested[publicID] = true
+
+		if _, err := uuid.Parse(publicID); err != nil {
+			archive.skipped = append(archive.skipped, publicID)
+			continue
+		}
+
+		file, err := s.repo.GetFileByPublicID(ctx, publicID)
 		if err != nil {
-			return fmt.Errorf("user not found: %w", err)
+			if !errors.Is(err, sql.ErrNoRows) {
+				return nil, fmt.Errorf("failed to get file: %w", err)
+			}
+			archive.skipped = append(archive.skipped, publicID)
+			continue
 		}
 
-		if file.UploadedBy.Int64 != requesterInternalID {
-			return ErrUnauthorized
+		level, err := s.accessLevel(ctx, file, requesterInternalID, requesterID)
+		if err != nil {
+			return nil, err
 		}
+		if !level.Allows(AccessLevelRead) || checkScanStatus(file) != nil {
+			archive.skipped = append(archive.skipped, publicID)
+			continue
+		}
+
+		archive.entries = append(archive.entries, archiveEntry{
+			name: uniqueArchiveName(file.OriginalFilename, file.PublicID, names),
+			file: file,
+		})
 	}
 
-	// Soft delete in database
-	if err := s.repo.SoftDeleteFile(ctx, publicID); err != nil {
-		return fmt.Errorf("failed to delete file: %w", err)
+	if len(archive.entries) == 0 {
+		return nil, ErrArchiveEmpty
+	}
+
+	return archive, nil
+}
+
+// -------------------- Quota Methods --------------------
+
+func (s *service) GetMyStorageUsage(ctx context.Context, userID string) (*StorageUsageResponse, error) {
+	internalID, err := s.repo.GetUserInternalID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	usedBytes, fileCount, err := s.repo.GetUserStorageUsage(ctx, internalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage usage: %w", err)
+	}
+
+	quotas, err := s.quotasForUser(ctx, internalID, userID, usedBytes, fileCount)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StorageUsageResponse{
+		UsedBytes: usedBytes,
+		FileCount: fileCount,
+		Quotas:    quotas,
+	}, nil
+}
+
+func (s *service) ListQuotas(ctx context.Context, scopeType string) (*QuotaListResponse, error) {
+	scope := QuotaScope(strings.ToUpper(scopeType))
+	if scope != "" && !scope.IsValid() {
+		return nil, fmt.Errorf("%w: scope_type must be one of USER, DEPARTMENT, GROUP", ErrInvalidQuotaRequest)
+	}
+
+	quotas, err := s.repo.ListQuotas(ctx, scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list quotas: %w", err)
+	}
+
+	data := make([]QuotaResponse, 0, len(quotas))
+	for i := range quotas {
+		data = append(data, quotas[i].ToResponse())
+	}
+
+	return &QuotaListResponse{Data: data}, nil
+}
+
+func (s *service) SetQuota(ctx context.Context, req *SetQuotaRequest) (*QuotaResponse, error) {
+	scope := QuotaScope(strings.ToUpper(string(req.ScopeType)))
+	if !scope.IsValid() {
+		return nil, fmt.Errorf("%w: scope_type must be one of USER, DEPARTMENT, GROUP", ErrInvalidQuotaRequest)
+	}
+	if req.MaxBytes < 0 {
+		return nil, fmt.Errorf("%w: max_bytes must not be negative", ErrInvalidQuotaRequest)
+	}
+	if req.MaxFiles != nil && *req.MaxFiles < 0 {
+		return nil, fmt.Errorf("%w: max_files must not be negative", ErrInvalidQuotaRequest)
+	}
+
+	scopeID, err := s.repo.GetScopeInternalID(ctx, scope, req.ScopeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s %s not found", ErrInvalidQuotaRequest, strings.ToLower(string(scope)), req.ScopeID)
+		}
+		return nil, fmt.Errorf("failed to resolve quota scope: %w", err)
+	}
+
+	quota := &StorageQuota{
+		ScopeType:     scope,
+		ScopeID:       scopeID,
+		ScopePublicID: req.ScopeID,
+		MaxBytes:      req.MaxBytes,
+	}
+	if req.MaxFiles != nil {
+		quota.MaxFiles = sql.NullInt64{Int64: *req.MaxFiles, Valid: true}
+	}
+
+	if err := s.repo.UpsertQuota(ctx, quota); err != nil {
+		return nil, fmt.Errorf("failed to save quota: %w", err)
+	}
+
+	resp := quota.ToResponse()
+
+	// Report the current usage of the scope along with the new limits (best effort)
+	if quotas, err := s.repo.ListQuotas(ctx, scope); err == nil {
+		for i := range quotas {
+			if quotas[i].PublicID == quota.PublicID {
+				resp = quotas[i].ToResponse()
+				break
+			}
+		}
 	}
 
-	// Optionally delete from storage (best effort, file is already soft-deleted in DB)
-	// We don't fail the operation if physical deletion fails
-	_ = s.storage.Delete(ctx, file.RelativePath)
+	return &resp, nil
+}
+
+func (s *service) DeleteQuota(ctx context.Context, quotaID string) error {
+	if err := s.repo.DeleteQuota(ctx, quotaID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrQuotaNotFound
+		}
+		return fmt.Errorf("failed to delete quota: %w", err)
+	}
 
 	return nil
 }
 
-// -------------------- Helper Functions --------------------
+func (s *service) GetUsageReport(ctx context.Context, scopeType string, limit int) (*UsageReportResponse, error) {
+	scope := QuotaScope(strings.ToUpper(scopeType))
+	if scope == "" {
+		scope = QuotaScopeUser
+	}
+	if !scope.IsValid() {
+		return nil, fmt.Errorf("%w: scope_type must be one of USER, DEPARTMENT, GROUP", ErrInvalidQuotaRequest)
+	}
+
+	consumers, err := s.repo.ListTopStorageConsumers(ctx, scope, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage report: %w", err)
+	}
+
+	data := make([]UsageReportEntry, 0, len(consumers))
+	for i := range consumers {
+		entry := consumers[i].ToReportEntry()
+		if scope == QuotaScopeUser && entry.MaxBytes == nil && s.defaultUserQuota > 0 {
+			entry.MaxBytes = &s.defaultUserQuota
+		}
+		data = append(data, entry)
+	}
+
+	return &UsageReportResponse{ScopeType: scope, Data: data}, nil
+}
+
+// -------------------- Retention Methods --------------------
+
+func (s *service) SetLegalHold(ctx context.Context, publicID string, hold bool) error {
+	if err := s.repo.SetLegalHold(ctx, publicID, hold); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrFileNotFound
+		}
+		return fmt.Errorf("failed to set legal hold: %w", err)
+	}
+
+	return nil
+}
+
+func (s *service) CreateRetentionRule(ctx context.Context, req *CreateRetentionRuleRequest) (*RetentionRuleResponse, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidRetentionRule)
+	}
+	if req.RetainDays < 1 {
+		return nil, fmt.Errorf("%w: retain_days must be at least 1", ErrInvalidRetentionRule)
+	}
+	if req.MetadataValue != nil && (req.MetadataKey == nil || *req.MetadataKey == "") {
+		return nil, fmt.Errorf("%w: metadata_value requires metadata_key", ErrInvalidRetentionRule)
+	}
+	if req.MetadataKey != nil && *req.MetadataKey == scanMetadataKey {
+		return nil, fmt.Errorf("%w: %s is a reserved metadata key", ErrInvalidRetentionRule, scanMetadataKey)
+	}
+
+	rule := &RetentionRule{
+		Name:       strings.TrimSpace(req.Name),
+		RetainDays: req.RetainDays,
+		IsActive:   true,
+	}
+	if req.MimeType != nil && *req.MimeType != "" {
+		rule.MimeType = sql.NullString{String: strings.ToLower(*req.MimeType), Valid: true}
+	}
+	if req.MetadataKey != nil && *req.MetadataKey != "" {
+		rule.MetadataKey = sql.NullString{String: *req.MetadataKey, Valid: true}
+	}
+	if req.MetadataValue != nil {
+		rule.MetadataValue = sql.NullString{String: *req.MetadataValue, Valid: true}
+	}
+
+	if err := s.repo.CreateRetentionRule(ctx, rule); err != nil {
+		return nil, fmt.Errorf("failed to create retention rule: %w", err)
+	}
+
+	resp := rule.ToResponse()
+	return &resp, nil
+}
+
+func (s *service) ListRetentionRules(ctx context.Context) (*RetentionRuleListResponse, error) {
+	rules, err := s.repo.ListRetentionRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list retention rules: %w", err)
+	}
+
+	data := make([]RetentionRuleResponse, 0, len(rules))
+	for i := range rules {
+		data = append(data, rules[i].ToResponse())
+	}
+
+	return &RetentionRuleListResponse{Data: data}, nil
+}
+
+func (s *service) DeleteRetentionRule(ctx context.Context, ruleID string) error {
+	if err := s.repo.DeleteRetentionRule(ctx, ruleID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRetentionRuleNotFound
+		}
+		return fmt.Errorf("failed to delete retention rule: %w", err)
+	}
+
+	return nil
+}
+
+func (s *service) RunPurge(ctx context.Context) (*PurgeReport, error) {
+	return s.purger.Run(ctx)
+}
+
+// -------------------- Access Control Methods --------------------
+
+func (s *service) ShareFile(ctx context.Context, filePublicID string, requesterID string, req *ShareFileRequest) (*FileShareResponse, error) {
+	file, err := s.repo.GetFileByPublicID(ctx, filePublicID)
+	if err != nil {
+		return nil, ErrFileNotFound
+	}
+
+	requesterInternalID, err := s.checkOwnership(ctx, file, requesterID)
+	if err != nil {
+		return nil, err
+	}
+
+	granteeType := QuotaScope(strings.ToUpper(string(req.GranteeType)))
+	if !granteeType.IsValid() {
+		return nil, fmt.Errorf("%w: grantee_type must be USER, DEPARTMENT or GROUP", ErrInvalidFileShareRequest)
+	}
+
+	level := AccessLevel(strings.ToUpper(string(req.AccessLevel)))
+	if level == "" {
+		level = AccessLevelRead
+	}
+	if !level.IsValid() {
+		return nil, fmt.Errorf("%w: access_level must be READ or WRITE", ErrInvalidFileShareRequest)
+	}
+
+	granteeID, err := s.repo.GetScopeInternalID(ctx, granteeType, req.GranteeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s %s not found", ErrInvalidFileShareRequest, strings.ToLower(string(granteeType)), req.GranteeID)
+		}
+		return nil, fmt.Errorf("failed to resolve grantee: %w", err)
+	}
+
+	if granteeType == QuotaScopeUser && granteeID == requesterInternalID {
+		return nil, fmt.Errorf("%w: files cannot be shared with their uploader", ErrInvalidFileShareRequest)
+	}
+
+	share := &FileShare{
+		FileID:          file.ID,
+		GranteeType:     granteeType,
+		GranteeID:       granteeID,
+		GranteePublicID: req.GranteeID,
+		AccessLevel:     level,
+		CreatedBy:       sql.NullInt64{Int64: requesterInternalID, Valid: true},
+	}
+
+	if err := s.repo.UpsertFileShare(ctx, share); err != nil {
+		return nil, fmt.Errorf("failed to share file: %w", err)
+	}
+
+	resp := share.ToResponse(file.PublicID)
+	return &resp, nil
+}
+
+func (s *service) ListFileShares(ctx context.Context, filePublicID string, requesterID string) (*FileShareListResponse, error) {
+	file, err := s.repo.GetFileByPublicID(ctx, filePublicID)
+	if err != nil {
+		return nil, ErrFileNotFound
+	}
+
+	if _, err := s.checkOwnership(ctx, file, requesterID); err != nil {
+		return nil, err
+	}
+
+	shares, err := s.repo.ListFileShares(ctx, file.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list file shares: %w", err)
+	}
+
+	data := make([]FileShareResponse, 0, len(shares))
+	for i := range shares {
+		data = append(data, shares[i].ToResponse(file.PublicID))
+	}
+
+	return &FileShareListResponse{Data: data}, nil
+}
+
+func (s *service) RevokeFileShare(ctx context.Context, filePublicID string, sharePublicID string, requesterID string) error {
+	file, err := s.repo.GetFileByPublicID(ctx, filePublicID)
+	if err != nil {
+		return ErrFileNotFound
+	}
+
+	if _, err := s.checkOwnership(ctx, file, requesterID); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteFileShare(ctx, file.ID, sharePublicID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrFileShareNotFound
+		}
+		return fmt.Errorf("failed to revoke file share: %w", err)
+	}
+
+	return nil
+}
+
+// -------------------- Share Link Methods --------------------
+
+func (s *service) CreateShareLink(ctx context.Context, filePublicID string, requesterID string, req *CreateShareLinkRequest) (*ShareLinkResponse, error) {
+	file, err := s.repo.GetFileByPublicID(ctx, filePublicID)
+	if err != nil {
+		return nil, ErrFileNotFound
+	}
+
+	requesterInternalID, err := s.checkOwnership(ctx, file, requesterID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve expiry
+	duration := DefaultShareLinkDuration
+	if req.ExpiresIn != nil {
+		duration = time.Duration(*req.ExpiresIn) * time.Second
+		if duration <= 0 || duration > MaxShareLinkDuration {
+			return nil, fmt.Errorf("%w: expires_in must be between 1 and %d seconds",
+				ErrInvalidShareLinkRequest, int64(MaxShareLinkDuration.Seconds()))
+		}
+	}
+
+	link := &FileShareLink{
+		FileID:    file.ID,
+		CreatedBy: sql.NullInt64{Int64: requesterInternalID, Valid: true},
+		// Truncate to seconds so the signed expiry matches the stored value
+		ExpiresAt: time.Now().Add(duration).Truncate(time.Second),
+	}
+
+	if req.MaxDownloads != nil {
+		if *req.MaxDownloads < 1 {
+			return nil, fmt.Errorf("%w: max_downloads must be at least 1", ErrInvalidShareLinkRequest)
+		}
+		link.MaxDownloads = sql.NullInt64{Int64: *req.MaxDownloads, Valid: true}
+	}
+
+	if req.Password != nil && *req.Password != "" {
+		passwordHash, err := s.hashSharePassword(*req.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash share password: %w", err)
+		}
+		link.PasswordHash = sql.NullString{String: passwordHash, Valid: true}
+	}
+
+	if err := s.repo.CreateShareLink(ctx, link); err != nil {
+		return nil, fmt.Errorf("failed to create share link: %w", err)
+	}
+
+	resp := link.ToResponse(file.PublicID, s.buildShareURL(link))
+	return &resp, nil
+}
+
+func (s *service) ListShareLinks(ctx context.Context, filePublicID string, requesterID string) (*ShareLinkListResponse, error) {
+	file, err := s.repo.GetFileByPublicID(ctx, filePublicID)
+	if err != nil {
+		return nil, ErrFileNotFound
+	}
+
+	if _, err := s.checkOwnership(ctx, file, requesterID); err != nil {
+		return nil, err
+	}
+
+	links, err := s.repo.ListShareLinksByFileID(ctx, file.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list share links: %w", err)
+	}
+
+	data := make([]ShareLinkResponse, 0, len(links))
+	for i := range links {
+		data = append(data, links[i].ToResponse(file.PublicID, s.buildShareURL(&links[i])))
+	}
+
+	return &ShareLinkListResponse{Data: data}, nil
+}
+
+func (s *service) RevokeShareLink(ctx context.Context, filePublicID string, linkPublicID string, requesterID string) error {
+	file, err := s.repo.GetFileByPublicID(ctx, filePublicID)
+	if err != nil {
+		return ErrFileNotFound
+	}
+
+	if _, err := s.checkOwnership(ctx, file, requesterID); err != nil {
+		return err
+	}
+
+	if err := s.repo.RevokeShareLink(ctx, file.ID, linkPublicID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrShareLinkNotFound
+		}
+		return fmt.Errorf("failed to revoke share link: %w", err)
+	}
+
+	return nil
+}
+
+func (s *service) GetSharedFileForDownload(ctx context.Context, linkPublicID, expires, signature, password string) (io.ReadCloser, *File, error) {
+	// Verify the signature before touching the database
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !s.verifyShareSignature(linkPublicID, expiresAt, signature) {
+		return nil, nil, ErrInvalidShareSignature
+	}
+
+	if time.Now().Unix() >= expiresAt {
+		return nil, nil, ErrShareLinkExpired
+	}
+
+	link, err := s.repo.GetShareLinkByPublicID(ctx, linkPublicID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrShareLinkNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to get share link: %w", err)
+	}
+
+	if link.ExpiresAt.Unix() != expiresAt {
+		return nil, nil, ErrInvalidShareSignature
+	}
+	if link.IsRevoked {
+		return nil, nil, ErrShareLinkRevoked
+	}
+	if link.MaxDownloads.Valid && link.DownloadCount >= link.MaxDownloads.Int64 {
+		return nil, nil, ErrShareLinkExhausted
+	}
+
+	if link.PasswordHash.Valid {
+		if password == "" {
+			return nil, nil, ErrSharePasswordRequired
+		}
+		if err := s.checkSharePassword(ctx, link, password); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	file, err := s.repo.GetFileByID(ctx, link.FileID)
+	if err != nil {
+		return nil, nil, ErrFileNotFound
+	}
+
+	// Don't consume a use of the link for a file that can't be downloaded yet
+	if err := checkScanStatus(file); err != nil {
+		return nil, nil, err
+	}
+
+	// Open the content first, so a storage error doesn't use up a limited link
+	reader, err := s.storage.Get(ctx, file.RelativePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve file: %w", err)
+	}
+
+	// Consume one use of the link; the conditional update guards against concurrent overuse
+	if err := s.repo.IncrementShareLinkDownloadCount(ctx, link.ID); err != nil {
+		reader.Close()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrShareLinkExhausted
+		}
+		return nil, nil, fmt.Errorf("failed to record share link use: %w", err)
+	}
+
+	s.recordDownload(file)
+	return reader, file, nil
+}
+
+// checkSharePassword verifies the password of a share link. Wrong passwords are counted per link,
+// and a locked link is rejected before the password is checked.
+func (s *service) checkSharePassword(ctx context.Context, link *FileShareLink, password string) error {
+	if s.attempts != nil {
+		lockedFor, err := s.attempts.ShareLinkLockedFor(ctx, link.PublicID)
+		if err != nil {
+			return err
+		}
+		if lockedFor > 0 {
+			return &ShareLinkLockedError{RetryAfter: lockedFor}
+		}
+	}
+
+	if s.verifySharePassword(password, link.PasswordHash.String) {
+		return nil
+	}
+
+	if s.attempts != nil {
+		if err := s.attempts.RecordShareLinkFailure(ctx, link.PublicID); err != nil {
+			return err
+		}
+	}
+	return ErrInvalidSharePassword
+}
+
+func (s *service) GetPublicFileForDownload(ctx context.Context, publicID string) (io.ReadCloser, *File, error) {
+	file, err := s.repo.GetFileByPublicID(ctx, publicID)
+	if err != nil {
+		return nil, nil, ErrFileNotFound
+	}
+
+	// Private files are reported as not found so their existence is not disclosed
+	if !file.IsPublic {
+		return nil, nil, ErrFileNotFound
+	}
+
+	return s.openForDownload(ctx, file)
+}
+
+// -------------------- Helper Functions --------------------
+
+// openForDownload opens a file from storage and records the download
+func (s *service) openForDownload(ctx context.Context, file *File) (io.ReadCloser, *File, error) {
+	if err := checkScanStatus(file); err != nil {
+		return nil, nil, err
+	}
+
+	reader, err := s.storage.Get(ctx, file.RelativePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve file: %w", err)
+	}
+
+	s.recordDownload(file)
+	return reader, file, nil
+}
+
+// recordDownload increments the download count of a file asynchronously (best effort)
+func (s *service) recordDownload(file *File) {
+	go func() {
+		_ = s.repo.IncrementDownloadCount(context.Background(), file.PublicID)
+	}()
+}
+
+// quotasForUser returns the quotas applying to a user, including the default user quota
+// if the user has no explicit one
+func (s *service) quotasForUser(ctx context.Context, internalID int64, publicID string, usedBytes, fileCount int64) ([]QuotaResponse, error) {
+	quotas, err := s.repo.ListQuotasForUser(ctx, internalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quotas: %w", err)
+	}
+
+	return s.withDefaultQuota(quotas, publicID, usedBytes, fileCount), nil
+}
+
+// withDefaultQuota converts the quotas of a user to responses and adds the default user quota
+// if the user has no explicit one
+func (s *service) withDefaultQuota(quotas []StorageQuota, publicID string, usedBytes, fileCount int64) []QuotaResponse {
+	hasUserQuota := false
+	result := make([]QuotaResponse, 0, len(quotas)+1)
+	for i := range quotas {
+		if quotas[i].ScopeType == QuotaScopeUser {
+			hasUserQuota = true
+		}
+		result = append(result, quotas[i].ToResponse())
+	}
+
+	if !hasUserQuota && s.defaultUserQuota > 0 {
+		result = append(result, QuotaResponse{
+			ScopeType:      QuotaScopeUser,
+			ScopeID:        publicID,
+			MaxBytes:       s.defaultUserQuota,
+			UsedBytes:      usedBytes,
+			FileCount:      fileCount,
+			RemainingBytes: max(s.defaultUserQuota-usedBytes, 0),
+			IsDefault:      true,
+		})
+	}
+
+	return result
+}
+
+// checkQuota rejects an upload early, before its content is stored, if it would exceed
+// a quota of the uploader. CreateFile repeats the check under lock.
+func (s *service) checkQuota(ctx context.Context, userID int64, fileSize int64) error {
+	usedBytes, fileCount, err := s.repo.GetUserStorageUsage(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get storage usage: %w", err)
+	}
+
+	quotas, err := s.repo.ListQuotasForUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get quotas: %w", err)
+	}
+
+	return s.quotaCheck(fileSize)(usedBytes, fileCount, quotas)
+}
+
+// quotaCheck returns a check failing with ErrQuotaExceeded if storing a file of the given size would exceed
+// any quota of the uploader, their department or one of their groups
+func (s *service) quotaCheck(fileSize int64) QuotaCheck {
+	return func(usedBytes, fileCount int64, quotas []StorageQuota) error {
+		for _, quota := range s.withDefaultQuota(quotas, "", usedBytes, fileCount) {
+			scope := strings.ToLower(string(quota.ScopeType))
+			if quota.UsedBytes+fileSize > quota.MaxBytes {
+				return fmt.Errorf("%w: %s quota of %s would be exceeded (%s used, file is %s)",
+					ErrQuotaExceeded, scope, formatBytes(quota.MaxBytes), formatBytes(quota.UsedBytes), formatBytes(fileSize))
+			}
+			if quota.MaxFiles != nil && quota.FileCount+1 > *quota.MaxFiles {
+				return fmt.Errorf("%w: %s quota of %d files reached", ErrQuotaExceeded, scope, *quota.MaxFiles)
+			}
+		}
+
+		return nil
+	}
+}
+
+// formatBytes formats a byte count using binary units, e.g. 1536 -> "1.5 KiB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 5; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// checkScanStatus blocks access to files that have not been scanned clean.
+// Files without a scan record (uploaded while scanning was disabled) are allowed.
+func checkScanStatus(file *File) error {
+	info := scanInfoFromMetadata(file.Metadata)
+	if info == nil {
+		return nil
+	}
+
+	switch info.Status {
+	case ScanStatusClean:
+		return nil
+	case ScanStatusInfected:
+		return ErrFileInfected
+	default:
+		return ErrFileScanPending
+	}
+}
+
+// scanFile scans a file, records the verdict in its metadata and quarantines it if infected.
+// Files that become clean are released from quarantine and queued for thumbnail generation.
+func (s *service) scanFile(ctx context.Context, file *File) (*ScanInfo, error) {
+	previous := scanInfoFromMetadata(file.Metadata)
+
+	reader, err := s.storage.Get(ctx, file.RelativePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve file: %w", err)
+	}
+	result, err := s.scanner.Scan(ctx, reader)
+	reader.Close()
+
+	now := time.Now().UTC()
+
+	if err != nil {
+		// Keep an earlier verdict; only a file that was never checked is marked as failed.
+		// Repeated failures refresh the attempt time so retries rotate through failed files.
+		if previous == nil || previous.Status == ScanStatusPending || previous.Status == ScanStatusFailed {
+			info := &ScanInfo{Status: ScanStatusFailed, ScannedAt: &now}
+			if recordErr := s.recordScan(ctx, file, file.RelativePath, info); recordErr != nil {
+				return nil, recordErr
+			}
+		}
+		return nil, fmt.Errorf("%w: %v", ErrScanFailed, err)
+	}
+
+	info := &ScanInfo{
+		Status:    ScanStatusClean,
+		Engine:    result.Engine,
+		Signature: result.Signature,
+		ScannedAt: &now,
+	}
+
+	relativePath := file.RelativePath
+	if result.Infected {
+		info.Status = ScanStatusInfected
+		if !strings.HasPrefix(relativePath, quarantinePrefix) {
+			relativePath = quarantinePrefix + relativePath
+			if err := s.moveBlob(ctx, file.RelativePath, relativePath); err != nil {
+				return nil, fmt.Errorf("failed to quarantine file: %w", err)
+			}
+			s.deleteDerivatives(ctx, file)
+		}
+	} else if strings.HasPrefix(relativePath, quarantinePrefix) {
+		// Released after a rescan, e.g. a false positive fixed by a signature update
+		relativePath = strings.TrimPrefix(relativePath, quarantinePrefix)
+		if err := s.moveBlob(ctx, file.RelativePath, relativePath); err != nil {
+			return nil, fmt.Errorf("failed to release file from quarantine: %w", err)
+		}
+	}
+
+	if err := s.recordScan(ctx, file, relativePath, info); err != nil {
+		return nil, err
+	}
+
+	if info.Status == ScanStatusClean && (previous == nil || previous.Status != ScanStatusClean) {
+		s.thumbnailer.Enqueue(file)
+	}
+
+	return info, nil
+}
+
+// startScanRetry queues files still pending or failed for scanning again every interval in the background
+func (s *service) startScanRetry(interval time.Duration, batchSize int) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			// Files uploaded within the last interval may still be waiting in the queue
+			queued, err := s.requeueScans(ctx, time.Now().Add(-interval), batchSize)
+			cancel()
+
+			if err != nil {
+				log.Printf("[WARN] Malware scan retry failed: %v", err)
+			}
+			if queued > 0 {
+				log.Printf("Malware scan retry: %d files queued", queued)
+			}
+		}
+	}()
+}
+
+// requeueScans queues up to limit files uploaded before createdBefore whose scan is pending or failed.
+// It stops at the first job the queue rejects; the rest are picked up on the next run.
+func (s *service) requeueScans(ctx context.Context, createdBefore time.Time, limit int) (int, error) {
+	if limit < 1 {
+		limit = 1
+	}
+
+	files, err := s.repo.ListUnscannedFiles(ctx, createdBefore, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list unscanned files: %w", err)
+	}
+
+	queued := 0
+	for i := range files {
+		if !s.scanQueue.Enqueue(&files[i]) {
+			break
+		}
+		queued++
+	}
+
+	return queued, nil
+}
+
+// recordScan stores the scan record and storage path of a file and updates the in-memory copy
+func (s *service) recordScan(ctx context.Context, file *File, relativePath string, info *ScanInfo) error {
+	scanInfo, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdateFileScanStatus(ctx, file.ID, relativePath, scanInfo); err != nil {
+		return fmt.Errorf("failed to record scan result: %w", err)
+	}
+
+	metadata, err := withScanInfo(file.Metadata, info)
+	if err == nil {
+		file.Metadata = metadata
+	}
+	file.RelativePath = relativePath
+
+	return nil
+}
+
+// moveBlob moves content within storage by copying it and deleting the original
+func (s *service) moveBlob(ctx context.Context, from, to string) error {
+	reader, err := s.storage.Get(ctx, from)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if err := s.storage.Save(ctx, reader, to); err != nil {
+		return err
+	}
+
+	return s.storage.Delete(ctx, from)
+}
+
+// setThumbnailURL sets the thumbnail URL of a file response if thumbnails are supported for its type
+func (s *service) setThumbnailURL(resp *FileResponse) {
+	if s.thumbnailer.Supports(resp.MimeType) {
+		resp.ThumbnailURL = "/files/" + resp.ID + "/thumbnail"
+	}
+}
+
+// deleteDerivatives removes all derivatives of a file from storage and the database (best effort)
+func (s *service) deleteDerivatives(ctx context.Context, file *File) {
+	derivatives, err := s.repo.ListFileDerivatives(ctx, file.ID)
+	if err != nil {
+		return
+	}
+
+	for _, derivative := range derivatives {
+		_ = s.storage.Delete(ctx, derivative.RelativePath)
+	}
+
+	_ = s.repo.DeleteFileDerivatives(ctx, file.ID)
+}
+
+// checkAccess verifies that the requester has at least the required access level on a file.
+// Files the requester cannot read at all are reported as not found so their existence is not disclosed.
+func (s *service) checkAccess(ctx context.Context, file *File, requesterID string, required AccessLevel) error {
+	requesterInternalID, err := s.repo.GetUserInternalID(ctx, requesterID)
+	if err != nil {
+		return fmt.Errorf("user not found: %w", err)
+	}
+
+	level, err := s.accessLevel(ctx, file, requesterInternalID, requesterID)
+	if err != nil {
+		return err
+	}
+
+	if !level.Allows(AccessLevelRead) {
+		return ErrFileNotFound
+	}
+	if !level.Allows(required) {
+		return ErrUnauthorized
+	}
+
+	return nil
+}
+
+// accessLevel returns the requester's effective access level on a file, or "" if they have none.
+// The uploader has write access; other users get the highest level shared with them (directly,
+// through a group or through their department), and read access to public files and to files
+// attached to tickets they can read.
+func (s *service) accessLevel(ctx context.Context, file *File, requesterInternalID int64, requesterID string) (AccessLevel, error) {
+	if file.UploadedBy.Valid && file.UploadedBy.Int64 == requesterInternalID {
+		return AccessLevelWrite, nil
+	}
+
+	level, err := s.repo.GetSharedAccessLevel(ctx, file.ID, requesterInternalID)
+	if err != nil {
+		return "", fmt.Errorf("failed to check file shares: %w", err)
+	}
+	if level != "" {
+		return level, nil
+	}
+
+	if file.IsPublic {
+		return AccessLevelRead, nil
+	}
+
+	if s.attachments != nil {
+		canRead, err := s.attachments.CanReadAttachment(ctx, file.PublicID, requesterID)
+		if err != nil {
+			return "", fmt.Errorf("failed to check ticket access: %w", err)
+		}
+		if canRead {
+			return AccessLevelRead, nil
+		}
+	}
+
+	return "", nil
+}
+
+// checkOwnership verifies that the requester uploaded the file and returns the requester's internal ID
+func (s *service) checkOwnership(ctx context.Context, file *File, requesterID string) (int64, error) {
+	requesterInternalID, err := s.repo.GetUserInternalID(ctx, requesterID)
+	if err != nil {
+		return 0, fmt.Errorf("user not found: %w", err)
+	}
+
+	if !file.UploadedBy.Valid || file.UploadedBy.Int64 != requesterInternalID {
+		return 0, ErrUnauthorized
+	}
+
+	return requesterInternalID, nil
+}
+
+// buildShareURL builds the signed public URL for a share link
+func (s *service) buildShareURL(link *FileShareLink) string {
+	expiresAt := link.ExpiresAt.Unix()
+	return fmt.Sprintf("/public/files/shared/%s?expires=%d&signature=%s",
+		link.PublicID, expiresAt, s.signShareLink(link.PublicID, expiresAt))
+}
+
+// signShareLink computes the HMAC-SHA256 signature of a share link ID and expiry
+func (s *service) signShareLink(linkPublicID string, expiresAt int64) string {
+	mac := hmac.New(sha256.New, s.shareSecret)
+	mac.Write([]byte(linkPublicID + ":" + strconv.FormatInt(expiresAt, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyShareSignature checks a share link signature in constant time
+func (s *service) verifyShareSignature(linkPublicID string, expiresAt int64, signature string) bool {
+	expected := s.signShareLink(linkPublicID, expiresAt)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// hashSharePassword hashes a share link password with the configured Argon2id parameters
+func (s *service) hashSharePassword(plain string) (string, error) {
+	if s.passwords == nil {
+		return password.Hash(plain, password.DefaultParams())
+	}
+	return s.passwords.HashPassword(plain)
+}
+
+// verifySharePassword verifies a share link password. Hashes costing much more than the
+// configured parameters match no password, so a stored hash can't exhaust the server.
+func (s *service) verifySharePassword(plain, encodedHash string) bool {
+	if s.passwords == nil {
+		return password.DefaultParams().Verify(plain, encodedHash)
+	}
+	return s.passwords.VerifyPasswordHash(plain, encodedHash)
+}
 
 // saveWithChecksum saves the file and calculates SHA-256 checksum simultaneously
 func (s *service) saveWithChecksum(ctx context.Context, reader io.Reader, relativePath string) (string, error) {
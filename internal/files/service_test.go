@@ -0,0 +1,294 @@
+package files
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeFileRepository keeps files, users and shares in memory. Repository methods the tests
+// don't use are left to the nil embedded interface.
+type fakeFileRepository struct {
+	Repository
+	files   map[string]*File
+	users   map[string]int64                // public ID -> internal ID
+	shares  map[int64]map[int64]AccessLevel // file ID -> user ID -> level
+	deleted []string
+
+	unscanned []File // returned by ListUnscannedFiles, which records its arguments
+	listedAt  time.Time
+	listLimit int
+
+	links    map[string]*FileShareLink
+	linkUses int
+}
+
+func (r *fakeFileRepository) GetFileByPublicID(ctx context.Context, publicID string) (*File, error) {
+	file, ok := r.files[publicID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *file
+	return &copied, nil
+}
+
+func (r *fakeFileRepository) GetUserInternalID(ctx context.Context, publicID string) (int64, error) {
+	id, ok := r.users[publicID]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return id, nil
+}
+
+func (r *fakeFileRepository) GetSharedAccessLevel(ctx context.Context, fileID int64, userID int64) (AccessLevel, error) {
+	return r.shares[fileID][userID], nil
+}
+
+func (r *fakeFileRepository) SoftDeleteFile(ctx context.Context, publicID string) error {
+	r.deleted = append(r.deleted, publicID)
+	return nil
+}
+
+func (r *fakeFileRepository) ListUnscannedFiles(ctx context.Context, createdBefore time.Time, limit int) ([]File, error) {
+	r.listedAt, r.listLimit = createdBefore, limit
+	if len(r.unscanned) > limit {
+		return r.unscanned[:limit], nil
+	}
+	return r.unscanned, nil
+}
+
+func (r *fakeFileRepository) GetFileByID(ctx context.Context, id int64) (*File, error) {
+	for _, file := range r.files {
+		if file.ID == id {
+			copied := *file
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *fakeFileRepository) IncrementDownloadCount(ctx context.Context, publicID string) error {
+	return nil
+}
+
+func (r *fakeFileRepository) GetShareLinkByPublicID(ctx context.Context, publicID string) (*FileShareLink, error) {
+	link, ok := r.links[publicID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *link
+	return &copied, nil
+}
+
+func (r *fakeFileRepository) IncrementShareLinkDownloadCount(ctx context.Context, id int64) error {
+	r.linkUses++
+	return nil
+}
+
+// fakeStorage serves fixed content, or fails every read if err is set
+type fakeStorage struct {
+	Storage
+	err error
+}
+
+func (s *fakeStorage) Get(ctx context.Context, relativePath string) (io.ReadCloser, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return io.NopCloser(strings.NewReader("content")), nil
+}
+
+// plainPasswords compares share link passwords without hashing them
+type plainPasswords struct{}
+
+func (plainPasswords) HashPassword(plain string) (string, error) { return plain, nil }
+
+func (plainPasswords) VerifyPasswordHash(plain, encodedHash string) bool { return plain == encodedHash }
+
+// fakeAttemptLimiter locks a share link after the given number of wrong passwords
+type fakeAttemptLimiter struct {
+	threshold int
+	failures  map[string]int
+}
+
+func (l *fakeAttemptLimiter) ShareLinkLockedFor(ctx context.Context, linkPublicID string) (time.Duration, error) {
+	if l.failures[linkPublicID] >= l.threshold {
+		return 15 * time.Minute, nil
+	}
+	return 0, nil
+}
+
+func (l *fakeAttemptLimiter) RecordShareLinkFailure(ctx context.Context, linkPublicID string) error {
+	l.failures[linkPublicID]++
+	return nil
+}
+
+func TestService_GetSharedFileForDownload(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	repo := &fakeFileRepository{
+		files: map[string]*File{"shared": {ID: 1, PublicID: "shared", RelativePath: "2026/01/01/shared.txt"}},
+		links: map[string]*FileShareLink{
+			"link": {ID: 10, PublicID: "link", FileID: 1, ExpiresAt: expiresAt, PasswordHash: sql.NullString{String: "secret", Valid: true}},
+		},
+	}
+	storage := &fakeStorage{}
+	limiter := &fakeAttemptLimiter{threshold: 3, failures: map[string]int{}}
+	s := &service{repo: repo, storage: storage, shareSecret: []byte("share-secret"), passwords: plainPasswords{}, attempts: limiter}
+
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	signature := s.signShareLink("link", expiresAt.Unix())
+	download := func(password string) error {
+		reader, _, err := s.GetSharedFileForDownload(context.Background(), "link", expires, signature, password)
+		if err == nil {
+			reader.Close()
+		}
+		return err
+	}
+
+	// A storage error doesn't use up the link
+	storage.err = errors.New("disk unavailable")
+	if err := download("secret"); err == nil {
+		t.Fatal("expected the storage error")
+	}
+	if repo.linkUses != 0 {
+		t.Errorf("expected no use counted for a failed read, got %d", repo.linkUses)
+	}
+	storage.err = nil
+
+	if err := download("secret"); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if repo.linkUses != 1 {
+		t.Errorf("expected one use counted, got %d", repo.linkUses)
+	}
+
+	// Wrong passwords lock the link, after which even the right one is refused
+	for i := 0; i < 3; i++ {
+		if err := download("guess-" + strconv.Itoa(i)); !errors.Is(err, ErrInvalidSharePassword) {
+			t.Fatalf("expected ErrInvalidSharePassword, got %v", err)
+		}
+	}
+	err := download("secret")
+	var locked *ShareLinkLockedError
+	if !errors.As(err, &locked) || locked.RetryAfter != 15*time.Minute {
+		t.Fatalf("expected the link to be locked for 15m, got %v", err)
+	}
+	if limiter.failures["link"] != 3 {
+		t.Errorf("expected 3 failures counted, got %d", limiter.failures["link"])
+	}
+	if repo.linkUses != 1 {
+		t.Errorf("expected no further uses counted, got %d", repo.linkUses)
+	}
+}
+
+func TestService_RequeueScans(t *testing.T) {
+	repo := &fakeFileRepository{unscanned: []File{{ID: 1, PublicID: "pending"}, {ID: 2, PublicID: "failed"}, {ID: 3, PublicID: "dropped"}}}
+	// No workers, so jobs stay in the queue until it is full
+	queue := &jobQueue{name: "Malware scan", jobs: make(chan *File, 2)}
+	s := &service{repo: repo, scanQueue: queue}
+	createdBefore := time.Now().Add(-10 * time.Minute)
+
+	queued, err := s.requeueScans(context.Background(), createdBefore, 100)
+	if err != nil {
+		t.Fatalf("requeue failed: %v", err)
+	}
+	if queued != 2 {
+		t.Errorf("expected 2 files queued before the queue is full, got %d", queued)
+	}
+	if !repo.listedAt.Equal(createdBefore) || repo.listLimit != 100 {
+		t.Errorf("expected files created before %v, limit 100, got %v, limit %d", createdBefore, repo.listedAt, repo.listLimit)
+	}
+
+	for _, want := range []string{"pending", "failed"} {
+		if got := (<-queue.jobs).PublicID; got != want {
+			t.Errorf("expected %s to be queued, got %s", want, got)
+		}
+	}
+
+	// The file left over is queued on the next run
+	repo.unscanned = repo.unscanned[2:]
+	if queued, err := s.requeueScans(context.Background(), createdBefore, 100); err != nil || queued != 1 {
+		t.Errorf("expected the remaining file to be queued, got %d, %v", queued, err)
+	}
+}
+
+func TestService_DeleteFile(t *testing.T) {
+	newRepo := func() *fakeFileRepository {
+		return &fakeFileRepository{
+			files: map[string]*File{
+				"owned":    {ID: 1, PublicID: "owned", UploadedBy: sql.NullInt64{Int64: 1, Valid: true}},
+				"orphaned": {ID: 2, PublicID: "orphaned"}, // uploader deleted
+				"shared":   {ID: 3, PublicID: "shared", UploadedBy: sql.NullInt64{Int64: 1, Valid: true}},
+				"public":   {ID: 4, PublicID: "public", UploadedBy: sql.NullInt64{Int64: 1, Valid: true}, IsPublic: true},
+				"held":     {ID: 5, PublicID: "held", UploadedBy: sql.NullInt64{Int64: 1, Valid: true}, LegalHold: true},
+			},
+			users:  map[string]int64{"owner": 1, "writer": 2, "reader": 3},
+			shares: map[int64]map[int64]AccessLevel{3: {2: AccessLevelWrite, 3: AccessLevelRead}},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		fileID    string
+		requester string
+		wantErr   error
+	}{
+		{name: "owner", fileID: "owned", requester: "owner"},
+		{name: "other user", fileID: "owned", requester: "writer", wantErr: ErrFileNotFound},
+		{name: "no uploader", fileID: "orphaned", requester: "writer", wantErr: ErrFileNotFound},
+		{name: "shared for writing", fileID: "shared", requester: "writer"},
+		{name: "shared for reading", fileID: "shared", requester: "reader", wantErr: ErrUnauthorized},
+		{name: "public file", fileID: "public", requester: "reader", wantErr: ErrUnauthorized},
+		{name: "legal hold", fileID: "held", requester: "owner", wantErr: ErrLegalHold},
+		{name: "unknown file", fileID: "missing", requester: "owner", wantErr: ErrFileNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo()
+			s := &service{repo: repo}
+
+			err := s.DeleteFile(context.Background(), tt.fileID, tt.requester)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if deleted := len(repo.deleted) > 0; deleted != (tt.wantErr == nil) {
+				t.Errorf("expected deleted: %v, got %v", tt.wantErr == nil, repo.deleted)
+			}
+		})
+	}
+}
+
+func TestService_QuotaCheck(t *testing.T) {
+	s := &service{defaultUserQuota: 1000}
+	groupQuota := StorageQuota{ScopeType: QuotaScopeGroup, MaxBytes: 5000, MaxFiles: sql.NullInt64{Int64: 10, Valid: true}, UsedBytes: 4000, FileCount: 9}
+
+	tests := []struct {
+		name      string
+		fileSize  int64
+		usedBytes int64
+		quotas    []StorageQuota
+		wantErr   error
+	}{
+		{name: "within the default quota", fileSize: 100, usedBytes: 900},
+		{name: "over the default quota", fileSize: 101, usedBytes: 900, wantErr: ErrQuotaExceeded},
+		{name: "user quota replaces the default", fileSize: 500, usedBytes: 900, quotas: []StorageQuota{{ScopeType: QuotaScopeUser, MaxBytes: 2000, UsedBytes: 900}}},
+		{name: "group file count reached", fileSize: 1, quotas: []StorageQuota{{ScopeType: QuotaScopeUser, MaxBytes: 2000}, {ScopeType: QuotaScopeGroup, MaxBytes: 5000, MaxFiles: sql.NullInt64{Int64: 9, Valid: true}, FileCount: 9}}, wantErr: ErrQuotaExceeded},
+		{name: "group bytes exceeded", fileSize: 1001, quotas: []StorageQuota{{ScopeType: QuotaScopeUser, MaxBytes: 2000}, groupQuota}, wantErr: ErrQuotaExceeded},
+		{name: "within the group quota", fileSize: 1000, quotas: []StorageQuota{{ScopeType: QuotaScopeUser, MaxBytes: 2000}, groupQuota}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.quotaCheck(tt.fileSize)(tt.usedBytes, 0, tt.quotas)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	// Register decoders for image.Decode
+	_ "image/gif"
+	_ "image/png"
+)
+
+const (
+	// thumbnailJobTimeout bounds the time spent generating thumbnails for a single file
+	thumbnailJobTimeout = 2 * time.Minute
+
+	// maxThumbnailSourcePixels guards against decompression bombs (50 megapixels)
+	maxThumbnailSourcePixels = 50_000_000
+
+	// thumbnailJPEGQuality is the JPEG quality used for generated thumbnails
+	thumbnailJPEGQuality = 85
+
+	// pdfPreviewResolution is the longest edge in pixels of the rendered PDF first page
+	pdfPreviewResolution = 1024
+)
+
+// thumbnailer generates thumbnails for uploaded files in a bounded pool of background workers
+// so that uploads are not slowed down by image processing.
+type thumbnailer struct {
+	repo        Repository
+	storage     Storage
+	queue       *jobQueue
+	pdfRenderer string // Path to pdftoppm, empty if PDF previews are disabled
+}
+
+// newThumbnailer creates a thumbnailer and starts its workers
+func newThumbnailer(repo Repository, storage Storage, workers, queueSize int, enablePDF bool) *thumbnailer {
+	t := &thumbnailer{
+		repo:    repo,
+		storage: storage,
+	}
+
+	if enablePDF {
+		path, err := exec.LookPath("pdftoppm")
+		if err != nil {
+			log.Println("Warning: PDF previews enabled but pdftoppm was not found in PATH")
+		} else {
+			t.pdfRenderer = path
+		}
+	}
+
+	t.queue = newJobQueue("Thumbnail", workers, queueSize, thumbnailJobTimeout, t.Generate)
+
+	return t
+}
+
+// Supports reports whether thumbnails can be generated for the given MIME type
+func (t *thumbnailer) Supports(mimeType string) bool {
+	switch mimeType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	case "application/pdf":
+		return t.pdfRenderer != ""
+	}
+	return false
+}
+
+// Enqueue schedules thumbnail generation for a file without blocking.
+// Returns false if the file type is not supported or the queue is full.
+func (t *thumbnailer) Enqueue(file *File) bool {
+	if !t.Supports(file.MimeType) {
+		return false
+	}
+	return t.queue.Enqueue(file)
+}
+
+// Generate creates every thumbnail size for a file and records them as derivatives
+func (t *thumbnailer) Generate(ctx context.Context, file *File) error {
+	src, err := t.decode(ctx, file)
+	if err != nil {
+		return err
+	}
+
+	// Generate from the largest size down, reusing the previous result as the source
+	sizes := make([]ThumbnailSize, len(ThumbnailSizes))
+	copy(sizes, ThumbnailSizes)
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i].MaxDimension > sizes[j].MaxDimension })
+
+	for _, size := range sizes {
+		thumb := resizeToFit(src, size.MaxDimension)
+		src = thumb
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: thumbnailJPEGQuality}); err != nil {
+			return fmt.Errorf("failed to encode %s thumbnail: %w", size.Name, err)
+		}
+
+		derivative := &FileDerivative{
+			FileID:       file.ID,
+			Kind:         DerivativeKindThumbnail,
+			Variant:      size.Name,
+			RelativePath: derivativePath(file.RelativePath, string(DerivativeKindThumbnail), size.Name, ".jpg"),
+			MimeType:     "image/jpeg",
+			FileSize:     int64(buf.Len()),
+			Width:        thumb.Bounds().Dx(),
+			Height:       thumb.Bounds().Dy(),
+		}
+
+		if err := t.storage.Save(ctx, &buf, derivative.RelativePath); err != nil {
+			return fmt.Errorf("failed to save %s thumbnail: %w", size.Name, err)
+		}
+
+		if err := t.repo.UpsertFileDerivative(ctx, derivative); err != nil {
+			_ = t.storage.Delete(ctx, derivative.RelativePath)
+			return fmt.Errorf("failed to record %s thumbnail: %w", size.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// decode loads the source image of a file from storage
+func (t *thumbnailer) decode(ctx context.Context, file *File) (image.Image, error) {
+	if file.MimeType == "application/pdf" {
+		reader, err := t.storage.Get(ctx, file.RelativePath)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return renderPDFFirstPage(ctx, t.pdfRenderer, reader)
+	}
+
+	// Check dimensions before decoding the full image
+	reader, err := t.storage.Get(ctx, file.RelativePath)
+	if err != nil {
+		return nil, err
+	}
+	cfg, _, err := image.DecodeConfig(reader)
+	reader.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image header: %w", err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxThumbnailSourcePixels {
+		return nil, fmt.Errorf("image too large for thumbnail generation: %dx%d", cfg.Width, cfg.Height)
+	}
+
+	reader, err = t.storage.Get(ctx, file.RelativePath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	img, _, err := image.Decode(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	return img, nil
+}
+
+// renderPDFFirstPage renders the first page of a PDF to an image using pdftoppm
+func renderPDFFirstPage(ctx context.Context, pdftoppm string, reader io.Reader) (image.Image, error) {
+	tmpDir, err := os.MkdirTemp("", "kc-pdf-preview-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	inputPath := filepath.Join(tmpDir, "input.pdf")
+	input, err := os.Create(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	if _, err := io.Copy(input, reader); err != nil {
+		input.Close()
+		return nil, fmt.Errorf("failed to write temp file: %w", err)
+	}
+	input.Close()
+
+	outputPrefix := filepath.Join(tmpDir, "page")
+	cmd := exec.CommandContext(ctx, pdftoppm,
+		"-png", "-singlefile", "-f", "1", "-l", "1",
+		"-scale-to", fmt.Sprintf("%d", pdfPreviewResolution),
+		inputPath, outputPrefix)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("pdftoppm failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	page, err := os.Open(outputPrefix + ".png")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rendered page: %w", err)
+	}
+	defer page.Close()
+
+	img, _, err := image.Decode(page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode rendered page: %w", err)
+	}
+
+	return img, nil
+}
+
+// resizeToFit scales an image down to fit in a maxDim x maxDim box, preserving aspect ratio.
+// Pixels are box-filtered and composited over a white background since JPEG has no alpha channel.
+func resizeToFit(src image.Image, maxDim int) *image.RGBA {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	dstW, dstH := srcW, srcH
+	if srcW > maxDim || srcH > maxDim {
+		if srcW >= srcH {
+			dstW = maxDim
+			dstH = max(1, srcH*maxDim/srcW)
+		} else {
+			dstH = maxDim
+			dstW = max(1, srcW*maxDim/srcH)
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		sy0 := bounds.Min.Y + y*srcH/dstH
+		sy1 := max(sy0+1, bounds.Min.Y+(y+1)*srcH/dstH)
+
+		for x := 0; x < dstW; x++ {
+			sx0 := bounds.Min.X + x*srcW/dstW
+			sx1 := max(sx0+1, bounds.Min.X+(x+1)*srcW/dstW)
+
+			var sumR, sumG, sumB, sumA, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					r, g, b, a := src.At(sx, sy).RGBA()
+					sumR += uint64(r)
+					sumG += uint64(g)
+					sumB += uint64(b)
+					sumA += uint64(a)
+					n++
+				}
+			}
+
+			// Premultiplied colors over white: c + (1 - alpha) * white
+			background := n*0xffff - sumA
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(((sumR + background) / n) >> 8),
+				G: uint8(((sumG + background) / n) >> 8),
+				B: uint8(((sumB + background) / n) >> 8),
+				A: 0xff,
+			})
+		}
+	}
+
+	return dst
+}
+
+// derivativePath builds the storage path of a derivative next to its parent file,
+// e.g. 2024/12/05/{uuid}.png -> 2024/12/05/{uuid}.thumbnail.medium.jpg
+func derivativePath(parentPath, kind, variant, ext string) string {
+	base := strings.TrimSuffix(parentPath, filepath.Ext(parentPath))
+	return fmt.Sprintf("%s.%s.%s%s", base, strings.ToLower(kind), variant, ext)
+}
+
+// findThumbnailSize looks up a thumbnail size by name
+func findThumbnailSize(name string) (ThumbnailSize, bool) {
+	for _, size := range ThumbnailSizes {
+		if size.Name == name {
+			return size, true
+		}
+	}
+	return ThumbnailSize{}, false
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 )
 
 var (
@@ -40,13 +41,20 @@ type Service interface {
 	RemoveRoleFromGroup(ctx context.Context, publicID string, roleID int) error
 }
 
+// TokenRevoker revokes the access tokens issued to users whose group memberships changed.
+// It is implemented by the auth service.
+type TokenRevoker interface {
+	RevokeUserTokens(ctx context.Context, userIDs ...int) error
+}
+
 type service struct {
-	repo Repository
+	repo   Repository
+	tokens TokenRevoker // nil leaves issued access tokens valid until they expire
 }
 
-// NewService creates a new group service
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+// NewService creates a new group service. The token revoker may be nil.
+func NewService(repo Repository, tokens TokenRevoker) Service {
+	return &service{repo: repo, tokens: tokens}
 }
 
 // Create creates a new group
@@ -150,9 +158,11 @@ func (s *service) Delete(ctx context.Context, publicID string) error {
 		return fmt.Errorf("failed to get group: %w", err)
 	}
 
+	members := s.members(ctx, group.ID)
 	if err := s.repo.Delete(ctx, group.ID); err != nil {
 		return fmt.Errorf("failed to delete group: %w", err)
 	}
+	s.revokeTokens(ctx, members...)
 	return nil
 }
 
@@ -261,19 +271,21 @@ func (s *service) BatchDelete(ctx context.Context, req *BatchDeleteGroupRequest)
 		return nil, ErrEmptyBatchRequest
 	}
 
-	var ids []int
+	var ids, members []int
 	for _, publicID := range req.PublicIDs {
 		id, err := s.repo.GetIDByPublicID(ctx, publicID)
 		if err != nil {
 			continue
 		}
 		ids = append(ids, id)
+		members = append(members, s.members(ctx, id)...)
 	}
 
 	successCount, failedIDs, err := s.repo.BatchDelete(ctx, ids)
 	if err != nil {
 		return nil, fmt.Errorf("failed to batch delete: %w", err)
 	}
+	s.revokeTokens(ctx, members...)
 
 	return &BatchOperationResponse{
 		SuccessCount: successCount,
@@ -312,6 +324,7 @@ func (s *service) AssignUsersToGroup(ctx context.Context, publicID string, req *
 	if err := s.repo.AddUsersToGroup(ctx, group.ID, req.UserIDs); err != nil {
 		return fmt.Errorf("failed to assign users to group: %w", err)
 	}
+	s.revokeTokens(ctx, req.UserIDs...)
 	return nil
 }
 
@@ -331,6 +344,7 @@ func (s *service) RemoveUserFromGroup(ctx context.Context, publicID string, user
 		}
 		return fmt.Errorf("failed to remove user from group: %w", err)
 	}
+	s.revokeTokens(ctx, userID)
 	return nil
 }
 
@@ -378,6 +392,7 @@ func (s *service) AssignRolesToGroup(ctx context.Context, publicID string, req *
 	if err := s.repo.AssignRolesToGroup(ctx, group.ID, req.RoleIDs); err != nil {
 		return fmt.Errorf("failed to assign roles to group: %w", err)
 	}
+	s.revokeTokens(ctx, s.members(ctx, group.ID)...)
 	return nil
 }
 
@@ -397,9 +412,33 @@ func (s *service) RemoveRoleFromGroup(ctx context.Context, publicID string, role
 		}
 		return fmt.Errorf("failed to remove role from group: %w", err)
 	}
+	s.revokeTokens(ctx, s.members(ctx, group.ID)...)
 	return nil
 }
 
+// members returns the members of a group, for revoking their tokens once the group changed
+func (s *service) members(ctx context.Context, groupID int) []int {
+	if s.tokens == nil {
+		return nil
+	}
+	userIDs, err := s.repo.GetGroupUsers(ctx, groupID)
+	if err != nil {
+		log.Printf("[WARN] Failed to get users of group %d: %v", groupID, err)
+	}
+	return userIDs
+}
+
+// revokeTokens revokes the access tokens of users whose groups changed, so that the change
+// takes effect immediately. Failures are logged; the change itself has already been made.
+func (s *service) revokeTokens(ctx context.Context, userIDs ...int) {
+	if s.tokens == nil || len(userIDs) == 0 {
+		return
+	}
+	if err := s.tokens.RevokeUserTokens(ctx, userIDs...); err != nil {
+		log.Printf("[WARN] Failed to revoke access tokens of users %v: %v", userIDs, err)
+	}
+}
+
 // Helper functions
 
 func hasAtLeastOneLocale(jsonData json.RawMessage) bool {
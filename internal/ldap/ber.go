@@ -0,0 +1,262 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BER classes and universal tags used by LDAP (RFC 4511, section 5.1)
+const (
+	classUniversal   byte = 0x00
+	classApplication byte = 0x40
+	classContext     byte = 0x80
+
+	tagBoolean     = 1
+	tagInteger     = 2
+	tagOctetString = 4
+	tagEnumerated  = 10
+	tagSequence    = 16
+	tagSet         = 17
+)
+
+// maxPacketSize limits the size of a single message read from a server or client
+const maxPacketSize = 16 << 20
+
+var errMalformedPacket = errors.New("malformed BER packet")
+
+// packet is a BER element. Primitive elements have a value, constructed elements have children.
+type packet struct {
+	class       byte
+	constructed bool
+	tag         int
+	value       []byte
+	children    []*packet
+}
+
+func newConstructed(class byte, tag int, children ...*packet) *packet {
+	return &packet{class: class, constructed: true, tag: tag, children: children}
+}
+
+func newPrimitive(class byte, tag int, value []byte) *packet {
+	return &packet{class: class, tag: tag, value: value}
+}
+
+func newSequence(children ...*packet) *packet {
+	return newConstructed(classUniversal, tagSequence, children...)
+}
+
+func newOctetString(s string) *packet {
+	return newPrimitive(classUniversal, tagOctetString, []byte(s))
+}
+
+func newInteger(v int64) *packet {
+	return newPrimitive(classUniversal, tagInteger, encodeInt(v))
+}
+
+func newEnumerated(v int64) *packet {
+	return newPrimitive(classUniversal, tagEnumerated, encodeInt(v))
+}
+
+func newBoolean(v bool) *packet {
+	if v {
+		return newPrimitive(classUniversal, tagBoolean, []byte{0xff})
+	}
+	return newPrimitive(classUniversal, tagBoolean, []byte{0x00})
+}
+
+// is reports whether the packet has the given class and tag
+func (p *packet) is(class byte, tag int) bool {
+	return p.class == class && p.tag == tag
+}
+
+// child returns the i-th child, or an error if there is none
+func (p *packet) child(i int) (*packet, error) {
+	if i >= len(p.children) {
+		return nil, errMalformedPacket
+	}
+	return p.children[i], nil
+}
+
+// str returns the value as a string
+func (p *packet) str() string {
+	return string(p.value)
+}
+
+// int returns the value as an integer
+func (p *packet) int() (int64, error) {
+	if len(p.value) == 0 || len(p.value) > 8 {
+		return 0, errMalformedPacket
+	}
+	v := int64(int8(p.value[0]))
+	for _, b := range p.value[1:] {
+		v = v<<8 | int64(b)
+	}
+	return v, nil
+}
+
+// bytes encodes the packet
+func (p *packet) bytes() []byte {
+	content := p.value
+	if p.constructed {
+		content = nil
+		for _, c := range p.children {
+			content = append(content, c.bytes()...)
+		}
+	}
+
+	identifier := p.class | byte(p.tag)
+	if p.constructed {
+		identifier |= 0x20
+	}
+
+	out := []byte{identifier}
+	out = append(out, encodeLength(len(content))...)
+	return append(out, content...)
+}
+
+// readPacket reads one packet from r
+func readPacket(r *bufio.Reader) (*packet, error) {
+	identifier, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	length, err := readLength(r)
+	if err != nil {
+		return nil, err
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, err
+	}
+	return decodePacket(identifier, content)
+}
+
+// parsePacket decodes a single packet that fills data
+func parsePacket(data []byte) (*packet, error) {
+	p, rest, err := splitPacket(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errMalformedPacket
+	}
+	return p, nil
+}
+
+// splitPacket decodes the first packet of data and returns the remaining bytes
+func splitPacket(data []byte) (*packet, []byte, error) {
+	if len(data) < 2 {
+		return nil, nil, errMalformedPacket
+	}
+	identifier := data[0]
+	length, n, err := decodeLength(data[1:])
+	if err != nil {
+		return nil, nil, err
+	}
+	start := 1 + n
+	if length > len(data)-start {
+		return nil, nil, errMalformedPacket
+	}
+	p, err := decodePacket(identifier, data[start:start+length])
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, data[start+length:], nil
+}
+
+func decodePacket(identifier byte, content []byte) (*packet, error) {
+	// High tag numbers (0x1f) are not used by LDAP
+	if identifier&0x1f == 0x1f {
+		return nil, errMalformedPacket
+	}
+
+	p := &packet{
+		class:       identifier & 0xc0,
+		constructed: identifier&0x20 != 0,
+		tag:         int(identifier & 0x1f),
+	}
+	if !p.constructed {
+		p.value = content
+		return p, nil
+	}
+
+	for len(content) > 0 {
+		child, rest, err := splitPacket(content)
+		if err != nil {
+			return nil, err
+		}
+		p.children = append(p.children, child)
+		content = rest
+	}
+	return p, nil
+}
+
+func encodeInt(v int64) []byte {
+	out := []byte{byte(v)}
+	for {
+		next := v >> 8
+		// Stop once the remaining bytes are only sign extension
+		if (next == 0 && out[0]&0x80 == 0) || (next == -1 && out[0]&0x80 != 0) {
+			return out
+		}
+		v = next
+		out = append([]byte{byte(v)}, out...)
+	}
+}
+
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+// readLength reads a definite length; the indefinite form is not allowed in LDAP
+func readLength(r *bufio.Reader) (int, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if first < 0x80 {
+		return int(first), nil
+	}
+	size := int(first & 0x7f)
+	if size == 0 || size > 4 {
+		return 0, errMalformedPacket
+	}
+	length := 0
+	for i := 0; i < size; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		length = length<<8 | int(b)
+	}
+	if length > maxPacketSize {
+		return 0, fmt.Errorf("packet of %d bytes exceeds the limit", length)
+	}
+	return length, nil
+}
+
+// decodeLength decodes a definite length and returns the number of bytes it used
+func decodeLength(data []byte) (int, int, error) {
+	first := data[0]
+	if first < 0x80 {
+		return int(first), 1, nil
+	}
+	size := int(first & 0x7f)
+	if size == 0 || size > 4 || len(data) < 1+size {
+		return 0, 0, errMalformedPacket
+	}
+	length := 0
+	for _, b := range data[1 : 1+size] {
+		length = length<<8 | int(b)
+	}
+	return length, 1 + size, nil
+}
@@ -0,0 +1,584 @@
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Protocol operations (RFC 4511, section 4.2 ff.)
+const (
+	opBindRequest       = 0
+	opBindResponse      = 1
+	opUnbindRequest     = 2
+	opSearchRequest     = 3
+	opSearchResultEntry = 4
+	opSearchResultDone  = 5
+	opSearchResultRef   = 19
+	opExtendedRequest   = 23
+	opExtendedResponse  = 24
+)
+
+// Result codes, search scopes and other protocol values
+const (
+	resultSuccess           = 0
+	resultSizeLimitExceeded = 4
+	resultInvalidCredential = 49
+
+	scopeBaseObject   = 0
+	scopeSingleLevel  = 1
+	scopeWholeSubtree = 2
+	derefNever        = 0
+
+	oidStartTLS     = "1.3.6.1.4.1.1466.20037"
+	oidPagedResults = "1.2.840.113556.1.4.319"
+
+	// attrAccountControl holds the account flags in Active Directory; accountDisabled is the ACCOUNTDISABLE flag
+	attrAccountControl = "userAccountControl"
+	accountDisabled    = 0x2
+)
+
+// Config holds LDAP directory configuration
+type Config struct {
+	// URL of the server, ldap://host:389 or ldaps://host:636
+	URL string
+
+	// StartTLS upgrades ldap:// connections to TLS before binding
+	StartTLS           bool
+	InsecureSkipVerify bool
+
+	// BindDN and BindPassword are the credentials of the account used to search the directory
+	BindDN       string
+	BindPassword string
+
+	// BaseDN is where users are searched
+	BaseDN string
+
+	// UserFilter finds the user logging in; {login_id} is replaced by the escaped login ID
+	UserFilter string
+
+	// SyncFilter selects the users to synchronize
+	SyncFilter string
+
+	Attributes Attributes
+
+	// PageSize is the number of entries requested per page (Active Directory returns at most 1000)
+	PageSize int
+
+	Timeout time.Duration
+}
+
+// Attributes are the names of the directory attributes read for users
+type Attributes struct {
+	LoginID    string
+	Email      string
+	Name       string
+	Department string
+	Groups     string
+}
+
+// LoadConfig reads LDAP configuration from environment variables.
+// Returns nil if LDAP_URL is not set.
+func LoadConfig() (*Config, error) {
+	cfg := &Config{
+		URL:                getEnv("LDAP_URL", ""),
+		StartTLS:           getBoolEnv("LDAP_START_TLS", false),
+		InsecureSkipVerify: getBoolEnv("LDAP_INSECURE_SKIP_VERIFY", false),
+		BindDN:             getEnv("LDAP_BIND_DN", ""),
+		BindPassword:       getEnv("LDAP_BIND_PASSWORD", ""),
+		BaseDN:             getEnv("LDAP_BASE_DN", ""),
+		UserFilter:         getEnv("LDAP_USER_FILTER", "(&(objectCategory=person)(objectClass=user)(sAMAccountName={login_id}))"),
+		SyncFilter:         getEnv("LDAP_SYNC_FILTER", "(&(objectCategory=person)(objectClass=user))"),
+		Attributes: Attributes{
+			LoginID:    getEnv("LDAP_ATTR_LOGIN_ID", "sAMAccountName"),
+			Email:      getEnv("LDAP_ATTR_EMAIL", "mail"),
+			Name:       getEnv("LDAP_ATTR_NAME", "displayName"),
+			Department: getEnv("LDAP_ATTR_DEPARTMENT", "department"),
+			Groups:     getEnv("LDAP_ATTR_GROUPS", "memberOf"),
+		},
+		PageSize: getIntEnv("LDAP_PAGE_SIZE", 500),
+		Timeout:  getDurationEnv("LDAP_TIMEOUT", 10*time.Second),
+	}
+
+	// The directory is optional - return nil config if not configured
+	if cfg.URL == "" {
+		return nil, nil
+	}
+
+	if cfg.BaseDN == "" {
+		return nil, fmt.Errorf("LDAP_BASE_DN is required when LDAP_URL is set")
+	}
+	if !strings.Contains(cfg.UserFilter, "{login_id}") {
+		return nil, fmt.Errorf("LDAP_USER_FILTER must contain {login_id}")
+	}
+	if _, err := compileFilter(cfg.UserFilter); err != nil {
+		return nil, err
+	}
+	if _, err := compileFilter(cfg.SyncFilter); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Client reads users from an LDAP directory such as Active Directory.
+// Each call uses its own connection.
+type Client struct {
+	config *Config
+}
+
+// NewClient creates a new LDAP client
+func NewClient(cfg *Config) *Client {
+	return &Client{config: cfg}
+}
+
+// Authenticate finds the user with the search account, then binds as the user to check the password
+func (c *Client) Authenticate(ctx context.Context, loginID, password string) (*Entry, error) {
+	// A simple bind with an empty password is an unauthenticated bind, which servers accept
+	if loginID == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+
+	filter := strings.ReplaceAll(c.config.UserFilter, "{login_id}", EscapeFilter(loginID))
+	entries, err := conn.search(ctx, c.config.BaseDN, filter, c.attributes(), 2, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	// An ambiguous login ID is not guessed
+	if len(entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := conn.bind(ctx, entries[0].dn, password); err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	entry := c.toEntry(entries[0])
+	return &entry, nil
+}
+
+// Users returns all users matched by the sync filter, reading them in pages
+func (c *Client) Users(ctx context.Context) ([]Entry, error) {
+	conn, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+
+	raw, err := conn.search(ctx, c.config.BaseDN, c.config.SyncFilter, c.attributes(), 0, c.config.PageSize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	entries := make([]Entry, 0, len(raw))
+	for _, r := range raw {
+		entries = append(entries, c.toEntry(r))
+	}
+	return entries, nil
+}
+
+// connect dials the server and binds with the search account
+func (c *Client) connect(ctx context.Context) (*conn, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if err := conn.bind(ctx, c.config.BindDN, c.config.BindPassword); err != nil {
+		conn.close()
+		return nil, fmt.Errorf("%w: bind as search account failed: %v", ErrUnavailable, err)
+	}
+	return conn, nil
+}
+
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	u, err := url.Parse(c.config.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         u.Hostname(),
+		InsecureSkipVerify: c.config.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	dialer := &net.Dialer{Timeout: c.config.Timeout}
+
+	var netConn net.Conn
+	switch u.Scheme {
+	case "ldaps":
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: tlsConfig}
+		netConn, err = tlsDialer.DialContext(ctx, "tcp", hostPort(u, "636"))
+	case "ldap":
+		netConn, err = dialer.DialContext(ctx, "tcp", hostPort(u, "389"))
+	default:
+		return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	conn := newConn(netConn, c.config.Timeout)
+	if u.Scheme == "ldap" && c.config.StartTLS {
+		if err := conn.startTLS(ctx, tlsConfig); err != nil {
+			conn.netConn.Close()
+			return nil, fmt.Errorf("StartTLS failed: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+func (c *Client) attributes() []string {
+	a := c.config.Attributes
+	return []string{a.LoginID, a.Email, a.Name, a.Department, a.Groups, attrAccountControl}
+}
+
+// toEntry maps a search result to an Entry
+func (c *Client) toEntry(r searchEntry) Entry {
+	a := c.config.Attributes
+	entry := Entry{
+		DN:                  r.dn,
+		LoginID:             r.first(a.LoginID),
+		Email:               r.first(a.Email),
+		Name:                r.first(a.Name),
+		Department:          r.first(a.Department),
+		OrganizationalUnits: organizationalUnits(r.dn),
+	}
+	for _, group := range r.attributes[strings.ToLower(a.Groups)] {
+		entry.Groups = append(entry.Groups, CommonName(group))
+	}
+	if control, err := strconv.ParseInt(r.first(attrAccountControl), 10, 64); err == nil {
+		entry.Disabled = control&accountDisabled != 0
+	}
+	return entry
+}
+
+func hostPort(u *url.URL, defaultPort string) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Hostname(), defaultPort)
+}
+
+// -------------------- Connection --------------------
+
+// resultError is a non-success LDAP result
+type resultError struct {
+	Code    int64
+	Message string
+}
+
+func (e *resultError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("LDAP result code %d", e.Code)
+	}
+	return fmt.Sprintf("LDAP result code %d: %s", e.Code, e.Message)
+}
+
+// searchEntry is a search result with lowercased attribute names
+type searchEntry struct {
+	dn         string
+	attributes map[string][]string
+}
+
+func (e searchEntry) first(attribute string) string {
+	if values := e.attributes[strings.ToLower(attribute)]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// conn is a connection running one operation at a time
+type conn struct {
+	netConn net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+	nextID  int64
+}
+
+func newConn(netConn net.Conn, timeout time.Duration) *conn {
+	return &conn{netConn: netConn, reader: bufio.NewReader(netConn), timeout: timeout}
+}
+
+// roundTrip sends a request and calls handle for each response message until it returns true
+func (c *conn) roundTrip(ctx context.Context, op *packet, controls *packet, handle func(op, controls *packet) (bool, error)) error {
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.netConn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	c.nextID++
+	message := newSequence(newInteger(c.nextID), op)
+	if controls != nil {
+		message.children = append(message.children, controls)
+	}
+	if _, err := c.netConn.Write(message.bytes()); err != nil {
+		return err
+	}
+
+	for {
+		response, err := readPacket(c.reader)
+		if err != nil {
+			return err
+		}
+		if len(response.children) < 2 {
+			return errMalformedPacket
+		}
+		if id, err := response.children[0].int(); err != nil || id != c.nextID {
+			return fmt.Errorf("unexpected message ID in response")
+		}
+		var responseControls *packet
+		if len(response.children) > 2 && response.children[2].is(classContext, 0) {
+			responseControls = response.children[2]
+		}
+		done, err := handle(response.children[1], responseControls)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// bind performs a simple bind
+func (c *conn) bind(ctx context.Context, dn, password string) error {
+	op := newConstructed(classApplication, opBindRequest,
+		newInteger(3),
+		newOctetString(dn),
+		newPrimitive(classContext, 0, []byte(password)),
+	)
+	return c.roundTrip(ctx, op, nil, func(op, _ *packet) (bool, error) {
+		if !op.is(classApplication, opBindResponse) {
+			return false, errMalformedPacket
+		}
+		if err := checkResult(op); err != nil {
+			var result *resultError
+			if errors.As(err, &result) && result.Code == resultInvalidCredential {
+				return true, ErrInvalidCredentials
+			}
+			return true, err
+		}
+		return true, nil
+	})
+}
+
+// startTLS upgrades the connection to TLS (RFC 4511, section 4.14)
+func (c *conn) startTLS(ctx context.Context, config *tls.Config) error {
+	op := newConstructed(classApplication, opExtendedRequest,
+		newPrimitive(classContext, 0, []byte(oidStartTLS)),
+	)
+	err := c.roundTrip(ctx, op, nil, func(op, _ *packet) (bool, error) {
+		if !op.is(classApplication, opExtendedResponse) {
+			return false, errMalformedPacket
+		}
+		return true, checkResult(op)
+	})
+	if err != nil {
+		return err
+	}
+
+	tlsConn := tls.Client(c.netConn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return err
+	}
+	c.netConn = tlsConn
+	c.reader = bufio.NewReader(tlsConn)
+	return nil
+}
+
+// search runs a subtree search. With a page size, the results are read in pages
+// (RFC 2696) until the server has returned all of them.
+func (c *conn) search(ctx context.Context, baseDN, filter string, attributes []string, sizeLimit, pageSize int) ([]searchEntry, error) {
+	compiled, err := compileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	attributeList := newSequence()
+	for _, attribute := range attributes {
+		attributeList.children = append(attributeList.children, newOctetString(attribute))
+	}
+
+	var entries []searchEntry
+	var cookie string
+	for {
+		op := newConstructed(classApplication, opSearchRequest,
+			newOctetString(baseDN),
+			newEnumerated(scopeWholeSubtree),
+			newEnumerated(derefNever),
+			newInteger(int64(sizeLimit)),
+			newInteger(0),
+			newBoolean(false),
+			compiled,
+			attributeList,
+		)
+		var controls *packet
+		if pageSize > 0 {
+			controls = newConstructed(classContext, 0, newPagedResultsControl(pageSize, cookie))
+		}
+
+		cookie = ""
+		err := c.roundTrip(ctx, op, controls, func(op, responseControls *packet) (bool, error) {
+			switch {
+			case op.is(classApplication, opSearchResultEntry):
+				entry, err := parseSearchEntry(op)
+				if err != nil {
+					return false, err
+				}
+				entries = append(entries, entry)
+				return false, nil
+			case op.is(classApplication, opSearchResultRef):
+				// Referrals to other servers are not followed
+				return false, nil
+			case op.is(classApplication, opSearchResultDone):
+				// Hitting the size limit of the search still returns the entries up to it. A server
+				// limit cut off a search for all entries, which must not pass for a complete result.
+				var result *resultError
+				if err := checkResult(op); errors.As(err, &result) && result.Code == resultSizeLimitExceeded && sizeLimit > 0 && pageSize == 0 {
+					return true, nil
+				} else if err != nil {
+					return true, err
+				}
+				cookie = pagedResultsCookie(responseControls)
+				return true, nil
+			default:
+				return false, errMalformedPacket
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		if cookie == "" {
+			return entries, nil
+		}
+	}
+}
+
+// close unbinds and closes the connection
+func (c *conn) close() {
+	c.nextID++
+	message := newSequence(newInteger(c.nextID), newPrimitive(classApplication, opUnbindRequest, nil))
+	c.netConn.SetDeadline(time.Now().Add(time.Second))
+	c.netConn.Write(message.bytes())
+	c.netConn.Close()
+}
+
+// checkResult returns the error of a non-success LDAPResult
+func checkResult(op *packet) error {
+	if len(op.children) < 3 {
+		return errMalformedPacket
+	}
+	code, err := op.children[0].int()
+	if err != nil {
+		return err
+	}
+	if code == resultSuccess {
+		return nil
+	}
+	return &resultError{Code: code, Message: op.children[2].str()}
+}
+
+func parseSearchEntry(op *packet) (searchEntry, error) {
+	dn, err := op.child(0)
+	if err != nil {
+		return searchEntry{}, err
+	}
+	list, err := op.child(1)
+	if err != nil {
+		return searchEntry{}, err
+	}
+
+	entry := searchEntry{dn: dn.str(), attributes: make(map[string][]string)}
+	for _, attribute := range list.children {
+		name, err := attribute.child(0)
+		if err != nil {
+			return searchEntry{}, err
+		}
+		values, err := attribute.child(1)
+		if err != nil {
+			return searchEntry{}, err
+		}
+		key := strings.ToLower(name.str())
+		for _, value := range values.children {
+			entry.attributes[key] = append(entry.attributes[key], value.str())
+		}
+	}
+	return entry, nil
+}
+
+func newPagedResultsControl(pageSize int, cookie string) *packet {
+	value := newSequence(newInteger(int64(pageSize)), newOctetString(cookie))
+	return newSequence(
+		newOctetString(oidPagedResults),
+		newBoolean(false),
+		newOctetString(string(value.bytes())),
+	)
+}
+
+// pagedResultsCookie returns the cookie for the next page, or "" on the last page
+func pagedResultsCookie(controls *packet) string {
+	if controls == nil {
+		return ""
+	}
+	for _, control := range controls.children {
+		if len(control.children) < 2 || control.children[0].str() != oidPagedResults {
+			continue
+		}
+		value, err := parsePacket(control.children[len(control.children)-1].value)
+		if err != nil || len(value.children) < 2 {
+			return ""
+		}
+		return value.children[1].str()
+	}
+	return ""
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
+}
+
+func getBoolEnv(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,230 @@
+package ldap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+const (
+	testBaseDN     = "dc=example,dc=com"
+	testBindDN     = "cn=svc-kc,ou=Service,dc=example,dc=com"
+	testBindSecret = "search-secret"
+)
+
+func newTestDirectory(t *testing.T) (*FakeServer, *Client) {
+	t.Helper()
+
+	server, err := NewFakeServer()
+	if err != nil {
+		t.Fatalf("failed to start fake server: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	server.Add(testBindDN, testBindSecret, map[string][]string{"objectClass": {"user"}, "sAMAccountName": {"svc-kc"}})
+	server.Add("cn=Jane Doe,ou=Backend,ou=Engineering,dc=example,dc=com", "jane-secret", map[string][]string{
+		"objectClass":    {"user"},
+		"objectCategory": {"person"},
+		"sAMAccountName": {"jdoe"},
+		"mail":           {"jane.doe@example.com"},
+		"displayName":    {"Jane Doe"},
+		"department":     {"Backend"},
+		"memberOf":       {"CN=KC Admins,OU=Groups,DC=example,DC=com", "CN=Staff\\, All,OU=Groups,DC=example,DC=com"},
+	})
+	server.Add("cn=Old Timer,ou=Sales,dc=example,dc=com", "old-secret", map[string][]string{
+		"objectClass":        {"user"},
+		"objectCategory":     {"person"},
+		"sAMAccountName":     {"otimer"},
+		"mail":               {"old.timer@example.com"},
+		"userAccountControl": {"514"},
+	})
+
+	client := NewClient(&Config{
+		URL:          server.URL(),
+		BindDN:       testBindDN,
+		BindPassword: testBindSecret,
+		BaseDN:       testBaseDN,
+		UserFilter:   "(&(objectCategory=person)(objectClass=user)(sAMAccountName={login_id}))",
+		SyncFilter:   "(&(objectCategory=person)(objectClass=user))",
+		Attributes: Attributes{
+			LoginID:    "sAMAccountName",
+			Email:      "mail",
+			Name:       "displayName",
+			Department: "department",
+			Groups:     "memberOf",
+		},
+		PageSize: 2,
+		Timeout:  5 * time.Second,
+	})
+	return server, client
+}
+
+func TestClient_Authenticate(t *testing.T) {
+	_, client := newTestDirectory(t)
+
+	entry, err := client.Authenticate(context.Background(), "jdoe", "jane-secret")
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+
+	if entry.DN != "cn=Jane Doe,ou=Backend,ou=Engineering,dc=example,dc=com" {
+		t.Errorf("DN = %q", entry.DN)
+	}
+	if entry.LoginID != "jdoe" || entry.Email != "jane.doe@example.com" || entry.Name != "Jane Doe" || entry.Department != "Backend" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if !slices.Equal(entry.OrganizationalUnits, []string{"Engineering", "Backend"}) {
+		t.Errorf("OrganizationalUnits = %v", entry.OrganizationalUnits)
+	}
+	if !slices.Equal(entry.Groups, []string{"KC Admins", "Staff, All"}) {
+		t.Errorf("Groups = %v", entry.Groups)
+	}
+	if entry.Disabled {
+		t.Error("expected enabled account")
+	}
+}
+
+func TestClient_AuthenticateRejected(t *testing.T) {
+	_, client := newTestDirectory(t)
+
+	tests := []struct {
+		name     string
+		loginID  string
+		password string
+	}{
+		{"wrong password", "jdoe", "wrong"},
+		{"empty password", "jdoe", ""},
+		{"unknown user", "nobody", "jane-secret"},
+		{"filter injection", "*", "jane-secret"},
+		{"filter injection with parenthesis", "jdoe)(sAMAccountName=*", "jane-secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Authenticate(context.Background(), tt.loginID, tt.password)
+			if !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("expected ErrInvalidCredentials, got %v", err)
+			}
+		})
+	}
+}
+
+func TestClient_Unavailable(t *testing.T) {
+	server, client := newTestDirectory(t)
+
+	// Wrong search account credentials are a configuration problem, not a failed login
+	client.config.BindPassword = "wrong"
+	if _, err := client.Authenticate(context.Background(), "jdoe", "jane-secret"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable for a failed search account bind, got %v", err)
+	}
+
+	client.config.BindPassword = testBindSecret
+	server.Close()
+	if _, err := client.Users(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable for a stopped server, got %v", err)
+	}
+}
+
+func TestClient_Users(t *testing.T) {
+	server, client := newTestDirectory(t)
+
+	// More users than fit on one page
+	for i := 1; i <= 5; i++ {
+		server.Add(fmt.Sprintf("cn=User %d,ou=Sales,dc=example,dc=com", i), "", map[string][]string{
+			"objectClass":    {"user"},
+			"objectCategory": {"person"},
+			"sAMAccountName": {fmt.Sprintf("user%d", i)},
+		})
+	}
+
+	entries, err := client.Users(context.Background())
+	if err != nil {
+		t.Fatalf("Users failed: %v", err)
+	}
+	if len(entries) != 7 {
+		t.Fatalf("expected 7 users, got %d", len(entries))
+	}
+
+	byLogin := make(map[string]Entry)
+	for _, entry := range entries {
+		byLogin[entry.LoginID] = entry
+	}
+	if _, ok := byLogin["svc-kc"]; ok {
+		t.Error("the search account is not a person and should not be returned")
+	}
+	if !byLogin["otimer"].Disabled {
+		t.Error("expected otimer to be disabled")
+	}
+	if byLogin["user3"].DN != "cn=User 3,ou=Sales,dc=example,dc=com" {
+		t.Errorf("unexpected entry %+v", byLogin["user3"])
+	}
+}
+
+func TestClient_UsersSizeLimitExceeded(t *testing.T) {
+	for _, pageSize := range []int{0, 2} {
+		t.Run(fmt.Sprintf("page size %d", pageSize), func(t *testing.T) {
+			server, client := newTestDirectory(t)
+			client.config.PageSize = pageSize
+			server.SetSizeLimit(1)
+
+			// A partial list of users would make the sync delete the users left out
+			entries, err := client.Users(context.Background())
+			if !errors.Is(err, ErrUnavailable) {
+				t.Fatalf("expected ErrUnavailable, got %v", err)
+			}
+			if entries != nil {
+				t.Errorf("expected no users, got %d", len(entries))
+			}
+
+			// Authentication only needs the first entries
+			if _, err := client.Authenticate(context.Background(), "jdoe", "jane-secret"); err != nil {
+				t.Errorf("expected authentication to pass, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCompileFilter(t *testing.T) {
+	valid := []string{
+		"(uid=jdoe)",
+		"(&(objectClass=user)(!(mail=*)))",
+		"(|(cn=J*n)(cn=*Doe)(cn=*an*))",
+		"(cn=a\\2ab)",
+		"(uidNumber>=1000)",
+	}
+	for _, filter := range valid {
+		if _, err := compileFilter(filter); err != nil {
+			t.Errorf("compileFilter(%q) failed: %v", filter, err)
+		}
+	}
+
+	invalid := []string{"uid=jdoe", "(uid=jdoe", "(&(uid=a)", "(=x)", "(cn=a\\2)", "(uid=a))"}
+	for _, filter := range invalid {
+		if _, err := compileFilter(filter); err == nil {
+			t.Errorf("compileFilter(%q) should fail", filter)
+		}
+	}
+}
+
+func TestEscapeFilter(t *testing.T) {
+	if got := EscapeFilter(`a*b(c)\d`); got != `a\2ab\28c\29\5cd` {
+		t.Errorf("EscapeFilter = %q", got)
+	}
+}
+
+func TestCommonName(t *testing.T) {
+	tests := map[string]string{
+		"CN=KC Admins,OU=Groups,DC=example,DC=com": "KC Admins",
+		`cn=Doe\, Jane,ou=People,dc=example`:       "Doe, Jane",
+		`cn=Sm\C3\B8rrebr\C3\B8d,dc=example`:       "Smørrebrød",
+		"ou=Groups,dc=example":                     "ou=Groups,dc=example",
+	}
+	for dn, want := range tests {
+		if got := CommonName(dn); got != want {
+			t.Errorf("CommonName(%q) = %q, want %q", dn, got, want)
+		}
+	}
+}
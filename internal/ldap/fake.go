@@ -0,0 +1,373 @@
+package ldap
+
+import (
+	"bufio"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// -------------------- Fake Server --------------------
+
+// FakeServer is a local LDAP server supporting simple binds and searches with paging.
+// It is meant for tests and local development.
+type FakeServer struct {
+	listener net.Listener
+
+	mu        sync.Mutex
+	entries   map[string]*fakeEntry // by normalized DN
+	sizeLimit int                   // administrative limit on the entries a search returns; 0 for none
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup
+}
+
+// fakeResponse is a response message with optional controls
+type fakeResponse struct {
+	op       *packet
+	controls *packet
+}
+
+// fakeEntry is a directory entry with lowercased attribute names
+type fakeEntry struct {
+	dn         string
+	password   string
+	attributes map[string][]string
+}
+
+// NewFakeServer starts a fake server on a local port. Call Close when done.
+func NewFakeServer() (*FakeServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FakeServer{
+		listener: listener,
+		entries:  make(map[string]*fakeEntry),
+		conns:    make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// URL returns the ldap:// URL of the server
+func (s *FakeServer) URL() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+// Close shuts the server down and closes open connections
+func (s *FakeServer) Close() {
+	s.listener.Close()
+	s.mu.Lock()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Add adds or replaces an entry. An entry with a password can bind.
+func (s *FakeServer) Add(dn, password string, attributes map[string][]string) {
+	entry := &fakeEntry{dn: dn, password: password, attributes: make(map[string][]string)}
+	for name, values := range attributes {
+		entry.attributes[strings.ToLower(name)] = values
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[normalizeDN(dn)] = entry
+}
+
+// SetSizeLimit sets the administrative limit on the entries a search returns, like the
+// MaxResultSetSize of Active Directory. 0 removes the limit.
+func (s *FakeServer) SetSizeLimit(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sizeLimit = limit
+}
+
+// Remove removes an entry
+func (s *FakeServer) Remove(dn string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, normalizeDN(dn))
+}
+
+func (s *FakeServer) serve() {
+	defer s.wg.Done()
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(c)
+			s.mu.Lock()
+			delete(s.conns, c)
+			s.mu.Unlock()
+			c.Close()
+		}()
+	}
+}
+
+// handle serves the requests of one connection until it is unbound or closed
+func (s *FakeServer) handle(c net.Conn) {
+	reader := bufio.NewReader(c)
+	for {
+		message, err := readPacket(reader)
+		if err != nil || len(message.children) < 2 {
+			return
+		}
+		id, err := message.children[0].int()
+		if err != nil {
+			return
+		}
+		op := message.children[1]
+		var controls *packet
+		if len(message.children) > 2 {
+			controls = message.children[2]
+		}
+
+		var responses []fakeResponse
+		switch {
+		case op.is(classApplication, opBindRequest):
+			responses = []fakeResponse{{op: s.bind(op)}}
+		case op.is(classApplication, opSearchRequest):
+			responses = s.search(op, controls)
+		case op.is(classApplication, opUnbindRequest):
+			return
+		case op.is(classApplication, opExtendedRequest):
+			// StartTLS and other extended operations are not supported
+			responses = []fakeResponse{{op: fakeResult(opExtendedResponse, 2, "unsupported extended operation")}}
+		default:
+			return
+		}
+
+		for _, response := range responses {
+			message := newSequence(newInteger(id), response.op)
+			if response.controls != nil {
+				message.children = append(message.children, newConstructed(classContext, 0, response.controls))
+			}
+			if _, err := c.Write(message.bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *FakeServer) bind(op *packet) *packet {
+	if len(op.children) < 3 {
+		return fakeResult(opBindResponse, 2, "malformed bind request")
+	}
+	dn, password := op.children[1].str(), op.children[2].str()
+
+	s.mu.Lock()
+	entry, ok := s.entries[normalizeDN(dn)]
+	s.mu.Unlock()
+
+	if !ok || entry.password == "" || entry.password != password {
+		return fakeResult(opBindResponse, resultInvalidCredential, "invalid credentials")
+	}
+	return fakeResult(opBindResponse, resultSuccess, "")
+}
+
+// search returns the matching entries in DN order, paged if the request asks for it
+func (s *FakeServer) search(op *packet, controls *packet) []fakeResponse {
+	if len(op.children) < 8 {
+		return []fakeResponse{{op: fakeResult(opSearchResultDone, 2, "malformed search request")}}
+	}
+	base := normalizeDN(op.children[0].str())
+	scope, _ := op.children[1].int()
+	sizeLimit, _ := op.children[3].int()
+	filter := op.children[6]
+
+	var requested []string
+	for _, attribute := range op.children[7].children {
+		requested = append(requested, strings.ToLower(attribute.str()))
+	}
+
+	s.mu.Lock()
+	if s.sizeLimit > 0 && (sizeLimit == 0 || sizeLimit > int64(s.sizeLimit)) {
+		sizeLimit = int64(s.sizeLimit)
+	}
+	var matches []*fakeEntry
+	for key, entry := range s.entries {
+		if inScope(key, base, scope) && matchFilter(filter, entry) {
+			matches = append(matches, entry)
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(matches, func(i, j int) bool { return matches[i].dn < matches[j].dn })
+
+	// The paged results cookie is the offset of the next page
+	pageSize, offset, paged := pagedRequest(controls)
+	if paged {
+		matches = matches[min(offset, len(matches)):]
+	}
+
+	// The size limit applies to all pages together
+	var responses []fakeResponse
+	for i, entry := range matches {
+		if sizeLimit > 0 && int64(offset+i) == sizeLimit {
+			return append(responses, fakeResponse{op: fakeResult(opSearchResultDone, resultSizeLimitExceeded, "size limit exceeded")})
+		}
+		if paged && i == pageSize {
+			return append(responses, fakeResponse{
+				op:       fakeResult(opSearchResultDone, resultSuccess, ""),
+				controls: newPagedResultsControl(0, strconv.Itoa(offset+pageSize)),
+			})
+		}
+		responses = append(responses, fakeResponse{op: entry.toPacket(requested)})
+	}
+
+	done := fakeResponse{op: fakeResult(opSearchResultDone, resultSuccess, "")}
+	if paged {
+		done.controls = newPagedResultsControl(0, "")
+	}
+	return append(responses, done)
+}
+
+// toPacket encodes the entry with the requested attributes (all if none are requested)
+func (e *fakeEntry) toPacket(requested []string) *packet {
+	attributes := newSequence()
+	for name, values := range e.attributes {
+		if len(requested) > 0 && !containsFold(requested, name) {
+			continue
+		}
+		set := newConstructed(classUniversal, tagSet)
+		for _, value := range values {
+			set.children = append(set.children, newOctetString(value))
+		}
+		attributes.children = append(attributes.children, newSequence(newOctetString(name), set))
+	}
+	return newConstructed(classApplication, opSearchResultEntry, newOctetString(e.dn), attributes)
+}
+
+func fakeResult(op int, code int64, message string) *packet {
+	return newConstructed(classApplication, op,
+		newEnumerated(code),
+		newOctetString(""),
+		newOctetString(message),
+	)
+}
+
+// pagedRequest returns the page size and offset of a paged results control
+func pagedRequest(controls *packet) (pageSize, offset int, ok bool) {
+	if controls == nil {
+		return 0, 0, false
+	}
+	for _, control := range controls.children {
+		if len(control.children) < 2 || control.children[0].str() != oidPagedResults {
+			continue
+		}
+		value, err := parsePacket(control.children[len(control.children)-1].value)
+		if err != nil || len(value.children) < 2 {
+			return 0, 0, false
+		}
+		size, err := value.children[0].int()
+		if err != nil || size < 1 {
+			return 0, 0, false
+		}
+		offset, _ = strconv.Atoi(value.children[1].str())
+		return int(size), offset, true
+	}
+	return 0, 0, false
+}
+
+// inScope reports whether a normalized DN is within the scope of a search base
+func inScope(dn, base string, scope int64) bool {
+	switch scope {
+	case scopeBaseObject:
+		return dn == base
+	case scopeSingleLevel:
+		_, parent, _ := strings.Cut(dn, ",")
+		return parent == base
+	default:
+		return dn == base || strings.HasSuffix(dn, ","+base) || base == ""
+	}
+}
+
+// matchFilter evaluates a filter against an entry. Values are compared case-insensitively;
+// ordering and approximate matches are not supported and never match.
+func matchFilter(filter *packet, entry *fakeEntry) bool {
+	switch filter.tag {
+	case filterAnd:
+		for _, child := range filter.children {
+			if !matchFilter(child, entry) {
+				return false
+			}
+		}
+		return true
+	case filterOr:
+		for _, child := range filter.children {
+			if matchFilter(child, entry) {
+				return true
+			}
+		}
+		return false
+	case filterNot:
+		return len(filter.children) == 1 && !matchFilter(filter.children[0], entry)
+	case filterPresent:
+		return len(entry.attributes[strings.ToLower(filter.str())]) > 0
+	case filterEqualityMatch:
+		if len(filter.children) != 2 {
+			return false
+		}
+		values := entry.attributes[strings.ToLower(filter.children[0].str())]
+		return containsFold(values, filter.children[1].str())
+	case filterSubstrings:
+		if len(filter.children) != 2 {
+			return false
+		}
+		for _, value := range entry.attributes[strings.ToLower(filter.children[0].str())] {
+			if matchSubstrings(strings.ToLower(value), filter.children[1].children) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func matchSubstrings(value string, parts []*packet) bool {
+	for _, part := range parts {
+		s := strings.ToLower(part.str())
+		switch part.tag {
+		case substringInitial:
+			if !strings.HasPrefix(value, s) {
+				return false
+			}
+			value = value[len(s):]
+		case substringAny:
+			i := strings.Index(value, s)
+			if i < 0 {
+				return false
+			}
+			value = value[i+len(s):]
+		case substringFinal:
+			if !strings.HasSuffix(value, s) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func containsFold(values []string, s string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Filter choices (RFC 4511, section 4.5.1.7)
+const (
+	filterAnd            = 0
+	filterOr             = 1
+	filterNot            = 2
+	filterEqualityMatch  = 3
+	filterSubstrings     = 4
+	filterGreaterOrEqual = 5
+	filterLessOrEqual    = 6
+	filterPresent        = 7
+	filterApproxMatch    = 8
+	substringInitial     = 0
+	substringAny         = 1
+	substringFinal       = 2
+)
+
+// filterSpecials are the characters escaped in filter values
+const filterSpecials = `\*()` + "\x00"
+
+// EscapeFilter escapes a value for use in a search filter (RFC 4515), so user input
+// such as a login ID cannot change the filter
+func EscapeFilter(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if strings.IndexByte(filterSpecials, value[i]) >= 0 {
+			fmt.Fprintf(&b, `\%02x`, value[i])
+		} else {
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
+
+// compileFilter encodes a search filter in its string form (RFC 4515)
+func compileFilter(filter string) (*packet, error) {
+	p, rest, err := parseFilter(strings.TrimSpace(filter))
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", filter, err)
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("invalid filter %q: unexpected %q", filter, rest)
+	}
+	return p, nil
+}
+
+func parseFilter(s string) (*packet, string, error) {
+	if !strings.HasPrefix(s, "(") || len(s) < 2 {
+		return nil, "", fmt.Errorf("expected ( at %q", s)
+	}
+	s = s[1:]
+
+	var p *packet
+	switch s[0] {
+	case '&', '|':
+		tag := filterAnd
+		if s[0] == '|' {
+			tag = filterOr
+		}
+		p = newConstructed(classContext, tag)
+		s = s[1:]
+		for strings.HasPrefix(s, "(") {
+			child, rest, err := parseFilter(s)
+			if err != nil {
+				return nil, "", err
+			}
+			p.children = append(p.children, child)
+			s = rest
+		}
+	case '!':
+		child, rest, err := parseFilter(s[1:])
+		if err != nil {
+			return nil, "", err
+		}
+		p = newConstructed(classContext, filterNot, child)
+		s = rest
+	default:
+		end := strings.IndexByte(s, ')')
+		if end < 0 {
+			return nil, "", fmt.Errorf("missing ) in %q", s)
+		}
+		item, err := parseItem(s[:end])
+		if err != nil {
+			return nil, "", err
+		}
+		p = item
+		s = s[end:]
+	}
+
+	if !strings.HasPrefix(s, ")") {
+		return nil, "", fmt.Errorf("expected ) at %q", s)
+	}
+	return p, s[1:], nil
+}
+
+// parseItem encodes a simple filter item such as mail=*, cn=J*n or uid=jdoe
+func parseItem(item string) (*packet, error) {
+	eq := strings.IndexByte(item, '=')
+	if eq < 1 {
+		return nil, fmt.Errorf("invalid item %q", item)
+	}
+	attr, value := item[:eq], item[eq+1:]
+
+	tag := filterEqualityMatch
+	switch attr[len(attr)-1] {
+	case '>':
+		tag = filterGreaterOrEqual
+	case '<':
+		tag = filterLessOrEqual
+	case '~':
+		tag = filterApproxMatch
+	}
+	if tag != filterEqualityMatch {
+		attr = attr[:len(attr)-1]
+	}
+	if attr == "" {
+		return nil, fmt.Errorf("invalid item %q", item)
+	}
+
+	if tag == filterEqualityMatch && value == "*" {
+		return newPrimitive(classContext, filterPresent, []byte(attr)), nil
+	}
+
+	// Unescaped asterisks are wildcards; escaped ones (\2a) are literal
+	if tag == filterEqualityMatch && strings.Contains(value, "*") {
+		parts := strings.Split(value, "*")
+		substrings := newSequence()
+		for i, part := range parts {
+			if part == "" {
+				continue
+			}
+			unescaped, err := unescapeFilterValue(part)
+			if err != nil {
+				return nil, err
+			}
+			kind := substringAny
+			if i == 0 {
+				kind = substringInitial
+			} else if i == len(parts)-1 {
+				kind = substringFinal
+			}
+			substrings.children = append(substrings.children, newPrimitive(classContext, kind, []byte(unescaped)))
+		}
+		return newConstructed(classContext, filterSubstrings, newOctetString(attr), substrings), nil
+	}
+
+	unescaped, err := unescapeFilterValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return newConstructed(classContext, tag, newOctetString(attr), newOctetString(unescaped)), nil
+}
+
+// unescapeFilterValue decodes \XX escapes of a filter value
+func unescapeFilterValue(value string) (string, error) {
+	if !strings.Contains(value, `\`) {
+		return value, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			b.WriteByte(value[i])
+			continue
+		}
+		if i+3 > len(value) {
+			return "", fmt.Errorf("invalid escape in %q", value)
+		}
+		decoded, err := hex.DecodeString(value[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in %q", value)
+		}
+		b.Write(decoded)
+		i += 2
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,137 @@
+package ldap
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid directory credentials")
+	ErrUnavailable        = errors.New("directory server unavailable")
+)
+
+// Entry is a user read from the directory
+type Entry struct {
+	DN      string
+	LoginID string
+	Email   string
+	Name    string
+
+	// Department is the value of the department attribute
+	Department string
+
+	// OrganizationalUnits are the OU names of the DN, outermost first,
+	// e.g. [Staff Engineering] for cn=Jane,ou=Engineering,ou=Staff,dc=example,dc=com
+	OrganizationalUnits []string
+
+	// Groups are the common names of the groups the user is a member of
+	Groups []string
+
+	// Disabled is set for accounts disabled in Active Directory (userAccountControl)
+	Disabled bool
+}
+
+// Directory defines the interface of an LDAP directory holding the users
+type Directory interface {
+	// Authenticate checks the password of a user by binding as them and returns their entry.
+	// Returns ErrInvalidCredentials for unknown users and wrong passwords.
+	Authenticate(ctx context.Context, loginID, password string) (*Entry, error)
+
+	// Users returns all users matched by the sync filter. Returns an error rather than the users
+	// read so far if the server cuts the search off, e.g. at its size limit.
+	Users(ctx context.Context) ([]Entry, error)
+}
+
+// rdn is a relative distinguished name such as ou=Engineering
+type rdn struct {
+	Type  string
+	Value string
+}
+
+// parseDN splits a distinguished name into its RDNs (RFC 4514), outermost last.
+// Multi-valued RDNs (a+b) are kept as one value.
+func parseDN(dn string) []rdn {
+	var rdns []rdn
+	var current strings.Builder
+	flush := func() {
+		part := strings.TrimSpace(current.String())
+		current.Reset()
+		if typ, value, ok := strings.Cut(part, "="); ok {
+			rdns = append(rdns, rdn{Type: strings.ToLower(strings.TrimSpace(typ)), Value: unescapeDNValue(strings.TrimSpace(value))})
+		}
+	}
+
+	for i := 0; i < len(dn); i++ {
+		switch dn[i] {
+		case '\\':
+			// Keep escapes for unescapeDNValue
+			current.WriteByte(dn[i])
+			if i+1 < len(dn) {
+				i++
+				current.WriteByte(dn[i])
+			}
+		case ',', ';':
+			flush()
+		default:
+			current.WriteByte(dn[i])
+		}
+	}
+	flush()
+	return rdns
+}
+
+// unescapeDNValue decodes \, style and \2C style escapes of an attribute value
+func unescapeDNValue(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 >= len(value) {
+			b.WriteByte(value[i])
+			continue
+		}
+		if i+2 < len(value) {
+			if decoded, err := hex.DecodeString(value[i+1 : i+3]); err == nil {
+				b.Write(decoded)
+				i += 2
+				continue
+			}
+		}
+		i++
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
+
+// CommonName returns the CN of a distinguished name, or the DN itself if it has none
+func CommonName(dn string) string {
+	rdns := parseDN(dn)
+	if len(rdns) > 0 && rdns[0].Type == "cn" {
+		return rdns[0].Value
+	}
+	return dn
+}
+
+// organizationalUnits returns the OU names of a DN, outermost first
+func organizationalUnits(dn string) []string {
+	var units []string
+	for _, r := range parseDN(dn) {
+		if r.Type == "ou" {
+			units = append([]string{r.Value}, units...)
+		}
+	}
+	return units
+}
+
+// normalizeDN lowercases a DN and removes spaces around separators, for comparisons
+func normalizeDN(dn string) string {
+	rdns := parseDN(dn)
+	parts := make([]string, len(rdns))
+	for i, r := range rdns {
+		parts[i] = r.Type + "=" + strings.ToLower(r.Value)
+	}
+	return strings.Join(parts, ",")
+}
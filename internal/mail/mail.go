@@ -0,0 +1,49 @@
+package mail
+
+import (
+	"context"
+	"sync"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Sender defines the interface for delivering email
+type Sender interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// -------------------- In-memory Sender --------------------
+
+// MemorySender keeps sent messages in memory instead of delivering them.
+// It is meant for tests and local development.
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemorySender creates an in-memory sender
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+// Send records the message
+func (m *MemorySender) Send(ctx context.Context, msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = append(m.messages, *msg)
+	return nil
+}
+
+// Messages returns a copy of the messages sent so far
+func (m *MemorySender) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Message(nil), m.messages...)
+}
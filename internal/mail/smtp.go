@@ -0,0 +1,254 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds SMTP configuration
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+
+	// ImplicitTLS connects with TLS from the start (usually port 465) instead of using STARTTLS
+	ImplicitTLS bool
+
+	Timeout time.Duration
+}
+
+// LoadConfig reads SMTP configuration from environment variables.
+// Returns nil if MAIL_SMTP_HOST is not set.
+func LoadConfig() (*Config, error) {
+	cfg := &Config{
+		Host:        getEnv("MAIL_SMTP_HOST", ""),
+		Port:        getIntEnv("MAIL_SMTP_PORT", 587),
+		Username:    getEnv("MAIL_SMTP_USERNAME", ""),
+		Password:    getEnv("MAIL_SMTP_PASSWORD", ""),
+		From:        getEnv("MAIL_FROM", ""),
+		ImplicitTLS: getBoolEnv("MAIL_SMTP_IMPLICIT_TLS", false),
+		Timeout:     getDurationEnv("MAIL_SMTP_TIMEOUT", 30*time.Second),
+	}
+
+	// Mail is optional - return nil config if not configured
+	if cfg.Host == "" {
+		return nil, nil
+	}
+
+	if _, err := mail.ParseAddress(cfg.From); err != nil {
+		return nil, fmt.Errorf("MAIL_FROM must be a valid address when MAIL_SMTP_HOST is set: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// -------------------- SMTP Sender --------------------
+
+// SMTPSender implements Sender using an SMTP server
+type SMTPSender struct {
+	config *Config
+}
+
+// NewSMTPSender creates a sender delivering through the configured SMTP server
+func NewSMTPSender(cfg *Config) *SMTPSender {
+	return &SMTPSender{config: cfg}
+}
+
+// Send delivers the message. STARTTLS is used whenever the server offers it,
+// and credentials are only sent over TLS.
+func (s *SMTPSender) Send(ctx context.Context, msg *Message) error {
+	if len(msg.To) == 0 {
+		return errors.New("message has no recipients")
+	}
+
+	data, err := buildMessage(s.config.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	from, err := mail.ParseAddress(s.config.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+
+	client, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if !s.config.ImplicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: s.config.Host}); err != nil {
+				return fmt.Errorf("failed to start TLS: %w", err)
+			}
+		}
+	}
+
+	if s.config.Username != "" {
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+	for _, to := range msg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("failed to add recipient: %w", err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// dial connects to the SMTP server bounded by the configured timeout and the context deadline
+func (s *SMTPSender) dial(ctx context.Context) (*smtp.Client, error) {
+	address := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+
+	dialer := &net.Dialer{Timeout: s.config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+
+	deadline := time.Now().Add(s.config.Timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if s.config.ImplicitTLS {
+		conn = tls.Client(conn, &tls.Config{ServerName: s.config.Host})
+	}
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+
+	return client, nil
+}
+
+// buildMessage renders the message as RFC 5322 text with a quoted-printable UTF-8 body
+func buildMessage(from string, msg *Message, date time.Time) ([]byte, error) {
+	headers := append([]string{from, msg.Subject}, msg.To...)
+	for _, value := range headers {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, errors.New("header values must not contain line breaks")
+		}
+	}
+
+	messageID, err := newMessageID(from)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: %s\r\n", messageID)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	buf.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	body := strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n")
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// newMessageID creates a unique Message-ID in the sender's domain
+func newMessageID(from string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	domain := "localhost"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if at := strings.LastIndex(addr.Address, "@"); at != -1 {
+			domain = addr.Address[at+1:]
+		}
+	}
+
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain), nil
+}
+
+// Helper functions for environment variables
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+func getBoolEnv(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
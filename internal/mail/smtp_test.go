@@ -0,0 +1,74 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildMessage(t *testing.T) {
+	msg := &Message{
+		To:      []string{"john.doe@example.com"},
+		Subject: "Réinitialisation du mot de passe",
+		Body:    "Hello,\nopen https://kc.example.com/reset-password?token=abc=def to continue.",
+	}
+
+	data, err := buildMessage("Knowledge Center <no-reply@example.com>", msg, time.Date(2024, 12, 5, 10, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("buildMessage() error = %v", err)
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("generated message is not parseable: %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Errorf("Subject = %q (%v), want %q", subject, err, msg.Subject)
+	}
+	if got := parsed.Header.Get("To"); got != "john.doe@example.com" {
+		t.Errorf("To = %q", got)
+	}
+	if !strings.HasSuffix(parsed.Header.Get("Message-ID"), "@example.com>") {
+		t.Errorf("Message-ID = %q", parsed.Header.Get("Message-ID"))
+	}
+
+	body, _ := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	if want := strings.ReplaceAll(msg.Body, "\n", "\r\n"); string(body) != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+}
+
+func TestBuildMessage_HeaderInjection(t *testing.T) {
+	tests := []*Message{
+		{To: []string{"a@example.com"}, Subject: "Hello\r\nBcc: victim@example.com"},
+		{To: []string{"a@example.com\nBcc: victim@example.com"}, Subject: "Hello"},
+	}
+
+	for _, msg := range tests {
+		if _, err := buildMessage("no-reply@example.com", msg, time.Now()); err == nil {
+			t.Errorf("buildMessage(%q, %q) expected error", msg.To, msg.Subject)
+		}
+	}
+}
+
+func TestMemorySender(t *testing.T) {
+	sender := NewMemorySender()
+
+	if err := sender.Send(context.Background(), &Message{To: []string{"a@example.com"}, Subject: "One"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	sender.Send(context.Background(), &Message{To: []string{"b@example.com"}, Subject: "Two"})
+
+	messages := sender.Messages()
+	if len(messages) != 2 || messages[0].Subject != "One" || messages[1].Subject != "Two" {
+		t.Errorf("Messages() = %+v", messages)
+	}
+}
@@ -0,0 +1,456 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// maxResponseSize bounds the provider responses that are read
+	maxResponseSize = 1 << 20
+
+	// keyRefreshInterval limits how often the JWKS is fetched again for an unknown key ID
+	keyRefreshInterval = time.Minute
+
+	// clockSkew is the leeway for the time claims of ID tokens
+	clockSkew = time.Minute
+)
+
+// Config holds OpenID Connect relying party configuration
+type Config struct {
+	// IssuerURL is the issuer of the provider; the discovery document is read from it
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+
+	// RedirectURL is the page the provider sends the user back to with the authorization code
+	RedirectURL string
+
+	Scopes []string
+
+	// GroupsClaim is the ID token claim that lists the groups of the user
+	GroupsClaim string
+
+	// JWKSCacheTTL is how long the signing keys of the provider are cached
+	JWKSCacheTTL time.Duration
+
+	Timeout time.Duration
+}
+
+// LoadConfig reads OpenID Connect configuration from environment variables.
+// Returns nil if OIDC_ISSUER_URL is not set.
+func LoadConfig() (*Config, error) {
+	cfg := &Config{
+		IssuerURL:    getEnv("OIDC_ISSUER_URL", ""),
+		ClientID:     getEnv("OIDC_CLIENT_ID", ""),
+		ClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+		RedirectURL:  getEnv("OIDC_REDIRECT_URL", ""),
+		Scopes:       strings.Fields(getEnv("OIDC_SCOPES", "openid email profile")),
+		GroupsClaim:  getEnv("OIDC_GROUPS_CLAIM", "groups"),
+		JWKSCacheTTL: getDurationEnv("OIDC_JWKS_CACHE_TTL", time.Hour),
+		Timeout:      getDurationEnv("OIDC_TIMEOUT", 10*time.Second),
+	}
+
+	// Single sign-on is optional - return nil config if not configured
+	if cfg.IssuerURL == "" {
+		return nil, nil
+	}
+
+	if cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, fmt.Errorf("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required when OIDC_ISSUER_URL is set")
+	}
+	if !slices.Contains(cfg.Scopes, "openid") {
+		cfg.Scopes = append([]string{"openid"}, cfg.Scopes...)
+	}
+
+	return cfg, nil
+}
+
+// discoveryDocument is the part of the provider metadata the client uses
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// jsonWebKey is a public key of a JWK set (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// tokenResponse is the response of the token endpoint
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Client is an OpenID Connect relying party. The provider metadata is discovered on first use
+// and its signing keys are cached.
+type Client struct {
+	config     *Config
+	httpClient *http.Client
+
+	mu            sync.Mutex
+	discovery     *discoveryDocument
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+// NewClient creates an OpenID Connect client
+func NewClient(cfg *Config) *Client {
+	return &Client{
+		config:     cfg,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// AuthorizationURL returns the URL to send the user to for signing in
+func (c *Client) AuthorizationURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	doc, err := c.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(doc.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid authorization endpoint: %v", ErrDiscovery, err)
+	}
+
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", c.config.ClientID)
+	query.Set("redirect_uri", c.config.RedirectURL)
+	query.Set("scope", strings.Join(c.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), nil
+}
+
+// Exchange redeems an authorization code and returns the identity from the verified ID token
+func (c *Client) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	doc, err := c.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.config.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	if c.config.ClientSecret == "" {
+		// Public client
+		form.Set("client_id", c.config.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenExchange, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.config.ClientSecret != "" {
+		// client_secret_basic (RFC 6749 section 2.3.1)
+		req.SetBasicAuth(url.QueryEscape(c.config.ClientID), url.QueryEscape(c.config.ClientSecret))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenExchange, err)
+	}
+	defer resp.Body.Close()
+
+	var tokens tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("%w: invalid response (status %d): %v", ErrTokenExchange, resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || tokens.Error != "" {
+		return nil, fmt.Errorf("%w: %s %s (status %d)", ErrTokenExchange, tokens.Error, tokens.ErrorDescription, resp.StatusCode)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("%w: no ID token in response", ErrTokenExchange)
+	}
+
+	return c.verifyIDToken(ctx, doc, tokens.IDToken, nonce)
+}
+
+// verifyIDToken checks the signature, issuer, audience, lifetime and nonce of an ID token
+func (c *Client) verifyIDToken(ctx context.Context, doc *discoveryDocument, rawToken, nonce string) (*Identity, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(doc.Issuer),
+		jwt.WithAudience(c.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return c.getKey(ctx, doc, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce == "" || tokenNonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	// With several audiences the token must have been issued to this client
+	if aud, _ := claims.GetAudience(); len(aud) > 1 {
+		if azp, _ := claims["azp"].(string); azp != c.config.ClientID {
+			return nil, fmt.Errorf("%w: authorized party mismatch", ErrInvalidIDToken)
+		}
+	}
+
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+
+	return &Identity{
+		Subject:       subject,
+		Email:         claimString(claims, "email"),
+		EmailVerified: claimBool(claims, "email_verified"),
+		Name:          claimString(claims, "name"),
+		Locale:        claimString(claims, "locale"),
+		Groups:        claimStrings(claims, c.config.GroupsClaim),
+		AuthMethods:   claimStrings(claims, "amr"),
+	}, nil
+}
+
+// getDiscovery returns the provider metadata, fetching it on first use
+func (c *Client) getDiscovery(ctx context.Context) (*discoveryDocument, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.discovery != nil {
+		return c.discovery, nil
+	}
+
+	var doc discoveryDocument
+	discoveryURL := strings.TrimSuffix(c.config.IssuerURL, "/") + "/.well-known/openid-configuration"
+	if err := c.getJSON(ctx, discoveryURL, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	if doc.Issuer != c.config.IssuerURL {
+		return nil, fmt.Errorf("%w: issuer %q does not match %q", ErrDiscovery, doc.Issuer, c.config.IssuerURL)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: incomplete provider metadata", ErrDiscovery)
+	}
+
+	c.discovery = &doc
+	return c.discovery, nil
+}
+
+// getKey returns the signing key with the given ID. The key set is fetched again when it is
+// older than the cache TTL, or when the key is unknown (the provider may have rotated its keys).
+func (c *Client) getKey(ctx context.Context, doc *discoveryDocument, kid string) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	age := time.Since(c.keysFetchedAt)
+	key, found := c.lookupKey(kid)
+	if (!found && age >= keyRefreshInterval) || age >= c.config.JWKSCacheTTL {
+		if err := c.fetchKeys(ctx, doc); err != nil {
+			return nil, err
+		}
+		key, found = c.lookupKey(kid)
+	}
+	if !found {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// lookupKey finds a cached key by ID; without an ID, a single cached key is used
+func (c *Client) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key, true
+		}
+	}
+	key, ok := c.keys[kid]
+	return key, ok
+}
+
+// fetchKeys reads the signing keys of the provider. Keys of unsupported types are skipped.
+func (c *Client) fetchKeys(ctx context.Context, doc *discoveryDocument) error {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := c.getJSON(ctx, doc.JWKSURI, &set); err != nil {
+		return fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	c.keys = keys
+	c.keysFetchedAt = time.Now()
+	return nil
+}
+
+// getJSON reads a JSON document from the provider
+func (c *Client) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v)
+}
+
+// publicKey converts an RSA or EC JSON web key to a public key
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// decodeBigInt decodes a base64url encoded unsigned big-endian integer
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// claimString reads a string claim
+func claimString(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// claimBool reads a boolean claim; some providers send booleans as strings
+func claimBool(claims jwt.MapClaims, name string) bool {
+	switch value := claims[name].(type) {
+	case bool:
+		return value
+	case string:
+		return strings.EqualFold(value, "true")
+	}
+	return false
+}
+
+// claimStrings reads a claim that is a list of strings or a single string
+func claimStrings(claims jwt.MapClaims, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Helper functions for environment variables
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,187 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+const testRedirectURL = "http://localhost:3000/sso/callback"
+
+func newTestClient(t *testing.T) (*Client, *FakeProvider) {
+	t.Helper()
+
+	provider, err := NewFakeProvider("kc-api", "secret")
+	if err != nil {
+		t.Fatalf("failed to start fake provider: %v", err)
+	}
+	t.Cleanup(provider.Close)
+
+	client := NewClient(&Config{
+		IssuerURL:    provider.Issuer(),
+		ClientID:     "kc-api",
+		ClientSecret: "secret",
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+		GroupsClaim:  "groups",
+		JWKSCacheTTL: time.Hour,
+		Timeout:      5 * time.Second,
+	})
+	return client, provider
+}
+
+// signIn runs the authorization code flow up to the code exchange
+func signIn(t *testing.T, client *Client, provider *FakeProvider, claims map[string]interface{}) (code, verifier string) {
+	t.Helper()
+
+	verifier, err := NewCodeVerifier()
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	authURL, err := client.AuthorizationURL(context.Background(), "state-1", "nonce-1", CodeChallengeS256(verifier))
+	if err != nil {
+		t.Fatalf("failed to build authorization URL: %v", err)
+	}
+
+	u, _ := url.Parse(authURL)
+	if got := u.Query().Get("redirect_uri"); got != testRedirectURL {
+		t.Errorf("expected redirect_uri %q, got %q", testRedirectURL, got)
+	}
+
+	code, state, err := provider.Authorize(authURL, claims)
+	if err != nil {
+		t.Fatalf("failed to authorize: %v", err)
+	}
+	if state != "state-1" {
+		t.Errorf("expected state to be returned, got %q", state)
+	}
+	return code, verifier
+}
+
+func TestClient_Exchange(t *testing.T) {
+	client, provider := newTestClient(t)
+
+	code, verifier := signIn(t, client, provider, map[string]interface{}{
+		"sub":            "user-1",
+		"email":          "john.doe@example.com",
+		"email_verified": true,
+		"name":           "John Doe",
+		"groups":         []string{"staff", "admins"},
+		"amr":            []string{"pwd", "mfa"},
+	})
+
+	identity, err := client.Exchange(context.Background(), code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if identity.Subject != "user-1" || identity.Email != "john.doe@example.com" || !identity.EmailVerified || identity.Name != "John Doe" {
+		t.Errorf("unexpected identity: %+v", identity)
+	}
+	if len(identity.Groups) != 2 || identity.Groups[1] != "admins" {
+		t.Errorf("expected groups [staff admins], got %v", identity.Groups)
+	}
+	if len(identity.AuthMethods) != 2 || identity.AuthMethods[1] != "mfa" {
+		t.Errorf("expected amr [pwd mfa], got %v", identity.AuthMethods)
+	}
+
+	// Codes are single use
+	if _, err := client.Exchange(context.Background(), code, verifier, "nonce-1"); !errors.Is(err, ErrTokenExchange) {
+		t.Errorf("expected ErrTokenExchange for a reused code, got %v", err)
+	}
+}
+
+func TestClient_ExchangeRejected(t *testing.T) {
+	tests := []struct {
+		name        string
+		claims      map[string]interface{}
+		verifier    string
+		nonce       string
+		expectedErr error
+	}{
+		{
+			name:        "wrong code verifier",
+			claims:      map[string]interface{}{"sub": "user-1"},
+			verifier:    "wrong-verifier",
+			nonce:       "nonce-1",
+			expectedErr: ErrTokenExchange,
+		},
+		{
+			name:        "nonce mismatch",
+			claims:      map[string]interface{}{"sub": "user-1"},
+			nonce:       "nonce-2",
+			expectedErr: ErrInvalidIDToken,
+		},
+		{
+			name:        "other audience",
+			claims:      map[string]interface{}{"sub": "user-1", "aud": "other-client"},
+			nonce:       "nonce-1",
+			expectedErr: ErrInvalidIDToken,
+		},
+		{
+			name:        "other issuer",
+			claims:      map[string]interface{}{"sub": "user-1", "iss": "https://evil.example.com"},
+			nonce:       "nonce-1",
+			expectedErr: ErrInvalidIDToken,
+		},
+		{
+			name:        "expired",
+			claims:      map[string]interface{}{"sub": "user-1", "exp": time.Now().Add(-time.Hour).Unix()},
+			nonce:       "nonce-1",
+			expectedErr: ErrInvalidIDToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, provider := newTestClient(t)
+
+			code, verifier := signIn(t, client, provider, tt.claims)
+			if tt.verifier != "" {
+				verifier = tt.verifier
+			}
+
+			_, err := client.Exchange(context.Background(), code, verifier, tt.nonce)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("expected %v, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestClient_KeyRotation(t *testing.T) {
+	client, provider := newTestClient(t)
+
+	code, verifier := signIn(t, client, provider, map[string]interface{}{"sub": "user-1"})
+	if _, err := client.Exchange(context.Background(), code, verifier, "nonce-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := provider.RotateKey(); err != nil {
+		t.Fatalf("failed to rotate key: %v", err)
+	}
+
+	// An unknown key ID is only looked up again after the refresh interval
+	code, verifier = signIn(t, client, provider, map[string]interface{}{"sub": "user-1"})
+	if _, err := client.Exchange(context.Background(), code, verifier, "nonce-1"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("expected ErrInvalidIDToken right after rotation, got %v", err)
+	}
+
+	client.mu.Lock()
+	client.keysFetchedAt = time.Now().Add(-keyRefreshInterval)
+	client.mu.Unlock()
+
+	code, verifier = signIn(t, client, provider, map[string]interface{}{"sub": "user-1"})
+	if _, err := client.Exchange(context.Background(), code, verifier, "nonce-1"); err != nil {
+		t.Errorf("expected the rotated key to be fetched, got %v", err)
+	}
+}
+
+func TestCodeChallengeS256(t *testing.T) {
+	// Example from RFC 7636 appendix B
+	got := CodeChallengeS256("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if got != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("unexpected code challenge %q", got)
+	}
+}
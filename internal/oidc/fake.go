@@ -0,0 +1,245 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// -------------------- Fake Provider --------------------
+
+// FakeProvider is a local OpenID provider serving discovery, JWKS and token endpoints.
+// Instead of a login page, Authorize signs a user in directly.
+// It is meant for tests and local development.
+type FakeProvider struct {
+	server       *httptest.Server
+	clientID     string
+	clientSecret string
+
+	mu     sync.Mutex
+	key    *rsa.PrivateKey
+	keyID  string
+	grants map[string]fakeGrant // by authorization code
+}
+
+// fakeGrant is an issued authorization code
+type fakeGrant struct {
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	claims        map[string]interface{}
+}
+
+// NewFakeProvider starts a fake provider for a client. Call Close when done.
+func NewFakeProvider(clientID, clientSecret string) (*FakeProvider, error) {
+	p := &FakeProvider{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		grants:       make(map[string]fakeGrant),
+	}
+	if err := p.RotateKey(); err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", p.handleDiscovery)
+	mux.HandleFunc("GET /jwks", p.handleJWKS)
+	mux.HandleFunc("POST /token", p.handleToken)
+	p.server = httptest.NewServer(mux)
+
+	return p, nil
+}
+
+// Issuer returns the issuer URL of the provider
+func (p *FakeProvider) Issuer() string {
+	return p.server.URL
+}
+
+// Close shuts the provider down
+func (p *FakeProvider) Close() {
+	p.server.Close()
+}
+
+// RotateKey replaces the signing key. ID tokens signed afterwards have a new key ID.
+func (p *FakeProvider) RotateKey() error {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.key = key
+	p.keyID = hex.EncodeToString(b)
+	return nil
+}
+
+// Authorize signs a user in at an authorization URL built by a client and returns the
+// authorization code and state the provider redirects back with. The claims are added to
+// the ID token, e.g. email, email_verified, name, groups and amr; sub is required.
+// They override the standard claims, so invalid ID tokens can be issued as well.
+func (p *FakeProvider) Authorize(authorizationURL string, claims map[string]interface{}) (code, state string, err error) {
+	u, err := url.Parse(authorizationURL)
+	if err != nil {
+		return "", "", err
+	}
+	query := u.Query()
+
+	switch {
+	case query.Get("response_type") != "code":
+		return "", "", errors.New("unsupported response_type")
+	case query.Get("client_id") != p.clientID:
+		return "", "", errors.New("unknown client_id")
+	case query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "":
+		return "", "", errors.New("PKCE with S256 is required")
+	case query.Get("redirect_uri") == "":
+		return "", "", errors.New("redirect_uri is required")
+	}
+	if _, ok := claims["sub"]; !ok {
+		return "", "", errors.New("sub claim is required")
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	code = hex.EncodeToString(b)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.grants[code] = fakeGrant{
+		redirectURI:   query.Get("redirect_uri"),
+		codeChallenge: query.Get("code_challenge"),
+		nonce:         query.Get("nonce"),
+		claims:        claims,
+	}
+	return code, query.Get("state"), nil
+}
+
+func (p *FakeProvider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.Issuer(),
+		"authorization_endpoint":                p.Issuer() + "/authorize",
+		"token_endpoint":                        p.Issuer() + "/token",
+		"jwks_uri":                              p.Issuer() + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *FakeProvider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	key, keyID := p.key, p.keyID
+	p.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *FakeProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, "invalid_request", "malformed form")
+		return
+	}
+
+	clientID, clientSecret, basic := r.BasicAuth()
+	if basic {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		clientSecret = r.PostForm.Get("client_secret")
+	}
+	if clientID != p.clientID || clientSecret != p.clientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeTokenError(w, "unsupported_grant_type", "")
+		return
+	}
+
+	// Codes are single use
+	p.mu.Lock()
+	grant, ok := p.grants[r.PostForm.Get("code")]
+	delete(p.grants, r.PostForm.Get("code"))
+	key, keyID := p.key, p.keyID
+	p.mu.Unlock()
+
+	switch {
+	case !ok:
+		writeTokenError(w, "invalid_grant", "unknown or used authorization code")
+		return
+	case r.PostForm.Get("redirect_uri") != grant.redirectURI:
+		writeTokenError(w, "invalid_grant", "redirect_uri mismatch")
+		return
+	case CodeChallengeS256(r.PostForm.Get("code_verifier")) != grant.codeChallenge:
+		writeTokenError(w, "invalid_grant", "PKCE verification failed")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": p.Issuer(),
+		"aud": p.clientID,
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+	}
+	if grant.nonce != "" {
+		claims["nonce"] = grant.nonce
+	}
+	for name, value := range grant.claims {
+		claims[name] = value
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": fmt.Sprintf("fake-access-%d", now.UnixNano()),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func writeTokenError(w http.ResponseWriter, code, description string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code, "error_description": description})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,52 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+var (
+	ErrDiscovery      = errors.New("OpenID provider discovery failed")
+	ErrTokenExchange  = errors.New("authorization code exchange failed")
+	ErrInvalidIDToken = errors.New("invalid ID token")
+)
+
+// Identity is the verified identity of a user signed in at the OpenID provider
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Locale        string
+	Groups        []string
+
+	// AuthMethods are the authentication methods the provider reports (amr claim), e.g. "pwd", "mfa"
+	AuthMethods []string
+}
+
+// Provider defines the interface of an OpenID Connect provider for the authorization code flow with PKCE
+type Provider interface {
+	// AuthorizationURL returns the URL to send the user to for signing in
+	AuthorizationURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+
+	// Exchange redeems an authorization code and returns the identity from the verified ID token
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error)
+}
+
+// NewCodeVerifier creates a random PKCE code verifier (RFC 7636)
+func NewCodeVerifier() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallengeS256 derives the S256 PKCE code challenge of a code verifier
+func CodeChallengeS256(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
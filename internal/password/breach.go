@@ -0,0 +1,71 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// rangePrefixLength is the number of hex digits of the SHA-1 hash naming a range file
+const rangePrefixLength = 5
+
+// BreachList looks up passwords in a list of passwords exposed in data breaches
+type BreachList interface {
+	// Contains reports whether the password appears in the list
+	Contains(password string) (bool, error)
+}
+
+// RangeDirectory is a BreachList reading a local copy of the Pwned Passwords k-anonymity range
+// files, as written by the PwnedPasswordsDownloader: one file per first five hex digits of the
+// SHA-1 hash (e.g. 21BD1.txt) holding the remaining 35 digits and a count per line (SUFFIX:COUNT).
+// Only the range file of a password is read on each lookup, so the list needs no memory and may
+// be a subset of the full list. Missing range files and padding lines with a count of 0 match nothing.
+type RangeDirectory struct {
+	dir string
+}
+
+// NewRangeDirectory creates a breach list reading the range files in dir
+func NewRangeDirectory(dir string) (*RangeDirectory, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("breached password list %s is not a directory", dir)
+	}
+	return &RangeDirectory{dir: dir}, nil
+}
+
+// Contains reports whether the SHA-1 hash of the password appears in its range file
+func (d *RangeDirectory) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:rangePrefixLength], hash[rangePrefixLength:]
+
+	file, err := os.Open(filepath.Join(d.dir, prefix+".txt"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(entry, suffix) {
+			return count != "0", nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+	return false, nil
+}
@@ -0,0 +1,258 @@
+package password
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidParams   = errors.New("invalid password hash parameters")
+	ErrUnsupportedHash = errors.New("unsupported password hash")
+)
+
+// Limits of Argon2id parameters, applied to the configured parameters and to stored hashes,
+// so that a single verification cannot exhaust the server
+const (
+	MaxArgon2Time    = 64
+	MaxArgon2Memory  = 4 * 1024 * 1024 // 4 GiB in KiB
+	MaxArgon2Threads = 64
+
+	// MaxPBKDF2Iterations bounds the iterations of imported PBKDF2 hashes
+	MaxPBKDF2Iterations = 10_000_000
+
+	// maxCostFactor bounds the time and memory of hashes checked with Params.Verify,
+	// as a multiple of the parameters
+	maxCostFactor = 4
+)
+
+// Sizes of the salt and key of new Argon2id hashes
+const (
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+// Params are the Argon2id parameters new password hashes are created with
+type Params struct {
+	Time    uint32 // Number of passes over the memory
+	Memory  uint32 // Memory in KiB
+	Threads uint8  // Degree of parallelism
+}
+
+// DefaultParams returns the parameters used unless others are configured
+func DefaultParams() Params {
+	return Params{Time: 1, Memory: 64 * 1024, Threads: 4}
+}
+
+// Validate checks that the parameters are within their limits
+func (p Params) Validate() error {
+	switch {
+	case p.Time < 1 || p.Time > MaxArgon2Time:
+		return fmt.Errorf("%w: time must be between 1 and %d", ErrInvalidParams, MaxArgon2Time)
+	case p.Threads < 1 || p.Threads > MaxArgon2Threads:
+		return fmt.Errorf("%w: threads must be between 1 and %d", ErrInvalidParams, MaxArgon2Threads)
+	case p.Memory < 8*uint32(p.Threads) || p.Memory > MaxArgon2Memory:
+		return fmt.Errorf("%w: memory must be between %d and %d KiB", ErrInvalidParams, 8*uint32(p.Threads), MaxArgon2Memory)
+	}
+	return nil
+}
+
+// Hash hashes a password with Argon2id. The parameters are encoded in the hash:
+// $argon2id$v=19$m=65536,t=1,p=4$<salt>$<hash>
+func Hash(password string, params Params) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, argon2KeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.Memory, params.Time, params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify reports whether a password matches an encoded hash. Besides Argon2id hashes created by
+// Hash with any parameters, hashes imported from other systems are accepted:
+//   - bcrypt: $2a$, $2b$ or $2y$
+//   - PBKDF2 in the Django format: pbkdf2_sha256$<iterations>$<salt>$<base64 key> (or pbkdf2_sha1)
+//   - PBKDF2 in the passlib format: $pbkdf2-sha256$<iterations>$<salt>$<key> (or $pbkdf2$, $pbkdf2-sha512$)
+//
+// Malformed hashes and hashes in other formats match no password.
+func Verify(password, encoded string) bool {
+	if isBcrypt(encoded) {
+		return bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) == nil
+	}
+
+	h, err := parseHash(encoded)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(h.derive(password), h.key) == 1
+}
+
+// Verify reports whether a password matches an Argon2id hash created by Hash. Unlike the
+// package-level Verify, hashes in other formats and hashes needing more than maxCostFactor
+// times the time or memory of the parameters match no password, so verifying a stored hash
+// costs about as much as creating one.
+func (p Params) Verify(password, encoded string) bool {
+	if !strings.HasPrefix(encoded, "$argon2id$") {
+		return false
+	}
+	h, err := parseArgon2(encoded)
+	if err != nil || h.params.Time > maxCostFactor*p.Time || h.params.Memory > maxCostFactor*p.Memory {
+		return false
+	}
+	return subtle.ConstantTimeCompare(h.derive(password), h.key) == 1
+}
+
+// CheckHash checks that an encoded hash, e.g. one imported from another system, is in a format
+// Verify accepts
+func CheckHash(encoded string) error {
+	if isBcrypt(encoded) {
+		if _, err := bcrypt.Cost([]byte(encoded)); err != nil {
+			return fmt.Errorf("%w: %v", ErrUnsupportedHash, err)
+		}
+		return nil
+	}
+	_, err := parseHash(encoded)
+	return err
+}
+
+// NeedsRehash reports whether an encoded hash is weaker than hashes created with the parameters:
+// it is not an Argon2id hash, or it was created with less time, memory, salt or key. A hash
+// created with more of them is kept, so that lowering the parameters never weakens stored hashes.
+func (p Params) NeedsRehash(encoded string) bool {
+	h, err := parseHash(encoded)
+	if err != nil || h.algorithm != "argon2id" {
+		return true
+	}
+	return h.version != argon2.Version ||
+		h.params.Time < p.Time ||
+		h.params.Memory < p.Memory ||
+		len(h.salt) < argon2SaltLen ||
+		len(h.key) < argon2KeyLen
+}
+
+// parsedHash is a decoded Argon2id or PBKDF2 hash
+type parsedHash struct {
+	algorithm  string
+	version    int
+	params     Params           // Argon2id only
+	iterations int              // PBKDF2 only
+	newHash    func() hash.Hash // PBKDF2 only
+	salt       []byte
+	key        []byte
+}
+
+// derive derives the key of a password with the algorithm, parameters and salt of the hash
+func (h *parsedHash) derive(password string) []byte {
+	if h.algorithm == "argon2id" {
+		return argon2.IDKey([]byte(password), h.salt, h.params.Time, h.params.Memory, h.params.Threads, uint32(len(h.key)))
+	}
+	key, err := pbkdf2.Key(h.newHash, password, h.salt, h.iterations, len(h.key))
+	if err != nil {
+		return nil
+	}
+	return key
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// pbkdf2Hashes maps the names of PBKDF2 hash functions in Django and passlib hashes
+var pbkdf2Hashes = map[string]func() hash.Hash{
+	"pbkdf2_sha1":    sha1.New,
+	"pbkdf2_sha256":  sha256.New,
+	"$pbkdf2":        sha1.New,
+	"$pbkdf2-sha256": sha256.New,
+	"$pbkdf2-sha512": sha512.New,
+}
+
+// passlibEncoding is the base64 variant of passlib hashes, using "." instead of "+"
+var passlibEncoding = base64.NewEncoding("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789./").WithPadding(base64.NoPadding)
+
+func parseHash(encoded string) (*parsedHash, error) {
+	if strings.HasPrefix(encoded, "$argon2id$") {
+		return parseArgon2(encoded)
+	}
+	return parsePBKDF2(encoded)
+}
+
+func parseArgon2(encoded string) (*parsedHash, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return nil, fmt.Errorf("%w: malformed argon2id hash", ErrUnsupportedHash)
+	}
+
+	h := &parsedHash{algorithm: "argon2id"}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &h.version); err != nil {
+		return nil, fmt.Errorf("%w: malformed argon2id version", ErrUnsupportedHash)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.params.Memory, &h.params.Time, &h.params.Threads); err != nil {
+		return nil, fmt.Errorf("%w: malformed argon2id parameters", ErrUnsupportedHash)
+	}
+	if err := h.params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupportedHash, err)
+	}
+
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("%w: malformed argon2id salt", ErrUnsupportedHash)
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(h.key) == 0 {
+		return nil, fmt.Errorf("%w: malformed argon2id key", ErrUnsupportedHash)
+	}
+	return h, nil
+}
+
+func parsePBKDF2(encoded string) (*parsedHash, error) {
+	// Passlib hashes start with "$", which is part of their name here
+	passlib := strings.HasPrefix(encoded, "$")
+	parts := strings.Split(strings.TrimPrefix(encoded, "$"), "$")
+	if passlib {
+		parts[0] = "$" + parts[0]
+	}
+	if len(parts) != 4 {
+		return nil, ErrUnsupportedHash
+	}
+
+	newHash, ok := pbkdf2Hashes[parts[0]]
+	if !ok {
+		return nil, ErrUnsupportedHash
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < 1 || iterations > MaxPBKDF2Iterations {
+		return nil, fmt.Errorf("%w: PBKDF2 iterations must be between 1 and %d", ErrUnsupportedHash, MaxPBKDF2Iterations)
+	}
+
+	h := &parsedHash{algorithm: "pbkdf2", iterations: iterations, newHash: newHash}
+	if passlib {
+		h.salt, err = passlibEncoding.DecodeString(parts[2])
+		if err == nil {
+			h.key, err = passlibEncoding.DecodeString(parts[3])
+		}
+	} else {
+		// Django uses the salt as it is
+		h.salt = []byte(parts[2])
+		h.key, err = base64.StdEncoding.DecodeString(parts[3])
+	}
+	if err != nil || len(h.key) == 0 {
+		return nil, fmt.Errorf("%w: malformed PBKDF2 hash", ErrUnsupportedHash)
+	}
+	return h, nil
+}
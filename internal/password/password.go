@@ -0,0 +1,216 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrInvalidPolicy = errors.New("invalid password policy")
+
+// Rules a password can violate
+const (
+	RuleMinLength = "min_length"
+	RuleMaxLength = "max_length"
+	RuleUppercase = "uppercase"
+	RuleLowercase = "lowercase"
+	RuleDigit     = "digit"
+	RuleSymbol    = "symbol"
+	RuleUserInfo  = "user_info"
+	RuleHistory   = "history"
+	RuleBreached  = "breached"
+)
+
+// Limits of policy settings
+const (
+	MaxLengthLimit  = 1024 // Longer passwords only make hashing expensive
+	MaxHistoryCount = 24
+	MaxAgeDaysLimit = 3650
+)
+
+// minFragmentLength is the shortest part of a login ID or email that passwords may not contain
+const minFragmentLength = 3
+
+// Policy defines the rules new passwords must follow
+type Policy struct {
+	MinLength        int  `json:"min_length" example:"12"`
+	MaxLength        int  `json:"max_length" example:"128"`
+	RequireUppercase bool `json:"require_uppercase" example:"true"`
+	RequireLowercase bool `json:"require_lowercase" example:"true"`
+	RequireDigit     bool `json:"require_digit" example:"true"`
+	RequireSymbol    bool `json:"require_symbol" example:"false"`
+
+	// DisallowUserInfo rejects passwords containing the login ID, the email or parts of them
+	DisallowUserInfo bool `json:"disallow_user_info" example:"true"`
+
+	// HistoryCount is how many of the user's previous passwords, including the current one, cannot be reused (0 allows reuse)
+	HistoryCount int `json:"history_count" example:"5"`
+
+	// MaxAgeDays is how long a password may be used before it must be changed on the next login (0 never expires)
+	MaxAgeDays int `json:"max_age_days" example:"90"`
+
+	// CheckBreached rejects passwords found in the breached password list, if one is loaded
+	CheckBreached bool `json:"check_breached" example:"true"`
+}
+
+// DefaultPolicy returns the policy used until an administrator configures one
+func DefaultPolicy() Policy {
+	return Policy{
+		MinLength:        8,
+		MaxLength:        128,
+		DisallowUserInfo: true,
+		CheckBreached:    true,
+	}
+}
+
+// Validate checks that the settings of the policy are within their limits
+func (p *Policy) Validate() error {
+	switch {
+	case p.MinLength < 1:
+		return fmt.Errorf("%w: min_length must be at least 1", ErrInvalidPolicy)
+	case p.MaxLength < p.MinLength:
+		return fmt.Errorf("%w: max_length must not be less than min_length", ErrInvalidPolicy)
+	case p.MaxLength > MaxLengthLimit:
+		return fmt.Errorf("%w: max_length must be at most %d", ErrInvalidPolicy, MaxLengthLimit)
+	case p.HistoryCount < 0 || p.HistoryCount > MaxHistoryCount:
+		return fmt.Errorf("%w: history_count must be between 0 and %d", ErrInvalidPolicy, MaxHistoryCount)
+	case p.MaxAgeDays < 0 || p.MaxAgeDays > MaxAgeDaysLimit:
+		return fmt.Errorf("%w: max_age_days must be between 0 and %d", ErrInvalidPolicy, MaxAgeDaysLimit)
+	}
+	return nil
+}
+
+// User identifies the owner of a password for the user info rule
+type User struct {
+	LoginID string
+	Email   string
+}
+
+// Violation is a rule a password does not follow
+type Violation struct {
+	Rule    string `json:"rule" example:"min_length"`
+	Message string `json:"message" example:"Password must be at least 12 characters"`
+}
+
+// PolicyError lists the rules a password violates
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+	return "password does not meet the policy: " + strings.Join(messages, "; ")
+}
+
+// ErrorResponse is the response to a password violating the policy
+type ErrorResponse struct {
+	Error      string      `json:"error" example:"Bad Request"`
+	Message    string      `json:"message" example:"Password does not meet the password policy"`
+	Violations []Violation `json:"violations"`
+}
+
+// NewErrorResponse creates the response to a policy error
+func NewErrorResponse(err *PolicyError) ErrorResponse {
+	return ErrorResponse{
+		Error:      "Bad Request",
+		Message:    "Password does not meet the password policy",
+		Violations: err.Violations,
+	}
+}
+
+// Check returns the violations of the rules that depend only on the password and its owner.
+// History and breached password checks need stored data and are made by the caller.
+func (p *Policy) Check(password string, user User) []Violation {
+	var violations []Violation
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, Violation{RuleMinLength, fmt.Sprintf("Password must be at least %d characters", p.MinLength)})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, Violation{RuleMaxLength, fmt.Sprintf("Password must be at most %d characters", p.MaxLength)})
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r):
+			symbol = true
+		}
+	}
+	if p.RequireUppercase && !upper {
+		violations = append(violations, Violation{RuleUppercase, "Password must contain an uppercase letter"})
+	}
+	if p.RequireLowercase && !lower {
+		violations = append(violations, Violation{RuleLowercase, "Password must contain a lowercase letter"})
+	}
+	if p.RequireDigit && !digit {
+		violations = append(violations, Violation{RuleDigit, "Password must contain a digit"})
+	}
+	if p.RequireSymbol && !symbol {
+		violations = append(violations, Violation{RuleSymbol, "Password must contain a symbol"})
+	}
+
+	if p.DisallowUserInfo && containsUserInfo(password, user) {
+		violations = append(violations, Violation{RuleUserInfo, "Password must not contain the login ID or email"})
+	}
+
+	return violations
+}
+
+// containsUserInfo reports whether a password contains the login ID or email, or a part of
+// them such as a name, e.g. "john" or "doe" for john.doe@example.com. Top-level domains and
+// parts shorter than minFragmentLength are ignored.
+func containsUserInfo(password string, user User) bool {
+	lowered := strings.ToLower(password)
+	for _, fragment := range userInfoFragments(user) {
+		if strings.Contains(lowered, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+func userInfoFragments(user User) []string {
+	// Login IDs are often emails too
+	var values []string
+	for _, value := range []string{user.LoginID, user.Email} {
+		local, domain, ok := strings.Cut(value, "@")
+		if !ok {
+			values = append(values, value)
+			continue
+		}
+		values = append(values, local)
+		if i := strings.LastIndex(domain, "."); i > 0 {
+			values = append(values, domain[:i])
+		}
+	}
+
+	var fragments []string
+	for _, value := range values {
+		value = strings.ToLower(value)
+		fragments = append(fragments, value)
+		fragments = append(fragments, strings.FieldsFunc(value, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+	}
+
+	kept := fragments[:0]
+	for _, fragment := range fragments {
+		if utf8.RuneCountInString(fragment) >= minFragmentLength {
+			kept = append(kept, fragment)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,295 @@
+package password
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	strict := Policy{
+		MinLength:        10,
+		MaxLength:        20,
+		RequireUppercase: true,
+		RequireLowercase: true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+		DisallowUserInfo: true,
+	}
+	user := User{LoginID: "john.doe@example.com", Email: "john.doe@example.com"}
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		password string
+		rules    []string
+	}{
+		{name: "valid", policy: strict, password: "Kettle-Sun-42", rules: nil},
+		{name: "too short", policy: strict, password: "Ab1!", rules: []string{RuleMinLength}},
+		{name: "too long", policy: strict, password: "Kettle-Sun-42-Kettle-Sun", rules: []string{RuleMaxLength}},
+		{name: "length counts characters", policy: strict, password: "Äöü-Kettle-42", rules: nil},
+		{name: "missing classes", policy: strict, password: "kettlesunrise", rules: []string{RuleUppercase, RuleDigit, RuleSymbol}},
+		{name: "contains login ID part", policy: strict, password: "Doe-Kettle-42", rules: []string{RuleUserInfo}},
+		{name: "contains email domain", policy: strict, password: "Example-2024!", rules: []string{RuleUserInfo}},
+		{name: "top-level domain is ignored", policy: strict, password: "Welcome-To-42", rules: nil},
+		{name: "default policy", policy: DefaultPolicy(), password: "kettlesun", rules: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rules []string
+			for _, v := range tt.policy.Check(tt.password, user) {
+				rules = append(rules, v.Rule)
+			}
+			if !slices.Equal(rules, tt.rules) {
+				t.Errorf("expected rules %v, got %v", tt.rules, rules)
+			}
+		})
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(p *Policy)
+		wantErr bool
+	}{
+		{name: "default", modify: func(p *Policy) {}},
+		{name: "zero min length", modify: func(p *Policy) { p.MinLength = 0 }, wantErr: true},
+		{name: "max below min", modify: func(p *Policy) { p.MinLength, p.MaxLength = 16, 12 }, wantErr: true},
+		{name: "max too long", modify: func(p *Policy) { p.MaxLength = MaxLengthLimit + 1 }, wantErr: true},
+		{name: "history too long", modify: func(p *Policy) { p.HistoryCount = MaxHistoryCount + 1 }, wantErr: true},
+		{name: "negative max age", modify: func(p *Policy) { p.MaxAgeDays = -1 }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := DefaultPolicy()
+			tt.modify(&policy)
+
+			err := policy.Validate()
+			if tt.wantErr != errors.Is(err, ErrInvalidPolicy) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRangeDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	// One range file holding a breached password and a padding entry
+	sum := sha1.Sum([]byte("P@ssw0rd"))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	padding := sha1.Sum([]byte("padding"))
+	paddingHash := strings.ToUpper(hex.EncodeToString(padding[:]))
+
+	files := map[string]string{
+		hash[:5]:        hash[5:] + ":52579\r\n0000000000000000000000000000000000A:3\r\n",
+		paddingHash[:5]: strings.ToLower(paddingHash[5:]) + ":0\r\n",
+	}
+	for prefix, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, prefix+".txt"), []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write range file: %v", err)
+		}
+	}
+
+	list, err := NewRangeDirectory(dir)
+	if err != nil {
+		t.Fatalf("failed to open list: %v", err)
+	}
+
+	tests := []struct {
+		password string
+		want     bool
+	}{
+		{password: "P@ssw0rd", want: true},
+		{password: "padding", want: false},
+		{password: "correct horse battery staple", want: false},
+	}
+	for _, tt := range tests {
+		got, err := list.Contains(tt.password)
+		if err != nil {
+			t.Fatalf("lookup of %q failed: %v", tt.password, err)
+		}
+		if got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.password, got, tt.want)
+		}
+	}
+
+	if _, err := NewRangeDirectory(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestHash(t *testing.T) {
+	params := Params{Time: 2, Memory: 1024, Threads: 1}
+	encoded, err := Hash("Kettle-Sun-42", params)
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=2,p=1$") {
+		t.Errorf("expected the parameters in the hash, got %s", encoded)
+	}
+	if !Verify("Kettle-Sun-42", encoded) {
+		t.Error("expected the password to match")
+	}
+	if Verify("kettle-sun-42", encoded) {
+		t.Error("expected a different password not to match")
+	}
+}
+
+func TestVerify_ImportedHashes(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("legacy-secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{name: "bcrypt", encoded: string(bcryptHash)},
+		{name: "bcrypt 2y", encoded: "$2y$" + strings.TrimPrefix(string(bcryptHash), "$2a$")},
+		{name: "django pbkdf2", encoded: "pbkdf2_sha256$1000$seasalt$TJUl5ej5vzB/wFyiG1I9DSpiGKo/Co2GhB70QN0G5io="},
+		{name: "passlib pbkdf2", encoded: "$pbkdf2-sha512$1000$MDEyMzQ1Njc4OWFiY2RlZg$SZqTEP3Ge9y7R7W6uLdO1YWXQ1oW.Zi9ieGcBW0moasLOCW9x2GhJCDbsyKwgqZ1nqbSBiGS.bsHRxR30iL15Q"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckHash(tt.encoded); err != nil {
+				t.Errorf("expected a supported hash, got %v", err)
+			}
+			if !Verify("legacy-secret", tt.encoded) {
+				t.Error("expected the password to match")
+			}
+			if Verify("legacy-secret!", tt.encoded) {
+				t.Error("expected a different password not to match")
+			}
+			if !DefaultParams().NeedsRehash(tt.encoded) {
+				t.Error("expected an imported hash to need rehashing")
+			}
+		})
+	}
+}
+
+func TestCheckHash_Unsupported(t *testing.T) {
+	for _, encoded := range []string{
+		"",
+		"plaintext",
+		"$1$salt$md5crypt",
+		"pbkdf2_sha256$abc$salt$key",
+		"pbkdf2_sha256$100000000$salt$a2V5",
+		"$argon2id$v=19$m=65536,t=1000,p=4$c2FsdA$a2V5",
+		"$argon2id$v=19$m=65536,t=1,p=4$c2FsdA",
+		"$2a$04$short",
+	} {
+		if err := CheckHash(encoded); !errors.Is(err, ErrUnsupportedHash) {
+			t.Errorf("CheckHash(%q) = %v, want ErrUnsupportedHash", encoded, err)
+		}
+		if Verify("", encoded) {
+			t.Errorf("expected %q to match nothing", encoded)
+		}
+	}
+}
+
+func TestParams_NeedsRehash(t *testing.T) {
+	current := Params{Time: 2, Memory: 2048, Threads: 2}
+
+	tests := []struct {
+		name   string
+		params Params
+		want   bool
+	}{
+		{name: "same parameters", params: current, want: false},
+		{name: "fewer passes", params: Params{Time: 1, Memory: 2048, Threads: 2}, want: true},
+		{name: "less memory", params: Params{Time: 2, Memory: 1024, Threads: 2}, want: true},
+		{name: "other parallelism", params: Params{Time: 2, Memory: 2048, Threads: 1}, want: false},
+		{name: "stronger parameters", params: Params{Time: 3, Memory: 4096, Threads: 2}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := Hash("Kettle-Sun-42", tt.params)
+			if err != nil {
+				t.Fatalf("failed to hash: %v", err)
+			}
+			if got := current.NeedsRehash(encoded); got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParams_Verify(t *testing.T) {
+	current := Params{Time: 1, Memory: 1024, Threads: 1}
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("Kettle-Sun-42"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+
+	hash := func(params Params) string {
+		encoded, err := Hash("Kettle-Sun-42", params)
+		if err != nil {
+			t.Fatalf("failed to hash: %v", err)
+		}
+		return encoded
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+		want    bool
+	}{
+		{name: "same parameters", encoded: hash(current), want: true},
+		{name: "weaker parameters", encoded: hash(Params{Time: 1, Memory: 512, Threads: 1}), want: true},
+		{name: "within the cost limit", encoded: hash(Params{Time: 4, Memory: 4096, Threads: 1}), want: true},
+		{name: "too many passes", encoded: hash(Params{Time: 5, Memory: 1024, Threads: 1}), want: false},
+		{name: "too much memory", encoded: hash(Params{Time: 1, Memory: 4097, Threads: 1}), want: false},
+		// Not computed: 4 GiB
+		{name: "maximum memory", encoded: "$argon2id$v=19$m=4194304,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5", want: false},
+		{name: "other format", encoded: string(bcryptHash), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := current.Verify("Kettle-Sun-42", tt.encoded); got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if current.Verify("kettle-sun-42", hash(current)) {
+		t.Error("expected a different password not to match")
+	}
+}
+
+func TestParams_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  Params
+		wantErr bool
+	}{
+		{name: "default", params: DefaultParams()},
+		{name: "zero time", params: Params{Time: 0, Memory: 65536, Threads: 4}, wantErr: true},
+		{name: "zero threads", params: Params{Time: 1, Memory: 65536, Threads: 0}, wantErr: true},
+		{name: "memory below threads", params: Params{Time: 1, Memory: 16, Threads: 4}, wantErr: true},
+		{name: "memory too large", params: Params{Time: 1, Memory: MaxArgon2Memory + 1, Threads: 4}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if tt.wantErr != errors.Is(err, ErrInvalidParams) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
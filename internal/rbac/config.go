@@ -0,0 +1,49 @@
+package rbac
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// Config holds RBAC configuration
+type Config struct {
+	// DenyByDefault rejects requests to protected routes that have no permission rule.
+	// By default such routes are open to every authenticated user.
+	DenyByDefault bool
+
+	// Listen reloads the permissions when another instance, or a direct database edit, changes
+	// permission rules, role assignments or group roles (PostgreSQL LISTEN/NOTIFY)
+	Listen bool
+
+	// ReloadInterval is how often the permissions are reloaded regardless of notifications, in
+	// case one was missed. Zero disables the periodic reload.
+	ReloadInterval time.Duration
+}
+
+// LoadConfig reads RBAC configuration from environment variables
+func LoadConfig() *Config {
+	return &Config{
+		DenyByDefault:  getBoolEnv("RBAC_DENY_BY_DEFAULT", false),
+		Listen:         getBoolEnv("RBAC_LISTEN", true),
+		ReloadInterval: getDurationEnv("RBAC_RELOAD_INTERVAL", 5*time.Minute),
+	}
+}
+
+func getBoolEnv(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,75 @@
+package rbac
+
+import "slices"
+
+// CoverageReport compares the permission rules with the routes the middleware protects, so that
+// deny by default can be enabled without locking out routes that have no rule yet
+type CoverageReport struct {
+	RouteCount       int              `json:"route_count" example:"120"`
+	RuleCount        int              `json:"rule_count" example:"95"`
+	UncoveredRoutes  []Route          `json:"uncovered_routes"`  // routes without a rule for their method or "*"
+	StaleRules       []Route          `json:"stale_rules"`       // rules whose path pattern is not a registered route
+	MethodMismatches []MethodMismatch `json:"method_mismatches"` // rules whose route is registered, but not with their method
+}
+
+// MethodMismatch is a permission rule for a registered path pattern whose method is not
+// registered for it
+type MethodMismatch struct {
+	Method            string   `json:"method" example:"PATCH"`
+	Pattern           string   `json:"path_pattern" example:"/users/{id}"`
+	RegisteredMethods []string `json:"registered_methods" example:"GET,PUT,DELETE"`
+}
+
+// HasIssues reports whether any route or rule needs attention
+func (c *CoverageReport) HasIssues() bool {
+	return len(c.UncoveredRoutes) > 0 || len(c.StaleRules) > 0 || len(c.MethodMismatches) > 0
+}
+
+// BuildCoverageReport compares permission rules, given by method and path pattern, with the
+// registered routes. The lists of the report are sorted by pattern and method.
+func BuildCoverageReport(routes, rules []Route) *CoverageReport {
+	routes = slices.Clone(routes)
+	sortRoutes(routes)
+
+	registered := make(map[string][]string) // path_pattern -> methods
+	for _, route := range routes {
+		registered[route.Pattern] = append(registered[route.Pattern], route.Method)
+	}
+
+	covered := make(map[Route]bool)
+	for _, rule := range rules {
+		covered[rule] = true
+	}
+
+	report := &CoverageReport{
+		RouteCount:       len(routes),
+		RuleCount:        len(rules),
+		UncoveredRoutes:  []Route{},
+		StaleRules:       []Route{},
+		MethodMismatches: []MethodMismatch{},
+	}
+
+	for _, route := range routes {
+		if !covered[route] && !covered[Route{Method: "*", Pattern: route.Pattern}] {
+			report.UncoveredRoutes = append(report.UncoveredRoutes, route)
+		}
+	}
+
+	rules = slices.Clone(rules)
+	sortRoutes(rules)
+	for _, rule := range rules {
+		methods, exists := registered[rule.Pattern]
+		switch {
+		case !exists:
+			report.StaleRules = append(report.StaleRules, rule)
+		case rule.Method != "*" && !slices.Contains(methods, rule.Method):
+			report.MethodMismatches = append(report.MethodMismatches, MethodMismatch{
+				Method:            rule.Method,
+				Pattern:           rule.Pattern,
+				RegisteredMethods: methods,
+			})
+		}
+	}
+
+	return report
+}
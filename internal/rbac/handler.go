@@ -1,26 +1,54 @@
 package rbac
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"kc-api/internal/auth"
 	"kc-api/internal/utils"
 )
 
 // Handler handles HTTP requests for RBAC operations
 type Handler struct {
 	permissionManager *PermissionManager
+	service           Service
 }
 
 // NewHandler creates a new RBAC handler
-func NewHandler(pm *PermissionManager) *Handler {
-	return &Handler{permissionManager: pm}
+func NewHandler(pm *PermissionManager, service Service) *Handler {
+	return &Handler{permissionManager: pm, service: service}
 }
 
 // RegisterRoutes registers RBAC admin routes on the given router
 // These routes should only be accessible by sysadmin users
 func (h *Handler) RegisterRoutes(r chi.Router) {
+	// Permission administration is restricted to administrators and off limits while impersonating a user
+	r = r.With(auth.DenyImpersonation, auth.RequireAdmin)
+
 	r.Post("/admin/refresh-permissions", h.RefreshPermissions)
+
+	r.Route("/admin/permission-rules", func(r chi.Router) {
+		r.Get("/", h.ListRules)
+		r.Post("/", h.CreateRule)
+		r.Get("/history", h.ListRuleChanges)
+		r.Get("/routes", h.ListRoutes)
+		r.Get("/coverage", h.GetCoverage)
+		r.Get("/status", h.GetStatus)
+		r.Get("/{id}", h.GetRule)
+		r.Put("/{id}", h.UpdateRule)
+		r.Delete("/{id}", h.DeleteRule)
+	})
+
+	r.Route("/admin/permissions", func(r chi.Router) {
+		r.Get("/", h.ListPermissions)
+		r.Post("/", h.CreatePermission)
+		r.Get("/{id}", h.GetPermission)
+		r.Put("/{id}", h.UpdatePermission)
+		r.Delete("/{id}", h.DeletePermission)
+	})
 }
 
 // SuccessResponse represents a success response
@@ -30,21 +58,417 @@ type SuccessResponse struct {
 
 // RefreshPermissions godoc
 // @Summary      Refresh API permissions cache
-// @Description  Reloads API permissions from the database into the in-memory cache. This endpoint allows hot-reloading of permission rules without restarting the server.
+// @Description  Reloads API permissions from the database into the in-memory cache and notifies the other instances to reload theirs. This endpoint allows hot-reloading of permission rules without restarting the server.
 // @Tags         admin
 // @Accept       json
 // @Produce      json
 // @Success      200  {object}  SuccessResponse
 // @Failure      401  {object}  ErrorResponse  "Unauthorized"
-// @Failure      403  {object}  ErrorResponse  "Forbidden - requires sysadmin role"
+// @Failure      403  {object}  ErrorResponse  "Forbidden - requires the admin or full_access role"
 // @Failure      500  {object}  ErrorResponse  "Internal server error"
 // @Security     BearerAuth
 // @Router       /admin/refresh-permissions [post]
 func (h *Handler) RefreshPermissions(w http.ResponseWriter, r *http.Request) {
-	if err := h.permissionManager.LoadPermissions(r.Context()); err != nil {
+	if err := h.service.RefreshPermissions(r.Context()); err != nil {
 		utils.RespondInternalError(w, r, err, "Failed to refresh permissions")
 		return
 	}
 
 	utils.RespondJSON(w, http.StatusOK, SuccessResponse{Message: "Permissions refreshed successfully"})
 }
+
+// ListRules godoc
+// @Summary      List permission rules
+// @Description  Lists all permission rules of the RBAC middleware
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  PermissionRuleListResponse
+// @Failure      401  {object}  ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  ErrorResponse  "Forbidden"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/permission-rules [get]
+func (h *Handler) ListRules(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.ListRules(r.Context())
+	if err != nil {
+		utils.RespondInternalError(w, r, err, "Failed to retrieve permission rules")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// CreateRule godoc
+// @Summary      Create a permission rule
+// @Description  Creates a permission rule requiring one of the given named permissions (or, for rules not migrated yet, one of the given roles) for a method (or * for all methods) of a registered route. The rule takes effect immediately and is recorded in the change history.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request  body      CreatePermissionRuleRequest  true  "Permission rule"
+// @Success      201      {object}  PermissionRuleResponse
+// @Failure      400      {object}  ErrorResponse  "Invalid method, unknown route, unknown permission or unknown role"
+// @Failure      401      {object}  ErrorResponse  "Unauthorized"
+// @Failure      403      {object}  ErrorResponse  "Forbidden"
+// @Failure      409      {object}  ErrorResponse  "A rule for the method and path pattern already exists"
+// @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/permission-rules [post]
+func (h *Handler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var req CreatePermissionRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	result, err := h.service.CreateRule(r.Context(), auth.GetUserIDFromContext(r.Context()), &req)
+	if err != nil {
+		respondRuleError(w, r, err, "Failed to create permission rule")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, result)
+}
+
+// GetRule godoc
+// @Summary      Get a permission rule
+// @Description  Retrieves a permission rule by its ID
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "Permission rule ID"
+// @Success      200  {object}  PermissionRuleResponse
+// @Failure      400  {object}  ErrorResponse  "Invalid ID"
+// @Failure      404  {object}  ErrorResponse  "Permission rule not found"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/permission-rules/{id} [get]
+func (h *Handler) GetRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid ID")
+		return
+	}
+
+	result, err := h.service.GetRule(r.Context(), id)
+	if err != nil {
+		respondRuleError(w, r, err, "Failed to retrieve permission rule")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// UpdateRule godoc
+// @Summary      Update a permission rule
+// @Description  Changes the given fields of a permission rule. The change takes effect immediately and is recorded in the change history.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                          true  "Permission rule ID"
+// @Param        request  body      UpdatePermissionRuleRequest  true  "Fields to change"
+// @Success      200      {object}  PermissionRuleResponse
+// @Failure      400      {object}  ErrorResponse  "Invalid method, unknown route, unknown permission or unknown role"
+// @Failure      404      {object}  ErrorResponse  "Permission rule not found"
+// @Failure      409      {object}  ErrorResponse  "A rule for the method and path pattern already exists"
+// @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/permission-rules/{id} [put]
+func (h *Handler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid ID")
+		return
+	}
+
+	var req UpdatePermissionRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	result, err := h.service.UpdateRule(r.Context(), auth.GetUserIDFromContext(r.Context()), id, &req)
+	if err != nil {
+		respondRuleError(w, r, err, "Failed to update permission rule")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// DeleteRule godoc
+// @Summary      Delete a permission rule
+// @Description  Deletes a permission rule; the route is then handled by the default policy. The change takes effect immediately and is recorded in the change history.
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "Permission rule ID"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse  "Invalid ID"
+// @Failure      404  {object}  ErrorResponse  "Permission rule not found"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/permission-rules/{id} [delete]
+func (h *Handler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid ID")
+		return
+	}
+
+	if err := h.service.DeleteRule(r.Context(), auth.GetUserIDFromContext(r.Context()), id); err != nil {
+		respondRuleError(w, r, err, "Failed to delete permission rule")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, SuccessResponse{Message: "Permission rule deleted successfully"})
+}
+
+// ListRuleChanges godoc
+// @Summary      List permission rule changes
+// @Description  Lists the change history of permission rules with the rule before and after each change, newest first
+// @Tags         admin
+// @Produce      json
+// @Param        permission_id  query     int  false  "Only changes of this permission rule"
+// @Param        page           query     int  false  "Page number"  default(1)
+// @Param        limit          query     int  false  "Items per page"  default(20)
+// @Success      200            {object}  PermissionChangeListResponse
+// @Failure      400            {object}  ErrorResponse  "Invalid permission rule ID"
+// @Failure      500            {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/permission-rules/history [get]
+func (h *Handler) ListRuleChanges(w http.ResponseWriter, r *http.Request) {
+	var permissionID int64
+	if value := r.URL.Query().Get("permission_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id < 1 {
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid permission rule ID")
+			return
+		}
+		permissionID = id
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	result, err := h.service.ListRuleChanges(r.Context(), permissionID, page, limit)
+	if err != nil {
+		utils.RespondInternalError(w, r, err, "Failed to retrieve permission rule changes")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// ListRoutes godoc
+// @Summary      List routes
+// @Description  Lists the methods and path patterns registered on the router, which permission rules can be created for
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  RouteListResponse
+// @Failure      401  {object}  ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  ErrorResponse  "Forbidden"
+// @Security     BearerAuth
+// @Router       /admin/permission-rules/routes [get]
+func (h *Handler) ListRoutes(w http.ResponseWriter, r *http.Request) {
+	utils.RespondJSON(w, http.StatusOK, h.service.ListRoutes())
+}
+
+// GetCoverage godoc
+// @Summary      Get permission rule coverage
+// @Description  Compares the permission rules with the protected routes: routes without a rule (denied when RBAC_DENY_BY_DEFAULT is enabled), rules for routes that are not registered and rules whose method is not registered for their route
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  CoverageReport
+// @Failure      401  {object}  ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  ErrorResponse  "Forbidden"
+// @Failure      503  {object}  ErrorResponse  "Routes not recorded yet"
+// @Security     BearerAuth
+// @Router       /admin/permission-rules/coverage [get]
+func (h *Handler) GetCoverage(w http.ResponseWriter, r *http.Request) {
+	report, err := h.service.Coverage()
+	if err != nil {
+		respondRuleError(w, r, err, "Failed to compare permission rules with routes")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, report)
+}
+
+// GetStatus godoc
+// @Summary      Get loaded permission version
+// @Description  Reports the version (a hash of the rules, equal on instances with the same rules), number and load time of the permission rules loaded by the instance serving the request
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  PermissionStatus
+// @Failure      401  {object}  ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  ErrorResponse  "Forbidden"
+// @Security     BearerAuth
+// @Router       /admin/permission-rules/status [get]
+func (h *Handler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	utils.RespondJSON(w, http.StatusOK, h.service.Status())
+}
+
+// ListPermissions godoc
+// @Summary      List named permissions
+// @Description  Lists all named permissions with the roles they are assigned to
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  NamedPermissionListResponse
+// @Failure      401  {object}  ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  ErrorResponse  "Forbidden"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/permissions [get]
+func (h *Handler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.ListPermissions(r.Context())
+	if err != nil {
+		utils.RespondInternalError(w, r, err, "Failed to retrieve permissions")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// CreatePermission godoc
+// @Summary      Create a named permission
+// @Description  Creates a named permission such as tickets:update and assigns it to roles. Users get the permission through any of the roles, directly or through their groups. The change takes effect immediately.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request  body      CreateNamedPermissionRequest  true  "Named permission"
+// @Success      201      {object}  NamedPermissionResponse
+// @Failure      400      {object}  ErrorResponse  "Invalid name or unknown role"
+// @Failure      401      {object}  ErrorResponse  "Unauthorized"
+// @Failure      403      {object}  ErrorResponse  "Forbidden"
+// @Failure      409      {object}  ErrorResponse  "A permission with this name already exists"
+// @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/permissions [post]
+func (h *Handler) CreatePermission(w http.ResponseWriter, r *http.Request) {
+	var req CreateNamedPermissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	result, err := h.service.CreatePermission(r.Context(), auth.GetUserIDFromContext(r.Context()), &req)
+	if err != nil {
+		respondRuleError(w, r, err, "Failed to create permission")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, result)
+}
+
+// GetPermission godoc
+// @Summary      Get a named permission
+// @Description  Retrieves a named permission with its roles by its ID
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "Permission ID"
+// @Success      200  {object}  NamedPermissionResponse
+// @Failure      400  {object}  ErrorResponse  "Invalid ID"
+// @Failure      404  {object}  ErrorResponse  "Permission not found"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/permissions/{id} [get]
+func (h *Handler) GetPermission(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid ID")
+		return
+	}
+
+	result, err := h.service.GetPermission(r.Context(), id)
+	if err != nil {
+		respondRuleError(w, r, err, "Failed to retrieve permission")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// UpdatePermission godoc
+// @Summary      Update a named permission
+// @Description  Changes the description or replaces the roles of a named permission. The name cannot be changed. The change takes effect immediately.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                           true  "Permission ID"
+// @Param        request  body      UpdateNamedPermissionRequest  true  "Fields to change"
+// @Success      200      {object}  NamedPermissionResponse
+// @Failure      400      {object}  ErrorResponse  "Unknown role"
+// @Failure      404      {object}  ErrorResponse  "Permission not found"
+// @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/permissions/{id} [put]
+func (h *Handler) UpdatePermission(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid ID")
+		return
+	}
+
+	var req UpdateNamedPermissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	result, err := h.service.UpdatePermission(r.Context(), auth.GetUserIDFromContext(r.Context()), id, &req)
+	if err != nil {
+		respondRuleError(w, r, err, "Failed to update permission")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, result)
+}
+
+// DeletePermission godoc
+// @Summary      Delete a named permission
+// @Description  Deletes a named permission and its role assignments. Permissions still required by permission rules cannot be deleted.
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "Permission ID"
+// @Success      200  {object}  SuccessResponse
+// @Failure      400  {object}  ErrorResponse  "Invalid ID"
+// @Failure      404  {object}  ErrorResponse  "Permission not found"
+// @Failure      409  {object}  ErrorResponse  "Permission is required by permission rules"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Security     BearerAuth
+// @Router       /admin/permissions/{id} [delete]
+func (h *Handler) DeletePermission(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid ID")
+		return
+	}
+
+	if err := h.service.DeletePermission(r.Context(), auth.GetUserIDFromContext(r.Context()), id); err != nil {
+		respondRuleError(w, r, err, "Failed to delete permission")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, SuccessResponse{Message: "Permission deleted successfully"})
+}
+
+// respondRuleError maps permission rule and named permission errors to responses
+func respondRuleError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, ErrRuleNotFound):
+		utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Permission rule not found")
+	case errors.Is(err, ErrInvalidMethod), errors.Is(err, ErrInvalidPattern), errors.Is(err, ErrUnknownRoute),
+		errors.Is(err, ErrNoRequirement), errors.Is(err, ErrUnknownRole), errors.Is(err, ErrUnknownPermission),
+		errors.Is(err, ErrInvalidPermissionName):
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", err.Error())
+	case errors.Is(err, ErrPermissionNotFound):
+		utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Permission not found")
+	case errors.Is(err, ErrPermissionExists):
+		utils.RespondError(w, r, http.StatusConflict, "Conflict", "A permission with this name already exists")
+	case errors.Is(err, ErrPermissionInUse):
+		utils.RespondError(w, r, http.StatusConflict, "Conflict", err.Error())
+	case errors.Is(err, ErrRuleExists):
+		utils.RespondError(w, r, http.StatusConflict, "Conflict", "A permission rule for this method and path pattern already exists")
+	case errors.Is(err, ErrRoutesUnknown):
+		utils.RespondError(w, r, http.StatusServiceUnavailable, "Service Unavailable", "Routes have not been recorded yet")
+	default:
+		utils.RespondInternalError(w, r, err, message)
+	}
+}
@@ -1,10 +1,15 @@
 package rbac
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
@@ -14,7 +19,25 @@ import (
 
 // MockRepository is a mock implementation of the Repository interface for testing
 type MockRepository struct {
-	GetAllPermissionsFunc func(ctx context.Context) ([]APIPermission, error)
+	GetAllPermissionsFunc     func(ctx context.Context) ([]APIPermission, error)
+	GetPermissionFunc         func(ctx context.Context, id int64) (*APIPermission, error)
+	PermissionExistsFunc      func(ctx context.Context, method, pathPattern string, excludeID int64) (bool, error)
+	CreatePermissionFunc      func(ctx context.Context, perm *APIPermission, actorID string) error
+	UpdatePermissionFunc      func(ctx context.Context, before, after *APIPermission, actorID string) error
+	DeletePermissionFunc      func(ctx context.Context, perm *APIPermission, actorID string) error
+	ListPermissionChangesFunc func(ctx context.Context, permissionID int64, limit, offset int) ([]PermissionChange, int, error)
+	FindMissingRolesFunc      func(ctx context.Context, names []string) ([]string, error)
+
+	NotifyPermissionsChangedFunc func(ctx context.Context) error
+
+	FindMissingPermissionsFunc       func(ctx context.Context, names []string) ([]string, error)
+	ListNamedPermissionsFunc         func(ctx context.Context) ([]NamedPermission, error)
+	GetNamedPermissionFunc           func(ctx context.Context, id int64) (*NamedPermission, error)
+	NamedPermissionExistsFunc        func(ctx context.Context, name string) (bool, error)
+	CreateNamedPermissionFunc        func(ctx context.Context, perm *NamedPermission) error
+	UpdateNamedPermissionFunc        func(ctx context.Context, perm *NamedPermission) error
+	DeleteNamedPermissionFunc        func(ctx context.Context, id int64) error
+	ListRulesRequiringPermissionFunc func(ctx context.Context, name string) ([]APIPermission, error)
 }
 
 func (m *MockRepository) GetAllPermissions(ctx context.Context) ([]APIPermission, error) {
@@ -24,6 +47,118 @@ func (m *MockRepository) GetAllPermissions(ctx context.Context) ([]APIPermission
 	return nil, nil
 }
 
+func (m *MockRepository) GetPermission(ctx context.Context, id int64) (*APIPermission, error) {
+	if m.GetPermissionFunc != nil {
+		return m.GetPermissionFunc(ctx, id)
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *MockRepository) PermissionExists(ctx context.Context, method, pathPattern string, excludeID int64) (bool, error) {
+	if m.PermissionExistsFunc != nil {
+		return m.PermissionExistsFunc(ctx, method, pathPattern, excludeID)
+	}
+	return false, nil
+}
+
+func (m *MockRepository) CreatePermission(ctx context.Context, perm *APIPermission, actorID string) error {
+	if m.CreatePermissionFunc != nil {
+		return m.CreatePermissionFunc(ctx, perm, actorID)
+	}
+	return nil
+}
+
+func (m *MockRepository) UpdatePermission(ctx context.Context, before, after *APIPermission, actorID string) error {
+	if m.UpdatePermissionFunc != nil {
+		return m.UpdatePermissionFunc(ctx, before, after, actorID)
+	}
+	return nil
+}
+
+func (m *MockRepository) DeletePermission(ctx context.Context, perm *APIPermission, actorID string) error {
+	if m.DeletePermissionFunc != nil {
+		return m.DeletePermissionFunc(ctx, perm, actorID)
+	}
+	return nil
+}
+
+func (m *MockRepository) ListPermissionChanges(ctx context.Context, permissionID int64, limit, offset int) ([]PermissionChange, int, error) {
+	if m.ListPermissionChangesFunc != nil {
+		return m.ListPermissionChangesFunc(ctx, permissionID, limit, offset)
+	}
+	return nil, 0, nil
+}
+
+func (m *MockRepository) FindMissingRoles(ctx context.Context, names []string) ([]string, error) {
+	if m.FindMissingRolesFunc != nil {
+		return m.FindMissingRolesFunc(ctx, names)
+	}
+	return nil, nil
+}
+
+func (m *MockRepository) NotifyPermissionsChanged(ctx context.Context) error {
+	if m.NotifyPermissionsChangedFunc != nil {
+		return m.NotifyPermissionsChangedFunc(ctx)
+	}
+	return nil
+}
+
+func (m *MockRepository) FindMissingPermissions(ctx context.Context, names []string) ([]string, error) {
+	if m.FindMissingPermissionsFunc != nil {
+		return m.FindMissingPermissionsFunc(ctx, names)
+	}
+	return nil, nil
+}
+
+func (m *MockRepository) ListNamedPermissions(ctx context.Context) ([]NamedPermission, error) {
+	if m.ListNamedPermissionsFunc != nil {
+		return m.ListNamedPermissionsFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *MockRepository) GetNamedPermission(ctx context.Context, id int64) (*NamedPermission, error) {
+	if m.GetNamedPermissionFunc != nil {
+		return m.GetNamedPermissionFunc(ctx, id)
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *MockRepository) NamedPermissionExists(ctx context.Context, name string) (bool, error) {
+	if m.NamedPermissionExistsFunc != nil {
+		return m.NamedPermissionExistsFunc(ctx, name)
+	}
+	return false, nil
+}
+
+func (m *MockRepository) CreateNamedPermission(ctx context.Context, perm *NamedPermission) error {
+	if m.CreateNamedPermissionFunc != nil {
+		return m.CreateNamedPermissionFunc(ctx, perm)
+	}
+	return nil
+}
+
+func (m *MockRepository) UpdateNamedPermission(ctx context.Context, perm *NamedPermission) error {
+	if m.UpdateNamedPermissionFunc != nil {
+		return m.UpdateNamedPermissionFunc(ctx, perm)
+	}
+	return nil
+}
+
+func (m *MockRepository) DeleteNamedPermission(ctx context.Context, id int64) error {
+	if m.DeleteNamedPermissionFunc != nil {
+		return m.DeleteNamedPermissionFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *MockRepository) ListRulesRequiringPermission(ctx context.Context, name string) ([]APIPermission, error) {
+	if m.ListRulesRequiringPermissionFunc != nil {
+		return m.ListRulesRequiringPermissionFunc(ctx, name)
+	}
+	return nil, nil
+}
+
 func TestPermissionManager_LoadPermissions(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -76,7 +211,54 @@ func TestPermissionManager_LoadPermissions(t *testing.T) {
 	}
 }
 
-func TestPermissionManager_GetRequiredRoles(t *testing.T) {
+func TestPermissionManager_Status(t *testing.T) {
+	rules := []APIPermission{
+		{ID: 1, Method: "GET", PathPattern: "/users", RequiredRoles: []string{"user", "admin"}},
+		{ID: 2, Method: "POST", PathPattern: "/users", RequiredRoles: []string{"admin"}},
+	}
+	var loadErr error
+	pm := NewPermissionManager(&MockRepository{
+		GetAllPermissionsFunc: func(ctx context.Context) ([]APIPermission, error) {
+			return rules, loadErr
+		},
+	})
+
+	if status := pm.Status(); status.Version != "" || status.LoadedAt != nil {
+		t.Errorf("expected no version before the first load, got %+v", status)
+	}
+
+	_ = pm.LoadPermissions(context.Background())
+	status := pm.Status()
+	if status.Version == "" || status.RuleCount != 2 || status.Reloads != 1 || status.LoadedAt == nil {
+		t.Errorf("unexpected status after load: %+v", status)
+	}
+
+	// The version does not depend on the order of rules and roles
+	rules = []APIPermission{
+		{ID: 2, Method: "POST", PathPattern: "/users", RequiredRoles: []string{"admin"}},
+		{ID: 1, Method: "GET", PathPattern: "/users", RequiredRoles: []string{"admin", "user"}},
+	}
+	_ = pm.LoadPermissions(context.Background())
+	if pm.Version() != status.Version {
+		t.Errorf("expected version %s for the same rules, got %s", status.Version, pm.Version())
+	}
+
+	rules = rules[:1]
+	_ = pm.LoadPermissions(context.Background())
+	if pm.Version() == status.Version {
+		t.Error("expected a new version after a rule was removed")
+	}
+
+	// A failed reload keeps the loaded rules and reports the error
+	version := pm.Version()
+	loadErr = errors.New("database connection failed")
+	_ = pm.LoadPermissions(context.Background())
+	if status := pm.Status(); status.Version != version || status.LastError == "" {
+		t.Errorf("expected version %s and an error after a failed reload, got %+v", version, status)
+	}
+}
+
+func TestPermissionManager_GetRequirement(t *testing.T) {
 	mockRepo := &MockRepository{
 		GetAllPermissionsFunc: func(ctx context.Context) ([]APIPermission, error) {
 			return []APIPermission{
@@ -144,21 +326,112 @@ func TestPermissionManager_GetRequiredRoles(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			roles, found := pm.GetRequiredRoles(tt.method, tt.path)
+			requirement, found := pm.GetRequirement(tt.method, tt.path)
 
 			if found != tt.expectedFound {
 				t.Errorf("expected found=%v, got found=%v", tt.expectedFound, found)
 			}
 
 			if tt.expectedFound {
-				if len(roles) != len(tt.expectedRoles) {
-					t.Errorf("expected %d roles, got %d", len(tt.expectedRoles), len(roles))
+				if len(requirement.Roles) != len(tt.expectedRoles) {
+					t.Errorf("expected %d roles, got %d", len(tt.expectedRoles), len(requirement.Roles))
 				}
 			}
 		})
 	}
 }
 
+// permissionRepository returns rules requiring named permissions and the roles of the permissions
+func permissionRepository() *MockRepository {
+	return &MockRepository{
+		GetAllPermissionsFunc: func(ctx context.Context) ([]APIPermission, error) {
+			return []APIPermission{
+				{ID: 1, Method: "GET", PathPattern: "/tickets", RequiredPermissions: []string{"tickets:read"}},
+				{ID: 2, Method: "PUT", PathPattern: "/tickets/{id}", RequiredPermissions: []string{"tickets:update", "tickets:admin"}},
+				{ID: 3, Method: "DELETE", PathPattern: "/tickets/{id}", RequiredPermissions: []string{"tickets:delete"}, RequiredRoles: []string{"admin"}},
+			}, nil
+		},
+		ListNamedPermissionsFunc: func(ctx context.Context) ([]NamedPermission, error) {
+			return []NamedPermission{
+				{ID: 1, Name: "tickets:read", Roles: []string{"requester", "agent"}},
+				{ID: 2, Name: "tickets:update", Roles: []string{"agent"}},
+				{ID: 3, Name: "tickets:delete", Roles: []string{"manager"}},
+				{ID: 4, Name: "tickets:admin"},
+			}, nil
+		},
+	}
+}
+
+func TestPermissionManager_Authorize(t *testing.T) {
+	pm := NewPermissionManager(permissionRepository())
+	if err := pm.LoadPermissions(context.Background()); err != nil {
+		t.Fatalf("failed to load permissions: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		roles         []string
+		expectAllowed bool
+		expectFound   bool
+	}{
+		{name: "permission of a role", method: "GET", path: "/tickets", roles: []string{"requester"}, expectAllowed: true, expectFound: true},
+		{name: "permission of one of several roles", method: "PUT", path: "/tickets/{id}", roles: []string{"requester", "agent"}, expectAllowed: true, expectFound: true},
+		{name: "no role grants the permission", method: "PUT", path: "/tickets/{id}", roles: []string{"requester"}, expectAllowed: false, expectFound: true},
+		{name: "permission assigned to no role", method: "PUT", path: "/tickets/{id}", roles: []string{"manager"}, expectAllowed: false, expectFound: true},
+		{name: "required role of a rule not migrated yet", method: "DELETE", path: "/tickets/{id}", roles: []string{"admin"}, expectAllowed: true, expectFound: true},
+		{name: "permission next to required roles", method: "DELETE", path: "/tickets/{id}", roles: []string{"manager"}, expectAllowed: true, expectFound: true},
+		{name: "full_access has every permission", method: "PUT", path: "/tickets/{id}", roles: []string{"full_access"}, expectAllowed: true, expectFound: true},
+		{name: "no rule", method: "POST", path: "/tickets", roles: []string{"agent"}, expectAllowed: false, expectFound: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, found := pm.Authorize(tt.method, tt.path, tt.roles)
+			if allowed != tt.expectAllowed || found != tt.expectFound {
+				t.Errorf("expected allowed=%v found=%v, got allowed=%v found=%v", tt.expectAllowed, tt.expectFound, allowed, found)
+			}
+		})
+	}
+}
+
+func TestPermissionManager_EffectivePermissions(t *testing.T) {
+	repo := permissionRepository()
+	pm := NewPermissionManager(repo)
+	if err := pm.LoadPermissions(context.Background()); err != nil {
+		t.Fatalf("failed to load permissions: %v", err)
+	}
+
+	effective := pm.EffectivePermissions([]string{"agent", "requester", "agent"})
+	if expected := []string{"tickets:read", "tickets:update"}; !slices.Equal(effective, expected) {
+		t.Errorf("expected %v, got %v", expected, effective)
+	}
+
+	// Role sets are cached regardless of order
+	if cached := pm.EffectivePermissions([]string{"requester", "agent"}); !slices.Equal(cached, effective) {
+		t.Errorf("expected the cached %v, got %v", effective, cached)
+	}
+
+	if all := pm.EffectivePermissions([]string{"full_access"}); len(all) != 4 {
+		t.Errorf("expected full_access to have all 4 permissions, got %v", all)
+	}
+	if none := pm.EffectivePermissions(nil); len(none) != 0 {
+		t.Errorf("expected no permissions without roles, got %v", none)
+	}
+
+	// A reload replaces cached results
+	repo.ListNamedPermissionsFunc = func(ctx context.Context) ([]NamedPermission, error) {
+		return []NamedPermission{{ID: 1, Name: "tickets:read", Roles: []string{"agent"}}}, nil
+	}
+	if err := pm.LoadPermissions(context.Background()); err != nil {
+		t.Fatalf("failed to reload permissions: %v", err)
+	}
+	if effective := pm.EffectivePermissions([]string{"agent", "requester"}); !slices.Equal(effective, []string{"tickets:read"}) {
+		t.Errorf("expected [tickets:read] after the reload, got %v", effective)
+	}
+}
+
 func TestMiddleware_Authorize(t *testing.T) {
 	// Setup mock repository with test permissions
 	mockRepo := &MockRepository{
@@ -167,13 +440,16 @@ func TestMiddleware_Authorize(t *testing.T) {
 				{ID: 1, Method: "GET", PathPattern: "/users", RequiredRoles: []string{"admin", "user"}},
 				{ID: 2, Method: "POST", PathPattern: "/users", RequiredRoles: []string{"admin"}},
 				{ID: 3, Method: "GET", PathPattern: "/admin/settings", RequiredRoles: []string{"admin"}},
+				{ID: 4, Method: "PUT", PathPattern: "/users", RequiredPermissions: []string{"users:update"}},
 			}, nil
 		},
+		ListNamedPermissionsFunc: func(ctx context.Context) ([]NamedPermission, error) {
+			return []NamedPermission{{ID: 1, Name: "users:update", Roles: []string{"hr"}}}, nil
+		},
 	}
 
 	pm := NewPermissionManager(mockRepo)
 	_ = pm.LoadPermissions(context.Background())
-	middleware := NewMiddleware(pm)
 
 	tests := []struct {
 		name           string
@@ -181,6 +457,7 @@ func TestMiddleware_Authorize(t *testing.T) {
 		path           string
 		routePattern   string
 		userRoles      []string
+		denyByDefault  bool
 		expectedStatus int
 	}{
 		{
@@ -223,6 +500,22 @@ func TestMiddleware_Authorize(t *testing.T) {
 			userRoles:      nil,
 			expectedStatus: http.StatusForbidden,
 		},
+		{
+			name:           "role grants the required permission",
+			method:         "PUT",
+			path:           "/users",
+			routePattern:   "/users",
+			userRoles:      []string{"user", "hr"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "no role grants the required permission",
+			method:         "PUT",
+			path:           "/users",
+			routePattern:   "/users",
+			userRoles:      []string{"user"},
+			expectedStatus: http.StatusForbidden,
+		},
 		{
 			name:           "unregistered route - allow by default",
 			method:         "GET",
@@ -239,6 +532,42 @@ func TestMiddleware_Authorize(t *testing.T) {
 			userRoles:      nil,
 			expectedStatus: http.StatusOK,
 		},
+		{
+			name:           "deny by default - unregistered route denied",
+			method:         "GET",
+			path:           "/unknown",
+			routePattern:   "/unknown",
+			userRoles:      []string{"user"},
+			denyByDefault:  true,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "deny by default - method without rule denied",
+			method:         "DELETE",
+			path:           "/users",
+			routePattern:   "/users",
+			userRoles:      []string{"admin"},
+			denyByDefault:  true,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "deny by default - rule still grants access",
+			method:         "GET",
+			path:           "/users",
+			routePattern:   "/users",
+			userRoles:      []string{"user"},
+			denyByDefault:  true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "deny by default - full_access bypass",
+			method:         "GET",
+			path:           "/unknown",
+			routePattern:   "/unknown",
+			userRoles:      []string{"full_access"},
+			denyByDefault:  true,
+			expectedStatus: http.StatusOK,
+		},
 	}
 
 	for _, tt := range tests {
@@ -260,7 +589,7 @@ func TestMiddleware_Authorize(t *testing.T) {
 					next.ServeHTTP(w, r.WithContext(ctx))
 				})
 			})
-			r.Use(middleware.Authorize)
+			r.Use(NewMiddleware(pm, &Config{DenyByDefault: tt.denyByDefault}).Authorize)
 			r.Method(tt.method, tt.routePattern, testHandler)
 
 			req := httptest.NewRequest(tt.method, tt.path, nil)
@@ -295,6 +624,7 @@ func TestHandler_RefreshPermissions(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			notified := false
 			mockRepo := &MockRepository{
 				GetAllPermissionsFunc: func(ctx context.Context) ([]APIPermission, error) {
 					if tt.mockError != nil {
@@ -304,17 +634,101 @@ func TestHandler_RefreshPermissions(t *testing.T) {
 						{ID: 1, Method: "GET", PathPattern: "/users", RequiredRoles: []string{"user"}},
 					}, nil
 				},
+				NotifyPermissionsChangedFunc: func(ctx context.Context) error {
+					notified = true
+					return nil
+				},
 			}
 
 			pm := NewPermissionManager(mockRepo)
-			handler := NewHandler(pm)
+			handler := NewHandler(pm, NewService(mockRepo, pm))
 
 			r := chi.NewRouter()
 			handler.RegisterRoutes(r)
 
 			req := httptest.NewRequest(http.MethodPost, "/admin/refresh-permissions", nil)
+			req = req.WithContext(setUserRolesInContext(req.Context(), []string{"admin"}))
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			// Other instances are only notified after a successful reload
+			if notified != (tt.mockError == nil) {
+				t.Errorf("expected other instances to be notified: %v, got %v", tt.mockError == nil, notified)
+			}
+		})
+	}
+}
+
+func TestHandler_AdministrationWhileImpersonating(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{method: http.MethodPost, path: "/admin/refresh-permissions"},
+		{method: http.MethodGet, path: "/admin/permission-rules"},
+		{method: http.MethodPost, path: "/admin/permission-rules"},
+		{method: http.MethodPut, path: "/admin/permission-rules/1"},
+		{method: http.MethodPost, path: "/admin/permissions"},
+		{method: http.MethodDelete, path: "/admin/permissions/1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			mockRepo := &MockRepository{}
+			pm := NewPermissionManager(mockRepo)
+			handler := NewHandler(pm, NewService(mockRepo, pm))
+
+			r := chi.NewRouter()
+			r.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					// The impersonated user's roles would pass the administrator check
+					claims := &auth.TokenClaims{UserID: "user-456", Roles: []string{"full_access"}, Actor: &auth.TokenActor{UserID: "admin-123"}}
+					ctx := auth.SetClaimsInContext(req.Context(), claims)
+					next.ServeHTTP(w, req.WithContext(setUserRolesInContext(ctx, claims.Roles)))
+				})
+			})
+			handler.RegisterRoutes(r)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader("{}"))
 			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
 
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_AdministrationWithoutAdminRole(t *testing.T) {
+	tests := []struct {
+		name           string
+		roles          []string
+		expectedStatus int
+	}{
+		{name: "no roles", expectedStatus: http.StatusForbidden},
+		{name: "regular user", roles: []string{"user"}, expectedStatus: http.StatusForbidden},
+		{name: "admin", roles: []string{"user", "admin"}, expectedStatus: http.StatusOK},
+		{name: "full access", roles: []string{"full_access"}, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockRepository{}
+			pm := NewPermissionManager(mockRepo)
+			handler := NewHandler(pm, NewService(mockRepo, pm))
+
+			r := chi.NewRouter()
+			handler.RegisterRoutes(r)
+
+			// No permission rule covers the route, so only the administrator check applies
+			req := httptest.NewRequest(http.MethodGet, "/admin/permission-rules", nil)
+			req = req.WithContext(setUserRolesInContext(req.Context(), tt.roles))
+			rec := httptest.NewRecorder()
 			r.ServeHTTP(rec, req)
 
 			if rec.Code != tt.expectedStatus {
@@ -324,6 +738,633 @@ func TestHandler_RefreshPermissions(t *testing.T) {
 	}
 }
 
+// testRouter registers the routes permission rules are validated against in the tests below
+func testRouter() chi.Router {
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+
+	r := chi.NewRouter()
+	r.Group(func(r chi.Router) {
+		r.Route("/users", func(r chi.Router) {
+			r.Get("/", noop)
+			r.Post("/", noop)
+			r.Get("/{id}", noop)
+		})
+		r.Delete("/files/{id}", noop)
+	})
+	return r
+}
+
+func TestCollectRoutes(t *testing.T) {
+	routes, err := CollectRoutes(testRouter())
+	if err != nil {
+		t.Fatalf("failed to collect routes: %v", err)
+	}
+
+	expected := []Route{
+		{Method: "DELETE", Pattern: "/files/{id}"},
+		{Method: "GET", Pattern: "/users"},
+		{Method: "POST", Pattern: "/users"},
+		{Method: "GET", Pattern: "/users/{id}"},
+	}
+	if !slices.Equal(routes, expected) {
+		t.Errorf("expected routes %v, got %v", expected, routes)
+	}
+
+	// The collected patterns are the ones the middleware resolves before the sub-router matches
+	for _, route := range expected {
+		var seen string
+		probe := chi.NewRouter()
+		probe.Group(func(r chi.Router) {
+			r.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					seen = RoutePattern(req)
+					next.ServeHTTP(w, req)
+				})
+			})
+			r.Mount("/", testRouter())
+		})
+
+		path := strings.ReplaceAll(route.Pattern, "{id}", "42")
+		probe.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(route.Method, path, nil))
+		if seen != route.Pattern {
+			t.Errorf("expected the middleware to see %s for %s %s, got %q", route.Pattern, route.Method, path, seen)
+		}
+	}
+}
+
+// TestMiddleware_AuthorizeNestedRoutes runs the middleware on a group the way the server does, so
+// that rules on the routes of sub-routers are enforced before the sub-router matches the path
+func TestMiddleware_AuthorizeNestedRoutes(t *testing.T) {
+	pm := NewPermissionManager(&MockRepository{
+		GetAllPermissionsFunc: func(ctx context.Context) ([]APIPermission, error) {
+			return []APIPermission{
+				{ID: 1, Method: "GET", PathPattern: "/users", RequiredRoles: []string{"user"}},
+				{ID: 2, Method: "GET", PathPattern: "/users/{id}", RequiredRoles: []string{"admin"}},
+			}, nil
+		},
+	})
+	if err := pm.LoadPermissions(context.Background()); err != nil {
+		t.Fatalf("failed to load permissions: %v", err)
+	}
+	if err := pm.SetRoutes(testRouter()); err != nil {
+		t.Fatalf("failed to set routes: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		userRoles      []string
+		denyByDefault  bool
+		expectedStatus int
+	}{
+		{name: "sub-router root allowed", method: "GET", path: "/users", userRoles: []string{"user"}, expectedStatus: http.StatusOK},
+		{name: "param route denied", method: "GET", path: "/users/42", userRoles: []string{"user"}, expectedStatus: http.StatusForbidden},
+		{name: "param route allowed", method: "GET", path: "/users/42", userRoles: []string{"admin"}, expectedStatus: http.StatusOK},
+		{name: "route without rule allowed", method: "POST", path: "/users", userRoles: []string{"user"}, expectedStatus: http.StatusOK},
+		{name: "deny by default - covered param route allowed", method: "GET", path: "/users/42", userRoles: []string{"admin"}, denyByDefault: true, expectedStatus: http.StatusOK},
+		{name: "deny by default - covered sub-router root allowed", method: "GET", path: "/users", userRoles: []string{"user"}, denyByDefault: true, expectedStatus: http.StatusOK},
+		{name: "deny by default - uncovered route denied", method: "POST", path: "/users", userRoles: []string{"user"}, denyByDefault: true, expectedStatus: http.StatusForbidden},
+	}
+
+	// Deny mode enforces exactly what the coverage report shows
+	report := pm.Coverage()
+	expectedUncovered := []Route{{Method: "DELETE", Pattern: "/files/{id}"}, {Method: "POST", Pattern: "/users"}}
+	if !slices.Equal(report.UncoveredRoutes, expectedUncovered) {
+		t.Errorf("expected uncovered routes %v, got %v", expectedUncovered, report.UncoveredRoutes)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := chi.NewRouter()
+			r.Group(func(r chi.Router) {
+				r.Use(func(next http.Handler) http.Handler {
+					return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+						next.ServeHTTP(w, req.WithContext(setUserRolesInContext(req.Context(), tt.userRoles)))
+					})
+				})
+				r.Use(NewMiddleware(pm, &Config{DenyByDefault: tt.denyByDefault}).Authorize)
+				r.Route("/users", func(r chi.Router) {
+					r.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+					r.Post("/", func(w http.ResponseWriter, r *http.Request) {})
+					r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {})
+				})
+			})
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestPermissionManager_HasRoute(t *testing.T) {
+	pm := NewPermissionManager(&MockRepository{})
+	if !pm.HasRoute("GET", "/anything") {
+		t.Error("expected every route to be accepted before routes are set")
+	}
+
+	if err := pm.SetRoutes(testRouter()); err != nil {
+		t.Fatalf("failed to set routes: %v", err)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		want   bool
+	}{
+		{method: "GET", path: "/users", want: true},
+		{method: "POST", path: "/users", want: true},
+		{method: "DELETE", path: "/users", want: false},
+		{method: "*", path: "/users/{id}", want: true},
+		{method: "GET", path: "/users/{userId}", want: false},
+		{method: "*", path: "/unknown", want: false},
+	}
+	for _, tt := range tests {
+		if got := pm.HasRoute(tt.method, tt.path); got != tt.want {
+			t.Errorf("HasRoute(%s, %s) = %v, want %v", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestPermissionManager_Coverage(t *testing.T) {
+	pm := NewPermissionManager(&MockRepository{
+		GetAllPermissionsFunc: func(ctx context.Context) ([]APIPermission, error) {
+			return []APIPermission{
+				{ID: 1, Method: "GET", PathPattern: "/users", RequiredRoles: []string{"user"}},
+				{ID: 2, Method: "*", PathPattern: "/users/{id}", RequiredRoles: []string{"admin"}},
+				{ID: 3, Method: "PATCH", PathPattern: "/files/{id}", RequiredRoles: []string{"admin"}},
+				{ID: 4, Method: "GET", PathPattern: "/legacy", RequiredRoles: []string{"admin"}},
+			}, nil
+		},
+	})
+	_ = pm.LoadPermissions(context.Background())
+
+	if pm.Coverage() != nil {
+		t.Error("expected no report before routes are set")
+	}
+	if err := pm.SetRoutes(testRouter()); err != nil {
+		t.Fatalf("failed to set routes: %v", err)
+	}
+
+	report := pm.Coverage()
+	if report.RouteCount != 4 || report.RuleCount != 4 {
+		t.Errorf("expected 4 routes and 4 rules, got %d and %d", report.RouteCount, report.RuleCount)
+	}
+
+	uncovered := []Route{{Method: "DELETE", Pattern: "/files/{id}"}, {Method: "POST", Pattern: "/users"}}
+	if !slices.Equal(report.UncoveredRoutes, uncovered) {
+		t.Errorf("expected uncovered routes %v, got %v", uncovered, report.UncoveredRoutes)
+	}
+
+	stale := []Route{{Method: "GET", Pattern: "/legacy"}}
+	if !slices.Equal(report.StaleRules, stale) {
+		t.Errorf("expected stale rules %v, got %v", stale, report.StaleRules)
+	}
+
+	if len(report.MethodMismatches) != 1 {
+		t.Fatalf("expected 1 method mismatch, got %v", report.MethodMismatches)
+	}
+	mismatch := report.MethodMismatches[0]
+	if mismatch.Method != "PATCH" || mismatch.Pattern != "/files/{id}" || !slices.Equal(mismatch.RegisteredMethods, []string{"DELETE"}) {
+		t.Errorf("unexpected method mismatch %+v", mismatch)
+	}
+
+	if !report.HasIssues() {
+		t.Error("expected the report to have issues")
+	}
+	if BuildCoverageReport(uncovered, uncovered).HasIssues() {
+		t.Error("expected no issues when every route has a rule")
+	}
+}
+
+func TestService_CreateRule(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         CreatePermissionRuleRequest
+		exists      bool
+		roles       []string
+		expectedErr error
+	}{
+		{
+			name:  "valid rule",
+			req:   CreatePermissionRuleRequest{Method: "delete", PathPattern: " /files/{id} ", RequiredRoles: []string{"admin", " admin", "", "user"}},
+			roles: []string{"admin", "user"},
+		},
+		{
+			name:  "wildcard method",
+			req:   CreatePermissionRuleRequest{Method: "*", PathPattern: "/users/{id}", RequiredRoles: []string{"user"}},
+			roles: []string{"user"},
+		},
+		{
+			name: "named permissions",
+			req:  CreatePermissionRuleRequest{Method: "GET", PathPattern: "/users/{id}", RequiredPermissions: []string{"users:read", " users:read"}},
+		},
+		{
+			name:        "unknown permission",
+			req:         CreatePermissionRuleRequest{Method: "GET", PathPattern: "/users/{id}", RequiredPermissions: []string{"users:read", "users:peek"}},
+			expectedErr: ErrUnknownPermission,
+		},
+		{
+			name:        "invalid method",
+			req:         CreatePermissionRuleRequest{Method: "FETCH", PathPattern: "/users", RequiredRoles: []string{"admin"}},
+			expectedErr: ErrInvalidMethod,
+		},
+		{
+			name:        "relative pattern",
+			req:         CreatePermissionRuleRequest{Method: "GET", PathPattern: "users", RequiredRoles: []string{"admin"}},
+			expectedErr: ErrInvalidPattern,
+		},
+		{
+			name:        "unknown route",
+			req:         CreatePermissionRuleRequest{Method: "GET", PathPattern: "/users/{userId}", RequiredRoles: []string{"admin"}},
+			expectedErr: ErrUnknownRoute,
+		},
+		{
+			name:        "method not registered",
+			req:         CreatePermissionRuleRequest{Method: "PUT", PathPattern: "/users", RequiredRoles: []string{"admin"}},
+			expectedErr: ErrUnknownRoute,
+		},
+		{
+			name:        "no roles",
+			req:         CreatePermissionRuleRequest{Method: "GET", PathPattern: "/users", RequiredRoles: []string{" "}},
+			expectedErr: ErrNoRequirement,
+		},
+		{
+			name:        "unknown role",
+			req:         CreatePermissionRuleRequest{Method: "GET", PathPattern: "/users", RequiredRoles: []string{"admin", "superuser"}},
+			expectedErr: ErrUnknownRole,
+		},
+		{
+			name:        "duplicate rule",
+			req:         CreatePermissionRuleRequest{Method: "GET", PathPattern: "/users", RequiredRoles: []string{"admin"}},
+			exists:      true,
+			expectedErr: ErrRuleExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var saved []APIPermission
+			mockRepo := &MockRepository{
+				GetAllPermissionsFunc: func(ctx context.Context) ([]APIPermission, error) {
+					return saved, nil
+				},
+				PermissionExistsFunc: func(ctx context.Context, method, pathPattern string, excludeID int64) (bool, error) {
+					return tt.exists, nil
+				},
+				FindMissingRolesFunc: func(ctx context.Context, names []string) ([]string, error) {
+					var missing []string
+					for _, name := range names {
+						if !slices.Contains([]string{"admin", "user"}, name) {
+							missing = append(missing, name)
+						}
+					}
+					return missing, nil
+				},
+				FindMissingPermissionsFunc: func(ctx context.Context, names []string) ([]string, error) {
+					var missing []string
+					for _, name := range names {
+						if name != "users:read" {
+							missing = append(missing, name)
+						}
+					}
+					return missing, nil
+				},
+				CreatePermissionFunc: func(ctx context.Context, perm *APIPermission, actorID string) error {
+					perm.ID = int64(len(saved) + 1)
+					saved = append(saved, *perm)
+					return nil
+				},
+			}
+			pm := NewPermissionManager(mockRepo)
+			if err := pm.SetRoutes(testRouter()); err != nil {
+				t.Fatalf("failed to set routes: %v", err)
+			}
+
+			rule, err := NewService(mockRepo, pm).CreateRule(context.Background(), "actor-id", &tt.req)
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+				}
+				if len(saved) != 0 {
+					t.Error("expected the rule not to be saved")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			// The rule is normalized and applied without a manual refresh
+			if rule.Method != strings.ToUpper(tt.req.Method) || strings.TrimSpace(rule.PathPattern) != rule.PathPattern {
+				t.Errorf("expected a normalized rule, got %s %q", rule.Method, rule.PathPattern)
+			}
+			requirement, found := pm.GetRequirement(rule.Method, rule.PathPattern)
+			if !found || !slices.Equal(requirement.Roles, rule.RequiredRoles) || !slices.Equal(requirement.Permissions, rule.RequiredPermissions) {
+				t.Errorf("expected the rule to be loaded, got %+v (found=%v)", requirement, found)
+			}
+			if !slices.Equal(rule.RequiredRoles, tt.roles) {
+				t.Errorf("expected roles %v, got %v", tt.roles, rule.RequiredRoles)
+			}
+		})
+	}
+}
+
+func TestService_UpdateAndDeleteRule(t *testing.T) {
+	stored := APIPermission{ID: 7, Method: "GET", PathPattern: "/users", RequiredRoles: []string{"admin"}}
+	rules := []APIPermission{stored}
+	var changes []string
+
+	mockRepo := &MockRepository{
+		GetAllPermissionsFunc: func(ctx context.Context) ([]APIPermission, error) {
+			return rules, nil
+		},
+		GetPermissionFunc: func(ctx context.Context, id int64) (*APIPermission, error) {
+			for _, rule := range rules {
+				if rule.ID == id {
+					return &rule, nil
+				}
+			}
+			return nil, sql.ErrNoRows
+		},
+		UpdatePermissionFunc: func(ctx context.Context, before, after *APIPermission, actorID string) error {
+			changes = append(changes, before.Method+">"+after.Method)
+			rules = []APIPermission{*after}
+			return nil
+		},
+		DeletePermissionFunc: func(ctx context.Context, perm *APIPermission, actorID string) error {
+			changes = append(changes, "delete "+perm.Method)
+			rules = nil
+			return nil
+		},
+	}
+	pm := NewPermissionManager(mockRepo)
+	if err := pm.SetRoutes(testRouter()); err != nil {
+		t.Fatalf("failed to set routes: %v", err)
+	}
+	svc := NewService(mockRepo, pm)
+	ctx := context.Background()
+
+	method := "post"
+	if _, err := svc.UpdateRule(ctx, "actor-id", 7, &UpdatePermissionRuleRequest{Method: &method}); err != nil {
+		t.Fatalf("failed to update rule: %v", err)
+	}
+	if _, found := pm.GetRequirement("POST", "/users"); !found {
+		t.Error("expected the updated rule to be loaded")
+	}
+	if _, found := pm.GetRequirement("GET", "/users"); found {
+		t.Error("expected the previous rule to be gone")
+	}
+
+	if err := svc.DeleteRule(ctx, "actor-id", 7); err != nil {
+		t.Fatalf("failed to delete rule: %v", err)
+	}
+	if _, found := pm.GetRequirement("POST", "/users"); found {
+		t.Error("expected the deleted rule to be gone")
+	}
+	if err := svc.DeleteRule(ctx, "actor-id", 7); !errors.Is(err, ErrRuleNotFound) {
+		t.Errorf("expected ErrRuleNotFound, got %v", err)
+	}
+
+	if !slices.Equal(changes, []string{"GET>POST", "delete POST"}) {
+		t.Errorf("expected the changes to be recorded with the rule before and after, got %v", changes)
+	}
+}
+
+func TestService_NamedPermissions(t *testing.T) {
+	var saved []NamedPermission
+	var rules []APIPermission
+	mockRepo := &MockRepository{
+		FindMissingRolesFunc: func(ctx context.Context, names []string) ([]string, error) {
+			var missing []string
+			for _, name := range names {
+				if !slices.Contains([]string{"agent", "manager"}, name) {
+					missing = append(missing, name)
+				}
+			}
+			return missing, nil
+		},
+		NamedPermissionExistsFunc: func(ctx context.Context, name string) (bool, error) {
+			return slices.ContainsFunc(saved, func(p NamedPermission) bool { return p.Name == name }), nil
+		},
+		CreateNamedPermissionFunc: func(ctx context.Context, perm *NamedPermission) error {
+			perm.ID = int64(len(saved) + 1)
+			saved = append(saved, *perm)
+			return nil
+		},
+		ListNamedPermissionsFunc: func(ctx context.Context) ([]NamedPermission, error) {
+			return saved, nil
+		},
+		GetNamedPermissionFunc: func(ctx context.Context, id int64) (*NamedPermission, error) {
+			for _, perm := range saved {
+				if perm.ID == id {
+					return &perm, nil
+				}
+			}
+			return nil, sql.ErrNoRows
+		},
+		UpdateNamedPermissionFunc: func(ctx context.Context, perm *NamedPermission) error {
+			saved[perm.ID-1] = *perm
+			return nil
+		},
+		ListRulesRequiringPermissionFunc: func(ctx context.Context, name string) ([]APIPermission, error) {
+			return rules, nil
+		},
+	}
+	pm := NewPermissionManager(mockRepo)
+	svc := NewService(mockRepo, pm)
+	ctx := context.Background()
+
+	for _, name := range []string{"tickets", "Tickets:", "tickets update", ":update"} {
+		if _, err := svc.CreatePermission(ctx, "actor-id", &CreateNamedPermissionRequest{Name: name}); !errors.Is(err, ErrInvalidPermissionName) {
+			t.Errorf("expected ErrInvalidPermissionName for %q, got %v", name, err)
+		}
+	}
+	if _, err := svc.CreatePermission(ctx, "actor-id", &CreateNamedPermissionRequest{Name: "tickets:update", Roles: []string{"agent", "ghost"}}); !errors.Is(err, ErrUnknownRole) {
+		t.Errorf("expected ErrUnknownRole, got %v", err)
+	}
+
+	perm, err := svc.CreatePermission(ctx, "actor-id", &CreateNamedPermissionRequest{Name: " Tickets:Update ", Roles: []string{"agent", "agent "}})
+	if err != nil {
+		t.Fatalf("failed to create permission: %v", err)
+	}
+	if perm.Name != "tickets:update" || !slices.Equal(perm.Roles, []string{"agent"}) {
+		t.Errorf("expected a normalized permission, got %+v", perm)
+	}
+	if _, err := svc.CreatePermission(ctx, "actor-id", &CreateNamedPermissionRequest{Name: "tickets:update"}); !errors.Is(err, ErrPermissionExists) {
+		t.Errorf("expected ErrPermissionExists, got %v", err)
+	}
+
+	// Role assignments take effect without a manual refresh
+	if effective := pm.EffectivePermissions([]string{"agent"}); !slices.Equal(effective, []string{"tickets:update"}) {
+		t.Errorf("expected agents to have tickets:update, got %v", effective)
+	}
+	if _, err := svc.UpdatePermission(ctx, "actor-id", perm.ID, &UpdateNamedPermissionRequest{Roles: []string{"manager"}}); err != nil {
+		t.Fatalf("failed to update permission: %v", err)
+	}
+	if effective := pm.EffectivePermissions([]string{"agent"}); len(effective) != 0 {
+		t.Errorf("expected agents to lose tickets:update, got %v", effective)
+	}
+
+	// Permissions required by rules cannot be deleted
+	rules = []APIPermission{{ID: 3, Method: "PUT", PathPattern: "/tickets/{id}"}}
+	if err := svc.DeletePermission(ctx, "actor-id", perm.ID); !errors.Is(err, ErrPermissionInUse) {
+		t.Errorf("expected ErrPermissionInUse, got %v", err)
+	}
+	rules = nil
+	if err := svc.DeletePermission(ctx, "actor-id", perm.ID); err != nil {
+		t.Errorf("failed to delete permission: %v", err)
+	}
+	if err := svc.DeletePermission(ctx, "actor-id", 99); !errors.Is(err, ErrPermissionNotFound) {
+		t.Errorf("expected ErrPermissionNotFound, got %v", err)
+	}
+}
+
+func TestHandler_PermissionRules(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           interface{}
+		expectedStatus int
+	}{
+		{
+			name:           "create rule",
+			method:         http.MethodPost,
+			path:           "/admin/permission-rules",
+			body:           CreatePermissionRuleRequest{Method: "GET", PathPattern: "/users/{id}", RequiredRoles: []string{"admin"}},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "create rule for unknown route",
+			method:         http.MethodPost,
+			path:           "/admin/permission-rules",
+			body:           CreatePermissionRuleRequest{Method: "GET", PathPattern: "/nowhere", RequiredRoles: []string{"admin"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "create duplicate rule",
+			method:         http.MethodPost,
+			path:           "/admin/permission-rules",
+			body:           CreatePermissionRuleRequest{Method: "GET", PathPattern: "/users", RequiredRoles: []string{"admin"}},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "invalid body",
+			method:         http.MethodPost,
+			path:           "/admin/permission-rules",
+			body:           "invalid json",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "get missing rule",
+			method:         http.MethodGet,
+			path:           "/admin/permission-rules/99",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid ID",
+			method:         http.MethodDelete,
+			path:           "/admin/permission-rules/abc",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "history of a rule",
+			method:         http.MethodGet,
+			path:           "/admin/permission-rules/history?permission_id=1",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "history with invalid rule ID",
+			method:         http.MethodGet,
+			path:           "/admin/permission-rules/history?permission_id=x",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "list routes",
+			method:         http.MethodGet,
+			path:           "/admin/permission-rules/routes",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "coverage report",
+			method:         http.MethodGet,
+			path:           "/admin/permission-rules/coverage",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "loaded permission status",
+			method:         http.MethodGet,
+			path:           "/admin/permission-rules/status",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "list named permissions",
+			method:         http.MethodGet,
+			path:           "/admin/permissions",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "create named permission",
+			method:         http.MethodPost,
+			path:           "/admin/permissions",
+			body:           CreateNamedPermissionRequest{Name: "tickets:update"},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "create named permission with invalid name",
+			method:         http.MethodPost,
+			path:           "/admin/permissions",
+			body:           CreateNamedPermissionRequest{Name: "update tickets"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "delete missing named permission",
+			method:         http.MethodDelete,
+			path:           "/admin/permissions/99",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockRepository{
+				PermissionExistsFunc: func(ctx context.Context, method, pathPattern string, excludeID int64) (bool, error) {
+					return method == "GET" && pathPattern == "/users", nil
+				},
+			}
+			pm := NewPermissionManager(mockRepo)
+			if err := pm.SetRoutes(testRouter()); err != nil {
+				t.Fatalf("failed to set routes: %v", err)
+			}
+			handler := NewHandler(pm, NewService(mockRepo, pm))
+
+			r := chi.NewRouter()
+			handler.RegisterRoutes(r)
+
+			var body []byte
+			if s, ok := tt.body.(string); ok {
+				body = []byte(s)
+			} else if tt.body != nil {
+				body, _ = json.Marshal(tt.body)
+			}
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewReader(body))
+			req = req.WithContext(setUserRolesInContext(req.Context(), []string{"admin"}))
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
 // setUserRolesInContext is a helper function for testing
 // It uses auth.SetUserRolesInContext to set roles properly
 func setUserRolesInContext(ctx context.Context, roles []string) context.Context {
@@ -0,0 +1,106 @@
+package rbac
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// NotifyChannel is the PostgreSQL channel notified when permission rules, role assignments or
+// group roles change
+const NotifyChannel = "rbac_permissions_changed"
+
+const (
+	listenerMinReconnect  = 10 * time.Second
+	listenerMaxReconnect  = time.Minute
+	listenerReloadTimeout = 30 * time.Second
+)
+
+// Listener keeps the permissions of this instance in sync with the database: it reloads them
+// when a change is notified on NotifyChannel and, as a fallback for missed notifications,
+// every ReloadInterval
+type Listener struct {
+	permissionManager *PermissionManager
+	connString        string
+	config            *Config
+}
+
+// NewListener creates a listener connecting to the database with its own connection
+func NewListener(pm *PermissionManager, connString string, config *Config) *Listener {
+	return &Listener{permissionManager: pm, connString: connString, config: config}
+}
+
+// Start listens for notifications and reloads periodically in the background. The connection
+// is re-established after a failure; since notifications may have been missed in the meantime,
+// the permissions are reloaded then as well.
+func (l *Listener) Start() {
+	var notifications <-chan *pq.Notification
+	if l.config.Listen {
+		listener := pq.NewListener(l.connString, listenerMinReconnect, listenerMaxReconnect, logListenerEvent)
+		notifications = listener.Notify
+		go func() {
+			// Listen blocks until the first connection is established
+			if err := listener.Listen(NotifyChannel); err != nil {
+				log.Printf("[WARN] Failed to listen for permission changes: %v", err)
+			}
+		}()
+	}
+
+	var ticks <-chan time.Time
+	if l.config.ReloadInterval > 0 {
+		ticks = time.NewTicker(l.config.ReloadInterval).C
+	}
+
+	if notifications == nil && ticks == nil {
+		return
+	}
+
+	go func() {
+		for {
+			select {
+			case <-notifications:
+				// Reload once for a burst of notifications
+				for pending := true; pending; {
+					select {
+					case <-notifications:
+					default:
+						pending = false
+					}
+				}
+				l.reload("change notification")
+			case <-ticks:
+				l.reload("periodic reload")
+			}
+		}
+	}()
+}
+
+// reload loads the permissions and logs when their version changed
+func (l *Listener) reload(reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), listenerReloadTimeout)
+	defer cancel()
+
+	before := l.permissionManager.Version()
+	if err := l.permissionManager.LoadPermissions(ctx); err != nil {
+		log.Printf("[WARN] Failed to reload permissions (%s): %v", reason, err)
+		return
+	}
+	if after := l.permissionManager.Version(); after != before {
+		log.Printf("RBAC: permissions reloaded after %s, version %s", reason, after)
+	}
+}
+
+func logListenerEvent(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventConnected:
+		log.Printf("RBAC: listening for permission changes on %s", NotifyChannel)
+	case pq.ListenerEventDisconnected:
+		log.Printf("[WARN] RBAC: lost the connection for permission change notifications: %v", err)
+	case pq.ListenerEventReconnected:
+		log.Println("RBAC: reconnected for permission change notifications")
+	case pq.ListenerEventConnectionAttemptFailed:
+		log.Printf("[WARN] RBAC: failed to connect for permission change notifications: %v", err)
+	}
+}
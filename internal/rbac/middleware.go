@@ -4,8 +4,6 @@ import (
 	"net/http"
 	"slices"
 
-	"github.com/go-chi/chi/v5"
-
 	"kc-api/internal/auth"
 	"kc-api/internal/utils"
 )
@@ -13,11 +11,12 @@ import (
 // Middleware provides RBAC authorization middleware
 type Middleware struct {
 	permissionManager *PermissionManager
+	denyByDefault     bool
 }
 
 // NewMiddleware creates a new RBAC middleware
-func NewMiddleware(pm *PermissionManager) *Middleware {
-	return &Middleware{permissionManager: pm}
+func NewMiddleware(pm *PermissionManager, config *Config) *Middleware {
+	return &Middleware{permissionManager: pm, denyByDefault: config.DenyByDefault}
 }
 
 // Authorize is a Chi middleware that intercepts requests and checks permissions.
@@ -34,44 +33,35 @@ func (m *Middleware) Authorize(next http.Handler) http.Handler {
 			return
 		}
 
-		// 2. Path Matching: Use Chi RouteContext to get the registered route pattern
-		// This returns patterns like "/users/{id}" instead of "/users/123"
-		routeCtx := chi.RouteContext(r.Context())
-		routePattern := routeCtx.RoutePattern()
-
-		// If route pattern is empty, use the raw URL path
-		if routePattern == "" {
-			routePattern = r.URL.Path
-		}
+		// 2. Path Matching: Resolve the full registered route pattern, e.g. "/users/{id}" instead
+		// of "/users/123", the same way the routes permission rules are validated against are collected
+		routePattern := RoutePattern(r)
 
 		method := r.Method
 
-		// 3. Permission Check: Retrieve required roles from PermissionManager
-		requiredRoles, found := m.permissionManager.GetRequiredRoles(method, routePattern)
+		// 3. Permission Check: Expand the user's roles into permissions and check the rule
+		allowed, found := m.permissionManager.Authorize(method, routePattern, userRoles)
 
 		if !found {
+			// Deny by default: routes must be granted explicitly (see the coverage report)
+			if m.denyByDefault {
+				utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "Access denied: no permission rule for this route")
+				return
+			}
+
 			// Default policy: Allow requests to routes not defined in the permission manager.
-			// This assumes that routes not explicitly restricted are public.
-			// NOTE: To change this to a "deny by default" policy, return 403 here instead.
+			// This assumes that routes not explicitly restricted are open to every authenticated user.
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		// Check if user has at least one of the required roles
+		// Check if user has one of the required permissions or roles
 		if len(userRoles) == 0 {
 			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "Access denied: authentication required")
 			return
 		}
 
-		hasRequiredRole := false
-		for _, requiredRole := range requiredRoles {
-			if slices.Contains(userRoles, requiredRole) {
-				hasRequiredRole = true
-				break
-			}
-		}
-
-		if !hasRequiredRole {
+		if !allowed {
 			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "Access denied: insufficient permissions")
 			return
 		}
@@ -0,0 +1,159 @@
+package rbac
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Actions recorded in the change history of permission rules
+const (
+	PermissionChangeCreate = "create"
+	PermissionChangeUpdate = "update"
+	PermissionChangeDelete = "delete"
+)
+
+// PermissionRuleResponse represents the API response for a permission rule
+type PermissionRuleResponse struct {
+	ID                  int64           `json:"id" example:"1"`
+	Method              string          `json:"method" example:"GET"`
+	PathPattern         string          `json:"path_pattern" example:"/users/{id}"`
+	RequiredPermissions []string        `json:"required_permissions" example:"users:read"`
+	RequiredRoles       []string        `json:"required_roles" example:"admin,user"`
+	Description         json.RawMessage `json:"description,omitempty" swaggertype:"object"`
+	CreatedAt           time.Time       `json:"created_at"`
+	UpdatedAt           time.Time       `json:"updated_at"`
+}
+
+// PermissionRuleListResponse lists all permission rules
+type PermissionRuleListResponse struct {
+	Data []PermissionRuleResponse `json:"data"`
+}
+
+// CreatePermissionRuleRequest represents the request to create a permission rule. Rules should
+// require named permissions; required roles are supported for rules not migrated yet.
+type CreatePermissionRuleRequest struct {
+	Method              string           `json:"method" example:"GET"`
+	PathPattern         string           `json:"path_pattern" example:"/users/{id}"`
+	RequiredPermissions []string         `json:"required_permissions" example:"users:read"`
+	RequiredRoles       []string         `json:"required_roles,omitempty" example:"admin,user"`
+	Description         *json.RawMessage `json:"description,omitempty" swaggertype:"object"`
+}
+
+// UpdatePermissionRuleRequest represents the request to update a permission rule. Omitted fields
+// are kept; an empty list removes all required permissions or roles.
+type UpdatePermissionRuleRequest struct {
+	Method              *string          `json:"method,omitempty" example:"GET"`
+	PathPattern         *string          `json:"path_pattern,omitempty" example:"/users/{id}"`
+	RequiredPermissions []string         `json:"required_permissions,omitempty" example:"users:read"`
+	RequiredRoles       []string         `json:"required_roles,omitempty" example:"admin,user"`
+	Description         *json.RawMessage `json:"description,omitempty" swaggertype:"object"`
+}
+
+// PermissionRuleSnapshot is a permission rule as recorded in the change history
+type PermissionRuleSnapshot struct {
+	Method              string          `json:"method" example:"GET"`
+	PathPattern         string          `json:"path_pattern" example:"/users/{id}"`
+	RequiredPermissions []string        `json:"required_permissions,omitempty" example:"users:read"`
+	RequiredRoles       []string        `json:"required_roles" example:"admin,user"`
+	Description         json.RawMessage `json:"description,omitempty" swaggertype:"object"`
+}
+
+// PermissionChange is a change of a permission rule. Before is empty for creations and after
+// for deletions.
+type PermissionChange struct {
+	ID           int64                   `json:"id" example:"1"`
+	PermissionID int64                   `json:"permission_id" example:"12"`
+	Action       string                  `json:"action" example:"update" enums:"create,update,delete"`
+	Before       *PermissionRuleSnapshot `json:"before,omitempty"`
+	After        *PermissionRuleSnapshot `json:"after,omitempty"`
+	ChangedBy    string                  `json:"changed_by,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`
+	ChangedAt    time.Time               `json:"changed_at"`
+}
+
+// PermissionChangeListResponse wraps the change history with pagination
+type PermissionChangeListResponse struct {
+	Data       []PermissionChange `json:"data"`
+	Page       int                `json:"page" example:"1"`
+	Limit      int                `json:"limit" example:"20"`
+	TotalCount int                `json:"total_count" example:"100"`
+	TotalPages int                `json:"total_pages" example:"5"`
+}
+
+// RouteListResponse lists the routes registered on the router
+type RouteListResponse struct {
+	Data []Route `json:"data"`
+}
+
+// PermissionStatus reports the permission rules loaded by this instance
+type PermissionStatus struct {
+	Version   string     `json:"version" example:"9f86d081884c7d65"`
+	RuleCount int        `json:"rule_count" example:"95"`
+	LoadedAt  *time.Time `json:"loaded_at,omitempty"`
+	Reloads   int64      `json:"reloads" example:"12"`
+	LastError string     `json:"last_error,omitempty"` // error of the last reload, if it failed
+}
+
+// NamedPermissionResponse represents the API response for a named permission
+type NamedPermissionResponse struct {
+	ID          int64           `json:"id" example:"1"`
+	Name        string          `json:"name" example:"tickets:update"`
+	Description json.RawMessage `json:"description,omitempty" swaggertype:"object"`
+	Roles       []string        `json:"roles" example:"agent,admin"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// NamedPermissionListResponse lists all named permissions
+type NamedPermissionListResponse struct {
+	Data []NamedPermissionResponse `json:"data"`
+}
+
+// CreateNamedPermissionRequest represents the request to create a named permission
+type CreateNamedPermissionRequest struct {
+	Name        string           `json:"name" example:"tickets:update"`
+	Description *json.RawMessage `json:"description,omitempty" swaggertype:"object"`
+	Roles       []string         `json:"roles" example:"agent,admin"`
+}
+
+// UpdateNamedPermissionRequest represents the request to update a named permission. The name
+// cannot be changed, since rules refer to it.
+type UpdateNamedPermissionRequest struct {
+	Description *json.RawMessage `json:"description,omitempty" swaggertype:"object"`
+	Roles       []string         `json:"roles,omitempty" example:"agent,admin"`
+}
+
+// ToResponse converts an APIPermission to PermissionRuleResponse
+func (p *APIPermission) ToResponse() PermissionRuleResponse {
+	return PermissionRuleResponse{
+		ID:                  p.ID,
+		Method:              p.Method,
+		PathPattern:         p.PathPattern,
+		RequiredPermissions: nonNil(p.RequiredPermissions),
+		RequiredRoles:       nonNil(p.RequiredRoles),
+		Description:         p.Description,
+		CreatedAt:           p.CreatedAt,
+		UpdatedAt:           p.UpdatedAt,
+	}
+}
+
+func (p *APIPermission) snapshot() PermissionRuleSnapshot {
+	return PermissionRuleSnapshot{
+		Method:              p.Method,
+		PathPattern:         p.PathPattern,
+		RequiredPermissions: p.RequiredPermissions,
+		RequiredRoles:       p.RequiredRoles,
+		Description:         p.Description,
+	}
+}
+
+// ToResponse converts a NamedPermission to NamedPermissionResponse
+func (p *NamedPermission) ToResponse() NamedPermissionResponse {
+	return NamedPermissionResponse{
+		ID:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		Roles:       nonNil(p.Roles),
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+}
@@ -2,22 +2,56 @@ package rbac
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
 )
 
-// PermissionManager holds permission rules in memory to avoid DB lookups on every request.
-// It uses a map structure: map[method]map[path_pattern][]allowed_roles
+// maxCachedRoleSets bounds the cache of effective permissions; it is cleared when full
+const maxCachedRoleSets = 1024
+
+// Requirement is what a permission rule requires: one of its named permissions or, for rules
+// not migrated to named permissions yet, one of its roles
+type Requirement struct {
+	Permissions []string
+	Roles       []string
+}
+
+// PermissionManager holds permission rules and the named permissions of roles in memory to
+// avoid DB lookups on every request.
+// It uses a map structure: map[method]map[path_pattern]Requirement
 type PermissionManager struct {
 	mu          sync.RWMutex
-	permissions map[string]map[string][]string // method -> path_pattern -> roles
+	permissions map[string]map[string]Requirement // method -> path_pattern -> requirement
 	repository  Repository
+
+	rolePermissions map[string][]string // role -> named permissions
+	allPermissions  []string            // all named permissions, granted by full_access
+	effective       map[string][]string // role set -> effective permissions, cleared on reload
+	generation      uint64              // incremented on reload
+
+	routes map[string]map[string]bool // path_pattern -> method -> registered; nil until SetRoutes
+
+	// Load state, reported by Status
+	version   string // hash of the loaded rules, equal on instances with the same rules
+	ruleCount int
+	loadedAt  time.Time
+	reloads   int64
+	lastError string
 }
 
 // NewPermissionManager creates a new PermissionManager with the given repository
 func NewPermissionManager(repo Repository) *PermissionManager {
 	return &PermissionManager{
-		permissions: make(map[string]map[string][]string),
-		repository:  repo,
+		permissions:     make(map[string]map[string]Requirement),
+		rolePermissions: make(map[string][]string),
+		effective:       make(map[string][]string),
+		repository:      repo,
 	}
 }
 
@@ -26,52 +60,282 @@ func NewPermissionManager(repo Repository) *PermissionManager {
 func (pm *PermissionManager) LoadPermissions(ctx context.Context) error {
 	// Fetch all permissions from database
 	dbPermissions, err := pm.repository.GetAllPermissions(ctx)
-	if err != nil {
-		return err
+	if err == nil {
+		var namedPermissions []NamedPermission
+		if namedPermissions, err = pm.repository.ListNamedPermissions(ctx); err == nil {
+			pm.replace(dbPermissions, namedPermissions)
+			return nil
+		}
 	}
 
+	pm.mu.Lock()
+	pm.lastError = err.Error()
+	pm.mu.Unlock()
+	return err
+}
+
+// replace atomically replaces the rules and the named permissions of roles
+func (pm *PermissionManager) replace(dbPermissions []APIPermission, namedPermissions []NamedPermission) {
 	// Build new permissions map
-	newPermissions := make(map[string]map[string][]string)
+	newPermissions := make(map[string]map[string]Requirement)
 
 	for _, perm := range dbPermissions {
 		method := perm.Method
 		pathPattern := perm.PathPattern
-		roles := perm.RequiredRoles
 
 		if _, exists := newPermissions[method]; !exists {
-			newPermissions[method] = make(map[string][]string)
+			newPermissions[method] = make(map[string]Requirement)
 		}
 
-		newPermissions[method][pathPattern] = roles
+		newPermissions[method][pathPattern] = Requirement{Permissions: perm.RequiredPermissions, Roles: perm.RequiredRoles}
+	}
+
+	// Build the named permissions of each role
+	rolePermissions := make(map[string][]string)
+	allPermissions := make([]string, 0, len(namedPermissions))
+	for _, named := range namedPermissions {
+		allPermissions = append(allPermissions, named.Name)
+		for _, role := range named.Roles {
+			rolePermissions[role] = append(rolePermissions[role], named.Name)
+		}
 	}
+	slices.Sort(allPermissions)
+
+	version := permissionVersion(dbPermissions, rolePermissions)
 
 	// Atomically replace the permissions map
 	pm.mu.Lock()
 	pm.permissions = newPermissions
+	pm.rolePermissions = rolePermissions
+	pm.allPermissions = allPermissions
+	pm.effective = make(map[string][]string)
+	pm.generation++
+	pm.version = version
+	pm.ruleCount = len(dbPermissions)
+	pm.loadedAt = time.Now()
+	pm.reloads++
+	pm.lastError = ""
 	pm.mu.Unlock()
+}
 
-	return nil
+// Version returns the version of the loaded permission rules, empty before the first load
+func (pm *PermissionManager) Version() string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.version
 }
 
-// GetRequiredRoles retrieves the required roles for a given HTTP method and path pattern.
-// Returns the roles and a boolean indicating whether the permission rule was found.
-func (pm *PermissionManager) GetRequiredRoles(method, path string) ([]string, bool) {
+// Status reports which permission rules are loaded and when they were loaded
+func (pm *PermissionManager) Status() *PermissionStatus {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	status := &PermissionStatus{
+		Version:   pm.version,
+		RuleCount: pm.ruleCount,
+		Reloads:   pm.reloads,
+		LastError: pm.lastError,
+	}
+	if !pm.loadedAt.IsZero() {
+		loadedAt := pm.loadedAt
+		status.LoadedAt = &loadedAt
+	}
+	return status
+}
+
+// permissionVersion hashes the method, path pattern, permissions and roles of the rules and the
+// permissions of roles, independent of their order, so that instances with the same rules report
+// the same version
+func permissionVersion(perms []APIPermission, rolePermissions map[string][]string) string {
+	lines := make([]string, 0, len(perms)+len(rolePermissions))
+	for _, perm := range perms {
+		lines = append(lines, "rule "+perm.Method+" "+perm.PathPattern+" "+sortedList(perm.RequiredPermissions)+" "+sortedList(perm.RequiredRoles))
+	}
+	for role, permissions := range rolePermissions {
+		lines = append(lines, "role "+role+" "+sortedList(permissions))
+	}
+	slices.Sort(lines)
+
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// GetRequirement retrieves the requirement of the rule for a given HTTP method and path pattern.
+// Returns the requirement and a boolean indicating whether the permission rule was found.
+func (pm *PermissionManager) GetRequirement(method, path string) (Requirement, bool) {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
 
 	// First, try exact method match
 	if pathMap, methodExists := pm.permissions[method]; methodExists {
-		if roles, pathExists := pathMap[path]; pathExists {
-			return roles, true
+		if requirement, pathExists := pathMap[path]; pathExists {
+			return requirement, true
 		}
 	}
 
 	// Second, try wildcard method "*"
 	if pathMap, wildcardExists := pm.permissions["*"]; wildcardExists {
-		if roles, pathExists := pathMap[path]; pathExists {
-			return roles, true
+		if requirement, pathExists := pathMap[path]; pathExists {
+			return requirement, true
+		}
+	}
+
+	return Requirement{}, false
+}
+
+// Authorize checks whether a user with the given roles may call a method and path pattern: the
+// user has one of the permissions the rule requires, through any of the roles, or one of the roles
+// the rule requires. found is false if there is no rule for the method and path pattern.
+func (pm *PermissionManager) Authorize(method, path string, roles []string) (allowed, found bool) {
+	requirement, found := pm.GetRequirement(method, path)
+	if !found {
+		return false, false
+	}
+
+	for _, role := range requirement.Roles {
+		if slices.Contains(roles, role) {
+			return true, true
+		}
+	}
+
+	if len(requirement.Permissions) > 0 {
+		effective := pm.EffectivePermissions(roles)
+		for _, permission := range requirement.Permissions {
+			if _, ok := slices.BinarySearch(effective, permission); ok {
+				return true, true
+			}
 		}
 	}
 
-	return nil, false
+	return false, true
+}
+
+// EffectivePermissions returns the sorted named permissions granted by a set of roles. The roles
+// of a user already include the roles of their groups. full_access grants every permission.
+// Results are cached per role set until the next reload and must not be modified.
+func (pm *PermissionManager) EffectivePermissions(roles []string) []string {
+	key := roleSetKey(roles)
+
+	pm.mu.RLock()
+	generation := pm.generation
+	effective, cached := pm.effective[key]
+	if !cached {
+		effective = pm.expandRoles(roles)
+	}
+	pm.mu.RUnlock()
+
+	if !cached {
+		pm.mu.Lock()
+		// A reload in the meantime replaced the cache; the result may be outdated then
+		if pm.generation == generation {
+			if len(pm.effective) >= maxCachedRoleSets {
+				clear(pm.effective)
+			}
+			pm.effective[key] = effective
+		}
+		pm.mu.Unlock()
+	}
+
+	return effective
+}
+
+// expandRoles collects the named permissions of roles. The caller must hold the read lock.
+func (pm *PermissionManager) expandRoles(roles []string) []string {
+	if slices.Contains(roles, "full_access") {
+		return slices.Clone(pm.allPermissions)
+	}
+
+	effective := []string{}
+	for _, role := range roles {
+		effective = append(effective, pm.rolePermissions[role]...)
+	}
+	slices.Sort(effective)
+	return slices.Compact(effective)
+}
+
+// roleSetKey identifies a set of roles regardless of order and duplicates
+func roleSetKey(roles []string) string {
+	sorted := slices.Clone(roles)
+	slices.Sort(sorted)
+	return strings.Join(slices.Compact(sorted), "\x00")
+}
+
+// SetRoutes records the routes registered on the router, which permission rules are validated
+// against. It is called once all routes are registered.
+func (pm *PermissionManager) SetRoutes(routes chi.Routes) error {
+	collected, err := CollectRoutes(routes)
+	if err != nil {
+		return err
+	}
+
+	table := make(map[string]map[string]bool)
+	for _, route := range collected {
+		if _, exists := table[route.Pattern]; !exists {
+			table[route.Pattern] = make(map[string]bool)
+		}
+		table[route.Pattern][route.Method] = true
+	}
+
+	pm.mu.Lock()
+	pm.routes = table
+	pm.mu.Unlock()
+
+	return nil
+}
+
+// HasRoute reports whether a route with the method and path pattern is registered. The method "*"
+// matches a route with any method. Without recorded routes, every route is reported as registered.
+func (pm *PermissionManager) HasRoute(method, path string) bool {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	if pm.routes == nil {
+		return true
+	}
+	methods, exists := pm.routes[path]
+	if !exists {
+		return false
+	}
+	return method == "*" || methods[method]
+}
+
+// Routes returns the registered routes, sorted by pattern and method
+func (pm *PermissionManager) Routes() []Route {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	var routes []Route
+	for pattern, methods := range pm.routes {
+		for method := range methods {
+			routes = append(routes, Route{Method: method, Pattern: pattern})
+		}
+	}
+	sortRoutes(routes)
+	return routes
+}
+
+// Coverage compares the permission rules in memory with the registered routes. It returns nil
+// until the routes are recorded with SetRoutes.
+func (pm *PermissionManager) Coverage() *CoverageReport {
+	routes := pm.Routes()
+	if routes == nil {
+		return nil
+	}
+
+	pm.mu.RLock()
+	var rules []Route
+	for method, pathMap := range pm.permissions {
+		for pattern := range pathMap {
+			rules = append(rules, Route{Method: method, Pattern: pattern})
+		}
+	}
+	pm.mu.RUnlock()
+
+	return BuildCoverageReport(routes, rules)
+}
+
+func sortedList(values []string) string {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	return strings.Join(sorted, ",")
 }
@@ -3,6 +3,8 @@ package rbac
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"time"
 
 	"github.com/lib/pq"
 )
@@ -13,11 +15,51 @@ type APIPermission struct {
 	Method        string
 	PathPattern   string
 	RequiredRoles []string
+	Description   json.RawMessage
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+
+	// RequiredPermissions are named permissions, one of which grants access. Rules that still
+	// require roles grant access to those roles as well.
+	RequiredPermissions []string
+}
+
+// NamedPermission represents a named permission, e.g. "tickets:update", and the roles it is
+// assigned to
+type NamedPermission struct {
+	ID          int64
+	Name        string
+	Description json.RawMessage
+	Roles       []string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
 }
 
 // Repository defines the interface for RBAC data access
 type Repository interface {
 	GetAllPermissions(ctx context.Context) ([]APIPermission, error)
+
+	// Permission rule management. Every change is recorded in the change history.
+	GetPermission(ctx context.Context, id int64) (*APIPermission, error)
+	PermissionExists(ctx context.Context, method, pathPattern string, excludeID int64) (bool, error)
+	CreatePermission(ctx context.Context, perm *APIPermission, actorID string) error
+	UpdatePermission(ctx context.Context, before, after *APIPermission, actorID string) error
+	DeletePermission(ctx context.Context, perm *APIPermission, actorID string) error
+	ListPermissionChanges(ctx context.Context, permissionID int64, limit, offset int) ([]PermissionChange, int, error)
+	FindMissingRoles(ctx context.Context, names []string) ([]string, error)
+	FindMissingPermissions(ctx context.Context, names []string) ([]string, error)
+
+	// Named permissions and their role assignments
+	ListNamedPermissions(ctx context.Context) ([]NamedPermission, error)
+	GetNamedPermission(ctx context.Context, id int64) (*NamedPermission, error)
+	NamedPermissionExists(ctx context.Context, name string) (bool, error)
+	CreateNamedPermission(ctx context.Context, perm *NamedPermission) error
+	UpdateNamedPermission(ctx context.Context, perm *NamedPermission) error
+	DeleteNamedPermission(ctx context.Context, id int64) error
+	ListRulesRequiringPermission(ctx context.Context, name string) ([]APIPermission, error)
+
+	// NotifyPermissionsChanged asks every instance listening on NotifyChannel to reload
+	NotifyPermissionsChanged(ctx context.Context) error
 }
 
 // repository implements the Repository interface
@@ -33,7 +75,7 @@ func NewRepository(db *sql.DB) Repository {
 // GetAllPermissions fetches all API permissions from the database
 func (r *repository) GetAllPermissions(ctx context.Context) ([]APIPermission, error) {
 	query := `
-		SELECT id, method, path_pattern, required_roles
+		SELECT id, method, path_pattern, required_roles, required_permissions, description, created_at, updated_at
 		FROM managements.api_permissions
 		ORDER BY id
 	`
@@ -47,7 +89,7 @@ func (r *repository) GetAllPermissions(ctx context.Context) ([]APIPermission, er
 	var permissions []APIPermission
 	for rows.Next() {
 		var perm APIPermission
-		err := rows.Scan(&perm.ID, &perm.Method, &perm.PathPattern, pq.Array(&perm.RequiredRoles))
+		err := rows.Scan(&perm.ID, &perm.Method, &perm.PathPattern, pq.Array(&perm.RequiredRoles), pq.Array(&perm.RequiredPermissions), &perm.Description, &perm.CreatedAt, &perm.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -60,3 +102,418 @@ func (r *repository) GetAllPermissions(ctx context.Context) ([]APIPermission, er
 
 	return permissions, nil
 }
+
+// GetPermission retrieves a permission rule by ID
+func (r *repository) GetPermission(ctx context.Context, id int64) (*APIPermission, error) {
+	query := `
+		SELECT id, method, path_pattern, required_roles, required_permissions, description, created_at, updated_at
+		FROM managements.api_permissions
+		WHERE id = $1`
+
+	perm := &APIPermission{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&perm.ID,
+		&perm.Method,
+		&perm.PathPattern,
+		pq.Array(&perm.RequiredRoles),
+		pq.Array(&perm.RequiredPermissions),
+		&perm.Description,
+		&perm.CreatedAt,
+		&perm.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return perm, nil
+}
+
+// PermissionExists checks whether another rule than excludeID has the method and path pattern
+func (r *repository) PermissionExists(ctx context.Context, method, pathPattern string, excludeID int64) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM managements.api_permissions WHERE method = $1 AND path_pattern = $2 AND id != $3)`
+
+	var exists bool
+	err := r.db.QueryRowContext(ctx, query, method, pathPattern, excludeID).Scan(&exists)
+	return exists, err
+}
+
+// CreatePermission inserts a permission rule and records its creation
+func (r *repository) CreatePermission(ctx context.Context, perm *APIPermission, actorID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO managements.api_permissions (method, path_pattern, required_roles, required_permissions, description)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`
+
+	err = tx.QueryRowContext(ctx, query,
+		perm.Method,
+		perm.PathPattern,
+		pq.Array(nonNil(perm.RequiredRoles)),
+		pq.Array(nonNil(perm.RequiredPermissions)),
+		perm.Description,
+	).Scan(&perm.ID, &perm.CreatedAt, &perm.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := recordPermissionChange(ctx, tx, perm.ID, PermissionChangeCreate, nil, perm, actorID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdatePermission replaces a permission rule and records the change
+func (r *repository) UpdatePermission(ctx context.Context, before, after *APIPermission, actorID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE managements.api_permissions
+		SET method = $1, path_pattern = $2, required_roles = $3, required_permissions = $4, description = $5, updated_at = NOW()
+		WHERE id = $6
+		RETURNING updated_at`
+
+	err = tx.QueryRowContext(ctx, query,
+		after.Method,
+		after.PathPattern,
+		pq.Array(nonNil(after.RequiredRoles)),
+		pq.Array(nonNil(after.RequiredPermissions)),
+		after.Description,
+		after.ID,
+	).Scan(&after.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := recordPermissionChange(ctx, tx, after.ID, PermissionChangeUpdate, before, after, actorID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeletePermission deletes a permission rule and records its deletion. The history of the rule is kept.
+func (r *repository) DeletePermission(ctx context.Context, perm *APIPermission, actorID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM managements.api_permissions WHERE id = $1`, perm.ID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := recordPermissionChange(ctx, tx, perm.ID, PermissionChangeDelete, perm, nil, actorID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// recordPermissionChange adds a change of a permission rule to the history, with the rule before
+// and after the change (nil for creations and deletions)
+func recordPermissionChange(ctx context.Context, tx *sql.Tx, permissionID int64, action string, before, after *APIPermission, actorID string) error {
+	beforeJSON, err := marshalPermissionSnapshot(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := marshalPermissionSnapshot(after)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO managements.api_permission_history (permission_id, action, before, after, changed_by)
+		VALUES ($1, $2, $3, $4, (SELECT id FROM organizations.users WHERE public_id::text = $5))`
+
+	_, err = tx.ExecContext(ctx, query, permissionID, action, beforeJSON, afterJSON, actorID)
+	return err
+}
+
+func marshalPermissionSnapshot(perm *APIPermission) ([]byte, error) {
+	if perm == nil {
+		return nil, nil
+	}
+	return json.Marshal(perm.snapshot())
+}
+
+// ListPermissionChanges lists changes of permission rules, newest first. permissionID 0 lists
+// the changes of all rules.
+func (r *repository) ListPermissionChanges(ctx context.Context, permissionID int64, limit, offset int) ([]PermissionChange, int, error) {
+	var totalCount int
+	countQuery := `SELECT COUNT(*) FROM managements.api_permission_history WHERE $1 = 0 OR permission_id = $1`
+	if err := r.db.QueryRowContext(ctx, countQuery, permissionID).Scan(&totalCount); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT h.id, h.permission_id, h.action, h.before, h.after, COALESCE(u.public_id::text, ''), h.changed_at
+		FROM managements.api_permission_history h
+		LEFT JOIN organizations.users u ON u.id = h.changed_by
+		WHERE $1 = 0 OR h.permission_id = $1
+		ORDER BY h.changed_at DESC, h.id DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, permissionID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var changes []PermissionChange
+	for rows.Next() {
+		var change PermissionChange
+		var before, after []byte
+		if err := rows.Scan(&change.ID, &change.PermissionID, &change.Action, &before, &after, &change.ChangedBy, &change.ChangedAt); err != nil {
+			return nil, 0, err
+		}
+		if before != nil {
+			change.Before = &PermissionRuleSnapshot{}
+			if err := json.Unmarshal(before, change.Before); err != nil {
+				return nil, 0, err
+			}
+		}
+		if after != nil {
+			change.After = &PermissionRuleSnapshot{}
+			if err := json.Unmarshal(after, change.After); err != nil {
+				return nil, 0, err
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return changes, totalCount, nil
+}
+
+// FindMissingRoles returns the names that are not roles
+func (r *repository) FindMissingRoles(ctx context.Context, names []string) ([]string, error) {
+	query := `
+		SELECT wanted.name FROM unnest($1::text[]) AS wanted(name)
+		WHERE NOT EXISTS (SELECT 1 FROM organizations.roles r WHERE r.name = wanted.name)
+		ORDER BY wanted.name`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(names))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var missing []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		missing = append(missing, name)
+	}
+	return missing, rows.Err()
+}
+
+// NotifyPermissionsChanged sends a notification on NotifyChannel, like the triggers on the
+// permission and role tables do
+func (r *repository) NotifyPermissionsChanged(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `SELECT pg_notify($1, '')`, NotifyChannel)
+	return err
+}
+
+// FindMissingPermissions returns the names that are not named permissions
+func (r *repository) FindMissingPermissions(ctx context.Context, names []string) ([]string, error) {
+	query := `
+		SELECT wanted.name FROM unnest($1::text[]) AS wanted(name)
+		WHERE NOT EXISTS (SELECT 1 FROM managements.permissions p WHERE p.name = wanted.name)
+		ORDER BY wanted.name`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(names))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var missing []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		missing = append(missing, name)
+	}
+	return missing, rows.Err()
+}
+
+// namedPermissionQuery selects named permissions with the names of the roles they are assigned to
+const namedPermissionQuery = `
+	SELECT p.id, p.name, p.description, p.created_at, p.updated_at,
+		COALESCE(ARRAY(
+			SELECT r.name FROM managements.role_permissions rp
+			INNER JOIN organizations.roles r ON r.id = rp.role_id
+			WHERE rp.permission_id = p.id
+			ORDER BY r.name
+		), '{}')
+	FROM managements.permissions p`
+
+func scanNamedPermission(row interface{ Scan(...any) error }) (*NamedPermission, error) {
+	perm := &NamedPermission{}
+	err := row.Scan(&perm.ID, &perm.Name, &perm.Description, &perm.CreatedAt, &perm.UpdatedAt, pq.Array(&perm.Roles))
+	if err != nil {
+		return nil, err
+	}
+	return perm, nil
+}
+
+// ListNamedPermissions lists all named permissions with their roles, ordered by name
+func (r *repository) ListNamedPermissions(ctx context.Context) ([]NamedPermission, error) {
+	rows, err := r.db.QueryContext(ctx, namedPermissionQuery+` ORDER BY p.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var perms []NamedPermission
+	for rows.Next() {
+		perm, err := scanNamedPermission(rows)
+		if err != nil {
+			return nil, err
+		}
+		perms = append(perms, *perm)
+	}
+	return perms, rows.Err()
+}
+
+// GetNamedPermission retrieves a named permission by ID
+func (r *repository) GetNamedPermission(ctx context.Context, id int64) (*NamedPermission, error) {
+	return scanNamedPermission(r.db.QueryRowContext(ctx, namedPermissionQuery+` WHERE p.id = $1`, id))
+}
+
+// NamedPermissionExists checks whether a named permission with the name exists
+func (r *repository) NamedPermissionExists(ctx context.Context, name string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM managements.permissions WHERE name = $1)`, name).Scan(&exists)
+	return exists, err
+}
+
+// CreateNamedPermission inserts a named permission and assigns it to its roles
+func (r *repository) CreateNamedPermission(ctx context.Context, perm *NamedPermission) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO managements.permissions (name, description)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at`
+
+	if err := tx.QueryRowContext(ctx, query, perm.Name, perm.Description).Scan(&perm.ID, &perm.CreatedAt, &perm.UpdatedAt); err != nil {
+		return err
+	}
+
+	if err := assignPermissionRoles(ctx, tx, perm.ID, perm.Roles); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateNamedPermission replaces the description and the roles of a named permission
+func (r *repository) UpdateNamedPermission(ctx context.Context, perm *NamedPermission) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE managements.permissions
+		SET description = $1, updated_at = NOW()
+		WHERE id = $2
+		RETURNING updated_at`
+
+	if err := tx.QueryRowContext(ctx, query, perm.Description, perm.ID).Scan(&perm.UpdatedAt); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM managements.role_permissions WHERE permission_id = $1`, perm.ID); err != nil {
+		return err
+	}
+	if err := assignPermissionRoles(ctx, tx, perm.ID, perm.Roles); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// assignPermissionRoles assigns a named permission to the roles with the given names
+func assignPermissionRoles(ctx context.Context, tx *sql.Tx, permissionID int64, roles []string) error {
+	query := `
+		INSERT INTO managements.role_permissions (role_id, permission_id)
+		SELECT r.id, $1 FROM organizations.roles r WHERE r.name = ANY($2)`
+
+	_, err := tx.ExecContext(ctx, query, permissionID, pq.Array(roles))
+	return err
+}
+
+// DeleteNamedPermission deletes a named permission and its role assignments
+func (r *repository) DeleteNamedPermission(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM managements.permissions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListRulesRequiringPermission lists the permission rules that require a named permission
+func (r *repository) ListRulesRequiringPermission(ctx context.Context, name string) ([]APIPermission, error) {
+	query := `
+		SELECT id, method, path_pattern
+		FROM managements.api_permissions
+		WHERE $1 = ANY(required_permissions)
+		ORDER BY path_pattern, method`
+
+	rows, err := r.db.QueryContext(ctx, query, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []APIPermission
+	for rows.Next() {
+		var rule APIPermission
+		if err := rows.Scan(&rule.ID, &rule.Method, &rule.PathPattern); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// nonNil returns an empty slice for nil, so that NOT NULL array columns get '{}'
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,77 @@
+package rbac
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Route is a method and pattern registered on the router
+type Route struct {
+	Method  string `json:"method" example:"GET"`
+	Pattern string `json:"path_pattern" example:"/users/{id}"`
+}
+
+// CollectRoutes walks a router and returns its routes sorted by pattern and method. Patterns are
+// written the way RoutePattern resolves them for the middleware, e.g. "/roles" for the "/" route
+// of a sub-router mounted at "/roles".
+func CollectRoutes(routes chi.Routes) ([]Route, error) {
+	var collected []Route
+	err := chi.Walk(routes, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		collected = append(collected, Route{Method: method, Pattern: normalizePattern(route)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortRoutes(collected)
+	return collected, nil
+}
+
+// RoutePattern returns the pattern of the route a request resolves to, written the way CollectRoutes
+// reports it. Middleware registered on a group only sees the pattern matched so far, e.g.
+// "/users/*" for a route of a sub-router mounted at "/users", so the request is matched again
+// against the whole routing tree on a fresh route context.
+func RoutePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return r.URL.Path
+	}
+
+	path := r.URL.RawPath
+	if path == "" {
+		path = r.URL.Path
+	}
+	if rctx.Routes != nil {
+		if pattern := rctx.Routes.Find(chi.NewRouteContext(), r.Method, path); pattern != "" {
+			return normalizePattern(pattern)
+		}
+	}
+
+	// No route matches the method and path: fall back to the pattern matched so far
+	if pattern := rctx.RoutePattern(); pattern != "" {
+		return normalizePattern(pattern)
+	}
+	return r.URL.Path
+}
+
+func sortRoutes(routes []Route) {
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Pattern != routes[j].Pattern {
+			return routes[i].Pattern < routes[j].Pattern
+		}
+		return routes[i].Method < routes[j].Method
+	})
+}
+
+// normalizePattern removes the trailing slash chi.Walk reports for the root of sub-routers
+func normalizePattern(pattern string) string {
+	if pattern != "/" {
+		pattern = strings.TrimSuffix(pattern, "//")
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	return pattern
+}
@@ -0,0 +1,463 @@
+package rbac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrRuleNotFound   = errors.New("permission rule not found")
+	ErrRuleExists     = errors.New("a permission rule for this method and path pattern already exists")
+	ErrInvalidMethod  = errors.New("method must be GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS or *")
+	ErrInvalidPattern = errors.New("path pattern must start with /")
+	ErrUnknownRoute   = errors.New("path pattern does not match a registered route")
+	ErrNoRequirement  = errors.New("at least one required permission or role is needed")
+	ErrUnknownRole    = errors.New("unknown role")
+	ErrRoutesUnknown  = errors.New("routes have not been recorded yet")
+
+	ErrPermissionNotFound    = errors.New("permission not found")
+	ErrPermissionExists      = errors.New("a permission with this name already exists")
+	ErrInvalidPermissionName = errors.New("permission name must be lowercase resource:action, e.g. tickets:update")
+	ErrUnknownPermission     = errors.New("unknown permission")
+	ErrPermissionInUse       = errors.New("permission is required by permission rules")
+)
+
+// permissionNamePattern matches names of permissions such as "tickets:update" or "admin:auth:unlock"
+var permissionNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*(:[a-z][a-z0-9_-]*)+$`)
+
+// ruleMethods are the methods a permission rule can apply to; "*" applies to all methods
+var ruleMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS", "*"}
+
+// Service defines the interface for permission rule management
+type Service interface {
+	ListRules(ctx context.Context) (*PermissionRuleListResponse, error)
+	GetRule(ctx context.Context, id int64) (*PermissionRuleResponse, error)
+	CreateRule(ctx context.Context, actorID string, req *CreatePermissionRuleRequest) (*PermissionRuleResponse, error)
+	UpdateRule(ctx context.Context, actorID string, id int64, req *UpdatePermissionRuleRequest) (*PermissionRuleResponse, error)
+	DeleteRule(ctx context.Context, actorID string, id int64) error
+	ListRuleChanges(ctx context.Context, permissionID int64, page, limit int) (*PermissionChangeListResponse, error)
+	ListRoutes() *RouteListResponse
+	Coverage() (*CoverageReport, error)
+	RefreshPermissions(ctx context.Context) error
+	Status() *PermissionStatus
+
+	// Named permissions
+	ListPermissions(ctx context.Context) (*NamedPermissionListResponse, error)
+	GetPermission(ctx context.Context, id int64) (*NamedPermissionResponse, error)
+	CreatePermission(ctx context.Context, actorID string, req *CreateNamedPermissionRequest) (*NamedPermissionResponse, error)
+	UpdatePermission(ctx context.Context, actorID string, id int64, req *UpdateNamedPermissionRequest) (*NamedPermissionResponse, error)
+	DeletePermission(ctx context.Context, actorID string, id int64) error
+}
+
+type service struct {
+	repo              Repository
+	permissionManager *PermissionManager
+}
+
+// NewService creates a new permission rule service. Changes are applied to the permission manager
+// as soon as they are saved.
+func NewService(repo Repository, pm *PermissionManager) Service {
+	return &service{repo: repo, permissionManager: pm}
+}
+
+// ListRules lists all permission rules
+func (s *service) ListRules(ctx context.Context) (*PermissionRuleListResponse, error) {
+	perms, err := s.repo.GetAllPermissions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list permission rules: %w", err)
+	}
+
+	responses := make([]PermissionRuleResponse, 0, len(perms))
+	for _, perm := range perms {
+		responses = append(responses, perm.ToResponse())
+	}
+	return &PermissionRuleListResponse{Data: responses}, nil
+}
+
+// GetRule retrieves a permission rule by ID
+func (s *service) GetRule(ctx context.Context, id int64) (*PermissionRuleResponse, error) {
+	perm, err := s.getRule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	response := perm.ToResponse()
+	return &response, nil
+}
+
+// CreateRule creates a permission rule for a registered route
+func (s *service) CreateRule(ctx context.Context, actorID string, req *CreatePermissionRuleRequest) (*PermissionRuleResponse, error) {
+	perm := &APIPermission{
+		Method:              req.Method,
+		PathPattern:         req.PathPattern,
+		RequiredPermissions: req.RequiredPermissions,
+		RequiredRoles:       req.RequiredRoles,
+	}
+	if req.Description != nil {
+		perm.Description = *req.Description
+	}
+
+	if err := s.validateRule(ctx, perm); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreatePermission(ctx, perm, actorID); err != nil {
+		return nil, fmt.Errorf("failed to create permission rule: %w", err)
+	}
+	log.Printf("User %s created permission rule %d (%s %s)", actorID, perm.ID, perm.Method, perm.PathPattern)
+	s.reload(ctx)
+
+	response := perm.ToResponse()
+	return &response, nil
+}
+
+// UpdateRule changes the given fields of a permission rule
+func (s *service) UpdateRule(ctx context.Context, actorID string, id int64, req *UpdatePermissionRuleRequest) (*PermissionRuleResponse, error) {
+	before, err := s.getRule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	after := *before
+	if req.Method != nil {
+		after.Method = *req.Method
+	}
+	if req.PathPattern != nil {
+		after.PathPattern = *req.PathPattern
+	}
+	if req.RequiredPermissions != nil {
+		after.RequiredPermissions = req.RequiredPermissions
+	}
+	if req.RequiredRoles != nil {
+		after.RequiredRoles = req.RequiredRoles
+	}
+	if req.Description != nil {
+		after.Description = *req.Description
+	}
+
+	if err := s.validateRule(ctx, &after); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdatePermission(ctx, before, &after, actorID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRuleNotFound
+		}
+		return nil, fmt.Errorf("failed to update permission rule: %w", err)
+	}
+	log.Printf("User %s updated permission rule %d (%s %s)", actorID, after.ID, after.Method, after.PathPattern)
+	s.reload(ctx)
+
+	response := after.ToResponse()
+	return &response, nil
+}
+
+// DeleteRule deletes a permission rule. Its change history is kept.
+func (s *service) DeleteRule(ctx context.Context, actorID string, id int64) error {
+	perm, err := s.getRule(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeletePermission(ctx, perm, actorID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRuleNotFound
+		}
+		return fmt.Errorf("failed to delete permission rule: %w", err)
+	}
+	log.Printf("User %s deleted permission rule %d (%s %s)", actorID, perm.ID, perm.Method, perm.PathPattern)
+	s.reload(ctx)
+
+	return nil
+}
+
+// ListRuleChanges lists the change history of one permission rule, or of all rules if
+// permissionID is 0, newest first
+func (s *service) ListRuleChanges(ctx context.Context, permissionID int64, page, limit int) (*PermissionChangeListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	changes, totalCount, err := s.repo.ListPermissionChanges(ctx, permissionID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list permission rule changes: %w", err)
+	}
+	if changes == nil {
+		changes = []PermissionChange{}
+	}
+
+	return &PermissionChangeListResponse{
+		Data:       changes,
+		Page:       page,
+		Limit:      limit,
+		TotalCount: totalCount,
+		TotalPages: (totalCount + limit - 1) / limit,
+	}, nil
+}
+
+// ListRoutes lists the routes permission rules can be created for
+func (s *service) ListRoutes() *RouteListResponse {
+	routes := s.permissionManager.Routes()
+	if routes == nil {
+		routes = []Route{}
+	}
+	return &RouteListResponse{Data: routes}
+}
+
+// Coverage compares the permission rules in effect with the registered routes
+func (s *service) Coverage() (*CoverageReport, error) {
+	report := s.permissionManager.Coverage()
+	if report == nil {
+		return nil, ErrRoutesUnknown
+	}
+	return report, nil
+}
+
+// RefreshPermissions reloads the permissions of this instance and notifies the other instances
+// to reload theirs. A failed notification is logged; the others then catch up with their
+// periodic reload.
+func (s *service) RefreshPermissions(ctx context.Context) error {
+	if err := s.permissionManager.LoadPermissions(ctx); err != nil {
+		return err
+	}
+	if err := s.repo.NotifyPermissionsChanged(ctx); err != nil {
+		log.Printf("[WARN] Failed to notify other instances of refreshed permissions: %v", err)
+	}
+	return nil
+}
+
+// Status reports the permission rules loaded by this instance
+func (s *service) Status() *PermissionStatus {
+	return s.permissionManager.Status()
+}
+
+func (s *service) getRule(ctx context.Context, id int64) (*APIPermission, error) {
+	perm, err := s.repo.GetPermission(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRuleNotFound
+		}
+		return nil, fmt.Errorf("failed to get permission rule: %w", err)
+	}
+	return perm, nil
+}
+
+// validateRule normalizes a permission rule and checks that it applies to a registered route,
+// requires existing permissions or roles and is the only rule for its method and path pattern
+func (s *service) validateRule(ctx context.Context, perm *APIPermission) error {
+	perm.Method = strings.ToUpper(strings.TrimSpace(perm.Method))
+	if !slices.Contains(ruleMethods, perm.Method) {
+		return ErrInvalidMethod
+	}
+
+	perm.PathPattern = strings.TrimSpace(perm.PathPattern)
+	if !strings.HasPrefix(perm.PathPattern, "/") {
+		return ErrInvalidPattern
+	}
+	if !s.permissionManager.HasRoute(perm.Method, perm.PathPattern) {
+		return fmt.Errorf("%w: %s %s", ErrUnknownRoute, perm.Method, perm.PathPattern)
+	}
+
+	perm.RequiredPermissions = normalizeNames(perm.RequiredPermissions)
+	perm.RequiredRoles = normalizeNames(perm.RequiredRoles)
+	if len(perm.RequiredPermissions) == 0 && len(perm.RequiredRoles) == 0 {
+		return ErrNoRequirement
+	}
+
+	if len(perm.RequiredPermissions) > 0 {
+		missing, err := s.repo.FindMissingPermissions(ctx, perm.RequiredPermissions)
+		if err != nil {
+			return fmt.Errorf("failed to check permissions: %w", err)
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("%w: %s", ErrUnknownPermission, strings.Join(missing, ", "))
+		}
+	}
+
+	if err := s.checkRoles(ctx, perm.RequiredRoles); err != nil {
+		return err
+	}
+
+	exists, err := s.repo.PermissionExists(ctx, perm.Method, perm.PathPattern, perm.ID)
+	if err != nil {
+		return fmt.Errorf("failed to check existence: %w", err)
+	}
+	if exists {
+		return ErrRuleExists
+	}
+
+	return nil
+}
+
+// reload applies a saved change to the permissions in memory. A failure is logged; the change
+// then takes effect with the next successful reload, e.g. POST /admin/refresh-permissions.
+func (s *service) reload(ctx context.Context) {
+	if err := s.permissionManager.LoadPermissions(ctx); err != nil {
+		log.Printf("[WARN] Failed to reload permissions after a rule change: %v", err)
+	}
+}
+
+// ListPermissions lists all named permissions with their roles
+func (s *service) ListPermissions(ctx context.Context) (*NamedPermissionListResponse, error) {
+	perms, err := s.repo.ListNamedPermissions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list permissions: %w", err)
+	}
+
+	responses := make([]NamedPermissionResponse, 0, len(perms))
+	for _, perm := range perms {
+		responses = append(responses, perm.ToResponse())
+	}
+	return &NamedPermissionListResponse{Data: responses}, nil
+}
+
+// GetPermission retrieves a named permission by ID
+func (s *service) GetPermission(ctx context.Context, id int64) (*NamedPermissionResponse, error) {
+	perm, err := s.getPermission(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	response := perm.ToResponse()
+	return &response, nil
+}
+
+// CreatePermission creates a named permission and assigns it to roles
+func (s *service) CreatePermission(ctx context.Context, actorID string, req *CreateNamedPermissionRequest) (*NamedPermissionResponse, error) {
+	perm := &NamedPermission{
+		Name:  strings.ToLower(strings.TrimSpace(req.Name)),
+		Roles: normalizeNames(req.Roles),
+	}
+	if req.Description != nil {
+		perm.Description = *req.Description
+	}
+
+	if !permissionNamePattern.MatchString(perm.Name) {
+		return nil, ErrInvalidPermissionName
+	}
+	if err := s.checkRoles(ctx, perm.Roles); err != nil {
+		return nil, err
+	}
+
+	exists, err := s.repo.NamedPermissionExists(ctx, perm.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existence: %w", err)
+	}
+	if exists {
+		return nil, ErrPermissionExists
+	}
+
+	if err := s.repo.CreateNamedPermission(ctx, perm); err != nil {
+		return nil, fmt.Errorf("failed to create permission: %w", err)
+	}
+	log.Printf("User %s created permission %s for roles %v", actorID, perm.Name, perm.Roles)
+	s.reload(ctx)
+
+	response := perm.ToResponse()
+	return &response, nil
+}
+
+// UpdatePermission changes the description or the roles of a named permission
+func (s *service) UpdatePermission(ctx context.Context, actorID string, id int64, req *UpdateNamedPermissionRequest) (*NamedPermissionResponse, error) {
+	perm, err := s.getPermission(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Description != nil {
+		perm.Description = *req.Description
+	}
+	if req.Roles != nil {
+		perm.Roles = normalizeNames(req.Roles)
+		if err := s.checkRoles(ctx, perm.Roles); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.UpdateNamedPermission(ctx, perm); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPermissionNotFound
+		}
+		return nil, fmt.Errorf("failed to update permission: %w", err)
+	}
+	log.Printf("User %s updated permission %s for roles %v", actorID, perm.Name, perm.Roles)
+	s.reload(ctx)
+
+	response := perm.ToResponse()
+	return &response, nil
+}
+
+// DeletePermission deletes a named permission that no permission rule requires
+func (s *service) DeletePermission(ctx context.Context, actorID string, id int64) error {
+	perm, err := s.getPermission(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	rules, err := s.repo.ListRulesRequiringPermission(ctx, perm.Name)
+	if err != nil {
+		return fmt.Errorf("failed to check permission rules: %w", err)
+	}
+	if len(rules) > 0 {
+		routes := make([]string, 0, len(rules))
+		for _, rule := range rules {
+			routes = append(routes, rule.Method+" "+rule.PathPattern)
+		}
+		return fmt.Errorf("%w: %s", ErrPermissionInUse, strings.Join(routes, ", "))
+	}
+
+	if err := s.repo.DeleteNamedPermission(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPermissionNotFound
+		}
+		return fmt.Errorf("failed to delete permission: %w", err)
+	}
+	log.Printf("User %s deleted permission %s", actorID, perm.Name)
+	s.reload(ctx)
+
+	return nil
+}
+
+func (s *service) getPermission(ctx context.Context, id int64) (*NamedPermission, error) {
+	perm, err := s.repo.GetNamedPermission(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPermissionNotFound
+		}
+		return nil, fmt.Errorf("failed to get permission: %w", err)
+	}
+	return perm, nil
+}
+
+// checkRoles checks that roles exist
+func (s *service) checkRoles(ctx context.Context, roles []string) error {
+	if len(roles) == 0 {
+		return nil
+	}
+	missing, err := s.repo.FindMissingRoles(ctx, roles)
+	if err != nil {
+		return fmt.Errorf("failed to check roles: %w", err)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownRole, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// normalizeNames trims names and removes blank and duplicate ones
+func normalizeNames(names []string) []string {
+	var normalized []string
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" && !slices.Contains(normalized, name) {
+			normalized = append(normalized, name)
+		}
+	}
+	return normalized
+}
@@ -7,6 +7,7 @@ import (
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"kc-api/internal/auth"
 	"kc-api/internal/utils"
 )
 
@@ -35,10 +36,11 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 		r.Get("/{id}/users", h.GetUsersWithRole)
 	})
 
+	// Role assignments are off limits while impersonating a user
 	r.Route("/users/{userId}/roles", func(r chi.Router) {
 		r.Get("/", h.GetUserRoles)
-		r.Put("/", h.AssignUserRoles)
-		r.Delete("/{roleId}", h.RemoveUserRole)
+		r.With(auth.DenyImpersonation).Put("/", h.AssignUserRoles)
+		r.With(auth.DenyImpersonation).Delete("/{roleId}", h.RemoveUserRole)
 	})
 }
 
@@ -395,6 +397,7 @@ func (h *Handler) GetUserRoles(w http.ResponseWriter, r *http.Request) {
 // @Param        request  body      AssignUserRolesRequest  true  "Role IDs to assign"
 // @Success      200      {object}  SuccessResponse
 // @Failure      400      {object}  ErrorResponse
+// @Failure      403      {object}  ErrorResponse  "Forbidden - impersonating"
 // @Failure      500      {object}  ErrorResponse
 // @Security     BearerAuth
 // @Router       /users/{userId}/roles [put]
@@ -430,6 +433,7 @@ func (h *Handler) AssignUserRoles(w http.ResponseWriter, r *http.Request) {
 // @Param        userId  path      int  true  "User ID"
 // @Param        roleId  path      int  true  "Role ID"
 // @Success      200     {object}  SuccessResponse
+// @Failure      403     {object}  ErrorResponse  "Forbidden - impersonating"
 // @Failure      404     {object}  ErrorResponse
 // @Failure      500     {object}  ErrorResponse
 // @Security     BearerAuth
@@ -9,6 +9,8 @@ import (
 	"testing"
 
 	"github.com/go-chi/chi/v5"
+
+	"kc-api/internal/auth"
 )
 
 // MockService is a mock implementation of the Service interface for testing
@@ -370,3 +372,56 @@ func TestHandler_GetUserRoles(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_UserRolesWhileImpersonating(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "list allowed", method: http.MethodGet, path: "/users/1/roles", expectedStatus: http.StatusOK},
+		{name: "assign blocked", method: http.MethodPut, path: "/users/1/roles", body: `{"role_ids":[1]}`, expectedStatus: http.StatusForbidden},
+		{name: "remove blocked", method: http.MethodDelete, path: "/users/1/roles/1", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := false
+			mockService := &MockService{
+				GetUserRolesFunc: func(ctx context.Context, userID int) ([]UserRoleResponse, error) {
+					return []UserRoleResponse{}, nil
+				},
+				AssignUserRolesFunc: func(ctx context.Context, userID int, req *AssignUserRolesRequest) error {
+					changed = true
+					return nil
+				},
+				RemoveUserRoleFunc: func(ctx context.Context, userID int, roleID int) error {
+					changed = true
+					return nil
+				},
+			}
+
+			r := chi.NewRouter()
+			r.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					claims := &auth.TokenClaims{UserID: "user-456", Actor: &auth.TokenActor{UserID: "admin-123"}}
+					next.ServeHTTP(w, req.WithContext(auth.SetClaimsInContext(req.Context(), claims)))
+				})
+			})
+			NewHandler(mockService).RegisterRoutes(r)
+
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if changed {
+				t.Error("Expected role assignments to be left unchanged while impersonating")
+			}
+		})
+	}
+}
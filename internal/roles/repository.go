@@ -28,6 +28,7 @@ type Repository interface {
 	RemoveUserRole(ctx context.Context, userID int, roleID int) error
 	RemoveAllUserRoles(ctx context.Context, userID int) error
 	GetUsersWithRole(ctx context.Context, roleID int) ([]int, error)
+	GetRoleHolders(ctx context.Context, roleID int) ([]int, error)
 }
 
 type repository struct {
@@ -495,3 +496,35 @@ func (r *repository) GetUsersWithRole(ctx context.Context, roleID int) ([]int, e
 
 	return userIDs, nil
 }
+
+// GetRoleHolders retrieves all users that hold a role, directly or through a group
+func (r *repository) GetRoleHolders(ctx context.Context, roleID int) ([]int, error) {
+	query := `
+		SELECT user_id FROM organizations.user_roles WHERE role_id = $1
+		UNION
+		SELECT gu.user_id
+		FROM organizations.group_users gu
+		INNER JOIN organizations.group_roles gr ON gr.group_id = gu.group_id
+		WHERE gr.role_id = $1`
+
+	rows, err := r.db.QueryContext(ctx, query, roleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return userIDs, nil
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 )
 
 var (
@@ -35,13 +36,20 @@ type Service interface {
 	GetUsersWithRole(ctx context.Context, roleID int) ([]int, error)
 }
 
+// TokenRevoker revokes the access tokens issued to users, whose roles are embedded in the tokens.
+// It is implemented by the auth service.
+type TokenRevoker interface {
+	RevokeUserTokens(ctx context.Context, userIDs ...int) error
+}
+
 type service struct {
-	repo Repository
+	repo   Repository
+	tokens TokenRevoker // nil leaves issued access tokens valid until they expire
 }
 
-// NewService creates a new role service
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+// NewService creates a new role service. The token revoker may be nil.
+func NewService(repo Repository, tokens TokenRevoker) Service {
+	return &service{repo: repo, tokens: tokens}
 }
 
 // Create creates a new role
@@ -126,6 +134,7 @@ func (s *service) Update(ctx context.Context, id int, req *UpdateRoleRequest) (*
 		return nil, fmt.Errorf("failed to get role: %w", err)
 	}
 
+	renamed := req.Name != nil && *req.Name != "" && *req.Name != existingRole.Name
 	if req.Name != nil && *req.Name != "" {
 		existingRole.Name = *req.Name
 	}
@@ -147,6 +156,9 @@ func (s *service) Update(ctx context.Context, id int, req *UpdateRoleRequest) (*
 	if err := s.repo.Update(ctx, id, existingRole); err != nil {
 		return nil, fmt.Errorf("failed to update role: %w", err)
 	}
+	if renamed {
+		s.revokeHolderTokens(ctx, id)
+	}
 
 	response := existingRole.ToResponse()
 	return &response, nil
@@ -154,12 +166,14 @@ func (s *service) Update(ctx context.Context, id int, req *UpdateRoleRequest) (*
 
 // Delete deletes a role
 func (s *service) Delete(ctx context.Context, id int) error {
+	holders := s.holders(ctx, id)
 	if err := s.repo.Delete(ctx, id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrRoleNotFound
 		}
 		return fmt.Errorf("failed to delete role: %w", err)
 	}
+	s.revokeTokens(ctx, holders...)
 	return nil
 }
 
@@ -231,12 +245,16 @@ func (s *service) BatchUpdate(ctx context.Context, req *BatchUpdateRoleRequest)
 	}
 
 	var updates []Role
+	var renamed []int
 	for _, u := range req.Updates {
 		existingRole, err := s.repo.GetByID(ctx, u.ID)
 		if err != nil {
 			continue
 		}
 
+		if u.Name != nil && *u.Name != "" && *u.Name != existingRole.Name {
+			renamed = append(renamed, u.ID)
+		}
 		if u.Name != nil && *u.Name != "" {
 			existingRole.Name = *u.Name
 		}
@@ -251,6 +269,9 @@ func (s *service) BatchUpdate(ctx context.Context, req *BatchUpdateRoleRequest)
 	if err != nil {
 		return nil, fmt.Errorf("failed to batch update: %w", err)
 	}
+	for _, id := range renamed {
+		s.revokeHolderTokens(ctx, id)
+	}
 
 	return &BatchOperationResponse{
 		SuccessCount: successCount,
@@ -265,10 +286,16 @@ func (s *service) BatchDelete(ctx context.Context, req *BatchDeleteRoleRequest)
 		return nil, ErrEmptyBatchRequest
 	}
 
+	var holders []int
+	for _, id := range req.IDs {
+		holders = append(holders, s.holders(ctx, id)...)
+	}
+
 	successCount, failedIDs, err := s.repo.BatchDelete(ctx, req.IDs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to batch delete: %w", err)
 	}
+	s.revokeTokens(ctx, holders...)
 
 	return &BatchOperationResponse{
 		SuccessCount: successCount,
@@ -291,6 +318,7 @@ func (s *service) AssignUserRoles(ctx context.Context, userID int, req *AssignUs
 	if err := s.repo.AssignUserRoles(ctx, userID, req.RoleIDs); err != nil {
 		return fmt.Errorf("failed to assign user roles: %w", err)
 	}
+	s.revokeTokens(ctx, userID)
 	return nil
 }
 
@@ -302,6 +330,7 @@ func (s *service) RemoveUserRole(ctx context.Context, userID int, roleID int) er
 		}
 		return fmt.Errorf("failed to remove user role: %w", err)
 	}
+	s.revokeTokens(ctx, userID)
 	return nil
 }
 
@@ -314,6 +343,34 @@ func (s *service) GetUsersWithRole(ctx context.Context, roleID int) ([]int, erro
 	return userIDs, nil
 }
 
+// holders returns the users holding a role directly or through a group, for revoking their tokens once the role changed
+func (s *service) holders(ctx context.Context, roleID int) []int {
+	if s.tokens == nil {
+		return nil
+	}
+	userIDs, err := s.repo.GetRoleHolders(ctx, roleID)
+	if err != nil {
+		log.Printf("[WARN] Failed to get holders of role %d: %v", roleID, err)
+	}
+	return userIDs
+}
+
+// revokeHolderTokens revokes the access tokens of the users holding a role
+func (s *service) revokeHolderTokens(ctx context.Context, roleID int) {
+	s.revokeTokens(ctx, s.holders(ctx, roleID)...)
+}
+
+// revokeTokens revokes the access tokens of users whose roles changed, so that the change
+// takes effect immediately. Failures are logged; the change itself has already been made.
+func (s *service) revokeTokens(ctx context.Context, userIDs ...int) {
+	if s.tokens == nil || len(userIDs) == 0 {
+		return
+	}
+	if err := s.tokens.RevokeUserTokens(ctx, userIDs...); err != nil {
+		log.Printf("[WARN] Failed to revoke access tokens of users %v: %v", userIDs, err)
+	}
+}
+
 // Helper function
 func getOrDefault(value *json.RawMessage, defaultValue json.RawMessage) json.RawMessage {
 	if value != nil {
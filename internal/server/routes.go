@@ -4,11 +4,24 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
+
+	"kc-api/internal/aiqueue"
+	"kc-api/internal/auth"
+	"kc-api/internal/commoncodes"
+	"kc-api/internal/departments"
+	"kc-api/internal/files"
+	"kc-api/internal/groups"
+	"kc-api/internal/plugins/ews"
+	"kc-api/internal/rbac"
+	"kc-api/internal/roles"
+	"kc-api/internal/tickets"
+	"kc-api/internal/users"
 )
 
 func (s *Server) RegisterRoutes() http.Handler {
@@ -38,48 +51,117 @@ func (s *Server) RegisterRoutes() http.Handler {
 	// Register public auth routes (login, register, refresh, logout)
 	s.authHandler.RegisterRoutes(r)
 
+	// Register public file routes (public files and signed share links)
+	s.fileHandler.RegisterPublicRoutes(r)
+
 	// Protected routes requiring authentication and RBAC authorization
 	r.Group(func(r chi.Router) {
 		r.Use(s.authMiddleware.Authenticate)
 		r.Use(s.rbacMiddleware.Authorize)
 
-		// Protected auth routes (me, logout-all)
-		s.authHandler.RegisterProtectedRoutes(r)
+		s.registerProtectedRoutes(r)
+	})
+
+	// Swagger UI route
+	r.Get("/swagger/*", httpSwagger.Handler(
+		httpSwagger.URL("/swagger/doc.json"),
+	))
+
+	// Permission rules are validated against the routes the RBAC middleware protects
+	protected := chi.NewRouter()
+	s.registerProtectedRoutes(protected)
+	if err := s.permissionManager.SetRoutes(protected); err != nil {
+		log.Printf("Warning: Failed to collect routes for permission rules: %v", err)
+	} else {
+		s.logPermissionCoverage()
+	}
 
-		// Protected user routes
-		s.userHandler.RegisterRoutes(r)
+	return r
+}
 
-		// RBAC admin routes (refresh-permissions) - requires sysadmin role via RBAC
-		s.rbacHandler.RegisterRoutes(r)
+// registerProtectedRoutes registers the routes requiring authentication and RBAC authorization
+func (s *Server) registerProtectedRoutes(r chi.Router) {
+	// Protected auth routes (me, logout-all)
+	s.authHandler.RegisterProtectedRoutes(r)
 
-		// Protected ticket routes
-		s.ticketHandler.RegisterRoutes(r)
+	// Protected user routes
+	s.userHandler.RegisterRoutes(r)
 
-		// Protected file routes
-		s.fileHandler.RegisterRoutes(r)
+	// RBAC admin routes (refresh-permissions, permission rules) - requires sysadmin role via RBAC
+	s.rbacHandler.RegisterRoutes(r)
 
-		// EWS plugin routes (optional - only registered if configured)
-		if s.ewsHandler != nil {
-			s.ewsHandler.RegisterRoutes(r)
-		}
+	// Protected ticket routes
+	s.ticketHandler.RegisterRoutes(r)
+
+	// Protected file routes
+	s.fileHandler.RegisterRoutes(r)
+
+	// EWS plugin routes (optional - only registered if configured)
+	if s.ewsHandler != nil {
+		s.ewsHandler.RegisterRoutes(r)
+	}
+
+	// AI queue routes (optional - only registered if configured)
+	if s.aiQueueHandler != nil {
+		s.aiQueueHandler.RegisterRoutes(r)
+	}
 
-		// AI queue routes (optional - only registered if configured)
-		if s.aiQueueHandler != nil {
-			s.aiQueueHandler.RegisterRoutes(r)
+	// Organization management routes
+	s.commonCodeHandler.RegisterRoutes(r)
+	s.roleHandler.RegisterRoutes(r)
+	s.departmentHandler.RegisterRoutes(r)
+	s.groupHandler.RegisterRoutes(r)
+}
+
+// logPermissionCoverage reports protected routes without a permission rule and rules that match
+// no route. Uncovered routes are listed one by one when they are denied by default; otherwise
+// only their number is logged (see cmd/rbac-report for the full report).
+func (s *Server) logPermissionCoverage() {
+	report := s.permissionManager.Coverage()
+	if report == nil {
+		return
+	}
+
+	if s.rbacConfig.DenyByDefault {
+		log.Printf("RBAC: deny by default enabled, %d of %d protected routes have no permission rule and are denied",
+			len(report.UncoveredRoutes), report.RouteCount)
+		for _, route := range report.UncoveredRoutes {
+			log.Printf("[WARN] RBAC: no permission rule for %s %s", route.Method, route.Pattern)
 		}
+	} else {
+		log.Printf("RBAC: %d of %d protected routes have no permission rule and are open to every authenticated user",
+			len(report.UncoveredRoutes), report.RouteCount)
+	}
 
-		// Organization management routes
-		s.commonCodeHandler.RegisterRoutes(r)
-		s.roleHandler.RegisterRoutes(r)
-		s.departmentHandler.RegisterRoutes(r)
-		s.groupHandler.RegisterRoutes(r)
-	})
+	for _, rule := range report.StaleRules {
+		log.Printf("[WARN] RBAC: permission rule %s %s matches no registered route", rule.Method, rule.Pattern)
+	}
+	for _, mismatch := range report.MethodMismatches {
+		log.Printf("[WARN] RBAC: permission rule %s %s matches no method of the route (registered: %s)",
+			mismatch.Method, mismatch.Pattern, strings.Join(mismatch.RegisteredMethods, ", "))
+	}
+}
 
-	// Swagger UI route
-	r.Get("/swagger/*", httpSwagger.Handler(
-		httpSwagger.URL("/swagger/doc.json"),
-	))
+// ProtectedRoutes returns a router with the routes requiring authentication and RBAC
+// authorization, including the optional plugin routes. Its handlers have no services: it is
+// only meant for listing routes, e.g. to compare them with the permission rules.
+func ProtectedRoutes() chi.Routes {
+	s := &Server{
+		userHandler:       users.NewHandler(nil),
+		authHandler:       auth.NewHandler(nil, nil),
+		rbacHandler:       rbac.NewHandler(nil, nil),
+		ticketHandler:     tickets.NewHandler(nil, nil),
+		fileHandler:       files.NewHandler(nil),
+		ewsHandler:        ews.NewHandler(nil),
+		aiQueueHandler:    aiqueue.NewHandler(nil),
+		commonCodeHandler: commoncodes.NewHandler(nil),
+		roleHandler:       roles.NewHandler(nil),
+		departmentHandler: departments.NewHandler(nil),
+		groupHandler:      groups.NewHandler(nil),
+	}
 
+	r := chi.NewRouter()
+	s.registerProtectedRoutes(r)
 	return r
 }
 
@@ -105,13 +187,18 @@ func (s *Server) HelloWorldHandler(w http.ResponseWriter, r *http.Request) {
 
 // healthHandler godoc
 // @Summary      Health Check
-// @Description  Returns the health status of the service and database connection
+// @Description  Returns the health status of the service and database connection, and the version of the loaded permission rules
 // @Tags         health
 // @Accept       json
 // @Produce      json
 // @Success      200  {object}  map[string]string
 // @Router       /health [get]
 func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
-	jsonResp, _ := json.Marshal(s.db.Health())
+	stats := s.db.Health()
+
+	// Version of the loaded permission rules, to spot instances that did not reload
+	stats["rbac_permission_version"] = s.permissionManager.Version()
+
+	jsonResp, _ := json.Marshal(stats)
 	_, _ = w.Write(jsonResp)
 }
@@ -4,7 +4,10 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"testing"
+
+	"kc-api/internal/rbac"
 )
 
 func TestHandler(t *testing.T) {
@@ -29,3 +32,18 @@ func TestHandler(t *testing.T) {
 		t.Errorf("expected response body to be %v; got %v", expected, string(body))
 	}
 }
+
+func TestProtectedRoutes(t *testing.T) {
+	routes, err := rbac.CollectRoutes(ProtectedRoutes())
+	if err != nil {
+		t.Fatalf("failed to collect routes: %v", err)
+	}
+
+	// Protected routes are listed, public ones are not
+	if !slices.Contains(routes, rbac.Route{Method: http.MethodGet, Pattern: "/auth/me"}) {
+		t.Error("expected GET /auth/me to be a protected route")
+	}
+	if slices.Contains(routes, rbac.Route{Method: http.MethodPost, Pattern: "/auth/login"}) {
+		t.Error("expected POST /auth/login not to be a protected route")
+	}
+}
@@ -6,10 +6,12 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"time"
 
 	_ "github.com/joho/godotenv/autoload"
+	"github.com/redis/go-redis/v9"
 
 	"kc-api/internal/aiqueue"
 	"kc-api/internal/auth"
@@ -18,6 +20,9 @@ import (
 	"kc-api/internal/departments"
 	"kc-api/internal/files"
 	"kc-api/internal/groups"
+	"kc-api/internal/ldap"
+	"kc-api/internal/mail"
+	"kc-api/internal/oidc"
 	"kc-api/internal/plugins/ews"
 	"kc-api/internal/rbac"
 	"kc-api/internal/roles"
@@ -25,6 +30,12 @@ import (
 	"kc-api/internal/users"
 )
 
+// placeholderJWTSecrets are JWT secrets published with the code, which must not sign tokens
+var placeholderJWTSecrets = []string{
+	"default-jwt-secret-change-in-production",
+	"your-jwt-secret-key-change-in-production", // .env.sample
+}
+
 type Server struct {
 	port int
 
@@ -34,6 +45,7 @@ type Server struct {
 	authMiddleware    *auth.Middleware
 	rbacHandler       *rbac.Handler
 	rbacMiddleware    *rbac.Middleware
+	rbacConfig        *rbac.Config
 	permissionManager *rbac.PermissionManager
 	ticketHandler     *tickets.Handler
 	fileHandler       *files.Handler
@@ -53,50 +65,139 @@ func NewServer() *http.Server {
 	if encryptionKey == "" {
 		encryptionKey = "default-encryption-key-change-in-production"
 	}
+	// Anyone knowing the secret can sign access tokens (with HS256 or while AUTH_JWT_ACCEPT_HMAC
+	// is on) and MFA challenge tokens, so a missing or published secret is refused
 	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "default-jwt-secret-change-in-production"
+	if jwtSecret == "" || slices.Contains(placeholderJWTSecrets, jwtSecret) {
+		log.Fatal("JWT_SECRET must be set to a secret value")
 	}
 
 	// Initialize database
 	db := database.New()
 
-	// Initialize user domain with DI
-	userRepo := users.NewRepository(db.DB())
-	userService := users.NewService(userRepo, encryptionKey)
-	userHandler := users.NewHandler(userService)
+	// Initialize AI queue (optional); its Redis connection also backs the access token denylist
+	var aiQueueHandler *aiqueue.Handler
+	var redisClient *redis.Client
+	redisAddr := os.Getenv("REDIS_ADDR")
+	if redisAddr != "" {
+		redisPassword := os.Getenv("REDIS_PASSWORD")
+		redisDB, _ := strconv.Atoi(os.Getenv("REDIS_DB"))
+		redisQueueName := os.Getenv("REDIS_QUEUE_NAME")
+		if redisQueueName == "" {
+			redisQueueName = "celery"
+		}
+
+		aiQueueClient, err := aiqueue.NewClient(redisAddr, redisPassword, redisDB, redisQueueName)
+		if err != nil {
+			log.Printf("Warning: Failed to create AI queue client: %v", err)
+		} else {
+			redisClient = aiQueueClient.Redis()
+			aiQueueService := aiqueue.NewService(aiQueueClient)
+			aiQueueHandler = aiqueue.NewHandler(aiQueueService)
+			log.Println("AI queue integration initialized successfully")
+		}
+	} else {
+		log.Println("AI queue integration not configured (REDIS_ADDR not set)")
+	}
+
+	// Initialize mail delivery (optional)
+	var mailSender mail.Sender
+	mailConfig, err := mail.LoadConfig()
+	if err != nil {
+		log.Printf("Warning: Failed to load mail config: %v", err)
+	} else if mailConfig != nil {
+		mailSender = mail.NewSMTPSender(mailConfig)
+		log.Println("Mail delivery initialized successfully")
+	} else {
+		log.Println("Mail delivery not configured (MAIL_SMTP_HOST not set)")
+	}
+
+	// Initialize single sign-on (optional)
+	var ssoProvider oidc.Provider
+	oidcConfig, err := oidc.LoadConfig()
+	if err != nil {
+		log.Printf("Warning: Failed to load OIDC config: %v", err)
+	} else if oidcConfig != nil {
+		ssoProvider = oidc.NewClient(oidcConfig)
+		log.Println("Single sign-on initialized successfully")
+	} else {
+		log.Println("Single sign-on not configured (OIDC_ISSUER_URL not set)")
+	}
+
+	// Initialize LDAP directory (optional)
+	var directory ldap.Directory
+	ldapConfig, err := ldap.LoadConfig()
+	if err != nil {
+		log.Printf("Warning: Failed to load LDAP config: %v", err)
+	} else if ldapConfig != nil {
+		directory = ldap.NewClient(ldapConfig)
+		log.Println("LDAP directory initialized successfully")
+	} else {
+		log.Println("LDAP directory not configured (LDAP_URL not set)")
+	}
 
 	// Initialize auth domain with DI
 	authRepo := auth.NewRepository(db.DB())
-	authService := auth.NewService(authRepo, jwtSecret)
-	authHandler := auth.NewHandler(authService)
-	authMiddleware := auth.NewMiddleware(authService)
-
-	// Initialize RBAC domain with DI
+	authConfig := auth.LoadConfig()
+	if authConfig.EncryptionKey == "" {
+		authConfig.EncryptionKey = encryptionKey
+	}
+	// The permission manager is shared with the RBAC domain below
 	rbacRepo := rbac.NewRepository(db.DB())
 	permissionManager := rbac.NewPermissionManager(rbacRepo)
-	rbacHandler := rbac.NewHandler(permissionManager)
-	rbacMiddleware := rbac.NewMiddleware(permissionManager)
+	authService := auth.NewService(authRepo, jwtSecret, authConfig, mailSender, ssoProvider, directory, redisClient, permissionManager)
+	clientIPs, err := auth.NewClientIPResolver(authConfig.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Invalid AUTH_TRUSTED_PROXIES: %v", err)
+	}
+	authHandler := auth.NewHandler(authService, clientIPs)
+	authMiddleware := auth.NewMiddleware(authService, clientIPs)
+
+	// Initialize user domain with DI
+	userRepo := users.NewRepository(db.DB())
+	userService := users.NewService(userRepo, encryptionKey, authService, authService)
+	userHandler := users.NewHandler(userService)
+
+	// Initialize RBAC domain with DI
+	rbacConfig := rbac.LoadConfig()
+	rbacService := rbac.NewService(rbacRepo, permissionManager)
+	rbacHandler := rbac.NewHandler(permissionManager, rbacService)
+	rbacMiddleware := rbac.NewMiddleware(permissionManager, rbacConfig)
 
 	// Load initial permissions from database
 	if err := permissionManager.LoadPermissions(context.Background()); err != nil {
 		log.Printf("Warning: Failed to load initial permissions: %v", err)
 	}
 
+	// Reload permissions when they change on any instance, with a periodic fallback
+	rbac.NewListener(permissionManager, database.ConnString(), rbacConfig).Start()
+
 	// Initialize tickets domain with DI
 	ticketRepo := tickets.NewRepository(db.DB())
-	ticketService := tickets.NewService(ticketRepo)
-	ticketHandler := tickets.NewHandler(ticketService)
+	ticketService := tickets.NewService(ticketRepo, permissionManager)
 
-	// Initialize files domain with DI
-	fileStoragePath := os.Getenv("FILE_STORAGE_PATH")
-	if fileStoragePath == "" {
-		fileStoragePath = "./uploads"
+	// Initialize files domain with DI (files attached to tickets are readable by ticket readers)
+	fileConfig := files.LoadConfig()
+	if fileConfig.ShareSecret == "" {
+		fileConfig.ShareSecret = jwtSecret
+	}
+	var fileScanner files.Scanner
+	if fileConfig.ClamdAddress != "" {
+		clamdScanner, err := files.NewClamdScanner(fileConfig.ClamdAddress, fileConfig.ScanTimeout)
+		if err != nil {
+			log.Printf("Warning: Failed to create clamd scanner: %v", err)
+		} else {
+			fileScanner = clamdScanner
+			log.Println("Malware scanning of uploads enabled (clamd)")
+		}
 	}
 	fileRepo := files.NewRepository(db.DB())
-	fileService := files.NewService(fileRepo, fileStoragePath)
+	fileService := files.NewService(fileRepo, fileConfig, fileScanner, ticketService, authService, authService)
 	fileHandler := files.NewHandler(fileService)
 
+	// Ticket attachments are archived by the files domain
+	ticketHandler := tickets.NewHandler(ticketService, fileHandler)
+
 	// Initialize EWS plugin (optional)
 	var ewsHandler *ews.Handler
 	ewsConfig, err := ews.LoadConfig()
@@ -114,29 +215,6 @@ func NewServer() *http.Server {
 		log.Println("EWS plugin not configured (EWS_SERVER_URL not set)")
 	}
 
-	// Initialize AI queue (optional)
-	var aiQueueHandler *aiqueue.Handler
-	redisAddr := os.Getenv("REDIS_ADDR")
-	if redisAddr != "" {
-		redisPassword := os.Getenv("REDIS_PASSWORD")
-		redisDB, _ := strconv.Atoi(os.Getenv("REDIS_DB"))
-		redisQueueName := os.Getenv("REDIS_QUEUE_NAME")
-		if redisQueueName == "" {
-			redisQueueName = "celery"
-		}
-
-		aiQueueClient, err := aiqueue.NewClient(redisAddr, redisPassword, redisDB, redisQueueName)
-		if err != nil {
-			log.Printf("Warning: Failed to create AI queue client: %v", err)
-		} else {
-			aiQueueService := aiqueue.NewService(aiQueueClient)
-			aiQueueHandler = aiqueue.NewHandler(aiQueueService)
-			log.Println("AI queue integration initialized successfully")
-		}
-	} else {
-		log.Println("AI queue integration not configured (REDIS_ADDR not set)")
-	}
-
 	// Initialize common codes domain with DI
 	commonCodeRepo := commoncodes.NewRepository(db.DB())
 	commonCodeService := commoncodes.NewService(commonCodeRepo)
@@ -144,7 +222,7 @@ func NewServer() *http.Server {
 
 	// Initialize roles domain with DI
 	roleRepo := roles.NewRepository(db.DB())
-	roleService := roles.NewService(roleRepo)
+	roleService := roles.NewService(roleRepo, authService)
 	roleHandler := roles.NewHandler(roleService)
 
 	// Initialize departments domain with DI
@@ -154,7 +232,7 @@ func NewServer() *http.Server {
 
 	// Initialize groups domain with DI
 	groupRepo := groups.NewRepository(db.DB())
-	groupService := groups.NewService(groupRepo)
+	groupService := groups.NewService(groupRepo, authService)
 	groupHandler := groups.NewHandler(groupService)
 
 	NewServer := &Server{
@@ -165,6 +243,7 @@ func NewServer() *http.Server {
 		authMiddleware:    authMiddleware,
 		rbacHandler:       rbacHandler,
 		rbacMiddleware:    rbacMiddleware,
+		rbacConfig:        rbacConfig,
 		permissionManager: permissionManager,
 		ticketHandler:     ticketHandler,
 		fileHandler:       fileHandler,
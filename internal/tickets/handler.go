@@ -1,6 +1,7 @@
 package tickets
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -11,14 +12,20 @@ import (
 	"kc-api/internal/utils"
 )
 
+// AttachmentArchiver streams a ZIP archive of files to the client, skipping files that can't be read
+type AttachmentArchiver interface {
+	ServeArchive(w http.ResponseWriter, r *http.Request, fileIDs []string, filename string)
+}
+
 // Handler handles HTTP requests for ticket operations
 type Handler struct {
-	service Service
+	service  Service
+	archiver AttachmentArchiver
 }
 
-// NewHandler creates a new ticket handler with the given service
-func NewHandler(service Service) *Handler {
-	return &Handler{service: service}
+// NewHandler creates a new ticket handler with the given service and attachment archiver
+func NewHandler(service Service, archiver AttachmentArchiver) *Handler {
+	return &Handler{service: service, archiver: archiver}
 }
 
 // RegisterRoutes registers ticket routes on the given router
@@ -31,6 +38,7 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 		r.Get("/{id}", h.GetTicketByID)
 		r.Put("/{id}", h.UpdateTicket)
 		r.Delete("/{id}", h.DeleteTicket)
+		r.Get("/{id}/attachments.zip", h.DownloadAttachments)
 
 		// Ticket-Tag routes
 		r.Post("/{id}/tags", h.AddTagsToTicket)
@@ -65,7 +73,7 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 
 // ListTickets godoc
 // @Summary      List tickets
-// @Description  Retrieves a paginated list of tickets with simplified response
+// @Description  Retrieves a paginated list of the tickets visible to the current user with simplified response. Users see the tickets they requested or are assigned to, the tickets of the departments they lead, and with the tickets:read:department or tickets:read:all permission those of their department or all tickets. Tickets with a confidential tag in this scope require tickets:read:confidential.
 // @Tags         tickets
 // @Accept       json
 // @Produce      json
@@ -86,7 +94,7 @@ func (h *Handler) ListTickets(w http.ResponseWriter, r *http.Request) {
 		limit = 10
 	}
 
-	result, err := h.service.ListTickets(r.Context(), page, limit)
+	result, err := h.service.ListTickets(r.Context(), requesterFromContext(r.Context()), page, limit)
 	if err != nil {
 		utils.RespondInternalError(w, r, err, "Failed to retrieve tickets")
 		return
@@ -97,13 +105,14 @@ func (h *Handler) ListTickets(w http.ResponseWriter, r *http.Request) {
 
 // CreateTicket godoc
 // @Summary      Create a new ticket
-// @Description  Creates a new ticket with an initial entry. Title and initial_entry are required.
+// @Description  Creates a new ticket with an initial entry. Title and initial_entry are required. The current user becomes the requester and the ticket belongs to their department. Confidential tags require the tickets:read:confidential permission.
 // @Tags         tickets
 // @Accept       json
 // @Produce      json
 // @Param        request  body      CreateTicketRequest  true  "Ticket data with initial entry"
 // @Success      201      {object}  TicketDetailResponse
 // @Failure      400      {object}  ErrorResponse
+// @Failure      403      {object}  ErrorResponse  "Confidential tag without tickets:read:confidential"
 // @Failure      500      {object}  ErrorResponse
 // @Security     BearerAuth
 // @Router       /tickets [post]
@@ -114,14 +123,16 @@ func (h *Handler) CreateTicket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get current user ID from context
-	authorUserID := auth.GetUserIDFromContext(r.Context())
+	// The author is the current user, with the roles of the request
+	author := requesterFromContext(r.Context())
 
-	result, err := h.service.CreateTicket(r.Context(), &req, authorUserID)
+	result, err := h.service.CreateTicket(r.Context(), &req, author)
 	if err != nil {
 		switch {
 		case errors.Is(err, ErrInvalidTitle):
 			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Title is required")
+		case errors.Is(err, ErrConfidentialTag):
+			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "Not allowed to add confidential tags")
 		default:
 			utils.RespondInternalError(w, r, err, "Failed to create ticket")
 		}
@@ -133,7 +144,7 @@ func (h *Handler) CreateTicket(w http.ResponseWriter, r *http.Request) {
 
 // GetTicketByID godoc
 // @Summary      Get ticket by ID
-// @Description  Retrieves detailed ticket information including entries and tags
+// @Description  Retrieves detailed ticket information including entries and tags. Tickets not visible to the current user (see GET /tickets) are not found.
 // @Tags         tickets
 // @Accept       json
 // @Produce      json
@@ -150,7 +161,7 @@ func (h *Handler) GetTicketByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.service.GetTicketByID(r.Context(), id)
+	result, err := h.service.GetTicketByID(r.Context(), id, requesterFromContext(r.Context()))
 	if err != nil {
 		if errors.Is(err, ErrTicketNotFound) {
 			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Ticket not found")
@@ -190,7 +201,7 @@ func (h *Handler) UpdateTicket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.service.UpdateTicket(r.Context(), id, &req)
+	result, err := h.service.UpdateTicket(r.Context(), id, requesterFromContext(r.Context()), &req)
 	if err != nil {
 		if errors.Is(err, ErrTicketNotFound) {
 			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Ticket not found")
@@ -222,7 +233,7 @@ func (h *Handler) DeleteTicket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.service.DeleteTicket(r.Context(), id)
+	err := h.service.DeleteTicket(r.Context(), id, requesterFromContext(r.Context()))
 	if err != nil {
 		if errors.Is(err, ErrTicketNotFound) {
 			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Ticket not found")
@@ -237,7 +248,7 @@ func (h *Handler) DeleteTicket(w http.ResponseWriter, r *http.Request) {
 
 // SearchTickets godoc
 // @Summary      Search tickets
-// @Description  Searches for tickets based on various criteria
+// @Description  Searches the tickets visible to the current user (see GET /tickets) based on various criteria
 // @Tags         tickets
 // @Accept       json
 // @Produce      json
@@ -266,7 +277,7 @@ func (h *Handler) SearchTickets(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.service.SearchTickets(r.Context(), &req, page, limit)
+	result, err := h.service.SearchTickets(r.Context(), requesterFromContext(r.Context()), &req, page, limit)
 	if err != nil {
 		utils.RespondInternalError(w, r, err, "Failed to search tickets")
 		return
@@ -277,7 +288,7 @@ func (h *Handler) SearchTickets(w http.ResponseWriter, r *http.Request) {
 
 // AddTagsToTicket godoc
 // @Summary      Add tags to ticket
-// @Description  Adds tags to a ticket
+// @Description  Adds tags to a ticket. Adding a confidential tag requires the tickets:read:confidential permission.
 // @Tags         tickets
 // @Accept       json
 // @Produce      json
@@ -285,6 +296,7 @@ func (h *Handler) SearchTickets(w http.ResponseWriter, r *http.Request) {
 // @Param        request  body      AddTagRequest  true  "Tags to add"
 // @Success      200      {object}  SuccessResponse
 // @Failure      400      {object}  ErrorResponse
+// @Failure      403      {object}  ErrorResponse  "Confidential tag without tickets:read:confidential"
 // @Failure      404      {object}  ErrorResponse
 // @Failure      500      {object}  ErrorResponse
 // @Security     BearerAuth
@@ -302,12 +314,16 @@ func (h *Handler) AddTagsToTicket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.service.AddTagsToTicket(r.Context(), id, &req)
+	err := h.service.AddTagsToTicket(r.Context(), id, requesterFromContext(r.Context()), &req)
 	if err != nil {
 		if errors.Is(err, ErrTicketNotFound) {
 			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Ticket not found")
 			return
 		}
+		if errors.Is(err, ErrConfidentialTag) {
+			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "Not allowed to add confidential tags")
+			return
+		}
 		utils.RespondInternalError(w, r, err, "Internal server error")
 		return
 	}
@@ -317,13 +333,14 @@ func (h *Handler) AddTagsToTicket(w http.ResponseWriter, r *http.Request) {
 
 // RemoveTagFromTicket godoc
 // @Summary      Remove tag from ticket
-// @Description  Removes a tag from a ticket
+// @Description  Removes a tag from a ticket. Removing a confidential tag requires the tickets:read:confidential permission.
 // @Tags         tickets
 // @Accept       json
 // @Produce      json
 // @Param        id     path      string  true  "Ticket Public ID (UUID)"
 // @Param        tagId  path      int     true  "Tag ID"
 // @Success      200    {object}  SuccessResponse
+// @Failure      403    {object}  ErrorResponse  "Confidential tag without tickets:read:confidential"
 // @Failure      404    {object}  ErrorResponse
 // @Failure      500    {object}  ErrorResponse
 // @Security     BearerAuth
@@ -341,7 +358,7 @@ func (h *Handler) RemoveTagFromTicket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.RemoveTagFromTicket(r.Context(), id, tagID)
+	err = h.service.RemoveTagFromTicket(r.Context(), id, requesterFromContext(r.Context()), tagID)
 	if err != nil {
 		if errors.Is(err, ErrTicketNotFound) {
 			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Ticket not found")
@@ -351,6 +368,10 @@ func (h *Handler) RemoveTagFromTicket(w http.ResponseWriter, r *http.Request) {
 			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Tag not found on ticket")
 			return
 		}
+		if errors.Is(err, ErrConfidentialTag) {
+			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "Not allowed to remove confidential tags")
+			return
+		}
 		utils.RespondInternalError(w, r, err, "Internal server error")
 		return
 	}
@@ -358,6 +379,42 @@ func (h *Handler) RemoveTagFromTicket(w http.ResponseWriter, r *http.Request) {
 	utils.RespondJSON(w, http.StatusOK, SuccessResponse{Message: "Tag removed successfully"})
 }
 
+// DownloadAttachments godoc
+// @Summary      Download ticket attachments as a ZIP archive
+// @Description  Streams a ZIP archive of the files attached to the ticket through FILE entries. Duplicate file names are numbered, e.g. "report (2).pdf". Files that no longer exist or are blocked by malware scanning are left out; their count is returned in the X-Archive-Skipped header. Tickets not visible to the current user (see GET /tickets) are not found.
+// @Tags         tickets
+// @Produce      application/zip
+// @Param        id   path      string  true  "Ticket Public ID (UUID)"
+// @Success      200  {file}    binary
+// @Failure      404  {object}  ErrorResponse  "Ticket not found or no downloadable attachments"
+// @Failure      500  {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /tickets/{id}/attachments.zip [get]
+func (h *Handler) DownloadAttachments(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Ticket ID is required")
+		return
+	}
+
+	fileIDs, err := h.service.ListAttachmentFileIDs(r.Context(), id, requesterFromContext(r.Context()))
+	if err != nil {
+		if errors.Is(err, ErrTicketNotFound) {
+			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Ticket not found")
+			return
+		}
+		utils.RespondInternalError(w, r, err, "Internal server error")
+		return
+	}
+
+	if len(fileIDs) == 0 {
+		utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Ticket has no attachments")
+		return
+	}
+
+	h.archiver.ServeArchive(w, r, fileIDs, "ticket-"+id+"-attachments.zip")
+}
+
 // -------------------- Entry Handlers --------------------
 
 // CreateEntry godoc
@@ -387,10 +444,10 @@ func (h *Handler) CreateEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get current user ID from context
-	authorUserID := auth.GetUserIDFromContext(r.Context())
+	// The author is the current user, with the roles of the request
+	author := requesterFromContext(r.Context())
 
-	result, err := h.service.CreateEntry(r.Context(), ticketID, &req, authorUserID)
+	result, err := h.service.CreateEntry(r.Context(), ticketID, &req, author)
 	if err != nil {
 		if errors.Is(err, ErrTicketNotFound) {
 			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Ticket not found")
@@ -405,7 +462,7 @@ func (h *Handler) CreateEntry(w http.ResponseWriter, r *http.Request) {
 
 // GetEntryByID godoc
 // @Summary      Get entry by ID
-// @Description  Retrieves detailed entry information including tags and references
+// @Description  Retrieves detailed entry information including tags and references. Entries of tickets not visible to the current user (see GET /tickets) are not found.
 // @Tags         entries
 // @Accept       json
 // @Produce      json
@@ -422,7 +479,7 @@ func (h *Handler) GetEntryByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.service.GetEntryByID(r.Context(), entryID)
+	result, err := h.service.GetEntryByID(r.Context(), entryID, requesterFromContext(r.Context()))
 	if err != nil {
 		if errors.Is(err, ErrEntryNotFound) {
 			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Entry not found")
@@ -462,7 +519,7 @@ func (h *Handler) UpdateEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.service.UpdateEntry(r.Context(), entryID, &req)
+	result, err := h.service.UpdateEntry(r.Context(), entryID, requesterFromContext(r.Context()), &req)
 	if err != nil {
 		if errors.Is(err, ErrEntryNotFound) {
 			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Entry not found")
@@ -494,7 +551,7 @@ func (h *Handler) DeleteEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.DeleteEntry(r.Context(), entryID)
+	err = h.service.DeleteEntry(r.Context(), entryID, requesterFromContext(r.Context()))
 	if err != nil {
 		if errors.Is(err, ErrEntryNotFound) {
 			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Entry not found")
@@ -534,7 +591,7 @@ func (h *Handler) AddTagsToEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.AddTagsToEntry(r.Context(), entryID, &req)
+	err = h.service.AddTagsToEntry(r.Context(), entryID, requesterFromContext(r.Context()), &req)
 	if err != nil {
 		if errors.Is(err, ErrEntryNotFound) {
 			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Entry not found")
@@ -573,7 +630,7 @@ func (h *Handler) RemoveTagFromEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.RemoveTagFromEntry(r.Context(), entryID, tagID)
+	err = h.service.RemoveTagFromEntry(r.Context(), entryID, requesterFromContext(r.Context()), tagID)
 	if err != nil {
 		if errors.Is(err, ErrEntryNotFound) {
 			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Entry not found")
@@ -626,7 +683,7 @@ func (h *Handler) ListTags(w http.ResponseWriter, r *http.Request) {
 
 // CreateTag godoc
 // @Summary      Create a new tag
-// @Description  Creates a new tag
+// @Description  Creates a new tag. Confidential tags hide tickets from users without the tickets:read:confidential permission, except their requester and assignee.
 // @Tags         tags
 // @Accept       json
 // @Produce      json
@@ -691,7 +748,7 @@ func (h *Handler) GetTagByID(w http.ResponseWriter, r *http.Request) {
 
 // UpdateTag godoc
 // @Summary      Update tag
-// @Description  Updates an existing tag
+// @Description  Updates an existing tag. Changing is_confidential requires the tickets:read:confidential permission.
 // @Tags         tags
 // @Accept       json
 // @Produce      json
@@ -699,6 +756,7 @@ func (h *Handler) GetTagByID(w http.ResponseWriter, r *http.Request) {
 // @Param        request  body      UpdateTagRequest  true  "Tag data to update"
 // @Success      200      {object}  TagResponse
 // @Failure      400      {object}  ErrorResponse
+// @Failure      403      {object}  ErrorResponse  "Changing is_confidential without tickets:read:confidential"
 // @Failure      404      {object}  ErrorResponse
 // @Failure      500      {object}  ErrorResponse
 // @Security     BearerAuth
@@ -716,12 +774,16 @@ func (h *Handler) UpdateTag(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.service.UpdateTag(r.Context(), tagID, &req)
+	result, err := h.service.UpdateTag(r.Context(), tagID, requesterFromContext(r.Context()), &req)
 	if err != nil {
 		if errors.Is(err, ErrTagNotFound) {
 			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Tag not found")
 			return
 		}
+		if errors.Is(err, ErrConfidentialTag) {
+			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "Not allowed to change whether tags are confidential")
+			return
+		}
 		utils.RespondInternalError(w, r, err, "Internal server error")
 		return
 	}
@@ -731,12 +793,13 @@ func (h *Handler) UpdateTag(w http.ResponseWriter, r *http.Request) {
 
 // DeleteTag godoc
 // @Summary      Delete tag
-// @Description  Performs a soft delete on a tag
+// @Description  Performs a soft delete on a tag. Deleting a confidential tag requires the tickets:read:confidential permission.
 // @Tags         tags
 // @Accept       json
 // @Produce      json
 // @Param        id   path      int  true  "Tag ID"
 // @Success      200  {object}  SuccessResponse
+// @Failure      403  {object}  ErrorResponse  "Confidential tag without tickets:read:confidential"
 // @Failure      404  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Security     BearerAuth
@@ -748,12 +811,16 @@ func (h *Handler) DeleteTag(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.DeleteTag(r.Context(), tagID)
+	err = h.service.DeleteTag(r.Context(), tagID, requesterFromContext(r.Context()))
 	if err != nil {
 		if errors.Is(err, ErrTagNotFound) {
 			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "Tag not found")
 			return
 		}
+		if errors.Is(err, ErrConfidentialTag) {
+			utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "Not allowed to delete confidential tags")
+			return
+		}
 		utils.RespondInternalError(w, r, err, "Internal server error")
 		return
 	}
@@ -762,3 +829,12 @@ func (h *Handler) DeleteTag(w http.ResponseWriter, r *http.Request) {
 }
 
 // -------------------- Helper Functions --------------------
+
+// requesterFromContext returns the authenticated user of a request with the roles of its access
+// token or API key, rather than all roles the user holds
+func requesterFromContext(ctx context.Context) Requester {
+	return Requester{
+		UserID: auth.GetUserIDFromContext(ctx),
+		Roles:  auth.GetUserRolesFromContext(ctx),
+	}
+}
@@ -3,108 +3,142 @@ package tickets
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"kc-api/internal/auth"
 )
 
 // MockService is a mock implementation of the Service interface for testing
 type MockService struct {
-	CreateTicketFunc        func(ctx context.Context, req *CreateTicketRequest, authorUserPublicID string) (*TicketDetailResponse, error)
-	GetTicketByIDFunc       func(ctx context.Context, publicID string) (*TicketDetailResponse, error)
-	ListTicketsFunc         func(ctx context.Context, page, limit int) (*TicketListResponseWrapper, error)
-	UpdateTicketFunc        func(ctx context.Context, publicID string, req *UpdateTicketRequest) (*TicketListResponse, error)
-	DeleteTicketFunc        func(ctx context.Context, publicID string) error
-	SearchTicketsFunc       func(ctx context.Context, criteria *SearchTicketRequest, page, limit int) (*TicketListResponseWrapper, error)
-	CreateEntryFunc         func(ctx context.Context, ticketPublicID string, req *CreateEntryRequest, authorUserPublicID string) (*EntryDetailResponse, error)
-	GetEntryByIDFunc        func(ctx context.Context, entryID int64) (*EntryDetailResponse, error)
-	UpdateEntryFunc         func(ctx context.Context, entryID int64, req *UpdateEntryRequest) (*EntryListResponse, error)
-	DeleteEntryFunc         func(ctx context.Context, entryID int64) error
-	CreateTagFunc           func(ctx context.Context, req *CreateTagRequest) (*TagResponse, error)
-	GetTagByIDFunc          func(ctx context.Context, tagID int64) (*TagResponse, error)
-	ListTagsFunc            func(ctx context.Context, page, limit int) (*TagListResponseWrapper, error)
-	UpdateTagFunc           func(ctx context.Context, tagID int64, req *UpdateTagRequest) (*TagResponse, error)
-	DeleteTagFunc           func(ctx context.Context, tagID int64) error
-	AddTagsToTicketFunc     func(ctx context.Context, ticketPublicID string, req *AddTagRequest) error
-	RemoveTagFromTicketFunc func(ctx context.Context, ticketPublicID string, tagID int64) error
-	AddTagsToEntryFunc      func(ctx context.Context, entryID int64, req *AddTagRequest) error
-	RemoveTagFromEntryFunc  func(ctx context.Context, entryID int64, tagID int64) error
-}
-
-func (m *MockService) CreateTicket(ctx context.Context, req *CreateTicketRequest, authorUserPublicID string) (*TicketDetailResponse, error) {
+	CreateTicketFunc          func(ctx context.Context, req *CreateTicketRequest, author Requester) (*TicketDetailResponse, error)
+	GetTicketByIDFunc         func(ctx context.Context, publicID string, requester Requester) (*TicketDetailResponse, error)
+	ListTicketsFunc           func(ctx context.Context, requester Requester, page, limit int) (*TicketListResponseWrapper, error)
+	UpdateTicketFunc          func(ctx context.Context, publicID string, requester Requester, req *UpdateTicketRequest) (*TicketListResponse, error)
+	DeleteTicketFunc          func(ctx context.Context, publicID string, requester Requester) error
+	SearchTicketsFunc         func(ctx context.Context, requester Requester, criteria *SearchTicketRequest, page, limit int) (*TicketListResponseWrapper, error)
+	CreateEntryFunc           func(ctx context.Context, ticketPublicID string, req *CreateEntryRequest, author Requester) (*EntryDetailResponse, error)
+	GetEntryByIDFunc          func(ctx context.Context, entryID int64, requester Requester) (*EntryDetailResponse, error)
+	UpdateEntryFunc           func(ctx context.Context, entryID int64, requester Requester, req *UpdateEntryRequest) (*EntryListResponse, error)
+	DeleteEntryFunc           func(ctx context.Context, entryID int64, requester Requester) error
+	ListAttachmentFileIDsFunc func(ctx context.Context, ticketPublicID string, requester Requester) ([]string, error)
+	CanReadAttachmentFunc     func(ctx context.Context, filePublicID string, userPublicID string) (bool, error)
+	CreateTagFunc             func(ctx context.Context, req *CreateTagRequest) (*TagResponse, error)
+	GetTagByIDFunc            func(ctx context.Context, tagID int64) (*TagResponse, error)
+	ListTagsFunc              func(ctx context.Context, page, limit int) (*TagListResponseWrapper, error)
+	UpdateTagFunc             func(ctx context.Context, tagID int64, requester Requester, req *UpdateTagRequest) (*TagResponse, error)
+	DeleteTagFunc             func(ctx context.Context, tagID int64, requester Requester) error
+	AddTagsToTicketFunc       func(ctx context.Context, ticketPublicID string, requester Requester, req *AddTagRequest) error
+	RemoveTagFromTicketFunc   func(ctx context.Context, ticketPublicID string, requester Requester, tagID int64) error
+	AddTagsToEntryFunc        func(ctx context.Context, entryID int64, requester Requester, req *AddTagRequest) error
+	RemoveTagFromEntryFunc    func(ctx context.Context, entryID int64, requester Requester, tagID int64) error
+}
+
+func (m *MockService) CreateTicket(ctx context.Context, req *CreateTicketRequest, author Requester) (*TicketDetailResponse, error) {
 	if m.CreateTicketFunc != nil {
-		return m.CreateTicketFunc(ctx, req, authorUserPublicID)
+		return m.CreateTicketFunc(ctx, req, author)
 	}
 	return nil, nil
 }
 
-func (m *MockService) GetTicketByID(ctx context.Context, publicID string) (*TicketDetailResponse, error) {
+func (m *MockService) GetTicketByID(ctx context.Context, publicID string, requester Requester) (*TicketDetailResponse, error) {
 	if m.GetTicketByIDFunc != nil {
-		return m.GetTicketByIDFunc(ctx, publicID)
+		return m.GetTicketByIDFunc(ctx, publicID, requester)
 	}
 	return nil, nil
 }
 
-func (m *MockService) ListTickets(ctx context.Context, page, limit int) (*TicketListResponseWrapper, error) {
+func (m *MockService) ListTickets(ctx context.Context, requester Requester, page, limit int) (*TicketListResponseWrapper, error) {
 	if m.ListTicketsFunc != nil {
-		return m.ListTicketsFunc(ctx, page, limit)
+		return m.ListTicketsFunc(ctx, requester, page, limit)
 	}
 	return nil, nil
 }
 
-func (m *MockService) UpdateTicket(ctx context.Context, publicID string, req *UpdateTicketRequest) (*TicketListResponse, error) {
+func (m *MockService) UpdateTicket(ctx context.Context, publicID string, requester Requester, req *UpdateTicketRequest) (*TicketListResponse, error) {
 	if m.UpdateTicketFunc != nil {
-		return m.UpdateTicketFunc(ctx, publicID, req)
+		return m.UpdateTicketFunc(ctx, publicID, requester, req)
 	}
 	return nil, nil
 }
 
-func (m *MockService) DeleteTicket(ctx context.Context, publicID string) error {
+func (m *MockService) DeleteTicket(ctx context.Context, publicID string, requester Requester) error {
 	if m.DeleteTicketFunc != nil {
-		return m.DeleteTicketFunc(ctx, publicID)
+		return m.DeleteTicketFunc(ctx, publicID, requester)
 	}
 	return nil
 }
 
-func (m *MockService) SearchTickets(ctx context.Context, criteria *SearchTicketRequest, page, limit int) (*TicketListResponseWrapper, error) {
+func (m *MockService) SearchTickets(ctx context.Context, requester Requester, criteria *SearchTicketRequest, page, limit int) (*TicketListResponseWrapper, error) {
 	if m.SearchTicketsFunc != nil {
-		return m.SearchTicketsFunc(ctx, criteria, page, limit)
+		return m.SearchTicketsFunc(ctx, requester, criteria, page, limit)
 	}
 	return nil, nil
 }
 
-func (m *MockService) CreateEntry(ctx context.Context, ticketPublicID string, req *CreateEntryRequest, authorUserPublicID string) (*EntryDetailResponse, error) {
+func (m *MockService) CreateEntry(ctx context.Context, ticketPublicID string, req *CreateEntryRequest, author Requester) (*EntryDetailResponse, error) {
 	if m.CreateEntryFunc != nil {
-		return m.CreateEntryFunc(ctx, ticketPublicID, req, authorUserPublicID)
+		return m.CreateEntryFunc(ctx, ticketPublicID, req, author)
 	}
 	return nil, nil
 }
 
-func (m *MockService) GetEntryByID(ctx context.Context, entryID int64) (*EntryDetailResponse, error) {
+func (m *MockService) GetEntryByID(ctx context.Context, entryID int64, requester Requester) (*EntryDetailResponse, error) {
 	if m.GetEntryByIDFunc != nil {
-		return m.GetEntryByIDFunc(ctx, entryID)
+		return m.GetEntryByIDFunc(ctx, entryID, requester)
 	}
 	return nil, nil
 }
 
-func (m *MockService) UpdateEntry(ctx context.Context, entryID int64, req *UpdateEntryRequest) (*EntryListResponse, error) {
+func (m *MockService) UpdateEntry(ctx context.Context, entryID int64, requester Requester, req *UpdateEntryRequest) (*EntryListResponse, error) {
 	if m.UpdateEntryFunc != nil {
-		return m.UpdateEntryFunc(ctx, entryID, req)
+		return m.UpdateEntryFunc(ctx, entryID, requester, req)
 	}
 	return nil, nil
 }
 
-func (m *MockService) DeleteEntry(ctx context.Context, entryID int64) error {
+func (m *MockService) DeleteEntry(ctx context.Context, entryID int64, requester Requester) error {
 	if m.DeleteEntryFunc != nil {
-		return m.DeleteEntryFunc(ctx, entryID)
+		return m.DeleteEntryFunc(ctx, entryID, requester)
 	}
 	return nil
 }
 
+func (m *MockService) ListAttachmentFileIDs(ctx context.Context, ticketPublicID string, requester Requester) ([]string, error) {
+	if m.ListAttachmentFileIDsFunc != nil {
+		return m.ListAttachmentFileIDsFunc(ctx, ticketPublicID, requester)
+	}
+	return nil, nil
+}
+
+func (m *MockService) CanReadAttachment(ctx context.Context, filePublicID string, userPublicID string) (bool, error) {
+	if m.CanReadAttachmentFunc != nil {
+		return m.CanReadAttachmentFunc(ctx, filePublicID, userPublicID)
+	}
+	return false, nil
+}
+
+// MockArchiver records the files it was asked to archive
+type MockArchiver struct {
+	FileIDs  []string
+	Filename string
+}
+
+func (m *MockArchiver) ServeArchive(w http.ResponseWriter, r *http.Request, fileIDs []string, filename string) {
+	m.FileIDs = fileIDs
+	m.Filename = filename
+	w.Header().Set("Content-Type", "application/zip")
+	w.WriteHeader(http.StatusOK)
+}
+
 func (m *MockService) CreateTag(ctx context.Context, req *CreateTagRequest) (*TagResponse, error) {
 	if m.CreateTagFunc != nil {
 		return m.CreateTagFunc(ctx, req)
@@ -126,44 +160,44 @@ func (m *MockService) ListTags(ctx context.Context, page, limit int) (*TagListRe
 	return nil, nil
 }
 
-func (m *MockService) UpdateTag(ctx context.Context, tagID int64, req *UpdateTagRequest) (*TagResponse, error) {
+func (m *MockService) UpdateTag(ctx context.Context, tagID int64, requester Requester, req *UpdateTagRequest) (*TagResponse, error) {
 	if m.UpdateTagFunc != nil {
-		return m.UpdateTagFunc(ctx, tagID, req)
+		return m.UpdateTagFunc(ctx, tagID, requester, req)
 	}
 	return nil, nil
 }
 
-func (m *MockService) DeleteTag(ctx context.Context, tagID int64) error {
+func (m *MockService) DeleteTag(ctx context.Context, tagID int64, requester Requester) error {
 	if m.DeleteTagFunc != nil {
-		return m.DeleteTagFunc(ctx, tagID)
+		return m.DeleteTagFunc(ctx, tagID, requester)
 	}
 	return nil
 }
 
-func (m *MockService) AddTagsToTicket(ctx context.Context, ticketPublicID string, req *AddTagRequest) error {
+func (m *MockService) AddTagsToTicket(ctx context.Context, ticketPublicID string, requester Requester, req *AddTagRequest) error {
 	if m.AddTagsToTicketFunc != nil {
-		return m.AddTagsToTicketFunc(ctx, ticketPublicID, req)
+		return m.AddTagsToTicketFunc(ctx, ticketPublicID, requester, req)
 	}
 	return nil
 }
 
-func (m *MockService) RemoveTagFromTicket(ctx context.Context, ticketPublicID string, tagID int64) error {
+func (m *MockService) RemoveTagFromTicket(ctx context.Context, ticketPublicID string, requester Requester, tagID int64) error {
 	if m.RemoveTagFromTicketFunc != nil {
-		return m.RemoveTagFromTicketFunc(ctx, ticketPublicID, tagID)
+		return m.RemoveTagFromTicketFunc(ctx, ticketPublicID, requester, tagID)
 	}
 	return nil
 }
 
-func (m *MockService) AddTagsToEntry(ctx context.Context, entryID int64, req *AddTagRequest) error {
+func (m *MockService) AddTagsToEntry(ctx context.Context, entryID int64, requester Requester, req *AddTagRequest) error {
 	if m.AddTagsToEntryFunc != nil {
-		return m.AddTagsToEntryFunc(ctx, entryID, req)
+		return m.AddTagsToEntryFunc(ctx, entryID, requester, req)
 	}
 	return nil
 }
 
-func (m *MockService) RemoveTagFromEntry(ctx context.Context, entryID int64, tagID int64) error {
+func (m *MockService) RemoveTagFromEntry(ctx context.Context, entryID int64, requester Requester, tagID int64) error {
 	if m.RemoveTagFromEntryFunc != nil {
-		return m.RemoveTagFromEntryFunc(ctx, entryID, tagID)
+		return m.RemoveTagFromEntryFunc(ctx, entryID, requester, tagID)
 	}
 	return nil
 }
@@ -218,12 +252,12 @@ func TestHandler_ListTickets(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockService := &MockService{
-				ListTicketsFunc: func(ctx context.Context, page, limit int) (*TicketListResponseWrapper, error) {
+				ListTicketsFunc: func(ctx context.Context, requester Requester, page, limit int) (*TicketListResponseWrapper, error) {
 					return tt.mockReturn, tt.mockError
 				},
 			}
 
-			handler := NewHandler(mockService)
+			handler := NewHandler(mockService, nil)
 			r := chi.NewRouter()
 			handler.RegisterRoutes(r)
 
@@ -295,12 +329,12 @@ func TestHandler_CreateTicket(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockService := &MockService{
-				CreateTicketFunc: func(ctx context.Context, req *CreateTicketRequest, authorUserPublicID string) (*TicketDetailResponse, error) {
+				CreateTicketFunc: func(ctx context.Context, req *CreateTicketRequest, author Requester) (*TicketDetailResponse, error) {
 					return tt.mockReturn, tt.mockError
 				},
 			}
 
-			handler := NewHandler(mockService)
+			handler := NewHandler(mockService, nil)
 			r := chi.NewRouter()
 			handler.RegisterRoutes(r)
 
@@ -362,12 +396,12 @@ func TestHandler_GetTicketByID(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockService := &MockService{
-				GetTicketByIDFunc: func(ctx context.Context, publicID string) (*TicketDetailResponse, error) {
+				GetTicketByIDFunc: func(ctx context.Context, publicID string, requester Requester) (*TicketDetailResponse, error) {
 					return tt.mockReturn, tt.mockError
 				},
 			}
 
-			handler := NewHandler(mockService)
+			handler := NewHandler(mockService, nil)
 			r := chi.NewRouter()
 			handler.RegisterRoutes(r)
 
@@ -435,12 +469,12 @@ func TestHandler_UpdateTicket(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockService := &MockService{
-				UpdateTicketFunc: func(ctx context.Context, publicID string, req *UpdateTicketRequest) (*TicketListResponse, error) {
+				UpdateTicketFunc: func(ctx context.Context, publicID string, requester Requester, req *UpdateTicketRequest) (*TicketListResponse, error) {
 					return tt.mockReturn, tt.mockError
 				},
 			}
 
-			handler := NewHandler(mockService)
+			handler := NewHandler(mockService, nil)
 			r := chi.NewRouter()
 			handler.RegisterRoutes(r)
 
@@ -488,12 +522,12 @@ func TestHandler_DeleteTicket(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockService := &MockService{
-				DeleteTicketFunc: func(ctx context.Context, publicID string) error {
+				DeleteTicketFunc: func(ctx context.Context, publicID string, requester Requester) error {
 					return tt.mockError
 				},
 			}
 
-			handler := NewHandler(mockService)
+			handler := NewHandler(mockService, nil)
 			r := chi.NewRouter()
 			handler.RegisterRoutes(r)
 
@@ -509,6 +543,192 @@ func TestHandler_DeleteTicket(t *testing.T) {
 	}
 }
 
+func TestHandler_DownloadAttachments(t *testing.T) {
+	tests := []struct {
+		name           string
+		ticketID       string
+		mockReturn     []string
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "successful download",
+			ticketID:       "01912345-6789-7abc-def0-123456789abc",
+			mockReturn:     []string{"01912345-6789-7abc-def0-000000000001", "01912345-6789-7abc-def0-000000000002"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "no attachments",
+			ticketID:       "01912345-6789-7abc-def0-123456789abc",
+			mockReturn:     []string{},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "ticket not found",
+			ticketID:       "non-existent-id",
+			mockError:      ErrTicketNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockService{
+				ListAttachmentFileIDsFunc: func(ctx context.Context, ticketPublicID string, requester Requester) ([]string, error) {
+					return tt.mockReturn, tt.mockError
+				},
+			}
+			archiver := &MockArchiver{}
+
+			handler := NewHandler(mockService, archiver)
+			r := chi.NewRouter()
+			handler.RegisterRoutes(r)
+
+			req := httptest.NewRequest(http.MethodGet, "/tickets/"+tt.ticketID+"/attachments.zip", nil)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedStatus == http.StatusOK && len(archiver.FileIDs) != len(tt.mockReturn) {
+				t.Errorf("expected %d files to be archived, got %d", len(tt.mockReturn), len(archiver.FileIDs))
+			}
+		})
+	}
+}
+
+func TestAttachmentFileID(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    string
+	}{
+		{name: "file id", payload: `{"file_id":"01912345-6789-7abc-def0-123456789abc"}`, want: "01912345-6789-7abc-def0-123456789abc"},
+		{name: "download url", payload: `{"file_url":"/files/01912345-6789-7abc-def0-123456789abc/download","file_name":"a.pdf"}`, want: "01912345-6789-7abc-def0-123456789abc"},
+		{name: "absolute url", payload: `{"file_url":"https://kc.example.com/files/01912345-6789-7abc-def0-123456789abc"}`, want: "01912345-6789-7abc-def0-123456789abc"},
+		{name: "external url", payload: `{"file_url":"https://example.com/a.pdf"}`, want: ""},
+		{name: "not an object", payload: `"a.pdf"`, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := attachmentFileID(json.RawMessage(tt.payload)); got != tt.want {
+				t.Errorf("attachmentFileID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestViewer_Grant(t *testing.T) {
+	tests := []struct {
+		name             string
+		roles            []string
+		permissions      []string
+		readAll          bool
+		readDepartment   bool
+		readConfidential bool
+	}{
+		{name: "requester", roles: []string{"user"}},
+		{name: "agent", roles: []string{"agent"}, permissions: []string{PermissionReadDepartment, "tickets:update"}, readDepartment: true},
+		{name: "auditor", roles: []string{"auditor"}, permissions: []string{PermissionReadAll, PermissionReadConfidential}, readAll: true, readConfidential: true},
+		{name: "full access", roles: []string{"full_access"}, readAll: true, readDepartment: true, readConfidential: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viewer := &Viewer{Roles: tt.roles}
+			viewer.grant(tt.permissions)
+			if viewer.ReadAll != tt.readAll || viewer.ReadDepartment != tt.readDepartment || viewer.ReadConfidential != tt.readConfidential {
+				t.Errorf("grant() = all %v, department %v, confidential %v", viewer.ReadAll, viewer.ReadDepartment, viewer.ReadConfidential)
+			}
+		})
+	}
+}
+
+func TestViewer_Filter(t *testing.T) {
+	tests := []struct {
+		name         string
+		viewer       Viewer
+		argIndex     int
+		contains     []string
+		notContains  []string
+		expectedArgs int
+	}{
+		{
+			name:         "unrestricted",
+			viewer:       Viewer{UserID: 7, ReadAll: true, ReadConfidential: true},
+			argIndex:     1,
+			contains:     []string{"TRUE"},
+			notContains:  []string{"requester_user_id"},
+			expectedArgs: 0,
+		},
+		{
+			name:         "requester only sees own tickets",
+			viewer:       Viewer{UserID: 7, DeptID: sql.NullInt64{Int64: 3, Valid: true}},
+			argIndex:     2,
+			contains:     []string{"t.requester_user_id = $2", "t.assigned_user_id = $2"},
+			notContains:  []string{"dept_id", "is_confidential"},
+			expectedArgs: 1,
+		},
+		{
+			name:         "agent sees department without confidential tickets",
+			viewer:       Viewer{UserID: 7, DeptID: sql.NullInt64{Int64: 3, Valid: true}, ReadDepartment: true},
+			argIndex:     1,
+			contains:     []string{"t.requester_user_id = $1", "t.dept_id = ANY($2)", "NOT EXISTS", "cg.is_confidential"},
+			expectedArgs: 2,
+		},
+		{
+			name:         "manager with confidential access sees subtree",
+			viewer:       Viewer{UserID: 7, ManagedDeptIDs: []int64{4, 5}, ReadConfidential: true},
+			argIndex:     3,
+			contains:     []string{"t.dept_id = ANY($4)"},
+			notContains:  []string{"is_confidential"},
+			expectedArgs: 2,
+		},
+		{
+			name:         "all tickets without confidential access",
+			viewer:       Viewer{UserID: 7, ReadAll: true},
+			argIndex:     1,
+			contains:     []string{"t.requester_user_id = $1", "NOT EXISTS"},
+			notContains:  []string{"dept_id"},
+			expectedArgs: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, args := tt.viewer.filter("t", tt.argIndex)
+			for _, want := range tt.contains {
+				if !strings.Contains(condition, want) {
+					t.Errorf("expected condition to contain %q, got %s", want, condition)
+				}
+			}
+			for _, unwanted := range tt.notContains {
+				if strings.Contains(condition, unwanted) {
+					t.Errorf("expected condition not to contain %q, got %s", unwanted, condition)
+				}
+			}
+			if len(args) != tt.expectedArgs {
+				t.Errorf("expected %d args, got %d", tt.expectedArgs, len(args))
+			}
+		})
+	}
+}
+
+func TestViewer_Departments(t *testing.T) {
+	viewer := Viewer{DeptID: sql.NullInt64{Int64: 4, Valid: true}, ManagedDeptIDs: []int64{9, 4, 6}}
+	if depts := viewer.departments(); !slices.Equal(depts, []int64{4, 6, 9}) {
+		t.Errorf("expected managed departments only once, got %v", depts)
+	}
+
+	viewer = Viewer{DeptID: sql.NullInt64{Int64: 4, Valid: true}, ManagedDeptIDs: []int64{6}, ReadDepartment: true}
+	if depts := viewer.departments(); !slices.Equal(depts, []int64{4, 6}) {
+		t.Errorf("expected own and managed departments, got %v", depts)
+	}
+}
+
 func TestHandler_SearchTickets(t *testing.T) {
 	now := time.Now()
 	query := "bug"
@@ -559,12 +779,12 @@ func TestHandler_SearchTickets(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockService := &MockService{
-				SearchTicketsFunc: func(ctx context.Context, criteria *SearchTicketRequest, page, limit int) (*TicketListResponseWrapper, error) {
+				SearchTicketsFunc: func(ctx context.Context, requester Requester, criteria *SearchTicketRequest, page, limit int) (*TicketListResponseWrapper, error) {
 					return tt.mockReturn, tt.mockError
 				},
 			}
 
-			handler := NewHandler(mockService)
+			handler := NewHandler(mockService, nil)
 			r := chi.NewRouter()
 			handler.RegisterRoutes(r)
 
@@ -606,16 +826,16 @@ func TestHandler_CreateEntry(t *testing.T) {
 				Body:      ptrString("New comment"),
 			},
 			mockReturn: &EntryDetailResponse{
-				ID:        1,
-				TicketID:  "01912345-6789-7abc-def0-123456789abc",
-				EntryType: EntryTypeComment,
-				Format:    ContentFormatNone,
-				Body:      ptrString("New comment"),
-				Payload:   json.RawMessage("{}"),
-				Tags:      []TagResponse{},
+				ID:         1,
+				TicketID:   "01912345-6789-7abc-def0-123456789abc",
+				EntryType:  EntryTypeComment,
+				Format:     ContentFormatNone,
+				Body:       ptrString("New comment"),
+				Payload:    json.RawMessage("{}"),
+				Tags:       []TagResponse{},
 				References: []ReferenceResponse{},
-				CreatedAt: now,
-				UpdatedAt: now,
+				CreatedAt:  now,
+				UpdatedAt:  now,
 			},
 			mockError:      nil,
 			expectedStatus: http.StatusCreated,
@@ -635,12 +855,12 @@ func TestHandler_CreateEntry(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockService := &MockService{
-				CreateEntryFunc: func(ctx context.Context, ticketPublicID string, req *CreateEntryRequest, authorUserPublicID string) (*EntryDetailResponse, error) {
+				CreateEntryFunc: func(ctx context.Context, ticketPublicID string, req *CreateEntryRequest, author Requester) (*EntryDetailResponse, error) {
 					return tt.mockReturn, tt.mockError
 				},
 			}
 
-			handler := NewHandler(mockService)
+			handler := NewHandler(mockService, nil)
 			r := chi.NewRouter()
 			handler.RegisterRoutes(r)
 
@@ -677,16 +897,16 @@ func TestHandler_GetEntryByID(t *testing.T) {
 			name:    "successful get",
 			entryID: "1",
 			mockReturn: &EntryDetailResponse{
-				ID:        1,
-				TicketID:  "01912345-6789-7abc-def0-123456789abc",
-				EntryType: EntryTypeComment,
-				Format:    ContentFormatMarkdown,
-				Body:      ptrString("Test entry"),
-				Payload:   json.RawMessage("{}"),
-				Tags:      []TagResponse{},
+				ID:         1,
+				TicketID:   "01912345-6789-7abc-def0-123456789abc",
+				EntryType:  EntryTypeComment,
+				Format:     ContentFormatMarkdown,
+				Body:       ptrString("Test entry"),
+				Payload:    json.RawMessage("{}"),
+				Tags:       []TagResponse{},
 				References: []ReferenceResponse{},
-				CreatedAt: now,
-				UpdatedAt: now,
+				CreatedAt:  now,
+				UpdatedAt:  now,
 			},
 			mockError:      nil,
 			expectedStatus: http.StatusOK,
@@ -710,12 +930,12 @@ func TestHandler_GetEntryByID(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockService := &MockService{
-				GetEntryByIDFunc: func(ctx context.Context, entryID int64) (*EntryDetailResponse, error) {
+				GetEntryByIDFunc: func(ctx context.Context, entryID int64, requester Requester) (*EntryDetailResponse, error) {
 					return tt.mockReturn, tt.mockError
 				},
 			}
 
-			handler := NewHandler(mockService)
+			handler := NewHandler(mockService, nil)
 			r := chi.NewRouter()
 			handler.RegisterRoutes(r)
 
@@ -768,7 +988,7 @@ func TestHandler_ListTags(t *testing.T) {
 				},
 			}
 
-			handler := NewHandler(mockService)
+			handler := NewHandler(mockService, nil)
 			r := chi.NewRouter()
 			handler.RegisterRoutes(r)
 
@@ -825,7 +1045,7 @@ func TestHandler_CreateTag(t *testing.T) {
 				},
 			}
 
-			handler := NewHandler(mockService)
+			handler := NewHandler(mockService, nil)
 			r := chi.NewRouter()
 			handler.RegisterRoutes(r)
 
@@ -875,17 +1095,26 @@ func TestHandler_AddTagsToTicket(t *testing.T) {
 			mockError:      ErrTicketNotFound,
 			expectedStatus: http.StatusNotFound,
 		},
+		{
+			name:     "confidential tag",
+			ticketID: "01912345-6789-7abc-def0-123456789abc",
+			requestBody: AddTagRequest{
+				TagIDs: []int64{1, 7},
+			},
+			mockError:      ErrConfidentialTag,
+			expectedStatus: http.StatusForbidden,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockService := &MockService{
-				AddTagsToTicketFunc: func(ctx context.Context, ticketPublicID string, req *AddTagRequest) error {
+				AddTagsToTicketFunc: func(ctx context.Context, ticketPublicID string, requester Requester, req *AddTagRequest) error {
 					return tt.mockError
 				},
 			}
 
-			handler := NewHandler(mockService)
+			handler := NewHandler(mockService, nil)
 			r := chi.NewRouter()
 			handler.RegisterRoutes(r)
 
@@ -938,17 +1167,24 @@ func TestHandler_RemoveTagFromTicket(t *testing.T) {
 			mockError:      ErrTagNotFound,
 			expectedStatus: http.StatusNotFound,
 		},
+		{
+			name:           "confidential tag",
+			ticketID:       "01912345-6789-7abc-def0-123456789abc",
+			tagID:          "7",
+			mockError:      ErrConfidentialTag,
+			expectedStatus: http.StatusForbidden,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockService := &MockService{
-				RemoveTagFromTicketFunc: func(ctx context.Context, ticketPublicID string, tagID int64) error {
+				RemoveTagFromTicketFunc: func(ctx context.Context, ticketPublicID string, requester Requester, tagID int64) error {
 					return tt.mockError
 				},
 			}
 
-			handler := NewHandler(mockService)
+			handler := NewHandler(mockService, nil)
 			r := chi.NewRouter()
 			handler.RegisterRoutes(r)
 
@@ -964,7 +1200,172 @@ func TestHandler_RemoveTagFromTicket(t *testing.T) {
 	}
 }
 
+// fakeTagRepository knows which tags are confidential. Repository methods the tag checks
+// don't use are left to the nil embedded interface.
+type fakeTagRepository struct {
+	Repository
+	confidential []int64
+}
+
+func (r *fakeTagRepository) HasConfidentialTag(ctx context.Context, tagIDs []int64) (bool, error) {
+	for _, tagID := range tagIDs {
+		if slices.Contains(r.confidential, tagID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeTagRepository) GetTagByID(ctx context.Context, tagID int64) (*Tag, error) {
+	return &Tag{ID: tagID, Name: "tag", IsConfidential: slices.Contains(r.confidential, tagID)}, nil
+}
+
+func (r *fakeTagRepository) UpdateTag(ctx context.Context, tagID int64, tag *Tag) error {
+	return nil
+}
+
+func (r *fakeTagRepository) GetViewer(ctx context.Context, userPublicID string) (*Viewer, error) {
+	return &Viewer{UserID: 1}, nil
+}
+
+func TestService_CheckConfidentialTags(t *testing.T) {
+	s := &service{repo: &fakeTagRepository{confidential: []int64{7}}}
+
+	tests := []struct {
+		name    string
+		viewer  *Viewer
+		tagIDs  []int64
+		wantErr error
+	}{
+		{name: "regular tags", viewer: &Viewer{}, tagIDs: []int64{1, 2}},
+		{name: "confidential tag", viewer: &Viewer{}, tagIDs: []int64{1, 7}, wantErr: ErrConfidentialTag},
+		{name: "confidential tag with permission", viewer: &Viewer{ReadConfidential: true}, tagIDs: []int64{7}},
+		{name: "no tags", viewer: &Viewer{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.checkConfidentialTags(context.Background(), tt.viewer, tt.tagIDs); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestService_UpdateTagConfidential(t *testing.T) {
+	s := &service{repo: &fakeTagRepository{confidential: []int64{7}}}
+
+	tests := []struct {
+		name     string
+		tagID    int64
+		roles    []string
+		req      UpdateTagRequest
+		wantErr  error
+	}{
+		{name: "mark confidential", tagID: 1, req: UpdateTagRequest{IsConfidential: ptrBool(true)}, wantErr: ErrConfidentialTag},
+		{name: "unmark confidential", tagID: 7, req: UpdateTagRequest{IsConfidential: ptrBool(false)}, wantErr: ErrConfidentialTag},
+		{name: "unchanged flag", tagID: 7, req: UpdateTagRequest{Name: ptrString("renamed"), IsConfidential: ptrBool(true)}},
+		{name: "with permission", tagID: 1, roles: []string{"full_access"}, req: UpdateTagRequest{IsConfidential: ptrBool(true)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.UpdateTag(context.Background(), tt.tagID, Requester{UserID: "user", Roles: tt.roles}, &tt.req); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// fakeViewerRepository records the viewer tickets are listed for. Repository methods the
+// listing doesn't use are left to the nil embedded interface.
+type fakeViewerRepository struct {
+	Repository
+	viewer *Viewer
+}
+
+func (r *fakeViewerRepository) GetViewer(ctx context.Context, userPublicID string) (*Viewer, error) {
+	return &Viewer{UserID: 1, DeptID: sql.NullInt64{Int64: 3, Valid: true}}, nil
+}
+
+func (r *fakeViewerRepository) ListTickets(ctx context.Context, viewer *Viewer, page, limit int) ([]Ticket, int, error) {
+	r.viewer = viewer
+	return nil, 0, nil
+}
+
+// fakePermissions grants the permissions of each role
+type fakePermissions map[string][]string
+
+func (p fakePermissions) EffectivePermissions(roles []string) []string {
+	var permissions []string
+	for _, role := range roles {
+		permissions = append(permissions, p[role]...)
+	}
+	return permissions
+}
+
+func TestService_ListTicketsRequestRoles(t *testing.T) {
+	permissions := fakePermissions{"support": {PermissionReadAll, PermissionReadConfidential}, "viewer": {}}
+
+	tests := []struct {
+		name       string
+		roles      []string
+		wantFilter string
+	}{
+		// The user holds support, but the scoped API key only carries viewer
+		{name: "scoped key", roles: []string{"viewer"}, wantFilter: "(t.requester_user_id = $1 OR t.assigned_user_id = $1)"},
+		{name: "full key", roles: []string{"viewer", "support"}, wantFilter: "TRUE"},
+		{name: "no roles", wantFilter: "(t.requester_user_id = $1 OR t.assigned_user_id = $1)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeViewerRepository{}
+			s := NewService(repo, permissions)
+
+			if _, err := s.ListTickets(context.Background(), Requester{UserID: "user", Roles: tt.roles}, 1, 10); err != nil {
+				t.Fatalf("list failed: %v", err)
+			}
+			if filter, _ := repo.viewer.filter("t", 1); filter != tt.wantFilter {
+				t.Errorf("expected filter %q, got %q", tt.wantFilter, filter)
+			}
+		})
+	}
+}
+
+func TestHandler_ListTicketsRequestRoles(t *testing.T) {
+	var got Requester
+	mockService := &MockService{
+		ListTicketsFunc: func(ctx context.Context, requester Requester, page, limit int) (*TicketListResponseWrapper, error) {
+			got = requester
+			return &TicketListResponseWrapper{Data: []TicketListResponse{}}, nil
+		},
+	}
+
+	handler := NewHandler(mockService, nil)
+	r := chi.NewRouter()
+	handler.RegisterRoutes(r)
+
+	req := httptest.NewRequest(http.MethodGet, "/tickets", nil)
+	req = req.WithContext(auth.SetUserRolesInContext(req.Context(), []string{"viewer"}))
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if !slices.Equal(got.Roles, []string{"viewer"}) {
+		t.Errorf("expected the roles of the request, got %v", got.Roles)
+	}
+}
+
 // Helper function to create string pointers
 func ptrString(s string) *string {
 	return &s
 }
+
+// Helper function to create bool pointers
+func ptrBool(b bool) *bool {
+	return &b
+}
@@ -60,16 +60,18 @@ const (
 
 // Ticket represents the internal ticket entity in the database
 type Ticket struct {
-	ID             int64          `json:"-"`
-	PublicID       string         `json:"id"`
-	Title          string         `json:"title"`
-	AssignedUserID sql.NullInt64  `json:"-"`
-	Status         TicketStatus   `json:"status"`
-	Priority       TicketPriority `json:"priority"`
-	RequestType    TicketRequestType `json:"request_type"`
-	DueDate        sql.NullTime   `json:"-"`
-	CreatedAt      time.Time      `json:"-"`
-	UpdatedAt      time.Time      `json:"-"`
+	ID              int64             `json:"-"`
+	PublicID        string            `json:"id"`
+	Title           string            `json:"title"`
+	RequesterUserID sql.NullInt64     `json:"-"`
+	DeptID          sql.NullInt64     `json:"-"`
+	AssignedUserID  sql.NullInt64     `json:"-"`
+	Status          TicketStatus      `json:"status"`
+	Priority        TicketPriority    `json:"priority"`
+	RequestType     TicketRequestType `json:"request_type"`
+	DueDate         sql.NullTime      `json:"-"`
+	CreatedAt       time.Time         `json:"-"`
+	UpdatedAt       time.Time         `json:"-"`
 }
 
 // TicketEntry represents the internal ticket entry entity in the database
@@ -89,12 +91,13 @@ type TicketEntry struct {
 
 // Tag represents the internal tag entity in the database
 type Tag struct {
-	ID        int64          `json:"-"`
-	Name      string         `json:"name"`
-	ColorCode sql.NullString `json:"-"`
-	IsDeleted bool           `json:"-"`
-	CreatedAt time.Time      `json:"-"`
-	UpdatedAt time.Time      `json:"-"`
+	ID             int64          `json:"-"`
+	Name           string         `json:"name"`
+	ColorCode      sql.NullString `json:"-"`
+	IsConfidential bool           `json:"-"`
+	IsDeleted      bool           `json:"-"`
+	CreatedAt      time.Time      `json:"-"`
+	UpdatedAt      time.Time      `json:"-"`
 }
 
 // EntryReference represents a reference from an entry to other entities
@@ -188,6 +191,10 @@ type TagResponse struct {
 	Name      string  `json:"name" example:"urgent"`
 	ColorCode *string `json:"color_code,omitempty" example:"#FF0000"`
 	Category  *string `json:"category,omitempty" example:"priority"`
+
+	// IsConfidential restricts tickets with the tag to their requester, their assignee and
+	// users with the tickets:read:confidential permission
+	IsConfidential bool `json:"is_confidential" example:"false"`
 }
 
 // ReferenceResponse represents a reference response
@@ -245,14 +252,16 @@ type UpdateEntryRequest struct {
 
 // CreateTagRequest represents the request body for creating a tag
 type CreateTagRequest struct {
-	Name      string  `json:"name" example:"urgent"`
-	ColorCode *string `json:"color_code,omitempty" example:"#FF0000"`
+	Name           string  `json:"name" example:"urgent"`
+	ColorCode      *string `json:"color_code,omitempty" example:"#FF0000"`
+	IsConfidential bool    `json:"is_confidential,omitempty" example:"false"`
 }
 
 // UpdateTagRequest represents the request body for updating a tag
 type UpdateTagRequest struct {
-	Name      *string `json:"name,omitempty" example:"very-urgent"`
-	ColorCode *string `json:"color_code,omitempty" example:"#FF5500"`
+	Name           *string `json:"name,omitempty" example:"very-urgent"`
+	ColorCode      *string `json:"color_code,omitempty" example:"#FF5500"`
+	IsConfidential *bool   `json:"is_confidential,omitempty" example:"true"`
 }
 
 // CreateReferenceRequest represents a reference to be created
@@ -351,9 +360,10 @@ func (e *TicketEntry) ToListResponse() EntryListResponse {
 // ToResponse converts a Tag to TagResponse
 func (t *Tag) ToResponse(category *string) TagResponse {
 	resp := TagResponse{
-		ID:       t.ID,
-		Name:     t.Name,
-		Category: category,
+		ID:             t.ID,
+		Name:           t.Name,
+		Category:       category,
+		IsConfidential: t.IsConfidential,
 	}
 	if t.ColorCode.Valid {
 		resp.ColorCode = &t.ColorCode.String
@@ -0,0 +1,97 @@
+package tickets
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/lib/pq"
+)
+
+// Named permissions widening the tickets a user can see. Without them, users see the tickets
+// they requested or are assigned to, and the tickets of the departments they lead.
+const (
+	PermissionReadAll          = "tickets:read:all"          // every ticket
+	PermissionReadDepartment   = "tickets:read:department"   // tickets of the user's department
+	PermissionReadConfidential = "tickets:read:confidential" // tickets with a confidential tag in scope
+)
+
+// PermissionResolver expands roles into the named permissions they grant. It is implemented by
+// the RBAC permission manager.
+type PermissionResolver interface {
+	EffectivePermissions(roles []string) []string
+}
+
+// Requester is the authenticated user a request is made for, with the roles of its credentials.
+// An API key or impersonation token may carry fewer roles than the user holds.
+type Requester struct {
+	UserID string
+	Roles  []string
+}
+
+// Viewer is the user tickets are read for, with the attributes the visibility policy depends on
+type Viewer struct {
+	UserID int64
+	DeptID sql.NullInt64
+	Roles  []string
+
+	// ManagedDeptIDs are the departments the user leads and all their descendants
+	ManagedDeptIDs []int64
+
+	ReadAll          bool
+	ReadDepartment   bool
+	ReadConfidential bool
+}
+
+// grant applies the permissions granted by the roles of the viewer. full_access sees every ticket.
+func (v *Viewer) grant(permissions []string) {
+	if slices.Contains(v.Roles, "full_access") {
+		v.ReadAll, v.ReadDepartment, v.ReadConfidential = true, true, true
+		return
+	}
+	v.ReadAll = slices.Contains(permissions, PermissionReadAll)
+	v.ReadDepartment = slices.Contains(permissions, PermissionReadDepartment)
+	v.ReadConfidential = slices.Contains(permissions, PermissionReadConfidential)
+}
+
+// departments returns the departments whose tickets the viewer can see
+func (v *Viewer) departments() []int64 {
+	depts := slices.Clone(v.ManagedDeptIDs)
+	if v.ReadDepartment && v.DeptID.Valid && !slices.Contains(depts, v.DeptID.Int64) {
+		depts = append(depts, v.DeptID.Int64)
+	}
+	slices.Sort(depts)
+	return depts
+}
+
+// filter returns the SQL condition selecting the tickets visible to the viewer from the tickets
+// table aliased as alias, with its arguments numbered from argIndex. Requesters and assignees
+// always see their tickets; a confidential tag hides tickets otherwise in scope unless the viewer
+// may read confidential tickets.
+func (v *Viewer) filter(alias string, argIndex int) (string, []interface{}) {
+	if v.ReadAll && v.ReadConfidential {
+		return "TRUE", nil
+	}
+
+	own := fmt.Sprintf("%[1]s.requester_user_id = $%[2]d OR %[1]s.assigned_user_id = $%[2]d", alias, argIndex)
+	args := []interface{}{v.UserID}
+
+	var scope string
+	if v.ReadAll {
+		scope = "TRUE"
+	} else if depts := v.departments(); len(depts) > 0 {
+		scope = fmt.Sprintf("%s.dept_id = ANY($%d)", alias, argIndex+1)
+		args = append(args, pq.Array(depts))
+	} else {
+		return "(" + own + ")", args
+	}
+
+	if !v.ReadConfidential {
+		scope += fmt.Sprintf(` AND NOT EXISTS (
+			SELECT 1 FROM ticket_systems.ticket_tags ct
+			INNER JOIN ticket_systems.tags cg ON cg.id = ct.tag_id
+			WHERE ct.ticket_id = %s.id AND cg.is_confidential AND cg.is_deleted = false)`, alias)
+	}
+
+	return fmt.Sprintf("(%s OR (%s))", own, scope), args
+}
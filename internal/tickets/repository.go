@@ -6,32 +6,39 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+
+	"github.com/lib/pq"
 )
 
 // Repository defines the interface for ticket data access operations
 type Repository interface {
 	// Ticket operations
 	CreateTicket(ctx context.Context, ticket *Ticket) error
-	GetTicketByPublicID(ctx context.Context, publicID string) (*Ticket, error)
-	GetTicketDetailByPublicID(ctx context.Context, publicID string) (*TicketDetailResponse, error)
-	ListTickets(ctx context.Context, page, limit int) ([]Ticket, int, error)
+	GetTicketByPublicID(ctx context.Context, publicID string, viewer *Viewer) (*Ticket, error)
+	GetTicketDetailByPublicID(ctx context.Context, publicID string, viewer *Viewer) (*TicketDetailResponse, error)
+	ListTickets(ctx context.Context, viewer *Viewer, page, limit int) ([]Ticket, int, error)
 	UpdateTicket(ctx context.Context, publicID string, ticket *Ticket) error
 	DeleteTicket(ctx context.Context, publicID string) error
-	SearchTickets(ctx context.Context, criteria *SearchTicketRequest, page, limit int) ([]Ticket, int, error)
+	SearchTickets(ctx context.Context, viewer *Viewer, criteria *SearchTicketRequest, page, limit int) ([]Ticket, int, error)
 	GetTicketInternalID(ctx context.Context, publicID string) (int64, error)
+	GetVisibleTicketID(ctx context.Context, publicID string, viewer *Viewer) (int64, error)
 	GetUserInternalID(ctx context.Context, publicID string) (int64, error)
+	GetViewer(ctx context.Context, userPublicID string) (*Viewer, error)
 
 	// Entry operations
 	CreateEntry(ctx context.Context, entry *TicketEntry) error
-	GetEntryByID(ctx context.Context, entryID int64) (*TicketEntry, error)
-	GetEntryDetailByID(ctx context.Context, entryID int64) (*EntryDetailResponse, error)
+	GetEntryByID(ctx context.Context, entryID int64, viewer *Viewer) (*TicketEntry, error)
+	GetEntryDetailByID(ctx context.Context, entryID int64, viewer *Viewer) (*EntryDetailResponse, error)
 	ListEntriesByTicketID(ctx context.Context, ticketID int64) ([]EntryListResponse, error)
+	ListFilePayloadsByTicketID(ctx context.Context, ticketID int64) ([]json.RawMessage, error)
+	ListTicketIDsByAttachment(ctx context.Context, filePublicID string, viewer *Viewer) ([]int64, error)
 	UpdateEntry(ctx context.Context, entryID int64, entry *TicketEntry) error
 	DeleteEntry(ctx context.Context, entryID int64) error
 
 	// Tag operations
 	CreateTag(ctx context.Context, tag *Tag) error
 	GetTagByID(ctx context.Context, tagID int64) (*Tag, error)
+	HasConfidentialTag(ctx context.Context, tagIDs []int64) (bool, error)
 	ListTags(ctx context.Context, page, limit int) ([]Tag, int, error)
 	UpdateTag(ctx context.Context, tagID int64, tag *Tag) error
 	DeleteTag(ctx context.Context, tagID int64) error
@@ -66,31 +73,35 @@ func NewRepository(db *sql.DB) Repository {
 func (r *repository) CreateTicket(ctx context.Context, ticket *Ticket) error {
 	query := `
 		INSERT INTO ticket_systems.tickets (
-			title, assigned_user_id, status, priority, request_type, due_date
-		) VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, public_id, created_at, updated_at`
+			title, requester_user_id, dept_id, assigned_user_id, status, priority, request_type, due_date
+		) VALUES ($1, $2, (SELECT dept_id FROM organizations.users WHERE id = $2), $3, $4, $5, $6, $7)
+		RETURNING id, public_id, dept_id, created_at, updated_at`
 
 	return r.db.QueryRowContext(ctx, query,
 		ticket.Title,
+		ticket.RequesterUserID,
 		ticket.AssignedUserID,
 		ticket.Status,
 		ticket.Priority,
 		ticket.RequestType,
 		ticket.DueDate,
-	).Scan(&ticket.ID, &ticket.PublicID, &ticket.CreatedAt, &ticket.UpdatedAt)
+	).Scan(&ticket.ID, &ticket.PublicID, &ticket.DeptID, &ticket.CreatedAt, &ticket.UpdatedAt)
 }
 
-func (r *repository) GetTicketByPublicID(ctx context.Context, publicID string) (*Ticket, error) {
+func (r *repository) GetTicketByPublicID(ctx context.Context, publicID string, viewer *Viewer) (*Ticket, error) {
+	visible, args := viewer.filter("t", 2)
 	query := `
-		SELECT id, public_id, title, assigned_user_id, status, priority, request_type, due_date, created_at, updated_at
-		FROM ticket_systems.tickets
-		WHERE public_id = $1`
+		SELECT t.id, t.public_id, t.title, t.requester_user_id, t.dept_id, t.assigned_user_id, t.status, t.priority, t.request_type, t.due_date, t.created_at, t.updated_at
+		FROM ticket_systems.tickets t
+		WHERE t.public_id = $1 AND ` + visible
 
 	ticket := &Ticket{}
-	err := r.db.QueryRowContext(ctx, query, publicID).Scan(
+	err := r.db.QueryRowContext(ctx, query, append([]interface{}{publicID}, args...)...).Scan(
 		&ticket.ID,
 		&ticket.PublicID,
 		&ticket.Title,
+		&ticket.RequesterUserID,
+		&ticket.DeptID,
 		&ticket.AssignedUserID,
 		&ticket.Status,
 		&ticket.Priority,
@@ -105,21 +116,22 @@ func (r *repository) GetTicketByPublicID(ctx context.Context, publicID string) (
 	return ticket, nil
 }
 
-func (r *repository) GetTicketDetailByPublicID(ctx context.Context, publicID string) (*TicketDetailResponse, error) {
+func (r *repository) GetTicketDetailByPublicID(ctx context.Context, publicID string, viewer *Viewer) (*TicketDetailResponse, error) {
+	visible, args := viewer.filter("t", 2)
 	query := `
 		SELECT
 			t.id, t.public_id, t.title, t.status, t.priority, t.request_type, t.due_date, t.created_at, t.updated_at,
 			u.public_id, u.name
 		FROM ticket_systems.tickets t
 		LEFT JOIN organizations.users u ON t.assigned_user_id = u.id
-		WHERE t.public_id = $1`
+		WHERE t.public_id = $1 AND ` + visible
 
 	var ticketID int64
 	var assignedUserPublicID sql.NullString
 	var assignedUserName sql.NullString
 	detail := &TicketDetailResponse{}
 
-	err := r.db.QueryRowContext(ctx, query, publicID).Scan(
+	err := r.db.QueryRowContext(ctx, query, append([]interface{}{publicID}, args...)...).Scan(
 		&ticketID,
 		&detail.ID,
 		&detail.Title,
@@ -160,22 +172,24 @@ func (r *repository) GetTicketDetailByPublicID(ctx context.Context, publicID str
 	return detail, nil
 }
 
-func (r *repository) ListTickets(ctx context.Context, page, limit int) ([]Ticket, int, error) {
+func (r *repository) ListTickets(ctx context.Context, viewer *Viewer, page, limit int) ([]Ticket, int, error) {
 	offset := (page - 1) * limit
+	visible, args := viewer.filter("t", 1)
 
 	var totalCount int
-	countQuery := `SELECT COUNT(*) FROM ticket_systems.tickets`
-	if err := r.db.QueryRowContext(ctx, countQuery).Scan(&totalCount); err != nil {
+	countQuery := `SELECT COUNT(*) FROM ticket_systems.tickets t WHERE ` + visible
+	if err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&totalCount); err != nil {
 		return nil, 0, err
 	}
 
-	query := `
-		SELECT id, public_id, title, assigned_user_id, status, priority, request_type, due_date, created_at, updated_at
-		FROM ticket_systems.tickets
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2`
+	query := fmt.Sprintf(`
+		SELECT t.id, t.public_id, t.title, t.requester_user_id, t.dept_id, t.assigned_user_id, t.status, t.priority, t.request_type, t.due_date, t.created_at, t.updated_at
+		FROM ticket_systems.tickets t
+		WHERE %s
+		ORDER BY t.created_at DESC
+		LIMIT $%d OFFSET $%d`, visible, len(args)+1, len(args)+2)
 
-	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, append(args, limit, offset)...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -188,6 +202,8 @@ func (r *repository) ListTickets(ctx context.Context, page, limit int) ([]Ticket
 			&ticket.ID,
 			&ticket.PublicID,
 			&ticket.Title,
+			&ticket.RequesterUserID,
+			&ticket.DeptID,
 			&ticket.AssignedUserID,
 			&ticket.Status,
 			&ticket.Priority,
@@ -258,12 +274,13 @@ func (r *repository) DeleteTicket(ctx context.Context, publicID string) error {
 	return nil
 }
 
-func (r *repository) SearchTickets(ctx context.Context, criteria *SearchTicketRequest, page, limit int) ([]Ticket, int, error) {
+func (r *repository) SearchTickets(ctx context.Context, viewer *Viewer, criteria *SearchTicketRequest, page, limit int) ([]Ticket, int, error) {
 	offset := (page - 1) * limit
 
-	var conditions []string
-	var args []interface{}
-	argIndex := 1
+	// Only tickets visible to the viewer are searched
+	visible, args := viewer.filter("t", 1)
+	conditions := []string{visible}
+	argIndex := len(args) + 1
 
 	if criteria.Query != nil && *criteria.Query != "" {
 		conditions = append(conditions, fmt.Sprintf("title ILIKE $%d", argIndex))
@@ -319,11 +336,6 @@ func (r *repository) SearchTickets(ctx context.Context, criteria *SearchTicketRe
 		argIndex++
 	}
 
-	whereClause := ""
-	if len(conditions) > 0 {
-		whereClause = "WHERE " + strings.Join(conditions, " AND ")
-	}
-
 	// Handle tag filtering with subquery
 	if len(criteria.TagIDs) > 0 {
 		tagPlaceholders := make([]string, len(criteria.TagIDs))
@@ -332,23 +344,20 @@ func (r *repository) SearchTickets(ctx context.Context, criteria *SearchTicketRe
 			args = append(args, tagID)
 			argIndex++
 		}
-		tagCondition := fmt.Sprintf("id IN (SELECT ticket_id FROM ticket_systems.ticket_tags WHERE tag_id IN (%s))", strings.Join(tagPlaceholders, ", "))
-		if whereClause == "" {
-			whereClause = "WHERE " + tagCondition
-		} else {
-			whereClause += " AND " + tagCondition
-		}
+		conditions = append(conditions, fmt.Sprintf("id IN (SELECT ticket_id FROM ticket_systems.ticket_tags WHERE tag_id IN (%s))", strings.Join(tagPlaceholders, ", ")))
 	}
 
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM ticket_systems.tickets %s", whereClause)
+	whereClause := "WHERE " + strings.Join(conditions, " AND ")
+
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM ticket_systems.tickets t %s", whereClause)
 	var totalCount int
 	if err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&totalCount); err != nil {
 		return nil, 0, err
 	}
 
 	dataQuery := fmt.Sprintf(`
-		SELECT id, public_id, title, assigned_user_id, status, priority, request_type, due_date, created_at, updated_at
-		FROM ticket_systems.tickets
+		SELECT id, public_id, title, requester_user_id, dept_id, assigned_user_id, status, priority, request_type, due_date, created_at, updated_at
+		FROM ticket_systems.tickets t
 		%s
 		ORDER BY created_at DESC
 		LIMIT $%d OFFSET $%d`, whereClause, argIndex, argIndex+1)
@@ -368,6 +377,8 @@ func (r *repository) SearchTickets(ctx context.Context, criteria *SearchTicketRe
 			&ticket.ID,
 			&ticket.PublicID,
 			&ticket.Title,
+			&ticket.RequesterUserID,
+			&ticket.DeptID,
 			&ticket.AssignedUserID,
 			&ticket.Status,
 			&ticket.Priority,
@@ -391,6 +402,47 @@ func (r *repository) GetTicketInternalID(ctx context.Context, publicID string) (
 	return id, err
 }
 
+// GetVisibleTicketID returns the internal ID of a ticket visible to the viewer, or sql.ErrNoRows
+func (r *repository) GetVisibleTicketID(ctx context.Context, publicID string, viewer *Viewer) (int64, error) {
+	visible, args := viewer.filter("t", 2)
+	var id int64
+	query := `SELECT t.id FROM ticket_systems.tickets t WHERE t.public_id = $1 AND ` + visible
+	err := r.db.QueryRowContext(ctx, query, append([]interface{}{publicID}, args...)...).Scan(&id)
+	return id, err
+}
+
+// GetViewer loads the attributes of a user the ticket visibility depends on: the department and
+// the departments the user leads together with all their descendants. The roles and permissions
+// are not set; they come from the authenticated request.
+func (r *repository) GetViewer(ctx context.Context, userPublicID string) (*Viewer, error) {
+	query := `
+		SELECT u.id, u.dept_id,
+			COALESCE(ARRAY(
+				WITH RECURSIVE managed AS (
+					SELECT d.id FROM organizations.departments d
+					WHERE d.leader_user_id = u.id AND d.is_deleted = false
+					UNION
+					SELECT d.id FROM organizations.departments d
+					INNER JOIN managed m ON d.parent_department_id = m.id
+					WHERE d.is_deleted = false
+				)
+				SELECT id FROM managed ORDER BY id
+			), '{}')
+		FROM organizations.users u
+		WHERE u.public_id = $1`
+
+	viewer := &Viewer{}
+	err := r.db.QueryRowContext(ctx, query, userPublicID).Scan(
+		&viewer.UserID,
+		&viewer.DeptID,
+		pq.Array(&viewer.ManagedDeptIDs),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return viewer, nil
+}
+
 func (r *repository) GetUserInternalID(ctx context.Context, publicID string) (int64, error) {
 	var id int64
 	query := `SELECT id FROM organizations.users WHERE public_id = $1`
@@ -418,14 +470,16 @@ func (r *repository) CreateEntry(ctx context.Context, entry *TicketEntry) error
 	).Scan(&entry.ID, &entry.CreatedAt, &entry.UpdatedAt)
 }
 
-func (r *repository) GetEntryByID(ctx context.Context, entryID int64) (*TicketEntry, error) {
+func (r *repository) GetEntryByID(ctx context.Context, entryID int64, viewer *Viewer) (*TicketEntry, error) {
+	visible, args := viewer.filter("t", 2)
 	query := `
-		SELECT id, ticket_id, author_user_id, parent_entry_id, entry_type, format, body, payload, is_deleted, created_at, updated_at
-		FROM ticket_systems.ticket_entries
-		WHERE id = $1 AND is_deleted = false`
+		SELECT e.id, e.ticket_id, e.author_user_id, e.parent_entry_id, e.entry_type, e.format, e.body, e.payload, e.is_deleted, e.created_at, e.updated_at
+		FROM ticket_systems.ticket_entries e
+		JOIN ticket_systems.tickets t ON e.ticket_id = t.id
+		WHERE e.id = $1 AND e.is_deleted = false AND ` + visible
 
 	entry := &TicketEntry{}
-	err := r.db.QueryRowContext(ctx, query, entryID).Scan(
+	err := r.db.QueryRowContext(ctx, query, append([]interface{}{entryID}, args...)...).Scan(
 		&entry.ID,
 		&entry.TicketID,
 		&entry.AuthorUserID,
@@ -444,7 +498,8 @@ func (r *repository) GetEntryByID(ctx context.Context, entryID int64) (*TicketEn
 	return entry, nil
 }
 
-func (r *repository) GetEntryDetailByID(ctx context.Context, entryID int64) (*EntryDetailResponse, error) {
+func (r *repository) GetEntryDetailByID(ctx context.Context, entryID int64, viewer *Viewer) (*EntryDetailResponse, error) {
+	visible, args := viewer.filter("t", 2)
 	query := `
 		SELECT
 			e.id, t.public_id, e.entry_type, e.format, e.body, e.payload, e.parent_entry_id, e.created_at, e.updated_at,
@@ -452,7 +507,7 @@ func (r *repository) GetEntryDetailByID(ctx context.Context, entryID int64) (*En
 		FROM ticket_systems.ticket_entries e
 		JOIN ticket_systems.tickets t ON e.ticket_id = t.id
 		LEFT JOIN organizations.users u ON e.author_user_id = u.id
-		WHERE e.id = $1 AND e.is_deleted = false`
+		WHERE e.id = $1 AND e.is_deleted = false AND ` + visible
 
 	var authorUserPublicID sql.NullString
 	var authorUserName sql.NullString
@@ -460,7 +515,7 @@ func (r *repository) GetEntryDetailByID(ctx context.Context, entryID int64) (*En
 	var parentEntryID sql.NullInt64
 	detail := &EntryDetailResponse{}
 
-	err := r.db.QueryRowContext(ctx, query, entryID).Scan(
+	err := r.db.QueryRowContext(ctx, query, append([]interface{}{entryID}, args...)...).Scan(
 		&detail.ID,
 		&detail.TicketID,
 		&detail.EntryType,
@@ -564,6 +619,63 @@ func (r *repository) ListEntriesByTicketID(ctx context.Context, ticketID int64)
 	return entries, rows.Err()
 }
 
+func (r *repository) ListFilePayloadsByTicketID(ctx context.Context, ticketID int64) ([]json.RawMessage, error) {
+	query := `
+		SELECT payload
+		FROM ticket_systems.ticket_entries
+		WHERE ticket_id = $1 AND entry_type = $2 AND is_deleted = false AND payload IS NOT NULL
+		ORDER BY created_at ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, ticketID, EntryTypeFile)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payloads []json.RawMessage
+	for rows.Next() {
+		var payload json.RawMessage
+		if err := rows.Scan(&payload); err != nil {
+			return nil, err
+		}
+		payloads = append(payloads, payload)
+	}
+
+	return payloads, rows.Err()
+}
+
+// ListTicketIDsByAttachment returns the tickets visible to the viewer with a FILE entry referencing
+// the file, either by "file_id" or by a "file_url" pointing at /files/{id}. Only entries written by
+// the uploader of the file count, so that attaching someone else's file grants no access to it.
+func (r *repository) ListTicketIDsByAttachment(ctx context.Context, filePublicID string, viewer *Viewer) ([]int64, error) {
+	visible, args := viewer.filter("t", 3)
+	query := `
+		SELECT DISTINCT e.ticket_id
+		FROM ticket_systems.ticket_entries e
+		JOIN ticket_systems.tickets t ON e.ticket_id = t.id
+		JOIN managements.files f ON f.public_id::text = $2 AND f.uploaded_by = e.author_user_id
+		WHERE e.entry_type = $1 AND e.is_deleted = false
+		  AND (e.payload->>'file_id' = $2 OR e.payload->>'file_url' ~ ('/files/' || $2 || '(/|$)'))
+		  AND ` + visible
+
+	rows, err := r.db.QueryContext(ctx, query, append([]interface{}{EntryTypeFile, filePublicID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ticketIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ticketIDs = append(ticketIDs, id)
+	}
+
+	return ticketIDs, rows.Err()
+}
+
 func (r *repository) UpdateEntry(ctx context.Context, entryID int64, entry *TicketEntry) error {
 	query := `
 		UPDATE ticket_systems.ticket_entries SET
@@ -616,19 +728,20 @@ func (r *repository) DeleteEntry(ctx context.Context, entryID int64) error {
 
 func (r *repository) CreateTag(ctx context.Context, tag *Tag) error {
 	query := `
-		INSERT INTO ticket_systems.tags (name, color_code)
-		VALUES ($1, $2)
+		INSERT INTO ticket_systems.tags (name, color_code, is_confidential)
+		VALUES ($1, $2, $3)
 		RETURNING id, created_at, updated_at`
 
 	return r.db.QueryRowContext(ctx, query,
 		tag.Name,
 		tag.ColorCode,
+		tag.IsConfidential,
 	).Scan(&tag.ID, &tag.CreatedAt, &tag.UpdatedAt)
 }
 
 func (r *repository) GetTagByID(ctx context.Context, tagID int64) (*Tag, error) {
 	query := `
-		SELECT id, name, color_code, is_deleted, created_at, updated_at
+		SELECT id, name, color_code, is_confidential, is_deleted, created_at, updated_at
 		FROM ticket_systems.tags
 		WHERE id = $1 AND is_deleted = false`
 
@@ -637,6 +750,7 @@ func (r *repository) GetTagByID(ctx context.Context, tagID int64) (*Tag, error)
 		&tag.ID,
 		&tag.Name,
 		&tag.ColorCode,
+		&tag.IsConfidential,
 		&tag.IsDeleted,
 		&tag.CreatedAt,
 		&tag.UpdatedAt,
@@ -647,6 +761,19 @@ func (r *repository) GetTagByID(ctx context.Context, tagID int64) (*Tag, error)
 	return tag, nil
 }
 
+// HasConfidentialTag reports whether any of the tags is confidential
+func (r *repository) HasConfidentialTag(ctx context.Context, tagIDs []int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM ticket_systems.tags
+			WHERE id = ANY($1) AND is_confidential AND is_deleted = false
+		)`
+
+	var confidential bool
+	err := r.db.QueryRowContext(ctx, query, pq.Array(tagIDs)).Scan(&confidential)
+	return confidential, err
+}
+
 func (r *repository) ListTags(ctx context.Context, page, limit int) ([]Tag, int, error) {
 	offset := (page - 1) * limit
 
@@ -657,7 +784,7 @@ func (r *repository) ListTags(ctx context.Context, page, limit int) ([]Tag, int,
 	}
 
 	query := `
-		SELECT id, name, color_code, is_deleted, created_at, updated_at
+		SELECT id, name, color_code, is_confidential, is_deleted, created_at, updated_at
 		FROM ticket_systems.tags
 		WHERE is_deleted = false
 		ORDER BY name ASC
@@ -676,6 +803,7 @@ func (r *repository) ListTags(ctx context.Context, page, limit int) ([]Tag, int,
 			&tag.ID,
 			&tag.Name,
 			&tag.ColorCode,
+			&tag.IsConfidential,
 			&tag.IsDeleted,
 			&tag.CreatedAt,
 			&tag.UpdatedAt,
@@ -692,12 +820,14 @@ func (r *repository) UpdateTag(ctx context.Context, tagID int64, tag *Tag) error
 	query := `
 		UPDATE ticket_systems.tags SET
 			name = $1,
-			color_code = $2
-		WHERE id = $3 AND is_deleted = false`
+			color_code = $2,
+			is_confidential = $3
+		WHERE id = $4 AND is_deleted = false`
 
 	result, err := r.db.ExecContext(ctx, query,
 		tag.Name,
 		tag.ColorCode,
+		tag.IsConfidential,
 		tagID,
 	)
 	if err != nil {
@@ -774,7 +904,7 @@ func (r *repository) RemoveTagFromTicket(ctx context.Context, ticketID int64, ta
 
 func (r *repository) GetTagsByTicketID(ctx context.Context, ticketID int64) ([]TagResponse, error) {
 	query := `
-		SELECT t.id, t.name, t.color_code, tt.category
+		SELECT t.id, t.name, t.color_code, t.is_confidential, tt.category
 		FROM ticket_systems.tags t
 		JOIN ticket_systems.ticket_tags tt ON t.id = tt.tag_id
 		WHERE tt.ticket_id = $1 AND t.is_deleted = false`
@@ -791,7 +921,7 @@ func (r *repository) GetTagsByTicketID(ctx context.Context, ticketID int64) ([]T
 		var colorCode sql.NullString
 		var category sql.NullString
 
-		if err := rows.Scan(&tag.ID, &tag.Name, &colorCode, &category); err != nil {
+		if err := rows.Scan(&tag.ID, &tag.Name, &colorCode, &tag.IsConfidential, &category); err != nil {
 			return nil, err
 		}
 
@@ -848,7 +978,7 @@ func (r *repository) RemoveTagFromEntry(ctx context.Context, entryID int64, tagI
 
 func (r *repository) GetTagsByEntryID(ctx context.Context, entryID int64) ([]TagResponse, error) {
 	query := `
-		SELECT t.id, t.name, t.color_code, et.category
+		SELECT t.id, t.name, t.color_code, t.is_confidential, et.category
 		FROM ticket_systems.tags t
 		JOIN ticket_systems.entry_tags et ON t.id = et.tag_id
 		WHERE et.entry_id = $1 AND t.is_deleted = false`
@@ -865,7 +995,7 @@ func (r *repository) GetTagsByEntryID(ctx context.Context, entryID int64) ([]Tag
 		var colorCode sql.NullString
 		var category sql.NullString
 
-		if err := rows.Scan(&tag.ID, &tag.Name, &colorCode, &category); err != nil {
+		if err := rows.Scan(&tag.ID, &tag.Name, &colorCode, &tag.IsConfidential, &category); err != nil {
 			return nil, err
 		}
 
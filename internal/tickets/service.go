@@ -6,66 +6,123 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"regexp"
+
+	"kc-api/internal/auth"
 )
 
 var (
-	ErrTicketNotFound   = errors.New("ticket not found")
-	ErrEntryNotFound    = errors.New("entry not found")
-	ErrTagNotFound      = errors.New("tag not found")
-	ErrInvalidTitle     = errors.New("title is required")
-	ErrInvalidEntryType = errors.New("entry_type is required")
-	ErrInvalidTagName   = errors.New("tag name is required")
+	ErrTicketNotFound    = errors.New("ticket not found")
+	ErrEntryNotFound     = errors.New("entry not found")
+	ErrTagNotFound       = errors.New("tag not found")
+	ErrInvalidTitle      = errors.New("title is required")
+	ErrInvalidEntryType  = errors.New("entry_type is required")
+	ErrInvalidTagName    = errors.New("tag name is required")
 	ErrReferenceNotFound = errors.New("reference not found")
+	ErrConfidentialTag   = errors.New("confidential tags require the tickets:read:confidential permission")
 )
 
-// Service defines the interface for ticket business logic
+// Service defines the interface for ticket business logic. Tickets and entries are read and
+// changed on behalf of a requester; those the requester cannot see are reported as not found.
 type Service interface {
 	// Ticket operations
-	CreateTicket(ctx context.Context, req *CreateTicketRequest, authorUserPublicID string) (*TicketDetailResponse, error)
-	GetTicketByID(ctx context.Context, publicID string) (*TicketDetailResponse, error)
-	ListTickets(ctx context.Context, page, limit int) (*TicketListResponseWrapper, error)
-	UpdateTicket(ctx context.Context, publicID string, req *UpdateTicketRequest) (*TicketListResponse, error)
-	DeleteTicket(ctx context.Context, publicID string) error
-	SearchTickets(ctx context.Context, criteria *SearchTicketRequest, page, limit int) (*TicketListResponseWrapper, error)
+	CreateTicket(ctx context.Context, req *CreateTicketRequest, author Requester) (*TicketDetailResponse, error)
+	GetTicketByID(ctx context.Context, publicID string, requester Requester) (*TicketDetailResponse, error)
+	ListTickets(ctx context.Context, requester Requester, page, limit int) (*TicketListResponseWrapper, error)
+	UpdateTicket(ctx context.Context, publicID string, requester Requester, req *UpdateTicketRequest) (*TicketListResponse, error)
+	DeleteTicket(ctx context.Context, publicID string, requester Requester) error
+	SearchTickets(ctx context.Context, requester Requester, criteria *SearchTicketRequest, page, limit int) (*TicketListResponseWrapper, error)
 
 	// Entry operations
-	CreateEntry(ctx context.Context, ticketPublicID string, req *CreateEntryRequest, authorUserPublicID string) (*EntryDetailResponse, error)
-	GetEntryByID(ctx context.Context, entryID int64) (*EntryDetailResponse, error)
-	UpdateEntry(ctx context.Context, entryID int64, req *UpdateEntryRequest) (*EntryListResponse, error)
-	DeleteEntry(ctx context.Context, entryID int64) error
+	CreateEntry(ctx context.Context, ticketPublicID string, req *CreateEntryRequest, author Requester) (*EntryDetailResponse, error)
+	GetEntryByID(ctx context.Context, entryID int64, requester Requester) (*EntryDetailResponse, error)
+	UpdateEntry(ctx context.Context, entryID int64, requester Requester, req *UpdateEntryRequest) (*EntryListResponse, error)
+	DeleteEntry(ctx context.Context, entryID int64, requester Requester) error
+	ListAttachmentFileIDs(ctx context.Context, ticketPublicID string, requester Requester) ([]string, error)
+	CanReadAttachment(ctx context.Context, filePublicID string, userPublicID string) (bool, error)
 
 	// Tag operations
 	CreateTag(ctx context.Context, req *CreateTagRequest) (*TagResponse, error)
 	GetTagByID(ctx context.Context, tagID int64) (*TagResponse, error)
 	ListTags(ctx context.Context, page, limit int) (*TagListResponseWrapper, error)
-	UpdateTag(ctx context.Context, tagID int64, req *UpdateTagRequest) (*TagResponse, error)
-	DeleteTag(ctx context.Context, tagID int64) error
+	UpdateTag(ctx context.Context, tagID int64, requester Requester, req *UpdateTagRequest) (*TagResponse, error)
+	DeleteTag(ctx context.Context, tagID int64, requester Requester) error
 
 	// Ticket-Tag operations
-	AddTagsToTicket(ctx context.Context, ticketPublicID string, req *AddTagRequest) error
-	RemoveTagFromTicket(ctx context.Context, ticketPublicID string, tagID int64) error
+	AddTagsToTicket(ctx context.Context, ticketPublicID string, requester Requester, req *AddTagRequest) error
+	RemoveTagFromTicket(ctx context.Context, ticketPublicID string, requester Requester, tagID int64) error
 
 	// Entry-Tag operations
-	AddTagsToEntry(ctx context.Context, entryID int64, req *AddTagRequest) error
-	RemoveTagFromEntry(ctx context.Context, entryID int64, tagID int64) error
+	AddTagsToEntry(ctx context.Context, entryID int64, requester Requester, req *AddTagRequest) error
+	RemoveTagFromEntry(ctx context.Context, entryID int64, requester Requester, tagID int64) error
 }
 
 type service struct {
-	repo Repository
+	repo        Repository
+	permissions PermissionResolver // nil grants no ticket permissions beyond the defaults
+}
+
+// NewService creates a new ticket service with the given repository. The permission resolver
+// expands the roles of viewers into the permissions widening the tickets they can see.
+func NewService(repo Repository, permissions PermissionResolver) Service {
+	return &service{repo: repo, permissions: permissions}
 }
 
-// NewService creates a new ticket service with the given repository
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+// viewer loads the user tickets are read for and applies the permissions granted by the roles
+// of the request, which may be fewer than the user's own
+func (s *service) viewer(ctx context.Context, requester Requester) (*Viewer, error) {
+	viewer, err := s.repo.GetViewer(ctx, requester.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	viewer.Roles = requester.Roles
+
+	var permissions []string
+	if s.permissions != nil {
+		permissions = s.permissions.EffectivePermissions(viewer.Roles)
+	}
+	viewer.grant(permissions)
+
+	return viewer, nil
+}
+
+// visibleTicketID returns the internal ID of a ticket visible to the viewer
+func (s *service) visibleTicketID(ctx context.Context, publicID string, viewer *Viewer) (int64, error) {
+	ticketID, err := s.repo.GetVisibleTicketID(ctx, publicID, viewer)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrTicketNotFound
+		}
+		return 0, fmt.Errorf("failed to get ticket: %w", err)
+	}
+	return ticketID, nil
+}
+
+// visibleEntry returns an entry of a ticket visible to the viewer
+func (s *service) visibleEntry(ctx context.Context, entryID int64, viewer *Viewer) (*TicketEntry, error) {
+	entry, err := s.repo.GetEntryByID(ctx, entryID, viewer)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrEntryNotFound
+		}
+		return nil, fmt.Errorf("failed to get entry: %w", err)
+	}
+	return entry, nil
 }
 
 // -------------------- Ticket Operations --------------------
 
-func (s *service) CreateTicket(ctx context.Context, req *CreateTicketRequest, authorUserPublicID string) (*TicketDetailResponse, error) {
+func (s *service) CreateTicket(ctx context.Context, req *CreateTicketRequest, author Requester) (*TicketDetailResponse, error) {
 	if req.Title == "" {
 		return nil, ErrInvalidTitle
 	}
 
+	// The author is the requester; the ticket belongs to the author's department
+	viewer, err := s.viewer(ctx, author)
+	if err != nil {
+		return nil, err
+	}
+
 	// Set default values
 	status := TicketStatusOpen
 	if req.Status != nil {
@@ -81,10 +138,11 @@ func (s *service) CreateTicket(ctx context.Context, req *CreateTicketRequest, au
 	}
 
 	ticket := &Ticket{
-		Title:       req.Title,
-		Status:      status,
-		Priority:    priority,
-		RequestType: requestType,
+		Title:           req.Title,
+		RequesterUserID: sql.NullInt64{Int64: viewer.UserID, Valid: true},
+		Status:          status,
+		Priority:        priority,
+		RequestType:     requestType,
 	}
 
 	// Handle assigned user
@@ -101,6 +159,10 @@ func (s *service) CreateTicket(ctx context.Context, req *CreateTicketRequest, au
 		ticket.DueDate = sql.NullTime{Time: *req.DueDate, Valid: true}
 	}
 
+	if err := s.checkConfidentialTags(ctx, viewer, req.TagIDs); err != nil {
+		return nil, err
+	}
+
 	// Create ticket
 	if err := s.repo.CreateTicket(ctx, ticket); err != nil {
 		return nil, fmt.Errorf("failed to create ticket: %w", err)
@@ -125,19 +187,11 @@ func (s *service) CreateTicket(ctx context.Context, req *CreateTicketRequest, au
 	}
 
 	entry := &TicketEntry{
-		TicketID:  ticket.ID,
-		EntryType: req.InitialEntry.EntryType,
-		Format:    entryFormat,
-		Payload:   payload,
-	}
-
-	// Set author user ID if provided
-	if authorUserPublicID != "" {
-		authorUserID, err := s.repo.GetUserInternalID(ctx, authorUserPublicID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get author user: %w", err)
-		}
-		entry.AuthorUserID = sql.NullInt64{Int64: authorUserID, Valid: true}
+		TicketID:     ticket.ID,
+		AuthorUserID: sql.NullInt64{Int64: viewer.UserID, Valid: true},
+		EntryType:    req.InitialEntry.EntryType,
+		Format:       entryFormat,
+		Payload:      payload,
 	}
 
 	if req.InitialEntry.Body != nil {
@@ -167,11 +221,16 @@ func (s *service) CreateTicket(ctx context.Context, req *CreateTicketRequest, au
 	}
 
 	// Return detailed response
-	return s.repo.GetTicketDetailByPublicID(ctx, ticket.PublicID)
+	return s.repo.GetTicketDetailByPublicID(ctx, ticket.PublicID, viewer)
 }
 
-func (s *service) GetTicketByID(ctx context.Context, publicID string) (*TicketDetailResponse, error) {
-	detail, err := s.repo.GetTicketDetailByPublicID(ctx, publicID)
+func (s *service) GetTicketByID(ctx context.Context, publicID string, requester Requester) (*TicketDetailResponse, error) {
+	viewer, err := s.viewer(ctx, requester)
+	if err != nil {
+		return nil, err
+	}
+
+	detail, err := s.repo.GetTicketDetailByPublicID(ctx, publicID, viewer)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrTicketNotFound
@@ -181,7 +240,7 @@ func (s *service) GetTicketByID(ctx context.Context, publicID string) (*TicketDe
 	return detail, nil
 }
 
-func (s *service) ListTickets(ctx context.Context, page, limit int) (*TicketListResponseWrapper, error) {
+func (s *service) ListTickets(ctx context.Context, requester Requester, page, limit int) (*TicketListResponseWrapper, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -189,7 +248,12 @@ func (s *service) ListTickets(ctx context.Context, page, limit int) (*TicketList
 		limit = 10
 	}
 
-	tickets, totalCount, err := s.repo.ListTickets(ctx, page, limit)
+	viewer, err := s.viewer(ctx, requester)
+	if err != nil {
+		return nil, err
+	}
+
+	tickets, totalCount, err := s.repo.ListTickets(ctx, viewer, page, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tickets: %w", err)
 	}
@@ -210,8 +274,13 @@ func (s *service) ListTickets(ctx context.Context, page, limit int) (*TicketList
 	}, nil
 }
 
-func (s *service) UpdateTicket(ctx context.Context, publicID string, req *UpdateTicketRequest) (*TicketListResponse, error) {
-	existingTicket, err := s.repo.GetTicketByPublicID(ctx, publicID)
+func (s *service) UpdateTicket(ctx context.Context, publicID string, requester Requester, req *UpdateTicketRequest) (*TicketListResponse, error) {
+	viewer, err := s.viewer(ctx, requester)
+	if err != nil {
+		return nil, err
+	}
+
+	existingTicket, err := s.repo.GetTicketByPublicID(ctx, publicID, viewer)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrTicketNotFound
@@ -254,7 +323,15 @@ func (s *service) UpdateTicket(ctx context.Context, publicID string, req *Update
 	return &response, nil
 }
 
-func (s *service) DeleteTicket(ctx context.Context, publicID string) error {
+func (s *service) DeleteTicket(ctx context.Context, publicID string, requester Requester) error {
+	viewer, err := s.viewer(ctx, requester)
+	if err != nil {
+		return err
+	}
+	if _, err := s.visibleTicketID(ctx, publicID, viewer); err != nil {
+		return err
+	}
+
 	if err := s.repo.DeleteTicket(ctx, publicID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrTicketNotFound
@@ -264,7 +341,7 @@ func (s *service) DeleteTicket(ctx context.Context, publicID string) error {
 	return nil
 }
 
-func (s *service) SearchTickets(ctx context.Context, criteria *SearchTicketRequest, page, limit int) (*TicketListResponseWrapper, error) {
+func (s *service) SearchTickets(ctx context.Context, requester Requester, criteria *SearchTicketRequest, page, limit int) (*TicketListResponseWrapper, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -272,7 +349,12 @@ func (s *service) SearchTickets(ctx context.Context, criteria *SearchTicketReque
 		limit = 10
 	}
 
-	tickets, totalCount, err := s.repo.SearchTickets(ctx, criteria, page, limit)
+	viewer, err := s.viewer(ctx, requester)
+	if err != nil {
+		return nil, err
+	}
+
+	tickets, totalCount, err := s.repo.SearchTickets(ctx, viewer, criteria, page, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search tickets: %w", err)
 	}
@@ -295,13 +377,15 @@ func (s *service) SearchTickets(ctx context.Context, criteria *SearchTicketReque
 
 // -------------------- Entry Operations --------------------
 
-func (s *service) CreateEntry(ctx context.Context, ticketPublicID string, req *CreateEntryRequest, authorUserPublicID string) (*EntryDetailResponse, error) {
-	ticketID, err := s.repo.GetTicketInternalID(ctx, ticketPublicID)
+func (s *service) CreateEntry(ctx context.Context, ticketPublicID string, req *CreateEntryRequest, author Requester) (*EntryDetailResponse, error) {
+	viewer, err := s.viewer(ctx, author)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrTicketNotFound
-		}
-		return nil, fmt.Errorf("failed to get ticket: %w", err)
+		return nil, err
+	}
+
+	ticketID, err := s.visibleTicketID(ctx, ticketPublicID, viewer)
+	if err != nil {
+		return nil, err
 	}
 
 	entryFormat := ContentFormatNone
@@ -315,19 +399,11 @@ func (s *service) CreateEntry(ctx context.Context, ticketPublicID string, req *C
 	}
 
 	entry := &TicketEntry{
-		TicketID:  ticketID,
-		EntryType: req.EntryType,
-		Format:    entryFormat,
-		Payload:   payload,
-	}
-
-	// Set author user ID if provided
-	if authorUserPublicID != "" {
-		authorUserID, err := s.repo.GetUserInternalID(ctx, authorUserPublicID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get author user: %w", err)
-		}
-		entry.AuthorUserID = sql.NullInt64{Int64: authorUserID, Valid: true}
+		TicketID:     ticketID,
+		AuthorUserID: sql.NullInt64{Int64: viewer.UserID, Valid: true},
+		EntryType:    req.EntryType,
+		Format:       entryFormat,
+		Payload:      payload,
 	}
 
 	if req.Body != nil {
@@ -356,11 +432,16 @@ func (s *service) CreateEntry(ctx context.Context, ticketPublicID string, req *C
 		}
 	}
 
-	return s.repo.GetEntryDetailByID(ctx, entry.ID)
+	return s.repo.GetEntryDetailByID(ctx, entry.ID, viewer)
 }
 
-func (s *service) GetEntryByID(ctx context.Context, entryID int64) (*EntryDetailResponse, error) {
-	detail, err := s.repo.GetEntryDetailByID(ctx, entryID)
+func (s *service) GetEntryByID(ctx context.Context, entryID int64, requester Requester) (*EntryDetailResponse, error) {
+	viewer, err := s.viewer(ctx, requester)
+	if err != nil {
+		return nil, err
+	}
+
+	detail, err := s.repo.GetEntryDetailByID(ctx, entryID, viewer)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrEntryNotFound
@@ -370,13 +451,15 @@ func (s *service) GetEntryByID(ctx context.Context, entryID int64) (*EntryDetail
 	return detail, nil
 }
 
-func (s *service) UpdateEntry(ctx context.Context, entryID int64, req *UpdateEntryRequest) (*EntryListResponse, error) {
-	existingEntry, err := s.repo.GetEntryByID(ctx, entryID)
+func (s *service) UpdateEntry(ctx context.Context, entryID int64, requester Requester, req *UpdateEntryRequest) (*EntryListResponse, error) {
+	viewer, err := s.viewer(ctx, requester)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrEntryNotFound
-		}
-		return nil, fmt.Errorf("failed to get entry: %w", err)
+		return nil, err
+	}
+
+	existingEntry, err := s.visibleEntry(ctx, entryID, viewer)
+	if err != nil {
+		return nil, err
 	}
 
 	if req.Format != nil {
@@ -397,7 +480,15 @@ func (s *service) UpdateEntry(ctx context.Context, entryID int64, req *UpdateEnt
 	return &response, nil
 }
 
-func (s *service) DeleteEntry(ctx context.Context, entryID int64) error {
+func (s *service) DeleteEntry(ctx context.Context, entryID int64, requester Requester) error {
+	viewer, err := s.viewer(ctx, requester)
+	if err != nil {
+		return err
+	}
+	if _, err := s.visibleEntry(ctx, entryID, viewer); err != nil {
+		return err
+	}
+
 	if err := s.repo.DeleteEntry(ctx, entryID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrEntryNotFound
@@ -407,6 +498,53 @@ func (s *service) DeleteEntry(ctx context.Context, entryID int64) error {
 	return nil
 }
 
+// ListAttachmentFileIDs returns the public IDs of the files attached to a ticket through FILE entries,
+// oldest first. Payloads are expected to carry a "file_id" or a "file_url" pointing at /files/{id}.
+func (s *service) ListAttachmentFileIDs(ctx context.Context, ticketPublicID string, requester Requester) ([]string, error) {
+	viewer, err := s.viewer(ctx, requester)
+	if err != nil {
+		return nil, err
+	}
+
+	ticketID, err := s.visibleTicketID(ctx, ticketPublicID, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	payloads, err := s.repo.ListFilePayloadsByTicketID(ctx, ticketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+
+	fileIDs := make([]string, 0, len(payloads))
+	for _, payload := range payloads {
+		if fileID := attachmentFileID(payload); fileID != "" {
+			fileIDs = append(fileIDs, fileID)
+		}
+	}
+
+	return fileIDs, nil
+}
+
+// CanReadAttachment reports whether the user can see a ticket the file is attached to, with the
+// roles of the authenticated request in the context
+func (s *service) CanReadAttachment(ctx context.Context, filePublicID string, userPublicID string) (bool, error) {
+	viewer, err := s.viewer(ctx, Requester{UserID: userPublicID, Roles: auth.GetUserRolesFromContext(ctx)})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	ticketIDs, err := s.repo.ListTicketIDsByAttachment(ctx, filePublicID, viewer)
+	if err != nil {
+		return false, fmt.Errorf("failed to find tickets by attachment: %w", err)
+	}
+
+	return len(ticketIDs) > 0, nil
+}
+
 // -------------------- Tag Operations --------------------
 
 func (s *service) CreateTag(ctx context.Context, req *CreateTagRequest) (*TagResponse, error) {
@@ -415,7 +553,8 @@ func (s *service) CreateTag(ctx context.Context, req *CreateTagRequest) (*TagRes
 	}
 
 	tag := &Tag{
-		Name: req.Name,
+		Name:           req.Name,
+		IsConfidential: req.IsConfidential,
 	}
 
 	if req.ColorCode != nil {
@@ -472,7 +611,9 @@ func (s *service) ListTags(ctx context.Context, page, limit int) (*TagListRespon
 	}, nil
 }
 
-func (s *service) UpdateTag(ctx context.Context, tagID int64, req *UpdateTagRequest) (*TagResponse, error) {
+// UpdateTag updates a tag. Only viewers who can read confidential tickets may change whether it is
+// confidential, as that hides or reveals every ticket with the tag.
+func (s *service) UpdateTag(ctx context.Context, tagID int64, requester Requester, req *UpdateTagRequest) (*TagResponse, error) {
 	existingTag, err := s.repo.GetTagByID(ctx, tagID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -481,12 +622,25 @@ func (s *service) UpdateTag(ctx context.Context, tagID int64, req *UpdateTagRequ
 		return nil, fmt.Errorf("failed to get tag: %w", err)
 	}
 
+	if req.IsConfidential != nil && *req.IsConfidential != existingTag.IsConfidential {
+		viewer, err := s.viewer(ctx, requester)
+		if err != nil {
+			return nil, err
+		}
+		if !viewer.ReadConfidential {
+			return nil, ErrConfidentialTag
+		}
+	}
+
 	if req.Name != nil && *req.Name != "" {
 		existingTag.Name = *req.Name
 	}
 	if req.ColorCode != nil {
 		existingTag.ColorCode = sql.NullString{String: *req.ColorCode, Valid: true}
 	}
+	if req.IsConfidential != nil {
+		existingTag.IsConfidential = *req.IsConfidential
+	}
 
 	if err := s.repo.UpdateTag(ctx, tagID, existingTag); err != nil {
 		return nil, fmt.Errorf("failed to update tag: %w", err)
@@ -496,7 +650,17 @@ func (s *service) UpdateTag(ctx context.Context, tagID int64, req *UpdateTagRequ
 	return &response, nil
 }
 
-func (s *service) DeleteTag(ctx context.Context, tagID int64) error {
+// DeleteTag soft deletes a tag. Deleting a confidential tag reveals its tickets, so it needs the
+// same permission as changing whether a tag is confidential.
+func (s *service) DeleteTag(ctx context.Context, tagID int64, requester Requester) error {
+	viewer, err := s.viewer(ctx, requester)
+	if err != nil {
+		return err
+	}
+	if err := s.checkConfidentialTags(ctx, viewer, []int64{tagID}); err != nil {
+		return err
+	}
+
 	if err := s.repo.DeleteTag(ctx, tagID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrTagNotFound
@@ -508,13 +672,18 @@ func (s *service) DeleteTag(ctx context.Context, tagID int64) error {
 
 // -------------------- Ticket-Tag Operations --------------------
 
-func (s *service) AddTagsToTicket(ctx context.Context, ticketPublicID string, req *AddTagRequest) error {
-	ticketID, err := s.repo.GetTicketInternalID(ctx, ticketPublicID)
+func (s *service) AddTagsToTicket(ctx context.Context, ticketPublicID string, requester Requester, req *AddTagRequest) error {
+	viewer, err := s.viewer(ctx, requester)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return ErrTicketNotFound
-		}
-		return fmt.Errorf("failed to get ticket: %w", err)
+		return err
+	}
+
+	ticketID, err := s.visibleTicketID(ctx, ticketPublicID, viewer)
+	if err != nil {
+		return err
+	}
+	if err := s.checkConfidentialTags(ctx, viewer, req.TagIDs); err != nil {
+		return err
 	}
 
 	if err := s.repo.AddTagsToTicket(ctx, ticketID, req.TagIDs, req.Category); err != nil {
@@ -524,13 +693,18 @@ func (s *service) AddTagsToTicket(ctx context.Context, ticketPublicID string, re
 	return nil
 }
 
-func (s *service) RemoveTagFromTicket(ctx context.Context, ticketPublicID string, tagID int64) error {
-	ticketID, err := s.repo.GetTicketInternalID(ctx, ticketPublicID)
+func (s *service) RemoveTagFromTicket(ctx context.Context, ticketPublicID string, requester Requester, tagID int64) error {
+	viewer, err := s.viewer(ctx, requester)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return ErrTicketNotFound
-		}
-		return fmt.Errorf("failed to get ticket: %w", err)
+		return err
+	}
+
+	ticketID, err := s.visibleTicketID(ctx, ticketPublicID, viewer)
+	if err != nil {
+		return err
+	}
+	if err := s.checkConfidentialTags(ctx, viewer, []int64{tagID}); err != nil {
+		return err
 	}
 
 	if err := s.repo.RemoveTagFromTicket(ctx, ticketID, tagID); err != nil {
@@ -543,14 +717,32 @@ func (s *service) RemoveTagFromTicket(ctx context.Context, ticketPublicID string
 	return nil
 }
 
+// checkConfidentialTags rejects adding or removing confidential tags by viewers who can't read
+// confidential tickets, as that would hide tickets from others or reveal them
+func (s *service) checkConfidentialTags(ctx context.Context, viewer *Viewer, tagIDs []int64) error {
+	if viewer.ReadConfidential || len(tagIDs) == 0 {
+		return nil
+	}
+
+	confidential, err := s.repo.HasConfidentialTag(ctx, tagIDs)
+	if err != nil {
+		return fmt.Errorf("failed to check tags: %w", err)
+	}
+	if confidential {
+		return ErrConfidentialTag
+	}
+	return nil
+}
+
 // -------------------- Entry-Tag Operations --------------------
 
-func (s *service) AddTagsToEntry(ctx context.Context, entryID int64, req *AddTagRequest) error {
-	if _, err := s.repo.GetEntryByID(ctx, entryID); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return ErrEntryNotFound
-		}
-		return fmt.Errorf("failed to get entry: %w", err)
+func (s *service) AddTagsToEntry(ctx context.Context, entryID int64, requester Requester, req *AddTagRequest) error {
+	viewer, err := s.viewer(ctx, requester)
+	if err != nil {
+		return err
+	}
+	if _, err := s.visibleEntry(ctx, entryID, viewer); err != nil {
+		return err
 	}
 
 	if err := s.repo.AddTagsToEntry(ctx, entryID, req.TagIDs, req.Category); err != nil {
@@ -560,12 +752,13 @@ func (s *service) AddTagsToEntry(ctx context.Context, entryID int64, req *AddTag
 	return nil
 }
 
-func (s *service) RemoveTagFromEntry(ctx context.Context, entryID int64, tagID int64) error {
-	if _, err := s.repo.GetEntryByID(ctx, entryID); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return ErrEntryNotFound
-		}
-		return fmt.Errorf("failed to get entry: %w", err)
+func (s *service) RemoveTagFromEntry(ctx context.Context, entryID int64, requester Requester, tagID int64) error {
+	viewer, err := s.viewer(ctx, requester)
+	if err != nil {
+		return err
+	}
+	if _, err := s.visibleEntry(ctx, entryID, viewer); err != nil {
+		return err
 	}
 
 	if err := s.repo.RemoveTagFromEntry(ctx, entryID, tagID); err != nil {
@@ -577,3 +770,27 @@ func (s *service) RemoveTagFromEntry(ctx context.Context, entryID int64, tagID i
 
 	return nil
 }
+
+// fileURLPattern matches the file public ID in URLs such as /files/{id}/download
+var fileURLPattern = regexp.MustCompile(`/files/([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})(?:/|$)`)
+
+// attachmentFileID extracts the file public ID from a FILE entry payload, or "" if there is none
+func attachmentFileID(payload json.RawMessage) string {
+	var attachment struct {
+		FileID  string `json:"file_id"`
+		FileURL string `json:"file_url"`
+	}
+	if err := json.Unmarshal(payload, &attachment); err != nil {
+		return ""
+	}
+
+	if attachment.FileID != "" {
+		return attachment.FileID
+	}
+
+	if match := fileURLPattern.FindStringSubmatch(attachment.FileURL); match != nil {
+		return match[1]
+	}
+
+	return ""
+}
@@ -7,6 +7,8 @@ import (
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"kc-api/internal/auth"
+	"kc-api/internal/password"
 	"kc-api/internal/utils"
 )
 
@@ -66,13 +68,13 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 
 // Create godoc
 // @Summary      Create a new user
-// @Description  Creates a new user with the provided data. Email and name are required. If login_id is not provided, email is used as login_id.
+// @Description  Creates a new user with the provided data. Email and name are required. If login_id is not provided, email is used as login_id. A password must meet the password policy; violations are listed per rule. Instead of a password, a bcrypt, PBKDF2 or Argon2id password_hash exported from another system can be imported; it is replaced with an Argon2id hash on the user's first login.
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param        request  body      CreateUserRequest  true  "User data"
 // @Success      201      {object}  UserListResponse
-// @Failure      400      {object}  ErrorResponse
+// @Failure      400      {object}  password.ErrorResponse  "Invalid request or password policy violations"
 // @Failure      409      {object}  ErrorResponse  "Email or login_id already exists"
 // @Failure      500      {object}  ErrorResponse
 // @Security     BearerAuth
@@ -86,11 +88,18 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 
 	result, err := h.service.Create(r.Context(), &req)
 	if err != nil {
+		var policyErr *password.PolicyError
 		switch {
 		case errors.Is(err, ErrInvalidEmail):
 			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid email format")
 		case errors.Is(err, ErrInvalidName):
 			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Name must have at least one locale value")
+		case errors.As(err, &policyErr):
+			utils.RespondJSON(w, http.StatusBadRequest, password.NewErrorResponse(policyErr))
+		case errors.Is(err, ErrInvalidHashFormat):
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Unsupported password hash format")
+		case errors.Is(err, ErrPasswordAndHash):
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Password and password_hash cannot both be set")
 		case errors.Is(err, ErrEmailExists):
 			utils.RespondError(w, r, http.StatusConflict, "Conflict", "Email already exists")
 		case errors.Is(err, ErrLoginIDExists):
@@ -138,14 +147,15 @@ func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
 
 // Update godoc
 // @Summary      Update user
-// @Description  Updates an existing user with the provided data
+// @Description  Updates an existing user with the provided data. A new password must meet the password policy, may be given as an imported password_hash as on creation, and cannot be set while impersonating a user.
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param        id       path      string             true  "User Public ID (UUID)"
 // @Param        request  body      UpdateUserRequest  true  "User data to update"
 // @Success      200      {object}  UserListResponse
-// @Failure      400      {object}  ErrorResponse
+// @Failure      400      {object}  password.ErrorResponse  "Invalid request or password policy violations"
+// @Failure      403      {object}  ErrorResponse  "Password change while impersonating"
 // @Failure      404      {object}  ErrorResponse
 // @Failure      409      {object}  ErrorResponse  "Email or login_id already exists"
 // @Failure      500      {object}  ErrorResponse
@@ -164,8 +174,14 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if (req.Password != nil || req.PasswordHash != nil) && auth.IsImpersonating(r.Context()) {
+		utils.RespondError(w, r, http.StatusForbidden, "Forbidden", "Not allowed while impersonating a user")
+		return
+	}
+
 	result, err := h.service.Update(r.Context(), id, &req)
 	if err != nil {
+		var policyErr *password.PolicyError
 		switch {
 		case errors.Is(err, ErrUserNotFound):
 			utils.RespondError(w, r, http.StatusNotFound, "Not Found", "User not found")
@@ -173,6 +189,12 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Invalid email format")
 		case errors.Is(err, ErrInvalidName):
 			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Name must have at least one locale value")
+		case errors.As(err, &policyErr):
+			utils.RespondJSON(w, http.StatusBadRequest, password.NewErrorResponse(policyErr))
+		case errors.Is(err, ErrInvalidHashFormat):
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Unsupported password hash format")
+		case errors.Is(err, ErrPasswordAndHash):
+			utils.RespondError(w, r, http.StatusBadRequest, "Bad Request", "Password and password_hash cannot both be set")
 		case errors.Is(err, ErrEmailExists):
 			utils.RespondError(w, r, http.StatusConflict, "Conflict", "Email already exists")
 		case errors.Is(err, ErrLoginIDExists):
@@ -131,6 +131,10 @@ func TestHandler_List(t *testing.T) {
 }
 
 func TestHandler_Create(t *testing.T) {
+	plainPassword := "Kettle-Sun-42"
+	bcryptHash := "$2b$12$R9h/cIPz0gi.URNNX3kh2OPST9/PgBkqquzi.Ss7KIUgO2t0jWMUW"
+	md5Hash := "$1$salt$md5crypt"
+
 	tests := []struct {
 		name           string
 		requestBody    interface{}
@@ -183,6 +187,29 @@ func TestHandler_Create(t *testing.T) {
 			mockError:      ErrInvalidName,
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name: "unsupported password hash",
+			requestBody: CreateUserRequest{
+				Email:        "john.doe@example.com",
+				Name:         json.RawMessage(`{"en-US": "John Doe"}`),
+				PasswordHash: &md5Hash,
+			},
+			mockReturn:     nil,
+			mockError:      ErrInvalidHashFormat,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "password and password hash",
+			requestBody: CreateUserRequest{
+				Email:        "john.doe@example.com",
+				Name:         json.RawMessage(`{"en-US": "John Doe"}`),
+				Password:     &plainPassword,
+				PasswordHash: &bcryptHash,
+			},
+			mockReturn:     nil,
+			mockError:      ErrPasswordAndHash,
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			name:           "invalid request body",
 			requestBody:    "invalid json",
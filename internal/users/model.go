@@ -66,6 +66,7 @@ type CreateUserRequest struct {
 	Name          json.RawMessage `json:"name" swaggertype:"object"`
 	LoginID       *string         `json:"login_id,omitempty" example:"john.doe"`
 	Password      *string         `json:"password,omitempty"`
+	PasswordHash  *string         `json:"password_hash,omitempty" example:"$2b$12$R9h/cIPz0gi.URNNX3kh2OPST9/PgBkqquzi.Ss7KIUgO2t0jWMUW"`
 	DeptID        *int64          `json:"dept_id,omitempty"`
 	RankID        *int64          `json:"rank_id,omitempty"`
 	DutyID        *int64          `json:"duty_id,omitempty"`
@@ -82,6 +83,7 @@ type UpdateUserRequest struct {
 	Name          *json.RawMessage `json:"name,omitempty" swaggertype:"object"`
 	Email         *string          `json:"email,omitempty" example:"john.doe@example.com"`
 	Password      *string          `json:"password,omitempty"`
+	PasswordHash  *string          `json:"password_hash,omitempty" example:"$2b$12$R9h/cIPz0gi.URNNX3kh2OPST9/PgBkqquzi.Ss7KIUgO2t0jWMUW"`
 	DeptID        *int64           `json:"dept_id,omitempty"`
 	RankID        *int64           `json:"rank_id,omitempty"`
 	DutyID        *int64           `json:"duty_id,omitempty"`
@@ -238,6 +238,7 @@ func (r *repository) Update(ctx context.Context, publicID string, user *User) er
 			login_id = $1,
 			name = $2,
 			email = $3,
+			email_verified_at = CASE WHEN email = $3 THEN email_verified_at END,
 			dept_id = $4,
 			rank_id = $5,
 			duty_id = $6,
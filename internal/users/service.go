@@ -13,9 +13,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"strings"
 
-	"golang.org/x/crypto/argon2"
+	"kc-api/internal/password"
 )
 
 var (
@@ -26,15 +27,8 @@ var (
 	ErrInvalidName       = errors.New("name must have at least one locale value")
 	ErrEncryptionFailed  = errors.New("encryption failed")
 	ErrDecryptionFailed  = errors.New("decryption failed")
-)
-
-// Argon2 parameters
-const (
-	argon2Time    = 1
-	argon2Memory  = 64 * 1024
-	argon2Threads = 4
-	argon2KeyLen  = 32
-	argon2SaltLen = 16
+	ErrInvalidHashFormat = errors.New("unsupported password hash format")
+	ErrPasswordAndHash   = errors.New("password and password_hash cannot both be set")
 )
 
 // Service defines the interface for user business logic
@@ -47,17 +41,37 @@ type Service interface {
 	Search(ctx context.Context, criteria *SearchUserRequest, page, limit int) (*UserListResponseWrapper, error)
 }
 
+// TokenRevoker revokes the access tokens issued to users whose login, email, password or status
+// changed. It is implemented by the auth service.
+type TokenRevoker interface {
+	RevokeUserTokens(ctx context.Context, userIDs ...int) error
+}
+
+// PasswordPolicy checks passwords set by administrators against the password policy, keeps
+// the password history and hashes passwords with the configured parameters. It is implemented
+// by the auth service.
+type PasswordPolicy interface {
+	CheckNewPassword(ctx context.Context, userID int, loginID, email, newPassword string) error
+	PasswordChanged(ctx context.Context, userID int, passwordHash string) error
+	HashPassword(plain string) (string, error)
+}
+
 type service struct {
 	repo          Repository
 	encryptionKey []byte
+	tokens        TokenRevoker   // nil leaves issued access tokens valid until they expire
+	passwords     PasswordPolicy // nil accepts any password and hashes with the default parameters
 }
 
-// NewService creates a new user service with the given repository and encryption key
-func NewService(repo Repository, encryptionKey string) Service {
+// NewService creates a new user service with the given repository and encryption key.
+// The token revoker and the password policy may be nil.
+func NewService(repo Repository, encryptionKey string, tokens TokenRevoker, passwords PasswordPolicy) Service {
 	key := sha256.Sum256([]byte(encryptionKey))
 	return &service{
 		repo:          repo,
 		encryptionKey: key[:],
+		tokens:        tokens,
+		passwords:     passwords,
 	}
 }
 
@@ -82,6 +96,10 @@ func (s *service) Create(ctx context.Context, req *CreateUserRequest) (*UserList
 		return nil, ErrEmailExists
 	}
 
+	if err := checkImportedHash(req.Password, req.PasswordHash); err != nil {
+		return nil, err
+	}
+
 	// Set login_id to email if not provided
 	loginID := req.Email
 	if req.LoginID != nil && *req.LoginID != "" {
@@ -149,6 +167,9 @@ func (s *service) Create(ctx context.Context, req *CreateUserRequest) (*UserList
 
 	// Handle password hashing
 	if req.Password != nil && *req.Password != "" {
+		if err := s.checkNewPassword(ctx, 0, loginID, req.Email, *req.Password); err != nil {
+			return nil, err
+		}
 		hashedPassword, err := s.hashPassword(*req.Password)
 		if err != nil {
 			return nil, fmt.Errorf("failed to hash password: %w", err)
@@ -156,9 +177,17 @@ func (s *service) Create(ctx context.Context, req *CreateUserRequest) (*UserList
 		user.PasswordHash = sql.NullString{String: hashedPassword, Valid: true}
 	}
 
+	// Password hashes imported from another system are replaced on the user's first login
+	if req.PasswordHash != nil && *req.PasswordHash != "" {
+		user.PasswordHash = sql.NullString{String: *req.PasswordHash, Valid: true}
+	}
+
 	if err := s.repo.Create(ctx, user); err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
+	if user.PasswordHash.Valid {
+		s.passwordChanged(ctx, user.ID, user.PasswordHash.String)
+	}
 
 	response := user.ToListResponse()
 	return &response, nil
@@ -217,6 +246,13 @@ func (s *service) Update(ctx context.Context, publicID string, req *UpdateUserRe
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
+	if err := checkImportedHash(req.Password, req.PasswordHash); err != nil {
+		return nil, err
+	}
+
+	// Changes to the claims or credentials of access tokens revoke them
+	revoke := false
+
 	// Update fields if provided
 	if req.LoginID != nil && *req.LoginID != "" && *req.LoginID != existingUser.LoginID {
 		exists, err := s.repo.ExistsByLoginID(ctx, *req.LoginID)
@@ -227,6 +263,7 @@ func (s *service) Update(ctx context.Context, publicID string, req *UpdateUserRe
 			return nil, ErrLoginIDExists
 		}
 		existingUser.LoginID = *req.LoginID
+		revoke = true
 	}
 
 	if req.Name != nil {
@@ -248,6 +285,7 @@ func (s *service) Update(ctx context.Context, publicID string, req *UpdateUserRe
 			return nil, ErrEmailExists
 		}
 		existingUser.Email = *req.Email
+		revoke = true
 	}
 
 	// Update ID fields
@@ -306,11 +344,19 @@ func (s *service) Update(ctx context.Context, publicID string, req *UpdateUserRe
 
 	// Handle password update
 	if req.Password != nil && *req.Password != "" {
+		if err := s.checkNewPassword(ctx, existingUser.ID, existingUser.LoginID, existingUser.Email, *req.Password); err != nil {
+			return nil, err
+		}
 		hashedPassword, err := s.hashPassword(*req.Password)
 		if err != nil {
 			return nil, fmt.Errorf("failed to hash password: %w", err)
 		}
 		existingUser.PasswordHash = sql.NullString{String: hashedPassword, Valid: true}
+		revoke = true
+	}
+	if req.PasswordHash != nil && *req.PasswordHash != "" {
+		existingUser.PasswordHash = sql.NullString{String: *req.PasswordHash, Valid: true}
+		revoke = true
 	}
 
 	// Handle visibility update
@@ -321,19 +367,79 @@ func (s *service) Update(ctx context.Context, publicID string, req *UpdateUserRe
 	if err := s.repo.Update(ctx, publicID, existingUser); err != nil {
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
+	if (req.Password != nil && *req.Password != "") || (req.PasswordHash != nil && *req.PasswordHash != "") {
+		s.passwordChanged(ctx, existingUser.ID, existingUser.PasswordHash.String)
+	}
+	if revoke {
+		s.revokeTokens(ctx, existingUser.ID)
+	}
 
 	response := existingUser.ToListResponse()
 	return &response, nil
 }
 
-// Delete performs a soft delete on a user
+// Delete performs a soft delete on a user and revokes their access tokens
 func (s *service) Delete(ctx context.Context, publicID string) error {
+	user, err := s.repo.GetByPublicID(ctx, publicID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
 	if err := s.repo.Delete(ctx, publicID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrUserNotFound
 		}
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
+	s.revokeTokens(ctx, user.ID)
+	return nil
+}
+
+// revokeTokens revokes the access tokens of a changed user, so that the change takes effect
+// immediately. Failures are logged; the change itself has already been made.
+func (s *service) revokeTokens(ctx context.Context, userID int) {
+	if s.tokens == nil {
+		return
+	}
+	if err := s.tokens.RevokeUserTokens(ctx, userID); err != nil {
+		log.Printf("[WARN] Failed to revoke access tokens of user %d: %v", userID, err)
+	}
+}
+
+// checkNewPassword checks a password about to be set against the password policy.
+// userID is 0 for new users.
+func (s *service) checkNewPassword(ctx context.Context, userID int, loginID, email, newPassword string) error {
+	if s.passwords == nil {
+		return nil
+	}
+	return s.passwords.CheckNewPassword(ctx, userID, loginID, email, newPassword)
+}
+
+// passwordChanged adds a new password to the user's password history, logging failures
+func (s *service) passwordChanged(ctx context.Context, userID int, passwordHash string) {
+	if s.passwords == nil {
+		return
+	}
+	if err := s.passwords.PasswordChanged(ctx, userID, passwordHash); err != nil {
+		log.Printf("[WARN] Failed to record password change of user %d: %v", userID, err)
+	}
+}
+
+// checkImportedHash checks a password hash imported from another system, which replaces setting
+// a password and must be in a format the login accepts
+func checkImportedHash(plain, encoded *string) error {
+	if encoded == nil || *encoded == "" {
+		return nil
+	}
+	if plain != nil && *plain != "" {
+		return ErrPasswordAndHash
+	}
+	if err := password.CheckHash(*encoded); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidHashFormat, err)
+	}
 	return nil
 }
 
@@ -443,21 +549,12 @@ func (s *service) hashSHA256(input string) string {
 	return hex.EncodeToString(hash[:])
 }
 
-// hashPassword hashes a password using Argon2id
-func (s *service) hashPassword(password string) (string, error) {
-	salt := make([]byte, argon2SaltLen)
-	if _, err := rand.Read(salt); err != nil {
-		return "", err
+// hashPassword hashes a password using Argon2id with the parameters of the password policy
+func (s *service) hashPassword(plain string) (string, error) {
+	if s.passwords == nil {
+		return password.Hash(plain, password.DefaultParams())
 	}
-
-	hash := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
-
-	// Format: $argon2id$v=19$m=65536,t=1,p=4$<salt>$<hash>
-	b64Salt := base64.RawStdEncoding.EncodeToString(salt)
-	b64Hash := base64.RawStdEncoding.EncodeToString(hash)
-
-	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
-		argon2.Version, argon2Memory, argon2Time, argon2Threads, b64Salt, b64Hash), nil
+	return s.passwords.HashPassword(plain)
 }
 
 // getLast4Digits extracts the last 4 digits from a phone number